@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/storage"
+	"strings"
+	"time"
+)
+
+// checkTimeout bounds each check that reaches another service
+const checkTimeout = 10 * time.Second
+
+// checkReport collects check results and prints one line per check.
+type checkReport struct {
+	failed bool
+}
+
+func (c *checkReport) ok(name, detail string) {
+	fmt.Println(strings.TrimSpace(fmt.Sprintf("ok    %-9s %s", name, detail)))
+}
+
+func (c *checkReport) skip(name, detail string) {
+	fmt.Printf("skip  %-9s %s\n", name, detail)
+}
+
+func (c *checkReport) fail(name string, err error) {
+	c.failed = true
+	fmt.Printf("FAIL  %-9s %v\n", name, err)
+}
+
+// runCheck validates what the engine needs to start, without starting it:
+// the configuration, the database and its schema, the JWT key and media
+// storage. It prints a report and returns the exit code, 1 if anything
+// failed, so deploys and entrypoint scripts can stop early.
+func runCheck() int {
+	report := &checkReport{}
+	defer func() {
+		if report.failed {
+			fmt.Println("check failed")
+		} else {
+			fmt.Println("check passed")
+		}
+	}()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		report.fail("config", err)
+		return 1
+	}
+	report.ok("config", "")
+
+	if cfg.Server.JWTSecret == "" {
+		report.fail("jwt", fmt.Errorf("JWT_SECRET is not set; tokens would be signed with the built-in development key"))
+	} else {
+		report.ok("jwt", "JWT_SECRET is set")
+	}
+
+	checkDatabase(report, cfg)
+	checkStorage(report, cfg.Storage)
+
+	if report.failed {
+		return 1
+	}
+	return 0
+}
+
+// checkDatabase connects and makes sure the database has every migration
+// this version of the engine ships.
+func checkDatabase(report *checkReport, cfg *config.Config) {
+	db, err := database.NewPostgresDB(database.WithApplicationName(cfg.Database.URI, "gator-check"))
+	if err != nil {
+		report.fail("database", err)
+		report.skip("schema", "no database connection")
+		return
+	}
+	defer db.Close(context.Background())
+	report.ok("database", "connected")
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	pending, err := db.PendingMigrations(ctx)
+	switch {
+	case err != nil:
+		report.fail("schema", err)
+	case len(pending) > 0:
+		names := make([]string, len(pending))
+		for i, m := range pending {
+			names[i] = m.String()
+		}
+		report.fail("schema", fmt.Errorf("pending migrations %s; run \"engine migrate\"", strings.Join(names, ", ")))
+	default:
+		report.ok("schema", "up to date")
+	}
+}
+
+// checkStorage writes and deletes a probe object locally, or makes sure the
+// S3 bucket is reachable with the configured credentials.
+func checkStorage(report *checkReport, cfg *config.StorageConfig) {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	switch cfg.Backend {
+	case "local":
+		store, err := storage.NewLocal(cfg.Dir, cfg.BaseURL)
+		if err == nil {
+			err = store.Put(ctx, "check/probe", bytes.NewReader(nil), "text/plain")
+		}
+		if err == nil {
+			err = store.Delete(ctx, "check/probe")
+		}
+		if err != nil {
+			report.fail("storage", err)
+			return
+		}
+		report.ok("storage", "writable: "+cfg.Dir)
+	case "s3":
+		store, err := storage.NewS3(storage.S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		})
+		if err == nil {
+			err = store.Ping(ctx)
+		}
+		if err != nil {
+			report.fail("storage", err)
+			return
+		}
+		report.ok("storage", "reachable: s3 bucket "+cfg.S3Bucket)
+	default:
+		report.fail("storage", fmt.Errorf("unknown storage backend %q (expected local or s3)", cfg.Backend))
+	}
+}
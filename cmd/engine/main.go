@@ -3,82 +3,221 @@ package main
 import (
 	"context"
 	"fmt"
+	"gator-swamp/internal/captcha"
 	"gator-swamp/internal/config"
 	"gator-swamp/internal/database"
 	"gator-swamp/internal/engine"
 	"gator-swamp/internal/engine/actors" // Import actors package
+	"gator-swamp/internal/events"
 	"gator-swamp/internal/handlers"
+	"gator-swamp/internal/jobs"
+	"gator-swamp/internal/logging"
+	"gator-swamp/internal/media"
 	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/policy"
+	"gator-swamp/internal/presence"
+	"gator-swamp/internal/profanity"
+	"gator-swamp/internal/search"
+	"gator-swamp/internal/storage"
 	"gator-swamp/internal/utils"
 	"gator-swamp/internal/websocket"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
 	_ "github.com/lib/pq" // PostgreSQL driver
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
-	// Configure logging
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	log.Println("Starting Gator Swamp API server...")
+	// "engine check" validates the deployment instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(runCheck())
+	}
+	// "engine migrate" applies or rolls back schema migrations
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+	// "engine search reindex" backfills an external search index
+	if len(os.Args) > 1 && os.Args[1] == "search" {
+		os.Exit(runSearch(os.Args[2:]))
+	}
 
 	// Load configuration
 	config, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
-	}
-
-	// --- BEGIN DEBUG LOGGING ---
-	log.Printf("[DEBUG] Loaded DB Config - Type: %s", config.Database.Type)
-	log.Printf("[DEBUG] Loaded DB Config - URI: %s", config.Database.URI)
-	log.Printf("[DEBUG] Loaded DB Config - Host: %s", config.Database.Host)
-	log.Printf("[DEBUG] Loaded DB Config - Port: %d", config.Database.Port)
-	log.Printf("[DEBUG] Loaded DB Config - User: %s", config.Database.User)
-	log.Printf("[DEBUG] Loaded DB Config - Name: %s", config.Database.Name)
-	log.Printf("[DEBUG] Loaded DB Config - SSLMode: %s", config.Database.SSLMode)
-	// Check if password field exists and log placeholder if it does
-	if config.Database.Password != "" {
-		log.Printf("[DEBUG] Loaded DB Config - Password: [SET]")
+		fatal("Failed to load configuration", "error", err)
+	}
+
+	// Everything logs through one structured logger; the standard log
+	// package and the actor system are routed to it too
+	logger, err := logging.New(os.Stderr, config.Log.Format, config.Log.Level)
+	if err != nil {
+		fatal("Failed to configure logging", "error", err)
+	}
+	slog.SetDefault(logger)
+	slog.Info("Starting Gator Swamp API server")
+
+	// The URI is left out, since it may hold the password
+	slog.Debug("Loaded database config",
+		"type", config.Database.Type,
+		"host", config.Database.Host,
+		"port", config.Database.Port,
+		"user", config.Database.User,
+		"name", config.Database.Name,
+		"sslmode", config.Database.SSLMode,
+		"password_set", config.Database.Password != "")
+
+	if config.Server.JWTSecret != "" {
+		middleware.SetJWTSecret(config.Server.JWTSecret)
 	} else {
-		log.Printf("[DEBUG] Loaded DB Config - Password: [NOT SET]")
+		slog.Warn("JWT_SECRET is not set; signing tokens with the built-in development key")
 	}
-	// --- END DEBUG LOGGING ---
 
 	// Initialize Actor System
-	system := actor.NewActorSystem()
+	system := actor.NewActorSystemWithConfig(actor.Configure(
+		actor.WithLoggerFactory(func(*actor.ActorSystem) *slog.Logger { return logger }),
+	))
 	rootContext := system.Root // Use system.Root based on engine.go
 
 	// Initialize Metrics Collector (but don't register it with Prometheus here)
 	metrics := utils.NewMetricsCollector()
 	// REMOVED: utils.RegisterMetrics(metrics) // Incorrect function call
 
-	// Initialize Database (PostgreSQL only)
-	dbAdapter, err := database.NewPostgresDB(config.Database.URI)
+	// Initialize Database (PostgreSQL only). The application name tags this
+	// instance's writes so its change feed can skip them.
+	instanceName := "gator-" + uuid.NewString()[:8]
+	dbURI := database.WithApplicationName(config.Database.URI, instanceName)
+	pgDB, err := database.NewPostgresDB(dbURI)
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		fatal("Failed to initialize database", "error", err)
 	}
-	defer dbAdapter.Close(context.Background()) // Ensure DB connection is closed on exit
-	if err := dbAdapter.InitializeTables(context.Background()); err != nil {
-		log.Fatalf("Failed to initialize tables: %v", err)
+	defer pgDB.Close(context.Background()) // Ensure DB connection is closed on exit
+	if config.Database.AutoMigrate {
+		applied, err := pgDB.Migrate(context.Background())
+		if err != nil {
+			fatal("Failed to migrate database", "error", err)
+		}
+		for _, m := range applied {
+			slog.Info("Applied migration", "migration", m)
+		}
+	} else if pending, err := pgDB.PendingMigrations(context.Background()); err != nil {
+		fatal("Failed to read migration status", "error", err)
+	} else if len(pending) > 0 {
+		fatal("Database is behind; run \"engine migrate\" first", "pending", len(pending), "next", pending[0])
+	}
+	// Fail fast while Postgres is unreachable rather than letting every
+	// request wait out its timeout
+	var dbAdapter database.Store = pgDB
+	if config.Database.BreakerThreshold > 0 {
+		dbAdapter = database.WithBreaker(pgDB, database.NewBreaker(config.Database.BreakerThreshold, config.Database.BreakerCooldown))
 	}
 
 	// Initialize WebSocket Hub
 	hub := websocket.NewHub()
-	go hub.Run() // Run the hub in a separate goroutine
+	hub.MaxConnsPerUser = config.Server.WSMaxConnsPerUser
+	hub.MaxConns = config.Server.WSMaxConns
+	// Keep users' connection status current from heartbeats and WebSocket pongs
+	activity := presence.NewTracker(dbAdapter, time.Minute)
+
+	// Initialize media storage for uploads
+	var mediaStore storage.Storage
+	var localStore *storage.LocalStorage // Set when this instance serves the files itself
+	switch config.Storage.Backend {
+	case "s3":
+		mediaStore, err = storage.NewS3(storage.S3Options{
+			Endpoint:  config.Storage.S3Endpoint,
+			Region:    config.Storage.S3Region,
+			Bucket:    config.Storage.S3Bucket,
+			AccessKey: config.Storage.S3AccessKey,
+			SecretKey: config.Storage.S3SecretKey,
+			PathStyle: config.Storage.S3PathStyle,
+			BaseURL:   config.Storage.BaseURL,
+		})
+	case "local":
+		localStore, err = storage.NewLocal(config.Storage.Dir, config.Storage.BaseURL)
+		mediaStore = localStore
+	default:
+		err = fmt.Errorf("unknown storage backend %q (expected local or s3)", config.Storage.Backend)
+	}
+	if err != nil {
+		fatal("Failed to initialize media storage", "error", err)
+	}
+
+	// Initialize background job queue
+	jobQueue := jobs.NewQueue(dbAdapter, jobs.Options{Workers: config.Jobs.Workers})
+	jobs.RegisterDefaultHandlers(jobQueue, dbAdapter, config.Mail)
+	media.RegisterHandlers(jobQueue, dbAdapter, mediaStore)
+	jobs.RegisterExportHandler(jobQueue, dbAdapter, mediaStore)
+	if err := jobQueue.Enqueue(context.Background(), jobs.TypeReconcileCounters, struct{}{}, jobs.UniqueKey(jobs.TypeReconcileCounters)); err != nil {
+		slog.Error("Failed to enqueue startup counter reconciliation", "error", err)
+	}
+	if config.Jobs.DigestEnabled {
+		if err := jobs.ScheduleNextDigestRun(context.Background(), jobQueue); err != nil {
+			slog.Error("Failed to schedule digests", "error", err)
+		}
+	}
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	jobsDone := make(chan struct{})
+	go func() {
+		jobQueue.Run(jobsCtx)
+		close(jobsDone)
+	}()
+
+	// Initialize periodic maintenance tasks (leased, so safe with several instances)
+	schedulerDone := make(chan struct{})
+	if config.Jobs.SchedulerEnabled {
+		scheduler := jobs.NewScheduler(dbAdapter, 30*time.Second)
+		jobs.RegisterMaintenanceTasks(scheduler, dbAdapter, time.Duration(config.Jobs.ArchiveAfterDays)*24*time.Hour)
+		jobs.RegisterRetentionTasks(scheduler, dbAdapter, config.Retention)
+		if config.Jobs.PublicWebURL != "" {
+			jobs.RegisterSitemapTask(scheduler, dbAdapter, mediaStore, config.Jobs.PublicWebURL)
+		}
+		go func() {
+			scheduler.Run(jobsCtx)
+			close(schedulerDone)
+		}()
+	} else {
+		close(schedulerDone)
+	}
+
+	// Initialize the domain event bus, streaming to an external sink if configured
+	var sink events.Sink
+	if config.Events.Sink != "" {
+		sink, err = events.NewSink(config.Events.Sink, config.Events.URL, config.Events.TopicPrefix)
+		if err != nil {
+			fatal("Failed to initialize event sink", "error", err)
+		}
+		slog.Info("Streaming domain events", "sink", config.Events.Sink, "url", config.Events.URL)
+	}
+	eventBus := events.NewBus(sink, config.Events.BufferSize)
+	go eventBus.Run()
+
+	// Initialize post search
+	searchProvider, err := search.NewProvider(config.Search.Provider, config.Search.URL, config.Search.Index, dbAdapter)
+	if err != nil {
+		fatal("Failed to initialize search", "error", err)
+	}
+
+	// CAPTCHA checks on registration and low-karma content, when configured
+	captchaVerifier, err := captcha.New(config.Captcha.Provider, config.Captcha.Secret)
+	if err != nil {
+		fatal("Failed to initialize CAPTCHA verification", "error", err)
+	}
 
 	// Initialize Engine Actor
-	engineInstance := engine.NewEngine(system, metrics, dbAdapter)
-	engineProps := actor.PropsFromProducer(func() actor.Actor { return engineInstance })
+	engineInstance := engine.NewEngine(system, metrics, dbAdapter, hub, eventBus, config.Policies, config.ActorCalls)
+	engineProps := logging.Props(func() actor.Actor { return engineInstance })
 	enginePID, err := rootContext.SpawnNamed(engineProps, "engine-actor")
 	if err != nil {
-		log.Fatalf("Failed to spawn engine actor instance: %v", err)
+		fatal("Failed to spawn engine actor", "error", err)
 	}
 
 	// Get PIDs for actors managed BY the Engine
@@ -87,11 +226,30 @@ func main() {
 	subredditActorPID := engineInstance.GetSubredditActor()
 	userSupervisorPID := engineInstance.GetUserSupervisor()
 
-	// Spawn DirectMessageActor directly, passing the DB adapter and Hub
-	directMessageActorPID := rootContext.Spawn(actor.PropsFromProducer(func() actor.Actor {
-		return actors.NewDirectMessageActor(dbAdapter, hub)
+	// The UserSupervisor records and announces users coming online and going offline
+	hub.OnActivity = func(userID uuid.UUID) { activity.Touch(userID) }
+	hub.OnOnline = func(userID uuid.UUID) {
+		rootContext.Send(userSupervisorPID, &actors.ConnectUserMsg{UserID: userID})
+	}
+	hub.OnOffline = func(userID uuid.UUID) {
+		activity.Reset(userID)
+		rootContext.Send(userSupervisorPID, &actors.DisconnectUserMsg{UserID: userID})
+	}
+	go hub.Run() // Run the hub in a separate goroutine
+
+	// Spawn DirectMessageActor directly, passing the DB adapter, Hub and job queue
+	directMessageActorPID := rootContext.Spawn(logging.Props(func() actor.Actor {
+		return actors.NewDirectMessageActor(dbAdapter, hub, jobQueue)
 	}))
-	log.Printf("Direct Message actor started with PID: %s", directMessageActorPID.String())
+	slog.Info("Direct message actor started", "pid", directMessageActorPID.String())
+
+	// Spawn SearchActor, which also indexes posts if the index is external
+	searchActorPID := rootContext.Spawn(logging.Props(func() actor.Actor {
+		return actors.NewSearchActor(searchProvider, dbAdapter)
+	}))
+	if searchProvider.External() {
+		actors.SubscribeSearchIndexer(eventBus, rootContext, searchActorPID)
+	}
 
 	// Initialize Server with dependencies including the hub
 	server := handlers.NewServer(
@@ -102,23 +260,49 @@ func main() {
 		metrics,        // Pass Metrics Collector
 		commentActorPID,
 		directMessageActorPID, // Pass the directly spawned PID
+		searchActorPID,
 		dbAdapter,
 		hub,
 		postActorPID,
 		subredditActorPID,
 		userSupervisorPID,
-		5*time.Second, // Example Request Timeout
+		mediaStore,
+		jobQueue,
+		activity,
+		config.ActorCalls,
 	)
 
+	server.Profanity = profanity.New(config.Content.ProfanityWords)
+	server.ScoreFuzzAge = config.Content.ScoreFuzzAge
+	server.Policies = config.Policies
+	if config.RateLimit.Enabled {
+		server.RateLimits = middleware.NewRateLimiter(middleware.RateLimitOptions{
+			Window: time.Minute,
+			Limits: map[string]int{
+				middleware.RateLimitRead:  config.RateLimit.ReadPerMinute,
+				middleware.RateLimitWrite: config.RateLimit.WritePerMinute,
+			},
+			BotLimits: map[string]int{
+				middleware.RateLimitRead:  config.RateLimit.BotReadPerMinute,
+				middleware.RateLimitWrite: config.RateLimit.BotWritePerMinute,
+			},
+		})
+	}
+
 	// Setup HTTP routes
 	mux := http.NewServeMux()
 
-	// CORS configuration
+	// CORS configuration; clients may read the rate limit headers to pace themselves
+	rateLimitHeaders := []string{
+		middleware.RateLimitLimitHeader, middleware.RateLimitRemainingHeader,
+		middleware.RateLimitResetHeader, middleware.RateLimitBucketHeader, "Retry-After",
+	}
 	corsConfig := middleware.CORSConfig{
 		AllowedOrigins: config.AllowedOrigins,
-		AllowedMethods: strings.Split("GET,POST,PUT,DELETE,OPTIONS", ","), // Split string into slice
-		AllowedHeaders: strings.Split("Content-Type,Authorization", ","),  // Split string into slice
-		MaxAge:         86400,
+		AllowedMethods: config.CORS.AllowedMethods,
+		AllowedHeaders: append(slices.Clone(config.CORS.AllowedHeaders), middleware.CaptchaHeader),
+		ExposedHeaders: append([]string{"X-Feed-Ranking", middleware.RequestIDHeader}, rateLimitHeaders...),
+		MaxAge:         config.CORS.MaxAge,
 		// AllowCredentials defaults true in DefaultCORSConfig
 	}
 
@@ -130,57 +314,185 @@ func main() {
 	// Public routes
 	mux.HandleFunc("/health", middleware.ApplyCORS(server.HandleSimpleHealth(), &corsConfig))
 	mux.HandleFunc("/health/full", middleware.ApplyCORS(server.HandleHealth(), &corsConfig))
-	mux.HandleFunc("/user/register", middleware.ApplyCORS(server.HandleUserRegistration(), &corsConfig))
+	mux.HandleFunc("/user/register", middleware.ApplyCORS(middleware.ApplyCaptcha(server.HandleUserRegistration(), captchaVerifier), &corsConfig))
 	mux.HandleFunc("/user/login", middleware.ApplyCORS(server.HandleUserLogin(), &corsConfig))
+	mux.HandleFunc("/user/refresh", middleware.ApplyCORS(server.HandleRefreshToken(), &corsConfig))
+	mux.HandleFunc("/user/logout", middleware.ApplyCORS(server.HandleLogout(), &corsConfig))
+	if config.Jobs.PublicWebURL != "" {
+		mux.HandleFunc("/sitemap.xml", server.HandleSitemap())
+		mux.HandleFunc("/public/subreddit/index", middleware.ApplyCORS(server.HandleSubredditIndex(), &corsConfig))
+	}
+
+	// Account requirements for operations, configured in config.Policies
+	requirePolicy := func(handler http.HandlerFunc, op string, methods ...string) http.HandlerFunc {
+		return middleware.ApplyPolicy(handler, config.Policies, dbAdapter, op, methods...)
+	}
+	// CAPTCHA tokens on content created by low-karma accounts
+	requireCaptcha := func(handler http.HandlerFunc) http.HandlerFunc {
+		return middleware.ApplyLowKarmaCaptcha(handler, captchaVerifier, dbAdapter, config.Captcha.ContentKarma, http.MethodPost)
+	}
 
 	// Protected routes (Apply JWT middleware)
 	mux.HandleFunc("/subreddit",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubreddits(), "/subreddit"), &corsConfig))
+	mux.HandleFunc("/subreddit/trending",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleTrendingSubreddits(), "/subreddit/trending"), &corsConfig))
 	mux.HandleFunc("/subreddit/members",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubredditMembers(), "/subreddit/members"), &corsConfig))
+	mux.HandleFunc("/subreddit/settings",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubredditSettings(), "/subreddit/settings"), &corsConfig))
+	mux.HandleFunc("/subreddit/rating",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubredditRating(), "/subreddit/rating"), &corsConfig))
+	mux.HandleFunc("/subreddit/moderators",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubredditModerators(), "/subreddit/moderators"), &corsConfig))
+	mux.HandleFunc("/subreddit/flairs",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubredditFlairs(), "/subreddit/flairs"), &corsConfig))
+	mux.HandleFunc("/report",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleReport(), "/report"), &corsConfig))
+	mux.HandleFunc("/subreddit/reports",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubredditReports(), "/subreddit/reports"), &corsConfig))
+	mux.HandleFunc("/subreddit/exports",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubredditExports(), "/subreddit/exports"), &corsConfig))
+	mux.HandleFunc("/subreddit/remove",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleModeratorRemove(), "/subreddit/remove"), &corsConfig))
+	mux.HandleFunc("/subreddit/lock",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleModeratorLock(), "/subreddit/lock"), &corsConfig))
+	mux.HandleFunc("/subreddit/pending",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandlePendingComments(), "/subreddit/pending"), &corsConfig))
+	mux.HandleFunc("/subreddit/pending/posts",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandlePendingPosts(), "/subreddit/pending/posts"), &corsConfig))
 	mux.HandleFunc("/post",
-		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandlePost(), "/post"), &corsConfig))
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(requirePolicy(requireCaptcha(server.HandlePost()), policy.OpCreatePost, http.MethodPost), "/post"), &corsConfig))
+	mux.HandleFunc("/post/full",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandlePostFull(), "/post/full"), &corsConfig))
+	mux.HandleFunc("/post/metadata",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandlePostMetadata(), "/post/metadata"), &corsConfig))
+	mux.HandleFunc("/post/history",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandlePostHistory(), "/post/history"), &corsConfig))
+	mux.HandleFunc("/post/lock",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandlePostLock(), "/post/lock"), &corsConfig))
 	mux.HandleFunc("/post/vote",
-		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleVote(), "/post/vote"), &corsConfig))
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(requirePolicy(server.HandleVote(), policy.OpVote), "/post/vote"), &corsConfig))
 	mux.HandleFunc("/user/feed",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetFeed(), "/user/feed"), &corsConfig))
+	mux.HandleFunc("/user/inbox",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleInbox(), "/user/inbox"), &corsConfig))
+	// Policies depend on what is answered, so the handler checks them itself
+	mux.HandleFunc("/inbox/{notificationId}/reply",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(requireCaptcha(server.HandleInboxReply()), "/inbox/reply"), &corsConfig))
+	mux.HandleFunc("/user/preferences",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUserPreferences(), "/user/preferences"), &corsConfig))
 	mux.HandleFunc("/user/profile",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUserProfile(), "/user/profile"), &corsConfig))
 	mux.HandleFunc("/comment",
-		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleComment(), "/comment"), &corsConfig))
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(requirePolicy(requireCaptcha(server.HandleComment()), policy.OpCreateComment, http.MethodPost), "/comment"), &corsConfig))
 	mux.HandleFunc("/comment/post",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetPostComments(), "/comment/post"), &corsConfig))
+	mux.HandleFunc("/comment/tree",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetCommentTree(), "/comment/tree"), &corsConfig))
 	mux.HandleFunc("/messages",
-		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleDirectMessages(), "/messages"), &corsConfig))
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(requirePolicy(server.HandleDirectMessages(), policy.OpSendMessage, http.MethodPost), "/messages"), &corsConfig))
 	mux.HandleFunc("/messages/conversation",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleConversation(), "/messages/conversation"), &corsConfig))
+	mux.HandleFunc("/messages/unread",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUnreadCount(), "/messages/unread"), &corsConfig))
 	mux.HandleFunc("/messages/read",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleMarkMessageRead(), "/messages/read"), &corsConfig))
 	mux.HandleFunc("/comment/vote",
-		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleCommentVote(), "/comment/vote"), &corsConfig))
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(requirePolicy(server.HandleCommentVote(), policy.OpVote), "/comment/vote"), &corsConfig))
 	mux.HandleFunc("/posts/recent",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleRecentPosts(), "/posts/recent"), &corsConfig))
 	mux.HandleFunc("/users",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetAllUsers(), "/users"), &corsConfig))
+	mux.HandleFunc("/users/leaderboard",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleKarmaLeaderboard(), "/users/leaderboard"), &corsConfig))
+	mux.HandleFunc("/user/limits",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUserLimits(), "/user/limits"), &corsConfig))
+	mux.HandleFunc("/user/heartbeat",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleHeartbeat(), "/user/heartbeat"), &corsConfig))
+	mux.HandleFunc("/admin/impersonate",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleImpersonate(), "/admin/impersonate"), &corsConfig))
+	mux.HandleFunc("/admin/audit",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleAuditLog(), "/admin/audit"), &corsConfig))
+	mux.HandleFunc("/admin/content",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleAdminContent(), "/admin/content"), &corsConfig))
+	mux.HandleFunc("/admin/content/restore",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleAdminRestore(), "/admin/content/restore"), &corsConfig))
+	mux.HandleFunc("/admin/users/merge",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleAdminMergeUsers(), "/admin/users/merge"), &corsConfig))
+	mux.HandleFunc("/admin/bots",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleAdminBots(), "/admin/bots"), &corsConfig))
+	mux.HandleFunc("/search",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSearch(), "/search"), &corsConfig))
+	middleware.AuditImpersonatedRequest = server.AuditImpersonatedRequest
+	mux.HandleFunc("/user/avatar",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleAvatarUpload(), "/user/avatar"), &corsConfig))
+	mux.HandleFunc("/upload",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleMediaUpload(), "/upload"), &corsConfig))
+
+	// Uploaded media, when served from this instance
+	if localStore != nil && strings.HasPrefix(config.Storage.BaseURL, "/") {
+		prefix := strings.TrimRight(config.Storage.BaseURL, "/") + "/"
+		mux.Handle(prefix, http.StripPrefix(prefix, localStore.Handler()))
+	}
 
 	// WebSocket endpoint
 	mux.HandleFunc("/ws", server.HandleWebSocket())
 
+	var rootHandler http.Handler = mux
+	if server.RateLimits != nil {
+		rootHandler = server.RateLimits.Middleware(rootHandler)
+	}
+	if config.AccessLog.Enabled {
+		rootHandler = middleware.AccessLog(rootHandler, middleware.AccessLogOptions{
+			SampleRate:     config.AccessLog.SampleRate,
+			ErrorBodyBytes: config.AccessLog.ErrorBodyBytes,
+		})
+	}
+	// Outermost, so the access log and everything after it see the ID
+	rootHandler = middleware.RequestID(rootHandler)
+
+	// Keep caches in step with writes made by other instances or tools
+	changesCtx, stopChanges := context.WithCancel(context.Background())
+	changesDone := make(chan struct{})
+	if config.Database.ChangeFeed {
+		go func() {
+			defer close(changesDone)
+			err := database.ListenChanges(changesCtx, dbURI, instanceName, func(change database.Change) {
+				engineInstance.ApplyChange(change)
+			})
+			if err != nil {
+				slog.Error("Change feed stopped", "error", err)
+			}
+		}()
+	} else {
+		close(changesDone)
+	}
+
 	// Set up HTTP server
 	serverAddr := fmt.Sprintf("%s:%d", config.Server.Host, config.Server.Port)
 	httpServer := &http.Server{
 		Addr:         serverAddr,
-		Handler:      mux,
+		Handler:      rootHandler,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Don't listen until the actors have loaded, or requests find empty caches
+	slog.Info("Waiting for actors to finish loading")
+	select {
+	case <-engineInstance.Ready():
+	case <-time.After(config.Server.StartupTimeout):
+		fatal("Actors not ready", "timeout", config.Server.StartupTimeout,
+			"pending", strings.Join(engineInstance.PendingActors(), ", "))
+	}
+
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Starting HTTP server on %s", serverAddr)
+		slog.Info("Starting HTTP server", "addr", serverAddr)
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+			fatal("Server failed to start", "error", err)
 		}
 	}()
 
@@ -190,7 +502,7 @@ func main() {
 
 	// Block until a signal is received.
 	sig := <-signalChan
-	log.Printf("Received signal: %s. Shutting down gracefully...", sig)
+	slog.Info("Shutting down gracefully", "signal", sig.String())
 
 	// Create a deadline to wait for.
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -199,12 +511,48 @@ func main() {
 	// Doesn't block if no connections, but will otherwise wait
 	// until the timeout deadline.
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
-		log.Printf("HTTP server shutdown failed: %v", err)
+		slog.Error("HTTP server shutdown failed", "error", err)
+	}
+
+	// WebSocket connections are hijacked, so Shutdown above doesn't touch them
+	if err := hub.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("WebSocket hub shutdown did not finish draining", "error", err)
+	}
+
+	// Stop applying outside changes before the actors they go to
+	stopChanges()
+	select {
+	case <-changesDone:
+	case <-shutdownCtx.Done():
 	}
 
 	// Stop the actor system
 	system.Shutdown()
-	log.Println("Actor system shut down.")
+	slog.Info("Actor system shut down")
+
+	// Let in-flight jobs finish; unstarted ones stay queued for the next start
+	stopJobs()
+	select {
+	case <-jobsDone:
+	case <-shutdownCtx.Done():
+		slog.Warn("Timed out waiting for background jobs to finish")
+	}
+	select {
+	case <-schedulerDone:
+	case <-shutdownCtx.Done():
+		slog.Warn("Timed out waiting for scheduled tasks to finish")
+	}
+
+	// Flush events published during shutdown
+	if err := eventBus.Close(shutdownCtx); err != nil {
+		slog.Error("Event bus shutdown failed", "error", err)
+	}
+
+	slog.Info("Server gracefully stopped")
+}
 
-	log.Println("Server gracefully stopped.")
+// fatal logs msg as an error and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
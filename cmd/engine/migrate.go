@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database"
+	"os"
+	"strconv"
+	"time"
+)
+
+const migrateUsage = `usage: engine migrate [up | down [steps] | status]
+
+  up      apply every pending migration (the default)
+  down    roll back the last steps applied migrations (default 1)
+  status  list migrations and when each was applied`
+
+// runMigrate applies, rolls back or lists schema migrations against the
+// configured database, and returns the exit code.
+func runMigrate(args []string) int {
+	command := "up"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	steps := 1
+	switch {
+	case command == "down" && len(args) == 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		steps = n
+	case command != "up" && command != "down" && command != "status", len(args) > 0:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	db, err := database.NewPostgresDB(database.WithApplicationName(cfg.Database.URI, "gator-migrate"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to the database: %v\n", err)
+		return 1
+	}
+	defer db.Close(context.Background())
+	ctx := context.Background()
+
+	switch command {
+	case "status":
+		statuses, err := db.MigrationStatuses(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, s := range statuses {
+			if s.AppliedAt != nil {
+				fmt.Printf("applied  %-30s %s\n", s.Migration, s.AppliedAt.Local().Format(time.RFC3339))
+			} else {
+				fmt.Printf("pending  %s\n", s.Migration)
+			}
+		}
+		return 0
+
+	case "down":
+		reverted, err := db.Rollback(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("rolled back  %s\n", m)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("nothing to roll back")
+		}
+		return 0
+
+	default:
+		applied, err := db.Migrate(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied  %s\n", m)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return 0
+	}
+}
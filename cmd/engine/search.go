@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/search"
+)
+
+const searchUsage = `usage: engine search reindex
+
+  reindex  index every post that isn't deleted in the configured external
+           search index, e.g. after configuring one for existing posts`
+
+// runSearch maintains the external search index, and returns the exit code.
+func runSearch(args []string) int {
+	if len(args) != 1 || args[0] != "reindex" {
+		fmt.Fprintln(os.Stderr, searchUsage)
+		return 2
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	db, err := database.NewPostgresDB(database.WithApplicationName(cfg.Database.URI, "gator-search"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to the database: %v\n", err)
+		return 1
+	}
+	defer db.Close(context.Background())
+
+	provider, err := search.NewProvider(cfg.Search.Provider, cfg.Search.URL, cfg.Search.Index, db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !provider.External() {
+		fmt.Println("posts are searched in Postgres; there is no index to rebuild")
+		return 0
+	}
+
+	indexed, err := search.Reindex(context.Background(), provider, db, func(sub *models.Subreddit, indexed int) {
+		fmt.Printf("indexed  %-30s %d posts so far\n", sub.Name, indexed)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("indexed %d posts\n", indexed)
+	return 0
+}
@@ -0,0 +1,98 @@
+// Command importer loads Reddit-format dumps, such as Pushshift's, into the
+// database to bootstrap a realistic dataset:
+//
+//	go run ./cmd/importer -dsn "postgres://localhost/gator?sslmode=disable" \
+//		-posts RS_2015-01.gz -comments RC_2015-01.gz -only golang,programming
+//
+// Dumps are newline-delimited JSON, optionally gzipped. Import posts before
+// their comments; importing a dump twice adds nothing the second time.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/importer"
+)
+
+func main() {
+	dsn := flag.String("dsn", os.Getenv("DATABASE_URL"), "Postgres connection string (defaults to DATABASE_URL)")
+	subredditsFile := flag.String("subreddits", "", "Dump of subreddits, for their descriptions and NSFW flags")
+	postsFile := flag.String("posts", "", "Dump of submissions")
+	commentsFile := flag.String("comments", "", "Dump of comments")
+	only := flag.String("only", "", "Comma-separated subreddits to import (default all)")
+	limit := flag.Int("limit", 0, "Records to read from each dump (0 reads them all)")
+	source := flag.String("source", "reddit", "Name of the dumps' source, recorded with each imported row")
+	prefix := flag.String("username-prefix", "reddit_", "Prefix for imported usernames, so they can't take local users' names")
+	batch := flag.Int("batch", 1000, "Rows per bulk insert")
+	flag.Parse()
+
+	if *dsn == "" {
+		log.Fatal("A Postgres connection string is required (-dsn or DATABASE_URL)")
+	}
+	if *subredditsFile == "" && *postsFile == "" && *commentsFile == "" {
+		log.Fatal("Nothing to import; give -subreddits, -posts or -comments")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := database.NewPostgresDB(database.WithApplicationName(*dsn, "gator-import"))
+	if err != nil {
+		log.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer db.Close(context.Background())
+	pending, err := db.PendingMigrations(ctx)
+	if err != nil {
+		log.Fatalf("Failed to read migration status: %v", err)
+	}
+	if len(pending) > 0 {
+		log.Fatalf("Database is %d migrations behind (next is %s); run \"engine migrate\" first", len(pending), pending[0])
+	}
+
+	opts := importer.Options{
+		Source:         *source,
+		UsernamePrefix: *prefix,
+		BatchSize:      *batch,
+		Limit:          *limit,
+	}
+	if *only != "" {
+		opts.Subreddits = make(map[string]bool)
+		for _, name := range strings.Split(*only, ",") {
+			opts.Subreddits[strings.ToLower(strings.TrimSpace(name))] = true
+		}
+	}
+	im := importer.New(db, opts)
+
+	start := time.Now()
+	steps := []struct {
+		file string
+		run  func(context.Context, string) error
+	}{
+		{*subredditsFile, im.ImportSubreddits},
+		{*postsFile, im.ImportPosts},
+		{*commentsFile, im.ImportComments},
+	}
+	for _, step := range steps {
+		if step.file == "" {
+			continue
+		}
+		if err := step.run(ctx, step.file); err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+	}
+	if err := im.Finish(ctx); err != nil {
+		log.Fatalf("Failed to update counters: %v", err)
+	}
+
+	s := im.Stats
+	log.Printf("Imported %d users, %d subreddits, %d posts and %d comments from %d records (%d skipped) in %v",
+		s.Users, s.Subreddits, s.Posts, s.Comments, s.Read, s.Skipped, time.Since(start).Round(time.Second))
+}
@@ -2,13 +2,69 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
+	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"gator-swamp/simulator" // This should match your module name
 )
 
 func main() {
+	seed := flag.Int64("seed", 0, "Seed for reproducible runs (0 picks a time-based seed)")
+	chaosDefaults := simulator.DefaultChaosConfig()
+	chaosEnabled := flag.Bool("chaos", false, "Enable fault injection")
+	chaosAbort := flag.Float64("chaos-abort", chaosDefaults.AbortRate, "Probability of aborting a request before it is sent")
+	chaosLatency := flag.Float64("chaos-latency", chaosDefaults.LatencyRate, "Probability of delaying a request")
+	chaosMaxLatency := flag.Duration("chaos-max-latency", chaosDefaults.MaxLatency, "Maximum injected latency")
+	chaosDrop := flag.Float64("chaos-drop", chaosDefaults.DropRate, "Probability of dropping a connection mid-request")
+	chaosRestart := flag.Duration("chaos-restart-tolerance", chaosDefaults.RestartTolerance, "How long to wait for the engine to recover from an outage")
+	dashboard := flag.Bool("dashboard", false, "Show a live terminal dashboard instead of periodic metric logs")
+	logFile := flag.String("log-file", "simulator.log", "Where to write logs while the dashboard is shown")
+	recordFile := flag.String("record", "", "Record all issued requests to this file")
+	replayFile := flag.String("replay", "", "Replay a recorded traffic file instead of simulating")
+	replaySpeed := flag.Float64("replay-speed", 1.0, "Time scale for replay (2.0 is twice as fast, 0 sends back to back)")
+	engineURL := flag.String("engine-url", "http://localhost:8080", "Engine base URL, or a comma-separated list to spread load across instances")
+	churn := flag.Float64("churn", 0, "Fraction of users retired and replaced per minute (e.g. 0.05)")
+	readFrequency := flag.Float64("read-frequency", 300.0, "Read actions per user per hour (0 disables reads)")
+	readMixDefaults := simulator.DefaultReadMix()
+	readFeed := flag.Float64("read-feed", readMixDefaults.Feed, "Relative weight of feed reads")
+	readRecent := flag.Float64("read-recent", readMixDefaults.Recent, "Relative weight of recent-posts reads")
+	readPost := flag.Float64("read-post", readMixDefaults.PostWithComments, "Relative weight of opening a post with its comments")
+	validate := flag.Bool("validate", false, "Verify backend consistency invariants after the run")
+	coordinatorAddr := flag.String("coordinator", "", "Run as coordinator listening on this address (e.g. :9090) instead of simulating")
+	coordinatorURL := flag.String("coordinator-url", "", "Coordinator to stream stats to when running as a worker")
+	workerIndex := flag.Int("worker-index", 0, "Index of this worker within the worker pool")
+	workerCount := flag.Int("worker-count", 1, "Total number of workers splitting the user population")
+	httpDefaults := simulator.DefaultHTTPConfig()
+	httpTimeout := flag.Duration("http-timeout", httpDefaults.Timeout, "Timeout for each request attempt")
+	httpRetries := flag.Int("http-retries", httpDefaults.MaxRetries, "Retries of a failed request, where retrying is safe (0 disables)")
+	httpBackoff := flag.Duration("http-retry-backoff", httpDefaults.RetryBackoff, "Wait before the first retry, doubled for each one after")
+	httpIdleConns := flag.Int("http-idle-conns", httpDefaults.MaxIdleConns, "Idle connections kept open per engine instance")
+	flag.Parse()
+
+	engineURLs := strings.Split(*engineURL, ",")
+	for i := range engineURLs {
+		engineURLs[i] = strings.TrimSpace(engineURLs[i])
+	}
+
+	if *coordinatorAddr != "" {
+		coordinator := simulator.NewCoordinator(*workerCount)
+		if err := coordinator.Run(context.Background(), *coordinatorAddr); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Coordinator failed: %v", err)
+		}
+		metrics := coordinator.Merged()
+		log.Printf("\nDistributed simulation completed. Merged metrics:")
+		log.Printf("- Total users: %d", metrics.TotalUsers)
+		log.Printf("- Total posts: %d", metrics.TotalPosts)
+		log.Printf("- Total requests: %d", metrics.TotalRequests)
+		log.Printf("- Error count: %d", metrics.ErrorCount)
+		log.Printf("- Average latency: %v", metrics.AverageLatency)
+		return
+	}
+
 	// Define simulation configuration
 	config := simulator.SimConfig{
 		NumUsers:         10,
@@ -20,9 +76,66 @@ func main() {
 		RepostPercentage: 0.1,
 		DisconnectRate:   0.01,
 		ReconnectRate:    0.05,
-		ZipfS:            1.07,
-		BatchSize:        50,
-		EngineURL:        "http://localhost:8080",
+		ReadFrequency:    *readFrequency,
+		ReadMix: simulator.ReadMix{
+			Feed:             *readFeed,
+			Recent:           *readRecent,
+			PostWithComments: *readPost,
+		},
+		VoteChangeRate: 0.3,
+		ChurnRate:      *churn,
+		ZipfS:          1.07,
+		BatchSize:      50,
+		EngineURL:      engineURLs[0],
+		EngineURLs:     engineURLs,
+		Seed:           *seed,
+		Dashboard:      *dashboard,
+		RecordFile:     *recordFile,
+		WorkerIndex:    *workerIndex,
+		WorkerCount:    *workerCount,
+		CoordinatorURL: *coordinatorURL,
+		HTTP: simulator.HTTPConfig{
+			Timeout:      *httpTimeout,
+			MaxRetries:   *httpRetries,
+			RetryBackoff: *httpBackoff,
+			MaxIdleConns: *httpIdleConns,
+		},
+		Chaos: simulator.ChaosConfig{
+			Enabled:          *chaosEnabled,
+			AbortRate:        *chaosAbort,
+			LatencyRate:      *chaosLatency,
+			MaxLatency:       *chaosMaxLatency,
+			DropRate:         *chaosDrop,
+			RestartTolerance: *chaosRestart,
+		},
+	}
+
+	if *replayFile != "" {
+		log.Printf("Replaying %s against %s at %.1fx speed", *replayFile, engineURLs[0], *replaySpeed)
+		result, err := simulator.Replay(context.Background(), *replayFile, engineURLs[0], *replaySpeed, config.HTTP)
+		if err != nil {
+			log.Fatalf("Replay failed: %v", err)
+		}
+		log.Printf("\nReplay completed in %v:", result.Duration)
+		log.Printf("- Requests: %d", result.Requests)
+		log.Printf("- Failures: %d", result.Failures)
+		log.Printf("- Average latency: %v", result.AverageLatency)
+		breakdown := make(map[string]simulator.EndpointStats, len(result.Endpoints))
+		for key, stats := range result.Endpoints {
+			breakdown[key] = *stats
+		}
+		log.Printf("Per-endpoint breakdown:\n%s", simulator.FormatEndpointBreakdown(breakdown))
+		return
+	}
+
+	// Keep log output from scrolling over the dashboard
+	if config.Dashboard {
+		f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatalf("Failed to open log file: %v", err)
+		}
+		defer f.Close()
+		log.SetOutput(f)
 	}
 
 	sim := simulator.NewEnhancedSimulator(config)
@@ -35,21 +148,37 @@ func main() {
 
 	// Log configuration
 	log.Printf("Starting simulation with configuration:")
-	log.Printf("- Engine URL: %s", config.EngineURL)
+	log.Printf("- Engine URLs: %s", strings.Join(config.EngineURLs, ", "))
 	log.Printf("- Number of users: %d", config.NumUsers)
 	log.Printf("- Number of subreddits: %d", config.NumSubreddits)
 	log.Printf("- Simulation time: %v", config.SimulationTime)
 	log.Printf("- Post frequency: %.2f posts/user/hour", config.PostFrequency)
 	log.Printf("- Comment frequency: %.2f comments/user/hour", config.CommentFrequency)
+	log.Printf("- Read frequency: %.2f reads/user/hour (feed %.2f, recent %.2f, post %.2f)",
+		config.ReadFrequency, config.ReadMix.Feed, config.ReadMix.Recent, config.ReadMix.PostWithComments)
 	log.Printf("- Repost percentage: %.1f%%", config.RepostPercentage*100)
 	log.Printf("- Disconnect rate: %.2f", config.DisconnectRate)
 	log.Printf("- Reconnect rate: %.2f", config.ReconnectRate)
+	log.Printf("- Churn rate: %.2f/min", config.ChurnRate)
 	log.Printf("- Zipf parameter: %.2f", config.ZipfS)
+	log.Printf("- HTTP: timeout=%v retries=%d backoff=%v idle conns=%d",
+		config.HTTP.Timeout, config.HTTP.MaxRetries, config.HTTP.RetryBackoff, config.HTTP.MaxIdleConns)
+	if config.CoordinatorURL != "" {
+		log.Printf("- Worker %d/%d reporting to %s", config.WorkerIndex, config.WorkerCount, config.CoordinatorURL)
+	}
+	if config.Chaos.Enabled {
+		log.Printf("- Chaos: abort=%.2f latency=%.2f (max %v) drop=%.2f restart tolerance=%v",
+			config.Chaos.AbortRate, config.Chaos.LatencyRate, config.Chaos.MaxLatency,
+			config.Chaos.DropRate, config.Chaos.RestartTolerance)
+	}
 
 	// Start simulation
 	if err := sim.Run(ctx); err != nil {
 		log.Fatalf("Simulation failed: %v", err)
 	}
+	if config.Dashboard {
+		log.SetOutput(os.Stderr)
+	}
 
 	// Print final metrics
 	metrics := sim.GetMetrics()
@@ -58,5 +187,29 @@ func main() {
 	log.Printf("- Active users at end: %d", metrics.ActiveUsers)
 	log.Printf("- Total posts: %d", metrics.TotalPosts)
 	log.Printf("- Reposts: %d", metrics.RepostCount)
+	log.Printf("- Reads: %d", metrics.TotalReads)
+	log.Printf("- Votes: %d cast, %d changed, %d removed", metrics.TotalVotes, metrics.VotesChanged, metrics.VotesRemoved)
+	log.Printf("- Users joined/retired by churn: %d/%d", metrics.UsersJoined, metrics.UsersRetired)
 	log.Printf("- Error count: %d", metrics.ErrorCount)
+	if config.Chaos.Enabled {
+		log.Printf("- Chaos: %s", simulator.FormatChaosReport(metrics))
+	}
+	log.Printf("Per-endpoint breakdown:\n%s", simulator.FormatEndpointBreakdown(sim.EndpointBreakdown()))
+	if len(config.EngineURLs) > 1 {
+		log.Printf("Per-instance breakdown:\n%s", simulator.FormatEndpointBreakdown(sim.TargetBreakdown()))
+	}
+
+	if *validate {
+		validateCtx, cancelValidate := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancelValidate()
+
+		report, err := sim.Validate(validateCtx)
+		if err != nil {
+			log.Fatalf("Validation failed: %v", err)
+		}
+		log.Printf("Consistency validation: %s", report)
+		if !report.OK() {
+			os.Exit(1)
+		}
+	}
 }
@@ -0,0 +1,177 @@
+// Package actorcall sends request messages to actors and waits for their
+// reply, with the timeout and retries configured for the message type. The
+// engine and the HTTP handlers share one set of policies, so a slow message
+// can be given more time in one place.
+package actorcall
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gator-swamp/internal/utils"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// Failures a policy can retry on
+const (
+	RetryOnTimeout     = "timeout"     // No reply before the timeout
+	RetryOnUnavailable = "unavailable" // The actor replied ErrActorTimeout or ErrUnavailable
+)
+
+// DefaultTimeout is how long a reply is waited for before configuration.
+const DefaultTimeout = 5 * time.Second
+
+// retryBackoff is waited before the first retry, and grows linearly after.
+const retryBackoff = 100 * time.Millisecond
+
+// Policy is how a request is sent. Retries re-send the same message, so
+// only enable them for messages that are safe to handle twice.
+type Policy struct {
+	Timeout time.Duration
+	Retries int      // Extra attempts after the first
+	RetryOn []string // RetryOn* failures that are retried
+}
+
+// Policies holds the default policy and overrides per message type, keyed
+// by type name such as "GetRecentPostsMsg".
+type Policies struct {
+	Default  Policy
+	Messages map[string]Policy
+}
+
+// Defaults are the policies before configuration: one attempt with
+// DefaultTimeout for every message.
+func Defaults() *Policies {
+	return &Policies{
+		Default:  Policy{Timeout: DefaultTimeout},
+		Messages: make(map[string]Policy),
+	}
+}
+
+// Requester sends a request to an actor. Both *actor.RootContext and
+// actor.Context satisfy it.
+type Requester interface {
+	RequestFuture(pid *actor.PID, message interface{}, timeout time.Duration) *actor.Future
+}
+
+// MessageName returns the name msg's policy is configured under.
+func MessageName(msg interface{}) string {
+	t := reflect.TypeOf(msg)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	return t.Name()
+}
+
+// For returns the policy for msg. A nil Policies gives the defaults.
+func (p *Policies) For(msg interface{}) Policy {
+	if p == nil {
+		return Policy{Timeout: DefaultTimeout}
+	}
+	if policy, ok := p.Messages[MessageName(msg)]; ok {
+		return policy
+	}
+	return p.Default
+}
+
+// Request sends msg to pid and waits for the reply, retrying as msg's
+// policy allows. Like a future's Result, an actor's *utils.AppError reply is
+// returned as the result, not the error.
+func (p *Policies) Request(ctx Requester, pid *actor.PID, msg interface{}) (interface{}, error) {
+	policy := p.For(msg)
+	for attempt := 0; ; attempt++ {
+		result, err := ctx.RequestFuture(pid, msg, policy.Timeout).Result()
+		failure := classify(result, err)
+		if failure == "" || attempt >= policy.Retries || !slices.Contains(policy.RetryOn, failure) {
+			return result, err
+		}
+		slog.Warn("Retrying actor request", "message", MessageName(msg), "failure", failure, "attempt", attempt+2, "attempts", policy.Retries+1)
+		time.Sleep(retryBackoff * time.Duration(attempt+1))
+	}
+}
+
+// Call is Request for callers that know the reply's type. Every failure is
+// returned as a *utils.AppError: one the actor replied with, ErrActorTimeout
+// if no reply came, or ErrMessageRejected for a reply of another type. A nil
+// reply gives T's zero value.
+func Call[T any](p *Policies, ctx Requester, pid *actor.PID, msg interface{}) (T, error) {
+	var zero T
+	result, err := p.Request(ctx, pid, msg)
+	if err != nil {
+		return zero, utils.NewAppError(utils.ErrActorTimeout, "No reply to "+MessageName(msg), err)
+	}
+	switch reply := result.(type) {
+	case nil:
+		return zero, nil
+	case *utils.AppError:
+		return zero, reply
+	case T:
+		return reply, nil
+	case error:
+		return zero, utils.NewAppError(utils.ErrMessageRejected, "Failed to process "+MessageName(msg), reply)
+	default:
+		return zero, utils.NewAppError(utils.ErrMessageRejected,
+			fmt.Sprintf("Unexpected reply %T to %s", reply, MessageName(msg)), nil)
+	}
+}
+
+// classify names the retryable failure of a reply, if it is one.
+func classify(result interface{}, err error) string {
+	if err != nil {
+		if errors.Is(err, actor.ErrTimeout) {
+			return RetryOnTimeout
+		}
+		return ""
+	}
+	if appErr, ok := result.(*utils.AppError); ok && (appErr.Code == utils.ErrActorTimeout || appErr.Code == utils.ErrUnavailable) {
+		return RetryOnUnavailable
+	}
+	return ""
+}
+
+// ParsePolicy applies settings such as "timeout=10s,retries=2,retryOn=timeout|unavailable"
+// to base. Settings that are left out keep base's value.
+func ParsePolicy(base Policy, spec string) (Policy, error) {
+	policy := base
+	for _, setting := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(setting), "=")
+		if !ok {
+			return base, fmt.Errorf("invalid setting %q (expected key=value)", setting)
+		}
+		switch key {
+		case "timeout":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return base, fmt.Errorf("invalid timeout %q", value)
+			}
+			policy.Timeout = d
+		case "retries":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return base, fmt.Errorf("invalid retries %q", value)
+			}
+			policy.Retries = n
+		case "retryOn":
+			policy.RetryOn = nil
+			for _, failure := range strings.Split(value, "|") {
+				if failure != RetryOnTimeout && failure != RetryOnUnavailable {
+					return base, fmt.Errorf("unknown retryOn %q (expected timeout or unavailable)", failure)
+				}
+				policy.RetryOn = append(policy.RetryOn, failure)
+			}
+		default:
+			return base, fmt.Errorf("unknown setting %q (expected timeout, retries or retryOn)", key)
+		}
+	}
+	return policy, nil
+}
@@ -0,0 +1,347 @@
+// Package actorclient wraps the actors' request messages in typed calls, so
+// a message can't be sent to the wrong actor or its reply read as the wrong
+// type. Each call returns the reply, or a *utils.AppError for any failure.
+package actorclient
+
+import (
+	"context"
+
+	"gator-swamp/internal/actorcall"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/logging"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/types"
+	"gator-swamp/internal/utils"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+// PIDs are the actors the clients send to. Messages the Engine validates
+// go through it; the rest go straight to the actor that handles them.
+type PIDs struct {
+	Engine         *actor.PID
+	Post           *actor.PID
+	Comment        *actor.PID
+	Subreddit      *actor.PID
+	UserSupervisor *actor.PID
+	DirectMessage  *actor.PID
+	Search         *actor.PID
+}
+
+// Clients has a client per actor.
+type Clients struct {
+	Posts      *PostClient
+	Comments   *CommentClient
+	Subreddits *SubredditClient
+	Users      *UserClient
+	Messages   *MessageClient
+	Search     *SearchClient
+
+	ctx      actorcall.Requester
+	policies *actorcall.Policies
+	pids     PIDs
+}
+
+// New builds the clients, sending with ctx under policies.
+func New(ctx actorcall.Requester, policies *actorcall.Policies, pids PIDs) *Clients {
+	c := caller{ctx: ctx, policies: policies}
+	return &Clients{
+		Posts:      &PostClient{c, pids.Engine, pids.Post},
+		Comments:   &CommentClient{c, pids.Engine, pids.Comment},
+		Subreddits: &SubredditClient{c, pids.Engine, pids.Subreddit},
+		Users:      &UserClient{c, pids.UserSupervisor},
+		Messages:   &MessageClient{c, pids.DirectMessage},
+		Search:     &SearchClient{c, pids.Search},
+		ctx:        ctx,
+		policies:   policies,
+		pids:       pids,
+	}
+}
+
+// For returns clients whose messages carry ctx's request ID, so the actors
+// handling them log it. Clients that don't send from a root context, or a
+// ctx without a request ID, give c itself.
+func (c *Clients) For(ctx context.Context) *Clients {
+	root, ok := c.ctx.(*actor.RootContext)
+	if !ok {
+		return c
+	}
+	scoped := logging.Root(root, ctx)
+	if scoped == root {
+		return c
+	}
+	return New(scoped, c.policies, c.pids)
+}
+
+type caller struct {
+	ctx      actorcall.Requester
+	policies *actorcall.Policies
+}
+
+func call[T any](c caller, pid *actor.PID, msg interface{}) (T, error) {
+	return actorcall.Call[T](c.policies, c.ctx, pid, msg)
+}
+
+// PostClient sends to the PostActor.
+type PostClient struct {
+	caller
+	engine, actor *actor.PID
+}
+
+// Count returns the number of posts.
+func (c *PostClient) Count() (int, error) {
+	return call[int](c.caller, c.actor, &actors.GetCountsMsg{})
+}
+
+// Create checks the author may post in the subreddit and creates the post.
+// It's Pending if held for approval.
+func (c *PostClient) Create(msg *actors.CreatePostMsg) (*models.Post, error) {
+	return call[*models.Post](c.caller, c.engine, msg)
+}
+
+func (c *PostClient) Get(msg *actors.GetPostMsg) (*models.Post, error) {
+	return call[*models.Post](c.caller, c.actor, msg)
+}
+
+func (c *PostClient) GetWithComments(msg *actors.GetPostWithCommentsMsg) (*models.PostWithComments, error) {
+	return call[*models.PostWithComments](c.caller, c.actor, msg)
+}
+
+func (c *PostClient) SubredditPosts(msg *actors.GetSubredditPostsMsg) ([]*models.Post, error) {
+	return call[[]*models.Post](c.caller, c.actor, msg)
+}
+
+func (c *PostClient) Recent(msg *actors.GetRecentPostsMsg) ([]*models.Post, error) {
+	return call[[]*models.Post](c.caller, c.actor, msg)
+}
+
+// Feed checks the user exists and returns their feed.
+func (c *PostClient) Feed(msg *actors.GetUserFeedMsg) ([]*models.Post, error) {
+	return call[[]*models.Post](c.caller, c.engine, msg)
+}
+
+func (c *PostClient) Edit(msg *actors.EditPostMsg) (*models.Post, error) {
+	return call[*models.Post](c.caller, c.engine, msg)
+}
+
+func (c *PostClient) UpdateMetadata(msg *actors.UpdatePostMetadataMsg) (*models.Post, error) {
+	return call[*models.Post](c.caller, c.actor, msg)
+}
+
+// Vote checks the voter exists and records the vote.
+func (c *PostClient) Vote(msg *actors.VotePostMsg) (*models.StatusResponse, error) {
+	return call[*models.StatusResponse](c.caller, c.engine, msg)
+}
+
+// Delete deletes a post as its author, or as an admin if Force is set.
+func (c *PostClient) Delete(msg *actors.DeletePostMsg) (*models.StatusResponse, error) {
+	return call[*models.StatusResponse](c.caller, c.actor, msg)
+}
+
+// Lock locks or unlocks a post as its author.
+func (c *PostClient) Lock(msg *actors.LockPostMsg) (*models.Post, error) {
+	return call[*models.Post](c.caller, c.actor, msg)
+}
+
+// Remove sends a Force delete through the Engine, which checks the
+// requester moderates the post's subreddit.
+func (c *PostClient) Remove(msg *actors.DeletePostMsg) (*models.StatusResponse, error) {
+	return call[*models.StatusResponse](c.caller, c.engine, msg)
+}
+
+// ModeratorLock sends a ByModerator lock through the Engine, which checks
+// the requester moderates the post's subreddit.
+func (c *PostClient) ModeratorLock(msg *actors.LockPostMsg) (*models.Post, error) {
+	return call[*models.Post](c.caller, c.engine, msg)
+}
+
+// Review approves or rejects a held post, replying with the approved post
+// or a StatusResponse.
+func (c *PostClient) Review(msg *actors.ReviewPostMsg) (interface{}, error) {
+	return call[interface{}](c.caller, c.actor, msg)
+}
+
+// CommentClient sends to the CommentActor.
+type CommentClient struct {
+	caller
+	engine, actor *actor.PID
+}
+
+// Create creates a comment. It's Pending if held for approval.
+func (c *CommentClient) Create(msg *actors.CreateCommentMsg) (*actors.CommentResponse, error) {
+	return call[*actors.CommentResponse](c.caller, c.actor, msg)
+}
+
+func (c *CommentClient) Get(msg *actors.GetCommentMsg) (*models.Comment, error) {
+	return call[*models.Comment](c.caller, c.actor, msg)
+}
+
+func (c *CommentClient) ForPost(msg *actors.GetCommentsForPostMsg) ([]*models.Comment, error) {
+	return call[[]*models.Comment](c.caller, c.actor, msg)
+}
+
+func (c *CommentClient) Edit(msg *actors.EditCommentMsg) (*models.Comment, error) {
+	return call[*models.Comment](c.caller, c.actor, msg)
+}
+
+func (c *CommentClient) Vote(msg *actors.VoteCommentMsg) (*models.StatusResponse, error) {
+	return call[*models.StatusResponse](c.caller, c.actor, msg)
+}
+
+// Delete deletes a comment as its author, or as an admin if Force is set.
+func (c *CommentClient) Delete(msg *actors.DeleteCommentMsg) (*models.StatusResponse, error) {
+	return call[*models.StatusResponse](c.caller, c.actor, msg)
+}
+
+// Remove sends a Force delete through the Engine, which checks the
+// requester moderates the comment's subreddit.
+func (c *CommentClient) Remove(msg *actors.DeleteCommentMsg) (*models.StatusResponse, error) {
+	return call[*models.StatusResponse](c.caller, c.engine, msg)
+}
+
+// Review approves or rejects a held comment, replying with the approved
+// comment or a StatusResponse.
+func (c *CommentClient) Review(msg *actors.ReviewCommentMsg) (interface{}, error) {
+	return call[interface{}](c.caller, c.actor, msg)
+}
+
+// SubredditClient sends to the SubredditActor.
+type SubredditClient struct {
+	caller
+	engine, actor *actor.PID
+}
+
+// Count returns the number of subreddits.
+func (c *SubredditClient) Count() (int, error) {
+	return call[int](c.caller, c.actor, &actors.GetCountsMsg{})
+}
+
+func (c *SubredditClient) List() ([]*models.Subreddit, error) {
+	return call[[]*models.Subreddit](c.caller, c.actor, &actors.ListSubredditsMsg{})
+}
+
+func (c *SubredditClient) GetByID(msg *actors.GetSubredditByIDMsg) (*actors.SubredditDetails, error) {
+	return call[*actors.SubredditDetails](c.caller, c.actor, msg)
+}
+
+func (c *SubredditClient) GetByName(msg *actors.GetSubredditByNameMsg) (*actors.SubredditDetails, error) {
+	return call[*actors.SubredditDetails](c.caller, c.actor, msg)
+}
+
+// Create checks the creator may create subreddits and creates it.
+func (c *SubredditClient) Create(msg *actors.CreateSubredditMsg) (*models.Subreddit, error) {
+	return call[*models.Subreddit](c.caller, c.engine, msg)
+}
+
+func (c *SubredditClient) Delete(msg *actors.DeleteSubredditMsg) (*models.StatusResponse, error) {
+	return call[*models.StatusResponse](c.caller, c.actor, msg)
+}
+
+func (c *SubredditClient) Members(msg *actors.GetSubredditMembersMsg) ([]uuid.UUID, error) {
+	return call[[]uuid.UUID](c.caller, c.actor, msg)
+}
+
+func (c *SubredditClient) Join(msg *actors.JoinSubredditMsg) (bool, error) {
+	return call[bool](c.caller, c.actor, msg)
+}
+
+func (c *SubredditClient) Leave(msg *actors.LeaveSubredditMsg) (bool, error) {
+	return call[bool](c.caller, c.actor, msg)
+}
+
+func (c *SubredditClient) SetRating(msg *actors.SetSubredditRatingMsg) (*models.Subreddit, error) {
+	return call[*models.Subreddit](c.caller, c.actor, msg)
+}
+
+func (c *SubredditClient) Moderators(msg *actors.GetModeratorsMsg) ([]*models.Moderator, error) {
+	return call[[]*models.Moderator](c.caller, c.actor, msg)
+}
+
+// AddModerator appoints a moderator through the Engine, which checks the
+// requester owns the subreddit, and returns the new moderators.
+func (c *SubredditClient) AddModerator(msg *actors.AddModeratorMsg) ([]*models.Moderator, error) {
+	return call[[]*models.Moderator](c.caller, c.engine, msg)
+}
+
+// RemoveModerator dismisses a moderator through the Engine, which checks
+// the requester owns the subreddit or is dismissing themselves.
+func (c *SubredditClient) RemoveModerator(msg *actors.RemoveModeratorMsg) ([]*models.Moderator, error) {
+	return call[[]*models.Moderator](c.caller, c.engine, msg)
+}
+
+// UserClient sends to the UserSupervisor.
+type UserClient struct {
+	caller
+	supervisor *actor.PID
+}
+
+func (c *UserClient) Register(msg *actors.RegisterUserMsg) (*actors.UserState, error) {
+	return call[*actors.UserState](c.caller, c.supervisor, msg)
+}
+
+// Login checks credentials. A failed login is a LoginResponse without
+// Success, not an error.
+func (c *UserClient) Login(msg *actors.LoginMsg) (*types.LoginResponse, error) {
+	return call[*types.LoginResponse](c.caller, c.supervisor, msg)
+}
+
+func (c *UserClient) Profile(msg *actors.GetUserProfileMsg) (*actors.UserState, error) {
+	state, err := call[*actors.UserState](c.caller, c.supervisor, msg)
+	if err == nil && state == nil {
+		return nil, utils.NewAppError(utils.ErrNotFound, "User not found", nil)
+	}
+	return state, err
+}
+
+// Forget stops a removed account's actor and drops its lookups.
+func (c *UserClient) Forget(msg *actors.ForgetUserMsg) (bool, error) {
+	return call[bool](c.caller, c.supervisor, msg)
+}
+
+// MessageClient sends to the DirectMessageActor.
+type MessageClient struct {
+	caller
+	actor *actor.PID
+}
+
+func (c *MessageClient) Send(msg *actors.SendDirectMessageMsg) (*models.DirectMessage, error) {
+	return call[*models.DirectMessage](c.caller, c.actor, msg)
+}
+
+func (c *MessageClient) ForUser(msg *actors.GetUserMessagesMsg) ([]*models.DirectMessage, error) {
+	return call[[]*models.DirectMessage](c.caller, c.actor, msg)
+}
+
+func (c *MessageClient) Conversation(msg *actors.GetConversationMsg) ([]*models.DirectMessage, error) {
+	return call[[]*models.DirectMessage](c.caller, c.actor, msg)
+}
+
+// Reply answers a message the user received and marks it read.
+func (c *MessageClient) Reply(msg *actors.ReplyToMessageMsg) (*models.DirectMessage, error) {
+	return call[*models.DirectMessage](c.caller, c.actor, msg)
+}
+
+// UnreadCount counts the messages a user received and hasn't read.
+func (c *MessageClient) UnreadCount(msg *actors.GetUnreadCountMsg) (int, error) {
+	return call[int](c.caller, c.actor, msg)
+}
+
+func (c *MessageClient) MarkRead(msg *actors.MarkMessageReadMsg) (bool, error) {
+	return call[bool](c.caller, c.actor, msg)
+}
+
+func (c *MessageClient) Delete(msg *actors.DeleteMessageMsg) (bool, error) {
+	return call[bool](c.caller, c.actor, msg)
+}
+
+// SearchClient sends to the SearchActor.
+type SearchClient struct {
+	caller
+	actor *actor.PID
+}
+
+// Search finds posts, comments or subreddits matching the query.
+func (c *SearchClient) Search(msg *actors.SearchMsg) (*actors.SearchResults, error) {
+	return call[*actors.SearchResults](c.caller, c.actor, msg)
+}
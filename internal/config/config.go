@@ -3,10 +3,16 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+
+	"gator-swamp/internal/actorcall"
+	"gator-swamp/internal/logging"
+	"gator-swamp/internal/policy"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +22,16 @@ type ServerConfig struct {
 	Port           int
 	Host           string
 	MetricsEnabled bool
+
+	// WebSocket connection limits; 0 disables the limit
+	WSMaxConnsPerUser int
+	WSMaxConns        int
+
+	// How long to wait for the actors to load before giving up on starting
+	StartupTimeout time.Duration
+
+	// Key access tokens are signed with; a built-in development key if empty
+	JWTSecret string
 }
 
 // DatabaseConfig holds database configuration settings
@@ -28,22 +44,158 @@ type DatabaseConfig struct {
 	Password string
 	Name     string
 	SSLMode  string
+
+	// Circuit breaker: after BreakerThreshold consecutive connection failures,
+	// DB calls fail fast for BreakerCooldown. A threshold of 0 disables it.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// Listen for rows changed by other instances or tools and refresh caches
+	ChangeFeed bool
+
+	// Apply pending migrations when the server starts. When off, the server
+	// refuses to start until "engine migrate" has brought the schema up to
+	// date.
+	AutoMigrate bool
+}
+
+// EventsConfig holds optional domain event streaming settings
+type EventsConfig struct {
+	Sink        string // "nats", "kafka" (via REST Proxy), or empty to disable
+	URL         string
+	TopicPrefix string // Events go to "<prefix>.<type>", e.g. gator.post.created
+	BufferSize  int
+}
+
+// ContentConfig holds settings for how content is shown
+type ContentConfig struct {
+	ProfanityWords []string      // Masked in responses; empty disables masking
+	ScoreFuzzAge   time.Duration // Posts younger than this show fuzzed vote counts; 0 disables
+}
+
+// CaptchaConfig selects how CAPTCHA tokens are verified. With no Provider
+// they aren't required.
+type CaptchaConfig struct {
+	Provider     string // "hcaptcha" or "recaptcha"
+	Secret       string
+	ContentKarma int // Posts and comments by accounts below this karma need a token; 0 disables
+}
+
+// SearchConfig selects where posts are searched
+type SearchConfig struct {
+	Provider string // "postgres" (default), "elasticsearch" or "opensearch"
+	URL      string // Cluster URL for Elasticsearch and OpenSearch
+	Index    string
+}
+
+// JobsConfig holds background job queue settings
+type JobsConfig struct {
+	Workers       int  // Concurrent jobs per engine instance
+	DigestEnabled bool // Send daily digest emails
+
+	SchedulerEnabled bool // Run periodic maintenance tasks
+	ArchiveAfterDays int  // Posts older than this are archived
+
+	PublicWebURL string // Public web frontend; set to generate a sitemap of its post pages
+}
+
+// RetentionConfig holds how long expired data is kept, in days. Zero keeps
+// it forever. With DryRun the purge tasks only count what they would delete.
+type RetentionConfig struct {
+	DryRun            bool
+	AuditDays         int // Admin audit log entries
+	JobsDays          int // Finished (done or failed) background jobs
+	PostViewsDays     int // Seen-post markers used to hide read posts in feeds
+	RefreshTokensDays int // Used, revoked and expired refresh tokens
+	DeletedDays       int // Soft-deleted posts and comments, counted from deletion
+}
+
+// MailConfig holds SMTP settings for outgoing email. With no Host, emails
+// are logged instead of sent.
+type MailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// StorageConfig holds settings for uploaded media storage
+type StorageConfig struct {
+	Backend string // "local" (default) or "s3"
+	Dir     string // Local directory uploads are written to
+	BaseURL string // Public URL prefix for stored files; a path ("/media") is served by the engine
+
+	// For the s3 backend. An empty BaseURL means the bucket's own URL.
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
+}
+
+// AccessLogConfig holds HTTP access log settings
+type AccessLogConfig struct {
+	Enabled        bool
+	SampleRate     float64 // Fraction of requests logged; server errors always are
+	ErrorBodyBytes int     // Bytes of error response bodies logged; 0 leaves them out
+}
+
+// LogConfig holds the settings of the server's log
+type LogConfig struct {
+	Format string // logging.FormatText or logging.FormatJSON
+	Level  slog.Level
+}
+
+// RateLimitConfig holds per-client request limits, counted per instance
+type RateLimitConfig struct {
+	Enabled        bool
+	ReadPerMinute  int // GET and HEAD requests; 0 is unlimited
+	WritePerMinute int // Other requests; 0 is unlimited
+	// Limits for bot accounts, in place of the ones above
+	BotReadPerMinute  int
+	BotWritePerMinute int
+}
+
+// CORSConfig holds the CORS settings besides the allowed origins
+type CORSConfig struct {
+	AllowedMethods []string
+	AllowedHeaders []string // The CAPTCHA header is always allowed as well
+	MaxAge         int      // Seconds browsers may cache a preflight response
 }
 
 // Config holds the complete application configuration
 type Config struct {
 	Server         *ServerConfig
 	Database       *DatabaseConfig
-	AllowedOrigins []string
+	Events         *EventsConfig
+	Search         *SearchConfig
+	Content        *ContentConfig
+	Captcha        *CaptchaConfig
+	Policies       policy.Policies     // Account requirements per operation
+	ActorCalls     *actorcall.Policies // Timeouts and retries of actor requests
+	Jobs           *JobsConfig
+	Mail           *MailConfig
+	Storage        *StorageConfig
+	Retention      *RetentionConfig
+	AllowedOrigins []string // Exact origins, "*", or wildcard subdomains such as "https://*.example.com"
+	CORS           *CORSConfig
+	AccessLog      *AccessLogConfig
+	Log            *LogConfig
+	RateLimit      *RateLimitConfig
 	Debug          bool
 }
 
 // DefaultConfig provides default server settings
 func DefaultConfig() *ServerConfig {
 	return &ServerConfig{
-		Port:           8080,
-		Host:           "0.0.0.0", // Change from "localhost" to "0.0.0.0"
-		MetricsEnabled: true,
+		Port:              8080,
+		Host:              "0.0.0.0", // Change from "localhost" to "0.0.0.0"
+		MetricsEnabled:    true,
+		WSMaxConnsPerUser: 5,
+		WSMaxConns:        10000,
+		StartupTimeout:    2 * time.Minute,
 	}
 }
 
@@ -53,6 +205,12 @@ func DefaultDatabaseConfig() *DatabaseConfig {
 		Type:    "postgres", // Default to PostgreSQL
 		Port:    5432,       // Default PostgreSQL port
 		SSLMode: "require",  // Default to requiring SSL for security
+
+		BreakerThreshold: 5,
+		BreakerCooldown:  10 * time.Second,
+
+		ChangeFeed:  true,
+		AutoMigrate: true,
 	}
 }
 
@@ -98,6 +256,26 @@ func LoadConfig() (*Config, error) {
 		serverConfig.MetricsEnabled = metricsEnabled == "true"
 	}
 
+	serverConfig.JWTSecret = os.Getenv("JWT_SECRET")
+
+	if v := os.Getenv("WS_MAX_CONNS_PER_USER"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			serverConfig.WSMaxConnsPerUser = n
+		}
+	}
+
+	if v := os.Getenv("WS_MAX_CONNS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			serverConfig.WSMaxConns = n
+		}
+	}
+
+	if v := os.Getenv("STARTUP_TIMEOUT_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			serverConfig.StartupTimeout = time.Duration(n) * time.Second
+		}
+	}
+
 	// Initialize database config
 	dbConfig := DefaultDatabaseConfig()
 
@@ -172,19 +350,280 @@ postgresCase:
 
 	// Initialize complete config
 	config := &Config{
-		Server:         serverConfig,
-		Database:       dbConfig,
+		Server:   serverConfig,
+		Database: dbConfig,
+		Events: &EventsConfig{
+			Sink:        os.Getenv("EVENT_SINK"),
+			URL:         os.Getenv("EVENT_SINK_URL"),
+			TopicPrefix: getEnvOrDefault("EVENT_TOPIC_PREFIX", "gator"),
+			BufferSize:  10000,
+		},
+		Search: &SearchConfig{
+			Provider: getEnvOrDefault("SEARCH_PROVIDER", "postgres"),
+			URL:      os.Getenv("SEARCH_URL"),
+			Index:    getEnvOrDefault("SEARCH_INDEX", "gator-posts"),
+		},
+		Content: &ContentConfig{},
+		Captcha: &CaptchaConfig{
+			Provider: os.Getenv("CAPTCHA_PROVIDER"),
+			Secret:   os.Getenv("CAPTCHA_SECRET"),
+		},
+		Policies:   policy.Defaults(),
+		ActorCalls: actorcall.Defaults(),
+		Jobs: &JobsConfig{
+			Workers:       4,
+			DigestEnabled: os.Getenv("DIGEST_ENABLED") == "true",
+
+			SchedulerEnabled: os.Getenv("SCHEDULER_ENABLED") != "false",
+			ArchiveAfterDays: 180,
+
+			PublicWebURL: os.Getenv("PUBLIC_WEB_URL"),
+		},
+		Mail: &MailConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     587,
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     getEnvOrDefault("SMTP_FROM", "no-reply@gatorswamp.local"),
+		},
+		Storage: &StorageConfig{
+			Backend: getEnvOrDefault("STORAGE_BACKEND", "local"),
+			Dir:     getEnvOrDefault("STORAGE_DIR", "data/media"),
+			BaseURL: os.Getenv("MEDIA_BASE_URL"),
+
+			S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+			S3Region:    getEnvOrDefault("S3_REGION", "us-east-1"),
+			S3Bucket:    os.Getenv("S3_BUCKET"),
+			S3AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+			S3SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			S3PathStyle: os.Getenv("S3_PATH_STYLE") == "true",
+		},
+		Retention: &RetentionConfig{
+			DryRun:            os.Getenv("RETENTION_DRY_RUN") == "true",
+			AuditDays:         365,
+			JobsDays:          7,
+			PostViewsDays:     90,
+			RefreshTokensDays: 7,
+			DeletedDays:       30,
+		},
 		AllowedOrigins: []string{"*"}, // Default to allow all origins
-		Debug:          false,
+		CORS: &CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Content-Type", "Authorization"},
+			MaxAge:         86400,
+		},
+		AccessLog: &AccessLogConfig{
+			Enabled:        os.Getenv("ACCESS_LOG") != "false",
+			SampleRate:     1,
+			ErrorBodyBytes: 512,
+		},
+		Log: &LogConfig{
+			Format: getEnvOrDefault("LOG_FORMAT", logging.FormatText),
+			Level:  slog.LevelInfo,
+		},
+		RateLimit: &RateLimitConfig{
+			Enabled:           os.Getenv("RATE_LIMIT") != "false",
+			ReadPerMinute:     1200,
+			WritePerMinute:    300,
+			BotReadPerMinute:  600,
+			BotWritePerMinute: 60,
+		},
+		Debug: false,
 	}
 
 	// Override remaining settings from environment if provided
 	if origins := os.Getenv("ALLOWED_ORIGINS"); origins != "" {
 		config.AllowedOrigins = strings.Split(origins, ",")
 	}
+	if config.Storage.BaseURL == "" && config.Storage.Backend != "s3" {
+		config.Storage.BaseURL = "/media"
+	}
+
+	if methods := os.Getenv("CORS_ALLOWED_METHODS"); methods != "" {
+		config.CORS.AllowedMethods = strings.Split(methods, ",")
+	}
+	if headers := os.Getenv("CORS_ALLOWED_HEADERS"); headers != "" {
+		config.CORS.AllowedHeaders = strings.Split(headers, ",")
+	}
+	if v := os.Getenv("CORS_MAX_AGE_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.CORS.MaxAge = n
+		}
+	}
+
+	if v := os.Getenv("ACCESS_LOG_SAMPLE_RATE"); v != "" {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil && rate >= 0 && rate <= 1 {
+			config.AccessLog.SampleRate = rate
+		}
+	}
+	if v := os.Getenv("ACCESS_LOG_ERROR_BODY_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.AccessLog.ErrorBodyBytes = n
+		}
+	}
+
+	if v := os.Getenv("RATE_LIMIT_READ_PER_MINUTE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.RateLimit.ReadPerMinute = n
+		}
+	}
+	if v := os.Getenv("RATE_LIMIT_WRITE_PER_MINUTE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.RateLimit.WritePerMinute = n
+		}
+	}
+	if v := os.Getenv("RATE_LIMIT_BOT_READ_PER_MINUTE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.RateLimit.BotReadPerMinute = n
+		}
+	}
+	if v := os.Getenv("RATE_LIMIT_BOT_WRITE_PER_MINUTE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.RateLimit.BotWritePerMinute = n
+		}
+	}
+
+	if words := os.Getenv("PROFANITY_WORDS"); words != "" {
+		config.Content.ProfanityWords = strings.Split(words, ",")
+	}
+	if path := os.Getenv("PROFANITY_WORDS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PROFANITY_WORDS_FILE: %v", err)
+		}
+		config.Content.ProfanityWords = append(config.Content.ProfanityWords, strings.Split(string(data), "\n")...)
+	}
+
+	if v := os.Getenv("SCORE_FUZZ_MINUTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.Content.ScoreFuzzAge = time.Duration(n) * time.Minute
+		}
+	}
+
+	if v := os.Getenv("CAPTCHA_CONTENT_KARMA"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.Captcha.ContentKarma = n
+		}
+	}
+
+	// e.g. POLICY_POST_CREATE_MIN_KARMA=10 for post.create
+	for _, op := range policy.Operations {
+		prefix := "POLICY_" + strings.ToUpper(strings.ReplaceAll(op, ".", "_")) + "_"
+		req := config.Policies[op]
+		if v := os.Getenv(prefix + "MIN_KARMA"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil {
+				req.MinKarma = n
+			}
+		}
+		if v := os.Getenv(prefix + "MIN_ACCOUNT_AGE_DAYS"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+				req.MinAccountAge = time.Duration(n) * 24 * time.Hour
+			}
+		}
+		if req != (policy.Requirement{}) {
+			config.Policies[op] = req
+		} else {
+			delete(config.Policies, op)
+		}
+	}
+
+	// e.g. ACTOR_POLICY=timeout=3s, ACTOR_POLICY_GetRecentPostsMsg=timeout=10s,retries=2,retryOn=timeout
+	if v := os.Getenv("ACTOR_POLICY"); v != "" {
+		p, err := actorcall.ParsePolicy(config.ActorCalls.Default, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ACTOR_POLICY: %v", err)
+		}
+		config.ActorCalls.Default = p
+	}
+	for _, kv := range os.Environ() {
+		key, v, _ := strings.Cut(kv, "=")
+		name, ok := strings.CutPrefix(key, "ACTOR_POLICY_")
+		if !ok || name == "" {
+			continue
+		}
+		p, err := actorcall.ParsePolicy(config.ActorCalls.Default, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", key, err)
+		}
+		config.ActorCalls.Messages[name] = p
+	}
+
+	if v := os.Getenv("JOB_WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			config.Jobs.Workers = n
+		}
+	}
+
+	if v := os.Getenv("ARCHIVE_AFTER_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			config.Jobs.ArchiveAfterDays = n
+		}
+	}
+
+	for env, days := range map[string]*int{
+		"RETENTION_AUDIT_DAYS":          &config.Retention.AuditDays,
+		"RETENTION_JOBS_DAYS":           &config.Retention.JobsDays,
+		"RETENTION_POST_VIEWS_DAYS":     &config.Retention.PostViewsDays,
+		"RETENTION_REFRESH_TOKENS_DAYS": &config.Retention.RefreshTokensDays,
+		"RETENTION_DELETED_DAYS":        &config.Retention.DeletedDays,
+	} {
+		if v := os.Getenv(env); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+				*days = n
+			}
+		}
+	}
+
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Mail.Port = n
+		}
+	}
+
+	if v := os.Getenv("DB_BREAKER_THRESHOLD"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.Database.BreakerThreshold = n
+		}
+	}
+
+	if v := os.Getenv("DB_BREAKER_COOLDOWN_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			config.Database.BreakerCooldown = time.Duration(n) * time.Second
+		}
+	}
+
+	if v := os.Getenv("DB_CHANGE_FEED"); v != "" {
+		config.Database.ChangeFeed = v != "false"
+	}
+
+	if v := os.Getenv("DB_AUTO_MIGRATE"); v != "" {
+		config.Database.AutoMigrate = v != "false"
+	}
+
+	if v := os.Getenv("EVENT_BUFFER_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			config.Events.BufferSize = n
+		}
+	}
+
+	if config.Events.Sink != "" && config.Events.URL == "" {
+		return nil, fmt.Errorf("EVENT_SINK_URL is required when EVENT_SINK is set")
+	}
 
 	if debug := os.Getenv("DEBUG"); debug == "true" {
 		config.Debug = true
+		config.Log.Level = slog.LevelDebug
+	}
+
+	if config.Log.Format != logging.FormatText && config.Log.Format != logging.FormatJSON {
+		return nil, fmt.Errorf("invalid LOG_FORMAT %q; use %s or %s", config.Log.Format, logging.FormatText, logging.FormatJSON)
+	}
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		level, err := logging.ParseLevel(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LOG_LEVEL: %v", err)
+		}
+		config.Log.Level = level
 	}
 
 	return config, nil
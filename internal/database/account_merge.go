@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// MergeUsers moves everything the duplicate account owns to the primary one
+// in a single transaction: posts, comments, held submissions, votes,
+// messages, created subreddits, memberships, moderator seats and seen posts.
+// Its karma is added to the primary's and the duplicate is left as a
+// tombstone that can't log in. A dry run does all of this and rolls back, so the report is exact.
+func (p *PostgresDB) MergeUsers(ctx context.Context, primaryID, duplicateID uuid.UUID, dryRun bool) (*models.AccountMerge, error) {
+	if primaryID == duplicateID {
+		return nil, utils.NewAppError(utils.ErrInvalidInput, "cannot merge an account into itself", nil)
+	}
+
+	tx, err := p.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to begin merge", err)
+	}
+	defer tx.Rollback()
+
+	var accounts []struct {
+		ID         uuid.UUID  `db:"id"`
+		MergedInto *uuid.UUID `db:"merged_into"`
+	}
+	err = tx.SelectContext(ctx, &accounts, `SELECT id, merged_into FROM users WHERE id IN ($1, $2) FOR UPDATE`, primaryID, duplicateID)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to lock accounts", err)
+	}
+	if len(accounts) != 2 {
+		return nil, utils.NewAppError(utils.ErrNotFound, "user not found", nil)
+	}
+	for _, account := range accounts {
+		if account.MergedInto != nil {
+			return nil, utils.NewAppError(utils.ErrInvalidInput, "account "+account.ID.String()+" was already merged", nil)
+		}
+	}
+
+	report := &models.AccountMerge{PrimaryID: primaryID, DuplicateID: duplicateID, DryRun: dryRun}
+	if report.DroppedVotes, err = dropSharedVotes(ctx, tx, primaryID, duplicateID); err != nil {
+		return nil, err
+	}
+
+	moves := []struct {
+		count *int64
+		query string
+	}{
+		{&report.Posts, `UPDATE posts SET author_id = $1 WHERE author_id = $2`},
+		{&report.Comments, `UPDATE comments SET author_id = $1 WHERE author_id = $2`},
+		{&report.PendingPosts, `UPDATE pending_posts SET author_id = $1 WHERE author_id = $2`},
+		{&report.PendingComments, `UPDATE pending_comments SET author_id = $1 WHERE author_id = $2`},
+		{&report.Votes, `UPDATE votes SET user_id = $1 WHERE user_id = $2`},
+		{&report.Messages, `UPDATE messages SET
+			sender_id = CASE WHEN sender_id = $2 THEN $1 ELSE sender_id END,
+			receiver_id = CASE WHEN receiver_id = $2 THEN $1 ELSE receiver_id END
+			WHERE $2 IN (sender_id, receiver_id)`},
+		{&report.Subreddits, `UPDATE subreddits SET created_by = $1 WHERE created_by = $2`},
+		// Shared memberships would be counted twice
+		{nil, `WITH shared AS (
+				DELETE FROM subreddit_members d USING subreddit_members m
+				WHERE d.user_id = $2 AND m.user_id = $1 AND m.subreddit_id = d.subreddit_id
+				RETURNING d.subreddit_id
+			)
+			UPDATE subreddits SET member_count = GREATEST(0, member_count - 1) WHERE id IN (SELECT subreddit_id FROM shared)`},
+		// Moved by delete and insert so the change feed sees both
+		{&report.Memberships, `WITH moved AS (DELETE FROM subreddit_members WHERE user_id = $2 RETURNING subreddit_id, joined_at)
+			INSERT INTO subreddit_members (subreddit_id, user_id, joined_at) SELECT subreddit_id, $1, joined_at FROM moved`},
+		{nil, `DELETE FROM subreddit_moderators d USING subreddit_moderators m
+			WHERE d.user_id = $2 AND m.user_id = $1 AND m.subreddit_id = d.subreddit_id`},
+		{nil, `UPDATE subreddit_moderators SET user_id = $1 WHERE user_id = $2`},
+		{nil, `UPDATE subreddit_moderators SET added_by = $1 WHERE added_by = $2`},
+		{nil, `DELETE FROM post_views d USING post_views v WHERE d.user_id = $2 AND v.user_id = $1 AND v.post_id = d.post_id`},
+		{nil, `UPDATE post_views SET user_id = $1 WHERE user_id = $2`},
+		// Replies and mentions between the two accounts would be the user's own
+		{nil, `DELETE FROM inbox_notifications WHERE (user_id, from_id) IN (($1, $2), ($2, $1))`},
+		{nil, `DELETE FROM inbox_notifications d USING inbox_notifications n
+			WHERE d.user_id = $2 AND n.user_id = $1 AND n.content_id = d.content_id`},
+		{nil, `UPDATE inbox_notifications SET user_id = $1 WHERE user_id = $2`},
+		{nil, `UPDATE inbox_notifications SET from_id = $1 WHERE from_id = $2`},
+		// The tombstone can't log in, so it mustn't refresh either
+		{nil, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $2 AND revoked_at IS NULL`},
+	}
+	for _, move := range moves {
+		result, err := tx.ExecContext(ctx, move.query, primaryID, duplicateID)
+		if err != nil {
+			return nil, utils.NewAppError(utils.ErrDatabase, "failed to move account data", err)
+		}
+		if move.count != nil {
+			*move.count, _ = result.RowsAffected()
+		}
+	}
+
+	// Read after dropping votes, which may have changed it
+	if err := tx.GetContext(ctx, &report.Karma, `SELECT COALESCE(karma, 0) FROM users WHERE id = $1`, duplicateID); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to read duplicate karma", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users p SET karma = p.karma + d.karma, post_karma = p.post_karma + d.post_karma,
+			comment_karma = p.comment_karma + d.comment_karma, updated_at = NOW()
+		FROM users d WHERE p.id = $1 AND d.id = $2`, primaryID, duplicateID)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to combine karma", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users SET merged_into = $1, karma = 0, post_karma = 0, comment_karma = 0, password_hash = '', is_connected = FALSE, updated_at = NOW()
+		WHERE id = $2`, primaryID, duplicateID)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to tombstone duplicate account", err)
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to commit merge", err)
+	}
+	return report, nil
+}
+
+// dropSharedVotes deletes the duplicate's votes on content the primary also
+// voted on, undoing each one's effect on the content and its author as
+// RecordVote does when a vote is removed.
+func dropSharedVotes(ctx context.Context, tx *sqlx.Tx, primaryID, duplicateID uuid.UUID) (int64, error) {
+	var dropped []struct {
+		ContentID   uuid.UUID              `db:"content_id"`
+		ContentType models.VoteContentType `db:"content_type"`
+		VoteType    models.VoteDirection   `db:"vote_type"`
+	}
+	err := tx.SelectContext(ctx, &dropped, `
+		DELETE FROM votes d USING votes v
+		WHERE d.user_id = $2 AND v.user_id = $1 AND v.content_id = d.content_id AND v.content_type = d.content_type
+		RETURNING d.content_id, d.content_type, d.vote_type`, primaryID, duplicateID)
+	if err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to drop shared votes", err)
+	}
+
+	for _, vote := range dropped {
+		karmaDelta, upvoteDelta, downvoteDelta := -1, -1, 0
+		if vote.VoteType == models.VoteDown {
+			karmaDelta, upvoteDelta, downvoteDelta = 1, 0, -1
+		}
+		table, column := "posts", karmaColumn(vote.ContentType)
+		if vote.ContentType == models.CommentVote {
+			table = "comments"
+		}
+		_, err := tx.ExecContext(ctx, `
+			WITH content AS (
+				UPDATE `+table+` SET karma = karma + $1, upvotes = upvotes + $2, downvotes = downvotes + $3, updated_at = NOW()
+				WHERE id = $4 RETURNING author_id
+			)
+			UPDATE users SET karma = karma + $1, `+column+` = `+column+` + $1, updated_at = NOW() WHERE id = (SELECT author_id FROM content)`,
+			karmaDelta, upvoteDelta, downvoteDelta, vote.ContentID)
+		if err != nil {
+			return 0, utils.NewAppError(utils.ErrDatabase, "failed to undo shared vote", err)
+		}
+	}
+	return int64(len(dropped)), nil
+}
@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// The benchmarks below time the hot queries against the Postgres at
+// DATABASE_URL, at several table sizes. They're skipped without it:
+//
+//	DATABASE_URL="postgres://localhost/gator?sslmode=disable" go test ./internal/database -run '^$' -bench . -cpuprofile cpu.out -memprofile mem.out
+//
+// Each size is seeded once per run into its own schema, dropped and
+// recreated first, so the application's tables are never touched. Pick a
+// size with e.g. -bench 'GetUserFeed/posts=10000'.
+
+var benchSizes = []int{1000, 10000, 100000}
+
+// benchFixture is a seeded schema and the IDs of rows the benchmarks query
+// against.
+type benchFixture struct {
+	db      *PostgresDB
+	userIDs []uuid.UUID
+	postIDs []uuid.UUID
+	hotPost uuid.UUID // Post with many comments
+}
+
+var benchFixtures = map[int]*benchFixture{}
+
+func BenchmarkGetRecentPosts(b *testing.B) {
+	runSizes(b, func(ctx context.Context, fx *benchFixture, i int) error {
+		_, err := fx.db.GetRecentPosts(ctx, 20, 0, nil, fx.userIDs[i%len(fx.userIDs)], models.SortNew)
+		return err
+	})
+}
+
+func BenchmarkGetUserFeed(b *testing.B) {
+	runSizes(b, func(ctx context.Context, fx *benchFixture, i int) error {
+		userID := fx.userIDs[i%len(fx.userIDs)]
+		_, err := fx.db.GetUserFeed(ctx, userID, 20, 0, nil, userID, false, models.SortNew)
+		return err
+	})
+}
+
+func BenchmarkGetPostComments(b *testing.B) {
+	runSizes(b, func(ctx context.Context, fx *benchFixture, i int) error {
+		_, err := fx.db.GetPostComments(ctx, fx.hotPost, fx.userIDs[i%len(fx.userIDs)])
+		return err
+	})
+}
+
+func BenchmarkRecordVote(b *testing.B) {
+	runSizes(b, func(ctx context.Context, fx *benchFixture, i int) error {
+		direction := models.VoteUp
+		if i%2 == 1 {
+			direction = models.VoteDown
+		}
+		return fx.db.RecordVote(ctx, fx.userIDs[i%len(fx.userIDs)], fx.postIDs[i%len(fx.postIDs)], models.PostVote, direction)
+	})
+}
+
+// runSizes runs fn b.N times as a sub-benchmark per size in benchSizes.
+func runSizes(b *testing.B, fn func(ctx context.Context, fx *benchFixture, i int) error) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		b.Skip("DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	for _, posts := range benchSizes {
+		b.Run(fmt.Sprintf("posts=%d", posts), func(b *testing.B) {
+			fx, err := benchFixtureFor(ctx, dsn, posts)
+			if err != nil {
+				b.Fatalf("failed to seed %d posts: %v", posts, err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := fn(ctx, fx, i); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// benchFixtureFor returns the fixture for a size, seeding it on first use.
+func benchFixtureFor(ctx context.Context, dsn string, posts int) (*benchFixture, error) {
+	if fx, ok := benchFixtures[posts]; ok {
+		return fx, nil
+	}
+	schema := fmt.Sprintf("dbbench_%d", posts)
+
+	admin, err := NewPostgresDB(dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close(ctx)
+	if _, err := admin.DB.ExecContext(ctx, fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE; CREATE SCHEMA %s", schema, schema)); err != nil {
+		return nil, fmt.Errorf("failed to reset schema: %v", err)
+	}
+
+	db, err := NewPostgresDB(withSearchPath(dsn, schema))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Migrate(ctx); err != nil {
+		db.Close(ctx)
+		return nil, err
+	}
+	fx, err := seedBench(ctx, db, posts)
+	if err != nil {
+		db.Close(ctx)
+		return nil, err
+	}
+	benchFixtures[posts] = fx
+	return fx, nil
+}
+
+// withSearchPath points every pooled connection at schema.
+func withSearchPath(dsn, schema string) string {
+	if strings.Contains(dsn, "://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		return dsn + sep + "search_path=" + schema
+	}
+	return dsn + " search_path=" + schema
+}
+
+// seedBench bulk-loads users, subreddits, memberships, posts and comments in
+// SQL.
+func seedBench(ctx context.Context, db *PostgresDB, posts int) (*benchFixture, error) {
+	users := max(posts/10, 100)
+	const subreddits = 50
+	const hotPostComments = 200
+
+	statements := []string{
+		fmt.Sprintf(`INSERT INTO users (id, username, email, password_hash, karma)
+			SELECT gen_random_uuid(), 'bench_user_' || g, 'bench_' || g || '@test.com', 'x', 300
+			FROM generate_series(1, %d) g`, users),
+		fmt.Sprintf(`INSERT INTO subreddits (id, name, description, created_by)
+			SELECT gen_random_uuid(), 'bench_sub_' || g, 'benchmark', (SELECT id FROM users LIMIT 1)
+			FROM generate_series(1, %d) g`, subreddits),
+		`INSERT INTO subreddit_members (subreddit_id, user_id)
+			SELECT s.id, u.id FROM users u CROSS JOIN LATERAL
+				(SELECT id FROM subreddits ORDER BY random() + (u.id IS NULL)::int LIMIT 5) s
+			ON CONFLICT DO NOTHING`,
+		`UPDATE subreddits s SET member_count = (SELECT COUNT(*) FROM subreddit_members m WHERE m.subreddit_id = s.id)`,
+		fmt.Sprintf(`INSERT INTO posts (id, title, content, author_id, subreddit_id, karma, created_at)
+			SELECT gen_random_uuid(), 'Post ' || g, 'content',
+				(SELECT id FROM users OFFSET (g %% %d) LIMIT 1),
+				(SELECT id FROM subreddits OFFSET (g %% %d) LIMIT 1),
+				1, NOW() - (g || ' seconds')::interval
+			FROM generate_series(1, %d) g`, users, subreddits, posts),
+		fmt.Sprintf(`INSERT INTO comments (id, content, author_id, post_id, karma)
+			SELECT gen_random_uuid(), 'comment', u.id, p.id, 1
+			FROM (SELECT id FROM posts ORDER BY random() LIMIT %d) p
+			CROSS JOIN LATERAL (SELECT id FROM users ORDER BY random() + (p.id IS NULL)::int LIMIT 2) u`, posts),
+		`ANALYZE`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.DB.ExecContext(ctx, stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	fx := &benchFixture{db: db}
+	if err := db.DB.SelectContext(ctx, &fx.userIDs, `SELECT id FROM users`); err != nil {
+		return nil, err
+	}
+	if err := db.DB.SelectContext(ctx, &fx.postIDs, `SELECT id FROM posts`); err != nil {
+		return nil, err
+	}
+	fx.hotPost = fx.postIDs[0]
+
+	_, err := db.DB.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO comments (id, content, author_id, post_id, karma, created_at)
+		SELECT gen_random_uuid(), 'hot comment ' || g, (SELECT id FROM users OFFSET (g %% %d) LIMIT 1), $1, 1,
+			NOW() - (g || ' seconds')::interval
+		FROM generate_series(1, %d) g`, users, hotPostComments), fx.hotPost)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.DB.ExecContext(ctx, `UPDATE posts p SET comment_count = (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id)`)
+	return fx, err
+}
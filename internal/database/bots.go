@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// SaveBotAccount marks bot.UserID as a bot and sets its scopes, creating or
+// replacing its bot account. CreatedBy is kept from the first save.
+func (p *PostgresDB) SaveBotAccount(ctx context.Context, bot *models.BotAccount) error {
+	query := `
+		WITH u AS (
+			UPDATE users SET is_bot = TRUE WHERE id = $1 AND merged_into IS NULL
+			RETURNING id
+		)
+		INSERT INTO bot_accounts (user_id, scopes, subreddit_ids, created_by)
+		SELECT id, $2, $3::uuid[], $4 FROM u
+		ON CONFLICT (user_id) DO UPDATE SET
+			scopes = EXCLUDED.scopes,
+			subreddit_ids = EXCLUDED.subreddit_ids,
+			updated_at = NOW()
+		RETURNING created_by, created_at, updated_at
+	`
+	err := p.DB.QueryRowxContext(ctx, query, bot.UserID, pq.Array(bot.Scopes), pq.Array(uuidStrings(bot.SubredditIDs)), bot.CreatedBy).
+		Scan(&bot.CreatedBy, &bot.CreatedAt, &bot.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("user %s not found", bot.UserID), err)
+	}
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to save bot account", err)
+	}
+	return nil
+}
+
+// GetBotAccount returns the bot account of userID, or a NotFound error if
+// the user isn't a bot.
+func (p *PostgresDB) GetBotAccount(ctx context.Context, userID uuid.UUID) (*models.BotAccount, error) {
+	bots, err := p.queryBotAccounts(ctx, `WHERE b.user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bots) == 0 {
+		return nil, utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("bot account %s not found", userID), nil)
+	}
+	return bots[0], nil
+}
+
+// ListBotAccounts lists every bot account, newest first.
+func (p *PostgresDB) ListBotAccounts(ctx context.Context) ([]*models.BotAccount, error) {
+	return p.queryBotAccounts(ctx, `WHERE u.merged_into IS NULL ORDER BY b.created_at DESC`)
+}
+
+func (p *PostgresDB) queryBotAccounts(ctx context.Context, where string, args ...interface{}) ([]*models.BotAccount, error) {
+	rows, err := p.DB.QueryContext(ctx, `
+		SELECT b.user_id, u.username, b.scopes, b.subreddit_ids::text[], b.created_by, b.created_at, b.updated_at
+		FROM bot_accounts b
+		JOIN users u ON u.id = b.user_id
+		`+where, args...)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query bot accounts", err)
+	}
+	defer rows.Close()
+
+	bots := []*models.BotAccount{}
+	for rows.Next() {
+		var bot models.BotAccount
+		var subredditIDs []string
+		if err := rows.Scan(&bot.UserID, &bot.Username, pq.Array(&bot.Scopes), pq.Array(&subredditIDs),
+			&bot.CreatedBy, &bot.CreatedAt, &bot.UpdatedAt); err != nil {
+			return nil, utils.NewAppError(utils.ErrDatabase, "failed to read bot account", err)
+		}
+		bot.SubredditIDs = make([]uuid.UUID, 0, len(subredditIDs))
+		for _, id := range subredditIDs {
+			if parsed, err := uuid.Parse(id); err == nil {
+				bot.SubredditIDs = append(bot.SubredditIDs, parsed)
+			}
+		}
+		bots = append(bots, &bot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query bot accounts", err)
+	}
+	return bots, nil
+}
+
+// uuidStrings formats ids for a uuid[] parameter.
+func uuidStrings(ids []uuid.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
@@ -0,0 +1,632 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrCircuitOpen is the origin of the ErrUnavailable errors returned while
+// the circuit breaker is open.
+var ErrCircuitOpen = errors.New("database circuit breaker is open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// Breaker is a circuit breaker for database calls. After threshold
+// consecutive connection failures it opens and rejects calls immediately
+// for cooldown, then lets a single trial call through: success closes it,
+// failure opens it again. Query errors (not found, constraint violations,
+// bad input) don't count, only signs that Postgres is unreachable.
+type Breaker struct {
+	Logger *slog.Logger // Defaults to slog's default logger
+
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool // A half-open trial call is in flight
+}
+
+// NewBreaker creates a breaker that opens after threshold consecutive
+// failures and retries after cooldown.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 10 * time.Second
+	}
+	return &Breaker{Logger: slog.Default(), threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether a call may proceed, and whether it is the trial
+// call of a half-open breaker. An open breaker goes half-open once its
+// cooldown has passed.
+func (b *Breaker) allow() (ok, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false, false
+		}
+		b.setState(breakerHalfOpen)
+		fallthrough
+	case breakerHalfOpen:
+		if b.probing {
+			return false, false
+		}
+		b.probing = true
+		return true, true
+	}
+	return true, false
+}
+
+// record updates the breaker with the outcome of an allowed call.
+func (b *Breaker) record(err error, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+	if !isConnectionError(err) {
+		b.failures = 0
+		if probe {
+			b.Logger.Info("Database circuit breaker closed: trial call succeeded")
+			b.setState(breakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state != breakerOpen && (probe || b.failures >= b.threshold) {
+		b.Logger.Warn("Database circuit breaker opened", "failures", b.failures, "error", err)
+		b.openedAt = time.Now()
+		b.setState(breakerOpen)
+	}
+}
+
+func (b *Breaker) setState(s breakerState) {
+	b.state = s
+	breakerStateGauge.Set(float64(s))
+}
+
+// do runs fn through the breaker.
+func (b *Breaker) do(fn func() error) error {
+	ok, probe := b.allow()
+	if !ok {
+		breakerRejected.Inc()
+		return utils.NewAppError(utils.ErrUnavailable, "database unavailable", ErrCircuitOpen)
+	}
+	err := fn()
+	b.record(err, probe)
+	return err
+}
+
+// guard is do for calls that also return a value.
+func guard[T any](b *Breaker, fn func() (T, error)) (T, error) {
+	var result T
+	err := b.do(func() error {
+		var err error
+		result, err = fn()
+		return err
+	})
+	return result, err
+}
+
+// isConnectionError reports whether err means the database couldn't be
+// reached, as opposed to a query failing. A caller cancelling its own
+// context is neither.
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "57P01", "57P02", "57P03", "53300": // Shutting down, starting up, too many connections
+			return true
+		}
+		return pqErr.Code.Class() == "08" // Connection exception
+	}
+	return false
+}
+
+// breakerDB wraps a Store so every call goes through a Breaker.
+type breakerDB struct {
+	db Store
+	b  *Breaker
+}
+
+// WithBreaker wraps db so calls fail fast with ErrUnavailable while the
+// database is unreachable, instead of each one waiting out its timeout.
+func WithBreaker(db Store, b *Breaker) Store {
+	breakerStateGauge.Set(float64(breakerClosed))
+	return &breakerDB{db: db, b: b}
+}
+
+func (d *breakerDB) Close(ctx context.Context) error {
+	return d.db.Close(ctx)
+}
+
+func (d *breakerDB) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return guard(d.b, func() (*models.User, error) { return d.db.GetUserByEmail(ctx, email) })
+}
+
+func (d *breakerDB) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return guard(d.b, func() (*models.User, error) { return d.db.GetUser(ctx, id) })
+}
+
+func (d *breakerDB) GetUserEmails(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	return guard(d.b, func() (map[uuid.UUID]string, error) { return d.db.GetUserEmails(ctx, ids) })
+}
+
+func (d *breakerDB) SaveUser(ctx context.Context, user *models.User) error {
+	return d.b.do(func() error { return d.db.SaveUser(ctx, user) })
+}
+
+func (d *breakerDB) UpdateUserActivity(ctx context.Context, id uuid.UUID, active bool) error {
+	return d.b.do(func() error { return d.db.UpdateUserActivity(ctx, id, active) })
+}
+
+func (d *breakerDB) UpdateUserSubreddits(ctx context.Context, userID uuid.UUID, subID uuid.UUID, join bool) error {
+	return d.b.do(func() error { return d.db.UpdateUserSubreddits(ctx, userID, subID, join) })
+}
+
+func (d *breakerDB) GetAllUsers(ctx context.Context) ([]*models.User, error) {
+	return guard(d.b, func() ([]*models.User, error) { return d.db.GetAllUsers(ctx) })
+}
+
+func (d *breakerDB) ListUsers(ctx context.Context, filter models.UserFilter, limit int, after *models.Keyset) ([]*models.User, error) {
+	return guard(d.b, func() ([]*models.User, error) { return d.db.ListUsers(ctx, filter, limit, after) })
+}
+
+func (d *breakerDB) GetKarmaLeaderboard(ctx context.Context, by models.Leaderboard, limit, offset int) ([]*models.User, error) {
+	return guard(d.b, func() ([]*models.User, error) { return d.db.GetKarmaLeaderboard(ctx, by, limit, offset) })
+}
+
+func (d *breakerDB) UpdateUserProfileImage(ctx context.Context, id uuid.UUID, key string) error {
+	return d.b.do(func() error { return d.db.UpdateUserProfileImage(ctx, id, key) })
+}
+
+func (d *breakerDB) GetUserPreferences(ctx context.Context, userID uuid.UUID) (*models.UserPreferences, error) {
+	return guard(d.b, func() (*models.UserPreferences, error) { return d.db.GetUserPreferences(ctx, userID) })
+}
+
+func (d *breakerDB) SaveUserPreferences(ctx context.Context, prefs *models.UserPreferences) error {
+	return d.b.do(func() error { return d.db.SaveUserPreferences(ctx, prefs) })
+}
+
+func (d *breakerDB) MergeUsers(ctx context.Context, primaryID, duplicateID uuid.UUID, dryRun bool) (*models.AccountMerge, error) {
+	return guard(d.b, func() (*models.AccountMerge, error) { return d.db.MergeUsers(ctx, primaryID, duplicateID, dryRun) })
+}
+
+func (d *breakerDB) CreateRefreshToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	return d.b.do(func() error { return d.db.CreateRefreshToken(ctx, userID, tokenHash, expiresAt) })
+}
+
+func (d *breakerDB) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (uuid.UUID, error) {
+	return guard(d.b, func() (uuid.UUID, error) { return d.db.RotateRefreshToken(ctx, oldHash, newHash, expiresAt) })
+}
+
+func (d *breakerDB) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	return d.b.do(func() error { return d.db.RevokeRefreshToken(ctx, tokenHash) })
+}
+
+func (d *breakerDB) SaveBotAccount(ctx context.Context, bot *models.BotAccount) error {
+	return d.b.do(func() error { return d.db.SaveBotAccount(ctx, bot) })
+}
+
+func (d *breakerDB) GetBotAccount(ctx context.Context, userID uuid.UUID) (*models.BotAccount, error) {
+	return guard(d.b, func() (*models.BotAccount, error) { return d.db.GetBotAccount(ctx, userID) })
+}
+
+func (d *breakerDB) ListBotAccounts(ctx context.Context) ([]*models.BotAccount, error) {
+	return guard(d.b, func() ([]*models.BotAccount, error) { return d.db.ListBotAccounts(ctx) })
+}
+
+func (d *breakerDB) CreateSubreddit(ctx context.Context, sub *models.Subreddit) error {
+	return d.b.do(func() error { return d.db.CreateSubreddit(ctx, sub) })
+}
+
+func (d *breakerDB) GetSubredditByID(ctx context.Context, id uuid.UUID) (*models.Subreddit, error) {
+	return guard(d.b, func() (*models.Subreddit, error) { return d.db.GetSubredditByID(ctx, id) })
+}
+
+func (d *breakerDB) GetSubredditByName(ctx context.Context, name string) (*models.Subreddit, error) {
+	return guard(d.b, func() (*models.Subreddit, error) { return d.db.GetSubredditByName(ctx, name) })
+}
+
+func (d *breakerDB) GetAllSubreddits(ctx context.Context) ([]*models.Subreddit, error) {
+	return guard(d.b, func() ([]*models.Subreddit, error) { return d.db.GetAllSubreddits(ctx) })
+}
+
+func (d *breakerDB) GetTrendingSubreddits(ctx context.Context, requestingUserID uuid.UUID) ([]*models.Subreddit, error) {
+	return guard(d.b, func() ([]*models.Subreddit, error) { return d.db.GetTrendingSubreddits(ctx, requestingUserID) })
+}
+
+func (d *breakerDB) UpdateSubredditMemberCount(ctx context.Context, subID uuid.UUID, delta int) error {
+	return d.b.do(func() error { return d.db.UpdateSubredditMemberCount(ctx, subID, delta) })
+}
+
+func (d *breakerDB) JoinSubreddit(ctx context.Context, subID, userID uuid.UUID) (bool, error) {
+	return guard(d.b, func() (bool, error) { return d.db.JoinSubreddit(ctx, subID, userID) })
+}
+
+func (d *breakerDB) LeaveSubreddit(ctx context.Context, subID, userID uuid.UUID) (bool, error) {
+	return guard(d.b, func() (bool, error) { return d.db.LeaveSubreddit(ctx, subID, userID) })
+}
+
+func (d *breakerDB) GetSubredditSettings(ctx context.Context, subredditID uuid.UUID) (*models.SubredditSettings, error) {
+	return guard(d.b, func() (*models.SubredditSettings, error) { return d.db.GetSubredditSettings(ctx, subredditID) })
+}
+
+func (d *breakerDB) SaveSubredditSettings(ctx context.Context, settings *models.SubredditSettings) error {
+	return d.b.do(func() error { return d.db.SaveSubredditSettings(ctx, settings) })
+}
+
+func (d *breakerDB) SetSubredditRating(ctx context.Context, subredditID uuid.UUID, nsfw, quarantined *bool) error {
+	return d.b.do(func() error { return d.db.SetSubredditRating(ctx, subredditID, nsfw, quarantined) })
+}
+
+func (d *breakerDB) GetSubredditMemberIDs(ctx context.Context, subredditID uuid.UUID) ([]uuid.UUID, error) {
+	return guard(d.b, func() ([]uuid.UUID, error) { return d.db.GetSubredditMemberIDs(ctx, subredditID) })
+}
+
+func (d *breakerDB) CountOnlineMembers(ctx context.Context, subredditID uuid.UUID, activeWithin time.Duration) (int, error) {
+	return guard(d.b, func() (int, error) { return d.db.CountOnlineMembers(ctx, subredditID, activeWithin) })
+}
+
+func (d *breakerDB) SavePost(ctx context.Context, post *models.Post) error {
+	return d.b.do(func() error { return d.db.SavePost(ctx, post) })
+}
+
+func (d *breakerDB) GetPost(ctx context.Context, postID uuid.UUID, requestingUserID uuid.UUID) (*models.Post, error) {
+	return guard(d.b, func() (*models.Post, error) { return d.db.GetPost(ctx, postID, requestingUserID) })
+}
+
+func (d *breakerDB) RecordVote(ctx context.Context, userID, contentID uuid.UUID, contentType models.VoteContentType, direction models.VoteDirection) error {
+	return d.b.do(func() error { return d.db.RecordVote(ctx, userID, contentID, contentType, direction) })
+}
+
+func (d *breakerDB) GetAuthorAffinity(ctx context.Context, userID uuid.UUID, authorIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	return guard(d.b, func() (map[uuid.UUID]int, error) { return d.db.GetAuthorAffinity(ctx, userID, authorIDs) })
+}
+
+func (d *breakerDB) GetRecentPosts(ctx context.Context, limit, offset int, after *models.Keyset, requestingUserID uuid.UUID, sortOrder string) ([]*models.Post, error) {
+	return guard(d.b, func() ([]*models.Post, error) {
+		return d.db.GetRecentPosts(ctx, limit, offset, after, requestingUserID, sortOrder)
+	})
+}
+
+func (d *breakerDB) GetUserFeed(ctx context.Context, userID uuid.UUID, limit, offset int, after *models.Keyset, requestingUserID uuid.UUID, hideSeen bool, sortOrder string) ([]*models.Post, error) {
+	return guard(d.b, func() ([]*models.Post, error) {
+		return d.db.GetUserFeed(ctx, userID, limit, offset, after, requestingUserID, hideSeen, sortOrder)
+	})
+}
+
+func (d *breakerDB) MarkPostsSeen(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) error {
+	return d.b.do(func() error { return d.db.MarkPostsSeen(ctx, userID, postIDs) })
+}
+
+func (d *breakerDB) GetPostsBySubreddit(ctx context.Context, subredditID uuid.UUID, flairID *uuid.UUID, limit int, after *models.Keyset, requestingUserID uuid.UUID) ([]*models.Post, error) {
+	return guard(d.b, func() ([]*models.Post, error) {
+		return d.db.GetPostsBySubreddit(ctx, subredditID, flairID, limit, after, requestingUserID)
+	})
+}
+
+func (d *breakerDB) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
+	return guard(d.b, func() ([]*models.Post, error) { return d.db.GetAllPosts(ctx) })
+}
+
+func (d *breakerDB) UpdatePostThumbnail(ctx context.Context, postID uuid.UUID, thumbnailURL string) error {
+	return d.b.do(func() error { return d.db.UpdatePostThumbnail(ctx, postID, thumbnailURL) })
+}
+
+func (d *breakerDB) EditPost(ctx context.Context, postID uuid.UUID, title, content string, language *string) (time.Time, error) {
+	return guard(d.b, func() (time.Time, error) { return d.db.EditPost(ctx, postID, title, content, language) })
+}
+
+func (d *breakerDB) GetPostRevisions(ctx context.Context, postID uuid.UUID) ([]*models.PostRevision, error) {
+	return guard(d.b, func() ([]*models.PostRevision, error) { return d.db.GetPostRevisions(ctx, postID) })
+}
+
+func (d *breakerDB) SetPostLocked(ctx context.Context, postID uuid.UUID, locked bool) error {
+	return d.b.do(func() error { return d.db.SetPostLocked(ctx, postID, locked) })
+}
+
+func (d *breakerDB) SetPostLockedByModerator(ctx context.Context, postID uuid.UUID, locked bool) error {
+	return d.b.do(func() error { return d.db.SetPostLockedByModerator(ctx, postID, locked) })
+}
+
+func (d *breakerDB) UpdatePostMetadata(ctx context.Context, postID uuid.UUID, meta *models.PostMetadata) error {
+	return d.b.do(func() error { return d.db.UpdatePostMetadata(ctx, postID, meta) })
+}
+
+func (d *breakerDB) SearchPosts(ctx context.Context, query, sort string, limit, offset int, requestingUserID uuid.UUID) ([]uuid.UUID, error) {
+	return guard(d.b, func() ([]uuid.UUID, error) {
+		return d.db.SearchPosts(ctx, query, sort, limit, offset, requestingUserID)
+	})
+}
+
+func (d *breakerDB) SearchComments(ctx context.Context, query, sort string, limit, offset int, requestingUserID uuid.UUID) ([]*models.Comment, error) {
+	return guard(d.b, func() ([]*models.Comment, error) {
+		return d.db.SearchComments(ctx, query, sort, limit, offset, requestingUserID)
+	})
+}
+
+func (d *breakerDB) AddModerator(ctx context.Context, subredditID, userID, addedBy uuid.UUID) (bool, error) {
+	return guard(d.b, func() (bool, error) { return d.db.AddModerator(ctx, subredditID, userID, addedBy) })
+}
+
+func (d *breakerDB) RemoveModerator(ctx context.Context, subredditID, userID uuid.UUID) (bool, error) {
+	return guard(d.b, func() (bool, error) { return d.db.RemoveModerator(ctx, subredditID, userID) })
+}
+
+func (d *breakerDB) GetModerators(ctx context.Context, subredditID uuid.UUID) ([]*models.Moderator, error) {
+	return guard(d.b, func() ([]*models.Moderator, error) { return d.db.GetModerators(ctx, subredditID) })
+}
+
+func (d *breakerDB) IsSubredditModerator(ctx context.Context, subredditID, userID uuid.UUID) (bool, error) {
+	return guard(d.b, func() (bool, error) { return d.db.IsSubredditModerator(ctx, subredditID, userID) })
+}
+
+func (d *breakerDB) CreateFlair(ctx context.Context, flair *models.Flair) error {
+	return d.b.do(func() error { return d.db.CreateFlair(ctx, flair) })
+}
+
+func (d *breakerDB) GetFlair(ctx context.Context, id uuid.UUID) (*models.Flair, error) {
+	return guard(d.b, func() (*models.Flair, error) { return d.db.GetFlair(ctx, id) })
+}
+
+func (d *breakerDB) GetFlairs(ctx context.Context, subredditID uuid.UUID) ([]*models.Flair, error) {
+	return guard(d.b, func() ([]*models.Flair, error) { return d.db.GetFlairs(ctx, subredditID) })
+}
+
+func (d *breakerDB) UpdateFlair(ctx context.Context, flair *models.Flair) error {
+	return d.b.do(func() error { return d.db.UpdateFlair(ctx, flair) })
+}
+
+func (d *breakerDB) DeleteFlair(ctx context.Context, id uuid.UUID) error {
+	return d.b.do(func() error { return d.db.DeleteFlair(ctx, id) })
+}
+
+func (d *breakerDB) CreateReport(ctx context.Context, report *models.Report) error {
+	return d.b.do(func() error { return d.db.CreateReport(ctx, report) })
+}
+
+func (d *breakerDB) GetReport(ctx context.Context, id uuid.UUID) (*models.Report, error) {
+	return guard(d.b, func() (*models.Report, error) { return d.db.GetReport(ctx, id) })
+}
+
+func (d *breakerDB) ListReports(ctx context.Context, subredditID uuid.UUID, status models.ReportStatus, limit, offset int) ([]*models.Report, error) {
+	return guard(d.b, func() ([]*models.Report, error) { return d.db.ListReports(ctx, subredditID, status, limit, offset) })
+}
+
+func (d *breakerDB) CloseReport(ctx context.Context, report *models.Report) error {
+	return d.b.do(func() error { return d.db.CloseReport(ctx, report) })
+}
+
+func (d *breakerDB) CreateSubredditExport(ctx context.Context, export *models.SubredditExport) error {
+	return d.b.do(func() error { return d.db.CreateSubredditExport(ctx, export) })
+}
+
+func (d *breakerDB) GetSubredditExport(ctx context.Context, id uuid.UUID) (*models.SubredditExport, error) {
+	return guard(d.b, func() (*models.SubredditExport, error) { return d.db.GetSubredditExport(ctx, id) })
+}
+
+func (d *breakerDB) ListSubredditExports(ctx context.Context, subredditID uuid.UUID) ([]*models.SubredditExport, error) {
+	return guard(d.b, func() ([]*models.SubredditExport, error) { return d.db.ListSubredditExports(ctx, subredditID) })
+}
+
+func (d *breakerDB) UpdateSubredditExport(ctx context.Context, export *models.SubredditExport) error {
+	return d.b.do(func() error { return d.db.UpdateSubredditExport(ctx, export) })
+}
+
+func (d *breakerDB) GetSubredditArchivePosts(ctx context.Context, subredditID uuid.UUID, limit int, after *models.Keyset) ([]*models.Post, error) {
+	return guard(d.b, func() ([]*models.Post, error) { return d.db.GetSubredditArchivePosts(ctx, subredditID, limit, after) })
+}
+
+func (d *breakerDB) SearchSubreddits(ctx context.Context, query, sort string, limit, offset int, requestingUserID uuid.UUID) ([]*models.Subreddit, error) {
+	return guard(d.b, func() ([]*models.Subreddit, error) {
+		return d.db.SearchSubreddits(ctx, query, sort, limit, offset, requestingUserID)
+	})
+}
+
+func (d *breakerDB) GetSitemapEntries(ctx context.Context, perSubreddit int) ([]*models.SitemapEntry, error) {
+	return guard(d.b, func() ([]*models.SitemapEntry, error) { return d.db.GetSitemapEntries(ctx, perSubreddit) })
+}
+
+func (d *breakerDB) HoldPost(ctx context.Context, post *models.Post) error {
+	return d.b.do(func() error { return d.db.HoldPost(ctx, post) })
+}
+
+func (d *breakerDB) ListPendingPosts(ctx context.Context, subredditID uuid.UUID) ([]*models.Post, error) {
+	return guard(d.b, func() ([]*models.Post, error) { return d.db.ListPendingPosts(ctx, subredditID) })
+}
+
+func (d *breakerDB) TakePendingPost(ctx context.Context, subredditID, id uuid.UUID) (*models.Post, error) {
+	return guard(d.b, func() (*models.Post, error) { return d.db.TakePendingPost(ctx, subredditID, id) })
+}
+
+func (d *breakerDB) SaveComment(ctx context.Context, comment *models.Comment) error {
+	return d.b.do(func() error { return d.db.SaveComment(ctx, comment) })
+}
+
+func (d *breakerDB) GetComment(ctx context.Context, id uuid.UUID, requestingUserID uuid.UUID) (*models.Comment, error) {
+	return guard(d.b, func() (*models.Comment, error) { return d.db.GetComment(ctx, id, requestingUserID) })
+}
+
+func (d *breakerDB) GetPostComments(ctx context.Context, postID uuid.UUID, requestingUserID uuid.UUID) ([]*models.Comment, error) {
+	return guard(d.b, func() ([]*models.Comment, error) { return d.db.GetPostComments(ctx, postID, requestingUserID) })
+}
+
+func (d *breakerDB) GetCommentThread(ctx context.Context, postID uuid.UUID, parentID, afterID *uuid.UUID, width, depth int, requestingUserID uuid.UUID) ([]*models.CommentNode, bool, error) {
+	var more bool
+	nodes, err := guard(d.b, func() ([]*models.CommentNode, error) {
+		nodes, m, err := d.db.GetCommentThread(ctx, postID, parentID, afterID, width, depth, requestingUserID)
+		more = m
+		return nodes, err
+	})
+	return nodes, more, err
+}
+
+func (d *breakerDB) CountCommentsByPost(ctx context.Context, postID uuid.UUID) (int, error) {
+	return guard(d.b, func() (int, error) { return d.db.CountCommentsByPost(ctx, postID) })
+}
+
+func (d *breakerDB) GetAllComments(ctx context.Context) ([]*models.Comment, error) {
+	return guard(d.b, func() ([]*models.Comment, error) { return d.db.GetAllComments(ctx) })
+}
+
+func (d *breakerDB) HoldComment(ctx context.Context, comment *models.Comment) error {
+	return d.b.do(func() error { return d.db.HoldComment(ctx, comment) })
+}
+
+func (d *breakerDB) ListPendingComments(ctx context.Context, subredditID uuid.UUID) ([]*models.Comment, error) {
+	return guard(d.b, func() ([]*models.Comment, error) { return d.db.ListPendingComments(ctx, subredditID) })
+}
+
+func (d *breakerDB) TakePendingComment(ctx context.Context, subredditID, id uuid.UUID) (*models.Comment, error) {
+	return guard(d.b, func() (*models.Comment, error) { return d.db.TakePendingComment(ctx, subredditID, id) })
+}
+
+func (d *breakerDB) SoftDelete(ctx context.Context, contentType models.ContentType, id uuid.UUID) error {
+	return d.b.do(func() error { return d.db.SoftDelete(ctx, contentType, id) })
+}
+
+func (d *breakerDB) DeletePost(ctx context.Context, id uuid.UUID) error {
+	return d.b.do(func() error { return d.db.DeletePost(ctx, id) })
+}
+
+func (d *breakerDB) Restore(ctx context.Context, contentType models.ContentType, id uuid.UUID) error {
+	return d.b.do(func() error { return d.db.Restore(ctx, contentType, id) })
+}
+
+func (d *breakerDB) ListDeleted(ctx context.Context, contentType models.ContentType, limit, offset int) ([]*models.DeletedContent, error) {
+	return guard(d.b, func() ([]*models.DeletedContent, error) { return d.db.ListDeleted(ctx, contentType, limit, offset) })
+}
+
+func (d *breakerDB) SaveMessage(ctx context.Context, msg *models.DirectMessage) error {
+	return d.b.do(func() error { return d.db.SaveMessage(ctx, msg) })
+}
+
+func (d *breakerDB) GetMessagesByUser(ctx context.Context, userID uuid.UUID) ([]*models.DirectMessage, error) {
+	return guard(d.b, func() ([]*models.DirectMessage, error) { return d.db.GetMessagesByUser(ctx, userID) })
+}
+
+func (d *breakerDB) GetUnreadMessageCount(ctx context.Context, userID uuid.UUID) (int, error) {
+	return guard(d.b, func() (int, error) { return d.db.GetUnreadMessageCount(ctx, userID) })
+}
+
+func (d *breakerDB) UpdateMessageStatus(ctx context.Context, msgID uuid.UUID, isRead *bool, isDeleted *bool, isDelivered *bool) error {
+	return d.b.do(func() error { return d.db.UpdateMessageStatus(ctx, msgID, isRead, isDeleted, isDelivered) })
+}
+
+func (d *breakerDB) GetInbox(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*models.InboxItem, error) {
+	return guard(d.b, func() ([]*models.InboxItem, error) { return d.db.GetInbox(ctx, userID, limit, offset) })
+}
+
+func (d *breakerDB) GetInboxItem(ctx context.Context, userID, id uuid.UUID) (*models.InboxItem, error) {
+	return guard(d.b, func() (*models.InboxItem, error) { return d.db.GetInboxItem(ctx, userID, id) })
+}
+
+func (d *breakerDB) EnqueueJob(ctx context.Context, job *models.Job) (bool, error) {
+	return guard(d.b, func() (bool, error) { return d.db.EnqueueJob(ctx, job) })
+}
+
+func (d *breakerDB) ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]*models.Job, error) {
+	return guard(d.b, func() ([]*models.Job, error) { return d.db.ClaimJobs(ctx, limit, lease) })
+}
+
+func (d *breakerDB) CompleteJob(ctx context.Context, id uuid.UUID) error {
+	return d.b.do(func() error { return d.db.CompleteJob(ctx, id) })
+}
+
+func (d *breakerDB) FailJob(ctx context.Context, id uuid.UUID, lastError string, retryAt *time.Time) error {
+	return d.b.do(func() error { return d.db.FailJob(ctx, id, lastError, retryAt) })
+}
+
+func (d *breakerDB) RecordAudit(ctx context.Context, entry *models.AuditEntry) error {
+	return d.b.do(func() error { return d.db.RecordAudit(ctx, entry) })
+}
+
+func (d *breakerDB) GetAuditLog(ctx context.Context, subjectID *uuid.UUID, limit, offset int) ([]*models.AuditEntry, error) {
+	return guard(d.b, func() ([]*models.AuditEntry, error) { return d.db.GetAuditLog(ctx, subjectID, limit, offset) })
+}
+
+func (d *breakerDB) ReconcileCounters(ctx context.Context) (int64, error) {
+	return guard(d.b, func() (int64, error) { return d.db.ReconcileCounters(ctx) })
+}
+
+func (d *breakerDB) AcquireTaskLease(ctx context.Context, name string, interval, lease time.Duration) (bool, error) {
+	return guard(d.b, func() (bool, error) { return d.db.AcquireTaskLease(ctx, name, interval, lease) })
+}
+
+func (d *breakerDB) ReleaseTaskLease(ctx context.Context, name string, runErr error) error {
+	return d.b.do(func() error { return d.db.ReleaseTaskLease(ctx, name, runErr) })
+}
+
+func (d *breakerDB) DecayHotScores(ctx context.Context, window time.Duration) (int64, error) {
+	return guard(d.b, func() (int64, error) { return d.db.DecayHotScores(ctx, window) })
+}
+
+func (d *breakerDB) UpdateKarmaVelocity(ctx context.Context) (int64, error) {
+	return guard(d.b, func() (int64, error) { return d.db.UpdateKarmaVelocity(ctx) })
+}
+
+func (d *breakerDB) RefreshTrendingSubreddits(ctx context.Context, window time.Duration, limit int) (int64, error) {
+	return guard(d.b, func() (int64, error) { return d.db.RefreshTrendingSubreddits(ctx, window, limit) })
+}
+
+func (d *breakerDB) ArchiveOldPosts(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return guard(d.b, func() (int64, error) { return d.db.ArchiveOldPosts(ctx, olderThan) })
+}
+
+func (d *breakerDB) ClearStaleConnections(ctx context.Context, idleFor time.Duration) (int64, error) {
+	return guard(d.b, func() (int64, error) { return d.db.ClearStaleConnections(ctx, idleFor) })
+}
+
+func (d *breakerDB) PurgeExpired(ctx context.Context, target string, olderThan time.Duration, dryRun bool) (int64, error) {
+	return guard(d.b, func() (int64, error) { return d.db.PurgeExpired(ctx, target, olderThan, dryRun) })
+}
+
+func (d *breakerDB) CreateMedia(ctx context.Context, media *models.Media) error {
+	return d.b.do(func() error { return d.db.CreateMedia(ctx, media) })
+}
+
+func (d *breakerDB) GetMedia(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	return guard(d.b, func() (*models.Media, error) { return d.db.GetMedia(ctx, id) })
+}
@@ -0,0 +1,209 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// bulkInsert loads rows in one transaction: it COPYs them into bulk_rows, a
+// temporary copy of table, then runs statements, which move them from there
+// into table. It returns the rows the first statement affected. Change
+// notifications are off for the transaction (see 0002_bulk_import.up.sql),
+// so loaded rows aren't published one by one.
+func (p *PostgresDB) bulkInsert(ctx context.Context, table string, columns []string, rows [][]interface{}, statements ...string) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	tx, err := p.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to begin bulk insert", err)
+	}
+	defer tx.Rollback()
+
+	inserted, err := bulkInsertTx(ctx, tx, table, columns, rows, statements)
+	if err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, fmt.Sprintf("failed to bulk insert into %s", table), err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, fmt.Sprintf("failed to bulk insert into %s", table), err)
+	}
+	return inserted, nil
+}
+
+func bulkInsertTx(ctx context.Context, tx *sqlx.Tx, table string, columns []string, rows [][]interface{}, statements []string) (int64, error) {
+	setup := `SET LOCAL gator.bulk_load = 'on';
+		CREATE TEMP TABLE bulk_rows (LIKE ` + table + ` INCLUDING DEFAULTS) ON COMMIT DROP`
+	if _, err := tx.ExecContext(ctx, setup); err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("bulk_rows", columns...))
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			stmt.Close()
+			return 0, err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return 0, err
+	}
+	if err := stmt.Close(); err != nil {
+		return 0, err
+	}
+
+	var inserted int64
+	for i, statement := range statements {
+		result, err := tx.ExecContext(ctx, statement)
+		if err != nil {
+			return 0, err
+		}
+		if i == 0 {
+			inserted, _ = result.RowsAffected()
+		}
+	}
+	return inserted, nil
+}
+
+// BulkInsertUsers adds users that don't exist yet, keeping their IDs and
+// timestamps. It returns the ID each username has afterwards, which is an
+// existing user's where the name was already taken.
+func (p *PostgresDB) BulkInsertUsers(ctx context.Context, users []*models.User) (map[string]uuid.UUID, error) {
+	columns := []string{"id", "username", "email", "password_hash", "karma", "created_at", "updated_at", "last_active", "is_bot"}
+	rows := make([][]interface{}, len(users))
+	names := make([]string, len(users))
+	for i, u := range users {
+		rows[i] = []interface{}{u.ID, u.Username, u.Email, u.HashedPassword, u.Karma, u.CreatedAt, u.CreatedAt, u.CreatedAt, u.IsBot}
+		names[i] = u.Username
+	}
+	list := strings.Join(columns, ", ")
+	_, err := p.bulkInsert(ctx, "users", columns, rows,
+		`INSERT INTO users (`+list+`) SELECT `+list+` FROM bulk_rows ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return nil, err
+	}
+	return p.idsByName(ctx, `SELECT id, username AS name FROM users WHERE username = ANY($1)`, names)
+}
+
+// BulkInsertSubreddits adds subreddits that don't exist yet, keeping their
+// IDs and timestamps. It returns the ID each name has afterwards, which is
+// an existing subreddit's where the name was already taken.
+func (p *PostgresDB) BulkInsertSubreddits(ctx context.Context, subs []*models.Subreddit) (map[string]uuid.UUID, error) {
+	columns := []string{"id", "name", "description", "created_by", "created_at", "nsfw"}
+	rows := make([][]interface{}, len(subs))
+	names := make([]string, len(subs))
+	for i, s := range subs {
+		rows[i] = []interface{}{s.ID, s.Name, s.Description, s.CreatorID, s.CreatedAt, s.NSFW}
+		names[i] = s.Name
+	}
+	list := strings.Join(columns, ", ")
+	_, err := p.bulkInsert(ctx, "subreddits", columns, rows,
+		`INSERT INTO subreddits (`+list+`) SELECT `+list+` FROM bulk_rows ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return nil, err
+	}
+	return p.idsByName(ctx, `SELECT id, name FROM subreddits WHERE name = ANY($1)`, names)
+}
+
+func (p *PostgresDB) idsByName(ctx context.Context, query string, names []string) (map[string]uuid.UUID, error) {
+	var found []struct {
+		ID   uuid.UUID `db:"id"`
+		Name string    `db:"name"`
+	}
+	if err := p.DB.SelectContext(ctx, &found, query, pq.Array(names)); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to look up inserted names", err)
+	}
+	ids := make(map[string]uuid.UUID, len(found))
+	for _, f := range found {
+		ids[f.Name] = f.ID
+	}
+	return ids, nil
+}
+
+// BulkInsertPosts adds posts that don't exist yet, keeping their IDs,
+// timestamps and votes. Their authors and subreddits must exist. Counters
+// that posts feed, such as subreddits' post counts, are left for
+// ReconcileCounters.
+func (p *PostgresDB) BulkInsertPosts(ctx context.Context, posts []*models.Post) (int64, error) {
+	columns := []string{"id", "title", "content", "url", "language", "author_id", "subreddit_id", "created_at", "updated_at", "karma", "upvotes", "downvotes"}
+	rows := make([][]interface{}, len(posts))
+	for i, post := range posts {
+		rows[i] = []interface{}{post.ID, post.Title, post.Content, post.URL, post.Language, post.AuthorID, post.SubredditID,
+			post.CreatedAt, post.UpdatedAt, post.Karma, post.Upvotes, post.Downvotes}
+	}
+	list := strings.Join(columns, ", ")
+	return p.bulkInsert(ctx, "posts", columns, rows,
+		`INSERT INTO posts (`+list+`) SELECT `+list+` FROM bulk_rows ON CONFLICT DO NOTHING`)
+}
+
+// BulkInsertComments adds comments that don't exist yet, keeping their IDs,
+// timestamps and votes. Comments on posts that don't exist are skipped. A
+// comment whose parent doesn't exist, in the database or the batch, becomes
+// a top-level comment. Post comment counts and reply counts are left for
+// ReconcileCounters.
+func (p *PostgresDB) BulkInsertComments(ctx context.Context, comments []*models.Comment) (int64, error) {
+	columns := []string{"id", "content", "author_id", "post_id", "parent_id", "created_at", "updated_at", "karma", "upvotes", "downvotes"}
+	rows := make([][]interface{}, len(comments))
+	for i, c := range comments {
+		rows[i] = []interface{}{c.ID, c.Content, c.AuthorID, c.PostID, c.ParentID,
+			c.CreatedAt, c.UpdatedAt, c.Karma, c.Upvotes, c.Downvotes}
+	}
+	// Parents are set once the whole batch is in, so replies can come
+	// before the comments they answer
+	return p.bulkInsert(ctx, "comments", columns, rows,
+		`INSERT INTO comments (id, content, author_id, post_id, created_at, updated_at, karma, upvotes, downvotes)
+		SELECT id, content, author_id, post_id, created_at, updated_at, karma, upvotes, downvotes FROM bulk_rows b
+		WHERE EXISTS (SELECT 1 FROM posts p WHERE p.id = b.post_id)
+		ON CONFLICT DO NOTHING`,
+		`UPDATE comments c SET parent_id = b.parent_id
+		FROM bulk_rows b JOIN comments parent ON parent.id = b.parent_id AND parent.post_id = b.post_id
+		WHERE c.id = b.id AND c.parent_id IS NULL`)
+}
+
+// SaveExternalIDs records where imported rows came from. IDs already
+// recorded are left as they are, and so are those of rows that weren't
+// imported, such as comments on posts that were skipped.
+func (p *PostgresDB) SaveExternalIDs(ctx context.Context, ids []models.ExternalID) error {
+	rows := make([][]interface{}, len(ids))
+	for i, id := range ids {
+		rows[i] = []interface{}{id.Source, string(id.Kind), id.ExternalID, id.ID, time.Now()}
+	}
+	_, err := p.bulkInsert(ctx, "external_ids", []string{"source", "kind", "external_id", "id", "imported_at"}, rows,
+		`INSERT INTO external_ids SELECT * FROM bulk_rows b
+		WHERE CASE b.kind
+			WHEN 'user' THEN EXISTS (SELECT 1 FROM users WHERE id = b.id)
+			WHEN 'subreddit' THEN EXISTS (SELECT 1 FROM subreddits WHERE id = b.id)
+			WHEN 'post' THEN EXISTS (SELECT 1 FROM posts WHERE id = b.id)
+			WHEN 'comment' THEN EXISTS (SELECT 1 FROM comments WHERE id = b.id)
+		END
+		ON CONFLICT DO NOTHING`)
+	return err
+}
+
+// SyncImportedKarma sets the karma of users imported from source to the
+// post and comment karma ReconcileCounters added up, and returns how many
+// changed. A dump's scores include the author's own upvote, which, as for
+// local posts, isn't counted towards them.
+func (p *PostgresDB) SyncImportedKarma(ctx context.Context, source string) (int64, error) {
+	result, err := p.DB.ExecContext(ctx, `
+		UPDATE users u SET karma = u.post_karma + u.comment_karma
+		FROM external_ids e
+		WHERE e.source = $1 AND e.kind = $2 AND e.id = u.id AND u.karma <> u.post_karma + u.comment_karma`,
+		source, models.ExternalUser)
+	if err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to sync imported karma", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ChangeChannel is the LISTEN/NOTIFY channel row changes are published on,
+// by the triggers the migrations install.
+const ChangeChannel = "gator_changes"
+
+// ChangeResync is the Op of a synthetic change delivered after the listener
+// reconnects. Notifications sent while it was down are lost, so every cache
+// should be treated as stale.
+const ChangeResync = "resync"
+
+// Change describes one row written to a cached table. Origin is the writing
+// connection's application_name, which lets an instance skip its own writes.
+type Change struct {
+	Table    string    `json:"table"`
+	Op       string    `json:"op"` // insert, update, delete or resync
+	ID       uuid.UUID `json:"id"`
+	ParentID uuid.UUID `json:"parentId"` // Subreddit of a post, post of a comment, user of a membership
+	Origin   string    `json:"origin"`
+}
+
+// WithApplicationName sets application_name on a connection string, in URL
+// or key=value form. Change notifications carry it as their Origin.
+func WithApplicationName(connectionString, name string) string {
+	if u, err := url.Parse(connectionString); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		q := u.Query()
+		q.Set("application_name", name)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return strings.TrimSpace(connectionString) + fmt.Sprintf(" application_name='%s'", name)
+}
+
+// ListenChanges calls handle for each change made by a connection whose
+// application_name isn't origin, until ctx is done. After a dropped
+// connection is re-established it delivers a ChangeResync change. handle
+// runs on the listener goroutine and must not block.
+func ListenChanges(ctx context.Context, connectionString, origin string, handle func(Change)) error {
+	listener := pq.NewListener(connectionString, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.WarnContext(ctx, "Change feed listener failed", "error", err)
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(ChangeChannel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", ChangeChannel, err)
+	}
+	slog.InfoContext(ctx, "Listening for database changes", "channel", ChangeChannel)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case n := <-listener.Notify:
+			// pq sends nil once it has reconnected
+			if n == nil {
+				changesReceived.WithLabelValues("*", ChangeResync).Inc()
+				handle(Change{Op: ChangeResync})
+				continue
+			}
+			var change Change
+			if err := json.Unmarshal([]byte(n.Extra), &change); err != nil {
+				slog.WarnContext(ctx, "Ignoring malformed change notification", "payload", n.Extra, "error", err)
+				continue
+			}
+			if change.Origin == origin {
+				continue
+			}
+			changesReceived.WithLabelValues(change.Table, change.Op).Inc()
+			handle(change)
+
+		case <-time.After(90 * time.Second):
+			// Detect a dead connection the server never closed
+			go listener.Ping()
+		}
+	}
+}
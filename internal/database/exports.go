@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const subredditExportColumns = `id, subreddit_id, requested_by, status, key, size_bytes, post_count, comment_count, error, created_at, completed_at`
+
+// CreateSubredditExport records a pending export. Only one export of a
+// subreddit may be pending or running at a time; another is a Duplicate
+// error.
+func (p *PostgresDB) CreateSubredditExport(ctx context.Context, export *models.SubredditExport) error {
+	query := `
+		INSERT INTO subreddit_exports (id, subreddit_id, requested_by, status)
+		SELECT $1, $2, $3, $4 FROM subreddits WHERE id = $2 AND deleted_at IS NULL
+		RETURNING created_at
+	`
+	err := p.DB.QueryRowxContext(ctx, query, export.ID, export.SubredditID, export.RequestedBy, export.Status).Scan(&export.CreatedAt)
+	if err == sql.ErrNoRows {
+		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("subreddit %s not found", export.SubredditID), err)
+	}
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+		return utils.NewAppError(utils.ErrDuplicate, "an export of this subreddit is already in progress", err)
+	}
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to create subreddit export", err)
+	}
+	return nil
+}
+
+// GetSubredditExport fetches an export by its ID.
+func (p *PostgresDB) GetSubredditExport(ctx context.Context, id uuid.UUID) (*models.SubredditExport, error) {
+	var export models.SubredditExport
+	err := p.DB.GetContext(ctx, &export, `SELECT `+subredditExportColumns+` FROM subreddit_exports WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("export %s not found", id), err)
+	}
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to fetch subreddit export", err)
+	}
+	return &export, nil
+}
+
+// ListSubredditExports lists a subreddit's exports, newest first.
+func (p *PostgresDB) ListSubredditExports(ctx context.Context, subredditID uuid.UUID) ([]*models.SubredditExport, error) {
+	exports := []*models.SubredditExport{}
+	err := p.DB.SelectContext(ctx, &exports, `SELECT `+subredditExportColumns+`
+		FROM subreddit_exports WHERE subreddit_id = $1 ORDER BY created_at DESC`, subredditID)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to list subreddit exports", err)
+	}
+	return exports, nil
+}
+
+// UpdateSubredditExport saves an export's progress: its status, archive and
+// counts, and error. Finished exports get their completion time.
+func (p *PostgresDB) UpdateSubredditExport(ctx context.Context, export *models.SubredditExport) error {
+	query := `
+		UPDATE subreddit_exports SET status = $2, key = $3, size_bytes = $4, post_count = $5, comment_count = $6, error = $7,
+			completed_at = CASE WHEN $2 IN ('done', 'failed') THEN NOW() END
+		WHERE id = $1
+		RETURNING completed_at
+	`
+	err := p.DB.QueryRowxContext(ctx, query, export.ID, export.Status, export.Key, export.SizeBytes,
+		export.PostCount, export.CommentCount, export.Error).Scan(&export.CompletedAt)
+	if err == sql.ErrNoRows {
+		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("export %s not found", export.ID), err)
+	}
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to update subreddit export", err)
+	}
+	return nil
+}
+
+// GetSubredditArchivePosts pages through a subreddit's posts for an export,
+// newest first, with their authors.
+func (p *PostgresDB) GetSubredditArchivePosts(ctx context.Context, subredditID uuid.UUID, limit int, after *models.Keyset) ([]*models.Post, error) {
+	query := `
+		SELECT p.id, p.title, p.content, p.author_id, u.username AS author_username, u.is_bot AS author_is_bot,
+			p.subreddit_id, s.name AS subreddit_name, p.created_at, p.updated_at, p.karma, p.upvotes, p.downvotes, p.comment_count,
+			p.url, p.thumbnail_url, p.locked_by_author, p.locked_by_moderator, p.archived, p.edited_at,
+			p.original_content, p.source_attribution, p.license, p.language, ` + postFlairColumns + `, ` + postMediaColumns + `
+		FROM posts p
+		JOIN users u ON u.id = p.author_id
+		JOIN subreddits s ON s.id = p.subreddit_id
+		` + postFlairJoin + `
+		` + postMediaJoin + `
+		WHERE p.subreddit_id = $1 AND p.deleted_at IS NULL
+		  AND ($2::timestamptz IS NULL OR (p.created_at, p.id) < ($2, $3::uuid))
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $4
+	`
+	afterCreated, afterID := keysetArgs(after)
+	posts := []*models.Post{}
+	if err := p.DB.SelectContext(ctx, &posts, query, subredditID, afterCreated, afterID, limit); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query posts for export", err)
+	}
+	return posts, nil
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Flairs are templates a subreddit's moderators define; posts refer to one
+// by flair_id. Deleting a flair clears it from its posts.
+
+// Post flair columns and the join they need, for queries over posts p
+const (
+	postFlairColumns = `p.flair_id, f.text AS flair_text, f.color AS flair_color`
+	postFlairJoin    = `LEFT JOIN subreddit_flairs f ON f.id = p.flair_id`
+)
+
+// CreateFlair adds a flair to a subreddit. Names are unique per subreddit,
+// ignoring case.
+func (p *PostgresDB) CreateFlair(ctx context.Context, flair *models.Flair) error {
+	query := `
+		INSERT INTO subreddit_flairs (id, subreddit_id, text, color)
+		SELECT $1, $2, $3, $4 FROM subreddits WHERE id = $2 AND deleted_at IS NULL
+		RETURNING created_at
+	`
+	err := p.DB.QueryRowxContext(ctx, query, flair.ID, flair.SubredditID, flair.Text, flair.Color).Scan(&flair.CreatedAt)
+	if err == sql.ErrNoRows {
+		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("subreddit %s not found", flair.SubredditID), err)
+	}
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+		return utils.NewAppError(utils.ErrDuplicate, fmt.Sprintf("flair %q already exists", flair.Text), err)
+	}
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to create flair", err)
+	}
+	return nil
+}
+
+// GetFlair fetches a flair by its ID.
+func (p *PostgresDB) GetFlair(ctx context.Context, id uuid.UUID) (*models.Flair, error) {
+	var flair models.Flair
+	err := p.DB.GetContext(ctx, &flair, `SELECT id, subreddit_id, text, color, created_at FROM subreddit_flairs WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("flair %s not found", id), err)
+	}
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to fetch flair", err)
+	}
+	return &flair, nil
+}
+
+// GetFlairs lists a subreddit's flairs by name.
+func (p *PostgresDB) GetFlairs(ctx context.Context, subredditID uuid.UUID) ([]*models.Flair, error) {
+	flairs := []*models.Flair{}
+	err := p.DB.SelectContext(ctx, &flairs, `
+		SELECT id, subreddit_id, text, color, created_at FROM subreddit_flairs
+		WHERE subreddit_id = $1
+		ORDER BY lower(text)`, subredditID)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to list flairs", err)
+	}
+	return flairs, nil
+}
+
+// UpdateFlair changes a flair's text and color. Its posts show the new ones.
+func (p *PostgresDB) UpdateFlair(ctx context.Context, flair *models.Flair) error {
+	result, err := p.DB.ExecContext(ctx, `UPDATE subreddit_flairs SET text = $2, color = $3 WHERE id = $1`, flair.ID, flair.Text, flair.Color)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+		return utils.NewAppError(utils.ErrDuplicate, fmt.Sprintf("flair %q already exists", flair.Text), err)
+	}
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to update flair", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("flair %s not found", flair.ID), nil)
+	}
+	return nil
+}
+
+// DeleteFlair removes a flair, clearing it from the posts that had it.
+func (p *PostgresDB) DeleteFlair(ctx context.Context, id uuid.UUID) error {
+	result, err := p.DB.ExecContext(ctx, `DELETE FROM subreddit_flairs WHERE id = $1`, id)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to delete flair", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("flair %s not found", id), nil)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// inboxItems merges a user's unread messages with their unread replies and
+// mentions ($1 is the user). Replies and mentions come from
+// inbox_notifications, recorded as the comments and posts were written;
+// those whose comment or post has since been deleted are left out.
+const inboxItems = `
+	SELECT 'message' AS type, m.id, m.sender_id AS from_id, u.username AS from_username,
+		NULL::uuid AS post_id, m.content, m.created_at
+	FROM messages m JOIN users u ON u.id = m.sender_id
+	WHERE m.receiver_id = $1 AND m.read_at IS NULL
+
+	UNION ALL
+	SELECT n.type, n.content_id, n.from_id, u.username, n.post_id, COALESCE(c.content, p.title), n.created_at
+	FROM inbox_notifications n
+	JOIN users u ON u.id = n.from_id
+	JOIN posts p ON p.id = n.post_id AND p.deleted_at IS NULL
+	LEFT JOIN comments c ON c.id = n.content_id
+	WHERE n.user_id = $1 AND n.read_at IS NULL
+	  AND (n.content_id = n.post_id OR c.deleted_at IS NULL AND c.id IS NOT NULL)
+`
+
+// mentionRE matches u/<username> where it isn't part of a longer word or
+// path; the backfill in migration 0005 uses the same pattern.
+var mentionRE = regexp.MustCompile(`(?:^|[^[:alnum:]_/])u/([[:alnum:]_-]+)`)
+
+// mentionedNames returns the lowercased usernames mentioned in texts, once
+// each.
+func mentionedNames(texts ...string) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, text := range texts {
+		for _, match := range mentionRE.FindAllStringSubmatch(text, -1) {
+			name := strings.ToLower(match[1])
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// recordReply adds a new comment to the inbox of the author of the comment
+// it answers, or of its post for a top-level comment, unless they wrote it
+// themselves.
+func recordReply(ctx context.Context, tx *sqlx.Tx, comment *models.Comment) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO inbox_notifications (user_id, content_id, type, from_id, post_id, created_at)
+		SELECT COALESCE(parent.author_id, p.author_id), $1, 'reply', $2, p.id, $5
+		FROM posts p LEFT JOIN comments parent ON parent.id = $4
+		WHERE p.id = $3 AND COALESCE(parent.author_id, p.author_id) <> $2
+		ON CONFLICT DO NOTHING`,
+		comment.ID, comment.AuthorID, comment.PostID, comment.ParentID, comment.CreatedAt)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to record reply", err)
+	}
+	return nil
+}
+
+// recordMentions adds a comment or post to the inbox of each user it
+// mentions, other than its author. contentID is the comment, or the post
+// itself. Users already notified of it, by an earlier version or as the
+// replied-to author, aren't notified again.
+func recordMentions(ctx context.Context, tx *sqlx.Tx, contentID, authorID, postID uuid.UUID, createdAt time.Time, texts ...string) error {
+	names := mentionedNames(texts...)
+	if len(names) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO inbox_notifications (user_id, content_id, type, from_id, post_id, created_at)
+		SELECT id, $1, 'mention', $2, $3, $4 FROM users
+		WHERE lower(username) = ANY($5) AND id <> $2
+		ON CONFLICT DO NOTHING`,
+		contentID, authorID, postID, createdAt, pq.Array(names))
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to record mentions", err)
+	}
+	return nil
+}
+
+// markInboxItemRead marks a reply or mention read for the user it's for,
+// taking it out of their inbox.
+func markInboxItemRead(ctx context.Context, tx *sqlx.Tx, userID, contentID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE inbox_notifications SET read_at = NOW()
+		WHERE user_id = $1 AND content_id = $2 AND read_at IS NULL`, userID, contentID)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to mark inbox item read", err)
+	}
+	return nil
+}
+
+// GetInbox returns a page of a user's inbox: unread direct messages, and the
+// comments on their posts, replies to their comments and posts or comments
+// that mention them they haven't answered yet, newest first.
+func (p *PostgresDB) GetInbox(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*models.InboxItem, error) {
+	items := []*models.InboxItem{}
+	query := inboxItems + ` ORDER BY created_at DESC, id LIMIT $2 OFFSET $3`
+	if err := p.DB.SelectContext(ctx, &items, query, userID, limit, offset); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to fetch inbox", err)
+	}
+	return items, nil
+}
+
+// GetInboxItem returns the entry of a user's inbox for the message, comment
+// or post id, if it's still there.
+func (p *PostgresDB) GetInboxItem(ctx context.Context, userID, id uuid.UUID) (*models.InboxItem, error) {
+	var item models.InboxItem
+	err := p.DB.GetContext(ctx, &item, `SELECT * FROM (`+inboxItems+`) inbox WHERE id = $2 LIMIT 1`, userID, id)
+	if err == sql.ErrNoRows {
+		return nil, utils.NewAppError(utils.ErrNotFound, "inbox item not found", nil)
+	}
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to fetch inbox item", err)
+	}
+	return &item, nil
+}
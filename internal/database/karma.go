@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+)
+
+// karmaColumn is the users column counting karma earned by contentType.
+func karmaColumn(contentType models.VoteContentType) string {
+	if contentType == models.CommentVote {
+		return "comment_karma"
+	}
+	return "post_karma"
+}
+
+// leaderboardColumns are the karma columns users can be ranked by.
+var leaderboardColumns = map[models.Leaderboard]string{
+	models.LeaderboardKarma:        "karma",
+	models.LeaderboardPostKarma:    "post_karma",
+	models.LeaderboardCommentKarma: "comment_karma",
+}
+
+// GetKarmaLeaderboard lists users with the most karma of the given kind,
+// best first, skipping offset of them.
+func (p *PostgresDB) GetKarmaLeaderboard(ctx context.Context, by models.Leaderboard, limit, offset int) ([]*models.User, error) {
+	column, ok := leaderboardColumns[by]
+	if !ok {
+		return nil, utils.NewAppError(utils.ErrInvalidInput, "unknown leaderboard", nil)
+	}
+	query := `
+		SELECT id, username, email, password_hash, karma, post_karma, comment_karma, created_at, updated_at, is_connected, last_active, profile_image, karma_velocity, is_admin, email_verified, is_bot
+		FROM users
+		WHERE merged_into IS NULL
+		ORDER BY ` + column + ` DESC, id
+		LIMIT $1 OFFSET $2
+	`
+	users := []*models.User{}
+	if err := p.DB.SelectContext(ctx, &users, query, limit, offset); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to get karma leaderboard", err)
+	}
+	return users, nil
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// Uploaded files live in storage; the media table records each one, so
+// posts and profiles can refer to it by ID and only its uploader can use it.
+
+// Post media columns and the join they need, for queries over posts p
+const (
+	postMediaColumns = `p.media_id, m.key AS media_key`
+	postMediaJoin    = `LEFT JOIN media m ON m.id = p.media_id`
+)
+
+// CreateMedia records an uploaded file.
+func (p *PostgresDB) CreateMedia(ctx context.Context, media *models.Media) error {
+	query := `
+		INSERT INTO media (id, owner_id, key, content_type, size_bytes, width, height)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`
+	err := p.DB.QueryRowxContext(ctx, query, media.ID, media.OwnerID, media.Key, media.ContentType,
+		media.Size, media.Width, media.Height).Scan(&media.CreatedAt)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to record media", err)
+	}
+	return nil
+}
+
+// GetMedia fetches an uploaded file's record.
+func (p *PostgresDB) GetMedia(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	var media models.Media
+	err := p.DB.GetContext(ctx, &media, `
+		SELECT id, owner_id, key, content_type, size_bytes, width, height, created_at
+		FROM media WHERE id = $1
+	`, id)
+	if err == sql.ErrNoRows {
+		return nil, utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("media %s not found", id), err)
+	}
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to get media", err)
+	}
+	return &media, nil
+}
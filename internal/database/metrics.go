@@ -0,0 +1,25 @@
+package database
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics for the database circuit breaker, exposed on /metrics.
+var (
+	breakerStateGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gator_db_breaker_state",
+		Help: "Database circuit breaker state: 0 closed, 1 open, 2 half-open.",
+	})
+
+	breakerRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gator_db_breaker_rejected_total",
+		Help: "Database calls rejected without running because the circuit breaker was open.",
+	})
+)
+
+// Prometheus metrics for the change feed.
+var changesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gator_db_changes_received_total",
+	Help: "Row changes from other instances or tools received on the change feed, by table and op.",
+}, []string{"table", "op"})
@@ -0,0 +1,251 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations are the SQL files in migrations/, named
+// <version>_<name>.up.sql with an optional matching .down.sql. Versions are
+// applied in order, each in its own transaction, and recorded in
+// schema_migrations. To change the schema, add a file with the next version;
+// never edit one that has been released.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the advisory lock migrating holds, so instances starting
+// together don't apply the same migration twice
+const migrationLockID = 7_406_247_731
+
+// Migration is one version of the schema.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string // Empty if the migration can't be rolled back
+}
+
+// MigrationStatus is a migration and when it was applied.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time // Nil while pending
+}
+
+// String is the migration's file name stem, e.g. 0001_baseline.
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// loadMigrations reads the embedded migrations in version order.
+func loadMigrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		base := strings.TrimPrefix(file, "migrations/")
+		stem, direction, ok := strings.Cut(strings.TrimSuffix(base, ".sql"), ".")
+		versionText, name, hasName := strings.Cut(stem, "_")
+		version, err := strconv.Atoi(versionText)
+		if !ok || !hasName || err != nil || version <= 0 || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration file %s isn't named <version>_<name>.up.sql or .down.sql", base)
+		}
+		data, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %s has no up file", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// withMigrationLock creates schema_migrations if needed and runs fn while
+// holding the migration lock, with the versions applied so far.
+func (p *PostgresDB) withMigrationLock(ctx context.Context, fn func(applied map[int]time.Time) error) error {
+	conn, err := p.DB.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to take the migration lock: %v", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+	applied, err := p.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	return fn(applied)
+}
+
+// appliedMigrations returns when each applied version was applied.
+func (p *PostgresDB) appliedMigrations(ctx context.Context) (map[int]time.Time, error) {
+	var rows []struct {
+		Version   int       `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	if err := p.DB.SelectContext(ctx, &rows, `SELECT version, applied_at FROM schema_migrations`); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+// runMigration runs one direction of a migration and records it.
+func (p *PostgresDB) runMigration(ctx context.Context, m Migration, up bool) error {
+	tx, err := p.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	script, record, args := m.Down, `DELETE FROM schema_migrations WHERE version = $1`, []interface{}{m.Version}
+	if up {
+		script, record, args = m.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, []interface{}{m.Version, m.Name}
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Migrate applies every pending migration in order, and returns the ones it
+// applied. It stops at the first that fails, leaving it pending.
+func (p *PostgresDB) Migrate(ctx context.Context) ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	err = p.withMigrationLock(ctx, func(applied map[int]time.Time) error {
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := p.runMigration(ctx, m, true); err != nil {
+				return fmt.Errorf("migration %s failed: %v", m, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Rollback reverts the last steps applied migrations, newest first, and
+// returns the ones it reverted. A migration without a down file stops it.
+func (p *PostgresDB) Rollback(ctx context.Context, steps int) ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	err = p.withMigrationLock(ctx, func(applied map[int]time.Time) error {
+		for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %s can't be rolled back", m)
+			}
+			if err := p.runMigration(ctx, m, false); err != nil {
+				return fmt.Errorf("rolling back migration %s failed: %v", m, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// MigrationStatuses lists every migration this version knows, in order,
+// with when it was applied. It changes nothing, so a database that was never
+// migrated shows every migration pending.
+func (p *PostgresDB) MigrationStatuses(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if err := p.DB.GetContext(ctx, &exists, `SELECT to_regclass('schema_migrations') IS NOT NULL`); err != nil {
+		return nil, fmt.Errorf("failed to look for schema_migrations: %v", err)
+	}
+	applied := map[int]time.Time{}
+	if exists {
+		if applied, err = p.appliedMigrations(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i].Migration = m
+		if at, ok := applied[m.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
+
+// PendingMigrations lists the migrations the database hasn't applied yet.
+func (p *PostgresDB) PendingMigrations(ctx context.Context) ([]Migration, error) {
+	statuses, err := p.MigrationStatuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,11 @@
+-- Drops everything. Only useful on a development database.
+
+DROP FUNCTION IF EXISTS gator_notify_change() CASCADE;
+
+DROP TABLE IF EXISTS
+	reports, subreddit_exports, bot_accounts, media, subreddit_flairs,
+	refresh_tokens, post_revisions, subreddit_moderators, user_preferences,
+	pending_posts, pending_comments, subreddit_settings, tenants,
+	scheduled_tasks, trending_subreddits, audit_log, jobs, messages,
+	post_views, votes, comments, posts, subreddit_members, subreddits, users
+CASCADE;
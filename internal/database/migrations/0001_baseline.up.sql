@@ -0,0 +1,453 @@
+-- The schema as InitializeTables left it before versioned migrations. Every
+-- statement is idempotent, so databases set up by earlier versions adopt it
+-- without changes.
+
+-- Users table
+CREATE TABLE IF NOT EXISTS users (
+	id UUID PRIMARY KEY,
+	username VARCHAR(50) UNIQUE NOT NULL,
+	email VARCHAR(100) UNIQUE NOT NULL,
+	password_hash VARCHAR(100) NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	karma INTEGER DEFAULT 0,
+	is_connected BOOLEAN DEFAULT FALSE NOT NULL,
+	last_active TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	bio TEXT,
+	profile_image VARCHAR(255)
+);
+
+-- Subreddits table
+CREATE TABLE IF NOT EXISTS subreddits (
+	id UUID PRIMARY KEY,
+	name VARCHAR(50) UNIQUE NOT NULL,
+	description TEXT,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	created_by UUID REFERENCES users(id),
+	rules JSONB,
+	member_count INTEGER DEFAULT 0
+);
+
+-- Subreddit members table
+CREATE TABLE IF NOT EXISTS subreddit_members (
+	subreddit_id UUID REFERENCES subreddits(id),
+	user_id UUID REFERENCES users(id),
+	joined_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	PRIMARY KEY (subreddit_id, user_id)
+);
+
+-- Posts table
+CREATE TABLE IF NOT EXISTS posts (
+	id UUID PRIMARY KEY,
+	title VARCHAR(300) NOT NULL,
+	content TEXT,
+	author_id UUID REFERENCES users(id),
+	subreddit_id UUID REFERENCES subreddits(id),
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	karma INTEGER DEFAULT 0,
+	upvotes INTEGER DEFAULT 0,
+	downvotes INTEGER DEFAULT 0,
+	comment_count INTEGER DEFAULT 0
+);
+
+-- Link post columns, added after the posts table was first deployed
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS url TEXT;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS thumbnail_url TEXT;
+
+-- Lets an author close their post to new comments
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS locked_by_author BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Post metadata: original content flag, attribution and license
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS original_content BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS source_attribution TEXT;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS license TEXT;
+
+-- Comments table
+CREATE TABLE IF NOT EXISTS comments (
+	id UUID PRIMARY KEY,
+	content TEXT NOT NULL,
+	author_id UUID REFERENCES users(id),
+	post_id UUID REFERENCES posts(id),
+	parent_id UUID REFERENCES comments(id),
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	karma INTEGER DEFAULT 0,
+	upvotes INTEGER DEFAULT 0,
+	downvotes INTEGER DEFAULT 0
+);
+
+-- Votes table
+CREATE TABLE IF NOT EXISTS votes (
+	id UUID PRIMARY KEY,
+	user_id UUID REFERENCES users(id),
+	content_id UUID NOT NULL,
+	content_type VARCHAR(20) NOT NULL,
+	vote_type INTEGER NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	UNIQUE(user_id, content_id, content_type)
+);
+
+-- Post views table (which posts a user has been served or opened)
+CREATE TABLE IF NOT EXISTS post_views (
+	user_id UUID REFERENCES users(id),
+	post_id UUID REFERENCES posts(id) ON DELETE CASCADE,
+	seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	PRIMARY KEY (user_id, post_id)
+);
+
+-- Messages table
+CREATE TABLE IF NOT EXISTS messages (
+	id UUID PRIMARY KEY,
+	sender_id UUID REFERENCES users(id),
+	receiver_id UUID REFERENCES users(id),
+	content TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	read_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Delivery receipts, tracked separately from reads
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP WITH TIME ZONE;
+
+-- Jobs table (background job queue)
+CREATE TABLE IF NOT EXISTS jobs (
+	id UUID PRIMARY KEY,
+	type VARCHAR(100) NOT NULL,
+	payload JSONB NOT NULL DEFAULT '{}',
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL DEFAULT 5,
+	unique_key TEXT,
+	run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	locked_until TIMESTAMP WITH TIME ZONE,
+	last_error TEXT,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS jobs_pending_run_at ON jobs (run_at) WHERE status = 'pending';
+CREATE UNIQUE INDEX IF NOT EXISTS jobs_active_unique_key ON jobs (unique_key) WHERE status IN ('pending', 'running');
+
+-- Admin flag and audit log
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE TABLE IF NOT EXISTS audit_log (
+	id UUID PRIMARY KEY,
+	actor_id UUID NOT NULL REFERENCES users(id),
+	subject_id UUID REFERENCES users(id),
+	action VARCHAR(100) NOT NULL,
+	details JSONB,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS audit_log_subject ON audit_log (subject_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_created ON audit_log (created_at DESC);
+
+-- Soft delete markers. Deleted rows are hidden from reads until restored
+-- or purged by the retention tasks.
+ALTER TABLE subreddits ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS subreddits_deleted_at ON subreddits (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS posts_deleted_at ON posts (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS comments_deleted_at ON comments (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Direct replies to each comment, counted as they're saved, deleted and
+-- restored. Existing rows are filled in by counter reconciliation.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS reply_count INTEGER NOT NULL DEFAULT 0;
+
+-- Columns and tables maintained by scheduled tasks
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS hot_score DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS karma_velocity DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS karma_snapshot INTEGER;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS karma_snapshot_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS posts_hot_score ON posts (hot_score DESC);
+CREATE TABLE IF NOT EXISTS trending_subreddits (
+	subreddit_id UUID PRIMARY KEY REFERENCES subreddits(id) ON DELETE CASCADE,
+	score DOUBLE PRECISION NOT NULL,
+	post_count INTEGER NOT NULL,
+	comment_count INTEGER NOT NULL,
+	refreshed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE TABLE IF NOT EXISTS scheduled_tasks (
+	name VARCHAR(100) PRIMARY KEY,
+	locked_until TIMESTAMP WITH TIME ZONE,
+	last_started_at TIMESTAMP WITH TIME ZONE,
+	last_finished_at TIMESTAMP WITH TIME ZONE,
+	last_error TEXT
+);
+
+-- Hosted communities. The default tenant owns everything created before
+-- multi-tenancy and any request that matches no other tenant.
+CREATE TABLE IF NOT EXISTS tenants (
+	id UUID PRIMARY KEY,
+	slug VARCHAR(50) UNIQUE NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	hostname VARCHAR(255) UNIQUE,
+	status VARCHAR(20) NOT NULL DEFAULT 'active',
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+INSERT INTO tenants (id, slug, name) VALUES ('00000000-0000-0000-0000-000000000001', 'default', 'Gator Swamp')
+ON CONFLICT (id) DO NOTHING;
+
+-- Subreddit submission settings, and comments held for moderator approval
+CREATE TABLE IF NOT EXISTS subreddit_settings (
+	subreddit_id UUID PRIMARY KEY REFERENCES subreddits(id) ON DELETE CASCADE,
+	allowed_post_types VARCHAR(10) NOT NULL DEFAULT 'any',
+	min_account_age_days INTEGER NOT NULL DEFAULT 0,
+	min_karma INTEGER NOT NULL DEFAULT 0,
+	comments_require_approval BOOLEAN NOT NULL DEFAULT FALSE,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE TABLE IF NOT EXISTS pending_comments (
+	id UUID PRIMARY KEY,
+	content TEXT NOT NULL,
+	author_id UUID REFERENCES users(id),
+	post_id UUID REFERENCES posts(id) ON DELETE CASCADE,
+	parent_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS pending_comments_post ON pending_comments (post_id, created_at);
+
+-- Approval thresholds for new posts, and posts held for approval
+ALTER TABLE subreddit_settings ADD COLUMN IF NOT EXISTS post_approval_karma INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE subreddit_settings ADD COLUMN IF NOT EXISTS post_approval_account_age_days INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS pending_posts (
+	id UUID PRIMARY KEY,
+	title VARCHAR(300) NOT NULL,
+	content TEXT,
+	author_id UUID REFERENCES users(id),
+	subreddit_id UUID REFERENCES subreddits(id) ON DELETE CASCADE,
+	url TEXT,
+	original_content BOOLEAN NOT NULL DEFAULT FALSE,
+	source_attribution TEXT,
+	license TEXT,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS pending_posts_subreddit ON pending_posts (subreddit_id, created_at);
+
+-- NSFW and quarantined subreddits, and the preferences that opt in to them
+ALTER TABLE subreddits ADD COLUMN IF NOT EXISTS nsfw BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE subreddits ADD COLUMN IF NOT EXISTS quarantined BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE TABLE IF NOT EXISTS user_preferences (
+	user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	show_nsfw BOOLEAN NOT NULL DEFAULT FALSE,
+	show_quarantined BOOLEAN NOT NULL DEFAULT FALSE,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Full-text search of posts, comments and subreddits. The expressions must
+-- match the search vectors in search.go for the indexes to be used.
+CREATE INDEX IF NOT EXISTS posts_search ON posts USING GIN (to_tsvector('english', title || ' ' || COALESCE(content, '')));
+CREATE INDEX IF NOT EXISTS comments_search ON comments USING GIN (to_tsvector('english', content));
+CREATE INDEX IF NOT EXISTS subreddits_search ON subreddits USING GIN (to_tsvector('english', name || ' ' || COALESCE(description, '')));
+
+-- Profanity masking switches, on unless turned off
+ALTER TABLE subreddit_settings ADD COLUMN IF NOT EXISTS mask_profanity BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS mask_profanity BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Checked by policies that require a verified email
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- When the author last edited a post's title or content
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP WITH TIME ZONE;
+
+-- Tombstones of accounts merged into another (see account_merge.go)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS merged_into UUID REFERENCES users(id);
+
+-- Karma split by what earned it, kept up by RecordVote. Existing rows are
+-- filled in by counter reconciliation.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS post_karma INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS comment_karma INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS users_karma ON users (karma DESC, id) WHERE merged_into IS NULL;
+CREATE INDEX IF NOT EXISTS users_post_karma ON users (post_karma DESC, id) WHERE merged_into IS NULL;
+CREATE INDEX IF NOT EXISTS users_comment_karma ON users (comment_karma DESC, id) WHERE merged_into IS NULL;
+
+-- Moderators appointed by a subreddit's creator, and posts they locked
+CREATE TABLE IF NOT EXISTS subreddit_moderators (
+	subreddit_id UUID NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	added_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	added_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	PRIMARY KEY (subreddit_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_subreddit_moderators_user ON subreddit_moderators(user_id);
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS locked_by_moderator BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Earlier versions of edited posts
+CREATE TABLE IF NOT EXISTS post_revisions (
+	id BIGSERIAL PRIMARY KEY,
+	post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+	title VARCHAR(300) NOT NULL,
+	content TEXT NOT NULL,
+	written_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	replaced_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS post_revisions_post ON post_revisions (post_id, replaced_at);
+
+-- Replies to a comment in order, for comment threads
+CREATE INDEX IF NOT EXISTS comments_thread ON comments (post_id, parent_id, created_at, id);
+
+-- Refresh tokens, by hash (see refresh_tokens.go)
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	token_hash TEXT PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	revoked_at TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id);
+
+-- Flairs subreddits define for their posts (see flairs.go)
+CREATE TABLE IF NOT EXISTS subreddit_flairs (
+	id UUID PRIMARY KEY,
+	subreddit_id UUID NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+	text VARCHAR(64) NOT NULL,
+	color VARCHAR(7),
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS subreddit_flairs_text ON subreddit_flairs (subreddit_id, lower(text));
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS flair_id UUID REFERENCES subreddit_flairs(id) ON DELETE SET NULL;
+ALTER TABLE pending_posts ADD COLUMN IF NOT EXISTS flair_id UUID REFERENCES subreddit_flairs(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS posts_flair ON posts (flair_id, created_at) WHERE flair_id IS NOT NULL;
+
+-- Uploaded media, which posts can show
+CREATE TABLE IF NOT EXISTS media (
+	id UUID PRIMARY KEY,
+	owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	key TEXT NOT NULL,
+	content_type VARCHAR(64) NOT NULL,
+	size_bytes INTEGER NOT NULL,
+	width INTEGER NOT NULL,
+	height INTEGER NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS media_owner ON media (owner_id, created_at);
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS media_id UUID REFERENCES media(id) ON DELETE SET NULL;
+ALTER TABLE pending_posts ADD COLUMN IF NOT EXISTS media_id UUID REFERENCES media(id) ON DELETE SET NULL;
+
+-- Unread messages per recipient, for unread counts and the inbox
+CREATE INDEX IF NOT EXISTS messages_unread ON messages (receiver_id) WHERE read_at IS NULL;
+
+-- Username prefix search when listing users
+CREATE INDEX IF NOT EXISTS users_username_prefix ON users (lower(username) text_pattern_ops);
+
+-- Posts per subreddit, kept as posts are created, deleted and restored.
+-- Subreddits still at 0 are recounted, which fills in ones from before
+-- the column.
+ALTER TABLE subreddits ADD COLUMN IF NOT EXISTS post_count INTEGER NOT NULL DEFAULT 0;
+UPDATE subreddits s SET post_count = c.n
+FROM (SELECT subreddit_id, COUNT(*) AS n FROM posts WHERE deleted_at IS NULL GROUP BY subreddit_id) c
+WHERE s.id = c.subreddit_id AND s.post_count = 0;
+
+-- Newest first listings, paged by keyset (created_at, id)
+CREATE INDEX IF NOT EXISTS posts_created ON posts (created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS posts_subreddit_created ON posts (subreddit_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS users_created ON users (created_at DESC, id DESC);
+
+-- Bot accounts and their scopes (see bots.go). The flag on users marks
+-- their content without a join.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE TABLE IF NOT EXISTS bot_accounts (
+	user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	subreddit_ids UUID[] NOT NULL DEFAULT '{}',
+	created_by UUID NOT NULL REFERENCES users(id),
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Subreddit exports (see exports.go). The partial index allows one
+-- unfinished export per subreddit.
+CREATE TABLE IF NOT EXISTS subreddit_exports (
+	id UUID PRIMARY KEY,
+	subreddit_id UUID NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+	requested_by UUID NOT NULL REFERENCES users(id),
+	status VARCHAR(16) NOT NULL,
+	key TEXT,
+	size_bytes BIGINT NOT NULL DEFAULT 0,
+	post_count INTEGER NOT NULL DEFAULT 0,
+	comment_count INTEGER NOT NULL DEFAULT 0,
+	error TEXT,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	completed_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS subreddit_exports_subreddit ON subreddit_exports (subreddit_id, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS subreddit_exports_unfinished ON subreddit_exports (subreddit_id)
+	WHERE status IN ('pending', 'running');
+
+-- Reports of posts, comments and users (see reports.go). The partial
+-- index allows one open report per reporter and target.
+CREATE TABLE IF NOT EXISTS reports (
+	id UUID PRIMARY KEY,
+	reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	target_type VARCHAR(16) NOT NULL,
+	target_id UUID NOT NULL,
+	subreddit_id UUID NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+	reason TEXT NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'open',
+	resolved_by UUID REFERENCES users(id),
+	note TEXT,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	resolved_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS reports_queue ON reports (subreddit_id, status, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS reports_open_once ON reports (reporter_id, target_type, target_id)
+	WHERE status = 'open';
+
+-- Change notifications for other instances' caches, published on
+-- ChangeChannel (see changes.go). Only columns those caches hold fire the
+-- update triggers, so presence updates, hot score decay and counter
+-- reconciliation stay quiet. Deleting rows that were already soft-deleted
+-- (the retention purge) isn't published either.
+CREATE OR REPLACE FUNCTION gator_notify_change() RETURNS trigger AS $$
+DECLARE
+	rec JSONB;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		rec := to_jsonb(OLD);
+		IF rec ->> 'deleted_at' IS NOT NULL THEN
+			RETURN NULL;
+		END IF;
+	ELSE
+		rec := to_jsonb(NEW);
+	END IF;
+	PERFORM pg_notify('gator_changes', json_build_object(
+		'table', TG_TABLE_NAME,
+		'op', lower(TG_OP),
+		'id', rec ->> TG_ARGV[0],
+		'parentId', rec ->> TG_ARGV[1],
+		'origin', current_setting('application_name')
+	)::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS posts_notify_change ON posts;
+CREATE TRIGGER posts_notify_change
+	AFTER INSERT OR DELETE OR UPDATE OF author_id, title, content, url, thumbnail_url, locked_by_author, locked_by_moderator, original_content, source_attribution, license, flair_id, media_id, deleted_at, karma, upvotes, downvotes ON posts
+	FOR EACH ROW EXECUTE FUNCTION gator_notify_change('id', 'subreddit_id');
+
+DROP TRIGGER IF EXISTS comments_notify_change ON comments;
+CREATE TRIGGER comments_notify_change
+	AFTER INSERT OR DELETE OR UPDATE OF author_id, content, deleted_at, karma, upvotes, downvotes ON comments
+	FOR EACH ROW EXECUTE FUNCTION gator_notify_change('id', 'post_id');
+
+DROP TRIGGER IF EXISTS subreddits_notify_change ON subreddits;
+CREATE TRIGGER subreddits_notify_change
+	AFTER INSERT OR DELETE OR UPDATE OF name, description, member_count, post_count, nsfw, quarantined, deleted_at ON subreddits
+	FOR EACH ROW EXECUTE FUNCTION gator_notify_change('id');
+
+DROP TRIGGER IF EXISTS subreddit_members_notify_change ON subreddit_members;
+CREATE TRIGGER subreddit_members_notify_change
+	AFTER INSERT OR DELETE ON subreddit_members
+	FOR EACH ROW EXECUTE FUNCTION gator_notify_change('subreddit_id', 'user_id');
+
+DROP TRIGGER IF EXISTS tenants_notify_change ON tenants;
+CREATE TRIGGER tenants_notify_change
+	AFTER INSERT OR DELETE OR UPDATE ON tenants
+	FOR EACH ROW EXECUTE FUNCTION gator_notify_change('id');
+
+DROP TRIGGER IF EXISTS users_notify_change ON users;
+CREATE TRIGGER users_notify_change
+	AFTER DELETE OR UPDATE OF username, email, password_hash, karma, bio, profile_image, is_admin, email_verified, is_bot ON users
+	FOR EACH ROW EXECUTE FUNCTION gator_notify_change('id');
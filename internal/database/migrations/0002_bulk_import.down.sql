@@ -0,0 +1,24 @@
+DROP TABLE IF EXISTS external_ids;
+
+CREATE OR REPLACE FUNCTION gator_notify_change() RETURNS trigger AS $$
+DECLARE
+	rec JSONB;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		rec := to_jsonb(OLD);
+		IF rec ->> 'deleted_at' IS NOT NULL THEN
+			RETURN NULL;
+		END IF;
+	ELSE
+		rec := to_jsonb(NEW);
+	END IF;
+	PERFORM pg_notify('gator_changes', json_build_object(
+		'table', TG_TABLE_NAME,
+		'op', lower(TG_OP),
+		'id', rec ->> TG_ARGV[0],
+		'parentId', rec ->> TG_ARGV[1],
+		'origin', current_setting('application_name')
+	)::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,39 @@
+-- Where imported rows came from (see bulk.go), by their ID in the source.
+CREATE TABLE IF NOT EXISTS external_ids (
+	source VARCHAR(32) NOT NULL,
+	kind VARCHAR(16) NOT NULL,
+	external_id TEXT NOT NULL,
+	id UUID NOT NULL,
+	imported_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	PRIMARY KEY (source, kind, external_id)
+);
+CREATE INDEX IF NOT EXISTS external_ids_id ON external_ids (id);
+
+-- Bulk loads set gator.bulk_load in their transaction, so imported rows
+-- aren't published one by one. Rows no instance has cached need no
+-- notification, and counters are reconciled after the load.
+CREATE OR REPLACE FUNCTION gator_notify_change() RETURNS trigger AS $$
+DECLARE
+	rec JSONB;
+BEGIN
+	IF current_setting('gator.bulk_load', true) = 'on' THEN
+		RETURN NULL;
+	END IF;
+	IF TG_OP = 'DELETE' THEN
+		rec := to_jsonb(OLD);
+		IF rec ->> 'deleted_at' IS NOT NULL THEN
+			RETURN NULL;
+		END IF;
+	ELSE
+		rec := to_jsonb(NEW);
+	END IF;
+	PERFORM pg_notify('gator_changes', json_build_object(
+		'table', TG_TABLE_NAME,
+		'op', lower(TG_OP),
+		'id', rec ->> TG_ARGV[0],
+		'parentId', rec ->> TG_ARGV[1],
+		'origin', current_setting('application_name')
+	)::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
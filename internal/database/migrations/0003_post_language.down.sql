@@ -0,0 +1,3 @@
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS languages;
+ALTER TABLE pending_posts DROP COLUMN IF EXISTS language;
+ALTER TABLE posts DROP COLUMN IF EXISTS language;
@@ -0,0 +1,7 @@
+-- The language a post is written in, as detected by internal/language when
+-- it's posted or edited; NULL when it couldn't be told.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS language VARCHAR(8);
+ALTER TABLE pending_posts ADD COLUMN IF NOT EXISTS language VARCHAR(8);
+
+-- Languages a user wants their feeds in; empty means any.
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS languages TEXT[] NOT NULL DEFAULT '{}';
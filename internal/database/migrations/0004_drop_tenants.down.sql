@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS tenants (
+	id UUID PRIMARY KEY,
+	slug VARCHAR(50) UNIQUE NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	hostname VARCHAR(255) UNIQUE,
+	status VARCHAR(20) NOT NULL DEFAULT 'active',
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+INSERT INTO tenants (id, slug, name) VALUES ('00000000-0000-0000-0000-000000000001', 'default', 'Gator Swamp')
+ON CONFLICT (id) DO NOTHING;
+
+DROP TRIGGER IF EXISTS tenants_notify_change ON tenants;
+CREATE TRIGGER tenants_notify_change
+	AFTER INSERT OR DELETE OR UPDATE ON tenants
+	FOR EACH ROW EXECUTE FUNCTION gator_notify_change('id');
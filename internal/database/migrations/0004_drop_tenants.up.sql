@@ -0,0 +1,2 @@
+-- Tenants were only ever a registry: no data was partitioned by them.
+DROP TABLE IF EXISTS tenants CASCADE;
@@ -0,0 +1,43 @@
+-- Replies and mentions, recorded for the user they're addressed to when the
+-- comment or post is written, so the inbox reads them by user instead of
+-- scanning every comment. content_id is the comment, or the post for a
+-- post mention.
+CREATE TABLE IF NOT EXISTS inbox_notifications (
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	content_id UUID NOT NULL,
+	type VARCHAR(10) NOT NULL CHECK (type IN ('reply', 'mention')),
+	from_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	read_at TIMESTAMP WITH TIME ZONE,
+	PRIMARY KEY (user_id, content_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbox_notifications_unread ON inbox_notifications (user_id, created_at DESC)
+	WHERE read_at IS NULL;
+
+-- Existing replies and mentions start out unread, as the inbox showed them
+-- before. A comment that is both is recorded as a reply.
+INSERT INTO inbox_notifications (user_id, content_id, type, from_id, post_id, created_at)
+SELECT COALESCE(parent.author_id, p.author_id), c.id, 'reply', c.author_id, c.post_id, c.created_at
+FROM comments c
+JOIN posts p ON p.id = c.post_id
+LEFT JOIN comments parent ON parent.id = c.parent_id
+WHERE COALESCE(parent.author_id, p.author_id) <> c.author_id
+ON CONFLICT DO NOTHING;
+
+INSERT INTO inbox_notifications (user_id, content_id, type, from_id, post_id, created_at)
+SELECT DISTINCT u.id, c.id, 'mention', c.author_id, c.post_id, c.created_at
+FROM comments c
+CROSS JOIN LATERAL regexp_matches(c.content, '(?:^|[^[:alnum:]_/])u/([[:alnum:]_-]+)', 'g') m
+JOIN users u ON lower(u.username) = lower(m[1])
+WHERE u.id <> c.author_id
+ON CONFLICT DO NOTHING;
+
+INSERT INTO inbox_notifications (user_id, content_id, type, from_id, post_id, created_at)
+SELECT DISTINCT u.id, p.id, 'mention', p.author_id, p.id, p.created_at
+FROM posts p
+CROSS JOIN LATERAL regexp_matches(p.title || E'\n' || p.content, '(?:^|[^[:alnum:]_/])u/([[:alnum:]_-]+)', 'g') m
+JOIN users u ON lower(u.username) = lower(m[1])
+WHERE u.id <> p.author_id
+ON CONFLICT DO NOTHING;
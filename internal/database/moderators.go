@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// AddModerator appoints userID to moderate a subreddit, reporting false if
+// they already do.
+func (p *PostgresDB) AddModerator(ctx context.Context, subredditID, userID, addedBy uuid.UUID) (bool, error) {
+	result, err := p.DB.ExecContext(ctx, `
+		INSERT INTO subreddit_moderators (subreddit_id, user_id, added_by) VALUES ($1, $2, $3)
+		ON CONFLICT (subreddit_id, user_id) DO NOTHING`, subredditID, userID, addedBy)
+	if err != nil {
+		return false, utils.NewAppError(utils.ErrDatabase, "failed to add moderator", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// RemoveModerator dismisses a subreddit's moderator, reporting false if
+// userID wasn't one.
+func (p *PostgresDB) RemoveModerator(ctx context.Context, subredditID, userID uuid.UUID) (bool, error) {
+	result, err := p.DB.ExecContext(ctx, `DELETE FROM subreddit_moderators WHERE subreddit_id = $1 AND user_id = $2`, subredditID, userID)
+	if err != nil {
+		return false, utils.NewAppError(utils.ErrDatabase, "failed to remove moderator", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// GetModerators lists a subreddit's appointed moderators, longest-serving
+// first.
+func (p *PostgresDB) GetModerators(ctx context.Context, subredditID uuid.UUID) ([]*models.Moderator, error) {
+	mods := []*models.Moderator{}
+	err := p.DB.SelectContext(ctx, &mods, `
+		SELECT m.subreddit_id, m.user_id, u.username, m.added_by, m.added_at
+		FROM subreddit_moderators m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.subreddit_id = $1
+		ORDER BY m.added_at`, subredditID)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to list moderators", err)
+	}
+	return mods, nil
+}
+
+// IsSubredditModerator reports whether userID was appointed to moderate a
+// subreddit.
+func (p *PostgresDB) IsSubredditModerator(ctx context.Context, subredditID, userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := p.DB.GetContext(ctx, &exists,
+		`SELECT EXISTS (SELECT 1 FROM subreddit_moderators WHERE subreddit_id = $1 AND user_id = $2)`, subredditID, userID)
+	if err != nil {
+		return false, utils.NewAppError(utils.ErrDatabase, "failed to check moderator", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// Comments in subreddits that require approval wait in pending_comments,
+// outside the comments table, so nothing that reads comments sees them
+// until a moderator approves one and it is saved as usual.
+
+// HoldComment queues a comment for moderator approval. The inbox item it
+// answers, if any, is marked read now: the author has answered it, whether
+// or not the comment is approved.
+func (p *PostgresDB) HoldComment(ctx context.Context, comment *models.Comment) error {
+	query := `
+		INSERT INTO pending_comments (id, content, author_id, post_id, parent_id, created_at)
+		VALUES (:id, :content, :author_id, :post_id, :parent_id, :created_at)
+	`
+	tx, err := p.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to begin transaction for hold comment", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.NamedExecContext(ctx, query, comment); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to hold comment for approval", err)
+	}
+	if comment.InboxItemID != nil {
+		if err := markInboxItemRead(ctx, tx, comment.AuthorID, *comment.InboxItemID); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to commit held comment", err)
+	}
+	return nil
+}
+
+// ListPendingComments returns the comments awaiting approval in a
+// subreddit, oldest first.
+func (p *PostgresDB) ListPendingComments(ctx context.Context, subredditID uuid.UUID) ([]*models.Comment, error) {
+	query := `
+		SELECT pc.id, pc.content, pc.author_id, u.username AS author_username, u.is_bot AS author_is_bot, pc.post_id,
+			p.subreddit_id, pc.parent_id, pc.created_at, pc.created_at AS updated_at
+		FROM pending_comments pc
+		JOIN posts p ON p.id = pc.post_id
+		JOIN users u ON u.id = pc.author_id
+		WHERE p.subreddit_id = $1
+		ORDER BY pc.created_at
+	`
+	comments := []*models.Comment{}
+	if err := p.DB.SelectContext(ctx, &comments, query, subredditID); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to list pending comments", err)
+	}
+	return comments, nil
+}
+
+// TakePendingComment removes a comment awaiting approval in a subreddit from
+// the queue and returns it, to be saved or discarded.
+func (p *PostgresDB) TakePendingComment(ctx context.Context, subredditID, id uuid.UUID) (*models.Comment, error) {
+	query := `
+		WITH taken AS (
+			DELETE FROM pending_comments
+			WHERE id = $1 AND post_id IN (SELECT id FROM posts WHERE subreddit_id = $2)
+			RETURNING *
+		)
+		SELECT id, content, author_id, post_id, $2 AS subreddit_id, parent_id, created_at, created_at AS updated_at
+		FROM taken
+	`
+	var comment models.Comment
+	err := p.DB.GetContext(ctx, &comment, query, id, subredditID)
+	if err == sql.ErrNoRows {
+		return nil, utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("pending comment %s not found", id), err)
+	}
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to take pending comment", err)
+	}
+	return &comment, nil
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// Like pending comments, posts held for approval wait in pending_posts so
+// feeds and listings never see them until a moderator approves one.
+
+// HoldPost queues a post for moderator approval.
+func (p *PostgresDB) HoldPost(ctx context.Context, post *models.Post) error {
+	query := `
+		INSERT INTO pending_posts (id, title, content, author_id, subreddit_id, url,
+			original_content, source_attribution, license, language, flair_id, media_id, created_at)
+		VALUES (:id, :title, :content, :author_id, :subreddit_id, :url,
+			:original_content, :source_attribution, :license, :language, :flair_id, :media_id, :created_at)
+	`
+	if _, err := p.DB.NamedExecContext(ctx, query, post); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to hold post for approval", err)
+	}
+	return nil
+}
+
+// ListPendingPosts returns the posts awaiting approval in a subreddit,
+// oldest first.
+func (p *PostgresDB) ListPendingPosts(ctx context.Context, subredditID uuid.UUID) ([]*models.Post, error) {
+	query := `
+		SELECT pp.id, pp.title, pp.content, pp.author_id, u.username AS author_username, u.is_bot AS author_is_bot,
+			pp.subreddit_id, s.name AS subreddit_name, pp.url,
+			pp.original_content, pp.source_attribution, pp.license, pp.language,
+			pp.flair_id, f.text AS flair_text, f.color AS flair_color,
+			pp.media_id, m.key AS media_key,
+			pp.created_at, pp.created_at AS updated_at
+		FROM pending_posts pp
+		JOIN users u ON u.id = pp.author_id
+		JOIN subreddits s ON s.id = pp.subreddit_id
+		LEFT JOIN subreddit_flairs f ON f.id = pp.flair_id
+		LEFT JOIN media m ON m.id = pp.media_id
+		WHERE pp.subreddit_id = $1
+		ORDER BY pp.created_at
+	`
+	posts := []*models.Post{}
+	if err := p.DB.SelectContext(ctx, &posts, query, subredditID); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to list pending posts", err)
+	}
+	return posts, nil
+}
+
+// TakePendingPost removes a post awaiting approval in a subreddit from the
+// queue and returns it, to be saved or discarded.
+func (p *PostgresDB) TakePendingPost(ctx context.Context, subredditID, id uuid.UUID) (*models.Post, error) {
+	query := `
+		WITH taken AS (
+			DELETE FROM pending_posts WHERE id = $1 AND subreddit_id = $2
+			RETURNING *
+		)
+		SELECT t.id, t.title, t.content, t.author_id, u.username AS author_username, u.is_bot AS author_is_bot,
+			t.subreddit_id, s.name AS subreddit_name, t.url,
+			t.original_content, t.source_attribution, t.license, t.language,
+			t.flair_id, f.text AS flair_text, f.color AS flair_color,
+			t.media_id, m.key AS media_key,
+			t.created_at, t.created_at AS updated_at
+		FROM taken t
+		JOIN users u ON u.id = t.author_id
+		JOIN subreddits s ON s.id = t.subreddit_id
+		LEFT JOIN subreddit_flairs f ON f.id = t.flair_id
+		LEFT JOIN media m ON m.id = t.media_id
+	`
+	var post models.Post
+	err := p.DB.GetContext(ctx, &post, query, id, subredditID)
+	if err == sql.ErrNoRows {
+		return nil, utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("pending post %s not found", id), err)
+	}
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to take pending post", err)
+	}
+	return &post, nil
+}
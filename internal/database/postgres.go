@@ -5,7 +5,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
 	"time"
 
 	"gator-swamp/internal/models"
@@ -16,55 +17,10 @@ import (
 	"github.com/lib/pq"
 )
 
-// DBAdapter defines the common interface for database operations.
-// It allows using PostgreSQL as the backend.
-type DBAdapter interface {
-	// Connection
-	Close(ctx context.Context) error
-
-	// User methods
-	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
-	GetUser(ctx context.Context, id uuid.UUID) (*models.User, error)
-	SaveUser(ctx context.Context, user *models.User) error
-	UpdateUserActivity(ctx context.Context, id uuid.UUID, active bool) error
-	UpdateUserSubreddits(ctx context.Context, userID uuid.UUID, subID uuid.UUID, join bool) error
-	GetAllUsers(ctx context.Context) ([]*models.User, error)
-	// TODO: Consider adding UpdateUserKarma directly?
-
-	// Subreddit methods
-	CreateSubreddit(ctx context.Context, sub *models.Subreddit) error
-	GetSubredditByID(ctx context.Context, id uuid.UUID) (*models.Subreddit, error)
-	GetSubredditByName(ctx context.Context, name string) (*models.Subreddit, error)
-	GetAllSubreddits(ctx context.Context) ([]*models.Subreddit, error)
-	UpdateSubredditMemberCount(ctx context.Context, subID uuid.UUID, delta int) error
-	GetSubredditMemberIDs(ctx context.Context, subredditID uuid.UUID) ([]uuid.UUID, error)
-
-	// Post methods
-	SavePost(ctx context.Context, post *models.Post) error
-	GetPost(ctx context.Context, postID uuid.UUID, requestingUserID uuid.UUID) (*models.Post, error)
-	RecordVote(ctx context.Context, userID, contentID uuid.UUID, contentType models.VoteContentType, direction models.VoteDirection) error
-	GetRecentPosts(ctx context.Context, limit, offset int, requestingUserID uuid.UUID) ([]*models.Post, error)
-	GetUserFeed(ctx context.Context, userID uuid.UUID, limit, offset int, requestingUserID uuid.UUID) ([]*models.Post, error)
-	GetPostsBySubreddit(ctx context.Context, subredditID uuid.UUID, limit int, offset int) ([]*models.Post, error)
-	GetAllPosts(ctx context.Context) ([]*models.Post, error)
-
-	// Comment methods
-	SaveComment(ctx context.Context, comment *models.Comment) error
-	GetComment(ctx context.Context, id uuid.UUID) (*models.Comment, error)
-	GetPostComments(ctx context.Context, postID uuid.UUID, requestingUserID uuid.UUID) ([]*models.Comment, error)
-	DeleteCommentAndDecrementCount(ctx context.Context, commentID uuid.UUID) error
-	// UpdateCommentVotes(ctx context.Context, commentID uuid.UUID, upvotes int, downvotes int) error // Replaced by RecordVote
-	GetAllComments(ctx context.Context) ([]*models.Comment, error) // For handleLoadComments
-
-	// Message methods
-	SaveMessage(ctx context.Context, msg *models.DirectMessage) error
-	GetMessagesByUser(ctx context.Context, userID uuid.UUID) ([]*models.DirectMessage, error)
-	UpdateMessageStatus(ctx context.Context, msgID uuid.UUID, isRead *bool, isDeleted *bool) error
-}
-
 // PostgresDB represents a PostgreSQL database connection
 type PostgresDB struct {
-	DB *sqlx.DB
+	DB     *sqlx.DB
+	Logger *slog.Logger // Defaults to slog's default logger
 }
 
 // NewPostgresDB creates a new PostgreSQL database connection
@@ -84,146 +40,24 @@ func NewPostgresDB(connectionString string) (*PostgresDB, error) {
 		return nil, fmt.Errorf("failed to ping PostgreSQL: %v", err)
 	}
 
-	log.Println("Successfully connected to PostgreSQL!")
+	logger := slog.Default()
+	logger.Info("Connected to PostgreSQL")
 
 	return &PostgresDB{
-		DB: db,
+		DB:     db,
+		Logger: logger,
 	}, nil
 }
 
 // Close closes the database connection
 func (p *PostgresDB) Close(ctx context.Context) error {
-	log.Println("Closing PostgreSQL connection...")
+	p.Logger.InfoContext(ctx, "Closing PostgreSQL connection")
 	return p.DB.Close()
 }
 
-// InitializeTables creates all necessary tables if they don't exist
-func (p *PostgresDB) InitializeTables(ctx context.Context) error {
-	// Users table
-	_, err := p.DB.ExecContext(ctx, `
-		CREATE TABLE IF NOT EXISTS users (
-			id UUID PRIMARY KEY,
-			username VARCHAR(50) UNIQUE NOT NULL,
-			email VARCHAR(100) UNIQUE NOT NULL,
-			password_hash VARCHAR(100) NOT NULL,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			karma INTEGER DEFAULT 0,
-			is_connected BOOLEAN DEFAULT FALSE NOT NULL,
-			last_active TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			bio TEXT,
-			profile_image VARCHAR(255)
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create users table: %v", err)
-	}
-
-	// Subreddits table
-	_, err = p.DB.ExecContext(ctx, `
-		CREATE TABLE IF NOT EXISTS subreddits (
-			id UUID PRIMARY KEY,
-			name VARCHAR(50) UNIQUE NOT NULL,
-			description TEXT,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			created_by UUID REFERENCES users(id),
-			rules JSONB,
-			member_count INTEGER DEFAULT 0
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create subreddits table: %v", err)
-	}
-
-	// Subreddit members table
-	_, err = p.DB.ExecContext(ctx, `
-		CREATE TABLE IF NOT EXISTS subreddit_members (
-			subreddit_id UUID REFERENCES subreddits(id),
-			user_id UUID REFERENCES users(id),
-			joined_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			PRIMARY KEY (subreddit_id, user_id)
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create subreddit_members table: %v", err)
-	}
-
-	// Posts table
-	_, err = p.DB.ExecContext(ctx, `
-		CREATE TABLE IF NOT EXISTS posts (
-			id UUID PRIMARY KEY,
-			title VARCHAR(300) NOT NULL,
-			content TEXT,
-			author_id UUID REFERENCES users(id),
-			subreddit_id UUID REFERENCES subreddits(id),
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			karma INTEGER DEFAULT 0,
-			upvotes INTEGER DEFAULT 0,
-			downvotes INTEGER DEFAULT 0,
-			comment_count INTEGER DEFAULT 0
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create posts table: %v", err)
-	}
-
-	// Comments table
-	_, err = p.DB.ExecContext(ctx, `
-		CREATE TABLE IF NOT EXISTS comments (
-			id UUID PRIMARY KEY,
-			content TEXT NOT NULL,
-			author_id UUID REFERENCES users(id),
-			post_id UUID REFERENCES posts(id),
-			parent_id UUID REFERENCES comments(id),
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			karma INTEGER DEFAULT 0,
-			upvotes INTEGER DEFAULT 0,
-			downvotes INTEGER DEFAULT 0
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create comments table: %v", err)
-	}
-
-	// Votes table
-	_, err = p.DB.ExecContext(ctx, `
-		CREATE TABLE IF NOT EXISTS votes (
-			id UUID PRIMARY KEY,
-			user_id UUID REFERENCES users(id),
-			content_id UUID NOT NULL,
-			content_type VARCHAR(20) NOT NULL,
-			vote_type INTEGER NOT NULL,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			UNIQUE(user_id, content_id, content_type)
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create votes table: %v", err)
-	}
-
-	// Messages table
-	_, err = p.DB.ExecContext(ctx, `
-		CREATE TABLE IF NOT EXISTS messages (
-			id UUID PRIMARY KEY,
-			sender_id UUID REFERENCES users(id),
-			receiver_id UUID REFERENCES users(id),
-			content TEXT NOT NULL,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			read_at TIMESTAMP WITH TIME ZONE
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create messages table: %v", err)
-	}
-
-	return nil
-}
-
 // GetUserByEmail fetches a user by their email address.
 func (p *PostgresDB) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	query := `SELECT id, username, email, password_hash, karma, created_at, updated_at, is_connected, last_active FROM users WHERE email = $1`
+	query := `SELECT id, username, email, password_hash, karma, post_karma, comment_karma, created_at, updated_at, is_connected, last_active, profile_image, karma_velocity, is_admin, email_verified, is_bot FROM users WHERE email = $1`
 	var user models.User
 	err := p.DB.GetContext(ctx, &user, query, email)
 	if err != nil {
@@ -238,7 +72,7 @@ func (p *PostgresDB) GetUserByEmail(ctx context.Context, email string) (*models.
 // GetUser fetches a user by their ID.
 func (p *PostgresDB) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	// First fetch basic user info
-	query := `SELECT id, username, email, password_hash, karma, created_at, updated_at, is_connected, last_active FROM users WHERE id = $1`
+	query := `SELECT id, username, email, password_hash, karma, post_karma, comment_karma, created_at, updated_at, is_connected, last_active, profile_image, karma_velocity, is_admin, email_verified, is_bot FROM users WHERE id = $1`
 	var user models.User
 	err := p.DB.GetContext(ctx, &user, query, id)
 	if err != nil {
@@ -262,6 +96,24 @@ func (p *PostgresDB) GetUser(ctx context.Context, id uuid.UUID) (*models.User, e
 	return &user, nil
 }
 
+// GetUserEmails returns the current email of each of ids. Users that were
+// deleted or merged into another account are left out.
+func (p *PostgresDB) GetUserEmails(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	var rows []struct {
+		ID    uuid.UUID `db:"id"`
+		Email string    `db:"email"`
+	}
+	query := `SELECT id, email FROM users WHERE id = ANY($1) AND merged_into IS NULL`
+	if err := p.DB.SelectContext(ctx, &rows, query, pq.Array(ids)); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query user emails", err)
+	}
+	emails := make(map[uuid.UUID]string, len(rows))
+	for _, row := range rows {
+		emails[row.ID] = row.Email
+	}
+	return emails, nil
+}
+
 // SaveUser inserts a new user into the database.
 func (p *PostgresDB) SaveUser(ctx context.Context, user *models.User) error {
 	// Ensure UpdatedAt and CreatedAt are set
@@ -341,9 +193,26 @@ func (p *PostgresDB) UpdateUserSubreddits(ctx context.Context, userID uuid.UUID,
 	return nil
 }
 
+// UpdateUserProfileImage points the user's profile image at a stored avatar.
+func (p *PostgresDB) UpdateUserProfileImage(ctx context.Context, id uuid.UUID, key string) error {
+	query := `UPDATE users SET profile_image = $1, updated_at = NOW() WHERE id = $2`
+	result, err := p.DB.ExecContext(ctx, query, key, id)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to update profile image", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to get rows affected after update", err)
+	}
+	if rowsAffected == 0 {
+		return utils.NewAppError(utils.ErrNotFound, "user not found for profile image update", nil)
+	}
+	return nil
+}
+
 // GetAllUsers fetches all users from the database.
 func (p *PostgresDB) GetAllUsers(ctx context.Context) ([]*models.User, error) {
-	query := `SELECT id, username, email, password_hash, karma, created_at, updated_at, is_connected, last_active FROM users ORDER BY created_at DESC`
+	query := `SELECT id, username, email, password_hash, karma, post_karma, comment_karma, created_at, updated_at, is_connected, last_active, profile_image, karma_velocity, is_admin, email_verified, is_bot FROM users WHERE merged_into IS NULL ORDER BY created_at DESC`
 	users := []*models.User{}
 	err := p.DB.SelectContext(ctx, &users, query)
 	if err != nil {
@@ -352,6 +221,42 @@ func (p *PostgresDB) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 	return users, nil
 }
 
+// likeEscaper escapes the wildcards of a LIKE pattern, so user input only
+// matches itself.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// keysetArgs returns after's creation time and ID as query arguments, both
+// NULL for a listing's first page. They're untyped nils rather than nil
+// pointers, which sqlx.In can't take.
+func keysetArgs(after *models.Keyset) (createdAt, id interface{}) {
+	if after == nil {
+		return nil, nil
+	}
+	return after.CreatedAt, after.ID
+}
+
+// ListUsers lists users matching filter, newest first, continuing after
+// after if it's set.
+func (p *PostgresDB) ListUsers(ctx context.Context, filter models.UserFilter, limit int, after *models.Keyset) ([]*models.User, error) {
+	query := `
+		SELECT id, username, email, password_hash, karma, post_karma, comment_karma, created_at, updated_at, is_connected, last_active, profile_image, karma_velocity, is_admin, email_verified, is_bot
+		FROM users
+		WHERE merged_into IS NULL
+		  AND ($1 = '' OR lower(username) LIKE lower($1) || '%')
+		  AND ($2::int IS NULL OR karma >= $2)
+		  AND ($3::timestamptz IS NULL OR (created_at, id) < ($3, $4::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $5
+	`
+	afterCreated, afterID := keysetArgs(after)
+	users := []*models.User{}
+	err := p.DB.SelectContext(ctx, &users, query, likeEscaper.Replace(filter.Username), filter.MinKarma, afterCreated, afterID, limit)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to list users", err)
+	}
+	return users, nil
+}
+
 // --- Subreddit Methods ---
 
 // CreateSubreddit inserts a new subreddit record.
@@ -380,7 +285,7 @@ func (p *PostgresDB) CreateSubreddit(ctx context.Context, sub *models.Subreddit)
 
 // GetSubredditByID fetches a subreddit by its ID.
 func (p *PostgresDB) GetSubredditByID(ctx context.Context, id uuid.UUID) (*models.Subreddit, error) {
-	query := `SELECT id, name, description, created_by, member_count, created_at FROM subreddits WHERE id = $1`
+	query := `SELECT id, name, description, created_by, member_count, post_count, created_at, deleted_at, nsfw, quarantined FROM subreddits WHERE id = $1` + notDeleted(ctx, "subreddits")
 	var sub models.Subreddit
 	err := p.DB.GetContext(ctx, &sub, query, id)
 	if err != nil {
@@ -394,7 +299,7 @@ func (p *PostgresDB) GetSubredditByID(ctx context.Context, id uuid.UUID) (*model
 
 // GetSubredditByName fetches a subreddit by its name.
 func (p *PostgresDB) GetSubredditByName(ctx context.Context, name string) (*models.Subreddit, error) {
-	query := `SELECT id, name, description, created_by, member_count, created_at FROM subreddits WHERE name = $1`
+	query := `SELECT id, name, description, created_by, member_count, post_count, created_at, deleted_at, nsfw, quarantined FROM subreddits WHERE name = $1` + notDeleted(ctx, "subreddits")
 	var sub models.Subreddit
 	err := p.DB.GetContext(ctx, &sub, query, name)
 	if err != nil {
@@ -408,7 +313,7 @@ func (p *PostgresDB) GetSubredditByName(ctx context.Context, name string) (*mode
 
 // GetAllSubreddits fetches all subreddit records.
 func (p *PostgresDB) GetAllSubreddits(ctx context.Context) ([]*models.Subreddit, error) {
-	query := `SELECT id, name, description, created_by, member_count, created_at FROM subreddits ORDER BY created_at DESC`
+	query := `SELECT id, name, description, created_by, member_count, post_count, created_at, nsfw, quarantined FROM subreddits WHERE deleted_at IS NULL ORDER BY created_at DESC`
 	var subs []*models.Subreddit
 	err := p.DB.SelectContext(ctx, &subs, query)
 	if err != nil {
@@ -436,6 +341,58 @@ func (p *PostgresDB) UpdateSubredditMemberCount(ctx context.Context, subID uuid.
 	return nil
 }
 
+// JoinSubreddit adds a member to a subreddit and bumps its member count in
+// one transaction. It reports false, changing nothing, if the user was
+// already a member.
+func (p *PostgresDB) JoinSubreddit(ctx context.Context, subID, userID uuid.UUID) (bool, error) {
+	return p.changeMembership(ctx, subID, userID, `
+		INSERT INTO subreddit_members (subreddit_id, user_id, joined_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (subreddit_id, user_id) DO NOTHING`, 1)
+}
+
+// LeaveSubreddit removes a member from a subreddit and drops its member
+// count in one transaction. It reports false, changing nothing, if the user
+// wasn't a member.
+func (p *PostgresDB) LeaveSubreddit(ctx context.Context, subID, userID uuid.UUID) (bool, error) {
+	return p.changeMembership(ctx, subID, userID, `
+		DELETE FROM subreddit_members WHERE subreddit_id = $1 AND user_id = $2`, -1)
+}
+
+// changeMembership runs a membership insert or delete and, if it touched a
+// row, applies delta to the member count. The subreddit row is locked first,
+// so concurrent joins can't skew the count.
+func (p *PostgresDB) changeMembership(ctx context.Context, subID, userID uuid.UUID, query string, delta int) (bool, error) {
+	tx, err := p.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, utils.NewAppError(utils.ErrDatabase, "failed to begin membership update", err)
+	}
+	defer tx.Rollback()
+
+	var locked uuid.UUID
+	err = tx.GetContext(ctx, &locked, `SELECT id FROM subreddits WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, subID)
+	if err == sql.ErrNoRows {
+		return false, utils.NewAppError(utils.ErrNotFound, "subreddit not found", err)
+	}
+	if err != nil {
+		return false, utils.NewAppError(utils.ErrDatabase, "failed to lock subreddit", err)
+	}
+
+	result, err := tx.ExecContext(ctx, query, subID, userID)
+	if err != nil {
+		return false, utils.NewAppError(utils.ErrDatabase, "failed to update subreddit membership", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE subreddits SET member_count = GREATEST(0, member_count + $2) WHERE id = $1`, subID, delta); err != nil {
+		return false, utils.NewAppError(utils.ErrDatabase, "failed to update subreddit member count", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, utils.NewAppError(utils.ErrDatabase, "failed to commit membership update", err)
+	}
+	return true, nil
+}
+
 // GetSubredditMemberIDs fetches all member IDs for a given subreddit.
 func (p *PostgresDB) GetSubredditMemberIDs(ctx context.Context, subredditID uuid.UUID) ([]uuid.UUID, error) {
 	query := `SELECT user_id FROM subreddit_members WHERE subreddit_id = $1`
@@ -447,9 +404,25 @@ func (p *PostgresDB) GetSubredditMemberIDs(ctx context.Context, subredditID uuid
 	return memberIDs, nil
 }
 
+// CountOnlineMembers counts the subreddit's members who are connected and
+// were active within activeWithin.
+func (p *PostgresDB) CountOnlineMembers(ctx context.Context, subredditID uuid.UUID, activeWithin time.Duration) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM subreddit_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.subreddit_id = $1 AND u.is_connected AND u.last_active > NOW() - $2 * INTERVAL '1 millisecond'
+	`
+	var count int
+	if err := p.DB.GetContext(ctx, &count, query, subredditID, activeWithin.Milliseconds()); err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to count online members", err)
+	}
+	return count, nil
+}
+
 // --- Post Methods ---
 
-// SavePost inserts a new post or updates an existing one based on the ID.
+// SavePost inserts a new post or updates an existing one based on the ID,
+// and adds it to the inboxes of users it mentions.
 func (p *PostgresDB) SavePost(ctx context.Context, post *models.Post) error {
 	// Ensure timestamps are set
 	post.UpdatedAt = time.Now()
@@ -457,22 +430,42 @@ func (p *PostgresDB) SavePost(ctx context.Context, post *models.Post) error {
 		post.CreatedAt = post.UpdatedAt
 	}
 
+	// xmax is 0 only for a freshly inserted row, so only new posts are counted
 	query := `
-		INSERT INTO posts (id, title, content, author_id, subreddit_id, karma, comment_count, created_at, updated_at)
-		VALUES (:id, :title, :content, :author_id, :subreddit_id, :karma, :comment_count, :created_at, :updated_at)
-		ON CONFLICT (id) DO UPDATE SET
-			title = EXCLUDED.title,
-			content = EXCLUDED.content,
-			karma = EXCLUDED.karma,
-			comment_count = EXCLUDED.comment_count,
-			updated_at = EXCLUDED.updated_at
+		WITH saved AS (
+			INSERT INTO posts (id, title, content, author_id, subreddit_id, karma, comment_count, url,
+				original_content, source_attribution, license, language, flair_id, media_id, created_at, updated_at)
+			VALUES (:id, :title, :content, :author_id, :subreddit_id, :karma, :comment_count, :url,
+				:original_content, :source_attribution, :license, :language, :flair_id, :media_id, :created_at, :updated_at)
+			ON CONFLICT (id) DO UPDATE SET
+				title = EXCLUDED.title,
+				content = EXCLUDED.content,
+				language = EXCLUDED.language,
+				karma = EXCLUDED.karma,
+				comment_count = EXCLUDED.comment_count,
+				updated_at = EXCLUDED.updated_at
+			RETURNING subreddit_id, xmax = 0 AS inserted
+		)
+		UPDATE subreddits SET post_count = post_count + 1
+		WHERE id = (SELECT subreddit_id FROM saved WHERE inserted)
 	`
-	// Note: We don't update author_id or subreddit_id on conflict
+	// Note: We don't update author_id, subreddit_id, url, metadata or flair on conflict
 
-	_, err := p.DB.NamedExecContext(ctx, query, post)
+	tx, err := p.DB.BeginTxx(ctx, nil)
 	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to begin transaction for save post", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.NamedExecContext(ctx, query, post); err != nil {
 		return utils.NewAppError(utils.ErrDatabase, "failed to save post", err)
 	}
+	if err := recordMentions(ctx, tx, post.ID, post.AuthorID, post.ID, post.CreatedAt, post.Title, post.Content); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to commit post", err)
+	}
 	return nil
 }
 
@@ -481,52 +474,36 @@ func (p *PostgresDB) GetPost(ctx context.Context, postID uuid.UUID, requestingUs
 	query := `SELECT 
 			p.id, p.title, p.content, p.author_id, p.subreddit_id, p.karma, 
 			p.upvotes, p.downvotes, p.comment_count, p.created_at, p.updated_at,
+			p.url, p.thumbnail_url, p.locked_by_author, p.locked_by_moderator, p.archived, p.edited_at, p.deleted_at,
+			p.original_content, p.source_attribution, p.license, p.language, ` + postFlairColumns + `, ` + postMediaColumns + `,
 			u.username as author_username, -- Join to get author username
-			s.name as subreddit_name      -- Join to get subreddit name
+			COALESCE(u.is_bot, FALSE) AS author_is_bot,
+			s.name as subreddit_name,     -- Join to get subreddit name
+			` + currentUserVoteColumn + `
 		FROM posts p
 		LEFT JOIN users u ON p.author_id = u.id
 		LEFT JOIN subreddits s ON p.subreddit_id = s.id
-		WHERE p.id = $1`
+		` + postFlairJoin + `
+		` + postMediaJoin + `
+		` + currentUserVoteJoin("p", models.PostVote, "$2") + `
+		WHERE p.id = $1` + notDeleted(ctx, "p", "s")
 	var post models.Post
-	err := p.DB.GetContext(ctx, &post, query, postID)
+	err := p.DB.GetContext(ctx, &post, query, postID, requestingUserID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, utils.NewAppError(utils.ErrNotFound, "post not found", err)
 		}
-		log.Printf("Error fetching post %s: %v", postID, err) // Log detailed error
+		p.Logger.ErrorContext(ctx, "Failed to fetch post", "post_id", postID, "error", err)
 		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query post by id", err)
 	}
+	post.CurrentUserVote = normalizeVote(post.CurrentUserVote)
 
-	// If a requesting user ID is provided and valid, fetch their vote status
-	if requestingUserID != uuid.Nil {
-		// Expect string type based on error logs (e.g., "up", "down")
-		var voteType sql.NullString
-		voteQuery := `SELECT vote_type FROM votes WHERE user_id = $1 AND content_id = $2 AND content_type = $3`
-		err = p.DB.GetContext(ctx, &voteType, voteQuery, requestingUserID, postID, string(models.PostVote))
-
-		if err != nil && err != sql.ErrNoRows {
-			log.Printf("Error fetching vote status for user %s on post %s: %v", requestingUserID, postID, err)
-			// Don't fail the whole request, just log the error and return post without vote status
-		} else if err == nil && voteType.Valid {
-			// Check the string value
-			if voteType.String == "up" || voteType.String == "1" { // Check for string "up" or legacy "1"
-				up := "up"
-				post.CurrentUserVote = &up
-			} else if voteType.String == "down" || voteType.String == "-1" { // Check for string "down" or legacy "-1"
-				down := "down"
-				post.CurrentUserVote = &down
-			}
-			// If voteType string is something else (e.g., "0", empty), CurrentUserVote remains nil
-		}
-		// If err == sql.ErrNoRows, CurrentUserVote remains nil (no vote)
-	}
-
-	// The rest of the post fields (like AuthorUsername, SubredditName) should be populated by the GetContext query now
 	return &post, nil
 }
 
 // RecordVote handles inserting, updating, or deleting a vote record
 // and updating the corresponding karma for the content and its author.
+// Votes on archived posts and their comments are refused.
 func (p *PostgresDB) RecordVote(ctx context.Context, userID, contentID uuid.UUID, contentType models.VoteContentType, direction models.VoteDirection) error {
 	tx, err := p.DB.BeginTxx(ctx, nil)
 	if err != nil {
@@ -538,6 +515,16 @@ func (p *PostgresDB) RecordVote(ctx context.Context, userID, contentID uuid.UUID
 	var existingVoteID uuid.UUID // Needed if we need to update/delete
 	var authorID uuid.UUID
 
+	// --- 0. Serialize votes by the same user on the same content ---
+	// Without this, two concurrent requests (e.g. a double-click) both read
+	// the same previous vote and both apply their karma delta. A row lock
+	// isn't enough because the vote row may not exist yet, so take a
+	// transaction-scoped advisory lock on (user, content) instead.
+	lockQuery := `SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))`
+	if _, err := tx.ExecContext(ctx, lockQuery, userID.String(), string(contentType)+":"+contentID.String()); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to lock vote", err)
+	}
+
 	// --- 1. Determine previous vote and content author ---
 	getVoteQuery := `SELECT id, vote_type FROM votes WHERE user_id = $1 AND content_id = $2 AND content_type = $3`
 	err = tx.QueryRowxContext(ctx, getVoteQuery, userID, contentID, contentType).Scan(&existingVoteID, &previousVoteType)
@@ -546,21 +533,22 @@ func (p *PostgresDB) RecordVote(ctx context.Context, userID, contentID uuid.UUID
 	}
 	// If err == sql.ErrNoRows, previousVoteType remains empty (zero value)
 
-	// Get author ID based on content type
+	// Get author ID based on content type, and whether the post is archived
 	var getAuthorQuery string
 	if contentType == models.PostVote {
-		getAuthorQuery = `SELECT author_id FROM posts WHERE id = $1`
+		getAuthorQuery = `SELECT author_id, archived FROM posts WHERE id = $1 AND deleted_at IS NULL`
 	} else if contentType == models.CommentVote {
-		getAuthorQuery = `SELECT author_id FROM comments WHERE id = $1`
+		getAuthorQuery = `SELECT c.author_id, p.archived FROM comments c JOIN posts p ON p.id = c.post_id WHERE c.id = $1 AND c.deleted_at IS NULL`
 	} else {
 		return utils.NewAppError(utils.ErrInvalidInput, "invalid content type for voting", nil)
 	}
 
-	err = tx.QueryRowxContext(ctx, getAuthorQuery, contentID).Scan(&authorID)
+	var archived bool
+	err = tx.QueryRowxContext(ctx, getAuthorQuery, contentID).Scan(&authorID, &archived)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// Content might have been deleted, or author set to NULL
-			log.Printf("Warning: Could not find author for content %s (%s) during vote recording", contentID, contentType)
+			p.Logger.WarnContext(ctx, "No author found for voted content", "content_id", contentID, "content_type", contentType)
 			// Proceed without author karma update if author is not found or null
 			authorID = uuid.Nil
 		} else {
@@ -568,6 +556,10 @@ func (p *PostgresDB) RecordVote(ctx context.Context, userID, contentID uuid.UUID
 		}
 	}
 
+	if archived {
+		return utils.NewAppError(utils.ErrForbidden, "post is archived", nil)
+	}
+
 	// --- 2. Calculate Karma Delta ---
 	karmaDelta := 0
 	upvoteDelta := 0
@@ -621,12 +613,14 @@ func (p *PostgresDB) RecordVote(ctx context.Context, userID, contentID uuid.UUID
 			return utils.NewAppError(utils.ErrDatabase, "failed to update content karma/votes", err)
 		}
 
-		// Update author's karma (upvotes/downvotes are not tracked on the user model)
+		// Update author's karma, in total and for the kind of content
+		// (upvotes/downvotes are not tracked on the user model)
 		if authorID != uuid.Nil && karmaDelta != 0 { // Only update author karma if it changed
-			updateAuthorKarmaQuery := `UPDATE users SET karma = karma + $1, updated_at = NOW() WHERE id = $2`
+			column := karmaColumn(contentType)
+			updateAuthorKarmaQuery := `UPDATE users SET karma = karma + $1, ` + column + ` = ` + column + ` + $1, updated_at = NOW() WHERE id = $2`
 			_, err = tx.ExecContext(ctx, updateAuthorKarmaQuery, karmaDelta, authorID)
 			if err != nil {
-				log.Printf("Warning: Failed to update author (%s) karma during vote: %v", authorID, err)
+				p.Logger.WarnContext(ctx, "Failed to update author karma for vote", "author_id", authorID, "error", err)
 			}
 		}
 	}
@@ -671,61 +665,123 @@ func (p *PostgresDB) RecordVote(ctx context.Context, userID, contentID uuid.UUID
 	return nil
 }
 
-// GetRecentPosts retrieves the most recent posts across all subreddits, including the requesting user's vote status.
-func (p *PostgresDB) GetRecentPosts(ctx context.Context, limit, offset int, requestingUserID uuid.UUID) ([]*models.Post, error) {
-	// Temporary struct to handle scanning potential string or int vote_type
-	type ScanPost struct {
-		models.Post
-		RawVoteType sql.NullString `db:"current_user_vote"` // Use NullString like in GetPost
+// GetAuthorAffinity returns userID's net votes (upvotes less downvotes) on
+// the posts and comments of each of authorIDs they voted on.
+func (p *PostgresDB) GetAuthorAffinity(ctx context.Context, userID uuid.UUID, authorIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	affinity := make(map[uuid.UUID]int)
+	if len(authorIDs) == 0 {
+		return affinity, nil
 	}
+	query, args, err := sqlx.In(`
+		SELECT author_id, SUM(CASE WHEN vote_type::text IN ('up', '1') THEN 1 ELSE -1 END) AS net
+		FROM (
+			SELECT p.author_id, v.vote_type FROM votes v JOIN posts p ON p.id = v.content_id
+			WHERE v.user_id = ? AND v.content_type = 'post' AND p.author_id IN (?)
+			UNION ALL
+			SELECT c.author_id, v.vote_type FROM votes v JOIN comments c ON c.id = v.content_id
+			WHERE v.user_id = ? AND v.content_type = 'comment' AND c.author_id IN (?)
+		) voted
+		GROUP BY author_id
+	`, userID, authorIDs, userID, authorIDs)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to build author affinity query", err)
+	}
+
+	var rows []struct {
+		AuthorID uuid.UUID `db:"author_id"`
+		Net      int       `db:"net"`
+	}
+	if err := p.DB.SelectContext(ctx, &rows, p.DB.Rebind(query), args...); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query author affinity", err)
+	}
+	for _, row := range rows {
+		affinity[row.AuthorID] = row.Net
+	}
+	return affinity, nil
+}
 
+// preferredLanguages keeps the posts of a listing joined to the reader's
+// user_preferences as pref that are in one of their languages. Posts whose
+// language wasn't detected are kept, and so is everything for readers who
+// chose no languages.
+const preferredLanguages = `(COALESCE(cardinality(pref.languages), 0) = 0 OR p.language IS NULL OR p.language = ANY(pref.languages))`
+
+// preferredRatings keeps the subreddits s of a listing joined to the
+// reader's user_preferences as pref that aren't NSFW or quarantined, unless
+// the reader opted in to them.
+const preferredRatings = `(NOT s.nsfw OR COALESCE(pref.show_nsfw, FALSE)) AND (NOT s.quarantined OR COALESCE(pref.show_quarantined, FALSE))`
+
+// postOrderBy returns the ORDER BY clause for a post listing sort order.
+// Controversy is the number of votes raised to the power of how evenly they
+// split, so it favours posts with many votes on both sides. Rising ranks the
+// last day's posts by karma per hour since posting; older posts follow,
+// newest first.
+func postOrderBy(sortOrder string) string {
+	switch sortOrder {
+	case models.SortHot:
+		return "ORDER BY p.hot_score DESC, p.created_at DESC"
+	case models.SortTop:
+		return "ORDER BY p.karma DESC, p.created_at DESC"
+	case models.SortControversial:
+		return `ORDER BY CASE WHEN p.upvotes > 0 AND p.downvotes > 0
+			THEN POWER(p.upvotes + p.downvotes, LEAST(p.upvotes, p.downvotes)::float / GREATEST(p.upvotes, p.downvotes))
+			ELSE 0 END DESC, p.created_at DESC`
+	case models.SortRising:
+		return `ORDER BY CASE WHEN p.created_at > NOW() - INTERVAL '1 day'
+			THEN p.karma / (EXTRACT(EPOCH FROM NOW() - p.created_at) / 3600 + 1)
+			ELSE NULL END DESC NULLS LAST, p.created_at DESC`
+	default:
+		return "ORDER BY p.created_at DESC, p.id DESC"
+	}
+}
+
+// GetRecentPosts retrieves posts across all subreddits, newest or hottest first,
+// including the requesting user's vote status. Posts from NSFW and quarantined
+// subreddits are left out unless the user's preferences opt in to them, and so
+// are posts in languages they didn't choose. Newest first listings continue
+// after after if it's set.
+func (p *PostgresDB) GetRecentPosts(ctx context.Context, limit, offset int, after *models.Keyset, requestingUserID uuid.UUID, sortOrder string) ([]*models.Post, error) {
 	query := `
 		SELECT 
-		    p.id, p.title, p.content, p.author_id, u.username AS author_username, 
+		    p.id, p.title, p.content, p.author_id, u.username AS author_username, u.is_bot AS author_is_bot,
 		    p.subreddit_id, s.name AS subreddit_name, 
 		    p.created_at, p.updated_at, p.karma, p.upvotes, p.downvotes, p.comment_count,
-		    v.vote_type AS current_user_vote -- Select the raw vote_type (might be string or int)
+		    p.url, p.thumbnail_url, p.locked_by_author, p.locked_by_moderator, p.archived, p.edited_at,
+		    p.original_content, p.source_attribution, p.license, p.language, ` + postFlairColumns + `, ` + postMediaColumns + `,
+		    ` + currentUserVoteColumn + `
 		FROM posts p
 		JOIN users u ON p.author_id = u.id
 		JOIN subreddits s ON p.subreddit_id = s.id
-		LEFT JOIN votes v ON v.content_id = p.id AND v.user_id = $3 AND v.content_type = 'post'
-		ORDER BY p.created_at DESC
+		` + postFlairJoin + `
+		` + postMediaJoin + `
+		` + currentUserVoteJoin("p", models.PostVote, "$3") + `
+		LEFT JOIN user_preferences pref ON pref.user_id = $3
+		WHERE p.deleted_at IS NULL AND s.deleted_at IS NULL
+		  AND ` + preferredRatings + `
+		  AND ` + preferredLanguages + `
+		  AND ($4::timestamptz IS NULL OR (p.created_at, p.id) < ($4, $5::uuid))
+		` + postOrderBy(sortOrder) + `
 		LIMIT $1 OFFSET $2
 	`
 
-	scannedPosts := []ScanPost{}
-	err := p.DB.SelectContext(ctx, &scannedPosts, query, limit, offset, requestingUserID)
-
+	afterCreated, afterID := keysetArgs(after)
+	posts := []*models.Post{}
+	err := p.DB.SelectContext(ctx, &posts, query, limit, offset, requestingUserID, afterCreated, afterID)
 	if err != nil {
-		log.Printf("Error querying recent posts: %v", err)
+		p.Logger.ErrorContext(ctx, "Failed to query recent posts", "error", err)
 		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query recent posts", err)
 	}
-
-	// Process scanned posts to populate CurrentUserVote string pointer correctly
-	posts := make([]*models.Post, len(scannedPosts))
-	for i, sp := range scannedPosts {
-		post := sp.Post // Create a copy
-		if sp.RawVoteType.Valid {
-			// Check string value like in GetPost workaround
-			voteStr := sp.RawVoteType.String
-			if voteStr == "up" || voteStr == "1" { // Check for "up" or legacy "1"
-				up := "up"
-				post.CurrentUserVote = &up
-			} else if voteStr == "down" || voteStr == "-1" { // Check for "down" or legacy "-1"
-				down := "down"
-				post.CurrentUserVote = &down
-			}
-			// If voteStr is something else (e.g., "0", empty), CurrentUserVote remains nil
-		}
-		posts[i] = &post // Assign the processed post pointer
-	}
+	hydratePostVotes(posts)
 
 	return posts, nil
 }
 
-// GetUserFeed retrieves posts from subreddits the user is subscribed to, ordered by creation date.
+// GetUserFeed retrieves posts from subreddits the user is subscribed to, newest or hottest first.
 // It now also fetches the requesting user's vote status for each post.
-func (p *PostgresDB) GetUserFeed(ctx context.Context, userID uuid.UUID, limit, offset int, requestingUserID uuid.UUID) ([]*models.Post, error) {
+// When hideSeen is set, posts the user has already been served or opened are excluded.
+// Posts in languages other than the user's preferred ones are too.
+// Newest first feeds continue after after if it's set.
+func (p *PostgresDB) GetUserFeed(ctx context.Context, userID uuid.UUID, limit, offset int, after *models.Keyset, requestingUserID uuid.UUID, hideSeen bool, sortOrder string) ([]*models.Post, error) {
 	// 1. Get subscribed subreddit IDs
 	var subscribedIDs []uuid.UUID
 	subQuery := `SELECT subreddit_id FROM subreddit_members WHERE user_id = $1`
@@ -739,20 +795,37 @@ func (p *PostgresDB) GetUserFeed(ctx context.Context, userID uuid.UUID, limit, o
 	}
 
 	// 2. Get posts from those subreddits, including vote status
+	seenFilter := ""
+	args := []interface{}{requestingUserID, userID, subscribedIDs}
+	if hideSeen {
+		seenFilter = `AND NOT EXISTS (SELECT 1 FROM post_views pv WHERE pv.user_id = ? AND pv.post_id = p.id)`
+		args = append(args, userID)
+	}
+	afterCreated, afterID := keysetArgs(after)
+	args = append(args, afterCreated, afterCreated, afterID, limit, offset)
+
 	query, args, err := sqlx.In(`
 		SELECT 
-		    p.id, p.title, p.content, p.author_id, u.username AS author_username, 
+		    p.id, p.title, p.content, p.author_id, u.username AS author_username, u.is_bot AS author_is_bot,
 		    p.subreddit_id, s.name AS subreddit_name, 
 		    p.created_at, p.updated_at, p.karma, p.upvotes, p.downvotes, p.comment_count,
-		    v.vote_type AS current_user_vote
+		    p.url, p.thumbnail_url, p.locked_by_author, p.locked_by_moderator, p.archived, p.edited_at,
+		    p.original_content, p.source_attribution, p.license, p.language, `+postFlairColumns+`, `+postMediaColumns+`,
+		    `+currentUserVoteColumn+`
 		FROM posts p
 		JOIN users u ON p.author_id = u.id
 		JOIN subreddits s ON p.subreddit_id = s.id
-		LEFT JOIN votes v ON v.content_id = p.id AND v.user_id = ? AND v.content_type = 'post' -- Placeholder for requestingUserID
-		WHERE p.subreddit_id IN (?)
-		ORDER BY p.created_at DESC
+		`+postFlairJoin+`
+		`+postMediaJoin+`
+		`+currentUserVoteJoin("p", models.PostVote, "?")+`
+		LEFT JOIN user_preferences pref ON pref.user_id = ?
+		WHERE p.subreddit_id IN (?) AND p.deleted_at IS NULL AND s.deleted_at IS NULL
+		AND `+preferredLanguages+`
+		`+seenFilter+`
+		AND (?::timestamptz IS NULL OR (p.created_at, p.id) < (?, ?::uuid))
+		`+postOrderBy(sortOrder)+`
 		LIMIT ? OFFSET ?
-	`, requestingUserID, subscribedIDs, limit, offset)
+	`, args...)
 
 	if err != nil {
 		return nil, utils.NewAppError(utils.ErrDatabase, "failed to build feed query with votes", err)
@@ -763,38 +836,62 @@ func (p *PostgresDB) GetUserFeed(ctx context.Context, userID uuid.UUID, limit, o
 	posts := []*models.Post{}
 	err = p.DB.SelectContext(ctx, &posts, query, args...)
 	if err != nil {
-		log.Printf("Error querying user feed posts: %v, Query: %s, Args: %v", err, query, args)
+		p.Logger.ErrorContext(ctx, "Failed to query user feed", "user_id", userID, "error", err)
 		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query user feed posts", err)
 	}
 
-	// Post-process vote status (same as GetRecentPosts)
-	for _, post := range posts {
-		if post.CurrentUserVote != nil {
-			vote := *post.CurrentUserVote
-			if vote != "up" && vote != "down" {
-				post.CurrentUserVote = nil
-			}
-		}
-	}
+	hydratePostVotes(posts)
 
 	return posts, nil
 }
 
-// GetPostsBySubreddit retrieves posts for a specific subreddit with pagination.
-// TODO: Add requestingUserID to GetPostsBySubreddit to fetch currentUserVote.
-func (p *PostgresDB) GetPostsBySubreddit(ctx context.Context, subredditID uuid.UUID, limit int, offset int) ([]*models.Post, error) {
+// MarkPostsSeen records that the user has been served or opened the given posts.
+func (p *PostgresDB) MarkPostsSeen(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+
 	query := `
-		SELECT id, title, content, author_id, subreddit_id, created_at, updated_at, karma, upvotes, downvotes, comment_count
-		FROM posts
-		WHERE subreddit_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
+		INSERT INTO post_views (user_id, post_id, seen_at)
+		SELECT $1, unnest($2::uuid[]), NOW()
+		ON CONFLICT (user_id, post_id) DO UPDATE SET seen_at = EXCLUDED.seen_at
 	`
+	ids := make([]string, len(postIDs))
+	for i, id := range postIDs {
+		ids[i] = id.String()
+	}
+	if _, err := p.DB.ExecContext(ctx, query, userID, pq.Array(ids)); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to mark posts seen", err)
+	}
+	return nil
+}
+
+// GetPostsBySubreddit retrieves posts for a specific subreddit, newest first,
+// continuing after after if it's set. Only those with flairID are included
+// if it's set. Each post carries requestingUserID's vote on it.
+func (p *PostgresDB) GetPostsBySubreddit(ctx context.Context, subredditID uuid.UUID, flairID *uuid.UUID, limit int, after *models.Keyset, requestingUserID uuid.UUID) ([]*models.Post, error) {
+	query := `
+		SELECT p.id, p.title, p.content, p.author_id, p.subreddit_id, p.created_at, p.updated_at, p.karma, p.upvotes, p.downvotes, p.comment_count,
+			p.url, p.thumbnail_url, p.locked_by_author, p.locked_by_moderator, p.archived, p.edited_at,
+			p.original_content, p.source_attribution, p.license, p.language, ` + postFlairColumns + `, ` + postMediaColumns + `,
+			` + currentUserVoteColumn + `
+		FROM posts p
+		` + postFlairJoin + `
+		` + postMediaJoin + `
+		` + currentUserVoteJoin("p", models.PostVote, "$6") + `
+		WHERE p.subreddit_id = $1 AND p.deleted_at IS NULL
+		  AND ($2::uuid IS NULL OR p.flair_id = $2)
+		  AND ($3::timestamptz IS NULL OR (p.created_at, p.id) < ($3, $4::uuid))
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $5
+	`
+	afterCreated, afterID := keysetArgs(after)
 	posts := []*models.Post{}
-	err := p.DB.SelectContext(ctx, &posts, query, subredditID, limit, offset)
+	err := p.DB.SelectContext(ctx, &posts, query, subredditID, flairID, afterCreated, afterID, limit, requestingUserID)
 	if err != nil {
 		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query posts by subreddit", err)
 	}
+	hydratePostVotes(posts)
 	return posts, nil
 }
 
@@ -802,9 +899,14 @@ func (p *PostgresDB) GetPostsBySubreddit(ctx context.Context, subredditID uuid.U
 func (p *PostgresDB) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
 	// Warning: Loading ALL posts might be memory-intensive for large datasets.
 	// Consider pagination or alternative loading strategies if needed.
-	query := `SELECT id, title, content, author_id, subreddit_id, created_at, updated_at, karma, upvotes, downvotes, comment_count
-	          FROM posts
-	          ORDER BY created_at DESC`
+	query := `SELECT p.id, p.title, p.content, p.author_id, p.subreddit_id, p.created_at, p.updated_at, p.karma, p.upvotes, p.downvotes, p.comment_count,
+	                 p.url, p.thumbnail_url, p.locked_by_author, p.locked_by_moderator, p.archived, p.edited_at,
+	                 p.original_content, p.source_attribution, p.license, p.language, ` + postFlairColumns + `, ` + postMediaColumns + `
+	          FROM posts p
+	          ` + postFlairJoin + `
+	          ` + postMediaJoin + `
+	          WHERE p.deleted_at IS NULL
+	          ORDER BY p.created_at DESC`
 	posts := []*models.Post{}
 	err := p.DB.SelectContext(ctx, &posts, query)
 	if err != nil {
@@ -816,32 +918,140 @@ func (p *PostgresDB) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
 	return posts, nil
 }
 
+// UpdatePostThumbnail records the URL of a post's generated preview image.
+func (p *PostgresDB) UpdatePostThumbnail(ctx context.Context, postID uuid.UUID, thumbnailURL string) error {
+	query := `UPDATE posts SET thumbnail_url = $1 WHERE id = $2`
+	if _, err := p.DB.ExecContext(ctx, query, thumbnailURL, postID); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to update post thumbnail", err)
+	}
+	return nil
+}
+
+// UpdatePostMetadata replaces a post's original content flag, attribution
+// and license.
+func (p *PostgresDB) UpdatePostMetadata(ctx context.Context, postID uuid.UUID, meta *models.PostMetadata) error {
+	query := `
+		UPDATE posts SET original_content = $1, source_attribution = $2, license = $3
+		WHERE id = $4 AND deleted_at IS NULL
+	`
+	result, err := p.DB.ExecContext(ctx, query, meta.OriginalContent, meta.SourceAttribution, meta.License, postID)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to update post metadata", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return utils.NewAppError(utils.ErrNotFound, "post not found", nil)
+	}
+	return nil
+}
+
+// EditPost replaces a post's title and content, and the language detected
+// from them, and returns when it was edited. The version it replaces is kept
+// as a revision, unless the edit didn't change it. Users the edit newly
+// mentions get it in their inbox.
+func (p *PostgresDB) EditPost(ctx context.Context, postID uuid.UUID, title, content string, language *string) (time.Time, error) {
+	query := `
+		WITH old AS (
+			SELECT id, title, content, COALESCE(edited_at, created_at) AS written_at
+			FROM posts WHERE id = $3 AND deleted_at IS NULL
+			FOR UPDATE
+		), revision AS (
+			INSERT INTO post_revisions (post_id, title, content, written_at)
+			SELECT id, title, content, written_at FROM old
+			WHERE (title, content) IS DISTINCT FROM ($1::text, $2::text)
+		)
+		UPDATE posts SET title = $1, content = $2, language = $4, edited_at = NOW(), updated_at = NOW()
+		WHERE id = (SELECT id FROM old)
+		RETURNING author_id, created_at, edited_at
+	`
+	tx, err := p.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return time.Time{}, utils.NewAppError(utils.ErrDatabase, "failed to begin transaction for edit post", err)
+	}
+	defer tx.Rollback()
+
+	var edited struct {
+		AuthorID  uuid.UUID `db:"author_id"`
+		CreatedAt time.Time `db:"created_at"`
+		EditedAt  time.Time `db:"edited_at"`
+	}
+	err = tx.GetContext(ctx, &edited, query, title, content, postID, language)
+	if err == sql.ErrNoRows {
+		return time.Time{}, utils.NewAppError(utils.ErrNotFound, "post not found", nil)
+	}
+	if err != nil {
+		return time.Time{}, utils.NewAppError(utils.ErrDatabase, "failed to edit post", err)
+	}
+	// Only users the edit newly mentions are notified
+	if err := recordMentions(ctx, tx, postID, edited.AuthorID, postID, edited.CreatedAt, title, content); err != nil {
+		return time.Time{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, utils.NewAppError(utils.ErrDatabase, "failed to commit post edit", err)
+	}
+	return edited.EditedAt, nil
+}
+
+// GetPostRevisions returns a post's earlier versions, oldest first.
+func (p *PostgresDB) GetPostRevisions(ctx context.Context, postID uuid.UUID) ([]*models.PostRevision, error) {
+	revisions := []*models.PostRevision{}
+	err := p.DB.SelectContext(ctx, &revisions, `
+		SELECT post_id, title, content, written_at, replaced_at FROM post_revisions
+		WHERE post_id = $1 ORDER BY replaced_at, id`, postID)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to fetch post revisions", err)
+	}
+	return revisions, nil
+}
+
+// SetPostLockedByModerator locks or unlocks a post to new comments on a
+// moderator's behalf. Its author can't undo this.
+func (p *PostgresDB) SetPostLockedByModerator(ctx context.Context, postID uuid.UUID, locked bool) error {
+	query := `UPDATE posts SET locked_by_moderator = $1 WHERE id = $2 AND deleted_at IS NULL`
+	result, err := p.DB.ExecContext(ctx, query, locked, postID)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to update post lock", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return utils.NewAppError(utils.ErrNotFound, "post not found", nil)
+	}
+	return nil
+}
+
+// SetPostLocked locks or unlocks a post to new comments on its author's behalf.
+func (p *PostgresDB) SetPostLocked(ctx context.Context, postID uuid.UUID, locked bool) error {
+	query := `UPDATE posts SET locked_by_author = $1 WHERE id = $2 AND deleted_at IS NULL`
+	result, err := p.DB.ExecContext(ctx, query, locked, postID)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to update post lock", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return utils.NewAppError(utils.ErrNotFound, "post not found", nil)
+	}
+	return nil
+}
+
 // --- Comment Methods ---
 
-// SaveComment inserts a new comment or updates an existing one.
-// It now also increments the comment_count on the associated post in a transaction.
+// SaveComment inserts a new comment or updates an existing one. Inserting
+// also counts the comment on its post and, for a reply, on its parent
+// comment, in the same transaction. Replies and mentions are added to the
+// inboxes of the users they're for, and the inbox item the comment answers,
+// if any, is marked read.
 func (p *PostgresDB) SaveComment(ctx context.Context, comment *models.Comment) error {
 	tx, err := p.DB.BeginTxx(ctx, nil)
 	if err != nil {
 		return utils.NewAppError(utils.ErrDatabase, "failed to begin transaction for save comment", err)
 	}
-	// Defers will not run if panic occurs, but Rollback is safe to call multiple times.
-	// We will explicitly call Rollback on error and Commit on success.
+	defer tx.Rollback()
 
 	comment.UpdatedAt = time.Now()
 	if comment.CreatedAt.IsZero() {
 		comment.CreatedAt = comment.UpdatedAt
 	}
 
-	// Add log just before DB execution
-	log.Printf("Saving comment ID %s. ParentID: %v, PostID: %s", comment.ID, comment.ParentID, comment.PostID)
-
-	// Determine if it's a new comment for the purpose of incrementing post's comment_count.
-	// A more robust way would be to check if the comment ID already exists, but for now,
-	// we assume if it's not an update (e.g. content change), it's new for counting purposes.
-	// For simplicity, we'll always try to increment if the main save succeeds and it's not an 'is_deleted' style update.
-	// Given the current actor logic, 'SaveComment' is called for new comments.
+	p.Logger.DebugContext(ctx, "Saving comment", "comment_id", comment.ID, "parent_id", comment.ParentID, "post_id", comment.PostID)
 
+	// xmax is 0 only for a freshly inserted row, so edits aren't counted again
 	commentQuery := `
 		INSERT INTO comments (id, content, author_id, post_id, parent_id, karma, upvotes, downvotes, created_at, updated_at)
 		VALUES (:id, :content, :author_id, :post_id, :parent_id, :karma, :upvotes, :downvotes, :created_at, :updated_at)
@@ -851,173 +1061,208 @@ func (p *PostgresDB) SaveComment(ctx context.Context, comment *models.Comment) e
 			upvotes = EXCLUDED.upvotes,
 			downvotes = EXCLUDED.downvotes,
 			updated_at = EXCLUDED.updated_at
+		RETURNING xmax = 0 AS inserted
 	`
 	// Note: We don't update author_id, post_id, parent_id on conflict
-
-	_, err = tx.NamedExecContext(ctx, commentQuery, comment)
+	query, args, err := sqlx.Named(commentQuery, comment)
 	if err != nil {
-		tx.Rollback() // Rollback on error
 		return utils.NewAppError(utils.ErrDatabase, "failed to save comment", err)
 	}
+	var inserted bool
+	if err := tx.QueryRowxContext(ctx, tx.Rebind(query), args...).Scan(&inserted); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to save comment", err)
+	}
+	if inserted {
+		if err := recordReply(ctx, tx, comment); err != nil {
+			return err
+		}
+		if comment.InboxItemID != nil {
+			if err := markInboxItemRead(ctx, tx, comment.AuthorID, *comment.InboxItemID); err != nil {
+				return err
+			}
+		}
+	}
+	// An edit notifies only users it newly mentions
+	if err := recordMentions(ctx, tx, comment.ID, comment.AuthorID, comment.PostID, comment.CreatedAt, comment.Content); err != nil {
+		return err
+	}
+	if !inserted {
+		return tx.Commit()
+	}
 
-	// If the comment save was successful, increment the post's comment_count
-	// We only do this for new comments. The ON CONFLICT clause handles updates to existing comments.
-	// A simple way to check if it was an insert vs an update is not straightforward with ON CONFLICT.
-	// However, based on current actor logic, SaveComment is primarily for new comments or full state saves.
-	// For incrementing count, we assume this call to SaveComment is for a new, non-deleted comment.
-	// A more robust system might involve triggers or checking returned rows from insert.
-
-	// Let's assume if `comment.IsDeleted` was a persisted field and true, we wouldn't increment.
-	// Since it's not persisted, we increment. This matches the user's report that counts are off.
-	updatePostCountQuery := `UPDATE posts SET comment_count = comment_count + 1, updated_at = NOW() WHERE id = $1`
+	updatePostCountQuery := `UPDATE posts SET comment_count = comment_count + 1, updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
 	result, err := tx.ExecContext(ctx, updatePostCountQuery, comment.PostID)
 	if err != nil {
-		tx.Rollback() // Rollback on error
-		log.Printf("Failed to increment comment_count for post %s: %v. Rolling back comment save.", comment.PostID, err)
+		p.Logger.ErrorContext(ctx, "Failed to count comment on its post; rolling back", "post_id", comment.PostID, "error", err)
 		return utils.NewAppError(utils.ErrDatabase, "failed to update post comment_count", err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		tx.Rollback() // Rollback if the post wasn't found to update its count
-		log.Printf("Post %s not found when trying to increment comment_count. Rolling back comment save.", comment.PostID)
+		p.Logger.WarnContext(ctx, "Comment's post not found; rolling back", "post_id", comment.PostID)
 		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("post %s not found to update comment count", comment.PostID), nil)
 	}
 
+	if comment.ParentID != nil {
+		_, err := tx.ExecContext(ctx, `UPDATE comments SET reply_count = reply_count + 1 WHERE id = $1`, *comment.ParentID)
+		if err != nil {
+			return utils.NewAppError(utils.ErrDatabase, "failed to update parent reply_count", err)
+		}
+	}
+
 	return tx.Commit()
 }
 
 // GetComment fetches a single comment by its ID.
-func (p *PostgresDB) GetComment(ctx context.Context, id uuid.UUID) (*models.Comment, error) {
-	// TODO: Consider adding requestingUserID here as well if individual comment GETs need vote status
+// Pass uuid.Nil as requestingUserID when the caller doesn't need vote status.
+func (p *PostgresDB) GetComment(ctx context.Context, id uuid.UUID, requestingUserID uuid.UUID) (*models.Comment, error) {
 	query := `
 		SELECT
-			c.id, c.content, c.author_id, u.username AS author_username, c.post_id,
+			c.id, c.content, c.author_id, u.username AS author_username, u.is_bot AS author_is_bot, c.post_id,
 			p.subreddit_id, c.parent_id, c.created_at, c.updated_at,
-			c.upvotes, c.downvotes, c.karma
+			c.upvotes, c.downvotes, c.karma, c.reply_count, c.deleted_at,
+			` + currentUserVoteColumn + `
 		FROM comments c
 		JOIN users u ON c.author_id = u.id
 		JOIN posts p ON c.post_id = p.id
-		WHERE c.id = $1
-	`
+		` + currentUserVoteJoin("c", models.CommentVote, "$2") + `
+		WHERE c.id = $1` + notDeleted(ctx, "c")
 	var comment models.Comment
-	err := p.DB.GetContext(ctx, &comment, query, id)
+	err := p.DB.GetContext(ctx, &comment, query, id, requestingUserID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, utils.NewAppError(utils.ErrNotFound, "comment not found", err)
 		}
 		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query comment by id", err)
 	}
+	comment.CurrentUserVote = normalizeVote(comment.CurrentUserVote)
 	return &comment, nil
 }
 
 // GetPostComments fetches all comments for a given post, including the requesting user's vote.
 func (p *PostgresDB) GetPostComments(ctx context.Context, postID uuid.UUID, requestingUserID uuid.UUID) ([]*models.Comment, error) {
-	// Temporary struct to scan vote_type robustly
-	type ScanComment struct {
-		models.Comment
-		RawVoteType sql.NullString `db:"current_user_vote"`
-	}
-
 	query := `
 		SELECT
-			c.id, c.content, c.author_id, u.username AS author_username, c.post_id,
+			c.id, c.content, c.author_id, u.username AS author_username, u.is_bot AS author_is_bot, c.post_id,
 			p.subreddit_id, c.parent_id, c.created_at, c.updated_at,
-			c.upvotes, c.downvotes, c.karma,
-			v.vote_type AS current_user_vote
+			c.upvotes, c.downvotes, c.karma, c.reply_count, c.deleted_at,
+			` + currentUserVoteColumn + `
 		FROM comments c
 		JOIN users u ON c.author_id = u.id
 		JOIN posts p ON c.post_id = p.id
-		LEFT JOIN votes v ON c.id = v.content_id AND v.content_type = 'comment' AND v.user_id = $2
-		WHERE c.post_id = $1
+		` + currentUserVoteJoin("c", models.CommentVote, "$2") + `
+		WHERE c.post_id = $1` + notDeleted(ctx, "c") + `
 		ORDER BY c.created_at ASC
 	`
-	var scannedComments []*ScanComment
-	err := p.DB.SelectContext(ctx, &scannedComments, query, postID, requestingUserID)
+	comments := []*models.Comment{}
+	err := p.DB.SelectContext(ctx, &comments, query, postID, requestingUserID)
 	if err != nil {
-		log.Printf("Error querying post comments: %v. Query: %s, PostID: %s, UserID: %s", err, query, postID, requestingUserID)
+		p.Logger.ErrorContext(ctx, "Failed to query post comments", "post_id", postID, "error", err)
 		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query post comments", err)
 	}
-
-	comments := make([]*models.Comment, len(scannedComments))
-	for i, sc := range scannedComments {
-		comment := sc.Comment // Extract the embedded Comment
-		if sc.RawVoteType.Valid {
-			rawVote := sc.RawVoteType.String
-			if rawVote == "1" || rawVote == "up" { // Handle integer or potential string "up"
-				up := "up"
-				comment.CurrentUserVote = &up
-			} else if rawVote == "-1" || rawVote == "down" { // Handle integer or potential string "down"
-				down := "down"
-				comment.CurrentUserVote = &down
-			}
-		}
-		// Ensure AuthorUsername is populated (it should be from the JOIN)
-		if comment.AuthorUsername == "" && sc.Comment.AuthorUsername != "" { // Redundant check, but safe
-			comment.AuthorUsername = sc.Comment.AuthorUsername
-		}
-		comments[i] = &comment
-	}
+	hydrateCommentVotes(comments)
 
 	return comments, nil
 }
 
-// DeleteCommentAndDecrementCount performs a hard delete of a comment and decrements the comment_count on the post.
-func (p *PostgresDB) DeleteCommentAndDecrementCount(ctx context.Context, commentID uuid.UUID) error {
-	tx, err := p.DB.BeginTxx(ctx, nil)
-	if err != nil {
-		return utils.NewAppError(utils.ErrDatabase, "failed to begin transaction for delete comment", err)
+// GetCommentThread fetches a window of a post's comment tree: up to width
+// replies to parentID (top-level comments if nil) after afterID, and up to
+// width replies to each of those, depth levels deep. It also reports whether
+// more replies to parentID follow the window.
+func (p *PostgresDB) GetCommentThread(ctx context.Context, postID uuid.UUID, parentID, afterID *uuid.UUID, width, depth int, requestingUserID uuid.UUID) ([]*models.CommentNode, bool, error) {
+	// Each level fetches one reply past width, which is dropped and only
+	// tells that its parent has more
+	query := `
+		WITH RECURSIVE thread AS (
+			SELECT id, 1 AS depth, rn FROM (
+				SELECT c.id, ROW_NUMBER() OVER (ORDER BY c.created_at, c.id) AS rn
+				FROM comments c
+				WHERE c.post_id = $1
+					AND (($2::uuid IS NULL AND c.parent_id IS NULL) OR c.parent_id = $2)
+					AND ($3::uuid IS NULL OR (c.created_at, c.id) > (SELECT a.created_at, a.id FROM comments a WHERE a.id = $3))` + notDeleted(ctx, "c") + `
+				ORDER BY c.created_at, c.id
+				LIMIT $4 + 1
+			) top
+			UNION ALL
+			SELECT r.id, t.depth + 1, r.rn
+			FROM thread t
+			CROSS JOIN LATERAL (
+				SELECT c.id, ROW_NUMBER() OVER (ORDER BY c.created_at, c.id) AS rn
+				FROM comments c
+				WHERE c.post_id = $1 AND c.parent_id = t.id` + notDeleted(ctx, "c") + `
+				ORDER BY c.created_at, c.id
+				LIMIT $4 + 1
+			) r
+			WHERE t.depth < $5 AND t.rn <= $4
+		)
+		SELECT
+			t.depth, t.rn,
+			EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = c.id` + notDeleted(ctx, "r") + `) AS has_replies,
+			c.id, c.content, c.author_id, u.username AS author_username, u.is_bot AS author_is_bot, c.post_id,
+			p.subreddit_id, c.parent_id, c.created_at, c.updated_at,
+			c.upvotes, c.downvotes, c.karma, c.reply_count, c.deleted_at,
+			` + currentUserVoteColumn + `
+		FROM thread t
+		JOIN comments c ON c.id = t.id
+		JOIN users u ON c.author_id = u.id
+		JOIN posts p ON c.post_id = p.id
+		` + currentUserVoteJoin("c", models.CommentVote, "$6") + `
+		ORDER BY t.depth, c.created_at, c.id
+	`
+	var rows []*struct {
+		models.Comment
+		Depth      int  `db:"depth"`
+		Rank       int  `db:"rn"`
+		HasReplies bool `db:"has_replies"`
 	}
-
-	var postID uuid.UUID
-	// Get the post_id of the comment to be deleted
-	getPostIDQuery := `SELECT post_id FROM comments WHERE id = $1`
-	err = tx.GetContext(ctx, &postID, getPostIDQuery, commentID)
+	err := p.DB.SelectContext(ctx, &rows, query, postID, parentID, afterID, width, depth, requestingUserID)
 	if err != nil {
-		tx.Rollback()
-		if err == sql.ErrNoRows {
-			return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("comment %s not found for deletion", commentID), err)
+		return nil, false, utils.NewAppError(utils.ErrDatabase, "failed to query comment thread", err)
+	}
+
+	// Rows come a level at a time, so parents are placed before their replies
+	var top []*models.CommentNode
+	more := false
+	nodes := make(map[uuid.UUID]*models.CommentNode, len(rows))
+	for _, row := range rows {
+		row.CurrentUserVote = normalizeVote(row.CurrentUserVote)
+		var parent *models.CommentNode
+		if row.Depth > 1 {
+			parent = nodes[*row.ParentID]
+		}
+		if row.Rank > width {
+			if parent == nil {
+				more = true
+			} else {
+				parent.More = true
+			}
+			continue
 		}
-		return utils.NewAppError(utils.ErrDatabase, "failed to get post_id from comment for deletion", err)
-	}
-
-	// Delete the comment
-	deleteCommentQuery := `DELETE FROM comments WHERE id = $1`
-	result, err := tx.ExecContext(ctx, deleteCommentQuery, commentID)
-	if err != nil {
-		tx.Rollback()
-		return utils.NewAppError(utils.ErrDatabase, "failed to delete comment", err)
-	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		tx.Rollback() // Should not happen if GetContext above found it, but as a safeguard.
-		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("comment %s not found during deletion exec, though it was found earlier", commentID), nil)
+		node := &models.CommentNode{Comment: &row.Comment, More: row.Depth == depth && row.HasReplies}
+		nodes[row.ID] = node
+		if parent == nil {
+			top = append(top, node)
+		} else {
+			parent.Replies = append(parent.Replies, node)
+		}
 	}
+	return top, more, nil
+}
 
-	// Decrement the post's comment_count
-	updatePostCountQuery := `UPDATE posts SET comment_count = GREATEST(0, comment_count - 1), updated_at = NOW() WHERE id = $1`
-	postUpdateResult, err := tx.ExecContext(ctx, updatePostCountQuery, postID)
+// CountCommentsByPost counts the comments stored for a post.
+func (p *PostgresDB) CountCommentsByPost(ctx context.Context, postID uuid.UUID) (int, error) {
+	var count int
+	err := p.DB.GetContext(ctx, &count, `SELECT COUNT(*) FROM comments WHERE post_id = $1 AND deleted_at IS NULL`, postID)
 	if err != nil {
-		tx.Rollback()
-		log.Printf("Failed to decrement comment_count for post %s: %v. Rolling back comment deletion.", postID, err)
-		return utils.NewAppError(utils.ErrDatabase, "failed to update post comment_count after deleting comment", err)
-	}
-
-	postRowsAffected, _ := postUpdateResult.RowsAffected()
-	if postRowsAffected == 0 {
-		tx.Rollback()
-		log.Printf("Post %s not found when trying to decrement comment_count after comment deletion. Rolling back.", postID)
-		// This indicates a data integrity issue if the comment had a post_id for a non-existent post.
-		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("post %s associated with deleted comment %s not found for count update", postID, commentID), nil)
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to count post comments", err)
 	}
-
-	return tx.Commit()
+	return count, nil
 }
 
 // GetAllComments fetches all comments (used for initial loading).
 func (p *PostgresDB) GetAllComments(ctx context.Context) ([]*models.Comment, error) {
-	query := `SELECT id, content, author_id, post_id, parent_id, karma, upvotes, downvotes, created_at, updated_at FROM comments ORDER BY created_at ASC`
+	query := `SELECT id, content, author_id, post_id, parent_id, karma, upvotes, downvotes, reply_count, created_at, updated_at FROM comments WHERE deleted_at IS NULL ORDER BY created_at ASC`
 	var comments []*models.Comment
 	err := p.DB.SelectContext(ctx, &comments, query)
 	if err != nil {
@@ -1036,23 +1281,43 @@ func (p *PostgresDB) SaveMessage(ctx context.Context, msg *models.DirectMessage)
 	if msg.CreatedAt.IsZero() {
 		msg.CreatedAt = time.Now()
 	}
-	// Note: msg.ReadAt is handled by UpdateMessageStatus
+	// Note: msg.ReadAt is handled by UpdateMessageStatus; DeliveredAt is set
+	// already if the message was pushed as it was sent
 
+	// Idempotent so a retried save job doesn't fail on its own earlier insert
 	query := `
-		INSERT INTO messages (id, sender_id, receiver_id, content, created_at, read_at)
-		VALUES (:id, :sender_id, :receiver_id, :content, :created_at, :read_at)
+		INSERT INTO messages (id, sender_id, receiver_id, content, created_at, delivered_at, read_at)
+		VALUES (:id, :sender_id, :receiver_id, :content, :created_at, :delivered_at, :read_at)
+		ON CONFLICT (id) DO NOTHING
 	`
-	_, err := p.DB.NamedExecContext(ctx, query, msg)
+	tx, err := p.DB.BeginTxx(ctx, nil)
 	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to begin transaction for save message", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.NamedExecContext(ctx, query, msg); err != nil {
 		return utils.NewAppError(utils.ErrDatabase, "failed to save message", err)
 	}
+	// A reply from the inbox marks the message it answers read with it
+	if msg.InReplyTo != nil {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE messages SET read_at = NOW(), delivered_at = COALESCE(delivered_at, NOW())
+			WHERE id = $1 AND receiver_id = $2 AND read_at IS NULL`, *msg.InReplyTo, msg.FromID)
+		if err != nil {
+			return utils.NewAppError(utils.ErrDatabase, "failed to mark answered message read", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to commit message", err)
+	}
 	return nil
 }
 
 // GetMessagesByUser fetches all messages sent or received by a user.
 func (p *PostgresDB) GetMessagesByUser(ctx context.Context, userID uuid.UUID) ([]*models.DirectMessage, error) {
 	query := `
-		SELECT id, sender_id, receiver_id, content, created_at, read_at 
+		SELECT id, sender_id, receiver_id, content, created_at, delivered_at, read_at
 		FROM messages 
 		WHERE sender_id = $1 OR receiver_id = $1 
 		ORDER BY created_at ASC
@@ -1065,38 +1330,359 @@ func (p *PostgresDB) GetMessagesByUser(ctx context.Context, userID uuid.UUID) ([
 	if messages == nil {
 		messages = make([]*models.DirectMessage, 0)
 	}
-	// Set IsRead based on ReadAt for every message
+	// Set IsDelivered and IsRead based on the timestamps for every message
 	for _, msg := range messages {
+		msg.IsDelivered = msg.DeliveredAt != nil
 		msg.IsRead = msg.ReadAt != nil
 	}
 	return messages, nil
 }
 
-// UpdateMessageStatus updates the read status of a message.
+// GetUnreadMessageCount counts the messages a user received and hasn't read.
+func (p *PostgresDB) GetUnreadMessageCount(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := p.DB.GetContext(ctx, &count, `SELECT COUNT(*) FROM messages WHERE receiver_id = $1 AND read_at IS NULL`, userID)
+	if err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to count unread messages", err)
+	}
+	return count, nil
+}
+
+// UpdateMessageStatus updates the delivery and read status of a message.
+// Reading a message also marks it delivered.
 // Note: The IsDeleted flag from the interface is ignored as it's not in the DB schema.
-func (p *PostgresDB) UpdateMessageStatus(ctx context.Context, msgID uuid.UUID, isRead *bool, isDeleted *bool) error {
-	if isRead == nil || !*isRead {
-		// We only care about marking as read. If isRead is nil or false, do nothing.
+func (p *PostgresDB) UpdateMessageStatus(ctx context.Context, msgID uuid.UUID, isRead *bool, isDeleted *bool, isDelivered *bool) error {
+	var query string
+	switch {
+	case isRead != nil && *isRead:
+		query = `UPDATE messages SET read_at = NOW(), delivered_at = COALESCE(delivered_at, NOW()) WHERE id = $1 AND read_at IS NULL`
+	case isDelivered != nil && *isDelivered:
+		query = `UPDATE messages SET delivered_at = NOW() WHERE id = $1 AND delivered_at IS NULL`
+	default:
+		// Nothing is ever marked unread or undelivered
 		return nil
 	}
 
-	// Set read_at to current time if isRead is true
-	query := `UPDATE messages SET read_at = NOW() WHERE id = $1 AND read_at IS NULL`
 	result, err := p.DB.ExecContext(ctx, query, msgID)
 	if err != nil {
-		return utils.NewAppError(utils.ErrDatabase, "failed to update message read status", err)
+		return utils.NewAppError(utils.ErrDatabase, "failed to update message status", err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
 		// This isn't necessarily an error - the message might not exist or might already be read.
 		// Depending on requirements, could return ErrNotFound or just log.
-		// log.Printf("Message %s not found or already marked as read during status update", msgID)
 	}
 
 	return nil
 }
 
+const jobColumns = `id, type, payload, status, attempts, max_attempts, unique_key, run_at, locked_until, last_error, created_at, updated_at`
+
+// EnqueueJob inserts a pending job. If the job has a UniqueKey and another
+// pending or running job holds it, nothing is inserted and false is returned.
+func (p *PostgresDB) EnqueueJob(ctx context.Context, job *models.Job) (bool, error) {
+	query := `
+		INSERT INTO jobs (id, type, payload, status, max_attempts, unique_key, run_at)
+		VALUES ($1, $2, $3::jsonb, 'pending', $4, $5, $6)
+		ON CONFLICT (unique_key) WHERE status IN ('pending', 'running') DO NOTHING
+	`
+	result, err := p.DB.ExecContext(ctx, query, job.ID, job.Type, string(job.Payload), job.MaxAttempts, job.UniqueKey, job.RunAt)
+	if err != nil {
+		return false, utils.NewAppError(utils.ErrDatabase, "failed to enqueue job", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// ClaimJobs atomically marks up to limit due jobs as running for lease and
+// returns them. Running jobs whose lease expired (crashed worker) are
+// reclaimed. SKIP LOCKED lets several engine instances poll concurrently.
+func (p *PostgresDB) ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]*models.Job, error) {
+	query := `
+		UPDATE jobs
+		SET status = 'running',
+		    attempts = attempts + 1,
+		    locked_until = NOW() + $2 * INTERVAL '1 millisecond',
+		    updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM jobs
+			WHERE (status = 'pending' AND run_at <= NOW())
+			   OR (status = 'running' AND locked_until < NOW())
+			ORDER BY run_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+	jobs := []*models.Job{}
+	if err := p.DB.SelectContext(ctx, &jobs, query, limit, lease.Milliseconds()); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to claim jobs", err)
+	}
+	return jobs, nil
+}
+
+// CompleteJob marks a job as done.
+func (p *PostgresDB) CompleteJob(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE jobs SET status = 'done', locked_until = NULL, last_error = NULL, updated_at = NOW() WHERE id = $1`
+	if _, err := p.DB.ExecContext(ctx, query, id); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to complete job", err)
+	}
+	return nil
+}
+
+// FailJob records a failed attempt. With a retryAt the job goes back to
+// pending until then; without one it is marked permanently failed.
+func (p *PostgresDB) FailJob(ctx context.Context, id uuid.UUID, lastError string, retryAt *time.Time) error {
+	var err error
+	if retryAt != nil {
+		_, err = p.DB.ExecContext(ctx,
+			`UPDATE jobs SET status = 'pending', run_at = $2, locked_until = NULL, last_error = $3, updated_at = NOW() WHERE id = $1`,
+			id, *retryAt, lastError)
+	} else {
+		_, err = p.DB.ExecContext(ctx,
+			`UPDATE jobs SET status = 'failed', locked_until = NULL, last_error = $2, updated_at = NOW() WHERE id = $1`,
+			id, lastError)
+	}
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to record job failure", err)
+	}
+	return nil
+}
+
+// ReconcileCounters recomputes denormalized counters (post comment_count,
+// comment reply_count, subreddit member_count and post_count, user
+// post_karma and comment_karma) from their source tables and returns how
+// many rows had drifted. Posts and comments start with a karma of 1 that
+// was never credited to their author, so author karma leaves it out.
+func (p *PostgresDB) ReconcileCounters(ctx context.Context) (int64, error) {
+	statements := []string{
+		`UPDATE posts p SET comment_count = c.n
+		FROM (SELECT p2.id, COUNT(c2.id) AS n FROM posts p2 LEFT JOIN comments c2 ON c2.post_id = p2.id AND c2.deleted_at IS NULL GROUP BY p2.id) c
+		WHERE p.id = c.id AND p.comment_count IS DISTINCT FROM c.n`,
+		`UPDATE comments c SET reply_count = r.n
+		FROM (SELECT c2.id, COUNT(r2.id) AS n FROM comments c2 LEFT JOIN comments r2 ON r2.parent_id = c2.id AND r2.deleted_at IS NULL GROUP BY c2.id) r
+		WHERE c.id = r.id AND c.reply_count IS DISTINCT FROM r.n`,
+		`UPDATE subreddits s SET member_count = m.n
+		FROM (SELECT s2.id, COUNT(m2.user_id) AS n FROM subreddits s2 LEFT JOIN subreddit_members m2 ON m2.subreddit_id = s2.id GROUP BY s2.id) m
+		WHERE s.id = m.id AND s.member_count IS DISTINCT FROM m.n`,
+		`UPDATE subreddits s SET post_count = c.n
+		FROM (SELECT s2.id, COUNT(p2.id) AS n FROM subreddits s2 LEFT JOIN posts p2 ON p2.subreddit_id = s2.id AND p2.deleted_at IS NULL GROUP BY s2.id) c
+		WHERE s.id = c.id AND s.post_count IS DISTINCT FROM c.n`,
+		`UPDATE users u SET post_karma = k.n
+		FROM (SELECT u2.id, COALESCE(SUM(p2.karma - 1), 0) AS n FROM users u2 LEFT JOIN posts p2 ON p2.author_id = u2.id WHERE u2.merged_into IS NULL GROUP BY u2.id) k
+		WHERE u.id = k.id AND u.post_karma IS DISTINCT FROM k.n`,
+		`UPDATE users u SET comment_karma = k.n
+		FROM (SELECT u2.id, COALESCE(SUM(c2.karma - 1), 0) AS n FROM users u2 LEFT JOIN comments c2 ON c2.author_id = u2.id WHERE u2.merged_into IS NULL GROUP BY u2.id) k
+		WHERE u.id = k.id AND u.comment_karma IS DISTINCT FROM k.n`,
+	}
+
+	var fixed int64
+	for _, stmt := range statements {
+		result, err := p.DB.ExecContext(ctx, stmt)
+		if err != nil {
+			return fixed, utils.NewAppError(utils.ErrDatabase, "failed to reconcile counters", err)
+		}
+		rows, _ := result.RowsAffected()
+		fixed += rows
+	}
+	return fixed, nil
+}
+
+// RecordAudit appends an entry to the audit log.
+func (p *PostgresDB) RecordAudit(ctx context.Context, entry *models.AuditEntry) error {
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	var details interface{}
+	if len(entry.Details) > 0 {
+		details = []byte(entry.Details)
+	}
+	query := `
+		INSERT INTO audit_log (id, actor_id, subject_id, action, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := p.DB.ExecContext(ctx, query, entry.ID, entry.ActorID, entry.SubjectID, entry.Action, details, entry.CreatedAt)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to record audit entry", err)
+	}
+	return nil
+}
+
+// GetAuditLog lists audit entries newest first, optionally only those about subjectID.
+func (p *PostgresDB) GetAuditLog(ctx context.Context, subjectID *uuid.UUID, limit, offset int) ([]*models.AuditEntry, error) {
+	query := `
+		SELECT id, actor_id, subject_id, action, details, created_at FROM audit_log
+		WHERE $1::uuid IS NULL OR subject_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	entries := []*models.AuditEntry{}
+	if err := p.DB.SelectContext(ctx, &entries, query, subjectID, limit, offset); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query audit log", err)
+	}
+	return entries, nil
+}
+
+// AcquireTaskLease claims a scheduled task for lease if it hasn't started
+// within interval and no other instance holds it. Returns false when the
+// task isn't due or is running elsewhere.
+func (p *PostgresDB) AcquireTaskLease(ctx context.Context, name string, interval, lease time.Duration) (bool, error) {
+	query := `
+		INSERT INTO scheduled_tasks (name, locked_until, last_started_at)
+		VALUES ($1, NOW() + $3 * INTERVAL '1 millisecond', NOW())
+		ON CONFLICT (name) DO UPDATE
+		SET locked_until = EXCLUDED.locked_until, last_started_at = EXCLUDED.last_started_at
+		WHERE (scheduled_tasks.locked_until IS NULL OR scheduled_tasks.locked_until < NOW())
+		  AND (scheduled_tasks.last_started_at IS NULL OR scheduled_tasks.last_started_at <= NOW() - $2 * INTERVAL '1 millisecond')
+	`
+	result, err := p.DB.ExecContext(ctx, query, name, interval.Milliseconds(), lease.Milliseconds())
+	if err != nil {
+		return false, utils.NewAppError(utils.ErrDatabase, "failed to acquire task lease", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// ReleaseTaskLease records a task run's outcome and frees its lease.
+func (p *PostgresDB) ReleaseTaskLease(ctx context.Context, name string, runErr error) error {
+	var lastError *string
+	if runErr != nil {
+		msg := runErr.Error()
+		lastError = &msg
+	}
+	query := `UPDATE scheduled_tasks SET locked_until = NULL, last_finished_at = NOW(), last_error = $2 WHERE name = $1`
+	if _, err := p.DB.ExecContext(ctx, query, name, lastError); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to release task lease", err)
+	}
+	return nil
+}
+
+// DecayHotScores recomputes hot_score for posts newer than window, so
+// scores fall as posts age, and zeroes scores that have aged out.
+// The score is net votes divided by (age in hours + 2)^1.8.
+func (p *PostgresDB) DecayHotScores(ctx context.Context, window time.Duration) (int64, error) {
+	query := `
+		UPDATE posts SET hot_score = CASE
+			WHEN created_at > NOW() - $1 * INTERVAL '1 millisecond'
+			THEN (karma - 1) / POWER(EXTRACT(EPOCH FROM NOW() - created_at) / 3600 + 2, 1.8)
+			ELSE 0
+		END
+		WHERE created_at > NOW() - $1 * INTERVAL '1 millisecond' OR hot_score <> 0
+	`
+	result, err := p.DB.ExecContext(ctx, query, window.Milliseconds())
+	if err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to decay hot scores", err)
+	}
+	return result.RowsAffected()
+}
+
+// UpdateKarmaVelocity refreshes each user's karma_velocity, the karma gained
+// per hour since the previous run, smoothed with the previous value so it
+// decays back to zero once a user stops gaining karma.
+func (p *PostgresDB) UpdateKarmaVelocity(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE users u SET
+			karma_velocity = CASE WHEN ABS(c.velocity) < 0.01 THEN 0 ELSE c.velocity END,
+			karma_snapshot = u.karma,
+			karma_snapshot_at = NOW()
+		FROM (
+			SELECT id, CASE WHEN karma_snapshot_at IS NULL THEN 0
+				ELSE 0.5 * karma_velocity + 0.5 * (karma - karma_snapshot)
+					/ GREATEST(EXTRACT(EPOCH FROM NOW() - karma_snapshot_at) / 3600, 0.01)
+			END AS velocity
+			FROM users
+			WHERE karma_snapshot_at IS NULL OR karma IS DISTINCT FROM karma_snapshot OR karma_velocity <> 0
+		) c
+		WHERE u.id = c.id
+	`
+	result, err := p.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to update karma velocity", err)
+	}
+	return result.RowsAffected()
+}
+
+// RefreshTrendingSubreddits replaces trending_subreddits with the limit
+// subreddits with the most posts (weighted double) and comments in window.
+func (p *PostgresDB) RefreshTrendingSubreddits(ctx context.Context, window time.Duration, limit int) (int64, error) {
+	tx, err := p.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to begin trending refresh", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM trending_subreddits`); err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to clear trending subreddits", err)
+	}
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO trending_subreddits (subreddit_id, score, post_count, comment_count, refreshed_at)
+		SELECT s.id, 2 * COALESCE(p.n, 0) + COALESCE(c.n, 0), COALESCE(p.n, 0), COALESCE(c.n, 0), NOW()
+		FROM subreddits s
+		LEFT JOIN (
+			SELECT subreddit_id, COUNT(*) AS n FROM posts
+			WHERE created_at > NOW() - $1 * INTERVAL '1 millisecond' AND deleted_at IS NULL GROUP BY subreddit_id
+		) p ON p.subreddit_id = s.id
+		LEFT JOIN (
+			SELECT po.subreddit_id, COUNT(*) AS n FROM comments cm JOIN posts po ON po.id = cm.post_id
+			WHERE cm.created_at > NOW() - $1 * INTERVAL '1 millisecond' AND cm.deleted_at IS NULL GROUP BY po.subreddit_id
+		) c ON c.subreddit_id = s.id
+		WHERE s.deleted_at IS NULL AND COALESCE(p.n, 0) + COALESCE(c.n, 0) > 0
+		ORDER BY 2 DESC
+		LIMIT $2
+	`, window.Milliseconds(), limit)
+	if err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to compute trending subreddits", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to commit trending refresh", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetTrendingSubreddits returns the subreddits RefreshTrendingSubreddits
+// last picked, busiest first, leaving out NSFW and quarantined ones the
+// requesting user hasn't opted into.
+func (p *PostgresDB) GetTrendingSubreddits(ctx context.Context, requestingUserID uuid.UUID) ([]*models.Subreddit, error) {
+	query := `
+		SELECT s.id, s.name, s.description, s.created_by, s.member_count, s.post_count, s.created_at, s.nsfw, s.quarantined
+		FROM trending_subreddits t
+		JOIN subreddits s ON s.id = t.subreddit_id AND s.deleted_at IS NULL
+		LEFT JOIN user_preferences pref ON pref.user_id = $1
+		WHERE ` + preferredRatings + `
+		ORDER BY t.score DESC, s.name
+	`
+	subs := []*models.Subreddit{}
+	if err := p.DB.SelectContext(ctx, &subs, query, requestingUserID); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query trending subreddits", err)
+	}
+	return subs, nil
+}
+
+// ArchiveOldPosts marks posts older than olderThan as archived.
+func (p *PostgresDB) ArchiveOldPosts(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := `UPDATE posts SET archived = TRUE WHERE NOT archived AND created_at < NOW() - $1 * INTERVAL '1 millisecond'`
+	result, err := p.DB.ExecContext(ctx, query, olderThan.Milliseconds())
+	if err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to archive old posts", err)
+	}
+	return result.RowsAffected()
+}
+
+// ClearStaleConnections marks users disconnected when they are flagged
+// connected but haven't been active for idleFor (e.g. the engine crashed
+// before they disconnected).
+func (p *PostgresDB) ClearStaleConnections(ctx context.Context, idleFor time.Duration) (int64, error) {
+	query := `UPDATE users SET is_connected = FALSE, updated_at = NOW() WHERE is_connected AND last_active < NOW() - $1 * INTERVAL '1 millisecond'`
+	result, err := p.DB.ExecContext(ctx, query, idleFor.Milliseconds())
+	if err != nil {
+		return 0, utils.NewAppError(utils.ErrDatabase, "failed to clear stale connections", err)
+	}
+	return result.RowsAffected()
+}
+
 // Implementation of repository methods will go here
 // This is just a starting template - you'll need to implement all the repository
 // methods that are currently defined in your PostgreSQL implementation
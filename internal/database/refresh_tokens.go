@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// Refresh tokens are stored by their SHA-256 hash, so a leaked table can't be
+// used to mint access tokens. Each is used once: refreshing revokes it and
+// issues the next one.
+
+// CreateRefreshToken stores a new refresh token for a user.
+func (p *PostgresDB) CreateRefreshToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	_, err := p.DB.ExecContext(ctx, `INSERT INTO refresh_tokens (token_hash, user_id, expires_at) VALUES ($1, $2, $3)`, tokenHash, userID, expiresAt)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to store refresh token", err)
+	}
+	return nil
+}
+
+// RotateRefreshToken revokes the refresh token with oldHash and stores
+// newHash in its place, returning the user it belongs to. A token that was
+// already used is taken as stolen: all of its user's tokens are revoked.
+func (p *PostgresDB) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (uuid.UUID, error) {
+	tx, err := p.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, utils.NewAppError(utils.ErrDatabase, "failed to begin token refresh", err)
+	}
+	defer tx.Rollback()
+
+	var token struct {
+		UserID    uuid.UUID  `db:"user_id"`
+		ExpiresAt time.Time  `db:"expires_at"`
+		RevokedAt *time.Time `db:"revoked_at"`
+	}
+	err = tx.GetContext(ctx, &token, `SELECT user_id, expires_at, revoked_at FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`, oldHash)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, utils.NewAppError(utils.ErrInvalidToken, "invalid refresh token", nil)
+	}
+	if err != nil {
+		return uuid.Nil, utils.NewAppError(utils.ErrDatabase, "failed to look up refresh token", err)
+	}
+
+	if token.RevokedAt != nil {
+		_, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, token.UserID)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			return uuid.Nil, utils.NewAppError(utils.ErrDatabase, "failed to revoke user's refresh tokens", err)
+		}
+		return uuid.Nil, utils.NewAppError(utils.ErrInvalidToken, "refresh token was already used", nil)
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return uuid.Nil, utils.NewAppError(utils.ErrInvalidToken, "refresh token expired", nil)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE token_hash = $1`, oldHash); err != nil {
+		return uuid.Nil, utils.NewAppError(utils.ErrDatabase, "failed to revoke refresh token", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO refresh_tokens (token_hash, user_id, expires_at) VALUES ($1, $2, $3)`, newHash, token.UserID, expiresAt); err != nil {
+		return uuid.Nil, utils.NewAppError(utils.ErrDatabase, "failed to store refresh token", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, utils.NewAppError(utils.ErrDatabase, "failed to commit token refresh", err)
+	}
+	return token.UserID, nil
+}
+
+// RevokeRefreshToken revokes a refresh token. Unknown and already revoked
+// tokens are left alone, so logging out twice isn't an error.
+func (p *PostgresDB) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	_, err := p.DB.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE token_hash = $1 AND revoked_at IS NULL`, tokenHash)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to revoke refresh token", err)
+	}
+	return nil
+}
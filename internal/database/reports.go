@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const reportColumns = `id, reporter_id, target_type, target_id, subreddit_id, reason, status, resolved_by, note, created_at, resolved_at`
+
+// CreateReport files a report. A user can have one open report per target;
+// another is a Duplicate error.
+func (p *PostgresDB) CreateReport(ctx context.Context, report *models.Report) error {
+	query := `
+		INSERT INTO reports (id, reporter_id, target_type, target_id, subreddit_id, reason, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`
+	err := p.DB.QueryRowxContext(ctx, query, report.ID, report.ReporterID, report.TargetType, report.TargetID,
+		report.SubredditID, report.Reason, report.Status).Scan(&report.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+		return utils.NewAppError(utils.ErrDuplicate, fmt.Sprintf("you already reported this %s", report.TargetType), err)
+	}
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to create report", err)
+	}
+	return nil
+}
+
+// GetReport fetches a report by its ID.
+func (p *PostgresDB) GetReport(ctx context.Context, id uuid.UUID) (*models.Report, error) {
+	var report models.Report
+	err := p.DB.GetContext(ctx, &report, `SELECT `+reportColumns+` FROM reports WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("report %s not found", id), err)
+	}
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to fetch report", err)
+	}
+	return &report, nil
+}
+
+// ListReports lists a subreddit's reports with the given status, oldest
+// first for open reports so the queue is worked in order, and newest first
+// otherwise.
+func (p *PostgresDB) ListReports(ctx context.Context, subredditID uuid.UUID, status models.ReportStatus, limit, offset int) ([]*models.Report, error) {
+	order := "created_at DESC, id DESC"
+	if status == models.ReportOpen {
+		order = "created_at ASC, id ASC"
+	}
+	reports := []*models.Report{}
+	err := p.DB.SelectContext(ctx, &reports, `SELECT `+reportColumns+`
+		FROM reports WHERE subreddit_id = $1 AND status = $2
+		ORDER BY `+order+` LIMIT $3 OFFSET $4`, subredditID, status, limit, offset)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to list reports", err)
+	}
+	return reports, nil
+}
+
+// CloseReport resolves or dismisses an open report. Closing one that's
+// already closed is an InvalidInput error.
+func (p *PostgresDB) CloseReport(ctx context.Context, report *models.Report) error {
+	query := `
+		UPDATE reports SET status = $2, resolved_by = $3, note = $4, resolved_at = NOW()
+		WHERE id = $1 AND status = 'open'
+		RETURNING resolved_at
+	`
+	err := p.DB.QueryRowxContext(ctx, query, report.ID, report.Status, report.ResolvedBy, report.Note).Scan(&report.ResolvedAt)
+	if err == sql.ErrNoRows {
+		return utils.NewAppError(utils.ErrInvalidInput, fmt.Sprintf("report %s is already closed", report.ID), err)
+	}
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to close report", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+)
+
+// Retention targets: classes of rows that expire, keyed by the name used in
+// retention policies. Each condition takes the cutoff age in milliseconds as $1.
+const (
+	RetentionAuditLog  = "audit_log"
+	RetentionJobs      = "jobs"
+	RetentionPostViews = "post_views"
+
+	// Refresh tokens, counted from when they were used, revoked or expired
+	RetentionRefreshTokens = "refresh_tokens"
+
+	// Soft-deleted content. Rows still referenced by other rows (a deleted
+	// comment's replies, a deleted post's comments) wait until those go first.
+	RetentionDeletedComments = "deleted_comments"
+	RetentionDeletedPosts    = "deleted_posts"
+)
+
+type retentionTarget struct {
+	table string
+	where string
+	votes models.VoteContentType // Votes on purged rows go with them
+}
+
+var retentionTargets = map[string]retentionTarget{
+	RetentionAuditLog:      {"audit_log", "created_at < NOW() - $1 * INTERVAL '1 millisecond'", ""},
+	RetentionJobs:          {"jobs", "status IN ('done', 'failed') AND updated_at < NOW() - $1 * INTERVAL '1 millisecond'", ""},
+	RetentionPostViews:     {"post_views", "seen_at < NOW() - $1 * INTERVAL '1 millisecond'", ""},
+	RetentionRefreshTokens: {"refresh_tokens", "LEAST(revoked_at, expires_at) < NOW() - $1 * INTERVAL '1 millisecond'", ""},
+	RetentionDeletedComments: {"comments", "deleted_at < NOW() - $1 * INTERVAL '1 millisecond'" +
+		" AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = comments.id)", models.CommentVote},
+	RetentionDeletedPosts: {"posts", "deleted_at < NOW() - $1 * INTERVAL '1 millisecond'" +
+		" AND NOT EXISTS (SELECT 1 FROM comments c WHERE c.post_id = posts.id)", models.PostVote},
+}
+
+// purgeBatchSize bounds each DELETE so a large backlog doesn't hold long locks.
+const purgeBatchSize = 5000
+
+// PurgeExpired deletes target's rows older than olderThan, in batches, and
+// returns how many were deleted. Batches repeat until one deletes nothing, so
+// each run also clears deleted comment threads one level at a time. With
+// dryRun it only counts the rows deletable right now.
+func (p *PostgresDB) PurgeExpired(ctx context.Context, target string, olderThan time.Duration, dryRun bool) (int64, error) {
+	t, ok := retentionTargets[target]
+	if !ok {
+		return 0, utils.NewAppError(utils.ErrInvalidInput, fmt.Sprintf("unknown retention target %q", target), nil)
+	}
+	cutoff := olderThan.Milliseconds()
+
+	if dryRun {
+		var count int64
+		query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, t.table, t.where)
+		if err := p.DB.GetContext(ctx, &count, query, cutoff); err != nil {
+			return 0, utils.NewAppError(utils.ErrDatabase, "failed to count expired "+target, err)
+		}
+		return count, nil
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %[1]s WHERE ctid IN (SELECT ctid FROM %[1]s WHERE %[2]s LIMIT %[3]d)`,
+		t.table, t.where, purgeBatchSize)
+	if t.votes != "" {
+		// One statement, so rows and their votes go together
+		query = fmt.Sprintf(`WITH purged AS (%s RETURNING id),
+			purged_votes AS (DELETE FROM votes WHERE content_type = '%s' AND content_id IN (SELECT id FROM purged))
+			SELECT COUNT(*) FROM purged`, query, t.votes)
+	}
+	var total int64
+	for {
+		var n int64
+		if t.votes != "" {
+			err := p.DB.GetContext(ctx, &n, query, cutoff)
+			if err != nil {
+				return total, utils.NewAppError(utils.ErrDatabase, "failed to purge expired "+target, err)
+			}
+		} else {
+			result, err := p.DB.ExecContext(ctx, query, cutoff)
+			if err != nil {
+				return total, utils.NewAppError(utils.ErrDatabase, "failed to purge expired "+target, err)
+			}
+			n, _ = result.RowsAffected()
+		}
+		total += n
+		if n == 0 {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// The text posts, comments and subreddits are searched by. The *_search
+// indexes are built on these exact expressions, so changing one needs a
+// migration that rebuilds its index.
+const (
+	postSearchVector      = `to_tsvector('english', title || ' ' || COALESCE(content, ''))`
+	commentSearchVector   = `to_tsvector('english', content)`
+	subredditSearchVector = `to_tsvector('english', name || ' ' || COALESCE(description, ''))`
+)
+
+// searchOrderBy returns the ORDER BY clause for a search sort, given the
+// rank and creation time columns.
+func searchOrderBy(sort, rank, createdAt string) string {
+	if sort == models.SearchSortNew {
+		return createdAt + ` DESC`
+	}
+	return rank + ` DESC, ` + createdAt + ` DESC`
+}
+
+// SearchPosts returns the IDs of posts matching a web-style query (quoted
+// phrases, OR, -word), ordered by sort. Posts from NSFW and quarantined
+// subreddits are left out unless the requesting user opted in to them.
+func (p *PostgresDB) SearchPosts(ctx context.Context, query, sort string, limit, offset int, requestingUserID uuid.UUID) ([]uuid.UUID, error) {
+	sqlQuery := `
+		SELECT p.id
+		FROM posts p
+		JOIN subreddits s ON s.id = p.subreddit_id
+		LEFT JOIN user_preferences pref ON pref.user_id = $4
+		WHERE ` + postSearchVector + ` @@ websearch_to_tsquery('english', $1)
+		  AND p.deleted_at IS NULL AND s.deleted_at IS NULL
+		  AND ` + preferredRatings + `
+		ORDER BY ` + searchOrderBy(sort, `ts_rank(`+postSearchVector+`, websearch_to_tsquery('english', $1))`, `p.created_at`) + `
+		LIMIT $2 OFFSET $3
+	`
+	ids := []uuid.UUID{}
+	if err := p.DB.SelectContext(ctx, &ids, sqlQuery, query, limit, offset, requestingUserID); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to search posts", err)
+	}
+	return ids, nil
+}
+
+// SearchComments returns comments matching a web-style query, ordered by
+// sort, with the requesting user's votes. Like SearchPosts, it leaves out
+// NSFW and quarantined subreddits the user didn't opt in to.
+func (p *PostgresDB) SearchComments(ctx context.Context, query, sort string, limit, offset int, requestingUserID uuid.UUID) ([]*models.Comment, error) {
+	// Matched in a subquery, where content can only be the comment's
+	sqlQuery := `
+		SELECT
+			c.id, c.content, c.author_id, u.username AS author_username, u.is_bot AS author_is_bot, c.post_id,
+			p.subreddit_id, c.parent_id, c.created_at, c.updated_at,
+			c.upvotes, c.downvotes, c.karma, c.reply_count, c.deleted_at,
+			` + currentUserVoteColumn + `
+		FROM (
+			SELECT id, ts_rank(` + commentSearchVector + `, q) AS rank
+			FROM comments, websearch_to_tsquery('english', $1) q
+			WHERE ` + commentSearchVector + ` @@ q AND deleted_at IS NULL
+		) m
+		JOIN comments c ON c.id = m.id
+		JOIN users u ON c.author_id = u.id
+		JOIN posts p ON c.post_id = p.id
+		JOIN subreddits s ON s.id = p.subreddit_id
+		` + currentUserVoteJoin("c", models.CommentVote, "$4") + `
+		LEFT JOIN user_preferences pref ON pref.user_id = $4
+		WHERE p.deleted_at IS NULL AND s.deleted_at IS NULL
+		  AND ` + preferredRatings + `
+		ORDER BY ` + searchOrderBy(sort, `m.rank`, `c.created_at`) + `
+		LIMIT $2 OFFSET $3
+	`
+	comments := []*models.Comment{}
+	if err := p.DB.SelectContext(ctx, &comments, sqlQuery, query, limit, offset, requestingUserID); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to search comments", err)
+	}
+	hydrateCommentVotes(comments)
+	return comments, nil
+}
+
+// SearchSubreddits returns subreddits whose name or description matches a
+// web-style query, ordered by sort. NSFW and quarantined subreddits are left
+// out unless the requesting user opted in to them.
+func (p *PostgresDB) SearchSubreddits(ctx context.Context, query, sort string, limit, offset int, requestingUserID uuid.UUID) ([]*models.Subreddit, error) {
+	sqlQuery := `
+		SELECT s.id, s.name, s.description, s.created_by, s.member_count, s.post_count, s.created_at, s.nsfw, s.quarantined
+		FROM subreddits s
+		LEFT JOIN user_preferences pref ON pref.user_id = $4
+		WHERE ` + subredditSearchVector + ` @@ websearch_to_tsquery('english', $1)
+		  AND s.deleted_at IS NULL
+		  AND ` + preferredRatings + `
+		ORDER BY ` + searchOrderBy(sort, `ts_rank(`+subredditSearchVector+`, websearch_to_tsquery('english', $1))`, `s.created_at`) + `
+		LIMIT $2 OFFSET $3
+	`
+	subs := []*models.Subreddit{}
+	if err := p.DB.SelectContext(ctx, &subs, sqlQuery, query, limit, offset, requestingUserID); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to search subreddits", err)
+	}
+	return subs, nil
+}
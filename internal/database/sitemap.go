@@ -0,0 +1,31 @@
+package database
+
+import (
+	"context"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+)
+
+// GetSitemapEntries returns the most recently changed posts of every
+// subreddit that isn't NSFW or quarantined, at most perSubreddit each, most
+// recently changed first.
+func (p *PostgresDB) GetSitemapEntries(ctx context.Context, perSubreddit int) ([]*models.SitemapEntry, error) {
+	query := `
+		SELECT post_id, title, subreddit_id, subreddit_name, last_mod
+		FROM (
+			SELECT p.id AS post_id, p.title, p.subreddit_id, s.name AS subreddit_name, p.updated_at AS last_mod,
+				ROW_NUMBER() OVER (PARTITION BY p.subreddit_id ORDER BY p.updated_at DESC) AS n
+			FROM posts p
+			JOIN subreddits s ON s.id = p.subreddit_id
+			WHERE p.deleted_at IS NULL AND s.deleted_at IS NULL AND s.nsfw = false AND s.quarantined = false
+		) ranked
+		WHERE n <= $1
+		ORDER BY last_mod DESC
+	`
+	entries := []*models.SitemapEntry{}
+	if err := p.DB.SelectContext(ctx, &entries, query, perSubreddit); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to query sitemap entries", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,254 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+type includeDeletedKey struct{}
+
+// IncludeDeleted returns a context under which single-item reads (GetPost,
+// GetComment, GetPostComments, GetSubredditByID/ByName) also return
+// soft-deleted rows. Listings and feeds always hide them. Admin use only.
+func IncludeDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeDeletedKey{}, true)
+}
+
+// notDeleted returns a WHERE clause suffix hiding soft-deleted rows of the
+// given table aliases, or nothing when ctx includes deleted rows.
+func notDeleted(ctx context.Context, aliases ...string) string {
+	if include, _ := ctx.Value(includeDeletedKey{}).(bool); include {
+		return ""
+	}
+	var b strings.Builder
+	for _, alias := range aliases {
+		fmt.Fprintf(&b, " AND %s.deleted_at IS NULL", alias)
+	}
+	return b.String()
+}
+
+// commentThread selects comment $1 and its replies, all levels deep, whose
+// deleted_at matches the given condition.
+func commentThread(condition string) string {
+	return `WITH RECURSIVE thread AS (
+			SELECT id FROM comments WHERE id = $1 AND ` + condition + `
+			UNION ALL
+			SELECT c.id FROM comments c JOIN thread t ON c.parent_id = t.id WHERE ` + strings.ReplaceAll(condition, "deleted_at", "c.deleted_at") + `
+		)`
+}
+
+// SoftDelete marks content deleted. Deleting a post also deletes its
+// comments, and deleting a comment also deletes its replies, with the same
+// timestamp so Restore can bring back exactly what went with it. Posts in a
+// deleted subreddit are hidden but keep their own state.
+func (p *PostgresDB) SoftDelete(ctx context.Context, contentType models.ContentType, id uuid.UUID) error {
+	tx, err := p.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to begin soft delete", err)
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	switch contentType {
+	case models.ContentSubreddit:
+		err = tx.GetContext(ctx, &deletedAt, `UPDATE subreddits SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING deleted_at`, id)
+
+	case models.ContentPost:
+		err = tx.GetContext(ctx, &deletedAt, `UPDATE posts SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING deleted_at`, id)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `UPDATE comments SET deleted_at = $2 WHERE post_id = $1 AND deleted_at IS NULL`, id, deletedAt)
+		}
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `UPDATE subreddits SET post_count = GREATEST(0, post_count - 1)
+				WHERE id = (SELECT subreddit_id FROM posts WHERE id = $1)`, id)
+		}
+
+	case models.ContentComment:
+		var postIDs []uuid.UUID
+		err = tx.SelectContext(ctx, &postIDs, commentThread("deleted_at IS NULL")+`
+			UPDATE comments SET deleted_at = NOW() WHERE id IN (SELECT id FROM thread) RETURNING post_id`, id)
+		if err == nil && len(postIDs) == 0 {
+			err = sql.ErrNoRows
+		}
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `UPDATE posts SET comment_count = GREATEST(0, comment_count - $2), updated_at = NOW() WHERE id = $1`,
+				postIDs[0], len(postIDs))
+		}
+		if err == nil {
+			// The replies went with it, so only its parent loses a reply
+			_, err = tx.ExecContext(ctx, `UPDATE comments SET reply_count = GREATEST(0, reply_count - 1)
+				WHERE id = (SELECT parent_id FROM comments WHERE id = $1)`, id)
+		}
+
+	default:
+		return utils.NewAppError(utils.ErrInvalidInput, fmt.Sprintf("unknown content type %q", contentType), nil)
+	}
+
+	if err == sql.ErrNoRows {
+		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("%s %s not found", contentType, id), err)
+	}
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, fmt.Sprintf("failed to delete %s", contentType), err)
+	}
+	if err := tx.Commit(); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to commit soft delete", err)
+	}
+	return nil
+}
+
+// DeletePost deletes a post as its author does: in one transaction it
+// soft-deletes the post, deletes its comments and the votes on it and on
+// them, and takes the karma those votes earned back from the authors. Restore
+// can bring back only the post, without votes.
+func (p *PostgresDB) DeletePost(ctx context.Context, id uuid.UUID) error {
+	tx, err := p.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to begin post delete", err)
+	}
+	defer tx.Rollback()
+
+	var found uuid.UUID
+	err = tx.GetContext(ctx, &found, `SELECT id FROM posts WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id)
+	if err == sql.ErrNoRows {
+		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("post %s not found", id), err)
+	}
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to delete post", err)
+	}
+
+	// Content starts with a karma of 1 that was never credited to its
+	// author, so the rest is what the votes earned them
+	statements := []string{
+		`UPDATE users u SET karma = u.karma - k.n, comment_karma = u.comment_karma - k.n, updated_at = NOW()
+		FROM (SELECT author_id, SUM(karma - 1) AS n FROM comments WHERE post_id = $1 GROUP BY author_id) k
+		WHERE u.id = k.author_id AND k.n <> 0`,
+		`UPDATE users u SET karma = u.karma - (p.karma - 1), post_karma = u.post_karma - (p.karma - 1), updated_at = NOW()
+		FROM posts p WHERE p.id = $1 AND u.id = p.author_id AND p.karma <> 1`,
+		`DELETE FROM votes WHERE (content_type = '` + string(models.PostVote) + `' AND content_id = $1)
+		OR (content_type = '` + string(models.CommentVote) + `' AND content_id IN (SELECT id FROM comments WHERE post_id = $1))`,
+		`DELETE FROM comments WHERE post_id = $1`,
+		`UPDATE posts SET deleted_at = NOW(), karma = 1, upvotes = 0, downvotes = 0, comment_count = 0 WHERE id = $1`,
+		`UPDATE subreddits SET post_count = GREATEST(0, post_count - 1)
+		WHERE id = (SELECT subreddit_id FROM posts WHERE id = $1)`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt, id); err != nil {
+			return utils.NewAppError(utils.ErrDatabase, "failed to delete post", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to commit post delete", err)
+	}
+	return nil
+}
+
+// Restore undoes SoftDelete, bringing back the comments or replies that were
+// deleted along with the content. A post deleted by DeletePost comes back
+// alone. A comment can't be restored while its post
+// or parent comment is still deleted.
+func (p *PostgresDB) Restore(ctx context.Context, contentType models.ContentType, id uuid.UUID) error {
+	tx, err := p.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to begin restore", err)
+	}
+	defer tx.Rollback()
+
+	switch contentType {
+	case models.ContentSubreddit:
+		var restored uuid.UUID
+		err = tx.GetContext(ctx, &restored, `UPDATE subreddits SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING id`, id)
+
+	case models.ContentPost:
+		var deletedAt time.Time
+		err = tx.GetContext(ctx, &deletedAt, `SELECT deleted_at FROM posts WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE`, id)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `UPDATE comments SET deleted_at = NULL WHERE post_id = $1 AND deleted_at = $2`, id, deletedAt)
+		}
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `UPDATE posts SET deleted_at = NULL WHERE id = $1`, id)
+		}
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `UPDATE subreddits SET post_count = post_count + 1
+				WHERE id = (SELECT subreddit_id FROM posts WHERE id = $1)`, id)
+		}
+
+	case models.ContentComment:
+		var c struct {
+			PostID        uuid.UUID  `db:"post_id"`
+			DeletedAt     time.Time  `db:"deleted_at"`
+			PostDeleted   bool       `db:"post_deleted"`
+			ParentDeleted *time.Time `db:"parent_deleted_at"`
+		}
+		err = tx.GetContext(ctx, &c, `
+			SELECT c.post_id, c.deleted_at, p.deleted_at IS NOT NULL AS post_deleted, parent.deleted_at AS parent_deleted_at
+			FROM comments c
+			JOIN posts p ON p.id = c.post_id
+			LEFT JOIN comments parent ON parent.id = c.parent_id
+			WHERE c.id = $1 AND c.deleted_at IS NOT NULL
+			FOR UPDATE OF c`, id)
+		if err == nil && (c.PostDeleted || c.ParentDeleted != nil) {
+			return utils.NewAppError(utils.ErrInvalidInput, "restore the post or parent comment first", nil)
+		}
+		if err == nil {
+			var restored int
+			err = tx.GetContext(ctx, &restored, commentThread("deleted_at = $2")+`,
+				restored AS (UPDATE comments SET deleted_at = NULL WHERE id IN (SELECT id FROM thread) RETURNING id)
+				SELECT COUNT(*) FROM restored`, id, c.DeletedAt)
+			if err == nil {
+				_, err = tx.ExecContext(ctx, `UPDATE posts SET comment_count = comment_count + $2, updated_at = NOW() WHERE id = $1`, c.PostID, restored)
+			}
+			if err == nil {
+				_, err = tx.ExecContext(ctx, `UPDATE comments SET reply_count = reply_count + 1
+					WHERE id = (SELECT parent_id FROM comments WHERE id = $1)`, id)
+			}
+		}
+
+	default:
+		return utils.NewAppError(utils.ErrInvalidInput, fmt.Sprintf("unknown content type %q", contentType), nil)
+	}
+
+	if err == sql.ErrNoRows {
+		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("deleted %s %s not found", contentType, id), err)
+	}
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, fmt.Sprintf("failed to restore %s", contentType), err)
+	}
+	if err := tx.Commit(); err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to commit restore", err)
+	}
+	return nil
+}
+
+// deletedListQueries select soft-deleted content of each type, most recently
+// deleted first. Comments deleted along with their post are left out.
+var deletedListQueries = map[models.ContentType]string{
+	models.ContentSubreddit: `SELECT 'subreddit' AS type, id, name AS summary, created_by AS author_id, deleted_at
+		FROM subreddits WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT $1 OFFSET $2`,
+	models.ContentPost: `SELECT 'post' AS type, id, title AS summary, author_id, deleted_at
+		FROM posts WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT $1 OFFSET $2`,
+	models.ContentComment: `SELECT 'comment' AS type, c.id, LEFT(c.content, 200) AS summary, c.author_id, c.deleted_at
+		FROM comments c JOIN posts p ON p.id = c.post_id
+		WHERE c.deleted_at IS NOT NULL AND p.deleted_at IS DISTINCT FROM c.deleted_at
+		ORDER BY c.deleted_at DESC LIMIT $1 OFFSET $2`,
+}
+
+// ListDeleted returns soft-deleted content of one type for admin review.
+func (p *PostgresDB) ListDeleted(ctx context.Context, contentType models.ContentType, limit, offset int) ([]*models.DeletedContent, error) {
+	query, ok := deletedListQueries[contentType]
+	if !ok {
+		return nil, utils.NewAppError(utils.ErrInvalidInput, fmt.Sprintf("unknown content type %q", contentType), nil)
+	}
+	items := []*models.DeletedContent{}
+	if err := p.DB.SelectContext(ctx, &items, query, limit, offset); err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to list deleted "+string(contentType)+"s", err)
+	}
+	return items, nil
+}
@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Store is the complete data access layer, composed of one repository per
+// area. Code that only needs part of it should depend on the repositories
+// it uses rather than the whole Store. PostgresDB implements it.
+type Store interface {
+	UserRepository
+	SubredditRepository
+	PostRepository
+	CommentRepository
+	VoteRepository
+	MessageRepository
+	ContentRepository
+	JobRepository
+	AuditRepository
+	MaintenanceRepository
+	MediaRepository
+	ReportRepository
+
+	Close(ctx context.Context) error
+}
+
+// UserRepository stores user accounts.
+type UserRepository interface {
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUser(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetUserEmails(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]string, error)
+	SaveUser(ctx context.Context, user *models.User) error
+	UpdateUserActivity(ctx context.Context, id uuid.UUID, active bool) error
+	UpdateUserSubreddits(ctx context.Context, userID uuid.UUID, subID uuid.UUID, join bool) error
+	GetAllUsers(ctx context.Context) ([]*models.User, error)
+	ListUsers(ctx context.Context, filter models.UserFilter, limit int, after *models.Keyset) ([]*models.User, error)
+	GetKarmaLeaderboard(ctx context.Context, by models.Leaderboard, limit, offset int) ([]*models.User, error)
+	UpdateUserProfileImage(ctx context.Context, id uuid.UUID, key string) error
+	GetUserPreferences(ctx context.Context, userID uuid.UUID) (*models.UserPreferences, error)
+	SaveUserPreferences(ctx context.Context, prefs *models.UserPreferences) error
+	MergeUsers(ctx context.Context, primaryID, duplicateID uuid.UUID, dryRun bool) (*models.AccountMerge, error)
+	CreateRefreshToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (uuid.UUID, error)
+	RevokeRefreshToken(ctx context.Context, tokenHash string) error
+	SaveBotAccount(ctx context.Context, bot *models.BotAccount) error
+	GetBotAccount(ctx context.Context, userID uuid.UUID) (*models.BotAccount, error)
+	ListBotAccounts(ctx context.Context) ([]*models.BotAccount, error)
+	// TODO: Consider adding UpdateUserKarma directly?
+}
+
+// SubredditRepository stores subreddits, their member counts and settings.
+type SubredditRepository interface {
+	CreateSubreddit(ctx context.Context, sub *models.Subreddit) error
+	GetSubredditByID(ctx context.Context, id uuid.UUID) (*models.Subreddit, error)
+	GetSubredditByName(ctx context.Context, name string) (*models.Subreddit, error)
+	GetAllSubreddits(ctx context.Context) ([]*models.Subreddit, error)
+	GetTrendingSubreddits(ctx context.Context, requestingUserID uuid.UUID) ([]*models.Subreddit, error)
+	UpdateSubredditMemberCount(ctx context.Context, subID uuid.UUID, delta int) error
+	JoinSubreddit(ctx context.Context, subID, userID uuid.UUID) (bool, error)
+	LeaveSubreddit(ctx context.Context, subID, userID uuid.UUID) (bool, error)
+	GetSubredditMemberIDs(ctx context.Context, subredditID uuid.UUID) ([]uuid.UUID, error)
+	GetSubredditSettings(ctx context.Context, subredditID uuid.UUID) (*models.SubredditSettings, error)
+	SaveSubredditSettings(ctx context.Context, settings *models.SubredditSettings) error
+	SetSubredditRating(ctx context.Context, subredditID uuid.UUID, nsfw, quarantined *bool) error
+	CountOnlineMembers(ctx context.Context, subredditID uuid.UUID, activeWithin time.Duration) (int, error)
+	SearchSubreddits(ctx context.Context, query, sort string, limit, offset int, requestingUserID uuid.UUID) ([]*models.Subreddit, error)
+	AddModerator(ctx context.Context, subredditID, userID, addedBy uuid.UUID) (bool, error)
+	RemoveModerator(ctx context.Context, subredditID, userID uuid.UUID) (bool, error)
+	GetModerators(ctx context.Context, subredditID uuid.UUID) ([]*models.Moderator, error)
+	IsSubredditModerator(ctx context.Context, subredditID, userID uuid.UUID) (bool, error)
+	CreateFlair(ctx context.Context, flair *models.Flair) error
+	GetFlair(ctx context.Context, id uuid.UUID) (*models.Flair, error)
+	GetFlairs(ctx context.Context, subredditID uuid.UUID) ([]*models.Flair, error)
+	UpdateFlair(ctx context.Context, flair *models.Flair) error
+	DeleteFlair(ctx context.Context, id uuid.UUID) error
+	CreateSubredditExport(ctx context.Context, export *models.SubredditExport) error
+	GetSubredditExport(ctx context.Context, id uuid.UUID) (*models.SubredditExport, error)
+	ListSubredditExports(ctx context.Context, subredditID uuid.UUID) ([]*models.SubredditExport, error)
+	UpdateSubredditExport(ctx context.Context, export *models.SubredditExport) error
+	GetSubredditArchivePosts(ctx context.Context, subredditID uuid.UUID, limit int, after *models.Keyset) ([]*models.Post, error)
+}
+
+// PostRepository stores posts and serves feeds.
+type PostRepository interface {
+	SavePost(ctx context.Context, post *models.Post) error
+	GetPost(ctx context.Context, postID uuid.UUID, requestingUserID uuid.UUID) (*models.Post, error)
+	GetRecentPosts(ctx context.Context, limit, offset int, after *models.Keyset, requestingUserID uuid.UUID, sortOrder string) ([]*models.Post, error)
+	GetUserFeed(ctx context.Context, userID uuid.UUID, limit, offset int, after *models.Keyset, requestingUserID uuid.UUID, hideSeen bool, sortOrder string) ([]*models.Post, error)
+	MarkPostsSeen(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) error
+	GetPostsBySubreddit(ctx context.Context, subredditID uuid.UUID, flairID *uuid.UUID, limit int, after *models.Keyset, requestingUserID uuid.UUID) ([]*models.Post, error)
+	GetAllPosts(ctx context.Context) ([]*models.Post, error)
+	UpdatePostThumbnail(ctx context.Context, postID uuid.UUID, thumbnailURL string) error
+	SetPostLocked(ctx context.Context, postID uuid.UUID, locked bool) error
+	SetPostLockedByModerator(ctx context.Context, postID uuid.UUID, locked bool) error
+	EditPost(ctx context.Context, postID uuid.UUID, title, content string, language *string) (time.Time, error)
+	GetPostRevisions(ctx context.Context, postID uuid.UUID) ([]*models.PostRevision, error)
+	UpdatePostMetadata(ctx context.Context, postID uuid.UUID, meta *models.PostMetadata) error
+	GetSitemapEntries(ctx context.Context, perSubreddit int) ([]*models.SitemapEntry, error)
+	SearchPosts(ctx context.Context, query, sort string, limit, offset int, requestingUserID uuid.UUID) ([]uuid.UUID, error)
+	HoldPost(ctx context.Context, post *models.Post) error
+	ListPendingPosts(ctx context.Context, subredditID uuid.UUID) ([]*models.Post, error)
+	TakePendingPost(ctx context.Context, subredditID, id uuid.UUID) (*models.Post, error)
+}
+
+// CommentRepository stores comments and the queue of comments awaiting approval.
+type CommentRepository interface {
+	SaveComment(ctx context.Context, comment *models.Comment) error
+	GetComment(ctx context.Context, id uuid.UUID, requestingUserID uuid.UUID) (*models.Comment, error)
+	GetPostComments(ctx context.Context, postID uuid.UUID, requestingUserID uuid.UUID) ([]*models.Comment, error)
+	GetCommentThread(ctx context.Context, postID uuid.UUID, parentID, afterID *uuid.UUID, width, depth int, requestingUserID uuid.UUID) ([]*models.CommentNode, bool, error)
+	CountCommentsByPost(ctx context.Context, postID uuid.UUID) (int, error)
+	GetAllComments(ctx context.Context) ([]*models.Comment, error) // For handleLoadComments
+	HoldComment(ctx context.Context, comment *models.Comment) error
+	ListPendingComments(ctx context.Context, subredditID uuid.UUID) ([]*models.Comment, error)
+	TakePendingComment(ctx context.Context, subredditID, id uuid.UUID) (*models.Comment, error)
+	SearchComments(ctx context.Context, query, sort string, limit, offset int, requestingUserID uuid.UUID) ([]*models.Comment, error)
+}
+
+// VoteRepository records votes on posts and comments.
+type VoteRepository interface {
+	RecordVote(ctx context.Context, userID, contentID uuid.UUID, contentType models.VoteContentType, direction models.VoteDirection) error
+	GetAuthorAffinity(ctx context.Context, userID uuid.UUID, authorIDs []uuid.UUID) (map[uuid.UUID]int, error)
+}
+
+// MessageRepository stores direct messages and reads users' inboxes.
+type MessageRepository interface {
+	SaveMessage(ctx context.Context, msg *models.DirectMessage) error
+	GetMessagesByUser(ctx context.Context, userID uuid.UUID) ([]*models.DirectMessage, error)
+	GetUnreadMessageCount(ctx context.Context, userID uuid.UUID) (int, error)
+	UpdateMessageStatus(ctx context.Context, msgID uuid.UUID, isRead *bool, isDeleted *bool, isDelivered *bool) error
+	GetInbox(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*models.InboxItem, error)
+	GetInboxItem(ctx context.Context, userID, id uuid.UUID) (*models.InboxItem, error)
+}
+
+// ContentRepository soft-deletes and restores subreddits, posts and comments,
+// and deletes posts for their authors.
+type ContentRepository interface {
+	SoftDelete(ctx context.Context, contentType models.ContentType, id uuid.UUID) error
+	DeletePost(ctx context.Context, id uuid.UUID) error
+	Restore(ctx context.Context, contentType models.ContentType, id uuid.UUID) error
+	ListDeleted(ctx context.Context, contentType models.ContentType, limit, offset int) ([]*models.DeletedContent, error)
+}
+
+// JobRepository backs the background job queue.
+type JobRepository interface {
+	EnqueueJob(ctx context.Context, job *models.Job) (bool, error)
+	ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]*models.Job, error)
+	CompleteJob(ctx context.Context, id uuid.UUID) error
+	FailJob(ctx context.Context, id uuid.UUID, lastError string, retryAt *time.Time) error
+}
+
+// AuditRepository stores the admin audit log.
+type AuditRepository interface {
+	RecordAudit(ctx context.Context, entry *models.AuditEntry) error
+	GetAuditLog(ctx context.Context, subjectID *uuid.UUID, limit, offset int) ([]*models.AuditEntry, error)
+}
+
+// MaintenanceRepository runs scheduled maintenance and holds task leases.
+type MaintenanceRepository interface {
+	ReconcileCounters(ctx context.Context) (int64, error)
+	AcquireTaskLease(ctx context.Context, name string, interval, lease time.Duration) (bool, error)
+	ReleaseTaskLease(ctx context.Context, name string, runErr error) error
+	DecayHotScores(ctx context.Context, window time.Duration) (int64, error)
+	UpdateKarmaVelocity(ctx context.Context) (int64, error)
+	RefreshTrendingSubreddits(ctx context.Context, window time.Duration, limit int) (int64, error)
+	ArchiveOldPosts(ctx context.Context, olderThan time.Duration) (int64, error)
+	ClearStaleConnections(ctx context.Context, idleFor time.Duration) (int64, error)
+	PurgeExpired(ctx context.Context, target string, olderThan time.Duration, dryRun bool) (int64, error)
+}
+
+// ReportRepository stores users' reports of posts, comments and users.
+type ReportRepository interface {
+	CreateReport(ctx context.Context, report *models.Report) error
+	GetReport(ctx context.Context, id uuid.UUID) (*models.Report, error)
+	ListReports(ctx context.Context, subredditID uuid.UUID, status models.ReportStatus, limit, offset int) ([]*models.Report, error)
+	CloseReport(ctx context.Context, report *models.Report) error
+}
+
+// MediaRepository records uploaded files.
+type MediaRepository interface {
+	CreateMedia(ctx context.Context, media *models.Media) error
+	GetMedia(ctx context.Context, id uuid.UUID) (*models.Media, error)
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+const subredditSettingsColumns = `subreddit_id, allowed_post_types, min_account_age_days, min_karma, comments_require_approval,
+	post_approval_karma, post_approval_account_age_days, mask_profanity, updated_at`
+
+// GetSubredditSettings returns a subreddit's submission settings, or the
+// defaults if its moderators never saved any.
+func (p *PostgresDB) GetSubredditSettings(ctx context.Context, subredditID uuid.UUID) (*models.SubredditSettings, error) {
+	var settings models.SubredditSettings
+	err := p.DB.GetContext(ctx, &settings, `SELECT `+subredditSettingsColumns+` FROM subreddit_settings WHERE subreddit_id = $1`, subredditID)
+	if err == sql.ErrNoRows {
+		return models.DefaultSubredditSettings(subredditID), nil
+	}
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to fetch subreddit settings", err)
+	}
+	return &settings, nil
+}
+
+// SaveSubredditSettings creates or replaces a subreddit's submission settings.
+func (p *PostgresDB) SaveSubredditSettings(ctx context.Context, settings *models.SubredditSettings) error {
+	query := `
+		INSERT INTO subreddit_settings (subreddit_id, allowed_post_types, min_account_age_days, min_karma, comments_require_approval,
+			post_approval_karma, post_approval_account_age_days, mask_profanity)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8 FROM subreddits WHERE id = $1 AND deleted_at IS NULL
+		ON CONFLICT (subreddit_id) DO UPDATE SET
+			allowed_post_types = EXCLUDED.allowed_post_types,
+			min_account_age_days = EXCLUDED.min_account_age_days,
+			min_karma = EXCLUDED.min_karma,
+			comments_require_approval = EXCLUDED.comments_require_approval,
+			post_approval_karma = EXCLUDED.post_approval_karma,
+			post_approval_account_age_days = EXCLUDED.post_approval_account_age_days,
+			mask_profanity = EXCLUDED.mask_profanity,
+			updated_at = NOW()
+		RETURNING updated_at
+	`
+	err := p.DB.QueryRowxContext(ctx, query, settings.SubredditID, settings.AllowedPostTypes,
+		settings.MinAccountAgeDays, settings.MinKarma, settings.CommentsRequireApproval,
+		settings.PostApprovalKarma, settings.PostApprovalAccountAgeDays, settings.MaskProfanity).Scan(&settings.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("subreddit %s not found", settings.SubredditID), err)
+	}
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to save subreddit settings", err)
+	}
+	return nil
+}
+
+// SetSubredditRating marks a subreddit NSFW or quarantined, leaving nil
+// flags unchanged.
+func (p *PostgresDB) SetSubredditRating(ctx context.Context, subredditID uuid.UUID, nsfw, quarantined *bool) error {
+	res, err := p.DB.ExecContext(ctx, `
+		UPDATE subreddits SET nsfw = COALESCE($2, nsfw), quarantined = COALESCE($3, quarantined)
+		WHERE id = $1 AND deleted_at IS NULL
+	`, subredditID, nsfw, quarantined)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to update subreddit rating", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return utils.NewAppError(utils.ErrNotFound, fmt.Sprintf("subreddit %s not found", subredditID), nil)
+	}
+	return nil
+}
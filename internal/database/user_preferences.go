@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// GetUserPreferences returns a user's preferences, or the defaults if they
+// never saved any.
+func (p *PostgresDB) GetUserPreferences(ctx context.Context, userID uuid.UUID) (*models.UserPreferences, error) {
+	var prefs models.UserPreferences
+	err := p.DB.QueryRowxContext(ctx, `SELECT user_id, show_nsfw, show_quarantined, mask_profanity, languages, updated_at FROM user_preferences WHERE user_id = $1`, userID).
+		Scan(&prefs.UserID, &prefs.ShowNSFW, &prefs.ShowQuarantined, &prefs.MaskProfanity, pq.Array(&prefs.Languages), &prefs.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return models.DefaultUserPreferences(userID), nil
+	}
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to fetch user preferences", err)
+	}
+	return &prefs, nil
+}
+
+// SaveUserPreferences creates or replaces a user's preferences.
+func (p *PostgresDB) SaveUserPreferences(ctx context.Context, prefs *models.UserPreferences) error {
+	query := `
+		INSERT INTO user_preferences (user_id, show_nsfw, show_quarantined, mask_profanity, languages)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			show_nsfw = EXCLUDED.show_nsfw,
+			show_quarantined = EXCLUDED.show_quarantined,
+			mask_profanity = EXCLUDED.mask_profanity,
+			languages = EXCLUDED.languages,
+			updated_at = NOW()
+		RETURNING updated_at
+	`
+	if prefs.Languages == nil {
+		prefs.Languages = []string{}
+	}
+	err := p.DB.QueryRowxContext(ctx, query, prefs.UserID, prefs.ShowNSFW, prefs.ShowQuarantined, prefs.MaskProfanity, pq.Array(prefs.Languages)).Scan(&prefs.UpdatedAt)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to save user preferences", err)
+	}
+	return nil
+}
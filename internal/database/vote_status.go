@@ -0,0 +1,49 @@
+package database
+
+import (
+	"fmt"
+
+	"gator-swamp/internal/models"
+)
+
+// currentUserVoteColumn selects the requesting user's raw vote alongside the
+// content row. Cast to text so both the current "up"/"down" values and legacy
+// integer votes scan into the model's *string field.
+const currentUserVoteColumn = `v.vote_type::text AS current_user_vote`
+
+// currentUserVoteJoin returns the LEFT JOIN that pairs currentUserVoteColumn
+// with the content aliased as contentAlias. userParam is the bind placeholder
+// for the requesting user ($N, or ? for queries built with sqlx.In).
+func currentUserVoteJoin(contentAlias string, contentType models.VoteContentType, userParam string) string {
+	return fmt.Sprintf(`LEFT JOIN votes v ON v.content_id = %s.id AND v.content_type = '%s' AND v.user_id = %s`,
+		contentAlias, contentType, userParam)
+}
+
+// normalizeVote maps a raw scanned vote to "up", "down" or nil (no vote).
+func normalizeVote(raw *string) *string {
+	if raw == nil {
+		return nil
+	}
+	var vote string
+	switch *raw {
+	case "up", "1":
+		vote = "up"
+	case "down", "-1":
+		vote = "down"
+	default:
+		return nil
+	}
+	return &vote
+}
+
+func hydratePostVotes(posts []*models.Post) {
+	for _, post := range posts {
+		post.CurrentUserVote = normalizeVote(post.CurrentUserVote)
+	}
+}
+
+func hydrateCommentVotes(comments []*models.Comment) {
+	for _, comment := range comments {
+		comment.CurrentUserVote = normalizeVote(comment.CurrentUserVote)
+	}
+}
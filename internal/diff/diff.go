@@ -0,0 +1,111 @@
+// Package diff compares two versions of a text word by word, for showing
+// what an edit changed.
+package diff
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Op is what an edit did to a Chunk.
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Chunk is a run of text an edit kept, inserted or deleted.
+type Chunk struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxCells bounds the table compared texts need. When the changed middle of
+// two texts is larger, it's shown as one deletion and one insertion.
+const maxCells = 1_000_000
+
+// Words and the whitespace between them, so joined tokens give the text back
+var tokenRE = regexp.MustCompile(`\s+|\S+`)
+
+// Words returns the chunks that turn before into after, split at word
+// boundaries. The Equal and Delete chunks join into before, and the Equal
+// and Insert chunks into after.
+func Words(before, after string) []Chunk {
+	a, b := tokenRE.FindAllString(before, -1), tokenRE.FindAllString(after, -1)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var d differ
+	d.add(Equal, a[:prefix]...)
+	d.middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	d.add(Equal, a[len(a)-suffix:]...)
+	return d.chunks
+}
+
+type differ struct {
+	chunks []Chunk
+}
+
+// add appends tokens, extending the last chunk if it has the same op.
+func (d *differ) add(op Op, tokens ...string) {
+	if len(tokens) == 0 {
+		return
+	}
+	text := strings.Join(tokens, "")
+	if n := len(d.chunks); n > 0 && d.chunks[n-1].Op == op {
+		d.chunks[n-1].Text += text
+		return
+	}
+	d.chunks = append(d.chunks, Chunk{Op: op, Text: text})
+}
+
+// middle diffs a and b by their longest common subsequence of tokens.
+func (d *differ) middle(a, b []string) {
+	if len(a)*len(b) > maxCells {
+		d.add(Delete, a...)
+		d.add(Insert, b...)
+		return
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			d.add(Equal, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			d.add(Delete, a[i])
+			i++
+		default:
+			d.add(Insert, b[j])
+			j++
+		}
+	}
+	d.add(Delete, a[i:]...)
+	d.add(Insert, b[j:]...)
+}
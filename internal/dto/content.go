@@ -0,0 +1,75 @@
+package dto
+
+import (
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// TextMask rewrites text shown from a subreddit, e.g. to mask profanity. A
+// nil TextMask leaves text alone.
+type TextMask func(subredditID uuid.UUID, text string) string
+
+// MaskPost applies mask to a post's title and content. Posts can be shared
+// with actor caches, so a changed post is returned as a copy.
+func MaskPost(post *models.Post, mask TextMask) *models.Post {
+	if mask == nil || post == nil {
+		return post
+	}
+	title, content := mask(post.SubredditID, post.Title), mask(post.SubredditID, post.Content)
+	if title == post.Title && content == post.Content {
+		return post
+	}
+	masked := *post
+	masked.Title, masked.Content = title, content
+	return &masked
+}
+
+// MaskPosts applies mask to each post.
+func MaskPosts(posts []*models.Post, mask TextMask) []*models.Post {
+	if mask == nil {
+		return posts
+	}
+	out := make([]*models.Post, len(posts))
+	for i, p := range posts {
+		out[i] = MaskPost(p, mask)
+	}
+	return out
+}
+
+// MaskComment applies mask to a comment's content, copying it if changed.
+func MaskComment(comment *models.Comment, mask TextMask) *models.Comment {
+	if mask == nil || comment == nil {
+		return comment
+	}
+	content := mask(comment.SubredditID, comment.Content)
+	if content == comment.Content {
+		return comment
+	}
+	masked := *comment
+	masked.Content = content
+	return &masked
+}
+
+// MaskComments applies mask to each comment.
+func MaskComments(comments []*models.Comment, mask TextMask) []*models.Comment {
+	if mask == nil {
+		return comments
+	}
+	out := make([]*models.Comment, len(comments))
+	for i, c := range comments {
+		out[i] = MaskComment(c, mask)
+	}
+	return out
+}
+
+// MaskPostWithComments applies mask to a post and its comments.
+func MaskPostWithComments(full *models.PostWithComments, mask TextMask) *models.PostWithComments {
+	if mask == nil || full == nil {
+		return full
+	}
+	masked := *full
+	masked.Post = MaskPost(full.Post, mask)
+	masked.Comments = MaskComments(full.Comments, mask)
+	return &masked
+}
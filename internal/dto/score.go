@@ -0,0 +1,55 @@
+package dto
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"time"
+
+	"gator-swamp/internal/models"
+)
+
+// ScorePost sets a post's upvote ratio and display score from its votes.
+// Posts younger than fuzzAge show fuzzed vote counts, so vote manipulation
+// can't be checked against exact counts while it matters most; a zero
+// fuzzAge shows exact ones. Like MaskPost, a copy is returned.
+func ScorePost(post *models.Post, fuzzAge time.Duration) *models.Post {
+	if post == nil {
+		return nil
+	}
+	scored := *post
+	if fuzzAge > 0 && time.Since(post.CreatedAt) < fuzzAge {
+		scored.Upvotes, scored.Downvotes = fuzzVotes(post)
+		scored.Karma = scored.Upvotes - scored.Downvotes
+	}
+	scored.Score = scored.Upvotes - scored.Downvotes
+	if votes := scored.Upvotes + scored.Downvotes; votes > 0 {
+		scored.UpvoteRatio = math.Round(float64(scored.Upvotes)/float64(votes)*100) / 100
+	}
+	return &scored
+}
+
+// ScorePosts applies ScorePost to each post.
+func ScorePosts(posts []*models.Post, fuzzAge time.Duration) []*models.Post {
+	out := make([]*models.Post, len(posts))
+	for i, p := range posts {
+		out[i] = ScorePost(p, fuzzAge)
+	}
+	return out
+}
+
+// fuzzVotes adds up to a tenth of a post's votes (at least one) to each of
+// its upvotes and downvotes. The noise is derived from the post and its
+// counts, so reloading a page doesn't average it away, but each vote
+// changes it.
+func fuzzVotes(post *models.Post) (upvotes, downvotes int) {
+	h := fnv.New64a()
+	h.Write(post.ID[:])
+	binary.Write(h, binary.LittleEndian, [2]int64{int64(post.Upvotes), int64(post.Downvotes)})
+	noise := h.Sum64()
+
+	spread := uint64(1 + (post.Upvotes+post.Downvotes)/10)
+	up := int(noise % (spread + 1))
+	down := int((noise >> 32) % (spread + 1))
+	return post.Upvotes + up, post.Downvotes + down
+}
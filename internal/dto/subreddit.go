@@ -0,0 +1,50 @@
+package dto
+
+import (
+	"time"
+
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Subreddit is a subreddit as returned by the API. MembersOnline is only
+// filled in on single-subreddit lookups.
+type Subreddit struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	CreatorID     uuid.UUID  `json:"creatorId"`
+	Members       int        `json:"members"`
+	MembersOnline *int       `json:"membersOnline,omitempty"`
+	PostCount     int        `json:"postCount"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	DeletedAt     *time.Time `json:"deletedAt,omitempty"`
+	NSFW          bool       `json:"nsfw"`
+	Quarantined   bool       `json:"quarantined"`
+}
+
+// NewSubreddit converts a subreddit.
+func NewSubreddit(s *models.Subreddit) *Subreddit {
+	return &Subreddit{
+		ID:          s.ID,
+		Name:        s.Name,
+		Description: s.Description,
+		CreatorID:   s.CreatorID,
+		Members:     s.Members,
+		PostCount:   s.PostCount,
+		CreatedAt:   s.CreatedAt,
+		DeletedAt:   s.DeletedAt,
+		NSFW:        s.NSFW,
+		Quarantined: s.Quarantined,
+	}
+}
+
+// NewSubreddits converts a list of subreddits.
+func NewSubreddits(subs []*models.Subreddit) []*Subreddit {
+	out := make([]*Subreddit, len(subs))
+	for i, s := range subs {
+		out[i] = NewSubreddit(s)
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+// Package dto defines the JSON shapes the HTTP API responds with. Handlers
+// convert models and actor state into these rather than encoding them
+// directly, so field names stay camelCase and private fields (password
+// hashes, auth tokens, other users' emails) are never serialized.
+package dto
+
+import (
+	"time"
+
+	"gator-swamp/internal/media"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// User is a user as seen by others. Email is only set for the user themself.
+type User struct {
+	ID            uuid.UUID         `json:"id"`
+	Username      string            `json:"username"`
+	Email         string            `json:"email,omitempty"`
+	Karma         int               `json:"karma"`
+	PostKarma     int               `json:"postKarma"`
+	CommentKarma  int               `json:"commentKarma"`
+	KarmaVelocity float64           `json:"karmaVelocity"`
+	IsConnected   bool              `json:"isConnected"`
+	LastActive    time.Time         `json:"lastActive"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	Avatar        *media.AvatarURLs `json:"avatar,omitempty"`
+	IsBot         bool              `json:"isBot,omitempty"`
+}
+
+// PublicUser is what anyone may see of a user in listings.
+type PublicUser struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	Karma        int       `json:"karma"`
+	PostKarma    int       `json:"postKarma"`
+	CommentKarma int       `json:"commentKarma"`
+	CreatedAt    time.Time `json:"createdAt"`
+	IsBot        bool      `json:"isBot,omitempty"`
+}
+
+// AdminUser is a user as admins see them, email and flags included.
+type AdminUser struct {
+	User
+	IsAdmin       bool `json:"isAdmin"`
+	EmailVerified bool `json:"emailVerified"`
+}
+
+// Profile is a user's profile with the subreddits they belong to.
+type Profile struct {
+	User
+	SubredditIDs   []uuid.UUID `json:"subredditIds"`
+	SubredditNames []string    `json:"subredditNames"`
+}
+
+// NewUser converts a user for viewerID, hiding the email from anyone else.
+func NewUser(u *models.User, viewerID uuid.UUID, store storage.Storage) *User {
+	user := &User{
+		ID:            u.ID,
+		Username:      u.Username,
+		Karma:         u.Karma,
+		PostKarma:     u.PostKarma,
+		CommentKarma:  u.CommentKarma,
+		KarmaVelocity: u.KarmaVelocity,
+		IsConnected:   u.IsConnected,
+		LastActive:    u.LastActive,
+		CreatedAt:     u.CreatedAt,
+		Avatar:        media.GetAvatarURLs(store, u.ProfileImage),
+		IsBot:         u.IsBot,
+	}
+	if u.ID == viewerID {
+		user.Email = u.Email
+	}
+	return user
+}
+
+// NewPublicUsers converts a list of users for a public listing.
+func NewPublicUsers(users []*models.User) []*PublicUser {
+	out := make([]*PublicUser, len(users))
+	for i, u := range users {
+		out[i] = &PublicUser{
+			ID:           u.ID,
+			Username:     u.Username,
+			Karma:        u.Karma,
+			PostKarma:    u.PostKarma,
+			CommentKarma: u.CommentKarma,
+			CreatedAt:    u.CreatedAt,
+			IsBot:        u.IsBot,
+		}
+	}
+	return out
+}
+
+// NewAdminUsers converts a list of users for an admin.
+func NewAdminUsers(users []*models.User, store storage.Storage) []*AdminUser {
+	out := make([]*AdminUser, len(users))
+	for i, u := range users {
+		out[i] = &AdminUser{User: *NewUser(u, u.ID, store), IsAdmin: u.IsAdmin, EmailVerified: u.EmailVerified}
+	}
+	return out
+}
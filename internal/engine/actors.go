@@ -1,11 +1,17 @@
 package engine
 
 import (
+	stdctx "context"
 	"fmt"
+	"gator-swamp/internal/actorcall"
 	"gator-swamp/internal/database"
 	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/events"
+	"gator-swamp/internal/logging"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/policy"
 	"gator-swamp/internal/utils"
-	"log"
+	"gator-swamp/internal/websocket"
 	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
@@ -39,46 +45,59 @@ type (
 type Engine struct {
 	context        *actor.RootContext // Use RootContext
 	metrics        *utils.MetricsCollector
-	db             database.DBAdapter // Database adapter interface
+	db             database.Store // Database adapter interface
+	policies       policy.Policies
+	actorCalls     *actorcall.Policies // Timeouts and retries of requests to other actors
 	userSupervisor *actor.PID
 	subredditActor *actor.PID
 	postActor      *actor.PID
 	commentActor   *actor.PID
+	scores         *scoreBatcher // Nil without a WebSocket hub
+	startup        *startup
 }
 
 // NewEngine creates a new engine instance with all required actors
-func NewEngine(system *actor.ActorSystem, metrics *utils.MetricsCollector, db database.DBAdapter) *Engine {
+func NewEngine(system *actor.ActorSystem, metrics *utils.MetricsCollector, db database.Store, hub *websocket.Hub, bus *events.Bus, policies policy.Policies, actorCalls *actorcall.Policies) *Engine {
 	context := system.Root
-	log.Printf("Creating Engine with actors...")
+	system.Logger().Info("Creating engine actors")
 
 	// Create the Engine first
 	e := &Engine{
-		context: context, // Assign RootContext here
-		metrics: metrics,
-		db:      db, // Assign the db interface
+		context:    context, // Assign RootContext here
+		metrics:    metrics,
+		db:         db, // Assign the db interface
+		policies:   policies,
+		actorCalls: actorCalls,
+		startup:    newStartup(startupActors...),
 	}
 
 	// Create props with Engine's PID
-	engineProps := actor.PropsFromProducer(func() actor.Actor {
+	engineProps := logging.Props(func() actor.Actor {
 		return e
 	})
 	enginePID := context.Spawn(engineProps)
 
+	// Side effects of domain events hang off the bus rather than the actors
+	if hub != nil {
+		subscribeFanout(bus, db, hub)
+		e.scores = startScoreBatcher(bus, db, hub)
+	}
+
 	// Now create other actors with enginePID
-	supervisorProps := actor.PropsFromProducer(func() actor.Actor {
+	supervisorProps := logging.Props(func() actor.Actor {
 		// TODO: Update NewUserSupervisor signature
-		return actors.NewUserSupervisor(e.db) // Pass db interface
+		return actors.NewUserSupervisor(enginePID, e.db, bus, actorCalls) // Pass db interface
 	})
 
-	subredditProps := actor.PropsFromProducer(func() actor.Actor {
+	subredditProps := logging.Props(func() actor.Actor {
 		// TODO: Update NewSubredditActor signature
-		return actors.NewSubredditActor(metrics, e.db) // Pass db interface
+		return actors.NewSubredditActor(enginePID, metrics, e.db) // Pass db interface
 	})
 
 	// Create the CommentActor first
-	commentProps := actor.PropsFromProducer(func() actor.Actor {
+	commentProps := logging.Props(func() actor.Actor {
 		// TODO: Update NewCommentActor signature
-		return actors.NewCommentActor(enginePID, e.db) // Pass db interface
+		return actors.NewCommentActor(enginePID, e.db, bus) // Pass db interface
 	})
 
 	userSupervisorPID := context.Spawn(supervisorProps)
@@ -86,9 +105,9 @@ func NewEngine(system *actor.ActorSystem, metrics *utils.MetricsCollector, db da
 	commentPID := context.Spawn(commentProps)
 
 	// Create PostActor and pass CommentActor PID to it
-	postProps := actor.PropsFromProducer(func() actor.Actor {
+	postProps := logging.Props(func() actor.Actor {
 		// TODO: Update NewPostActor signature
-		return actors.NewPostActor(metrics, enginePID, e.db, commentPID) // Pass db interface
+		return actors.NewPostActor(metrics, enginePID, e.db, commentPID, hub, bus, actorCalls) // Pass db interface
 	})
 	postPID := context.Spawn(postProps)
 
@@ -100,72 +119,75 @@ func NewEngine(system *actor.ActorSystem, metrics *utils.MetricsCollector, db da
 	return e
 }
 
+// checkSubredditCreator returns an error unless creatorID exists and meets
+// the subreddit creation policy. Karma is read from users, the database, not
+// an actor's copy, which may be stale.
+func checkSubredditCreator(ctx stdctx.Context, users database.UserRepository, policies policy.Policies, creatorID uuid.UUID, now time.Time) error {
+	creator, err := users.GetUser(ctx, creatorID)
+	if utils.IsErrorCode(err, utils.ErrNotFound) {
+		return utils.NewAppError(utils.ErrNotFound, "User not found", nil)
+	}
+	if err != nil {
+		return err
+	}
+	if err := policies.Check(policy.OpCreateSubreddit, creator, now); err != nil {
+		return err
+	}
+	if creator.IsBot {
+		return policy.CheckBot(ctx, users, policy.OpCreateSubreddit, creator.ID, uuid.Nil)
+	}
+	return nil
+}
+
 // Make Engine implement the Actor interface
 func (e *Engine) Receive(context actor.Context) {
+	ctx, logger := logging.Context(context), context.Logger()
 	switch msg := context.Message().(type) {
 	case *actor.Started:
-		log.Printf("Engine started")
+		logger.Info("Engine started")
 
 	case *actor.Stopping:
-		log.Printf("Engine stopping")
+		logger.Info("Engine stopping")
 
 	case *actor.Stopped:
-		log.Printf("Engine stopped")
+		logger.Info("Engine stopped")
 
 	case *actor.Restarting:
-		log.Printf("Engine restarting")
-
-	case *actors.CreateSubredditMsg:
-		log.Printf("Engine: Processing CreateSubredditMsg for creator: %s", msg.CreatorID)
+		logger.Info("Engine restarting")
 
-		// Validate user exists and has sufficient karma
-		userFuture := context.RequestFuture(e.userSupervisor,
-			&actors.GetUserProfileMsg{UserID: msg.CreatorID},
-			5*time.Second)
-
-		userResult, err := userFuture.Result()
-		if err != nil {
-			log.Printf("Engine: Error getting user profile: %v", err)
-			context.Respond(utils.NewAppError(utils.ErrActorTimeout,
-				fmt.Sprintf("Failed to validate user: %v", err), err))
-			return
-		}
-
-		userState, ok := userResult.(*actors.UserState)
-		if !ok || userState == nil {
-			log.Printf("Engine: User not found")
-			context.Respond(utils.NewAppError(utils.ErrNotFound, "User not found", nil))
-			return
+	case *actors.ReadyMsg:
+		if e.startup.markReady(msg.Actor) {
+			logger.Info("Actor ready", "actor", msg.Actor)
 		}
 
-		// Check karma requirement
-		if userState.Karma < 100 {
-			log.Printf("Engine: Insufficient karma for user %s", msg.CreatorID)
-			context.Respond(utils.NewAppError(utils.ErrInvalidInput,
-				fmt.Sprintf("Insufficient karma (required: 100, current: %d)", userState.Karma), nil))
+	case *actors.CreateSubredditMsg:
+		dbCtx, cancel := stdctx.WithTimeout(ctx, 5*time.Second)
+		err := checkSubredditCreator(dbCtx, e.db, e.policies, msg.CreatorID, time.Now())
+		cancel()
+		if err != nil {
+			if utils.IsErrorCode(err, utils.ErrForbidden) || utils.IsErrorCode(err, utils.ErrNotFound) {
+				logger.InfoContext(ctx, "User may not create subreddits", "user_id", msg.CreatorID, "reason", err)
+			} else {
+				logger.ErrorContext(ctx, "Failed to check subreddit creator", "user_id", msg.CreatorID, "error", err)
+			}
+			context.Respond(err)
 			return
 		}
 
 		// Forward to SubredditActor
-		future := context.RequestFuture(e.subredditActor, msg, 5*time.Second)
-		result, err := future.Result()
+		result, err := e.actorCalls.Request(context, e.subredditActor, msg)
 		if err != nil {
-			log.Printf("Engine: Error creating subreddit: %v", err)
+			logger.ErrorContext(ctx, "Failed to create subreddit", "error", err)
 			context.Respond(utils.NewAppError(utils.ErrActorTimeout,
 				fmt.Sprintf("Failed to create subreddit: %v", err), err))
 			return
 		}
-
-		log.Printf("Engine: Subreddit creation completed")
 		context.Respond(result)
 
 	case *actors.CreatePostMsg:
 		// Get user profile to check subreddit membership
-		userFuture := context.RequestFuture(e.GetUserSupervisor(),
-			&actors.GetUserProfileMsg{UserID: msg.AuthorID},
-			5*time.Second)
-
-		result, err := userFuture.Result()
+		result, err := e.actorCalls.Request(context, e.GetUserSupervisor(),
+			&actors.GetUserProfileMsg{UserID: msg.AuthorID})
 		if err != nil {
 			context.Respond(utils.NewAppError(utils.ErrActorTimeout, "Failed to validate user", err))
 			return
@@ -191,9 +213,15 @@ func (e *Engine) Receive(context actor.Context) {
 			return
 		}
 
+		hold, err := e.checkPostSettings(ctx, msg)
+		if err != nil {
+			context.Respond(err)
+			return
+		}
+		msg.HoldForApproval = hold
+
 		// Forward to PostActor
-		future := context.RequestFuture(e.postActor, msg, 5*time.Second)
-		result, err = future.Result()
+		result, err = e.actorCalls.Request(context, e.postActor, msg)
 		if err != nil {
 			context.Respond(utils.NewAppError(utils.ErrActorTimeout, "Failed to create post", err))
 			return
@@ -202,19 +230,15 @@ func (e *Engine) Receive(context actor.Context) {
 
 	case *actors.VotePostMsg:
 		// Validate user exists
-		userFuture := context.RequestFuture(e.userSupervisor,
-			&actors.GetUserProfileMsg{UserID: msg.UserID},
-			5*time.Second)
-
-		_, err := userFuture.Result()
+		_, err := e.actorCalls.Request(context, e.userSupervisor,
+			&actors.GetUserProfileMsg{UserID: msg.UserID})
 		if err != nil {
 			context.Respond(utils.NewAppError(utils.ErrActorTimeout, "Failed to validate user", err))
 			return
 		}
 
 		// Forward to PostActor
-		future := context.RequestFuture(e.postActor, msg, 5*time.Second)
-		result, err := future.Result()
+		result, err := e.actorCalls.Request(context, e.postActor, msg)
 		if err != nil {
 			context.Respond(utils.NewAppError(utils.ErrActorTimeout, "Failed to process vote", err))
 			return
@@ -223,11 +247,8 @@ func (e *Engine) Receive(context actor.Context) {
 
 	case *actors.GetUserFeedMsg:
 		// First validate user exists
-		userFuture := context.RequestFuture(e.userSupervisor,
-			&actors.GetUserProfileMsg{UserID: msg.UserID},
-			5*time.Second)
-
-		result, err := userFuture.Result()
+		result, err := e.actorCalls.Request(context, e.userSupervisor,
+			&actors.GetUserProfileMsg{UserID: msg.UserID})
 		if err != nil {
 			context.Respond(utils.NewAppError(utils.ErrActorTimeout, "Failed to validate user", err))
 			return
@@ -240,13 +261,39 @@ func (e *Engine) Receive(context actor.Context) {
 		}
 
 		// Forward to PostActor to get feed
-		future := context.RequestFuture(e.postActor, msg, 5*time.Second)
-		result, err = future.Result()
+		result, err = e.actorCalls.Request(context, e.postActor, msg)
 		if err != nil {
 			context.Respond(utils.NewAppError(utils.ErrActorTimeout, "Failed to get user feed", err))
 			return
 		}
 		context.Respond(result)
+	case *actors.AddModeratorMsg, *actors.RemoveModeratorMsg:
+		e.forwardModeration(context, e.subredditActor, msg)
+
+	case *actors.LockPostMsg:
+		if !msg.ByModerator {
+			e.forward(context, e.postActor, msg, "post")
+			return
+		}
+		e.forwardModeration(context, e.postActor, msg)
+
+	case *actors.DeletePostMsg:
+		if !msg.Force {
+			e.forward(context, e.postActor, msg, "post")
+			return
+		}
+		e.forwardModeration(context, e.postActor, msg)
+
+	case *actors.PostCountMsg:
+		context.Send(e.subredditActor, msg)
+
+	case *actors.DeleteCommentMsg:
+		if !msg.Force {
+			e.forward(context, e.commentActor, msg, "comment")
+			return
+		}
+		e.forwardModeration(context, e.commentActor, msg)
+
 	default:
 		// Route message based on type
 		var targetPID *actor.PID
@@ -263,20 +310,83 @@ func (e *Engine) Receive(context actor.Context) {
 			targetPID = e.postActor
 			msgType = "post"
 		default:
-			log.Printf("Unknown message type: %T", msg)
+			logger.WarnContext(ctx, "Unknown message type", "type", fmt.Sprintf("%T", msg))
 			context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Unknown message type", nil))
 			return
 		}
 
-		future := context.RequestFuture(targetPID, msg, 5*time.Second)
-		result, err := future.Result()
-		if err != nil {
-			context.Respond(utils.NewAppError(utils.ErrActorTimeout,
-				fmt.Sprintf("Failed to process %s request", msgType), err))
-			return
-		}
-		context.Respond(result)
+		e.forward(context, targetPID, msg, msgType)
+	}
+}
+
+// forward sends msg on to target and responds with its reply.
+func (e *Engine) forward(context actor.Context, target *actor.PID, msg interface{}, msgType string) {
+	result, err := e.actorCalls.Request(context, target, msg)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrActorTimeout,
+			fmt.Sprintf("Failed to process %s request", msgType), err))
+		return
+	}
+	context.Respond(result)
+}
+
+// checkPostSettings holds a new post to its subreddit's submission settings,
+// and reports whether it must wait for a moderator's approval. Moderators
+// may post regardless.
+func (e *Engine) checkPostSettings(ctx stdctx.Context, msg *actors.CreatePostMsg) (bool, error) {
+	ctx, cancel := stdctx.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	settings, err := e.db.GetSubredditSettings(ctx, msg.SubredditID)
+	if err != nil {
+		return false, err
+	}
+	author, err := e.db.GetUser(ctx, msg.AuthorID)
+	if err != nil {
+		return false, err
+	}
+	sub, err := e.db.GetSubredditByID(ctx, msg.SubredditID)
+	if err != nil {
+		return false, err
 	}
+	if isMod, err := actors.IsModerator(ctx, e.db, author, sub); err != nil || isMod {
+		return false, err
+	}
+	now := time.Now()
+	if err := checkPostRules(settings, author, msg.URL != "" || msg.MediaID != nil, now); err != nil {
+		return false, err
+	}
+	return needsPostApproval(settings, author, now), nil
+}
+
+// needsPostApproval reports whether the author's karma or account age is
+// below the subreddit's approval thresholds.
+func needsPostApproval(settings *models.SubredditSettings, author *models.User, now time.Time) bool {
+	if settings.PostApprovalKarma > 0 && author.Karma < settings.PostApprovalKarma {
+		return true
+	}
+	return settings.PostApprovalAccountAgeDays > 0 &&
+		now.Sub(author.CreatedAt) < time.Duration(settings.PostApprovalAccountAgeDays)*24*time.Hour
+}
+
+// checkPostRules rejects posts of a type the subreddit doesn't accept and
+// authors too new or with too little karma to post there.
+func checkPostRules(settings *models.SubredditSettings, author *models.User, isLink bool, now time.Time) *utils.AppError {
+	switch {
+	case settings.AllowedPostTypes == models.PostTypesText && isLink:
+		return utils.NewAppError(utils.ErrInvalidInput, "This subreddit only accepts text posts", nil)
+	case settings.AllowedPostTypes == models.PostTypesLink && !isLink:
+		return utils.NewAppError(utils.ErrInvalidInput, "This subreddit only accepts link posts", nil)
+	}
+	if age := now.Sub(author.CreatedAt); age < time.Duration(settings.MinAccountAgeDays)*24*time.Hour {
+		return utils.NewAppError(utils.ErrForbidden,
+			fmt.Sprintf("Accounts must be at least %d days old to post here", settings.MinAccountAgeDays), nil)
+	}
+	if author.Karma < settings.MinKarma {
+		return utils.NewAppError(utils.ErrForbidden,
+			fmt.Sprintf("Insufficient karma to post here (required: %d, current: %d)", settings.MinKarma, author.Karma), nil)
+	}
+	return nil
 }
 
 // Helper functions to identify message types
@@ -289,6 +399,7 @@ func isSubredditMessage(msg interface{}) bool {
 		*actors.GetSubredditMembersMsg,
 		*actors.GetSubredditByIDMsg,
 		*actors.GetSubredditByNameMsg,
+		*actors.GetModeratorsMsg,
 		*actors.GetCountsMsg:
 		return true
 	default:
@@ -301,7 +412,8 @@ func isUserMessage(msg interface{}) bool {
 	case *actors.RegisterUserMsg,
 		*actors.LoginMsg,
 		*actors.GetUserProfileMsg,
-		*actors.UpdateProfileMsg:
+		*actors.UpdateProfileMsg,
+		*actors.ForgetUserMsg:
 		return true
 	default:
 		return false
@@ -314,13 +426,50 @@ func isPostMessage(msg interface{}) bool {
 		*actors.GetPostMsg,
 		*actors.GetSubredditPostsMsg,
 		*actors.VotePostMsg,
-		*actors.DeletePostMsg:
+		*actors.DeletePostMsg,
+		*actors.EditPostMsg,
+		*actors.LockPostMsg,
+		*actors.UpdatePostMetadataMsg,
+		*actors.ReviewPostMsg:
 		return true
 	default:
 		return false
 	}
 }
 
+// ApplyChange forwards a row change made by another instance or an
+// out-of-band tool to the actors caching that table.
+func (e *Engine) ApplyChange(change database.Change) {
+	var targets []*actor.PID
+	switch change.Table {
+	case "posts":
+		targets = []*actor.PID{e.postActor}
+		// Votes cast on other instances reach this one's watchers this way
+		if change.Op == "update" && e.scores != nil {
+			e.scores.touch(change.ID)
+		}
+	case "comments":
+		targets = []*actor.PID{e.commentActor}
+		// Votes cast on other instances; new comments are relayed by the
+		// comment actor. ParentID is the comment's post.
+		if change.Op == "update" && e.scores != nil {
+			e.scores.touchComment(change.ID, change.ParentID)
+		}
+	case "subreddits":
+		targets = []*actor.PID{e.subredditActor}
+	case "subreddit_members":
+		targets = []*actor.PID{e.subredditActor, e.userSupervisor}
+	case "users":
+		targets = []*actor.PID{e.userSupervisor, e.commentActor}
+	}
+	if change.Op == database.ChangeResync {
+		targets = []*actor.PID{e.postActor, e.commentActor, e.subredditActor, e.userSupervisor}
+	}
+	for _, pid := range targets {
+		e.context.Send(pid, &actors.ExternalChangeMsg{Change: change})
+	}
+}
+
 // Getter methods for actor PIDs
 func (e *Engine) GetUserSupervisor() *actor.PID {
 	return e.userSupervisor
@@ -338,6 +487,6 @@ func (e *Engine) GetCommentActor() *actor.PID {
 	return e.commentActor
 }
 
-func (e *Engine) GetDB() database.DBAdapter {
+func (e *Engine) GetDB() database.Store {
 	return e.db
 }
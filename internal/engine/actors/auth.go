@@ -0,0 +1,38 @@
+package actors
+
+import (
+	"context"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// notAuthorized is the response to acting on a post, comment or message that
+// belongs to someone else. Every ownership check in the actors uses it, so
+// callers see the same ErrUnauthorized whatever the resource.
+func notAuthorized(action string) *utils.AppError {
+	return utils.NewAppError(utils.ErrUnauthorized, "Not authorized to "+action, nil)
+}
+
+// ModeratorLookup checks a subreddit's appointed moderators.
+// database.SubredditRepository satisfies it.
+type ModeratorLookup interface {
+	IsSubredditModerator(ctx context.Context, subredditID, userID uuid.UUID) (bool, error)
+}
+
+// IsOwner reports whether user may appoint and dismiss sub's moderators:
+// its creator and site admins may.
+func IsOwner(user *models.User, sub *models.Subreddit) bool {
+	return user.IsAdmin || user.ID == sub.CreatorID
+}
+
+// IsModerator reports whether user moderates sub: its owners and the
+// moderators they appointed do.
+func IsModerator(ctx context.Context, mods ModeratorLookup, user *models.User, sub *models.Subreddit) (bool, error) {
+	if IsOwner(user, sub) {
+		return true, nil
+	}
+	return mods.IsSubredditModerator(ctx, sub.ID, user.ID)
+}
@@ -0,0 +1,9 @@
+package actors
+
+import "gator-swamp/internal/database"
+
+// ExternalChangeMsg tells an actor that another engine instance or an
+// out-of-band tool changed a row it may hold in its cache.
+type ExternalChangeMsg struct {
+	Change database.Change
+}
@@ -2,10 +2,13 @@ package actors
 
 import (
 	stdctx "context"
+	"fmt"
 	"gator-swamp/internal/database"
+	"gator-swamp/internal/events"
+	"gator-swamp/internal/logging"
 	"gator-swamp/internal/models"
+	"gator-swamp/internal/policy"
 	"gator-swamp/internal/utils"
-	"log"
 	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
@@ -20,6 +23,7 @@ type (
 		PostID      uuid.UUID  `json:"postId"`
 		SubredditID uuid.UUID  `json:"subredditId"`
 		ParentID    *uuid.UUID `json:"parentId,omitempty"`
+		InboxItemID *uuid.UUID `json:"-"` // Set for replies from the inbox
 	}
 
 	EditCommentMsg struct {
@@ -31,10 +35,12 @@ type (
 	DeleteCommentMsg struct {
 		CommentID uuid.UUID `json:"commentId"`
 		AuthorID  uuid.UUID `json:"authorId"`
+		Force     bool      `json:"-"` // Admin or moderator removal: skip the author check
 	}
 
 	GetCommentMsg struct {
-		CommentID uuid.UUID `json:"commentId"`
+		CommentID        uuid.UUID `json:"commentId"`
+		RequestingUserID uuid.UUID `json:"requestingUserId,omitempty"` // For vote status
 	}
 
 	GetCommentsForPostMsg struct {
@@ -53,42 +59,70 @@ type (
 		PostID uuid.UUID `json:"postId"`
 	}
 
+	// ReviewCommentMsg approves or rejects a comment held for approval. The
+	// sender checks that the reviewer moderates the subreddit.
+	ReviewCommentMsg struct {
+		SubredditID uuid.UUID `json:"subredditId"`
+		CommentID   uuid.UUID `json:"commentId"`
+		Approve     bool      `json:"approve"`
+	}
+
 	loadCommentsFromDBMsg struct{}
+
+	// postDeletedMsg tells the CommentActor to drop a deleted post's cached comments
+	postDeletedMsg struct {
+		PostID uuid.UUID
+	}
 )
 
+// CommentStore is the data access CommentActor needs.
+type CommentStore interface {
+	database.CommentRepository
+	database.PostRepository
+	database.VoteRepository
+	database.ContentRepository
+	database.UserRepository
+	database.SubredditRepository
+}
+
 // CommentActor manages comment operations
 type CommentActor struct {
 	comments     map[uuid.UUID]*models.Comment
 	postComments map[uuid.UUID][]uuid.UUID
 	enginePID    *actor.PID
-	db           database.DBAdapter
-	userCache    map[uuid.UUID]string // Simple cache for usernames
+	db           CommentStore
+	userCache    map[uuid.UUID]commentAuthor // Simple cache for usernames
+	events       *events.Bus                 // Domain event stream (may be nil)
 }
 
-func NewCommentActor(enginePID *actor.PID, db database.DBAdapter) actor.Actor {
+// commentAuthor is what comments show of their author
+type commentAuthor struct {
+	username string
+	isBot    bool
+}
+
+func NewCommentActor(enginePID *actor.PID, db CommentStore, bus *events.Bus) actor.Actor {
 	return &CommentActor{
 		comments:     make(map[uuid.UUID]*models.Comment),
 		postComments: make(map[uuid.UUID][]uuid.UUID),
 		enginePID:    enginePID,
 		db:           db,
-		userCache:    make(map[uuid.UUID]string), // Initialize user cache
+		userCache:    make(map[uuid.UUID]commentAuthor), // Initialize user cache
+		events:       bus,
 	}
 }
 
 func (a *CommentActor) Receive(context actor.Context) {
 	switch msg := context.Message().(type) {
 	case *actor.Started:
-		log.Printf("CommentActor started with PID: %v", context.Self())
+		context.Logger().Info("CommentActor started", "pid", context.Self())
 		context.Send(context.Self(), &loadCommentsFromDBMsg{})
 
 	case *loadCommentsFromDBMsg:
-		log.Printf("Loading comments from database")
 		a.handleLoadComments(context)
 
 	case *CreateCommentMsg:
-		log.Printf("Received CreateCommentMsg: %+v", msg)
 		a.handleCreateComment(context, msg)
-		log.Printf("Finished handling CreateCommentMsg")
 
 	case *EditCommentMsg:
 		a.handleEditComment(context, msg)
@@ -108,49 +142,94 @@ func (a *CommentActor) Receive(context actor.Context) {
 	case *GetCommentCountMsg:
 		a.handleGetCommentCount(context, msg)
 
+	case *ReviewCommentMsg:
+		a.handleReviewComment(context, msg)
+
+	case *postDeletedMsg:
+		a.evictPostComments(msg.PostID)
+
+	// Rows written elsewhere; cached threads are reloaded on next read
+	case *ExternalChangeMsg:
+		switch {
+		case msg.Change.Op == database.ChangeResync:
+			a.comments = make(map[uuid.UUID]*models.Comment)
+			a.postComments = make(map[uuid.UUID][]uuid.UUID)
+			a.userCache = make(map[uuid.UUID]commentAuthor)
+			a.handleLoadComments(context)
+		case msg.Change.Table == "comments":
+			delete(a.comments, msg.Change.ID)
+			a.evictPostComments(msg.Change.ParentID)
+			if msg.Change.Op == "insert" {
+				a.relayCommentCreated(context, msg.Change.ID)
+			}
+		case msg.Change.Table == "users":
+			delete(a.userCache, msg.Change.ID)
+		}
+
 	default:
-		log.Printf("CommentActor: Unknown message type %T", msg)
+		context.Logger().Warn("CommentActor: unknown message type", "type", fmt.Sprintf("%T", msg))
 	}
 }
 
+// relayCommentCreated announces a comment created on another instance to
+// this one's subscribers, such as clients following its post.
+func (a *CommentActor) relayCommentCreated(context actor.Context, commentID uuid.UUID) {
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+	comment, err := a.db.GetComment(ctx, commentID, uuid.Nil)
+	if err != nil {
+		context.Logger().Error("CommentActor: failed to fetch comment created elsewhere", "comment_id", commentID, "error", err)
+		return
+	}
+	a.events.Relay(events.TypeCommentCreated, commentCreated(comment))
+}
+
 // Helper function to get username, using cache first
-func (a *CommentActor) getUsername(ctx stdctx.Context, userID uuid.UUID) string {
-	if username, ok := a.userCache[userID]; ok {
-		return username
+func (a *CommentActor) getAuthor(ctx stdctx.Context, context actor.Context, userID uuid.UUID) commentAuthor {
+	if author, ok := a.userCache[userID]; ok {
+		return author
 	}
 
 	user, err := a.db.GetUser(ctx, userID)
 	if err != nil {
-		log.Printf("Error fetching user %s for username: %v", userID, err)
-		return "[unknown]" // Return placeholder on error
+		context.Logger().WarnContext(ctx, "Failed to fetch comment author", "user_id", userID, "error", err)
+		return commentAuthor{username: "[unknown]"} // Return placeholder on error
 	}
 
 	// Cache the username
-	a.userCache[userID] = user.Username
-	return user.Username
+	author := commentAuthor{username: user.Username, isBot: user.IsBot}
+	a.userCache[userID] = author
+	return author
+}
+
+// setAuthor fills in a comment's author username and bot badge
+func (a *CommentActor) setAuthor(ctx stdctx.Context, context actor.Context, comment *models.Comment) {
+	author := a.getAuthor(ctx, context, comment.AuthorID)
+	comment.AuthorUsername, comment.AuthorIsBot = author.username, author.isBot
 }
 
 // Helper function to populate usernames for a slice of comments
-func (a *CommentActor) populateUsernames(ctx stdctx.Context, comments []*models.Comment) {
+func (a *CommentActor) populateUsernames(ctx stdctx.Context, context actor.Context, comments []*models.Comment) {
 	for _, comment := range comments {
 		if comment.AuthorUsername == "" { // Populate only if missing
-			comment.AuthorUsername = a.getUsername(ctx, comment.AuthorID)
+			a.setAuthor(ctx, context, comment)
 		}
 	}
 }
 
 func (a *CommentActor) handleLoadComments(context actor.Context) {
-	log.Println("CommentActor: Loading initial comments from database...")
-	ctx := stdctx.Background()
+	context.Logger().Info("CommentActor: loading comments from the database")
+	ctx := logging.Context(context)
 
 	comments, err := a.db.GetAllComments(ctx)
 	if err != nil {
-		log.Printf("CommentActor: CRITICAL - Failed to load initial comments: %v", err)
+		context.Logger().Error("CommentActor: failed to load comments", "retry_in", loadRetryDelay, "error", err)
+		retryLoad(context, &loadCommentsFromDBMsg{})
 		return
 	}
 
 	// Populate usernames after loading
-	a.populateUsernames(ctx, comments)
+	a.populateUsernames(ctx, context, comments)
 
 	loadedCount := 0
 	for _, comment := range comments {
@@ -173,40 +252,52 @@ func (a *CommentActor) handleLoadComments(context actor.Context) {
 		loadedCount++
 	}
 
-	log.Printf("CommentActor: Finished loading %d comments into cache.", loadedCount)
+	context.Logger().Info("CommentActor: loaded comments", "count", loadedCount)
+	context.Send(a.enginePID, &ReadyMsg{Actor: CommentActorName})
 }
 
 func (a *CommentActor) handleCreateComment(context actor.Context, msg *CreateCommentMsg) {
-	// Add initial logging
-	log.Printf("Creating new comment for post %s by user %s", msg.PostID, msg.AuthorID)
-
 	// First, fetch the post to get its subredditID
-	ctx := stdctx.Background()
+	ctx := logging.Context(context)
 	// Pass uuid.Nil as requestingUserID, as we only need subredditID here
 	post, err := a.db.GetPost(ctx, msg.PostID, uuid.Nil)
 	if err != nil {
-		log.Printf("Error fetching post: %v", err)
+		context.Logger().ErrorContext(ctx, "Failed to fetch post for comment", "post_id", msg.PostID, "error", err)
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch parent post", err))
 		return
 	}
+	if post.LockedByAuthor || post.LockedByModerator {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "This post is locked to new comments", nil))
+		return
+	}
+	if post.Archived {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "This post is archived", nil))
+		return
+	}
 
 	// Fetch the user to get their username
 	user, err := a.db.GetUser(ctx, msg.AuthorID)
 	if err != nil {
-		log.Printf("Error fetching user: %v", err)
+		context.Logger().ErrorContext(ctx, "Failed to fetch comment author", "user_id", msg.AuthorID, "error", err)
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch author details", err))
 		return
 	}
+	if user.IsBot {
+		if err := policy.CheckBot(ctx, a.db, policy.OpCreateComment, user.ID, post.SubredditID); err != nil {
+			context.Respond(err)
+			return
+		}
+	}
 
 	now := time.Now()
 	commentID := uuid.New()
-	log.Printf("Generated new comment ID: %s", commentID)
 
 	newComment := &models.Comment{
 		ID:             commentID,
 		Content:        msg.Content,
 		AuthorID:       msg.AuthorID,
 		AuthorUsername: user.Username,
+		AuthorIsBot:    user.IsBot,
 		PostID:         msg.PostID,
 		SubredditID:    post.SubredditID,
 		ParentID:       msg.ParentID,
@@ -215,13 +306,10 @@ func (a *CommentActor) handleCreateComment(context actor.Context, msg *CreateCom
 		UpdatedAt:      now,
 		IsDeleted:      false,
 		Karma:          1, // Start with 1 karma (author's implicit upvote?)
+		InboxItemID:    msg.InboxItemID,
 	}
 	if msg.ParentID != nil {
-		log.Printf("This is a reply to comment ID: %s", msg.ParentID.String())
-
-		parentComment, err := a.db.GetComment(ctx, *msg.ParentID)
-		if err != nil {
-			log.Printf("Error fetching parent comment: %v", err)
+		if _, err := a.db.GetComment(ctx, *msg.ParentID, uuid.Nil); err != nil {
 			if utils.IsErrorCode(err, utils.ErrNotFound) {
 				context.Respond(utils.NewAppError(utils.ErrNotFound, "Parent comment not found", nil))
 			} else {
@@ -229,88 +317,177 @@ func (a *CommentActor) handleCreateComment(context actor.Context, msg *CreateCom
 			}
 			return
 		}
+	}
 
-		// Update parent's children array
-		parentComment.Children = append(parentComment.Children, commentID)
-		parentComment.UpdatedAt = now
-
-		// Temporarily comment out saving the parent to isolate the issue
-		/*
-			// Save updated parent comment
-			if err := a.db.SaveComment(ctx, parentComment); err != nil {
-				log.Printf("Error updating parent comment: %v", err)
-				context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to update parent comment", err))
-				return
-			}
-		*/
-
-		// Update cache
-		a.comments[parentComment.ID] = parentComment
+	// Subreddits may hold comments until a moderator approves them
+	held, err := a.requiresApproval(ctx, post.SubredditID, user)
+	if err != nil {
+		context.Respond(err)
+		return
+	}
+	if held {
+		if err := a.db.HoldComment(ctx, newComment); err != nil {
+			context.Logger().ErrorContext(ctx, "Failed to hold comment for approval", "comment_id", newComment.ID, "error", err)
+			context.Respond(err)
+			return
+		}
+		response := newCommentResponse(newComment)
+		response.Pending = true
+		context.Respond(response)
+		return
 	}
 
-	// Add log right before saving the NEW comment
-	log.Printf("Before saving NEW comment %s, ParentID is: %v", newComment.ID, newComment.ParentID)
-	// Save the new comment
-	if err := a.db.SaveComment(ctx, newComment); err != nil {
-		log.Printf("Error saving comment to database: %v", err)
+	if err := a.addComment(ctx, newComment); err != nil {
+		context.Logger().ErrorContext(ctx, "Failed to save comment", "comment_id", commentID, "error", err)
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to save comment", err))
 		return
 	}
 
-	// Update local cache for the new comment
-	a.comments[commentID] = newComment
-	a.postComments[msg.PostID] = append(a.postComments[msg.PostID], commentID)
-
-	// Create response
-	response := struct {
-		ID             string    `json:"id"`
-		Content        string    `json:"content"`
-		AuthorID       string    `json:"authorId"`
-		AuthorUsername string    `json:"authorUsername"`
-		PostID         string    `json:"postId"`
-		SubredditID    string    `json:"subredditId"`
-		ParentID       *string   `json:"parentId,omitempty"`
-		Children       []string  `json:"children"`
-		CreatedAt      time.Time `json:"createdAt"`
-		UpdatedAt      time.Time `json:"updatedAt"`
-		IsDeleted      bool      `json:"isDeleted"`
-		Karma          int       `json:"karma"`
-	}{
-		ID:             newComment.ID.String(),
-		Content:        newComment.Content,
-		AuthorID:       newComment.AuthorID.String(),
-		AuthorUsername: newComment.AuthorUsername,
-		PostID:         newComment.PostID.String(),
-		SubredditID:    newComment.SubredditID.String(),
-		Children:       make([]string, 0),
-		CreatedAt:      newComment.CreatedAt,
-		UpdatedAt:      newComment.UpdatedAt,
-		IsDeleted:      newComment.IsDeleted,
-		Karma:          newComment.Karma,
+	context.Logger().DebugContext(ctx, "Comment created", "comment_id", commentID, "post_id", msg.PostID)
+	context.Respond(newCommentResponse(newComment))
+}
+
+// requiresApproval reports whether a new comment by author in a subreddit
+// must wait for a moderator. Moderators' own comments never do.
+func (a *CommentActor) requiresApproval(ctx stdctx.Context, subredditID uuid.UUID, author *models.User) (bool, error) {
+	settings, err := a.db.GetSubredditSettings(ctx, subredditID)
+	if err != nil || !settings.CommentsRequireApproval {
+		return false, err
+	}
+	sub, err := a.db.GetSubredditByID(ctx, subredditID)
+	if err != nil {
+		return false, err
 	}
+	isMod, err := IsModerator(ctx, a.db, author, sub)
+	return !isMod, err
+}
 
-	if newComment.ParentID != nil {
-		parentIDStr := newComment.ParentID.String()
-		response.ParentID = &parentIDStr
+// addComment saves a new comment and adds it to the caches.
+func (a *CommentActor) addComment(ctx stdctx.Context, comment *models.Comment) error {
+	if err := a.db.SaveComment(ctx, comment); err != nil {
+		return err
+	}
+
+	a.comments[comment.ID] = comment
+	a.postComments[comment.PostID] = append(a.postComments[comment.PostID], comment.ID)
+	if comment.ParentID != nil {
+		if parent, ok := a.comments[*comment.ParentID]; ok {
+			parent.Children = append(parent.Children, comment.ID)
+			parent.ReplyCount++
+			parent.UpdatedAt = comment.CreatedAt
+		}
 	}
 
-	log.Printf("Successfully created comment with ID: %s", commentID)
-	// Log the response struct right before sending
-	log.Printf("Responding to handler with: %+v", response)
-	context.Respond(response)
+	a.events.Publish(events.TypeCommentCreated, commentCreated(comment))
+	return nil
+}
+
+// commentCreated is the TypeCommentCreated payload for a comment.
+func commentCreated(comment *models.Comment) events.CommentCreated {
+	return events.CommentCreated{
+		CommentID:      comment.ID,
+		PostID:         comment.PostID,
+		ParentID:       comment.ParentID,
+		AuthorID:       comment.AuthorID,
+		AuthorUsername: comment.AuthorUsername,
+		AuthorIsBot:    comment.AuthorIsBot,
+		Content:        comment.Content,
+		CreatedAt:      comment.CreatedAt,
+	}
+}
+
+// CommentResponse is the reply to creating or approving a comment.
+type CommentResponse struct {
+	ID             string    `json:"id"`
+	Content        string    `json:"content"`
+	AuthorID       string    `json:"authorId"`
+	AuthorUsername string    `json:"authorUsername"`
+	AuthorIsBot    bool      `json:"authorIsBot,omitempty"`
+	PostID         string    `json:"postId"`
+	SubredditID    string    `json:"subredditId"`
+	ParentID       *string   `json:"parentId,omitempty"`
+	Children       []string  `json:"children"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	IsDeleted      bool      `json:"isDeleted"`
+	Karma          int       `json:"karma"`
+	Pending        bool      `json:"pending,omitempty"` // Held for moderator approval
+}
+
+func newCommentResponse(comment *models.Comment) *CommentResponse {
+	response := &CommentResponse{
+		ID:             comment.ID.String(),
+		Content:        comment.Content,
+		AuthorID:       comment.AuthorID.String(),
+		AuthorUsername: comment.AuthorUsername,
+		AuthorIsBot:    comment.AuthorIsBot,
+		PostID:         comment.PostID.String(),
+		SubredditID:    comment.SubredditID.String(),
+		Children:       make([]string, 0),
+		CreatedAt:      comment.CreatedAt,
+		UpdatedAt:      comment.UpdatedAt,
+		IsDeleted:      comment.IsDeleted,
+		Karma:          comment.Karma,
+	}
+	if comment.ParentID != nil {
+		parentIDStr := comment.ParentID.String()
+		response.ParentID = &parentIDStr
+	}
+	return response
 }
 
-// If this is a reply to another comment, update the parent comment's children array
+// handleReviewComment approves or rejects a comment held for approval.
+// Approved comments are saved as if just posted.
+func (a *CommentActor) handleReviewComment(context actor.Context, msg *ReviewCommentMsg) {
+	ctx := logging.Context(context)
+
+	comment, err := a.db.TakePendingComment(ctx, msg.SubredditID, msg.CommentID)
+	if err != nil {
+		context.Respond(err)
+		return
+	}
+	if !msg.Approve {
+		context.Respond(&models.StatusResponse{Success: true, Message: "Comment rejected"})
+		return
+	}
+
+	a.setAuthor(ctx, context, comment)
+	comment.Children = make([]uuid.UUID, 0)
+	comment.Karma = 1
+	if err := a.addComment(ctx, comment); err != nil {
+		context.Logger().ErrorContext(ctx, "Failed to save approved comment", "comment_id", comment.ID, "error", err)
+		// Put it back so it can be reviewed again
+		if holdErr := a.db.HoldComment(ctx, comment); holdErr != nil {
+			context.Logger().ErrorContext(ctx, "Failed to return comment to the approval queue", "comment_id", comment.ID, "error", holdErr)
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to save comment", err))
+		return
+	}
+	context.Respond(newCommentResponse(comment))
+}
 
 func (a *CommentActor) handleEditComment(context actor.Context, msg *EditCommentMsg) {
+	ctx := logging.Context(context)
+
+	// Deletes evict whole posts from the cache, so fall back to the
+	// database like handleGetComment does
 	comment, exists := a.comments[msg.CommentID]
 	if !exists {
-		context.Respond(utils.NewAppError(utils.ErrNotFound, "Comment not found", nil))
-		return
+		var err error
+		comment, err = a.db.GetComment(ctx, msg.CommentID, uuid.Nil)
+		if err != nil {
+			if utils.IsErrorCode(err, utils.ErrNotFound) {
+				context.Respond(utils.NewAppError(utils.ErrNotFound, "Comment not found", nil))
+				return
+			}
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get comment", err))
+			return
+		}
+		a.comments[comment.ID] = comment
 	}
 
 	if comment.AuthorID != msg.AuthorID {
-		context.Respond(utils.NewAppError(utils.ErrUnauthorized, "Not authorized to edit comment", nil))
+		context.Respond(notAuthorized("edit this comment"))
 		return
 	}
 
@@ -323,7 +500,6 @@ func (a *CommentActor) handleEditComment(context actor.Context, msg *EditComment
 	comment.UpdatedAt = time.Now()
 
 	// Update in database
-	ctx := stdctx.Background()
 	if err := a.db.SaveComment(ctx, comment); err != nil {
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to update comment", err))
 		return
@@ -332,75 +508,62 @@ func (a *CommentActor) handleEditComment(context actor.Context, msg *EditComment
 	context.Respond(comment)
 }
 
+// handleDeleteComment soft-deletes a comment and its replies. Only the
+// author may delete unless the message is an admin removal.
 func (a *CommentActor) handleDeleteComment(context actor.Context, msg *DeleteCommentMsg) {
-	ctx := stdctx.Background()
-	log.Printf("Attempting to delete comment ID: %s by user %s", msg.CommentID, msg.AuthorID)
+	ctx := logging.Context(context)
 
-	// Optional: Fetch the comment to verify authorship before deleting
-	comment, err := a.db.GetComment(ctx, msg.CommentID)
+	// Fetch the comment to verify authorship before deleting
+	comment, err := a.db.GetComment(ctx, msg.CommentID, uuid.Nil)
 	if err != nil {
 		if utils.IsErrorCode(err, utils.ErrNotFound) {
-			log.Printf("Comment %s not found for deletion.", msg.CommentID)
 			context.Respond(utils.NewAppError(utils.ErrNotFound, "Comment not found", nil))
 			return
 		}
-		log.Printf("Error fetching comment %s for deletion: %v", msg.CommentID, err)
+		context.Logger().ErrorContext(ctx, "Failed to fetch comment for deletion", "comment_id", msg.CommentID, "error", err)
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch comment for deletion", err))
 		return
 	}
 
-	if comment.AuthorID != msg.AuthorID {
-		log.Printf("User %s unauthorized to delete comment %s (author is %s)", msg.AuthorID, msg.CommentID, comment.AuthorID)
-		context.Respond(utils.NewAppError(utils.ErrUnauthorized, "User not authorized to delete this comment", nil))
+	if comment.AuthorID != msg.AuthorID && !msg.Force {
+		context.Respond(notAuthorized("delete this comment"))
 		return
 	}
 
-	// Perform hard delete using the new database function
-	err = a.db.DeleteCommentAndDecrementCount(ctx, msg.CommentID)
-	if err != nil {
-		// Log the detailed error from the DB layer
-		log.Printf("Error during DeleteCommentAndDecrementCount for comment %s: %v", msg.CommentID, err)
-		// Respond with the error passed up from the DB layer
-		context.Respond(err) // err from DB should already be an AppError or wrapped
+	if err := a.db.SoftDelete(ctx, models.ContentComment, msg.CommentID); err != nil {
+		context.Logger().ErrorContext(ctx, "Failed to delete comment", "comment_id", msg.CommentID, "error", err)
+		context.Respond(err) // err from DB is already an AppError
 		return
 	}
 
-	// If successful, update local caches (if any)
+	// Replies went with the comment, so drop the post's cached comments
+	// rather than tracking which ones were in the thread
 	delete(a.comments, msg.CommentID)
-	if comment.PostID != uuid.Nil {
-		if postCommentIDs, ok := a.postComments[comment.PostID]; ok {
-			newPostCommentIDs := make([]uuid.UUID, 0, len(postCommentIDs)-1)
-			for _, id := range postCommentIDs {
-				if id != msg.CommentID {
-					newPostCommentIDs = append(newPostCommentIDs, id)
-				}
-			}
-			a.postComments[comment.PostID] = newPostCommentIDs
-		}
-	}
-	// TODO: Handle recursive deletion of child comments if required.
-	// The current `deleteCommentAndChildren` logic would need to be adapted
-	// to use `DeleteCommentAndDecrementCount` for each child as well.
-	// For now, this commit only handles the direct deletion of the specified comment.
+	a.evictPostComments(comment.PostID)
 
-	log.Printf("Successfully deleted comment ID: %s and updated post count.", msg.CommentID)
+	context.Logger().InfoContext(ctx, "Comment deleted", "comment_id", msg.CommentID, "user_id", msg.AuthorID)
 	context.Respond(&models.StatusResponse{Success: true, Message: "Comment deleted successfully"})
 }
 
-// deleteCommentAndChildren recursively sets IsDeleted flag on a comment and its children.
-// THIS FUNCTION NEEDS TO BE REVISITED if hard deletes are fully implemented for children.
-// Currently, it sets a model field that isn't persisted as 'is_deleted' in the DB.
+// evictPostComments drops a post's comments from the cache.
+func (a *CommentActor) evictPostComments(postID uuid.UUID) {
+	for _, id := range a.postComments[postID] {
+		delete(a.comments, id)
+	}
+	delete(a.postComments, postID)
+}
 
 func (a *CommentActor) handleGetComment(context actor.Context, msg *GetCommentMsg) {
-	// Try cache first
-	if comment, exists := a.comments[msg.CommentID]; exists {
+	// Try cache first. Cached comments carry no vote status, so a requesting
+	// user always goes to the database.
+	if comment, exists := a.comments[msg.CommentID]; exists && msg.RequestingUserID == uuid.Nil {
 		context.Respond(comment)
 		return
 	}
 
 	// If not in cache, try database
-	ctx := stdctx.Background()
-	comment, err := a.db.GetComment(ctx, msg.CommentID)
+	ctx := logging.Context(context)
+	comment, err := a.db.GetComment(ctx, msg.CommentID, msg.RequestingUserID)
 	if err != nil {
 		if utils.IsErrorCode(err, utils.ErrNotFound) {
 			context.Respond(utils.NewAppError(utils.ErrNotFound, "Comment not found", nil))
@@ -410,38 +573,38 @@ func (a *CommentActor) handleGetComment(context actor.Context, msg *GetCommentMs
 		return
 	}
 
-	// Update cache
-	a.comments[comment.ID] = comment
+	// Update cache without the user-specific vote
+	cached := *comment
+	cached.CurrentUserVote = nil
+	a.comments[comment.ID] = &cached
 	context.Respond(comment)
 }
 
 // handleGetPostComments retrieves comments for a post, fetching from DB if needed.
 func (a *CommentActor) handleGetPostComments(context actor.Context, msg *GetCommentsForPostMsg) {
-	ctx := stdctx.Background()
-	log.Printf("Fetching comments for post %s, requesting user %s", msg.PostID, msg.RequestingUserID)
+	ctx := logging.Context(context)
 
 	// Pass RequestingUserID to the database method
 	comments, err := a.db.GetPostComments(ctx, msg.PostID, msg.RequestingUserID)
 	if err != nil {
-		log.Printf("Error fetching comments for post %s: %v", msg.PostID, err)
+		context.Logger().ErrorContext(ctx, "Failed to fetch post comments", "post_id", msg.PostID, "error", err)
 		// Check for specific error types if necessary, e.g., utils.IsErrorCode
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch comments", err))
 		return
 	}
 
 	// Populate usernames for the comments
-	a.populateUsernames(ctx, comments)
+	a.populateUsernames(ctx, context, comments)
 
 	// Update cache (optional, consider if this is the source of truth or if DB is always queried)
 	// For simplicity, we assume the DB query is the most up-to-date source for this specific request.
 	// If caching is implemented for this, ensure it handles user-specific data like CurrentUserVote correctly.
 
-	log.Printf("Fetched %d comments for post %s", len(comments), msg.PostID)
 	context.Respond(comments)
 }
 
 func (a *CommentActor) handleVoteComment(context actor.Context, msg *VoteCommentMsg) {
-	ctx := stdctx.Background()
+	ctx := logging.Context(context)
 
 	var direction models.VoteDirection
 	if msg.RemoveVote {
@@ -453,33 +616,50 @@ func (a *CommentActor) handleVoteComment(context actor.Context, msg *VoteComment
 	}
 
 	err := a.db.RecordVote(ctx, msg.UserID, msg.CommentID, models.CommentVote, direction)
+	if utils.IsErrorCode(err, utils.ErrForbidden) {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "This post is archived", nil))
+		return
+	}
 	if err != nil {
-		log.Printf("Error recording vote for comment %s by user %s: %v", msg.CommentID, msg.UserID, err)
+		context.Logger().ErrorContext(ctx, "Failed to record comment vote", "comment_id", msg.CommentID, "user_id", msg.UserID, "error", err)
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "failed to process comment vote", err))
 		return
 	}
 
+	// The comment's post, for subscribers following it
+	var postID uuid.UUID
+	if comment, ok := a.comments[msg.CommentID]; ok {
+		postID = comment.PostID
+	} else if comment, err := a.db.GetComment(ctx, msg.CommentID, uuid.Nil); err == nil {
+		postID = comment.PostID
+	}
+
+	a.events.Publish(events.TypeVoteRecorded, events.VoteRecorded{
+		UserID:      msg.UserID,
+		ContentID:   msg.CommentID,
+		ContentType: models.CommentVote,
+		PostID:      postID,
+		Direction:   direction,
+	})
+
 	// Invalidate comment cache entry
 	delete(a.comments, msg.CommentID)
 
-	context.Respond(&struct{ Success bool }{Success: true})
+	context.Respond(&models.StatusResponse{Success: true})
 }
 
-// handleGetCommentCount handles requests for comment counts (from PostActor)
+// handleGetCommentCount handles requests for comment counts (from PostActor).
+// The count comes from the database so it stays correct across restarts and
+// writes made outside this actor.
 func (a *CommentActor) handleGetCommentCount(context actor.Context, msg *GetCommentCountMsg) {
-	// This can be optimized. Instead of loading all comments, maybe query DB directly.
-	// For now, use the cache.
-	count := 0
-	if ids, ok := a.postComments[msg.PostID]; ok {
-		count = len(ids)
-		// We might want to filter out deleted comments if the cache holds them
-		// filteredCount := 0
-		// for _, id := range ids {
-		// 	if c, exists := a.comments[id]; exists && !c.IsDeleted {
-		// 		filteredCount++
-		// 	}
-		// }
-		// count = filteredCount
+	dbCtx, cancel := stdctx.WithTimeout(logging.Context(context), 5*time.Second)
+	defer cancel()
+
+	count, err := a.db.CountCommentsByPost(dbCtx, msg.PostID)
+	if err != nil {
+		context.Logger().ErrorContext(dbCtx, "Failed to count post comments", "post_id", msg.PostID, "error", err)
+		context.Respond(err)
+		return
 	}
 	context.Respond(count)
 }
@@ -4,9 +4,11 @@ import (
 	stdctx "context" // Alias for standard context to avoid confusion with actor.Context
 	"encoding/json"  // Add for marshalling
 	"gator-swamp/internal/database"
+	"gator-swamp/internal/jobs"
+	"gator-swamp/internal/logging"
 	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
 	"gator-swamp/internal/websocket" // Import websocket package
-	"log"
 	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
@@ -16,9 +18,10 @@ import (
 // Message types for DirectMessageActor
 type (
 	SendDirectMessageMsg struct {
-		FromID  uuid.UUID `json:"fromId"`
-		ToID    uuid.UUID `json:"toId"`
-		Content string    `json:"content"`
+		FromID    uuid.UUID  `json:"fromId"`
+		ToID      uuid.UUID  `json:"toId"`
+		Content   string     `json:"content"`
+		InReplyTo *uuid.UUID `json:"-"` // Set for replies from the inbox
 	}
 
 	GetUserMessagesMsg struct {
@@ -26,8 +29,9 @@ type (
 	}
 
 	GetConversationMsg struct {
-		UserID1 uuid.UUID `json:"userId1"`
-		UserID2 uuid.UUID `json:"userId2"`
+		UserID1          uuid.UUID `json:"userId1"`
+		UserID2          uuid.UUID `json:"userId2"`
+		RequestingUserID uuid.UUID `json:"requestingUserId"` // Must be one of the participants
 	}
 
 	MarkMessageReadMsg struct {
@@ -40,11 +44,34 @@ type (
 		UserID    uuid.UUID `json:"userId"`
 	}
 
-	// MessageStatusUpdate is sent via WebSocket when a message's read status changes
-	MessageStatusUpdate struct {
-		Type      string    `json:"type"` // e.g., "messageRead"
+	// ReplyToMessageMsg answers a message UserID received and marks it read
+	// in the same step
+	ReplyToMessageMsg struct {
 		MessageID uuid.UUID `json:"messageId"`
-		ReadAt    time.Time `json:"readAt"`
+		UserID    uuid.UUID `json:"userId"`
+		Content   string    `json:"content"`
+	}
+
+	// GetUnreadCountMsg asks how many messages UserID received and hasn't
+	// read
+	GetUnreadCountMsg struct {
+		UserID uuid.UUID `json:"userId"`
+	}
+
+	// UnreadCountUpdate is sent via WebSocket to a user whenever their
+	// unread message count changes, for badges
+	UnreadCountUpdate struct {
+		Type  string `json:"type"` // Always "unreadCount"
+		Count int    `json:"count"`
+	}
+
+	// MessageStatusUpdate is sent via WebSocket to a message's sender when it
+	// is delivered or read
+	MessageStatusUpdate struct {
+		Type        string     `json:"type"` // "messageDelivered" or "messageRead"
+		MessageID   uuid.UUID  `json:"messageId"`
+		DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+		ReadAt      *time.Time `json:"readAt,omitempty"`
 	}
 )
 
@@ -52,20 +79,39 @@ type (
 type DirectMessageActor struct {
 	messages     map[uuid.UUID]*models.DirectMessage
 	userMessages map[uuid.UUID]map[uuid.UUID][]*models.DirectMessage
-	db           database.DBAdapter
+	unread       map[uuid.UUID]int // Unread counts of users loaded so far
+	db           database.MessageRepository
 	hub          *websocket.Hub
+	jobs         *jobs.Queue // Persists writes with retries instead of fire-and-forget goroutines
 }
 
-func NewDirectMessageActor(db database.DBAdapter, hub *websocket.Hub) actor.Actor {
+func NewDirectMessageActor(db database.MessageRepository, hub *websocket.Hub, queue *jobs.Queue) actor.Actor {
 	return &DirectMessageActor{
 		messages:     make(map[uuid.UUID]*models.DirectMessage),
 		userMessages: make(map[uuid.UUID]map[uuid.UUID][]*models.DirectMessage),
+		unread:       make(map[uuid.UUID]int),
 		db:           db,
 		hub:          hub,
+		jobs:         queue,
+	}
+}
+
+// enqueue queues a persistence job, logging if even that fails.
+func (a *DirectMessageActor) enqueue(context actor.Context, jobType string, payload interface{}) {
+	ctx, cancel := stdctx.WithTimeout(logging.Context(context), 5*time.Second)
+	defer cancel()
+	if err := a.jobs.Enqueue(ctx, jobType, payload); err != nil {
+		context.Logger().ErrorContext(ctx, "Failed to enqueue job", "type", jobType, "error", err)
 	}
 }
 
 func (a *DirectMessageActor) handleSendMessage(context actor.Context, msg *SendDirectMessageMsg) {
+	context.Respond(a.send(context, msg))
+}
+
+// send stores, pushes and queues the save of a new message.
+func (a *DirectMessageActor) send(context actor.Context, msg *SendDirectMessageMsg) *models.DirectMessage {
+	ctx := logging.Context(context)
 	newMessage := &models.DirectMessage{
 		ID:        uuid.New(),
 		FromID:    msg.FromID,
@@ -74,6 +120,7 @@ func (a *DirectMessageActor) handleSendMessage(context actor.Context, msg *SendD
 		CreatedAt: time.Now(),
 		IsRead:    false,
 		IsDeleted: false,
+		InReplyTo: msg.InReplyTo,
 	}
 
 	// Store in messages map
@@ -91,37 +138,32 @@ func (a *DirectMessageActor) handleSendMessage(context actor.Context, msg *SendD
 	a.userMessages[msg.FromID][msg.ToID] = append(a.userMessages[msg.FromID][msg.ToID], newMessage)
 	a.userMessages[msg.ToID][msg.FromID] = append(a.userMessages[msg.ToID][msg.FromID], newMessage)
 
-	// Save to DB in the background
-	go func() {
-		ctx := stdctx.Background()
-		if err := a.db.SaveMessage(ctx, newMessage); err != nil {
-			log.Printf("Failed to save message to DB: %v", err)
-		}
-	}()
+	// Push message to the recipient if they're connected; it then counts as
+	// delivered and is saved that way
+	if payload, err := json.Marshal(newMessage); err != nil {
+		context.Logger().ErrorContext(ctx, "Failed to marshal message for WebSocket push", "message_id", newMessage.ID, "error", err)
+	} else if a.hub.SendToConnected([]uuid.UUID{newMessage.ToID}, payload) > 0 {
+		deliveredAt := time.Now()
+		newMessage.DeliveredAt = &deliveredAt
+		newMessage.IsDelivered = true
+		context.Logger().DebugContext(ctx, "Message pushed to recipient", "message_id", newMessage.ID, "to_id", newMessage.ToID)
+	}
 
-	// Respond to the original HTTP request immediately
-	context.Respond(newMessage)
+	// Counted before the save is queued, so a count loaded now can't include it
+	a.adjustUnread(context, newMessage.ToID, 1)
 
-	// Push message via WebSocket Hub to recipient
-	go func() {
-		payload, err := json.Marshal(newMessage)
-		if err != nil {
-			log.Printf("Failed to marshal message for WebSocket push: %v", err)
-			return
-		}
-		a.hub.SendDirectMessage(newMessage.ToID, payload)
-		log.Printf("Message %s pushed to Hub for recipient %s", newMessage.ID, newMessage.ToID)
-	}()
+	// Save to DB via the job queue so failures are retried
+	a.enqueue(context, jobs.TypeSaveMessage, newMessage)
 
-	log.Printf("New message %s processed (sent from %s to %s)", newMessage.ID, msg.FromID, msg.ToID)
+	return newMessage
 }
 
 func (a *DirectMessageActor) handleGetUserMessages(context actor.Context, msg *GetUserMessagesMsg) {
 	// Use a foreground DB fetch
-	ctx := stdctx.Background()
+	ctx := logging.Context(context)
 	messages, err := a.db.GetMessagesByUser(ctx, msg.UserID)
 	if err != nil {
-		log.Printf("Failed to get messages from DB: %v", err)
+		context.Logger().ErrorContext(ctx, "Failed to get messages", "user_id", msg.UserID, "error", err)
 		context.Respond([]*models.DirectMessage{})
 		return
 	}
@@ -157,19 +199,24 @@ func (a *DirectMessageActor) handleGetUserMessages(context actor.Context, msg *G
 	var activeMessages []*models.DirectMessage
 	for _, message := range messages {
 		if !message.IsDeleted {
+			a.markDelivered(context, message, msg.UserID)
 			activeMessages = append(activeMessages, message)
 		}
 	}
 
-	log.Printf("Found %d active messages for user %s", len(activeMessages), msg.UserID)
 	context.Respond(activeMessages)
 }
 
 func (a *DirectMessageActor) handleGetConversation(context actor.Context, msg *GetConversationMsg) {
+	if msg.RequestingUserID != msg.UserID1 && msg.RequestingUserID != msg.UserID2 {
+		context.Respond(notAuthorized("read this conversation"))
+		return
+	}
 	if messages, exists := a.userMessages[msg.UserID1][msg.UserID2]; exists {
 		var activeMessages []*models.DirectMessage
 		for _, message := range messages {
 			if !message.IsDeleted {
+				a.markDelivered(context, message, msg.RequestingUserID)
 				activeMessages = append(activeMessages, message)
 			}
 		}
@@ -181,68 +228,187 @@ func (a *DirectMessageActor) handleGetConversation(context actor.Context, msg *G
 
 func (a *DirectMessageActor) handleMarkMessageRead(context actor.Context, msg *MarkMessageReadMsg) {
 	if message, exists := a.messages[msg.MessageID]; exists {
-		// Check if the user marking read is the recipient AND the message is not already marked read
-		if message.ToID == msg.UserID && !message.IsRead {
-			readTime := time.Now()
-			message.IsRead = true
-			message.ReadAt = &readTime // Update in-memory struct as well
-
-			// Update DB in the background
-			go func() {
-				ctx := stdctx.Background()
-				isRead := true
-				// Call DB update with the correct signature (isRead bool pointer)
-				if err := a.db.UpdateMessageStatus(ctx, msg.MessageID, &isRead, nil); err != nil {
-					log.Printf("Failed to update message read status in DB: %v", err)
-					// Potentially revert in-memory change or log for reconciliation
-				}
-			}()
-
-			// Send WebSocket notification to the original sender
-			go func(originalSenderID uuid.UUID, msgID uuid.UUID, rt time.Time) {
-				statusUpdatePayload := MessageStatusUpdate{
-					Type:      "messageRead",
-					MessageID: msgID,
-					ReadAt:    rt,
-				}
-				payloadBytes, err := json.Marshal(statusUpdatePayload)
-				if err != nil {
-					log.Printf("Failed to marshal read status update for WebSocket push: %v", err)
-					return
-				}
-				a.hub.SendDirectMessage(originalSenderID, payloadBytes)
-				log.Printf("Read status update for message %s pushed to Hub for sender %s", msgID, originalSenderID)
-			}(message.FromID, message.ID, readTime) // Pass necessary data into the goroutine
-
-			context.Respond(true) // Respond to the original HTTP request
-			return
-		} else if message.ToID == msg.UserID && message.IsRead {
-			// If already marked read (e.g., duplicate request), still respond true
-			context.Respond(true)
+		if message.ToID != msg.UserID {
+			context.Respond(notAuthorized("mark this message read"))
 			return
 		}
+		// Already read (e.g., duplicate request) still responds true
+		a.markRead(context, message)
+		context.Respond(true) // Respond to the original HTTP request
+		return
 	}
-	// Message not found or user is not the recipient
+	// Message not found
 	context.Respond(false)
 }
 
+// markRead records that a message's recipient read it, unless they already
+// had, and tells the sender.
+func (a *DirectMessageActor) markRead(context actor.Context, message *models.DirectMessage) {
+	if message.IsRead {
+		return
+	}
+	// Counted by noteRead before the update is queued, so a count loaded now
+	// still includes it
+	a.noteRead(context, message)
+
+	// Update DB via the job queue
+	isRead := true
+	a.enqueue(context, jobs.TypeUpdateMessageStatus, jobs.MessageStatusPayload{MessageID: message.ID, IsRead: &isRead})
+}
+
+// noteRead marks a message read in memory and in the unread count, and tells
+// the sender. Replies from the inbox stop here: saving the reply marks the
+// message read in the database, in the same transaction.
+func (a *DirectMessageActor) noteRead(context actor.Context, message *models.DirectMessage) {
+	readTime := time.Now()
+	message.IsRead = true
+	message.ReadAt = &readTime // Update in-memory struct as well
+	if message.DeliveredAt == nil {
+		message.DeliveredAt = &readTime
+		message.IsDelivered = true
+	}
+
+	a.adjustUnread(context, message.ToID, -1)
+
+	// Send WebSocket notification to the original sender
+	a.notifySender(context, message.FromID, MessageStatusUpdate{
+		Type:      "messageRead",
+		MessageID: message.ID,
+		ReadAt:    &readTime,
+	})
+}
+
+// handleReplyToMessage sends the reply and marks the message read together:
+// the reply's save job marks it read in the same transaction, so the inbox
+// never shows it unread after it was answered. A message this
+// actor hasn't seen yet is looked up among the user's messages.
+func (a *DirectMessageActor) handleReplyToMessage(context actor.Context, msg *ReplyToMessageMsg) {
+	message, exists := a.messages[msg.MessageID]
+	if !exists {
+		messages, err := a.db.GetMessagesByUser(logging.Context(context), msg.UserID)
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch message", err))
+			return
+		}
+		for _, m := range messages {
+			if m.ID == msg.MessageID {
+				message, exists = m, true
+				a.messages[m.ID] = m
+			}
+		}
+	}
+	if !exists || message.IsDeleted {
+		context.Respond(utils.NewAppError(utils.ErrNotFound, "Message not found", nil))
+		return
+	}
+	if message.ToID != msg.UserID {
+		context.Respond(notAuthorized("reply to this message"))
+		return
+	}
+
+	reply := a.send(context, &SendDirectMessageMsg{FromID: msg.UserID, ToID: message.FromID, Content: msg.Content, InReplyTo: &message.ID})
+	if !message.IsRead {
+		a.noteRead(context, message)
+	}
+	context.Respond(reply)
+}
+
+// markDelivered records the first fetch of a message by its recipient as
+// its delivery, and tells the sender.
+func (a *DirectMessageActor) markDelivered(context actor.Context, message *models.DirectMessage, fetchedBy uuid.UUID) {
+	if message.ToID != fetchedBy || message.DeliveredAt != nil {
+		return
+	}
+	deliveredAt := time.Now()
+	message.DeliveredAt = &deliveredAt
+	message.IsDelivered = true
+
+	isDelivered := true
+	a.enqueue(context, jobs.TypeUpdateMessageStatus, jobs.MessageStatusPayload{MessageID: message.ID, IsDelivered: &isDelivered})
+
+	a.notifySender(context, message.FromID, MessageStatusUpdate{
+		Type:        "messageDelivered",
+		MessageID:   message.ID,
+		DeliveredAt: &deliveredAt,
+	})
+}
+
+func (a *DirectMessageActor) handleGetUnreadCount(context actor.Context, msg *GetUnreadCountMsg) {
+	count, err := a.unreadCount(context, msg.UserID)
+	if err != nil {
+		context.Respond(err)
+		return
+	}
+	context.Respond(count)
+}
+
+// unreadCount returns a user's unread message count, loading it on first
+// use. From then on it's kept here, since this actor makes every change to
+// it; saves and reads it has queued but not yet written can't skew it.
+func (a *DirectMessageActor) unreadCount(context actor.Context, userID uuid.UUID) (int, error) {
+	if count, ok := a.unread[userID]; ok {
+		return count, nil
+	}
+	ctx, cancel := stdctx.WithTimeout(logging.Context(context), 5*time.Second)
+	defer cancel()
+	count, err := a.db.GetUnreadMessageCount(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	a.unread[userID] = count
+	return count, nil
+}
+
+// adjustUnread changes a user's unread count by delta and pushes the new
+// count to them if they're connected.
+func (a *DirectMessageActor) adjustUnread(context actor.Context, userID uuid.UUID, delta int) {
+	ctx := logging.Context(context)
+	count, err := a.unreadCount(context, userID)
+	if err != nil {
+		context.Logger().ErrorContext(ctx, "Failed to load unread count", "user_id", userID, "error", err)
+		return
+	}
+	count = max(0, count+delta)
+	a.unread[userID] = count
+
+	payload, err := json.Marshal(UnreadCountUpdate{Type: "unreadCount", Count: count})
+	if err != nil {
+		context.Logger().ErrorContext(ctx, "Failed to marshal unread count for WebSocket push", "error", err)
+		return
+	}
+	a.hub.SendToConnected([]uuid.UUID{userID}, payload)
+}
+
+// notifySender pushes a status update to a message's sender in the
+// background. The actor context mustn't be used once the handler returns, so
+// the logger and request ID are taken from it first.
+func (a *DirectMessageActor) notifySender(context actor.Context, senderID uuid.UUID, update MessageStatusUpdate) {
+	logger, ctx := context.Logger(), logging.Context(context)
+	go func() {
+		payloadBytes, err := json.Marshal(update)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to marshal status update for WebSocket push", "type", update.Type, "error", err)
+			return
+		}
+		a.hub.SendDirectMessage(senderID, payloadBytes)
+		logger.DebugContext(ctx, "Status update pushed to sender", "type", update.Type, "message_id", update.MessageID, "sender_id", senderID)
+	}()
+}
+
 func (a *DirectMessageActor) handleDeleteMessage(context actor.Context, msg *DeleteMessageMsg) {
 	if message, exists := a.messages[msg.MessageID]; exists {
-		if message.FromID == msg.UserID || message.ToID == msg.UserID {
-			message.IsDeleted = true
-
-			// Update DB in the background
-			go func() {
-				ctx := stdctx.Background()
-				isDeleted := true
-				if err := a.db.UpdateMessageStatus(ctx, msg.MessageID, nil, &isDeleted); err != nil {
-					log.Printf("Failed to update message deleted status in DB: %v", err)
-				}
-			}()
-
-			context.Respond(true)
+		if message.FromID != msg.UserID && message.ToID != msg.UserID {
+			context.Respond(notAuthorized("delete this message"))
 			return
 		}
+		message.IsDeleted = true
+
+		// Update DB via the job queue
+		isDeleted := true
+		a.enqueue(context, jobs.TypeUpdateMessageStatus, jobs.MessageStatusPayload{MessageID: msg.MessageID, IsDeleted: &isDeleted})
+
+		context.Respond(true)
+		return
 	}
 	context.Respond(false)
 }
@@ -259,5 +425,9 @@ func (a *DirectMessageActor) Receive(context actor.Context) {
 		a.handleMarkMessageRead(context, msg)
 	case *DeleteMessageMsg:
 		a.handleDeleteMessage(context, msg)
+	case *ReplyToMessageMsg:
+		a.handleReplyToMessage(context, msg)
+	case *GetUnreadCountMsg:
+		a.handleGetUnreadCount(context, msg)
 	}
 }
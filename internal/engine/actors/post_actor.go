@@ -2,10 +2,19 @@ package actors
 
 import (
 	stdctx "context"
+	"encoding/json"
+	"fmt"
+	"gator-swamp/internal/actorcall"
 	"gator-swamp/internal/database"
+	"gator-swamp/internal/events"
+	"gator-swamp/internal/language"
+	"gator-swamp/internal/logging"
 	"gator-swamp/internal/models"
+	"gator-swamp/internal/policy"
+	"gator-swamp/internal/ranking"
 	"gator-swamp/internal/utils"
-	"log"
+	"gator-swamp/internal/websocket"
+	"sort"
 	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
@@ -20,6 +29,23 @@ type (
 		Content     string
 		AuthorID    uuid.UUID
 		SubredditID uuid.UUID
+		URL         string     // Optional link or image URL
+		FlairID     *uuid.UUID // Optional, one of the subreddit's flairs
+		MediaID     *uuid.UUID // Optional, an image the author uploaded
+		Metadata    models.PostMetadata
+
+		// Set by the Engine when the subreddit's settings hold the author's
+		// posts for a moderator's approval
+		HoldForApproval bool
+	}
+
+	// ReviewPostMsg approves or rejects a post held for approval. The sender
+	// checks that the reviewer moderates the subreddit.
+	ReviewPostMsg struct {
+		SubredditID uuid.UUID
+		PostID      uuid.UUID
+		Approve     bool
+		Reason      string // Optional, passed on to the author
 	}
 
 	GetPostMsg struct {
@@ -27,8 +53,14 @@ type (
 		RequestingUserID uuid.UUID
 	}
 
+	// Post listings respond with up to Limit+1 posts; the extra one only
+	// tells the caller that another page exists.
 	GetSubredditPostsMsg struct {
-		SubredditID uuid.UUID
+		SubredditID      uuid.UUID
+		FlairID          *uuid.UUID // Only posts with this flair, if set
+		Limit            int
+		After            *models.Keyset // Continue after this post; nil for the newest
+		RequestingUserID uuid.UUID      // User making the request (for vote status)
 	}
 
 	VotePostMsg struct {
@@ -39,15 +71,54 @@ type (
 	}
 
 	GetUserFeedMsg struct {
-		UserID           uuid.UUID `json:"userId"` // User whose feed is being requested
-		Limit            int       `json:"limit"`
-		Offset           int       `json:"offset"`
-		RequestingUserID uuid.UUID `json:"requestingUserId"` // User making the request (for vote status)
+		UserID           uuid.UUID      `json:"userId"` // User whose feed is being requested
+		Limit            int            `json:"limit"`
+		Offset           int            `json:"offset"`
+		After            *models.Keyset `json:"after,omitempty"`  // Newest first feeds continue after this post rather than at Offset
+		RequestingUserID uuid.UUID      `json:"requestingUserId"` // User making the request (for vote status)
+		HideSeen         bool           `json:"hideSeen"`         // Skip posts the user has already been served
+		Sort             string         `json:"sort"`             // One of models.PostSorts; models.SortNew by default
+		Ranking          string         `json:"ranking"`          // One of ranking.Experiments, which replaces Sort; empty for Sort
+	}
+
+	// GetPostWithCommentsMsg requests a post plus the first page of its comments
+	GetPostWithCommentsMsg struct {
+		PostID           uuid.UUID
+		RequestingUserID uuid.UUID
+		CommentLimit     int
+		CommentSort      string // "top", "new" or "old"
 	}
 
 	DeletePostMsg struct {
 		PostID uuid.UUID
 		UserID uuid.UUID
+		Force  bool // Admin or moderator removal: skip the author check
+	}
+
+	// UpdatePostMetadataMsg replaces a post's metadata. Only its author may
+	// send it.
+	UpdatePostMetadataMsg struct {
+		PostID   uuid.UUID
+		UserID   uuid.UUID
+		Metadata models.PostMetadata
+	}
+
+	// EditPostMsg replaces a post's title and content. Only its author may
+	// send it.
+	EditPostMsg struct {
+		PostID  uuid.UUID
+		UserID  uuid.UUID
+		Title   string
+		Content string
+	}
+
+	// LockPostMsg closes (or reopens) a post to new comments. Only its
+	// author may send it, unless ByModerator is set.
+	LockPostMsg struct {
+		PostID      uuid.UUID
+		UserID      uuid.UUID
+		Locked      bool
+		ByModerator bool // Moderator lock, which the author can't undo; callers must check the requester moderates the post's subreddit
 	}
 
 	// Internal messages for actor initialization and metrics
@@ -56,24 +127,51 @@ type (
 	loadPostsFromDBMsg     struct{}
 
 	GetRecentPostsMsg struct {
-		Limit            int       `json:"limit"`
-		Offset           int       `json:"offset"`
-		RequestingUserID uuid.UUID `json:"requestingUserId"`
+		Limit            int            `json:"limit"`
+		Offset           int            `json:"offset"`
+		After            *models.Keyset `json:"after,omitempty"` // Newest first listings continue after this post rather than at Offset
+		RequestingUserID uuid.UUID      `json:"requestingUserId"`
+		Sort             string         `json:"sort"` // One of models.PostSorts; models.SortNew by default
 	}
 )
 
+// PostStore is the data access PostActor needs.
+type PostStore interface {
+	database.PostRepository
+	database.VoteRepository
+	database.ContentRepository
+	database.UserRepository
+	database.SubredditRepository
+	database.MediaRepository
+}
+
 // PostActor manages posts and related operations.
 type PostActor struct {
 	postsByID       map[uuid.UUID]*models.Post // Cache for posts by their ID
 	subredditPosts  map[uuid.UUID][]uuid.UUID  // Mapping of subreddit IDs to their posts
 	metrics         *utils.MetricsCollector    // Metrics for performance tracking
 	enginePID       *actor.PID                 // Reference to the Engine actor
-	db              database.DBAdapter         // Database adapter interface
+	db              PostStore                  // Database access
 	commentActorPID *actor.PID                 // PID of the CommentActor for interaction
+	hub             *websocket.Hub             // WebSocket hub for review notices (may be nil)
+	events          *events.Bus                // Domain event stream (may be nil)
+	actorCalls      *actorcall.Policies        // Timeouts and retries of requests to other actors
+}
+
+// PostReviewedEvent is pushed over WebSocket to the author of a post held
+// for approval once a moderator approves or rejects it.
+type PostReviewedEvent struct {
+	Type          string    `json:"type"` // Always "postReviewed"
+	PostID        uuid.UUID `json:"postId"`
+	SubredditID   uuid.UUID `json:"subredditId"`
+	SubredditName string    `json:"subredditName"`
+	Title         string    `json:"title"`
+	Approved      bool      `json:"approved"`
+	Reason        string    `json:"reason,omitempty"`
 }
 
 // NewPostActor creates a new PostActor instance
-func NewPostActor(metrics *utils.MetricsCollector, enginePID *actor.PID, db database.DBAdapter, commentActorPID *actor.PID) actor.Actor {
+func NewPostActor(metrics *utils.MetricsCollector, enginePID *actor.PID, db PostStore, commentActorPID *actor.PID, hub *websocket.Hub, bus *events.Bus, actorCalls *actorcall.Policies) actor.Actor {
 	return &PostActor{
 		postsByID:       make(map[uuid.UUID]*models.Post),
 		subredditPosts:  make(map[uuid.UUID][]uuid.UUID),
@@ -81,6 +179,9 @@ func NewPostActor(metrics *utils.MetricsCollector, enginePID *actor.PID, db data
 		enginePID:       enginePID,
 		db:              db,
 		commentActorPID: commentActorPID,
+		hub:             hub,
+		events:          bus,
+		actorCalls:      actorCalls,
 	}
 }
 
@@ -88,7 +189,7 @@ func NewPostActor(metrics *utils.MetricsCollector, enginePID *actor.PID, db data
 func (a *PostActor) Receive(context actor.Context) {
 	switch msg := context.Message().(type) {
 	case *actor.Started:
-		log.Printf("PostActor started")
+		context.Logger().Info("PostActor started")
 		context.Send(context.Self(), &initializePostActorMsg{}) // Start initialization
 
 	case *initializePostActorMsg:
@@ -103,6 +204,9 @@ func (a *PostActor) Receive(context actor.Context) {
 	case *GetPostMsg:
 		a.handleGetPost(context, msg)
 
+	case *GetPostWithCommentsMsg:
+		a.handleGetPostWithComments(context, msg)
+
 	case *GetSubredditPostsMsg:
 		a.handleGetSubredditPosts(context, msg)
 
@@ -114,20 +218,38 @@ func (a *PostActor) Receive(context actor.Context) {
 	case *GetRecentPostsMsg:
 		a.handleGetRecentPosts(context, msg)
 
+	case *DeletePostMsg:
+		a.handleDeletePost(context, msg)
+
+	case *EditPostMsg:
+		a.handleEditPost(context, msg)
+
+	case *LockPostMsg:
+		a.handleLockPost(context, msg)
+
+	case *UpdatePostMetadataMsg:
+		a.handleUpdatePostMetadata(context, msg)
+
+	case *ReviewPostMsg:
+		a.handleReviewPost(context, msg)
+
+	case *ExternalChangeMsg:
+		a.handleExternalChange(context, msg.Change)
+
 	default:
-		log.Printf("PostActor: Unknown message type: %T", msg)
+		context.Logger().Warn("PostActor: unknown message type", "type", fmt.Sprintf("%T", msg))
 	}
 }
 
 // Handles loading all posts from DB into memory during initialization
 func (a *PostActor) handleLoadPosts(context actor.Context) {
-	log.Println("PostActor: Loading initial posts from database...")
-	ctx := stdctx.Background()
+	context.Logger().Info("PostActor: loading posts from the database")
+	ctx := logging.Context(context)
 
 	posts, err := a.db.GetAllPosts(ctx)
 	if err != nil {
-		log.Printf("PostActor: CRITICAL - Failed to load initial posts: %v", err)
-		// Consider how to handle this - retry? panic? For now, log and continue with empty cache.
+		context.Logger().Error("PostActor: failed to load posts", "retry_in", loadRetryDelay, "error", err)
+		retryLoad(context, &loadPostsFromDBMsg{})
 		return
 	}
 
@@ -136,7 +258,7 @@ func (a *PostActor) handleLoadPosts(context actor.Context) {
 		// Populate derived fields (essential for cache consistency if used directly)
 		// We need the actor context for getCommentCount
 		if err := a.populatePostDetails(ctx, context, post); err != nil {
-			log.Printf("PostActor: Warning - Failed to populate details for post %s during initial load: %v", post.ID, err)
+			context.Logger().Warn("PostActor: failed to populate post details", "post_id", post.ID, "error", err)
 			// Continue caching the post even if details are incomplete
 		}
 
@@ -148,13 +270,14 @@ func (a *PostActor) handleLoadPosts(context actor.Context) {
 		loadedCount++
 	}
 
-	log.Printf("PostActor: Finished loading %d posts into cache.", loadedCount)
+	context.Logger().Info("PostActor: loaded posts", "count", loadedCount)
+	context.Send(a.enginePID, &ReadyMsg{Actor: PostActorName})
 }
 
 // Handles creating a new post
 func (a *PostActor) handleCreatePost(context actor.Context, msg *CreatePostMsg) {
 	startTime := time.Now()
-	ctx := stdctx.Background()
+	ctx := logging.Context(context)
 
 	// Fetch the user to get their username
 	user, err := a.db.GetUser(ctx, msg.AuthorID)
@@ -163,6 +286,13 @@ func (a *PostActor) handleCreatePost(context actor.Context, msg *CreatePostMsg)
 		return
 	}
 
+	if user.IsBot {
+		if err := policy.CheckBot(ctx, a.db, policy.OpCreatePost, user.ID, msg.SubredditID); err != nil {
+			context.Respond(err)
+			return
+		}
+	}
+
 	// Fetch the subreddit to get its name
 	subreddit, err := a.db.GetSubredditByID(ctx, msg.SubredditID)
 	if err != nil {
@@ -176,69 +306,208 @@ func (a *PostActor) handleCreatePost(context actor.Context, msg *CreatePostMsg)
 		Content:        msg.Content,
 		AuthorID:       msg.AuthorID,
 		AuthorUsername: user.Username, // Populated from fetched user
+		AuthorIsBot:    user.IsBot,
 		SubredditID:    msg.SubredditID,
 		SubredditName:  subreddit.Name, // Populated from fetched subreddit
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(), // Initialize UpdatedAt
 		Karma:          1,          // Start with 1 karma (initial upvote from author?)
 		CommentCount:   0,
+		PostMetadata:   msg.Metadata,
+		Language:       detectLanguage(msg.Title, msg.Content),
 		// UserVotes field removed
 	}
+	if msg.URL != "" {
+		newPost.URL = &msg.URL
+	}
+	if msg.FlairID != nil {
+		flair, err := a.db.GetFlair(ctx, *msg.FlairID)
+		if err != nil && !utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(err)
+			return
+		}
+		if flair == nil || flair.SubredditID != msg.SubredditID {
+			context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Flair is not one of this subreddit's", nil))
+			return
+		}
+		newPost.FlairID, newPost.FlairText, newPost.FlairColor = &flair.ID, &flair.Text, flair.Color
+	}
+	if msg.MediaID != nil {
+		media, err := a.db.GetMedia(ctx, *msg.MediaID)
+		if err != nil && !utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(err)
+			return
+		}
+		// Others' uploads are treated as missing, so IDs can't be probed
+		if media == nil || media.OwnerID != msg.AuthorID {
+			context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Media not found", nil))
+			return
+		}
+		newPost.MediaID, newPost.MediaKey = &media.ID, &media.Key
+	}
+
+	if msg.HoldForApproval {
+		if err := a.db.HoldPost(ctx, newPost); err != nil {
+			context.Logger().ErrorContext(ctx, "Failed to hold post for approval", "post_id", newPost.ID, "error", err)
+			context.Respond(err)
+			return
+		}
+		newPost.Pending = true
+		context.Respond(newPost)
+		return
+	}
 
-	if err := a.db.SavePost(ctx, newPost); err != nil {
+	if err := a.addPost(ctx, context, newPost); err != nil {
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to save post", err))
 		return
 	}
 
+	a.metrics.AddOperationLatency("create_post", time.Since(startTime))
+	context.Respond(newPost)
+}
+
+// addPost saves a new post, adds it to the caches and announces it.
+func (a *PostActor) addPost(ctx stdctx.Context, context actor.Context, post *models.Post) error {
+	if err := a.db.SavePost(ctx, post); err != nil {
+		return err
+	}
+
 	// TODO: Consider if author should automatically upvote their own post via RecordVote?
 	// For now, just save the post with karma 1.
 
 	// Update local caches
-	a.postsByID[newPost.ID] = newPost
-	// a.postVotes[newPost.ID] = make(map[uuid.UUID]voteStatus) // REMOVED
-	a.subredditPosts[msg.SubredditID] = append(a.subredditPosts[msg.SubredditID], newPost.ID)
+	a.postsByID[post.ID] = post
+	a.subredditPosts[post.SubredditID] = append(a.subredditPosts[post.SubredditID], post.ID)
+	context.Send(a.enginePID, &PostCountMsg{SubredditID: post.SubredditID, Delta: 1})
 
-	a.metrics.AddOperationLatency("create_post", time.Since(startTime))
-	context.Respond(newPost)
+	a.events.Publish(events.TypePostCreated, postCreated(post))
+	return nil
+}
+
+// postCreated is the TypePostCreated payload for a post.
+func postCreated(post *models.Post) events.PostCreated {
+	return events.PostCreated{
+		PostID:         post.ID,
+		SubredditID:    post.SubredditID,
+		SubredditName:  post.SubredditName,
+		AuthorID:       post.AuthorID,
+		AuthorUsername: post.AuthorUsername,
+		AuthorIsBot:    post.AuthorIsBot,
+		Title:          post.Title,
+		CreatedAt:      post.CreatedAt,
+	}
+}
+
+// handleReviewPost approves or rejects a post held for approval and tells
+// its author. Approved posts are saved as if just posted.
+func (a *PostActor) handleReviewPost(context actor.Context, msg *ReviewPostMsg) {
+	ctx := logging.Context(context)
+
+	post, err := a.db.TakePendingPost(ctx, msg.SubredditID, msg.PostID)
+	if err != nil {
+		context.Respond(err)
+		return
+	}
+	if !msg.Approve {
+		a.notifyReviewed(context, post, false, msg.Reason)
+		context.Respond(&models.StatusResponse{Success: true, Message: "Post rejected"})
+		return
+	}
+
+	post.Karma = 1
+	if err := a.addPost(ctx, context, post); err != nil {
+		context.Logger().ErrorContext(ctx, "Failed to save approved post", "post_id", post.ID, "error", err)
+		// Put it back so it can be reviewed again
+		if holdErr := a.db.HoldPost(ctx, post); holdErr != nil {
+			context.Logger().ErrorContext(ctx, "Failed to return post to the approval queue", "post_id", post.ID, "error", holdErr)
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to save post", err))
+		return
+	}
+	a.notifyReviewed(context, post, true, msg.Reason)
+	context.Respond(post)
+}
+
+// notifyReviewed pushes a PostReviewedEvent to the post's author if they're
+// online.
+func (a *PostActor) notifyReviewed(context actor.Context, post *models.Post, approved bool, reason string) {
+	if a.hub == nil {
+		return
+	}
+	payload, err := json.Marshal(PostReviewedEvent{
+		Type:          "postReviewed",
+		PostID:        post.ID,
+		SubredditID:   post.SubredditID,
+		SubredditName: post.SubredditName,
+		Title:         post.Title,
+		Approved:      approved,
+		Reason:        reason,
+	})
+	if err != nil {
+		context.Logger().ErrorContext(logging.Context(context), "Failed to marshal review event", "post_id", post.ID, "error", err)
+		return
+	}
+	a.hub.SendToConnected([]uuid.UUID{post.AuthorID}, payload)
 }
 
 // Handles retrieving a specific post by ID
 func (a *PostActor) handleGetPost(context actor.Context, msg *GetPostMsg) {
+	post, appErr := a.getPost(context, msg.PostID, msg.RequestingUserID)
+	if appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	a.markSeen(context, msg.RequestingUserID, post.ID)
+	context.Respond(post)
+}
+
+// markSeen records posts as seen by the user so hide_seen feeds can skip them.
+// Failures are logged only; seen tracking must never fail a read.
+func (a *PostActor) markSeen(context actor.Context, userID uuid.UUID, postIDs ...uuid.UUID) {
+	if userID == uuid.Nil || len(postIDs) == 0 {
+		return
+	}
+	dbCtx, cancel := stdctx.WithTimeout(logging.Context(context), 5*time.Second)
+	defer cancel()
+	if err := a.db.MarkPostsSeen(dbCtx, userID, postIDs); err != nil {
+		context.Logger().ErrorContext(dbCtx, "Failed to mark posts seen", "user_id", userID, "count", len(postIDs), "error", err)
+	}
+}
+
+// getPost returns a post from cache or the database, populated with derived fields.
+func (a *PostActor) getPost(context actor.Context, postID, requestingUserID uuid.UUID) (*models.Post, *utils.AppError) {
+	ctx := logging.Context(context)
 	// Prefer cache, but fallback to DB
 	// NOTE: Cache does not currently store user-specific vote status.
 	// If cache hits, the CurrentUserVote will be nil. A DB refetch is needed for this.
 	// Consider invalidating cache more aggressively or enhancing cache structure.
-	if post, exists := a.postsByID[msg.PostID]; exists {
+	if post, exists := a.postsByID[postID]; exists {
 		// Temporarily, we will still fetch from DB if requesting user is provided
 		// to get their vote status, even if the post is cached.
 		// A better approach would be to store vote status separately or enhance the post cache.
-		if msg.RequestingUserID != uuid.Nil {
+		if requestingUserID != uuid.Nil {
 			// Fall through to DB fetch to get user-specific vote status
 		} else {
 			// Populate derived fields for cached post (without user vote)
-			if err := a.populatePostDetails(stdctx.Background(), context, post); err != nil {
-				log.Printf("Error populating cached post %s details: %v", msg.PostID, err)
+			if err := a.populatePostDetails(ctx, context, post); err != nil {
+				context.Logger().WarnContext(ctx, "Failed to populate cached post details", "post_id", postID, "error", err)
 			}
-			context.Respond(post) // Respond with cached post (no user vote info)
-			return
+			return post, nil // Cached post (no user vote info)
 		}
 	}
 
-	ctx := stdctx.Background()
 	// Modified DB call to include requesting user ID
-	post, err := a.db.GetPost(ctx, msg.PostID, msg.RequestingUserID)
+	post, err := a.db.GetPost(ctx, postID, requestingUserID)
 	if err != nil {
 		if appErr, ok := err.(*utils.AppError); ok && appErr.Code == utils.ErrNotFound {
-			context.Respond(utils.NewAppError(utils.ErrNotFound, "Post not found", nil))
-		} else {
-			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch post", err))
+			return nil, utils.NewAppError(utils.ErrNotFound, "Post not found", nil)
 		}
-		return
+		return nil, utils.NewAppError(utils.ErrDatabase, "Failed to fetch post", err)
 	}
 
 	// Populate derived fields for DB-fetched post
 	if err := a.populatePostDetails(ctx, context, post); err != nil {
-		log.Printf("Error populating fetched post %s details: %v", msg.PostID, err)
+		context.Logger().WarnContext(ctx, "Failed to populate post details", "post_id", postID, "error", err)
 		// Respond with post data anyway, but maybe log error
 	}
 
@@ -260,21 +529,78 @@ func (a *PostActor) handleGetPost(context actor.Context, msg *GetPostMsg) {
 		a.subredditPosts[post.SubredditID] = append(a.subredditPosts[post.SubredditID], post.ID)
 	}
 
-	context.Respond(post)
+	return post, nil
+}
+
+// Handles retrieving a post and its first page of comments, fanning out to the CommentActor
+func (a *PostActor) handleGetPostWithComments(context actor.Context, msg *GetPostWithCommentsMsg) {
+	startTime := time.Now()
+
+	post, appErr := a.getPost(context, msg.PostID, msg.RequestingUserID)
+	if appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	a.markSeen(context, msg.RequestingUserID, post.ID)
+
+	result, err := a.actorCalls.Request(context, a.commentActorPID, &GetCommentsForPostMsg{
+		PostID:           msg.PostID,
+		RequestingUserID: msg.RequestingUserID,
+	})
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrActorTimeout, "failed to fetch comments", err))
+		return
+	}
+	if appErr, ok := result.(*utils.AppError); ok {
+		context.Respond(appErr)
+		return
+	}
+	comments, ok := result.([]*models.Comment)
+	if !ok {
+		context.Respond(utils.NewAppError(utils.ErrMessageRejected, "unexpected comments response", nil))
+		return
+	}
+
+	sortComments(comments, msg.CommentSort)
+
+	total := len(comments)
+	if msg.CommentLimit > 0 && len(comments) > msg.CommentLimit {
+		comments = comments[:msg.CommentLimit]
+	}
+
+	a.metrics.AddOperationLatency("get_post_with_comments", time.Since(startTime))
+	context.Respond(&models.PostWithComments{
+		Post:          post,
+		Comments:      comments,
+		TotalComments: total,
+		HasMore:       len(comments) < total,
+	})
+}
+
+// sortComments orders comments in place. Comments arrive oldest first from the database.
+func sortComments(comments []*models.Comment, order string) {
+	switch order {
+	case "new":
+		sort.SliceStable(comments, func(i, j int) bool {
+			return comments[i].CreatedAt.After(comments[j].CreatedAt)
+		})
+	case "old":
+		// Already oldest first
+	default: // "top"
+		sort.SliceStable(comments, func(i, j int) bool {
+			return comments[i].Karma > comments[j].Karma
+		})
+	}
 }
 
 // Handles retrieving posts for a specific subreddit
 func (a *PostActor) handleGetSubredditPosts(context actor.Context, msg *GetSubredditPostsMsg) {
-	log.Printf("Getting posts for subreddit %s", msg.SubredditID)
-	ctx := stdctx.Background()
-
-	// Need to define defaults or add pagination to msg
-	defaultLimit := 50 // Example limit
-	defaultOffset := 0 // Example offset
+	ctx := logging.Context(context)
 
-	posts, err := a.db.GetPostsBySubreddit(ctx, msg.SubredditID, defaultLimit, defaultOffset)
+	posts, err := a.db.GetPostsBySubreddit(ctx, msg.SubredditID, msg.FlairID, msg.Limit+1, msg.After, msg.RequestingUserID)
 	if err != nil {
-		log.Printf("Error fetching posts for subreddit %s from DB: %v", msg.SubredditID, err)
+		context.Logger().ErrorContext(ctx, "Failed to fetch subreddit posts", "subreddit_id", msg.SubredditID, "error", err)
 		// Use NewAppError for consistency
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "failed to fetch subreddit posts", err))
 		return
@@ -283,7 +609,7 @@ func (a *PostActor) handleGetSubredditPosts(context actor.Context, msg *GetSubre
 	// Populate derived fields for each post
 	for _, post := range posts {
 		if err := a.populatePostDetails(ctx, context, post); err != nil {
-			log.Printf("Error populating details for post %s in subreddit %s feed: %v", post.ID, msg.SubredditID, err)
+			context.Logger().WarnContext(ctx, "Failed to populate post details", "post_id", post.ID, "error", err)
 			// Continue with potentially incomplete post data
 		}
 	}
@@ -291,10 +617,214 @@ func (a *PostActor) handleGetSubredditPosts(context actor.Context, msg *GetSubre
 	context.Respond(posts)
 }
 
-// Handles voting on a post using the DBAdapter
+// handleDeletePost soft-deletes a post along with its comments. Only the
+// author may delete unless the message is an admin removal.
+func (a *PostActor) handleDeletePost(context actor.Context, msg *DeletePostMsg) {
+	ctx := logging.Context(context)
+
+	post, err := a.db.GetPost(ctx, msg.PostID, uuid.Nil)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewAppError(utils.ErrNotFound, "Post not found", nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch post for deletion", err))
+		return
+	}
+	if post.AuthorID != msg.UserID && !msg.Force {
+		context.Respond(notAuthorized("delete this post"))
+		return
+	}
+
+	// Authors delete for good; moderator and admin removals stay
+	// restorable, comments and votes included
+	if msg.Force {
+		err = a.db.SoftDelete(ctx, models.ContentPost, msg.PostID)
+	} else {
+		err = a.db.DeletePost(ctx, msg.PostID)
+	}
+	if err != nil {
+		context.Logger().ErrorContext(ctx, "Failed to delete post", "post_id", msg.PostID, "error", err)
+		context.Respond(err)
+		return
+	}
+
+	a.evictPost(msg.PostID, post.SubredditID)
+	context.Send(a.enginePID, &PostCountMsg{SubredditID: post.SubredditID, Delta: -1})
+	if a.commentActorPID != nil {
+		context.Send(a.commentActorPID, &postDeletedMsg{PostID: msg.PostID})
+	}
+	a.events.Publish(events.TypePostDeleted, events.PostDeleted{PostID: msg.PostID, SubredditID: post.SubredditID, Removed: msg.Force})
+
+	context.Logger().InfoContext(ctx, "Post deleted", "post_id", msg.PostID, "user_id", msg.UserID)
+	context.Respond(&models.StatusResponse{Success: true, Message: "Post deleted successfully"})
+}
+
+func (a *PostActor) handleUpdatePostMetadata(context actor.Context, msg *UpdatePostMetadataMsg) {
+	ctx := logging.Context(context)
+
+	post, err := a.db.GetPost(ctx, msg.PostID, uuid.Nil)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewAppError(utils.ErrNotFound, "Post not found", nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch post", err))
+		return
+	}
+	if post.AuthorID != msg.UserID {
+		context.Respond(notAuthorized("edit this post"))
+		return
+	}
+
+	if err := a.db.UpdatePostMetadata(ctx, msg.PostID, &msg.Metadata); err != nil {
+		context.Logger().ErrorContext(ctx, "Failed to update post metadata", "post_id", msg.PostID, "error", err)
+		context.Respond(err)
+		return
+	}
+
+	if cached, ok := a.postsByID[msg.PostID]; ok {
+		cached.PostMetadata = msg.Metadata
+	}
+	post.PostMetadata = msg.Metadata
+	context.Respond(post)
+}
+
+func (a *PostActor) handleEditPost(context actor.Context, msg *EditPostMsg) {
+	ctx := logging.Context(context)
+
+	post, err := a.db.GetPost(ctx, msg.PostID, uuid.Nil)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewAppError(utils.ErrNotFound, "Post not found", nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch post", err))
+		return
+	}
+	if post.AuthorID != msg.UserID {
+		context.Respond(notAuthorized("edit this post"))
+		return
+	}
+
+	lang := detectLanguage(msg.Title, msg.Content)
+	editedAt, err := a.db.EditPost(ctx, msg.PostID, msg.Title, msg.Content, lang)
+	if err != nil {
+		context.Logger().ErrorContext(ctx, "Failed to edit post", "post_id", msg.PostID, "error", err)
+		context.Respond(err)
+		return
+	}
+
+	if cached, ok := a.postsByID[msg.PostID]; ok {
+		cached.Title, cached.Content, cached.Language = msg.Title, msg.Content, lang
+		cached.EditedAt, cached.UpdatedAt = &editedAt, editedAt
+	}
+	post.Title, post.Content, post.Language = msg.Title, msg.Content, lang
+	post.EditedAt, post.UpdatedAt = &editedAt, editedAt
+	a.events.Publish(events.TypePostEdited, events.PostEdited{PostID: post.ID, AuthorID: post.AuthorID, EditedAt: editedAt})
+	context.Respond(post)
+}
+
+// detectLanguage guesses the language of a post from its title and content,
+// returning nil when it can't tell.
+func detectLanguage(title, content string) *string {
+	lang := language.Detect(title + "\n" + content)
+	if lang == "" {
+		return nil
+	}
+	return &lang
+}
+
+func (a *PostActor) handleLockPost(context actor.Context, msg *LockPostMsg) {
+	ctx := logging.Context(context)
+
+	post, err := a.db.GetPost(ctx, msg.PostID, uuid.Nil)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewAppError(utils.ErrNotFound, "Post not found", nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch post", err))
+		return
+	}
+	if post.AuthorID != msg.UserID && !msg.ByModerator {
+		context.Respond(notAuthorized("lock this post"))
+		return
+	}
+
+	setLocked := a.db.SetPostLocked
+	if msg.ByModerator {
+		setLocked = a.db.SetPostLockedByModerator
+	}
+	if err := setLocked(ctx, msg.PostID, msg.Locked); err != nil {
+		context.Logger().ErrorContext(ctx, "Failed to lock post", "post_id", msg.PostID, "error", err)
+		context.Respond(err)
+		return
+	}
+
+	for _, p := range []*models.Post{a.postsByID[msg.PostID], post} {
+		if p == nil {
+			continue
+		}
+		if msg.ByModerator {
+			p.LockedByModerator = msg.Locked
+		} else {
+			p.LockedByAuthor = msg.Locked
+		}
+	}
+	context.Respond(post)
+}
+
+// evictPost drops a post from the caches.
+func (a *PostActor) evictPost(postID, subredditID uuid.UUID) {
+	delete(a.postsByID, postID)
+	ids := a.subredditPosts[subredditID]
+	for i, id := range ids {
+		if id == postID {
+			a.subredditPosts[subredditID] = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+}
+
+// handleExternalChange keeps the caches in step with posts written by other
+// instances, and relays new ones to this instance's event subscribers.
+func (a *PostActor) handleExternalChange(context actor.Context, change database.Change) {
+	switch {
+	case change.Op == database.ChangeResync:
+		a.postsByID = make(map[uuid.UUID]*models.Post)
+		a.subredditPosts = make(map[uuid.UUID][]uuid.UUID)
+		a.handleLoadPosts(context)
+
+	case change.Table != "posts":
+		return
+
+	case change.Op == "insert":
+		ctx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+		defer cancel()
+		post, err := a.db.GetPost(ctx, change.ID, uuid.Nil)
+		if err != nil {
+			context.Logger().Error("PostActor: failed to fetch post created elsewhere", "post_id", change.ID, "error", err)
+			return
+		}
+		if err := a.populatePostDetails(ctx, context, post); err != nil {
+			context.Logger().Warn("PostActor: failed to populate post created elsewhere", "post_id", post.ID, "error", err)
+		}
+		a.evictPost(post.ID, post.SubredditID)
+		a.postsByID[post.ID] = post
+		a.subredditPosts[post.SubredditID] = append(a.subredditPosts[post.SubredditID], post.ID)
+		a.events.Relay(events.TypePostCreated, postCreated(post))
+
+	default:
+		// Refetched from the database on next read
+		a.evictPost(change.ID, change.ParentID)
+	}
+}
+
+// Handles voting on a post using the store
 func (a *PostActor) handleVote(context actor.Context, msg *VotePostMsg) {
 	startTime := time.Now()
-	ctx := stdctx.Background()
+	ctx := logging.Context(context)
 
 	var direction models.VoteDirection
 	if msg.RemoveVote {
@@ -306,42 +836,99 @@ func (a *PostActor) handleVote(context actor.Context, msg *VotePostMsg) {
 	}
 
 	err := a.db.RecordVote(ctx, msg.UserID, msg.PostID, models.PostVote, direction)
+	if utils.IsErrorCode(err, utils.ErrForbidden) {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "This post is archived", nil))
+		return
+	}
 	if err != nil {
-		log.Printf("Error recording vote for post %s by user %s: %v", msg.PostID, msg.UserID, err)
+		context.Logger().ErrorContext(ctx, "Failed to record post vote", "post_id", msg.PostID, "user_id", msg.UserID, "error", err)
 		// Use NewAppError instead of WrapAppError
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "failed to process vote", err))
 		return
 	}
 
+	a.events.Publish(events.TypeVoteRecorded, events.VoteRecorded{
+		UserID:      msg.UserID,
+		ContentID:   msg.PostID,
+		ContentType: models.PostVote,
+		PostID:      msg.PostID,
+		Direction:   direction,
+	})
+
 	// Invalidate or update cache? For now, let's invalidate.
 	delete(a.postsByID, msg.PostID)
 
 	a.metrics.AddOperationLatency("vote_post", time.Since(startTime))
-	context.Respond(&struct{ Success bool }{Success: true}) // Simple success response
+	context.Respond(&models.StatusResponse{Success: true})
 }
 
 // Handles retrieving a personalized feed for a user
 func (a *PostActor) handleGetUserFeed(context actor.Context, msg *GetUserFeedMsg) {
-	log.Printf("Generating feed for user %s, limit %d, offset %d, requesting user %s", msg.UserID, msg.Limit, msg.Offset, msg.RequestingUserID)
-	ctx := stdctx.Background()
+	ctx := logging.Context(context)
 
-	posts, err := a.db.GetUserFeed(ctx, msg.UserID, msg.Limit, msg.Offset, msg.RequestingUserID)
+	var posts []*models.Post
+	var err error
+	if experiment, ok := ranking.Experiments[msg.Ranking]; ok {
+		posts, err = a.rankedFeed(ctx, msg, experiment)
+	} else {
+		posts, err = a.db.GetUserFeed(ctx, msg.UserID, msg.Limit+1, msg.Offset, msg.After, msg.RequestingUserID, msg.HideSeen, msg.Sort)
+	}
 	if err != nil {
-		log.Printf("Error fetching user feed for %s: %v", msg.UserID, err)
+		context.Logger().ErrorContext(ctx, "Failed to fetch user feed", "user_id", msg.UserID, "error", err)
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "failed to fetch user feed", err))
 		return
 	}
 
+	// Only the feed owner's own views count as seen, and not the lookahead post
+	if msg.UserID == msg.RequestingUserID {
+		served := make([]uuid.UUID, min(len(posts), msg.Limit))
+		for i := range served {
+			served[i] = posts[i].ID
+		}
+		a.markSeen(context, msg.UserID, served...)
+	}
+
 	context.Respond(posts)
 }
 
+// rankingPoolSize is how many of a feed's newest posts a ranking experiment
+// orders. Pages past it are empty.
+const rankingPoolSize = 200
+
+// rankedFeed orders the feed's newest posts by the experiment and returns the
+// page msg asks for, with one post of lookahead.
+func (a *PostActor) rankedFeed(ctx stdctx.Context, msg *GetUserFeedMsg, experiment ranking.Experiment) ([]*models.Post, error) {
+	pool, err := a.db.GetUserFeed(ctx, msg.UserID, rankingPoolSize, 0, nil, msg.RequestingUserID, msg.HideSeen, models.SortNew)
+	if err != nil {
+		return nil, err
+	}
+
+	signals := &ranking.Signals{Now: time.Now()}
+	if experiment.Uses(ranking.ScoreAffinity) {
+		var authorIDs []uuid.UUID
+		seen := make(map[uuid.UUID]bool)
+		for _, post := range pool {
+			if !seen[post.AuthorID] {
+				seen[post.AuthorID] = true
+				authorIDs = append(authorIDs, post.AuthorID)
+			}
+		}
+		if signals.Affinity, err = a.db.GetAuthorAffinity(ctx, msg.RequestingUserID, authorIDs); err != nil {
+			return nil, err
+		}
+	}
+	experiment.Rank(pool, signals)
+
+	start := min(msg.Offset, len(pool))
+	return pool[start:min(start+msg.Limit+1, len(pool))], nil
+}
+
 // Handles retrieving the most recent posts
 func (a *PostActor) handleGetRecentPosts(context actor.Context, msg *GetRecentPostsMsg) {
-	log.Printf("PostActor: Received GetRecentPostsMsg: Limit=%d, Offset=%d, RequestingUserID=%s", msg.Limit, msg.Offset, msg.RequestingUserID)
-	ctx := stdctx.Background()
-	posts, err := a.db.GetRecentPosts(ctx, msg.Limit, msg.Offset, msg.RequestingUserID)
+	ctx := logging.Context(context)
+	posts, err := a.db.GetRecentPosts(ctx, msg.Limit+1, msg.Offset, msg.After, msg.RequestingUserID, msg.Sort)
 	if err != nil {
-		log.Printf("PostActor: Error getting recent posts: %v", err)
+		context.Logger().ErrorContext(ctx, "Failed to fetch recent posts", "error", err)
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "failed to fetch recent posts", err))
 		return
 	}
@@ -356,24 +943,25 @@ func (a *PostActor) populatePostDetails(ctx stdctx.Context, context actor.Contex
 	author, err := a.db.GetUser(ctx, post.AuthorID)
 	if err != nil {
 		// Log error but don't fail entirely, maybe author was deleted
-		log.Printf("Warning: Failed to fetch author %s for post %s: %v", post.AuthorID, post.ID, err)
+		context.Logger().WarnContext(ctx, "Failed to fetch post author", "author_id", post.AuthorID, "post_id", post.ID, "error", err)
 		post.AuthorUsername = "[deleted]"
 	} else {
 		post.AuthorUsername = author.Username
+		post.AuthorIsBot = author.IsBot
 	}
 
 	// Fetch subreddit name
 	subreddit, err := a.db.GetSubredditByID(ctx, post.SubredditID)
 	if err != nil {
 		// Log error but don't fail entirely
-		log.Printf("Warning: Failed to fetch subreddit %s for post %s: %v", post.SubredditID, post.ID, err)
+		context.Logger().WarnContext(ctx, "Failed to fetch post subreddit", "subreddit_id", post.SubredditID, "post_id", post.ID, "error", err)
 		post.SubredditName = "[unknown]"
 	} else {
 		post.SubredditName = subreddit.Name
 	}
 
 	// Comment count is now sourced directly from the database query (e.g., in GetPost, GetRecentPosts)
-	// and should be up-to-date due to transactional updates in SaveComment and DeleteCommentAndDecrementCount.
+	// and should be up-to-date due to transactional updates in SaveComment and SoftDelete.
 	// Thus, no need to call a.getCommentCount(context, post.ID) here anymore.
 
 	// Note: Upvotes/Downvotes are not populated here as they aren't stored directly.
@@ -0,0 +1,31 @@
+package actors
+
+import (
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// Names the Engine's actors report themselves ready under
+const (
+	PostActorName      = "PostActor"
+	CommentActorName   = "CommentActor"
+	SubredditActorName = "SubredditActor"
+	UserSupervisorName = "UserSupervisor"
+)
+
+// loadRetryDelay is how long an actor waits to retry a failed initial load.
+const loadRetryDelay = 5 * time.Second
+
+// ReadyMsg tells the Engine an actor has started and loaded its initial
+// state, so requests to it won't find an empty cache.
+type ReadyMsg struct {
+	Actor string // One of the *Name constants
+}
+
+// retryLoad sends msg back to the actor after loadRetryDelay. The load stays
+// unacknowledged until it succeeds, so the engine doesn't serve without it.
+func retryLoad(context actor.Context, msg interface{}) {
+	root, self := context.ActorSystem().Root, context.Self()
+	time.AfterFunc(loadRetryDelay, func() { root.Send(self, msg) })
+}
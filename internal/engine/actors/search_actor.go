@@ -0,0 +1,195 @@
+package actors
+
+import (
+	stdctx "context"
+	"time"
+
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/events"
+	"gator-swamp/internal/logging"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/search"
+	"gator-swamp/internal/utils"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+// Message types for SearchActor
+type (
+	// SearchMsg finds posts, comments or subreddits (Type, one of
+	// models.SearchPosts, SearchComments and SearchSubreddits) matching
+	// Query. NSFW and quarantined subreddits ViewerID hasn't opted into are
+	// left out.
+	SearchMsg struct {
+		Query    string    `json:"query"`
+		Type     string    `json:"type"`
+		Sort     string    `json:"sort"` // models.SearchSortRelevance or SearchSortNew
+		Limit    int       `json:"limit"`
+		Offset   int       `json:"offset"`
+		ViewerID uuid.UUID `json:"viewerId"`
+	}
+
+	// IndexPostMsg adds or updates a post in an external search index
+	IndexPostMsg struct {
+		PostID uuid.UUID `json:"postId"`
+	}
+
+	// DropPostMsg removes a post from an external search index
+	DropPostMsg struct {
+		PostID uuid.UUID `json:"postId"`
+	}
+)
+
+// SearchResults is the reply to SearchMsg. Only the list for the message's
+// Type is set. More reports whether there are results past Limit.
+type SearchResults struct {
+	Posts      []*models.Post
+	Comments   []*models.Comment
+	Subreddits []*models.Subreddit
+	More       bool
+}
+
+// SearchActor runs searches through the configured provider and keeps an
+// external provider's index current. Comments and subreddits are always
+// searched in the database.
+type SearchActor struct {
+	provider search.Provider
+	db       database.Store
+}
+
+func NewSearchActor(provider search.Provider, db database.Store) actor.Actor {
+	return &SearchActor{provider: provider, db: db}
+}
+
+func (a *SearchActor) Receive(context actor.Context) {
+	switch msg := context.Message().(type) {
+	case *SearchMsg:
+		a.handleSearch(context, msg)
+	case *IndexPostMsg:
+		a.handleIndexPost(context, msg)
+	case *DropPostMsg:
+		a.handleDropPost(context, msg)
+	}
+}
+
+func (a *SearchActor) handleSearch(context actor.Context, msg *SearchMsg) {
+	ctx := logging.Context(context)
+	// One past the page tells whether there's another
+	limit := msg.Limit + 1
+
+	results := &SearchResults{}
+	var err error
+	switch msg.Type {
+	case models.SearchComments:
+		results.Comments, err = a.db.SearchComments(ctx, msg.Query, msg.Sort, limit, msg.Offset, msg.ViewerID)
+		if results.More = len(results.Comments) > msg.Limit; results.More {
+			results.Comments = results.Comments[:msg.Limit]
+		}
+	case models.SearchSubreddits:
+		results.Subreddits, err = a.db.SearchSubreddits(ctx, msg.Query, msg.Sort, limit, msg.Offset, msg.ViewerID)
+		if results.More = len(results.Subreddits) > msg.Limit; results.More {
+			results.Subreddits = results.Subreddits[:msg.Limit]
+		}
+	default:
+		results.Posts, results.More, err = a.searchPosts(ctx, msg)
+	}
+	if err != nil {
+		context.Logger().ErrorContext(ctx, "Search failed", "type", msg.Type, "query", msg.Query, "error", err)
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Search failed", err))
+		return
+	}
+	context.Respond(results)
+}
+
+// searchPosts asks the provider for a page of post IDs and reads the posts
+// back, so they're current. Posts deleted since they were indexed are
+// skipped. An external index doesn't know subreddit ratings, so they're
+// checked here against the viewer's preferences.
+func (a *SearchActor) searchPosts(ctx stdctx.Context, msg *SearchMsg) ([]*models.Post, bool, error) {
+	ids, err := a.provider.Search(ctx, msg.Query, msg.Sort, msg.Limit+1, msg.Offset, msg.ViewerID)
+	if err != nil {
+		return nil, false, err
+	}
+	// Whether there's another page depends on the provider's results, not
+	// on how many of them are left below
+	more := len(ids) > msg.Limit
+	if more {
+		ids = ids[:msg.Limit]
+	}
+
+	prefs, err := a.db.GetUserPreferences(ctx, msg.ViewerID)
+	if err != nil {
+		return nil, false, err
+	}
+	shown := make(map[uuid.UUID]bool)
+
+	posts := make([]*models.Post, 0, len(ids))
+	for _, id := range ids {
+		post, err := a.db.GetPost(ctx, id, msg.ViewerID)
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		show, ok := shown[post.SubredditID]
+		if !ok {
+			sub, err := a.db.GetSubredditByID(ctx, post.SubredditID)
+			if err != nil && !utils.IsErrorCode(err, utils.ErrNotFound) {
+				return nil, false, err
+			}
+			show = err == nil && prefs.ShowsSubreddit(sub)
+			shown[post.SubredditID] = show
+		}
+		if show {
+			posts = append(posts, post)
+		}
+	}
+	return posts, more, nil
+}
+
+func (a *SearchActor) handleIndexPost(context actor.Context, msg *IndexPostMsg) {
+	ctx, cancel := stdctx.WithTimeout(logging.Context(context), 10*time.Second)
+	defer cancel()
+
+	post, err := a.db.GetPost(ctx, msg.PostID, uuid.Nil)
+	if err != nil {
+		context.Logger().ErrorContext(ctx, "Search indexer failed to fetch post", "post_id", msg.PostID, "error", err)
+		return
+	}
+	if err := a.provider.Index(ctx, search.NewDocument(post)); err != nil {
+		context.Logger().ErrorContext(ctx, "Search indexer failed to index post", "post_id", post.ID, "error", err)
+	}
+}
+
+func (a *SearchActor) handleDropPost(context actor.Context, msg *DropPostMsg) {
+	ctx, cancel := stdctx.WithTimeout(logging.Context(context), 10*time.Second)
+	defer cancel()
+
+	if err := a.provider.Delete(ctx, msg.PostID); err != nil {
+		context.Logger().ErrorContext(ctx, "Search indexer failed to drop post", "post_id", msg.PostID, "error", err)
+	}
+}
+
+// SubscribeSearchIndexer keeps an external provider's index current by
+// sending the SearchActor each post created, edited or deleted on this
+// instance. Posts changed elsewhere are indexed by the instance they were
+// changed on. Posts restored by an admin, or hidden with their subreddit,
+// are caught up by search.Reindex; results are read back from the database
+// either way, so hidden posts never show.
+func SubscribeSearchIndexer(bus *events.Bus, root *actor.RootContext, searchPID *actor.PID) {
+	bus.Subscribe("search", func(e events.Event) {
+		if e.Remote {
+			return
+		}
+		switch payload := e.Payload.(type) {
+		case events.PostCreated:
+			root.Send(searchPID, &IndexPostMsg{PostID: payload.PostID})
+		case events.PostEdited:
+			root.Send(searchPID, &IndexPostMsg{PostID: payload.PostID})
+		case events.PostDeleted:
+			root.Send(searchPID, &DropPostMsg{PostID: payload.PostID})
+		}
+	}, events.TypePostCreated, events.TypePostEdited, events.TypePostDeleted)
+}
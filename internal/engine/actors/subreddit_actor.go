@@ -3,9 +3,9 @@ package actors
 import (
 	stdctx "context" // Import standard context package with alias to avoid confusion
 	"gator-swamp/internal/database"
+	"gator-swamp/internal/logging"
 	"gator-swamp/internal/models"
 	"gator-swamp/internal/utils"
-	"log"
 	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
@@ -43,25 +43,96 @@ type (
 	GetSubredditByNameMsg struct {
 		Name string
 	}
+
+	// DeleteSubredditMsg soft-deletes a subreddit, hiding it and its posts.
+	// Callers must check the requester is an admin.
+	DeleteSubredditMsg struct {
+		SubredditID uuid.UUID
+	}
+
+	// SetSubredditRatingMsg marks a subreddit NSFW or quarantined; nil flags
+	// are left as they are. Callers must check the requester may set them.
+	SetSubredditRatingMsg struct {
+		SubredditID uuid.UUID
+		NSFW        *bool
+		Quarantined *bool
+	}
+
+	// AddModeratorMsg appoints UserID to moderate a subreddit. The Engine
+	// checks ActorID owns the subreddit before forwarding it.
+	AddModeratorMsg struct {
+		SubredditID uuid.UUID
+		UserID      uuid.UUID
+		ActorID     uuid.UUID
+	}
+
+	// RemoveModeratorMsg dismisses a moderator. The Engine checks ActorID
+	// owns the subreddit, or is the moderator stepping down.
+	RemoveModeratorMsg struct {
+		SubredditID uuid.UUID
+		UserID      uuid.UUID
+		ActorID     uuid.UUID
+	}
+
+	// GetModeratorsMsg lists a subreddit's appointed moderators
+	GetModeratorsMsg struct {
+		SubredditID uuid.UUID
+	}
+
+	// PostCountMsg tells the SubredditActor a post was added to or deleted
+	// from a subreddit, so its cached post count keeps up. The PostActor
+	// sends it through the Engine.
+	PostCountMsg struct {
+		SubredditID uuid.UUID
+		Delta       int
+	}
+
+	// SubredditDetails answers GetSubredditByIDMsg and GetSubredditByNameMsg
+	SubredditDetails struct {
+		Subreddit     *models.Subreddit
+		MembersOnline int
+	}
 )
 
+const (
+	onlineWindow   = 5 * time.Minute  // Matches the presence staleness cutoff
+	onlineCacheTTL = 30 * time.Second // How long an online count is reused
+)
+
+// onlineCount is a cached "members online now" figure.
+type onlineCount struct {
+	count     int
+	fetchedAt time.Time
+}
+
+// SubredditStore is the data access SubredditActor needs.
+type SubredditStore interface {
+	database.SubredditRepository
+	database.UserRepository
+	database.ContentRepository
+}
+
 // SubredditActor handles all subreddit-related operations
 type SubredditActor struct {
 	subredditsByName map[string]*models.Subreddit
 	subredditsById   map[uuid.UUID]*models.Subreddit
 	subredditMembers map[uuid.UUID]map[uuid.UUID]bool
+	onlineCounts     map[uuid.UUID]onlineCount
 	metrics          *utils.MetricsCollector
 	context          actor.Context
-	db               database.DBAdapter
+	db               SubredditStore
+	enginePID        *actor.PID // Told when the actor is ready
 }
 
-func NewSubredditActor(metrics *utils.MetricsCollector, db database.DBAdapter) actor.Actor {
+func NewSubredditActor(enginePID *actor.PID, metrics *utils.MetricsCollector, db SubredditStore) actor.Actor {
 	return &SubredditActor{
 		subredditsByName: make(map[string]*models.Subreddit),
 		subredditsById:   make(map[uuid.UUID]*models.Subreddit),
 		subredditMembers: make(map[uuid.UUID]map[uuid.UUID]bool),
+		onlineCounts:     make(map[uuid.UUID]onlineCount),
 		metrics:          metrics,
 		db:               db,
+		enginePID:        enginePID,
 	}
 }
 
@@ -70,16 +141,18 @@ func (a *SubredditActor) Receive(context actor.Context) {
 	switch msg := context.Message().(type) {
 	case *actor.Started:
 		a.context = context
-		log.Printf("SubredditActor started")
+		context.Logger().Info("Actor started", "actor", SubredditActorName)
+		// Subreddits are cached as they're read, so there's nothing to load first
+		context.Send(a.enginePID, &ReadyMsg{Actor: SubredditActorName})
 
 	case *actor.Stopping:
-		log.Printf("SubredditActor stopping")
+		context.Logger().Info("Actor stopping", "actor", SubredditActorName)
 
 	case *actor.Stopped:
-		log.Printf("SubredditActor stopped")
+		context.Logger().Info("Actor stopped", "actor", SubredditActorName)
 
 	case *actor.Restarting:
-		log.Printf("SubredditActor restarting")
+		context.Logger().Warn("Actor restarting", "actor", SubredditActorName)
 
 	case *CreateSubredditMsg:
 		a.handleCreateSubreddit(context, msg)
@@ -99,17 +172,38 @@ func (a *SubredditActor) Receive(context actor.Context) {
 	case *GetSubredditMembersMsg:
 		a.handleGetMembers(context, msg)
 
+	case *DeleteSubredditMsg:
+		a.handleDeleteSubreddit(context, msg)
+
+	case *SetSubredditRatingMsg:
+		a.handleSetSubredditRating(context, msg)
 	case *GetSubredditByNameMsg:
 		a.handleGetSubredditByName(context, msg)
 
+	case *AddModeratorMsg:
+		a.handleAddModerator(context, msg)
+
+	case *RemoveModeratorMsg:
+		a.handleRemoveModerator(context, msg)
+
+	case *GetModeratorsMsg:
+		a.handleGetModerators(context, msg)
+
 	case *GetCountsMsg:
 		context.Respond(len(a.subredditsByName))
+
+	case *PostCountMsg:
+		if subreddit, ok := a.subredditsById[msg.SubredditID]; ok {
+			subreddit.PostCount = max(0, subreddit.PostCount+msg.Delta)
+		}
+
+	case *ExternalChangeMsg:
+		a.handleExternalChange(msg.Change)
 	}
 }
 
 // Handler functions for each message type
 func (a *SubredditActor) handleCreateSubreddit(ctx actor.Context, msg *CreateSubredditMsg) {
-	log.Printf("SubredditActor: Creating subreddit: %s", msg.Name)
 	startTime := time.Now()
 
 	// Check cache first
@@ -128,7 +222,7 @@ func (a *SubredditActor) handleCreateSubreddit(ctx actor.Context, msg *CreateSub
 	}
 
 	// Create a new context for DB operations
-	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	dbCtx, cancel := stdctx.WithTimeout(logging.Context(ctx), 5*time.Second)
 	defer cancel()
 
 	// Create the subreddit in DB
@@ -141,7 +235,7 @@ func (a *SubredditActor) handleCreateSubreddit(ctx actor.Context, msg *CreateSub
 	// Update the creator's subreddits list
 	err = a.db.UpdateUserSubreddits(dbCtx, msg.CreatorID, newSubreddit.ID, true)
 	if err != nil {
-		log.Printf("Warning: Failed to update creator's subreddit list: %v", err)
+		ctx.Logger().WarnContext(dbCtx, "Failed to update creator's subreddit list", "subreddit_id", newSubreddit.ID, "error", err)
 		// Don't fail the whole operation if this fails
 	}
 
@@ -153,13 +247,11 @@ func (a *SubredditActor) handleCreateSubreddit(ctx actor.Context, msg *CreateSub
 	}
 
 	a.metrics.AddOperationLatency("create_subreddit", time.Since(startTime))
-	log.Printf("SubredditActor: Successfully created subreddit: %s", newSubreddit.ID)
+	ctx.Logger().InfoContext(dbCtx, "Created subreddit", "subreddit_id", newSubreddit.ID, "name", newSubreddit.Name)
 	ctx.Respond(newSubreddit)
 }
 
 func (a *SubredditActor) handleGetSubredditByID(ctx actor.Context, msg *GetSubredditByIDMsg) {
-	log.Printf("Fetching subreddit details for ID: %s", msg.SubredditID)
-
 	// First check cache
 	var subreddit *models.Subreddit
 	for _, s := range a.subredditsByName {
@@ -171,13 +263,12 @@ func (a *SubredditActor) handleGetSubredditByID(ctx actor.Context, msg *GetSubre
 
 	// If not in cache, try DB
 	if subreddit == nil {
-		dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+		dbCtx, cancel := stdctx.WithTimeout(logging.Context(ctx), 5*time.Second)
 		defer cancel()
 
 		var err error
 		subreddit, err = a.db.GetSubredditByID(dbCtx, msg.SubredditID)
 		if err != nil {
-			log.Printf("Error fetching subreddit from DB: %v", err)
 			ctx.Respond(utils.NewAppError(utils.ErrNotFound, "subreddit not found", err))
 			return
 		}
@@ -198,35 +289,15 @@ func (a *SubredditActor) handleGetSubredditByID(ctx actor.Context, msg *GetSubre
 		return
 	}
 
-	// Use actual member count from DB
-	// _, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
-	// defer cancel()
-
-	response := struct {
-		ID          string      `json:"ID"`
-		Name        string      `json:"Name"`
-		Description string      `json:"Description"`
-		CreatorID   string      `json:"CreatorID"`
-		Members     int         `json:"Members"`
-		CreatedAt   time.Time   `json:"CreatedAt"`
-		Posts       []uuid.UUID `json:"Posts"`
-	}{
-		ID:          subreddit.ID.String(),
-		Name:        subreddit.Name,
-		Description: subreddit.Description,
-		CreatorID:   subreddit.CreatorID.String(),
-		Members:     subreddit.Members, // Use the value from the model
-		CreatedAt:   subreddit.CreatedAt,
-		Posts:       subreddit.Posts,
-	}
-
-	log.Printf("Successfully fetched subreddit details for ID: %s", msg.SubredditID)
+	response := &SubredditDetails{
+		Subreddit:     subreddit,
+		MembersOnline: a.onlineMembers(ctx, subreddit.ID),
+	}
+
 	ctx.Respond(response)
 }
 
 func (a *SubredditActor) handleGetSubredditByName(ctx actor.Context, msg *GetSubredditByNameMsg) {
-	log.Printf("Fetching subreddit details for name: %s", msg.Name)
-
 	// First check cache
 	var subreddit *models.Subreddit
 	if cached, exists := a.subredditsByName[msg.Name]; exists {
@@ -235,13 +306,12 @@ func (a *SubredditActor) handleGetSubredditByName(ctx actor.Context, msg *GetSub
 
 	// If not in cache, try DB
 	if subreddit == nil {
-		dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+		dbCtx, cancel := stdctx.WithTimeout(logging.Context(ctx), 5*time.Second)
 		defer cancel()
 
 		var err error
 		subreddit, err = a.db.GetSubredditByName(dbCtx, msg.Name)
 		if err != nil {
-			log.Printf("Error fetching subreddit from DB: %v", err)
 			ctx.Respond(utils.NewAppError(utils.ErrNotFound, "subreddit not found", err))
 			return
 		}
@@ -262,141 +332,280 @@ func (a *SubredditActor) handleGetSubredditByName(ctx actor.Context, msg *GetSub
 		return
 	}
 
-	response := struct {
-		ID          string      `json:"ID"`
-		Name        string      `json:"Name"`
-		Description string      `json:"Description"`
-		CreatorID   string      `json:"CreatorID"`
-		Members     int         `json:"Members"`
-		CreatedAt   time.Time   `json:"CreatedAt"`
-		Posts       []uuid.UUID `json:"Posts"`
-	}{
-		ID:          subreddit.ID.String(),
-		Name:        subreddit.Name,
-		Description: subreddit.Description,
-		CreatorID:   subreddit.CreatorID.String(),
-		Members:     subreddit.Members, // Use the value from the model
-		CreatedAt:   subreddit.CreatedAt,
-		Posts:       subreddit.Posts,
-	}
-
-	log.Printf("Successfully fetched subreddit details for name: %s", msg.Name)
+	response := &SubredditDetails{
+		Subreddit:     subreddit,
+		MembersOnline: a.onlineMembers(ctx, subreddit.ID),
+	}
+
 	ctx.Respond(response)
 }
 
+// onlineMembers returns the approximate number of members online now,
+// reusing a cached count for onlineCacheTTL. On error it falls back to the
+// last known count.
+func (a *SubredditActor) onlineMembers(ctx actor.Context, subredditID uuid.UUID) int {
+	cached, ok := a.onlineCounts[subredditID]
+	if ok && time.Since(cached.fetchedAt) < onlineCacheTTL {
+		return cached.count
+	}
+
+	dbCtx, cancel := stdctx.WithTimeout(logging.Context(ctx), 2*time.Second)
+	defer cancel()
+	count, err := a.db.CountOnlineMembers(dbCtx, subredditID, onlineWindow)
+	if err != nil {
+		ctx.Logger().ErrorContext(dbCtx, "Failed to count online members", "subreddit_id", subredditID, "error", err)
+		return cached.count
+	}
+	a.onlineCounts[subredditID] = onlineCount{count: count, fetchedAt: time.Now()}
+	return count
+}
+
 func (a *SubredditActor) handleJoinSubreddit(ctx actor.Context, msg *JoinSubredditMsg) {
-	log.Printf("User %s joining subreddit %s", msg.UserID, msg.SubredditID)
 	startTime := time.Now()
 
-	subreddit, exists := a.subredditsById[msg.SubredditID]
-	if !exists {
-		ctx.Respond(utils.NewAppError(utils.ErrNotFound, "subreddit not found", nil))
+	dbCtx, cancel := stdctx.WithTimeout(logging.Context(ctx), 5*time.Second)
+	defer cancel()
+
+	// Membership is checked and stored in the database; the cache only
+	// follows it
+	joined, err := a.db.JoinSubreddit(dbCtx, msg.SubredditID, msg.UserID)
+	if err != nil {
+		ctx.Respond(err)
+		return
+	}
+	if !joined {
+		ctx.Respond(utils.NewAppError(utils.ErrDuplicate, "user already a member", nil))
 		return
 	}
 
-	if a.subredditMembers[msg.SubredditID] == nil {
-		a.subredditMembers[msg.SubredditID] = make(map[uuid.UUID]bool)
+	// Update cache
+	if members, ok := a.subredditMembers[msg.SubredditID]; ok {
+		members[msg.UserID] = true
+	}
+	if subreddit, ok := a.subredditsById[msg.SubredditID]; ok {
+		subreddit.Members++
 	}
+	a.metrics.AddOperationLatency("join_subreddit", time.Since(startTime))
+	ctx.Logger().InfoContext(dbCtx, "User joined subreddit", "user_id", msg.UserID, "subreddit_id", msg.SubredditID)
+	ctx.Respond(true)
+}
 
-	if a.subredditMembers[msg.SubredditID][msg.UserID] {
-		ctx.Respond(utils.NewAppError(utils.ErrDuplicate, "user already a member", nil))
+// handleAddModerator appoints a moderator and responds with the updated
+// list of moderators.
+func (a *SubredditActor) handleAddModerator(ctx actor.Context, msg *AddModeratorMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(logging.Context(ctx), 5*time.Second)
+	defer cancel()
+
+	if _, err := a.db.GetUser(dbCtx, msg.UserID); err != nil {
+		ctx.Respond(err)
 		return
 	}
+	added, err := a.db.AddModerator(dbCtx, msg.SubredditID, msg.UserID, msg.ActorID)
+	if err != nil {
+		ctx.Respond(err)
+		return
+	}
+	if !added {
+		ctx.Respond(utils.NewAppError(utils.ErrDuplicate, "user is already a moderator", nil))
+		return
+	}
+	ctx.Logger().InfoContext(dbCtx, "Moderator appointed", "user_id", msg.UserID, "subreddit_id", msg.SubredditID, "actor_id", msg.ActorID)
+	a.handleGetModerators(ctx, &GetModeratorsMsg{SubredditID: msg.SubredditID})
+}
 
-	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+// handleRemoveModerator dismisses a moderator and responds with the updated
+// list of moderators.
+func (a *SubredditActor) handleRemoveModerator(ctx actor.Context, msg *RemoveModeratorMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(logging.Context(ctx), 5*time.Second)
 	defer cancel()
 
-	// Update member count and user's list in DB
-	err := a.db.UpdateSubredditMemberCount(dbCtx, msg.SubredditID, 1)
+	removed, err := a.db.RemoveModerator(dbCtx, msg.SubredditID, msg.UserID)
 	if err != nil {
-		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update member count", err))
+		ctx.Respond(err)
+		return
+	}
+	if !removed {
+		ctx.Respond(utils.NewAppError(utils.ErrNotFound, "user is not a moderator", nil))
 		return
 	}
-	err = a.db.UpdateUserSubreddits(dbCtx, msg.UserID, msg.SubredditID, true)
+	ctx.Logger().InfoContext(dbCtx, "Moderator dismissed", "user_id", msg.UserID, "subreddit_id", msg.SubredditID, "actor_id", msg.ActorID)
+	a.handleGetModerators(ctx, &GetModeratorsMsg{SubredditID: msg.SubredditID})
+}
+
+func (a *SubredditActor) handleGetModerators(ctx actor.Context, msg *GetModeratorsMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(logging.Context(ctx), 5*time.Second)
+	defer cancel()
+
+	mods, err := a.db.GetModerators(dbCtx, msg.SubredditID)
+	if err != nil {
+		ctx.Respond(err)
+		return
+	}
+	ctx.Respond(mods)
+}
+
+func (a *SubredditActor) handleLeaveSubreddit(ctx actor.Context, msg *LeaveSubredditMsg) {
+	startTime := time.Now()
+
+	dbCtx, cancel := stdctx.WithTimeout(logging.Context(ctx), 5*time.Second)
+	defer cancel()
+
+	left, err := a.db.LeaveSubreddit(dbCtx, msg.SubredditID, msg.UserID)
 	if err != nil {
-		// Attempt to rollback member count update - best effort
-		_ = a.db.UpdateSubredditMemberCount(dbCtx, msg.SubredditID, -1)
-		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update user's subreddit list", err))
+		ctx.Respond(err)
+		return
+	}
+	if !left {
+		ctx.Respond(utils.NewAppError(utils.ErrNotFound, "user not a member", nil))
 		return
 	}
 
 	// Update cache
-	a.subredditMembers[msg.SubredditID][msg.UserID] = true
-	subreddit.Members++
-	a.metrics.AddOperationLatency("join_subreddit", time.Since(startTime))
-	log.Printf("User %s successfully joined subreddit %s", msg.UserID, msg.SubredditID)
+	delete(a.subredditMembers[msg.SubredditID], msg.UserID)
+	if subreddit, ok := a.subredditsById[msg.SubredditID]; ok && subreddit.Members > 0 {
+		subreddit.Members--
+	}
+	a.metrics.AddOperationLatency("leave_subreddit", time.Since(startTime))
+	ctx.Logger().InfoContext(dbCtx, "User left subreddit", "user_id", msg.UserID, "subreddit_id", msg.SubredditID)
 	ctx.Respond(true)
 }
 
-func (a *SubredditActor) handleLeaveSubreddit(ctx actor.Context, msg *LeaveSubredditMsg) {
-	log.Printf("User %s leaving subreddit %s", msg.UserID, msg.SubredditID)
-	startTime := time.Now()
+func (a *SubredditActor) handleDeleteSubreddit(ctx actor.Context, msg *DeleteSubredditMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(logging.Context(ctx), 5*time.Second)
+	defer cancel()
 
-	subreddit, exists := a.subredditsById[msg.SubredditID]
-	if !exists {
-		ctx.Respond(utils.NewAppError(utils.ErrNotFound, "subreddit not found", nil))
+	if err := a.db.SoftDelete(dbCtx, models.ContentSubreddit, msg.SubredditID); err != nil {
+		ctx.Logger().ErrorContext(dbCtx, "Failed to delete subreddit", "subreddit_id", msg.SubredditID, "error", err)
+		ctx.Respond(err)
 		return
 	}
 
-	if a.subredditMembers[msg.SubredditID] == nil || !a.subredditMembers[msg.SubredditID][msg.UserID] {
-		ctx.Respond(utils.NewAppError(utils.ErrNotFound, "user not a member", nil))
+	if sub, ok := a.subredditsById[msg.SubredditID]; ok {
+		delete(a.subredditsByName, sub.Name)
+	}
+	delete(a.subredditsById, msg.SubredditID)
+	delete(a.subredditMembers, msg.SubredditID)
+	delete(a.onlineCounts, msg.SubredditID)
+
+	ctx.Logger().InfoContext(dbCtx, "Deleted subreddit", "subreddit_id", msg.SubredditID)
+	ctx.Respond(&models.StatusResponse{Success: true, Message: "Subreddit deleted successfully"})
+}
+
+// handleSetSubredditRating updates a subreddit's flags and responds with
+// the subreddit.
+func (a *SubredditActor) handleSetSubredditRating(ctx actor.Context, msg *SetSubredditRatingMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(logging.Context(ctx), 5*time.Second)
+	defer cancel()
+
+	if err := a.db.SetSubredditRating(dbCtx, msg.SubredditID, msg.NSFW, msg.Quarantined); err != nil {
+		ctx.Logger().ErrorContext(dbCtx, "Failed to rate subreddit", "subreddit_id", msg.SubredditID, "error", err)
+		ctx.Respond(err)
 		return
 	}
+	sub, err := a.db.GetSubredditByID(dbCtx, msg.SubredditID)
+	if err != nil {
+		ctx.Respond(err)
+		return
+	}
+	if _, ok := a.subredditsById[sub.ID]; ok {
+		a.subredditsById[sub.ID] = sub
+		a.subredditsByName[sub.Name] = sub
+	}
+	ctx.Respond(sub)
+}
+
+// handleExternalChange keeps cached subreddits and memberships in step with
+// writes made elsewhere. Cached subreddits are refreshed in place rather than
+// dropped, since joining and leaving only work on cached ones.
+func (a *SubredditActor) handleExternalChange(change database.Change) {
+	switch {
+	case change.Op == database.ChangeResync:
+		for id := range a.subredditsById {
+			a.refreshSubreddit(id)
+		}
+		for id := range a.subredditMembers {
+			a.refreshMembers(id)
+		}
+
+	case change.Table == "subreddits":
+		if _, ok := a.subredditsById[change.ID]; ok {
+			a.refreshSubreddit(change.ID)
+		}
+
+	case change.Table == "subreddit_members":
+		members, ok := a.subredditMembers[change.ID]
+		if !ok {
+			return
+		}
+		if change.Op == "delete" {
+			delete(members, change.ParentID)
+		} else {
+			members[change.ParentID] = true
+		}
+	}
+}
 
+// refreshSubreddit reloads a cached subreddit, evicting it once it's gone.
+func (a *SubredditActor) refreshSubreddit(id uuid.UUID) {
 	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
 	defer cancel()
 
-	// Update member count and user's list in DB
-	err := a.db.UpdateSubredditMemberCount(dbCtx, msg.SubredditID, -1)
+	if old, ok := a.subredditsById[id]; ok {
+		delete(a.subredditsByName, old.Name)
+	}
+	sub, err := a.db.GetSubredditByID(dbCtx, id)
 	if err != nil {
-		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update member count", err))
+		if !utils.IsErrorCode(err, utils.ErrNotFound) {
+			a.context.Logger().Error("Failed to refresh subreddit", "subreddit_id", id, "error", err)
+		}
+		delete(a.subredditsById, id)
+		delete(a.subredditMembers, id)
+		delete(a.onlineCounts, id)
 		return
 	}
-	err = a.db.UpdateUserSubreddits(dbCtx, msg.UserID, msg.SubredditID, false)
+	a.subredditsById[id] = sub
+	a.subredditsByName[sub.Name] = sub
+}
+
+// refreshMembers reloads a subreddit's cached member set.
+func (a *SubredditActor) refreshMembers(id uuid.UUID) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	memberIDs, err := a.db.GetSubredditMemberIDs(dbCtx, id)
 	if err != nil {
-		// Attempt to rollback member count update - best effort
-		_ = a.db.UpdateSubredditMemberCount(dbCtx, msg.SubredditID, 1)
-		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update user's subreddit list", err))
+		a.context.Logger().Error("Failed to refresh subreddit members", "subreddit_id", id, "error", err)
 		return
 	}
-
-	// Update cache
-	delete(a.subredditMembers[msg.SubredditID], msg.UserID)
-	subreddit.Members--
-	a.metrics.AddOperationLatency("leave_subreddit", time.Since(startTime))
-	log.Printf("User %s successfully left subreddit %s", msg.UserID, msg.SubredditID)
-	ctx.Respond(true)
+	members := make(map[uuid.UUID]bool, len(memberIDs))
+	for _, userID := range memberIDs {
+		members[userID] = true
+	}
+	a.subredditMembers[id] = members
 }
 
 func (a *SubredditActor) handleListSubreddits(ctx actor.Context) {
-	log.Println("SubredditActor: Listing all subreddits")
-	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 10*time.Second)
+	dbCtx, cancel := stdctx.WithTimeout(logging.Context(ctx), 10*time.Second)
 	defer cancel()
 
-	// TODO: Add GetAllSubreddits to DBAdapter interface
 	subreddits, err := a.db.GetAllSubreddits(dbCtx)
 	if err != nil {
-		log.Printf("Error fetching subreddits from DB: %v", err)
 		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to fetch subreddits", err))
 		return
 	}
 
-	log.Printf("SubredditActor: Found %d subreddits", len(subreddits))
 	ctx.Respond(subreddits)
 }
 
 func (a *SubredditActor) handleGetMembers(ctx actor.Context, msg *GetSubredditMembersMsg) {
-	log.Printf("SubredditActor: Getting members for subreddit: %s", msg.SubredditID)
 	startTime := time.Now()
 
 	// Always fetch from DB for now to ensure freshness, bypassing cache check.
-	log.Printf("SubredditActor: Fetching members from DB for %s.", msg.SubredditID)
-	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	dbCtx, cancel := stdctx.WithTimeout(logging.Context(ctx), 5*time.Second)
 	defer cancel()
 
 	memberIDs, err := a.db.GetSubredditMemberIDs(dbCtx, msg.SubredditID)
 	if err != nil {
-		log.Printf("SubredditActor: Error fetching members from DB: %v", err)
 		// Check if it's a specific AppError or just a general DB error
 		if appErr, ok := err.(*utils.AppError); ok {
 			ctx.Respond(appErr) // Respond with the specific AppError
@@ -420,6 +629,5 @@ func (a *SubredditActor) handleGetMembers(ctx actor.Context, msg *GetSubredditMe
 	}
 
 	a.metrics.AddOperationLatency("get_subreddit_members_db_fetch", time.Since(startTime))
-	log.Printf("SubredditActor: Successfully fetched %d members from DB for %s", len(memberIDs), msg.SubredditID)
 	ctx.Respond(memberIDs) // Respond with fetched members
 }
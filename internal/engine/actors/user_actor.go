@@ -3,7 +3,7 @@ package actors
 import (
 	"crypto/rand"
 	"encoding/base64"
-	"log"
+	"fmt"
 	"sync"
 	"time"
 
@@ -13,27 +13,47 @@ import (
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
+	"gator-swamp/internal/actorcall"
 	"gator-swamp/internal/database"
+	"gator-swamp/internal/events"
+	"gator-swamp/internal/logging"
 	"gator-swamp/internal/models"
 	"gator-swamp/internal/types"
 	"gator-swamp/internal/utils"
 )
 
+// UserStore is the data access the UserSupervisor and UserActors need.
+type UserStore interface {
+	database.UserRepository
+	database.SubredditRepository
+}
+
 // UserSupervisor is responsible for supervising and managing UserActor instances.
 // It ensures that each user has a corresponding actor and creates or retrieves them on-demand.
 type UserSupervisor struct {
 	userActors map[uuid.UUID]*actor.PID // Maps user IDs to their corresponding actor PIDs
 	emailToID  map[string]uuid.UUID     // Maps emails to user IDs for quick lookup
 	mu         sync.RWMutex             // Manages concurrent access to maps
-	db         database.DBAdapter       // Database adapter interface
+	db         UserStore                // Database access
+	events     *events.Bus              // Domain event stream (may be nil)
+	stopSweep  chan struct{}            // Closed to stop the periodic lookup sweep
+	actorCalls *actorcall.Policies      // Timeouts and retries of requests to user actors
+	enginePID  *actor.PID               // Told when the supervisor is ready
 }
 
-// NewUserSupervisor initializes a new UserSupervisor with DBAdapter.
-func NewUserSupervisor(db database.DBAdapter) actor.Actor {
+// userSweepInterval is how often the supervisor checks its lookup maps
+// against the database, catching changes made by other instances.
+const userSweepInterval = 15 * time.Minute
+
+// NewUserSupervisor initializes a new UserSupervisor with its store.
+func NewUserSupervisor(enginePID *actor.PID, db UserStore, bus *events.Bus, actorCalls *actorcall.Policies) actor.Actor {
 	return &UserSupervisor{
 		userActors: make(map[uuid.UUID]*actor.PID),
 		emailToID:  make(map[string]uuid.UUID),
 		db:         db, // Assign the db interface
+		events:     bus,
+		actorCalls: actorCalls,
+		enginePID:  enginePID,
 	}
 }
 
@@ -71,6 +91,9 @@ type (
 		SubredditID uuid.UUID
 	}
 
+	// ConnectUserMsg and DisconnectUserMsg tell the supervisor a user's
+	// first WebSocket connection opened or their last one closed. Send
+	// them; the supervisor records the change and publishes it.
 	ConnectUserMsg struct {
 		UserID uuid.UUID
 	}
@@ -78,6 +101,24 @@ type (
 	DisconnectUserMsg struct {
 		UserID uuid.UUID
 	}
+
+	// ForgetUserMsg stops a user's actor and drops the supervisor's lookup
+	// entries for them. Send it after deleting or merging away an account.
+	ForgetUserMsg struct {
+		UserID uuid.UUID
+	}
+
+	// sweepUserLookupsMsg triggers the periodic lookup map sweep
+	sweepUserLookupsMsg struct{}
+
+	// userLookupsCheckedMsg brings the sweep's database check back to the
+	// supervisor. Entries maps each email checked to its user; Emails holds
+	// the current email of each of those users still around.
+	userLookupsCheckedMsg struct {
+		Entries map[string]uuid.UUID
+		Emails  map[uuid.UUID]string
+		Err     error
+	}
 )
 
 // UserState represents the internal state of a user maintained by its actor.
@@ -86,6 +127,8 @@ type UserState struct {
 	Username       string
 	Email          string
 	Karma          int
+	PostKarma      int
+	CommentKarma   int
 	IsConnected    bool
 	LastActive     time.Time
 	Posts          []uuid.UUID
@@ -94,12 +137,76 @@ type UserState struct {
 	AuthToken      string
 	Subreddits     []uuid.UUID
 	SubredditNames []string // New field
+	ProfileImage   *string  // Storage key of the avatar's original upload
+	KarmaVelocity  float64  // Smoothed karma gained per hour
+	CreatedAt      time.Time
 }
 
 // Receive is the main message handler for the UserSupervisor.
 // It handles user registration, login, profile retrieval, and karma updates by delegating to UserActor instances.
 func (s *UserSupervisor) Receive(context actor.Context) {
 	switch msg := context.Message().(type) {
+	case *actor.Started:
+		s.stopSweep = make(chan struct{})
+		go s.runSweeps(context.ActorSystem().Root, context.Self(), s.stopSweep)
+		// User actors are spawned on demand, so there's nothing to load first
+		context.Send(s.enginePID, &ReadyMsg{Actor: UserSupervisorName})
+
+	case *actor.Stopping:
+		close(s.stopSweep)
+
+	case *sweepUserLookupsMsg:
+		s.sweepLookups(context)
+
+	case *userLookupsCheckedMsg:
+		s.applySweep(context, msg)
+
+	case *ForgetUserMsg:
+		s.mu.Lock()
+		s.forgetUser(context, msg.UserID)
+		s.mu.Unlock()
+		context.Respond(true)
+
+	// Users changed elsewhere are reloaded from the database on next use
+	case *ExternalChangeMsg:
+		s.mu.Lock()
+		switch {
+		case msg.Change.Op == database.ChangeResync:
+			for userID := range s.userActors {
+				s.forgetUser(context, userID)
+			}
+		case msg.Change.Table == "users":
+			s.forgetUser(context, msg.Change.ID)
+		case msg.Change.Table == "subreddit_members":
+			s.forgetUser(context, msg.Change.ParentID)
+		}
+		s.mu.Unlock()
+
+	case *ConnectUserMsg:
+		s.setConnected(context, msg.UserID, true, msg)
+
+	case *DisconnectUserMsg:
+		s.setConnected(context, msg.UserID, false, msg)
+
+	// Handle profile changes, keeping the email lookup in step
+	case *UpdateProfileMsg:
+		pid, err := s.getOrCreateUserActor(context, msg.UserID)
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrUserNotFound, "User not found", err))
+			return
+		}
+		result, err := s.actorCalls.Request(context, pid, msg)
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrActorTimeout, "Profile update failed", err))
+			return
+		}
+		if updated, _ := result.(bool); updated {
+			s.mu.Lock()
+			s.forgetEmails(msg.UserID)
+			s.emailToID[msg.NewEmail] = msg.UserID
+			s.mu.Unlock()
+		}
+		context.Respond(result)
 
 	// Handle user registration requests
 	case *RegisterUserMsg:
@@ -107,18 +214,16 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 		defer s.mu.Unlock()
 
 		// Check if the email is already registered
-		ctx := stdctx.Background()
-		// TODO: Add GetUserByEmail to DBAdapter interface
+		ctx := logging.Context(context)
 		existingUser, _ := s.db.GetUserByEmail(ctx, msg.Email)
 		if existingUser != nil {
-			log.Printf("Email already exists in DB: %s", msg.Email)
 			context.Respond(utils.NewAppError(utils.ErrDuplicate, "Email already registered", nil))
 			return
 		}
 
 		// Create a new user actor for this user
 		userID := uuid.New()
-		props := actor.PropsFromProducer(func() actor.Actor {
+		props := logging.Props(func() actor.Actor {
 			// TODO: Update NewUserActor signature
 			return NewUserActor(userID, msg, s.db)
 		})
@@ -128,25 +233,30 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 		s.emailToID[msg.Email] = userID
 
 		// Send the register message to the user actor and wait for a response
-		future := context.RequestFuture(pid, msg, 5*time.Second)
-		result, err := future.Result()
+		result, err := s.actorCalls.Request(context, pid, msg)
 		if err != nil {
-			log.Printf("Failed to create user: %v", err)
+			context.Logger().ErrorContext(ctx, "Failed to create user", "user_id", userID, "error", err)
+			s.forgetUser(context, userID)
 			context.Respond(utils.NewAppError(utils.ErrActorTimeout, "User creation failed", err))
 			return
 		}
+		if _, failed := result.(*utils.AppError); failed {
+			s.forgetUser(context, userID)
+		}
+		if state, ok := result.(*UserState); ok {
+			s.events.Publish(events.TypeUserRegistered, events.UserRegistered{
+				UserID:   state.ID,
+				Username: state.Username,
+			})
+		}
 		context.Respond(result)
 
 	// Handle login requests
 	case *LoginMsg:
-		log.Printf("UserSupervisor: Processing login request for email: %s", msg.Email)
-
 		// Fetch user from DB by email
-		ctx := stdctx.Background()
-		// TODO: Add GetUserByEmail to DBAdapter interface
+		ctx := logging.Context(context)
 		user, err := s.db.GetUserByEmail(ctx, msg.Email)
 		if err != nil {
-			log.Printf("UserSupervisor: User not found in DB: %v", err)
 			context.Respond(&types.LoginResponse{
 				Success: false,
 				Error:   "Invalid credentials",
@@ -161,7 +271,7 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 
 		if !exists {
 			// Create a new actor for this existing user from DB
-			props := actor.PropsFromProducer(func() actor.Actor {
+			props := logging.Props(func() actor.Actor {
 				// TODO: Update NewUserActor signature
 				return NewUserActor(user.ID, &RegisterUserMsg{
 					Username: user.Username,
@@ -179,10 +289,9 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 		}
 
 		// Forward the login message to the user actor
-		future := context.RequestFuture(pid, msg, 5*time.Second)
-		result, err := future.Result()
+		result, err := s.actorCalls.Request(context, pid, msg)
 		if err != nil {
-			log.Printf("UserSupervisor: Login request to user actor failed: %v", err)
+			context.Logger().ErrorContext(ctx, "Login request to user actor failed", "user_id", user.ID, "error", err)
 			context.Respond(&types.LoginResponse{
 				Success: false,
 				Error:   "Login failed",
@@ -195,8 +304,7 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 
 	// Handle user profile retrieval
 	case *GetUserProfileMsg:
-		ctx := stdctx.Background()
-		// TODO: Add GetUser to DBAdapter interface
+		ctx := logging.Context(context)
 		user, err := s.db.GetUser(ctx, msg.UserID)
 		if err != nil {
 			if utils.IsErrorCode(err, utils.ErrUserNotFound) {
@@ -210,10 +318,9 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 		// Get the names of all subreddits
 		subredditNames := make([]string, 0, len(user.Subreddits))
 		for _, subID := range user.Subreddits {
-			// TODO: Add GetSubredditByID to DBAdapter interface
 			subreddit, err := s.db.GetSubredditByID(ctx, subID)
 			if err != nil {
-				log.Printf("Error fetching subreddit %s: %v", subID, err)
+				context.Logger().ErrorContext(ctx, "Failed to fetch subreddit", "subreddit_id", subID, "error", err)
 				continue
 			}
 			subredditNames = append(subredditNames, subreddit.Name)
@@ -226,16 +333,151 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 			Username:       user.Username,
 			Email:          user.Email,
 			Karma:          user.Karma,
+			PostKarma:      user.PostKarma,
+			CommentKarma:   user.CommentKarma,
 			IsConnected:    user.IsConnected,
 			LastActive:     user.LastActive,
 			Subreddits:     user.Subreddits,
 			SubredditNames: subredditNames,
+			ProfileImage:   user.ProfileImage,
+			KarmaVelocity:  user.KarmaVelocity,
+			CreatedAt:      user.CreatedAt,
 		}
 
 		context.Respond(response)
 	}
 }
 
+// setConnected records a user connecting or disconnecting: in their actor,
+// if they have one, in the database, and as a presence event for anyone
+// watching the bus.
+func (s *UserSupervisor) setConnected(context actor.Context, userID uuid.UUID, connected bool, msg interface{}) {
+	s.mu.RLock()
+	pid, ok := s.userActors[userID]
+	s.mu.RUnlock()
+	if ok {
+		context.Send(pid, msg)
+	}
+
+	ctx, cancel := stdctx.WithTimeout(logging.Context(context), 5*time.Second)
+	defer cancel()
+	if err := s.db.UpdateUserActivity(ctx, userID, connected); err != nil {
+		context.Logger().ErrorContext(ctx, "Failed to record connection status", "user_id", userID, "error", err)
+	}
+	s.events.Publish(events.TypeUserPresence, events.UserPresence{
+		UserID:    userID,
+		Connected: connected,
+		At:        time.Now(),
+	})
+	if context.Sender() != nil {
+		context.Respond(true)
+	}
+}
+
+// forgetUser stops userID's actor, if any, and drops its lookup entries.
+// Callers must hold s.mu.
+func (s *UserSupervisor) forgetUser(context actor.Context, userID uuid.UUID) {
+	if pid, ok := s.userActors[userID]; ok {
+		context.Stop(pid)
+		delete(s.userActors, userID)
+	}
+	s.forgetEmails(userID)
+}
+
+// forgetEmails drops every email lookup pointing at userID. Callers must
+// hold s.mu.
+func (s *UserSupervisor) forgetEmails(userID uuid.UUID) {
+	for email, id := range s.emailToID {
+		if id == userID {
+			delete(s.emailToID, email)
+		}
+	}
+}
+
+// runSweeps asks the supervisor to sweep its lookup maps every
+// userSweepInterval until stop is closed.
+func (s *UserSupervisor) runSweeps(root *actor.RootContext, self *actor.PID, stop <-chan struct{}) {
+	ticker := time.NewTicker(userSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			root.Send(self, &sweepUserLookupsMsg{})
+		}
+	}
+}
+
+// sweepLookups drops email lookups for users without a live actor, and
+// checks the rest against the database in one query, off the supervisor's
+// goroutine so user messages aren't held up. applySweep handles the answer.
+func (s *UserSupervisor) sweepLookups(context actor.Context) {
+	s.mu.Lock()
+	dropped := 0
+	entries := make(map[string]uuid.UUID)
+	ids := make([]uuid.UUID, 0, len(s.userActors))
+	for email, id := range s.emailToID {
+		if _, live := s.userActors[id]; !live {
+			delete(s.emailToID, email)
+			dropped++
+			continue
+		}
+		entries[email] = id
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+
+	if dropped > 0 {
+		context.Logger().Info("Sweep dropped lookup entries without an actor", "dropped", dropped)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	root, self := context.ActorSystem().Root, context.Self()
+	go func() {
+		ctx, cancel := stdctx.WithTimeout(stdctx.Background(), time.Minute)
+		defer cancel()
+		emails, err := s.db.GetUserEmails(ctx, ids)
+		root.Send(self, &userLookupsCheckedMsg{Entries: entries, Emails: emails, Err: err})
+	}()
+}
+
+// applySweep forgets users deleted or merged away since their entry was
+// checked and re-points changed emails. Entries changed in the meantime,
+// such as by a profile update, are left alone. A failed check leaves every
+// entry alone until the next sweep.
+func (s *UserSupervisor) applySweep(context actor.Context, msg *userLookupsCheckedMsg) {
+	if msg.Err != nil {
+		context.Logger().Warn("Sweep could not check users", "users", len(msg.Entries), "error", msg.Err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dropped := 0
+	for email, id := range msg.Entries {
+		if s.emailToID[email] != id {
+			continue
+		}
+		current, ok := msg.Emails[id]
+		switch {
+		case !ok:
+			s.forgetUser(context, id)
+			dropped++
+		case current != email:
+			delete(s.emailToID, email)
+			s.emailToID[current] = id
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		context.Logger().Info("Sweep dropped stale lookup entries", "dropped", dropped)
+	}
+}
+
 // getOrCreateUserActor ensures that a user actor exists for the given userID.
 // If it doesn't, it fetches the user from the database and creates a new actor.
 func (s *UserSupervisor) getOrCreateUserActor(context actor.Context, userID uuid.UUID) (*actor.PID, error) {
@@ -248,15 +490,14 @@ func (s *UserSupervisor) getOrCreateUserActor(context actor.Context, userID uuid
 	}
 
 	// Fetch user details from the database
-	ctx := stdctx.Background()
-	// TODO: Add GetUser to DBAdapter interface
+	ctx := logging.Context(context)
 	user, err := s.db.GetUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Create a new actor if none exists
-	props := actor.PropsFromProducer(func() actor.Actor {
+	props := logging.Props(func() actor.Actor {
 		return NewUserActor(user.ID, &RegisterUserMsg{
 			Username: user.Username,
 			Email:    user.Email,
@@ -280,11 +521,11 @@ func (s *UserSupervisor) getOrCreateUserActor(context actor.Context, userID uuid
 type UserActor struct {
 	id    uuid.UUID
 	state *UserState
-	db    database.DBAdapter
+	db    UserStore
 }
 
 // NewUserActor creates a new user actor with initial user state, typically during registration or actor creation for an existing user.
-func NewUserActor(id uuid.UUID, msg *RegisterUserMsg, db database.DBAdapter) *UserActor {
+func NewUserActor(id uuid.UUID, msg *RegisterUserMsg, db UserStore) *UserActor {
 	return &UserActor{
 		id: id,
 		state: &UserState{
@@ -324,8 +565,6 @@ func (a *UserActor) Receive(context actor.Context) {
 
 	// Handle user registration inside the user actor
 	case *RegisterUserMsg:
-		log.Printf("UserActor [%s]: Registering new user", a.id)
-
 		// Hash password
 		hashedPassword, err := hashPassword(msg.Password)
 		if err != nil {
@@ -347,21 +586,23 @@ func (a *UserActor) Receive(context actor.Context) {
 		}
 
 		// Persist the user in the database
-		ctx := stdctx.Background()
-		// TODO: Add SaveUser to DBAdapter interface
+		ctx := logging.Context(context)
 		if err := a.db.SaveUser(ctx, user); err != nil {
-			log.Printf("Failed to save user to DB: %v", err)
+			context.Logger().ErrorContext(ctx, "Failed to save user", "user_id", a.id, "error", err)
 			context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Failed to save user", err))
 			return
 		}
 
-		log.Printf("Successfully created user %s in DB", a.id)
+		context.Logger().InfoContext(ctx, "Created user", "user_id", a.id)
 
 		context.Respond(&UserState{
-			ID:       a.id,
-			Username: msg.Username,
-			Email:    msg.Email,
-			Karma:    msg.Karma,
+			ID:          a.id,
+			Username:    msg.Username,
+			Email:       msg.Email,
+			Karma:       msg.Karma,
+			IsConnected: user.IsConnected,
+			LastActive:  user.LastActive,
+			CreatedAt:   user.CreatedAt,
 		})
 
 	// Handle user profile updates (username/email)
@@ -377,14 +618,14 @@ func (a *UserActor) Receive(context actor.Context) {
 	// Handle user profile retrieval
 	case *GetUserProfileMsg:
 		// Fetch latest persistent data from DB
-		ctx := stdctx.Background()
+		ctx := logging.Context(context)
 		user, err := a.db.GetUser(ctx, msg.UserID)
 		if err != nil {
 			if utils.IsErrorCode(err, utils.ErrNotFound) {
 				context.Respond(nil) // User not found
 				return
 			}
-			log.Printf("Error fetching user %s from DB for profile: %v", msg.UserID, err)
+			context.Logger().ErrorContext(ctx, "Failed to fetch user profile", "user_id", msg.UserID, "error", err)
 			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch user profile data", err))
 			return
 		}
@@ -394,8 +635,13 @@ func (a *UserActor) Receive(context actor.Context) {
 		a.state.Username = user.Username
 		a.state.Email = user.Email
 		a.state.Karma = user.Karma
+		a.state.PostKarma = user.PostKarma
+		a.state.CommentKarma = user.CommentKarma
 		a.state.HashedPassword = user.HashedPassword // Keep password hash synchronized
 		a.state.Subreddits = user.Subreddits
+		a.state.ProfileImage = user.ProfileImage
+		a.state.KarmaVelocity = user.KarmaVelocity
+		a.state.CreatedAt = user.CreatedAt
 		// a.state.IsConnected is managed by Connect/Disconnect messages
 		// a.state.LastActive is managed by Connect/Login messages
 		// a.state.AuthToken is managed by Login messages
@@ -405,7 +651,7 @@ func (a *UserActor) Receive(context actor.Context) {
 		for _, subID := range a.state.Subreddits {
 			subreddit, err := a.db.GetSubredditByID(ctx, subID)
 			if err != nil {
-				log.Printf("Error fetching subreddit %s for user %s profile: %v", subID, msg.UserID, err)
+				context.Logger().ErrorContext(ctx, "Failed to fetch subreddit", "subreddit_id", subID, "user_id", msg.UserID, "error", err)
 				// Optionally add a placeholder or skip
 				continue
 			}
@@ -418,12 +664,9 @@ func (a *UserActor) Receive(context actor.Context) {
 
 	// Handle user login
 	case *LoginMsg:
-		log.Printf("Processing login request for email: %s", msg.Email)
-
-		ctx := stdctx.Background()
+		ctx := logging.Context(context)
 		user, err := a.db.GetUserByEmail(ctx, msg.Email)
 		if err != nil {
-			log.Printf("Login failed - Error fetching user from DB: %v", err)
 			context.Respond(&types.LoginResponse{
 				Success: false,
 				Error:   "Invalid credentials",
@@ -434,7 +677,7 @@ func (a *UserActor) Receive(context actor.Context) {
 		// Verify password
 		err = bcrypt.CompareHashAndPassword([]byte(user.HashedPassword), []byte(msg.Password))
 		if err != nil {
-			log.Printf("Login failed - Password mismatch: %v", err)
+			context.Logger().InfoContext(ctx, "Login failed", "user_id", user.ID, "reason", "password mismatch")
 			context.Respond(&types.LoginResponse{
 				Success: false,
 				Error:   "Invalid credentials",
@@ -445,7 +688,7 @@ func (a *UserActor) Receive(context actor.Context) {
 		// Generate a new auth token for the session
 		token, err := generateToken()
 		if err != nil {
-			log.Printf("Failed to generate auth token: %v", err)
+			context.Logger().ErrorContext(ctx, "Failed to generate auth token", "error", err)
 			context.Respond(&types.LoginResponse{
 				Success: false,
 				Error:   "Authentication error",
@@ -456,7 +699,7 @@ func (a *UserActor) Receive(context actor.Context) {
 		// Update user activity in the database
 		err = a.db.UpdateUserActivity(ctx, user.ID, true)
 		if err != nil {
-			log.Printf("Warning: Failed to update user activity in DB: %v", err)
+			context.Logger().WarnContext(ctx, "Failed to update user activity", "user_id", user.ID, "error", err)
 		}
 
 		// Update actor state with new auth token and connection status
@@ -472,12 +715,13 @@ func (a *UserActor) Receive(context actor.Context) {
 			Subreddits:     user.Subreddits,
 		}
 
-		log.Printf("Login successful for user: %s", user.Username)
+		context.Logger().InfoContext(ctx, "Login succeeded", "user_id", user.ID)
 
 		context.Respond(&types.LoginResponse{
 			Success: true,
 			Token:   token,
 			UserID:  user.ID.String(),
+			IsBot:   user.IsBot,
 		})
 
 	// Handle feed additions — checks if the user follows the subreddit before adding
@@ -494,14 +738,18 @@ func (a *UserActor) Receive(context actor.Context) {
 	case *ConnectUserMsg:
 		a.state.IsConnected = true
 		a.state.LastActive = time.Now()
-		context.Respond(true)
+		if context.Sender() != nil {
+			context.Respond(true)
+		}
 
 	// Handle user disconnection events
 	case *DisconnectUserMsg:
 		a.state.IsConnected = false
-		context.Respond(true)
+		if context.Sender() != nil {
+			context.Respond(true)
+		}
 
 	default:
-		log.Printf("UserActor %s received unknown message type: %T", a.id, msg)
+		context.Logger().Warn("Unknown message", "user_id", a.id, "type", fmt.Sprintf("%T", msg))
 	}
 }
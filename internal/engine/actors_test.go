@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/policy"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// stubUsers serves users from a map. Methods other than GetUser aren't
+// needed by the creator check and panic if called.
+type stubUsers struct {
+	database.UserRepository
+	users map[uuid.UUID]*models.User
+	reads int
+}
+
+func (s *stubUsers) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	s.reads++
+	user, ok := s.users[id]
+	if !ok {
+		return nil, utils.NewAppError(utils.ErrNotFound, "user not found", nil)
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func TestCheckSubredditCreatorKarmaThreshold(t *testing.T) {
+	tests := []struct {
+		name    string
+		karma   int
+		admin   bool
+		wantErr string
+	}{
+		{name: "one short", karma: 99, wantErr: utils.ErrForbidden},
+		{name: "exactly the threshold", karma: 100},
+		{name: "one over", karma: 101},
+		{name: "no karma", karma: 0, wantErr: utils.ErrForbidden},
+		{name: "admin below the threshold", karma: 0, admin: true},
+	}
+
+	now := time.Now()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := uuid.New()
+			users := &stubUsers{users: map[uuid.UUID]*models.User{
+				id: {ID: id, Karma: tt.karma, IsAdmin: tt.admin, CreatedAt: now.Add(-time.Hour)},
+			}}
+			err := checkSubredditCreator(context.Background(), users, policy.Defaults(), id, now)
+			checkErrorCode(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCheckSubredditCreatorUnknownUser(t *testing.T) {
+	users := &stubUsers{users: map[uuid.UUID]*models.User{}}
+	err := checkSubredditCreator(context.Background(), users, policy.Defaults(), uuid.New(), time.Now())
+	checkErrorCode(t, err, utils.ErrNotFound)
+}
+
+// A UserSupervisor copy of the user may lag the database in either
+// direction; each check must go by the database's current karma.
+func TestCheckSubredditCreatorReadsFreshKarma(t *testing.T) {
+	tests := []struct {
+		name       string
+		staleKarma int
+		freshKarma int
+		wantErr    string
+	}{
+		{name: "earned since cached", staleKarma: 99, freshKarma: 100},
+		{name: "lost since cached", staleKarma: 100, freshKarma: 99, wantErr: utils.ErrForbidden},
+	}
+
+	now := time.Now()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := uuid.New()
+			user := &models.User{ID: id, Karma: tt.staleKarma, CreatedAt: now.Add(-time.Hour)}
+			users := &stubUsers{users: map[uuid.UUID]*models.User{id: user}}
+			// The first check sees the karma a cache would have kept
+			checkSubredditCreator(context.Background(), users, policy.Defaults(), id, now)
+
+			user.Karma = tt.freshKarma
+			err := checkSubredditCreator(context.Background(), users, policy.Defaults(), id, now)
+			checkErrorCode(t, err, tt.wantErr)
+			if users.reads != 2 {
+				t.Errorf("user read %d times, want once per check", users.reads)
+			}
+		})
+	}
+}
+
+// checkErrorCode fails t unless err is an AppError with code want, or nil
+// when want is empty.
+func checkErrorCode(t *testing.T, err error, want string) {
+	t.Helper()
+	if want == "" {
+		if err != nil {
+			t.Fatalf("got error %v, want none", err)
+		}
+		return
+	}
+	if !utils.IsErrorCode(err, want) {
+		t.Fatalf("got error %v, want %s", err, want)
+	}
+}
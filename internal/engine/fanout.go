@@ -0,0 +1,119 @@
+package engine
+
+import (
+	stdctx "context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/events"
+	"gator-swamp/internal/websocket"
+
+	"github.com/google/uuid"
+)
+
+// NewPostEvent is pushed over WebSocket to online members of a subreddit when
+// a post is created there, so clients can show "N new posts" without polling.
+type NewPostEvent struct {
+	Type           string    `json:"type"` // Always "newPost"
+	PostID         uuid.UUID `json:"postId"`
+	SubredditID    uuid.UUID `json:"subredditId"`
+	SubredditName  string    `json:"subredditName"`
+	Title          string    `json:"title"`
+	AuthorID       uuid.UUID `json:"authorId"`
+	AuthorUsername string    `json:"authorUsername"`
+	AuthorIsBot    bool      `json:"authorIsBot,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// NewCommentEvent is pushed over WebSocket to clients subscribed to a post
+// when a comment is added to it, so open threads show it without refetching.
+type NewCommentEvent struct {
+	Type           string     `json:"type"` // Always "newComment"
+	CommentID      uuid.UUID  `json:"commentId"`
+	PostID         uuid.UUID  `json:"postId"`
+	ParentID       *uuid.UUID `json:"parentId,omitempty"`
+	AuthorID       uuid.UUID  `json:"authorId"`
+	AuthorUsername string     `json:"authorUsername"`
+	AuthorIsBot    bool       `json:"authorIsBot,omitempty"`
+	Content        string     `json:"content"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// subscribeFanout pushes domain events to the WebSocket clients on this
+// instance they concern, including events relayed from other instances.
+func subscribeFanout(bus *events.Bus, db database.SubredditRepository, hub *websocket.Hub) {
+	bus.Subscribe("websocket", func(e events.Event) {
+		switch payload := e.Payload.(type) {
+		case events.PostCreated:
+			pushNewPost(db, hub, payload)
+		case events.CommentCreated:
+			pushNewComment(hub, payload)
+		}
+	}, events.TypePostCreated, events.TypeCommentCreated)
+}
+
+// pushNewComment sends a NewCommentEvent to the post's subscribers.
+func pushNewComment(hub *websocket.Hub, comment events.CommentCreated) {
+	topic := websocket.PostTopic(comment.PostID)
+	if !hub.HasSubscribers(topic) {
+		return
+	}
+	payload, err := json.Marshal(NewCommentEvent{
+		Type:           "newComment",
+		CommentID:      comment.CommentID,
+		PostID:         comment.PostID,
+		ParentID:       comment.ParentID,
+		AuthorID:       comment.AuthorID,
+		AuthorUsername: comment.AuthorUsername,
+		AuthorIsBot:    comment.AuthorIsBot,
+		Content:        comment.Content,
+		CreatedAt:      comment.CreatedAt,
+	})
+	if err != nil {
+		slog.Error("Failed to marshal new comment event", "comment_id", comment.CommentID, "error", err)
+		return
+	}
+	hub.Publish(topic, payload)
+}
+
+// pushNewPost sends a NewPostEvent to the subreddit's online members,
+// excluding the author.
+func pushNewPost(db database.SubredditRepository, hub *websocket.Hub, post events.PostCreated) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	memberIDs, err := db.GetSubredditMemberIDs(dbCtx, post.SubredditID)
+	if err != nil {
+		slog.Error("Failed to fetch subreddit members for new post event", "subreddit_id", post.SubredditID, "error", err)
+		return
+	}
+	recipients := make([]uuid.UUID, 0, len(memberIDs))
+	for _, id := range memberIDs {
+		if id != post.AuthorID {
+			recipients = append(recipients, id)
+		}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(NewPostEvent{
+		Type:           "newPost",
+		PostID:         post.PostID,
+		SubredditID:    post.SubredditID,
+		SubredditName:  post.SubredditName,
+		Title:          post.Title,
+		AuthorID:       post.AuthorID,
+		AuthorUsername: post.AuthorUsername,
+		AuthorIsBot:    post.AuthorIsBot,
+		CreatedAt:      post.CreatedAt,
+	})
+	if err != nil {
+		slog.Error("Failed to marshal new post event", "post_id", post.PostID, "error", err)
+		return
+	}
+	delivered := hub.SendToConnected(recipients, payload)
+	slog.Debug("Pushed new post to online members", "post_id", post.PostID, "subreddit", post.SubredditName, "delivered", delivered)
+}
@@ -0,0 +1,179 @@
+package engine
+
+import (
+	stdctx "context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/events"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"gator-swamp/internal/websocket"
+
+	"github.com/google/uuid"
+)
+
+// scoreFlushInterval is how often a watched post's new scores are pushed.
+// Votes and replies in between are folded into one update.
+const scoreFlushInterval = 3 * time.Second
+
+// PostScoreEvent is pushed over WebSocket to clients watching a post when its
+// score or comment count changes. Counts are absolute rather than deltas, so
+// a dropped update is corrected by the next one.
+type PostScoreEvent struct {
+	Type         string    `json:"type"` // Always "postScore"
+	PostID       uuid.UUID `json:"postId"`
+	Karma        int       `json:"karma"`
+	Upvotes      int       `json:"upvotes"`
+	Downvotes    int       `json:"downvotes"`
+	CommentCount int       `json:"commentCount"`
+}
+
+// CommentScoreEvent is pushed to clients watching a post when the score or
+// direct reply count of one of its comments changes, so open threads stay
+// current without refetching them.
+type CommentScoreEvent struct {
+	Type       string    `json:"type"` // Always "commentScore"
+	PostID     uuid.UUID `json:"postId"`
+	CommentID  uuid.UUID `json:"commentId"`
+	Karma      int       `json:"karma"`
+	Upvotes    int       `json:"upvotes"`
+	Downvotes  int       `json:"downvotes"`
+	ReplyCount int       `json:"replyCount"`
+}
+
+// scoreStore is what the batcher reads current counts from.
+type scoreStore interface {
+	database.PostRepository
+	database.CommentRepository
+}
+
+// scoreBatcher collects posts and comments whose counts changed and
+// periodically pushes their current counts to the clients watching the post.
+type scoreBatcher struct {
+	db  scoreStore
+	hub *websocket.Hub
+
+	mu       sync.Mutex
+	dirty    map[uuid.UUID]bool      // Posts
+	comments map[uuid.UUID]uuid.UUID // Comments, to their post
+}
+
+// startScoreBatcher pushes the counts of voted-on and replied-to posts and
+// comments to their watchers every scoreFlushInterval.
+func startScoreBatcher(bus *events.Bus, db scoreStore, hub *websocket.Hub) *scoreBatcher {
+	s := &scoreBatcher{
+		db:       db,
+		hub:      hub,
+		dirty:    make(map[uuid.UUID]bool),
+		comments: make(map[uuid.UUID]uuid.UUID),
+	}
+	bus.Subscribe("live-scores", func(e events.Event) {
+		switch payload := e.Payload.(type) {
+		case events.VoteRecorded:
+			if payload.ContentType == models.PostVote {
+				s.touch(payload.ContentID)
+			} else {
+				s.touchComment(payload.ContentID, payload.PostID)
+			}
+		case events.CommentCreated:
+			s.touch(payload.PostID)
+			if payload.ParentID != nil {
+				s.touchComment(*payload.ParentID, payload.PostID)
+			}
+		}
+	}, events.TypeVoteRecorded, events.TypeCommentCreated)
+	go s.run()
+	return s
+}
+
+// touch marks a post's counts as changed. Posts nobody here watches are skipped.
+func (s *scoreBatcher) touch(postID uuid.UUID) {
+	if !s.hub.HasSubscribers(websocket.PostTopic(postID)) {
+		return
+	}
+	s.mu.Lock()
+	s.dirty[postID] = true
+	s.mu.Unlock()
+}
+
+// touchComment marks the counts of a comment on postID as changed.
+func (s *scoreBatcher) touchComment(commentID, postID uuid.UUID) {
+	if !s.hub.HasSubscribers(websocket.PostTopic(postID)) {
+		return
+	}
+	s.mu.Lock()
+	s.comments[commentID] = postID
+	s.mu.Unlock()
+}
+
+func (s *scoreBatcher) run() {
+	ticker := time.NewTicker(scoreFlushInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.flush()
+	}
+}
+
+// flush pushes the current counts of every post and comment touched since
+// the last flush.
+func (s *scoreBatcher) flush() {
+	s.mu.Lock()
+	dirty, comments := s.dirty, s.comments
+	s.dirty = make(map[uuid.UUID]bool)
+	s.comments = make(map[uuid.UUID]uuid.UUID)
+	s.mu.Unlock()
+
+	for postID := range dirty {
+		dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+		post, err := s.db.GetPost(dbCtx, postID, uuid.Nil)
+		cancel()
+		if err != nil {
+			slog.Error("Failed to fetch post for live score update", "post_id", postID, "error", err)
+			continue
+		}
+		s.push(postID, PostScoreEvent{
+			Type:         "postScore",
+			PostID:       post.ID,
+			Karma:        post.Karma,
+			Upvotes:      post.Upvotes,
+			Downvotes:    post.Downvotes,
+			CommentCount: post.CommentCount,
+		})
+	}
+
+	for commentID, postID := range comments {
+		dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+		comment, err := s.db.GetComment(dbCtx, commentID, uuid.Nil)
+		cancel()
+		if err != nil {
+			// Deleted comments aren't found, and aren't pushed
+			if !utils.IsErrorCode(err, utils.ErrNotFound) {
+				slog.Error("Failed to fetch comment for live score update", "comment_id", commentID, "error", err)
+			}
+			continue
+		}
+		s.push(postID, CommentScoreEvent{
+			Type:       "commentScore",
+			PostID:     comment.PostID,
+			CommentID:  comment.ID,
+			Karma:      comment.Karma,
+			Upvotes:    comment.Upvotes,
+			Downvotes:  comment.Downvotes,
+			ReplyCount: comment.ReplyCount,
+		})
+	}
+}
+
+// push sends an update to the watchers of postID.
+func (s *scoreBatcher) push(postID uuid.UUID, update interface{}) {
+	payload, err := json.Marshal(update)
+	if err != nil {
+		slog.Error("Failed to marshal live score update", "post_id", postID, "error", err)
+		return
+	}
+	s.hub.Publish(websocket.PostTopic(postID), payload)
+}
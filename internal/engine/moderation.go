@@ -0,0 +1,106 @@
+package engine
+
+import (
+	stdctx "context"
+	"time"
+
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/logging"
+	"gator-swamp/internal/utils"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+// checkModerator returns a Forbidden error unless userID moderates the
+// subreddit, or owns it if ownerOnly is set.
+func (e *Engine) checkModerator(ctx stdctx.Context, subredditID, userID uuid.UUID, ownerOnly bool) error {
+	ctx, cancel := stdctx.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := e.db.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	sub, err := e.db.GetSubredditByID(ctx, subredditID)
+	if err != nil {
+		return err
+	}
+	if ownerOnly {
+		if !actors.IsOwner(user, sub) {
+			return utils.NewAppError(utils.ErrForbidden, "Only the subreddit's creator can manage its moderators", nil)
+		}
+		return nil
+	}
+	isMod, err := actors.IsModerator(ctx, e.db, user, sub)
+	if err != nil {
+		return err
+	}
+	if !isMod {
+		return utils.NewAppError(utils.ErrForbidden, "Moderator access required", nil)
+	}
+	return nil
+}
+
+// contentSubreddit returns the subreddit a post or comment is in.
+func (e *Engine) contentSubreddit(ctx stdctx.Context, postID, commentID uuid.UUID) (uuid.UUID, error) {
+	ctx, cancel := stdctx.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if commentID != uuid.Nil {
+		comment, err := e.db.GetComment(ctx, commentID, uuid.Nil)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return comment.SubredditID, nil
+	}
+	post, err := e.db.GetPost(ctx, postID, uuid.Nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return post.SubredditID, nil
+}
+
+// forwardModeration checks the sender of a moderator action may take it,
+// then forwards msg to target. Actions on posts and comments are checked
+// against the subreddit they're in.
+func (e *Engine) forwardModeration(context actor.Context, target *actor.PID, msg interface{}) {
+	ctx := logging.Context(context)
+	var err error
+	switch msg := msg.(type) {
+	case *actors.AddModeratorMsg:
+		err = e.checkModerator(ctx, msg.SubredditID, msg.ActorID, true)
+	case *actors.RemoveModeratorMsg:
+		// Moderators may step down themselves
+		if msg.ActorID != msg.UserID {
+			err = e.checkModerator(ctx, msg.SubredditID, msg.ActorID, true)
+		}
+	case *actors.LockPostMsg:
+		err = e.checkContentModerator(ctx, msg.PostID, uuid.Nil, msg.UserID)
+	case *actors.DeletePostMsg:
+		err = e.checkContentModerator(ctx, msg.PostID, uuid.Nil, msg.UserID)
+	case *actors.DeleteCommentMsg:
+		err = e.checkContentModerator(ctx, uuid.Nil, msg.CommentID, msg.AuthorID)
+	}
+	if err != nil {
+		context.Respond(err)
+		return
+	}
+
+	result, err := e.actorCalls.Request(context, target, msg)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrActorTimeout, "Failed to process moderator action", err))
+		return
+	}
+	context.Respond(result)
+}
+
+// checkContentModerator checks userID moderates the subreddit of a post or
+// comment.
+func (e *Engine) checkContentModerator(ctx stdctx.Context, postID, commentID, userID uuid.UUID) error {
+	subredditID, err := e.contentSubreddit(ctx, postID, commentID)
+	if err != nil {
+		return err
+	}
+	return e.checkModerator(ctx, subredditID, userID, false)
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"sort"
+	"sync"
+
+	"gator-swamp/internal/engine/actors"
+)
+
+// startup collects the ReadyMsg of each actor the Engine spawns. Requests
+// shouldn't be served until all have arrived, or they'd find empty caches.
+type startup struct {
+	mu      sync.Mutex
+	pending map[string]bool
+	ready   chan struct{}
+}
+
+func newStartup(names ...string) *startup {
+	s := &startup{pending: make(map[string]bool), ready: make(chan struct{})}
+	for _, name := range names {
+		s.pending[name] = true
+	}
+	return s
+}
+
+// markReady records an actor's ReadyMsg, reporting whether it's the first.
+// Actors that reload later report again, which changes nothing.
+func (s *startup) markReady(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.pending[name] {
+		return false
+	}
+	delete(s.pending, name)
+	if len(s.pending) == 0 {
+		close(s.ready)
+	}
+	return true
+}
+
+// Ready is closed once every actor has loaded its initial state.
+func (e *Engine) Ready() <-chan struct{} {
+	return e.startup.ready
+}
+
+// PendingActors names the actors that have yet to report ready.
+func (e *Engine) PendingActors() []string {
+	e.startup.mu.Lock()
+	defer e.startup.mu.Unlock()
+	names := make([]string, 0, len(e.startup.pending))
+	for name := range e.startup.pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// startupActors are the actors the Engine waits on.
+var startupActors = []string{
+	actors.PostActorName,
+	actors.CommentActorName,
+	actors.SubredditActorName,
+	actors.UserSupervisorName,
+}
@@ -0,0 +1,263 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxBatchSize  = 100
+	flushInterval = 500 * time.Millisecond
+	sendTimeout   = 5 * time.Second
+)
+
+// Sink delivers batches of events to an external system.
+type Sink interface {
+	Send(ctx context.Context, batch []Event) error
+	Close() error
+}
+
+// NewSink builds the sink named by kind ("nats" or "kafka").
+func NewSink(kind, url, prefix string) (Sink, error) {
+	switch kind {
+	case "nats":
+		sink, err := NewNATSSink(url, prefix)
+		if err != nil {
+			return nil, err
+		}
+		return sink, nil
+	case "kafka":
+		return NewKafkaRESTSink(url, prefix), nil
+	default:
+		return nil, fmt.Errorf("unknown event sink %q (expected nats or kafka)", kind)
+	}
+}
+
+// Handler reacts to an event on its subscription's goroutine.
+type Handler func(Event)
+
+// subscription delivers events of some types to one handler, in order.
+type subscription struct {
+	name    string
+	types   map[string]bool // Empty means every type
+	handler Handler
+	queue   chan Event
+	done    chan struct{}
+}
+
+// Bus is where actors announce what happened. Each event goes to the
+// in-process subscribers, which is how notifications, search indexing and
+// WebSocket fanout hear about it, and is buffered for delivery to the sink,
+// if any, in batches on its own goroutine. A nil *Bus is valid and drops
+// everything.
+type Bus struct {
+	sink       Sink // May be nil
+	bufferSize int
+	queue      chan Event
+	done       chan struct{}
+	mu         sync.RWMutex
+	subs       []*subscription
+	closed     bool
+}
+
+// NewBus creates a bus that buffers up to bufferSize events for the sink,
+// and for each subscriber, before dropping. sink may be nil to only deliver
+// in process. Call Run to start delivery to the sink.
+func NewBus(sink Sink, bufferSize int) *Bus {
+	return &Bus{
+		sink:       sink,
+		bufferSize: bufferSize,
+		queue:      make(chan Event, bufferSize),
+		done:       make(chan struct{}),
+	}
+}
+
+// Subscribe calls handler for each later event of the given types, or of
+// every type if none are given. Handlers run one event at a time on a
+// goroutine of their own, so a slow one only delays itself; if it falls
+// bufferSize events behind, further events are dropped for it. name labels
+// its metrics and logs.
+func (b *Bus) Subscribe(name string, handler Handler, types ...string) {
+	if b == nil {
+		return
+	}
+	sub := &subscription{
+		name:    name,
+		types:   make(map[string]bool, len(types)),
+		handler: handler,
+		queue:   make(chan Event, b.bufferSize),
+		done:    make(chan struct{}),
+	}
+	for _, t := range types {
+		sub.types[t] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.subs = append(b.subs, sub)
+	go sub.run()
+}
+
+// Publish announces an event without blocking. If a buffer is full the
+// event is dropped there and counted, since the request path must never
+// stall on the sink or a subscriber.
+func (b *Bus) Publish(eventType string, payload interface{}) {
+	b.publish(eventType, payload, false)
+}
+
+// Relay announces an event that was published on another instance, such as
+// a post seen through the database change feed. Only subscribers get it,
+// with Remote set, since that instance already sent it to the sink.
+func (b *Bus) Relay(eventType string, payload interface{}) {
+	b.publish(eventType, payload, true)
+}
+
+func (b *Bus) publish(eventType string, payload interface{}, remote bool) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+
+	event := Event{
+		ID:         uuid.New(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Payload:    payload,
+		Remote:     remote,
+	}
+	for _, sub := range b.subs {
+		if len(sub.types) > 0 && !sub.types[eventType] {
+			continue
+		}
+		select {
+		case sub.queue <- event:
+		default:
+			subscriberEventsDropped.WithLabelValues(sub.name).Inc()
+			slog.Warn("Event subscriber is behind, event dropped", "subscriber", sub.name, "type", eventType)
+		}
+	}
+
+	if remote || b.sink == nil {
+		return
+	}
+	select {
+	case b.queue <- event:
+		eventsPublished.WithLabelValues(eventType).Inc()
+	default:
+		eventsDropped.Inc()
+		slog.Warn("Event bus buffer full, event dropped", "type", eventType)
+	}
+}
+
+func (s *subscription) run() {
+	defer close(s.done)
+	for event := range s.queue {
+		s.handle(event)
+	}
+}
+
+// handle calls the handler, containing any panic to this event.
+func (s *subscription) handle(event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Event subscriber panicked", "subscriber", s.name, "type", event.Type, "event_id", event.ID, "panic", r)
+		}
+	}()
+	s.handler(event)
+}
+
+// Run delivers queued events to the sink until Close is called and the
+// queue is drained.
+func (b *Bus) Run() {
+	defer close(b.done)
+	if b.sink == nil {
+		for range b.queue {
+		}
+		return
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, maxBatchSize)
+	for {
+		select {
+		case event, ok := <-b.queue:
+			if !ok {
+				b.flush(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= maxBatchSize {
+				b.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			b.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (b *Bus) flush(batch []Event) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	if err := b.sink.Send(ctx, batch); err != nil {
+		eventsFailed.Add(float64(len(batch)))
+		slog.Error("Failed to deliver events", "events", len(batch), "error", err)
+		return
+	}
+	eventsDelivered.Add(float64(len(batch)))
+}
+
+// Close stops accepting events, waits for subscribers to handle and the
+// sink to receive the queued ones, and closes the sink. Returns ctx.Err()
+// if draining doesn't finish in time.
+func (b *Bus) Close(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+		for _, sub := range b.subs {
+			close(sub.queue)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, sub := range b.subs {
+		select {
+		case <-sub.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if b.sink == nil {
+		return nil
+	}
+	return b.sink.Close()
+}
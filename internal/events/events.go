@@ -0,0 +1,96 @@
+// Package events carries domain events from the actors that produce them to
+// in-process subscribers (notifications, search indexing, WebSocket fanout)
+// and an optional external sink (NATS or Kafka) for downstream consumers
+// such as analytics. Publishing never blocks the request path.
+package events
+
+import (
+	"time"
+
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Event types. Sinks use these as the subject/topic suffix.
+const (
+	TypePostCreated    = "post.created"
+	TypePostEdited     = "post.edited"
+	TypePostDeleted    = "post.deleted"
+	TypeVoteRecorded   = "vote.recorded"
+	TypeCommentCreated = "comment.created"
+	TypeUserRegistered = "user.registered"
+	TypeUserPresence   = "user.presence"
+)
+
+// Event is the envelope delivered to sinks.
+type Event struct {
+	ID         uuid.UUID   `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Payload    interface{} `json:"payload"`
+	Remote     bool        `json:"-"` // Published on another instance; see Bus.Relay
+}
+
+// PostCreated is the payload of TypePostCreated.
+type PostCreated struct {
+	PostID         uuid.UUID `json:"postId"`
+	SubredditID    uuid.UUID `json:"subredditId"`
+	SubredditName  string    `json:"subredditName"`
+	AuthorID       uuid.UUID `json:"authorId"`
+	AuthorUsername string    `json:"authorUsername"`
+	AuthorIsBot    bool      `json:"authorIsBot,omitempty"`
+	Title          string    `json:"title"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// PostEdited is the payload of TypePostEdited.
+type PostEdited struct {
+	PostID   uuid.UUID `json:"postId"`
+	AuthorID uuid.UUID `json:"authorId"`
+	EditedAt time.Time `json:"editedAt"`
+}
+
+// PostDeleted is the payload of TypePostDeleted, published when an author
+// deletes a post or a moderator or admin removes it.
+type PostDeleted struct {
+	PostID      uuid.UUID `json:"postId"`
+	SubredditID uuid.UUID `json:"subredditId"`
+	Removed     bool      `json:"removed"` // By a moderator or admin, and restorable
+}
+
+// VoteRecorded is the payload of TypeVoteRecorded. Direction "none" means
+// the vote was removed.
+type VoteRecorded struct {
+	UserID      uuid.UUID              `json:"userId"`
+	ContentID   uuid.UUID              `json:"contentId"`
+	ContentType models.VoteContentType `json:"contentType"`
+	PostID      uuid.UUID              `json:"postId"` // The post voted on, or the voted comment's post
+	Direction   models.VoteDirection   `json:"direction"`
+}
+
+// CommentCreated is the payload of TypeCommentCreated.
+type CommentCreated struct {
+	CommentID      uuid.UUID  `json:"commentId"`
+	PostID         uuid.UUID  `json:"postId"`
+	ParentID       *uuid.UUID `json:"parentId,omitempty"`
+	AuthorID       uuid.UUID  `json:"authorId"`
+	AuthorUsername string     `json:"authorUsername"`
+	AuthorIsBot    bool       `json:"authorIsBot,omitempty"`
+	Content        string     `json:"content"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// UserPresence is the payload of TypeUserPresence, published when a user's
+// first WebSocket connection opens or their last one closes.
+type UserPresence struct {
+	UserID    uuid.UUID `json:"userId"`
+	Connected bool      `json:"connected"`
+	At        time.Time `json:"at"`
+}
+
+// UserRegistered is the payload of TypeUserRegistered.
+type UserRegistered struct {
+	UserID   uuid.UUID `json:"userId"`
+	Username string    `json:"username"`
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// KafkaRESTSink produces events to Kafka through a Confluent-compatible REST
+// Proxy, one topic per event type ("<prefix>.<type>"), keyed by event ID.
+type KafkaRESTSink struct {
+	baseURL string
+	prefix  string
+	client  *http.Client
+}
+
+// NewKafkaRESTSink targets the REST Proxy at baseURL (e.g. http://localhost:8082).
+func NewKafkaRESTSink(baseURL, topicPrefix string) *KafkaRESTSink {
+	return &KafkaRESTSink{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		prefix:  topicPrefix,
+		client:  &http.Client{Timeout: sendTimeout},
+	}
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value Event  `json:"value"`
+}
+
+// Send produces the batch with one request per topic.
+func (s *KafkaRESTSink) Send(ctx context.Context, batch []Event) error {
+	byTopic := make(map[string][]kafkaRecord)
+	for _, event := range batch {
+		topic := s.prefix + "." + event.Type
+		byTopic[topic] = append(byTopic[topic], kafkaRecord{Key: event.ID.String(), Value: event})
+	}
+
+	for topic, records := range byTopic {
+		if err := s.produce(ctx, topic, records); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *KafkaRESTSink) produce(ctx context.Context, topic string, records []kafkaRecord) error {
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/topics/"+topic, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to produce to %s: %v", topic, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kafka REST proxy rejected %s (%d): %s", topic, resp.StatusCode, msg)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Close is a no-op; the HTTP client holds no dedicated connection.
+func (s *KafkaRESTSink) Close() error {
+	return nil
+}
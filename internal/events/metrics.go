@@ -0,0 +1,34 @@
+package events
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics for event streaming, exposed on /metrics.
+var (
+	eventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_events_published_total",
+		Help: "Domain events accepted by the event bus, by type.",
+	}, []string{"type"})
+
+	eventsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gator_events_dropped_total",
+		Help: "Domain events dropped because the event bus buffer was full.",
+	})
+
+	subscriberEventsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_events_subscriber_dropped_total",
+		Help: "Domain events dropped because an in-process subscriber fell behind, by subscriber.",
+	}, []string{"subscriber"})
+
+	eventsDelivered = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gator_events_delivered_total",
+		Help: "Domain events delivered to the configured sink.",
+	})
+
+	eventsFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gator_events_failed_total",
+		Help: "Domain events the sink failed to accept.",
+	})
+)
@@ -0,0 +1,166 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultNATSPort = "4222"
+
+// NATSSink publishes each event to "<prefix>.<type>" using the NATS text
+// protocol directly, so no client library is needed. A broken connection
+// is re-dialed on the next batch.
+type NATSSink struct {
+	addr    string
+	prefix  string
+	connect []byte // CONNECT line sent after the server's INFO
+
+	mu   sync.Mutex
+	conn net.Conn
+	w    *bufio.Writer
+}
+
+// NewNATSSink dials a NATS server at a nats://[user:pass@]host[:port] URL.
+func NewNATSSink(rawURL, subjectPrefix string) (*NATSSink, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid NATS URL %q", rawURL)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), defaultNATSPort)
+	}
+
+	opts := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "gator-swamp",
+		"lang":     "go",
+	}
+	if u.User != nil {
+		opts["user"] = u.User.Username()
+		if pass, ok := u.User.Password(); ok {
+			opts["pass"] = pass
+		}
+	}
+	connectJSON, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &NATSSink{
+		addr:    addr,
+		prefix:  subjectPrefix,
+		connect: []byte("CONNECT " + string(connectJSON) + "\r\n"),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.dialLocked(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *NATSSink) dialLocked() error {
+	conn, err := net.DialTimeout("tcp", s.addr, sendTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS at %s: %v", s.addr, err)
+	}
+
+	// The server greets with an INFO line before accepting CONNECT
+	conn.SetReadDeadline(time.Now().Add(sendTimeout))
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO") {
+		conn.Close()
+		return fmt.Errorf("unexpected NATS greeting from %s: %q (%v)", s.addr, line, err)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	if _, err := conn.Write(s.connect); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to send NATS CONNECT: %v", err)
+	}
+
+	s.conn = conn
+	s.w = bufio.NewWriter(conn)
+	go s.readLoop(conn, r)
+	slog.Info("Connected to NATS", "addr", s.addr)
+	return nil
+}
+
+// readLoop answers server PINGs so the connection isn't considered stale,
+// and logs protocol errors.
+func (s *NATSSink) readLoop(conn net.Conn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			s.mu.Lock()
+			if s.conn == conn {
+				s.w.WriteString("PONG\r\n")
+				s.w.Flush()
+			}
+			s.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			slog.Error("NATS error", "message", strings.TrimSpace(line))
+		}
+	}
+}
+
+// Send publishes the batch in a single flush.
+func (s *NATSSink) Send(ctx context.Context, batch []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if err := s.dialLocked(); err != nil {
+			return err
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	}
+
+	for _, event := range batch {
+		data, err := json.Marshal(event)
+		if err != nil {
+			slog.ErrorContext(ctx, "Skipping unencodable event", "type", event.Type, "error", err)
+			continue
+		}
+		fmt.Fprintf(s.w, "PUB %s.%s %d\r\n", s.prefix, event.Type, len(data))
+		s.w.Write(data)
+		s.w.WriteString("\r\n")
+	}
+	if err := s.w.Flush(); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("failed to publish to NATS: %v", err)
+	}
+	return nil
+}
+
+// Close closes the connection.
+func (s *NATSSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	s.w.Flush()
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,571 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/policy"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// ImpersonateRequest represents an admin's request to act as another user
+type ImpersonateRequest struct {
+	UserID string `json:"userId"`
+	Reason string `json:"reason"` // Required, recorded in the audit log
+}
+
+// requireAdmin returns the requesting admin's ID, or writes an error and
+// returns false. Impersonation tokens never count as admin, even when
+// minted by one.
+func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+	if _, impersonating := r.Context().Value(middleware.ImpersonatorIDKey).(uuid.UUID); impersonating {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+
+	user, err := s.DB.GetUser(r.Context(), userID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return uuid.Nil, false
+		}
+		http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+		return uuid.Nil, false
+	}
+	if !user.IsAdmin {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// HandleImpersonate mints a short-lived impersonation token for support
+// debugging. The token is flagged in its claims, can't perform destructive
+// actions, and it and every request made with it are audited.
+func (s *Server) HandleImpersonate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		adminID, ok := s.requireAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		var req ImpersonateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		targetID, err := uuid.Parse(req.UserID)
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Reason == "" {
+			http.Error(w, "A reason is required", http.StatusBadRequest)
+			return
+		}
+		if targetID == adminID {
+			http.Error(w, "Cannot impersonate yourself", http.StatusBadRequest)
+			return
+		}
+
+		target, err := s.DB.GetUser(r.Context(), targetID)
+		if err != nil {
+			if utils.IsErrorCode(err, utils.ErrNotFound) {
+				http.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+			return
+		}
+		if target.IsAdmin {
+			http.Error(w, "Cannot impersonate another admin", http.StatusForbidden)
+			return
+		}
+
+		token, tokenID, expiresAt, err := middleware.GenerateImpersonationToken(targetID, adminID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to generate impersonation token", "error", err)
+			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+
+		// No token is handed out unless its issuance is on record
+		details, _ := json.Marshal(map[string]interface{}{
+			"tokenId":   tokenID,
+			"reason":    req.Reason,
+			"expiresAt": expiresAt,
+		})
+		if err := s.DB.RecordAudit(r.Context(), &models.AuditEntry{
+			ActorID:   adminID,
+			SubjectID: &targetID,
+			Action:    models.AuditImpersonationStart,
+			Details:   details,
+		}); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to audit impersonation", "user_id", targetID, "admin_id", adminID, "error", err)
+			http.Error(w, "Failed to record audit entry", http.StatusInternalServerError)
+			return
+		}
+		slog.InfoContext(r.Context(), "Admin started impersonating user", "admin_id", adminID, "user_id", targetID, "token_id", tokenID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":     token,
+			"tokenId":   tokenID,
+			"userId":    targetID,
+			"expiresAt": expiresAt,
+		})
+	}
+}
+
+// HandleAuditLog lists audit entries for admins, optionally for one user.
+func (s *Server) HandleAuditLog() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if _, ok := s.requireAdmin(w, r); !ok {
+			return
+		}
+
+		var subjectID *uuid.UUID
+		if v := r.URL.Query().Get("userId"); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+				return
+			}
+			subjectID = &id
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if limit <= 0 || limit > 200 {
+			limit = 50
+		}
+		if offset < 0 {
+			offset = 0
+		}
+
+		entries, err := s.DB.GetAuditLog(r.Context(), subjectID, limit, offset)
+		if err != nil {
+			http.Error(w, "Failed to fetch audit log", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	}
+}
+
+// AuditImpersonatedRequest records a request made with an impersonation
+// token. It is installed as middleware.AuditImpersonatedRequest.
+func (s *Server) AuditImpersonatedRequest(r *http.Request, claims *middleware.Claims, status int) {
+	details, _ := json.Marshal(map[string]interface{}{
+		"tokenId": claims.ID,
+		"method":  r.Method,
+		"path":    r.URL.Path,
+		"query":   r.URL.RawQuery,
+		"status":  status,
+	})
+	subjectID := claims.UserID
+
+	// The request may already be cancelled once the handler has returned
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 2*time.Second)
+	defer cancel()
+	if err := s.DB.RecordAudit(ctx, &models.AuditEntry{
+		ActorID:   *claims.ImpersonatorID,
+		SubjectID: &subjectID,
+		Action:    models.AuditImpersonationRequest,
+		Details:   details,
+	}); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to audit impersonated request", "method", r.Method, "path", r.URL.Path, "admin_id", claims.ImpersonatorID, "error", err)
+	}
+}
+
+// ContentRequest identifies a post, comment or subreddit for moderation
+type ContentRequest struct {
+	Type   string `json:"type"` // "post", "comment" or "subreddit"
+	ID     string `json:"id"`
+	Reason string `json:"reason,omitempty"` // Recorded in the audit log
+}
+
+// parseContent validates a content type and ID from a request.
+func parseContent(contentType, id string) (models.ContentType, uuid.UUID, bool) {
+	ct := models.ContentType(contentType)
+	switch ct {
+	case models.ContentPost, models.ContentComment, models.ContentSubreddit:
+	default:
+		return "", uuid.Nil, false
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return "", uuid.Nil, false
+	}
+	return ct, parsed, true
+}
+
+// includeDeleted reports whether the request asked for soft-deleted content
+// with include_deleted=true. Only admins may ask; anyone else gets an error
+// written and ok false.
+func (s *Server) includeDeleted(w http.ResponseWriter, r *http.Request) (include bool, ok bool) {
+	if r.URL.Query().Get("include_deleted") != "true" {
+		return false, true
+	}
+	if _, ok := s.requireAdmin(w, r); !ok {
+		return false, false
+	}
+	return true, true
+}
+
+// auditContent records an admin delete or restore of content.
+func (s *Server) auditContent(ctx context.Context, adminID uuid.UUID, action string, ct models.ContentType, id uuid.UUID, reason string) {
+	details, _ := json.Marshal(map[string]interface{}{
+		"type":   ct,
+		"id":     id,
+		"reason": reason,
+	})
+	if err := s.DB.RecordAudit(ctx, &models.AuditEntry{
+		ActorID: adminID,
+		Action:  action,
+		Details: details,
+	}); err != nil {
+		slog.ErrorContext(ctx, "Failed to audit content action", "action", action, "content_type", ct, "content_id", id, "admin_id", adminID, "error", err)
+	}
+}
+
+// HandleAdminContent lists soft-deleted content (GET ?type=&limit=&offset=)
+// and removes any user's content (DELETE ?type=&id=&reason=). Removals go
+// through the owning actor so its cache stays consistent.
+func (s *Server) HandleAdminContent() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminID, ok := s.requireAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			ct := models.ContentType(r.URL.Query().Get("type"))
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			if limit <= 0 || limit > 200 {
+				limit = 50
+			}
+			if offset < 0 {
+				offset = 0
+			}
+
+			items, err := s.DB.ListDeleted(r.Context(), ct, limit, offset)
+			if err != nil {
+				if appErr, ok := err.(*utils.AppError); ok {
+					i18n.WriteError(w, r, appErr)
+					return
+				}
+				http.Error(w, "Failed to list deleted content", http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(items)
+
+		case http.MethodDelete:
+			q := r.URL.Query()
+			ct, id, ok := parseContent(q.Get("type"), q.Get("id"))
+			if !ok {
+				http.Error(w, "type must be post, comment or subreddit, with a valid id", http.StatusBadRequest)
+				return
+			}
+
+			var result *models.StatusResponse
+			var err error
+			switch ct {
+			case models.ContentPost:
+				result, err = s.clients(r).Posts.Delete(&actors.DeletePostMsg{PostID: id, UserID: adminID, Force: true})
+			case models.ContentComment:
+				result, err = s.clients(r).Comments.Delete(&actors.DeleteCommentMsg{CommentID: id, AuthorID: adminID, Force: true})
+			case models.ContentSubreddit:
+				result, err = s.clients(r).Subreddits.Delete(&actors.DeleteSubredditMsg{SubredditID: id})
+			}
+			if err != nil {
+				writeActorError(w, r, err, "Failed to delete content")
+				return
+			}
+			s.auditContent(r.Context(), adminID, models.AuditContentDelete, ct, id, q.Get("reason"))
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(result)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// HandleAdminRestore restores soft-deleted content along with whatever was
+// deleted with it.
+func (s *Server) HandleAdminRestore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		adminID, ok := s.requireAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		var req ContentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		ct, id, ok := parseContent(req.Type, req.ID)
+		if !ok {
+			http.Error(w, "type must be post, comment or subreddit, with a valid id", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.DB.Restore(r.Context(), ct, id); err != nil {
+			if appErr, ok := err.(*utils.AppError); ok {
+				i18n.WriteError(w, r, appErr)
+				return
+			}
+			http.Error(w, "Failed to restore content", http.StatusInternalServerError)
+			return
+		}
+		s.auditContent(r.Context(), adminID, models.AuditContentRestore, ct, id, req.Reason)
+		slog.InfoContext(r.Context(), "Admin restored content", "admin_id", adminID, "content_type", ct, "content_id", id)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&models.StatusResponse{Success: true, Message: "Content restored"})
+	}
+}
+
+// MergeUsersRequest asks to merge a duplicate account into a primary one
+type MergeUsersRequest struct {
+	PrimaryID   string `json:"primaryId"`
+	DuplicateID string `json:"duplicateId"`
+	DryRun      bool   `json:"dryRun"`
+	Reason      string `json:"reason,omitempty"` // Recorded in the audit log
+}
+
+// HandleAdminMergeUsers moves a duplicate account's content, votes,
+// messages and karma to a primary account and tombstones the duplicate. A
+// dry run only reports what would move.
+func (s *Server) HandleAdminMergeUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		adminID, ok := s.requireAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		var req MergeUsersRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		primaryID, err := uuid.Parse(req.PrimaryID)
+		if err != nil {
+			http.Error(w, "Invalid primary user ID format", http.StatusBadRequest)
+			return
+		}
+		duplicateID, err := uuid.Parse(req.DuplicateID)
+		if err != nil {
+			http.Error(w, "Invalid duplicate user ID format", http.StatusBadRequest)
+			return
+		}
+
+		report, err := s.DB.MergeUsers(r.Context(), primaryID, duplicateID, req.DryRun)
+		if err != nil {
+			if appErr, ok := err.(*utils.AppError); ok {
+				i18n.WriteError(w, r, appErr)
+				return
+			}
+			http.Error(w, "Failed to merge accounts", http.StatusInternalServerError)
+			return
+		}
+
+		if !req.DryRun {
+			// Cached posts, comments and users may name either account
+			s.Engine.ApplyChange(database.Change{Op: database.ChangeResync})
+			// The tombstone can't sign in, so its actor and email lookup go too
+			if _, err := s.clients(r).Users.Forget(&actors.ForgetUserMsg{UserID: duplicateID}); err != nil {
+				slog.WarnContext(r.Context(), "Failed to forget merged user", "duplicate_id", duplicateID, "error", err)
+			}
+
+			details, _ := json.Marshal(map[string]interface{}{
+				"primaryId": primaryID,
+				"report":    report,
+				"reason":    req.Reason,
+			})
+			if err := s.DB.RecordAudit(r.Context(), &models.AuditEntry{
+				ActorID:   adminID,
+				SubjectID: &duplicateID,
+				Action:    models.AuditUserMerge,
+				Details:   details,
+			}); err != nil {
+				slog.ErrorContext(r.Context(), "Failed to audit user merge", "duplicate_id", duplicateID, "primary_id", primaryID, "admin_id", adminID, "error", err)
+			}
+			slog.InfoContext(r.Context(), "Admin merged users", "admin_id", adminID, "duplicate_id", duplicateID, "primary_id", primaryID)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// BotRequest creates a bot account (POST) or changes what one may do (PUT).
+type BotRequest struct {
+	UserID       string   `json:"userId,omitempty"`   // PUT only
+	Username     string   `json:"username,omitempty"` // POST only
+	Email        string   `json:"email,omitempty"`    // POST only
+	Password     string   `json:"password,omitempty"` // POST only; the bot logs in with it
+	Scopes       []string `json:"scopes"`
+	SubredditIDs []string `json:"subredditIds"` // Empty for anywhere
+}
+
+// botPermissions validates the scopes and subreddits of a BotRequest.
+func botPermissions(req *BotRequest) ([]string, []uuid.UUID, string) {
+	scopes := []string{}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(policy.Operations, scope) {
+			return nil, nil, "unknown scope " + strconv.Quote(scope) + "; expected one of " + strings.Join(policy.Operations, ", ")
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	subredditIDs := []uuid.UUID{}
+	for _, s := range req.SubredditIDs {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, nil, "Invalid subreddit ID format"
+		}
+		subredditIDs = append(subredditIDs, id)
+	}
+	return scopes, subredditIDs, ""
+}
+
+// HandleAdminBots manages bot accounts: GET lists them, POST registers a new
+// bot with its scopes and subreddits, and PUT replaces them for an existing
+// bot. Bots log in like other users; their tokens are marked as a bot's.
+func (s *Server) HandleAdminBots() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminID, ok := s.requireAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		var req BotRequest
+		switch r.Method {
+		case http.MethodGet:
+			bots, err := s.DB.ListBotAccounts(r.Context())
+			if err != nil {
+				http.Error(w, "Failed to list bot accounts", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(bots)
+			return
+		case http.MethodPost, http.MethodPut:
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request", http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		scopes, subredditIDs, problem := botPermissions(&req)
+		if problem != "" {
+			http.Error(w, problem, http.StatusBadRequest)
+			return
+		}
+
+		bot := &models.BotAccount{Scopes: scopes, SubredditIDs: subredditIDs, CreatedBy: adminID}
+		action := models.AuditBotUpdate
+		status := http.StatusOK
+		if r.Method == http.MethodPost {
+			if req.Username == "" || req.Email == "" || req.Password == "" {
+				http.Error(w, "username, email and password are required", http.StatusBadRequest)
+				return
+			}
+			userState, err := s.clients(r).Users.Register(&actors.RegisterUserMsg{
+				Username: req.Username,
+				Email:    req.Email,
+				Password: req.Password,
+			})
+			if err != nil {
+				writeActorError(w, r, err, "Failed to register bot")
+				return
+			}
+			bot.UserID = userState.ID
+			bot.Username = userState.Username
+			action = models.AuditBotCreate
+			status = http.StatusCreated
+		} else {
+			userID, err := uuid.Parse(req.UserID)
+			if err != nil {
+				http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+				return
+			}
+			existing, err := s.DB.GetBotAccount(r.Context(), userID)
+			if err != nil {
+				writeActorError(w, r, err, "Failed to fetch bot account")
+				return
+			}
+			bot.UserID = existing.UserID
+			bot.Username = existing.Username
+		}
+
+		if err := s.DB.SaveBotAccount(r.Context(), bot); err != nil {
+			writeActorError(w, r, err, "Failed to save bot account")
+			return
+		}
+
+		details, _ := json.Marshal(bot)
+		subjectID := bot.UserID
+		if err := s.DB.RecordAudit(r.Context(), &models.AuditEntry{
+			ActorID:   adminID,
+			SubjectID: &subjectID,
+			Action:    action,
+			Details:   details,
+		}); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to audit bot action", "action", action, "user_id", bot.UserID, "admin_id", adminID, "error", err)
+		}
+		slog.InfoContext(r.Context(), "Admin changed bot", "admin_id", adminID, "action", action, "bot", bot.Username, "scopes", bot.Scopes)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(bot)
+	}
+}
@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/dto"
 	"gator-swamp/internal/engine/actors"
 	"gator-swamp/internal/middleware"
-	"gator-swamp/internal/utils"
+	"gator-swamp/internal/models"
 
 	"github.com/google/uuid"
 )
@@ -15,7 +18,7 @@ import (
 // CreateCommentRequest represents a request to create a new comment
 type CreateCommentRequest struct {
 	Content  string `json:"content"`
-	AuthorID string `json:"authorId"`
+	AuthorID string `json:"authorId,omitempty"` // Deprecated: the author is the authenticated user
 	PostID   string `json:"postId"`
 	ParentID string `json:"parentId,omitempty"` // Optional, for replies
 }
@@ -23,13 +26,13 @@ type CreateCommentRequest struct {
 // EditCommentRequest represents a request to edit an existing comment
 type EditCommentRequest struct {
 	CommentID string `json:"commentId"`
-	AuthorID  string `json:"authorId"`
+	AuthorID  string `json:"authorId,omitempty"` // Deprecated: the author is the authenticated user
 	Content   string `json:"content"`
 }
 
 // CommentVoteRequest represents a request to vote on a comment
 type CommentVoteRequest struct {
-	UserID     string `json:"userId"`
+	UserID     string `json:"userId,omitempty"` // Deprecated: the voter is the authenticated user
 	CommentID  string `json:"commentId"`
 	IsUpvote   bool   `json:"isUpvote"`
 	RemoveVote bool   `json:"removeVote,omitempty"` // Added optional field
@@ -41,26 +44,19 @@ func (s *Server) HandleComment() http.HandlerFunc {
 		switch r.Method {
 		case http.MethodPost:
 			// Create comment
-			log.Printf("Received comment creation request")
 			var req CreateCommentRequest
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				log.Printf("Error decoding request: %v", err)
 				http.Error(w, "Invalid request", http.StatusBadRequest)
 				return
 			}
 
-			log.Printf("Creating comment for post: %s by author: %s and parent: %s", req.PostID, req.AuthorID, req.ParentID)
-
-			authorID, err := uuid.Parse(req.AuthorID)
-			if err != nil {
-				log.Printf("Error parsing author ID: %v", err)
-				http.Error(w, "Invalid author ID", http.StatusBadRequest)
+			authorID, ok := actingUser(w, r, req.AuthorID)
+			if !ok {
 				return
 			}
 
 			postID, err := uuid.Parse(req.PostID)
 			if err != nil {
-				log.Printf("Error parsing post ID: %v", err)
 				http.Error(w, "Invalid post ID", http.StatusBadRequest)
 				return
 			}
@@ -69,36 +65,29 @@ func (s *Server) HandleComment() http.HandlerFunc {
 			if req.ParentID != "" {
 				parsed, err := uuid.Parse(req.ParentID)
 				if err != nil {
-					log.Printf("Error parsing parent ID: %v", err)
 					http.Error(w, "Invalid parent comment ID", http.StatusBadRequest)
 					return
 				}
 				parentID = &parsed
 			}
 
-			log.Printf("Sending CreateCommentMsg to comment actor")
-			future := s.Context.RequestFuture(s.CommentActor, &actors.CreateCommentMsg{
+			comment, err := s.clients(r).Comments.Create(&actors.CreateCommentMsg{
 				Content:  req.Content,
 				AuthorID: authorID,
 				PostID:   postID,
 				ParentID: parentID,
-			}, s.RequestTimeout)
-
-			result, err := future.Result()
+			})
 			if err != nil {
-				log.Printf("Error getting result from comment actor: %v", err)
-				http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+				writeActorError(w, r, err, "Failed to create comment")
 				return
 			}
 
-			log.Printf("Received result from comment actor: %+v", result)
 			w.Header().Set("Content-Type", "application/json")
-			if err := json.NewEncoder(w).Encode(result); err != nil {
-				log.Printf("Error encoding response: %v", err)
+			if err := json.NewEncoder(w).Encode(comment); err != nil {
+				slog.ErrorContext(r.Context(), "Failed to encode response", "error", err)
 				http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 				return
 			}
-			log.Printf("Successfully sent response")
 
 		case http.MethodPut:
 			// Edit comment
@@ -114,62 +103,48 @@ func (s *Server) HandleComment() http.HandlerFunc {
 				return
 			}
 
-			authorID, err := uuid.Parse(req.AuthorID)
-			if err != nil {
-				http.Error(w, "Invalid author ID", http.StatusBadRequest)
+			authorID, ok := actingUser(w, r, req.AuthorID)
+			if !ok {
 				return
 			}
 
-			future := s.Context.RequestFuture(s.CommentActor, &actors.EditCommentMsg{
+			comment, err := s.clients(r).Comments.Edit(&actors.EditCommentMsg{
 				CommentID: commentID,
 				AuthorID:  authorID,
 				Content:   req.Content,
-			}, s.RequestTimeout)
-
-			result, err := future.Result()
+			})
 			if err != nil {
-				http.Error(w, "Failed to edit comment", http.StatusInternalServerError)
+				writeActorError(w, r, err, "Failed to edit comment")
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(result)
+			json.NewEncoder(w).Encode(comment)
 
 		case http.MethodDelete:
 			// Delete comment
-			commentID := r.URL.Query().Get("commentId")
-			authorID := r.URL.Query().Get("authorId")
-
-			if commentID == "" || authorID == "" {
-				http.Error(w, "Missing comment ID or author ID", http.StatusBadRequest)
+			aID, ok := actingUser(w, r, r.URL.Query().Get("authorId"))
+			if !ok {
 				return
 			}
 
-			cID, err := uuid.Parse(commentID)
+			cID, err := uuid.Parse(r.URL.Query().Get("commentId"))
 			if err != nil {
 				http.Error(w, "Invalid comment ID", http.StatusBadRequest)
 				return
 			}
 
-			aID, err := uuid.Parse(authorID)
-			if err != nil {
-				http.Error(w, "Invalid author ID", http.StatusBadRequest)
-				return
-			}
-
-			future := s.Context.RequestFuture(s.CommentActor, &actors.DeleteCommentMsg{
+			result, err := s.clients(r).Comments.Delete(&actors.DeleteCommentMsg{
 				CommentID: cID,
 				AuthorID:  aID,
-			}, s.RequestTimeout)
-
-			result, err := future.Result()
+			})
 			if err != nil {
-				http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+				writeActorError(w, r, err, "Failed to delete comment")
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]bool{"success": result.(bool)})
+			json.NewEncoder(w).Encode(result)
 
 		case http.MethodGet:
 			// Get a specific comment
@@ -185,33 +160,20 @@ func (s *Server) HandleComment() http.HandlerFunc {
 				return
 			}
 
-			future := s.Context.RequestFuture(s.CommentActor, &actors.GetCommentMsg{
-				CommentID: cID,
-			}, s.RequestTimeout)
+			// Vote status is only included for authenticated users
+			requestingUserID, _ := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
 
-			result, err := future.Result()
+			comment, err := s.clients(r).Comments.Get(&actors.GetCommentMsg{
+				CommentID:        cID,
+				RequestingUserID: requestingUserID,
+			})
 			if err != nil {
-				http.Error(w, "Failed to get comment", http.StatusInternalServerError)
-				return
-			}
-
-			// Check for application errors
-			if appErr, ok := result.(*utils.AppError); ok {
-				var statusCode int
-				switch appErr.Code {
-				case utils.ErrNotFound:
-					statusCode = http.StatusNotFound
-				case utils.ErrDatabase:
-					statusCode = http.StatusInternalServerError
-				default:
-					statusCode = http.StatusInternalServerError
-				}
-				http.Error(w, appErr.Error(), statusCode)
+				writeActorError(w, r, err, "Failed to get comment")
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(result)
+			json.NewEncoder(w).Encode(dto.MaskComment(comment, s.profanityMask(r)))
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -247,44 +209,131 @@ func (s *Server) HandleGetPostComments() http.HandlerFunc {
 			// If UserIDKey is not present or not a UUID, send uuid.Nil
 			// This allows unauthenticated users to still fetch comments, but without their vote status.
 			requestingUserID = uuid.Nil
-			log.Printf("No authenticated user found or UserIDKey is not a UUID, using uuid.Nil for requestingUserID in HandleGetPostComments")
 		}
 
-		future := s.Context.RequestFuture(s.CommentActor, &actors.GetCommentsForPostMsg{
-			PostID:           pID,
-			RequestingUserID: requestingUserID, // Pass the user ID
-		}, s.RequestTimeout)
+		page, ok := parsePage(w, r, 100, 500)
+		if !ok {
+			return
+		}
 
-		result, err := future.Result()
-		if err != nil {
-			// Check if the error is an AppError and handle it specifically
-			if appErr, ok := err.(*utils.AppError); ok {
-				log.Printf("AppError fetching comments for post %s: %v", pID, appErr)
-				http.Error(w, appErr.Message, utils.AppErrorToHTTPStatus(appErr.Code))
+		// Admins may include soft-deleted comments, read straight from the database
+		include, ok := s.includeDeleted(w, r)
+		if !ok {
+			return
+		}
+		if include {
+			comments, err := s.DB.GetPostComments(database.IncludeDeleted(r.Context()), pID, requestingUserID)
+			if err != nil {
+				http.Error(w, "Failed to get comments", http.StatusInternalServerError)
 				return
 			}
-			// Generic error
-			log.Printf("Error fetching comments for post %s: %v", pID, err)
-			http.Error(w, "Failed to get comments", http.StatusInternalServerError)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(slicePage(dto.MaskComments(comments, s.profanityMask(r)), page))
 			return
 		}
 
-		// Check if the result itself is an AppError (e.g., from the actor logic, not just future.Result() error)
-		if appErr, ok := result.(*utils.AppError); ok {
-			log.Printf("AppError (from actor result) fetching comments for post %s: %v", pID, appErr)
-			http.Error(w, appErr.Message, utils.AppErrorToHTTPStatus(appErr.Code))
+		comments, err := s.clients(r).Comments.ForPost(&actors.GetCommentsForPostMsg{
+			PostID:           pID,
+			RequestingUserID: requestingUserID, // Pass the user ID
+		})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to fetch comments", "post_id", pID, "error", err)
+			writeActorError(w, r, err, "Failed to get comments")
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(result); err != nil {
-			log.Printf("Error encoding comments response for post %s: %v", pID, err)
+		if err := json.NewEncoder(w).Encode(slicePage(dto.MaskComments(comments, s.profanityMask(r)), page)); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to encode response", "post_id", pID, "error", err)
 			// Avoid writing another http.Error if headers already sent.
 			return
 		}
 	}
 }
 
+// CommentTreeNode is a comment with the replies loaded under it. When it has
+// replies that weren't loaded, MoreReplies is the cursor that loads them.
+type CommentTreeNode struct {
+	*models.Comment
+	Replies     []*CommentTreeNode `json:"replies"`
+	MoreReplies string             `json:"moreReplies,omitempty"`
+}
+
+// HandleGetCommentTree returns a post's comments as a tree: limit replies
+// per comment, depth levels deep. A node's moreReplies, or the page's
+// nextCursor, is passed back as ?after= to continue from there.
+func (s *Server) HandleGetCommentTree() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		q := r.URL.Query()
+		postID, err := uuid.Parse(q.Get("postId"))
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		limit := 20
+		if n, err := strconv.Atoi(q.Get("limit")); err == nil && n > 0 {
+			limit = min(n, 100)
+		}
+		depth := 3
+		if n, err := strconv.Atoi(q.Get("depth")); err == nil && n > 0 {
+			depth = min(n, 10)
+		}
+		var cursor threadCursor
+		if after := q.Get("after"); after != "" {
+			if cursor, err = decodeThreadCursor(after); err != nil {
+				http.Error(w, "Invalid cursor", http.StatusBadRequest)
+				return
+			}
+		}
+
+		include, ok := s.includeDeleted(w, r)
+		if !ok {
+			return
+		}
+		ctx := r.Context()
+		if include {
+			ctx = database.IncludeDeleted(ctx)
+		}
+
+		requestingUserID, _ := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		nodes, more, err := s.DB.GetCommentThread(ctx, postID, cursor.Parent, cursor.After, limit, depth, requestingUserID)
+		if err != nil {
+			writeActorError(w, r, err, "Failed to get comments")
+			return
+		}
+
+		mask := s.profanityMask(r)
+		var convert func(nodes []*models.CommentNode) []*CommentTreeNode
+		convert = func(nodes []*models.CommentNode) []*CommentTreeNode {
+			out := make([]*CommentTreeNode, len(nodes))
+			for i, node := range nodes {
+				out[i] = &CommentTreeNode{Comment: dto.MaskComment(node.Comment, mask), Replies: convert(node.Replies)}
+				if node.More {
+					var last *uuid.UUID
+					if len(node.Replies) > 0 {
+						last = &node.Replies[len(node.Replies)-1].Comment.ID
+					}
+					out[i].MoreReplies = encodeThreadCursor(&node.Comment.ID, last)
+				}
+			}
+			return out
+		}
+
+		page := &Page[*CommentTreeNode]{Items: convert(nodes), HasMore: more}
+		if more {
+			page.NextCursor = encodeThreadCursor(cursor.Parent, &nodes[len(nodes)-1].Comment.ID)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+	}
+}
+
 // HandleCommentVote handles voting on comments
 func (s *Server) HandleCommentVote() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -299,9 +348,8 @@ func (s *Server) HandleCommentVote() http.HandlerFunc {
 			return
 		}
 
-		userID, err := uuid.Parse(req.UserID)
-		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		userID, ok := actingUser(w, r, req.UserID)
+		if !ok {
 			return
 		}
 
@@ -312,46 +360,24 @@ func (s *Server) HandleCommentVote() http.HandlerFunc {
 		}
 
 		// Send the message to the CommentActor
-		future := s.Context.RequestFuture(s.CommentActor, &actors.VoteCommentMsg{
+		result, err := s.clients(r).Comments.Vote(&actors.VoteCommentMsg{
 			CommentID:  commentID,
 			UserID:     userID,
 			IsUpvote:   req.IsUpvote,
 			RemoveVote: req.RemoveVote, // Include RemoveVote
-		}, s.RequestTimeout)
-
-		result, err := future.Result()
+		})
 		if err != nil {
 			// Basic error handling for actor communication failure
-			log.Printf("Error requesting comment vote from actor: %v", err)
-			http.Error(w, "Failed to process vote", http.StatusInternalServerError)
-			return
-		}
-
-		// Check if the result itself is an AppError from the actor
-		if appErr, ok := result.(*utils.AppError); ok {
-			var statusCode int
-			switch appErr.Code {
-			case utils.ErrNotFound:
-				statusCode = http.StatusNotFound
-			case utils.ErrDuplicate:
-				statusCode = http.StatusConflict
-			case utils.ErrDatabase:
-				statusCode = http.StatusInternalServerError
-			default:
-				statusCode = http.StatusInternalServerError
-			}
-			http.Error(w, appErr.Error(), statusCode)
+			writeActorError(w, r, err, "Failed to process vote")
 			return
 		}
 
-		// Check if the result indicates success (assuming actor responds with {Success: true})
-		if successResp, ok := result.(*struct{ Success bool }); ok && successResp.Success {
+		if result.Success {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
 			json.NewEncoder(w).Encode(map[string]bool{"success": true})
 		} else {
-			// Handle unexpected result type or failure indication
-			log.Printf("Unexpected result from CommentActor vote: %T - %v", result, result)
+			slog.ErrorContext(r.Context(), "Unexpected comment vote result", "result", result)
 			http.Error(w, "Unexpected result from vote processing", http.StatusInternalServerError)
 		}
 	}
@@ -3,33 +3,67 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/diff"
+	"gator-swamp/internal/dto"
 	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/jobs"
+	"gator-swamp/internal/media"
 	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
 	"gator-swamp/internal/utils"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
 // CreatePostRequest represents a request to create a new post
 type CreatePostRequest struct {
-	Title       string `json:"title"`       // Post title
-	Content     string `json:"content"`     // Post content
-	AuthorID    string `json:"authorId"`    // Author ID (UUID as string)
-	SubredditID string `json:"subredditId"` // Subreddit ID (UUID as string)
+	Title       string `json:"title"`              // Post title
+	Content     string `json:"content"`            // Post content
+	AuthorID    string `json:"authorId,omitempty"` // Deprecated: the author is the authenticated user
+	SubredditID string `json:"subredditId"`        // Subreddit ID (UUID as string)
+	URL         string `json:"url"`                // Optional link or image URL (http/https)
+	FlairID     string `json:"flairId,omitempty"`  // Optional, one of the subreddit's flairs
+	MediaID     string `json:"mediaId,omitempty"`  // Optional, an image uploaded to /upload; not with URL
+	models.PostMetadata
+}
+
+// EditPostRequest replaces the title and content of the caller's post
+type EditPostRequest struct {
+	PostID  string `json:"postId"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
 }
 
 // VoteRequest represents a request to vote on a post
 type VoteRequest struct {
-	UserID     string `json:"userId"`
+	UserID     string `json:"userId,omitempty"` // Deprecated: the voter is the authenticated user
 	PostID     string `json:"postId"`
 	IsUpvote   bool   `json:"isUpvote"`
 	RemoveVote bool   `json:"removeVote"` // New field to support vote toggling
 }
 
+// PostMetadataRequest replaces the metadata of the caller's post
+type PostMetadataRequest struct {
+	PostID string `json:"postId"`
+	models.PostMetadata
+}
+
+// LockPostRequest closes or reopens the caller's post to new comments
+type LockPostRequest struct {
+	PostID string `json:"postId"`
+	Locked bool   `json:"locked"`
+}
+
 // HandleHealth handles health check requests
 func (s *Server) HandleHealth() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -40,22 +74,18 @@ func (s *Server) HandleHealth() http.HandlerFunc {
 		}
 
 		// Get the subreddit count from SubredditActor
-		futureSubreddits := s.Context.RequestFuture(s.Engine.GetSubredditActor(), &actors.GetCountsMsg{}, s.RequestTimeout)
-		subredditResult, err := futureSubreddits.Result()
+		subredditCount, err := s.clients(r).Subreddits.Count()
 		if err != nil {
 			http.Error(w, "Failed to get subreddit count", http.StatusInternalServerError)
 			return
 		}
-		subredditCount := subredditResult.(int) // Parse the result
 
 		// Get the post count from PostActor
-		futurePosts := s.Context.RequestFuture(s.Engine.GetPostActor(), &actors.GetCountsMsg{}, s.RequestTimeout)
-		postResult, err := futurePosts.Result()
+		postCount, err := s.clients(r).Posts.Count()
 		if err != nil {
 			http.Error(w, "Failed to get post count", http.StatusInternalServerError)
 			return
 		}
-		postCount := postResult.(int) // Parse the result
 
 		// Respond with the subreddit and post counts
 		w.Header().Set("Content-Type", "application/json")
@@ -91,9 +121,8 @@ func (s *Server) HandlePost() http.HandlerFunc {
 				return
 			}
 
-			authorID, err := uuid.Parse(req.AuthorID)
-			if err != nil {
-				http.Error(w, "Invalid author ID format", http.StatusBadRequest)
+			authorID, ok := actingUser(w, r, req.AuthorID)
+			if !ok {
 				return
 			}
 
@@ -103,40 +132,62 @@ func (s *Server) HandlePost() http.HandlerFunc {
 				return
 			}
 
-			future := s.Context.RequestFuture(s.EnginePID, &actors.CreatePostMsg{
+			if req.URL != "" {
+				u, err := url.Parse(req.URL)
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(req.URL) > 2048 {
+					http.Error(w, "Invalid URL: must be an absolute http or https URL", http.StatusBadRequest)
+					return
+				}
+			}
+			if err := req.PostMetadata.Normalize(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var flairID *uuid.UUID
+			if req.FlairID != "" {
+				id, err := uuid.Parse(req.FlairID)
+				if err != nil {
+					http.Error(w, "Invalid flair ID format", http.StatusBadRequest)
+					return
+				}
+				flairID = &id
+			}
+			var mediaID *uuid.UUID
+			if req.MediaID != "" {
+				if req.URL != "" {
+					http.Error(w, "A post can't have both a URL and media", http.StatusBadRequest)
+					return
+				}
+				id, err := uuid.Parse(req.MediaID)
+				if err != nil {
+					http.Error(w, "Invalid media ID format", http.StatusBadRequest)
+					return
+				}
+				mediaID = &id
+			}
+
+			post, err := s.clients(r).Posts.Create(&actors.CreatePostMsg{
 				Title:       req.Title,
 				Content:     req.Content,
 				AuthorID:    authorID,
 				SubredditID: subredditID,
-			}, s.RequestTimeout)
-
-			result, err := future.Result()
+				URL:         req.URL,
+				FlairID:     flairID,
+				MediaID:     mediaID,
+				Metadata:    req.PostMetadata,
+			})
 			if err != nil {
-				http.Error(w, fmt.Sprintf("Failed to create post: %v", err), http.StatusInternalServerError)
+				writeActorError(w, r, err, "Failed to create post")
 				return
 			}
 
-			// Check for application errors
-			if appErr, ok := result.(*utils.AppError); ok {
-				var statusCode int
-				switch appErr.Code {
-				case utils.ErrNotFound:
-					statusCode = http.StatusNotFound
-				case utils.ErrDatabase:
-					statusCode = http.StatusInternalServerError
-				case utils.ErrInvalidInput:
-					statusCode = http.StatusBadRequest
-				case utils.ErrUnauthorized:
-					statusCode = http.StatusUnauthorized
-				default:
-					statusCode = http.StatusInternalServerError
-				}
-				http.Error(w, appErr.Error(), statusCode)
-				return
+			// Posts held for approval get theirs once approved
+			if !post.Pending {
+				s.enqueueThumbnail(r, post)
 			}
 
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(result)
+			json.NewEncoder(w).Encode(s.showPost(r, post))
 
 		case http.MethodGet:
 			// Get post by ID or get posts from a subreddit
@@ -159,50 +210,49 @@ func (s *Server) HandlePost() http.HandlerFunc {
 					// Assert the type to uuid.UUID directly, as stored by SetUserIDInContext
 					parsedID, ok := userIDClaim.(uuid.UUID)
 					if !ok {
-						log.Printf("HandlePost GET: Invalid user ID type in token context key. Expected uuid.UUID, got %T", userIDClaim)
+						slog.WarnContext(r.Context(), "Invalid user ID type in request context", "type", fmt.Sprintf("%T", userIDClaim))
 						// Depending on policy, might treat as anonymous or return error
 						// http.Error(w, "Invalid user ID type in token", http.StatusInternalServerError)
 						// return
 					} else {
 						requestingUserID = parsedID
 					}
-				} else {
-					// User is likely not logged in, requestingUserID remains uuid.Nil
-					log.Printf("HandlePost GET: No user ID found in context (user likely not authenticated)")
 				}
+				// Without one the user is likely not logged in, and requestingUserID remains uuid.Nil
 				// ---- End: Extract UserID from JWT ----
 
-				// Send message to actor including requesting user ID
-				future := s.Context.RequestFuture(s.Engine.GetPostActor(),
-					&actors.GetPostMsg{
-						PostID:           id,
-						RequestingUserID: requestingUserID, // Pass the extracted/parsed user ID
-					},
-					s.RequestTimeout)
-
-				result, err := future.Result()
-				if err != nil {
-					http.Error(w, fmt.Sprintf("Failed to get post: %v", err), http.StatusInternalServerError)
+				// Admins may ask for a soft-deleted post, read straight from the database
+				include, ok := s.includeDeleted(w, r)
+				if !ok {
 					return
 				}
-
-				// Check for application errors
-				if appErr, ok := result.(*utils.AppError); ok {
-					var statusCode int
-					switch appErr.Code {
-					case utils.ErrNotFound:
-						statusCode = http.StatusNotFound
-					case utils.ErrDatabase:
-						statusCode = http.StatusInternalServerError
-					default:
-						statusCode = http.StatusInternalServerError
+				if include {
+					post, err := s.DB.GetPost(database.IncludeDeleted(r.Context()), id, requestingUserID)
+					if err != nil {
+						if appErr, ok := err.(*utils.AppError); ok {
+							i18n.WriteError(w, r, appErr)
+							return
+						}
+						http.Error(w, "Failed to get post", http.StatusInternalServerError)
+						return
 					}
-					http.Error(w, appErr.Error(), statusCode)
+					w.Header().Set("Content-Type", "application/json")
+					json.NewEncoder(w).Encode(s.showPost(r, post))
+					return
+				}
+
+				// Send message to actor including requesting user ID
+				post, err := s.clients(r).Posts.Get(&actors.GetPostMsg{
+					PostID:           id,
+					RequestingUserID: requestingUserID, // Pass the extracted/parsed user ID
+				})
+				if err != nil {
+					writeActorError(w, r, err, "Failed to get post")
 					return
 				}
 
 				w.Header().Set("Content-Type", "application/json")
-				json.NewEncoder(w).Encode(result)
+				json.NewEncoder(w).Encode(s.showPost(r, post))
 				return
 			}
 
@@ -214,29 +264,111 @@ func (s *Server) HandlePost() http.HandlerFunc {
 					return
 				}
 
-				future := s.Context.RequestFuture(s.Engine.GetPostActor(),
-					&actors.GetSubredditPostsMsg{SubredditID: id},
-					s.RequestTimeout)
+				page, ok := parsePage(w, r, 50, 100)
+				if !ok {
+					return
+				}
+				var flairID *uuid.UUID
+				if raw := r.URL.Query().Get("flair"); raw != "" {
+					parsed, err := uuid.Parse(raw)
+					if err != nil {
+						http.Error(w, "Invalid flair ID format", http.StatusBadRequest)
+						return
+					}
+					flairID = &parsed
+				}
 
-				result, err := future.Result()
+				requestingUserID, _ := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+				posts, err := s.clients(r).Posts.SubredditPosts(
+					&actors.GetSubredditPostsMsg{SubredditID: id, FlairID: flairID, Limit: page.Limit, After: page.After, RequestingUserID: requestingUserID})
 				if err != nil {
-					http.Error(w, fmt.Sprintf("Failed to get subreddit posts: %v", err), http.StatusInternalServerError)
+					writeActorError(w, r, err, "Failed to get subreddit posts")
 					return
 				}
 
 				w.Header().Set("Content-Type", "application/json")
-				json.NewEncoder(w).Encode(result)
+				json.NewEncoder(w).Encode(newKeysetPage(s.showPosts(r, posts), page, (*models.Post).Keyset))
 				return
 			}
 
 			http.Error(w, "Either post ID or subreddit ID is required", http.StatusBadRequest)
 
+		case http.MethodPut:
+			// Edit own post's title and content
+			userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			var req EditPostRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request", http.StatusBadRequest)
+				return
+			}
+			postID, err := uuid.Parse(req.PostID)
+			if err != nil {
+				http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+				return
+			}
+			req.Title = strings.TrimSpace(req.Title)
+			if req.Title == "" || utf8.RuneCountInString(req.Title) > 300 {
+				http.Error(w, "Title must be 1-300 characters", http.StatusBadRequest)
+				return
+			}
+
+			post, err := s.clients(r).Posts.Edit(
+				&actors.EditPostMsg{PostID: postID, UserID: userID, Title: req.Title, Content: req.Content})
+			if err != nil {
+				writeActorError(w, r, err, "Failed to edit post")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(s.showPost(r, post))
+
+		case http.MethodDelete:
+			// Soft-delete own post; admins remove others' posts via /admin/content
+			userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			id, err := uuid.Parse(r.URL.Query().Get("id"))
+			if err != nil {
+				http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+				return
+			}
+
+			result, err := s.clients(r).Posts.Delete(&actors.DeletePostMsg{PostID: id, UserID: userID})
+			if err != nil {
+				writeActorError(w, r, err, "Failed to delete post")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(result)
+
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}
 }
 
+// enqueueThumbnail has a link post's preview thumbnail generated in the
+// background.
+func (s *Server) enqueueThumbnail(r *http.Request, post *models.Post) {
+	if post.URL == nil {
+		return
+	}
+	err := s.Jobs.Enqueue(r.Context(), media.TypePostThumbnail, media.ThumbnailPayload{
+		PostID: post.ID,
+		URL:    *post.URL,
+	}, jobs.MaxAttempts(3))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to enqueue thumbnail", "post_id", post.ID, "error", err)
+	}
+}
+
 // HandleVote handles post voting
 func (s *Server) HandleVote() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -251,9 +383,8 @@ func (s *Server) HandleVote() http.HandlerFunc {
 			return
 		}
 
-		userID, err := uuid.Parse(req.UserID)
-		if err != nil {
-			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		userID, ok := actingUser(w, r, req.UserID)
+		if !ok {
 			return
 		}
 
@@ -263,41 +394,183 @@ func (s *Server) HandleVote() http.HandlerFunc {
 			return
 		}
 
-		future := s.Context.RequestFuture(s.EnginePID, &actors.VotePostMsg{
+		result, err := s.clients(r).Posts.Vote(&actors.VotePostMsg{
 			PostID:     postID,
 			UserID:     userID,
 			IsUpvote:   req.IsUpvote,
 			RemoveVote: req.RemoveVote, // Pass the RemoveVote parameter
-		}, s.RequestTimeout)
+		})
+		if err != nil {
+			writeActorError(w, r, err, "Failed to process vote")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandlePostMetadata lets a post's author set its original content flag,
+// source attribution and license
+func (s *Server) HandlePostMetadata() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
 
-		result, err := future.Result()
+		var req PostMetadataRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		postID, err := uuid.Parse(req.PostID)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to process vote: %v", err), http.StatusInternalServerError)
-			return
-		}
-
-		// Check for application errors
-		if appErr, ok := result.(*utils.AppError); ok {
-			var statusCode int
-			switch appErr.Code {
-			case utils.ErrNotFound:
-				statusCode = http.StatusNotFound
-			case utils.ErrUnauthorized:
-				statusCode = http.StatusUnauthorized
-			case utils.ErrDuplicate:
-				statusCode = http.StatusConflict
-			default:
-				statusCode = http.StatusInternalServerError
-			}
-			http.Error(w, appErr.Error(), statusCode)
+			http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+			return
+		}
+		if err := req.PostMetadata.Normalize(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		post, err := s.clients(r).Posts.UpdateMetadata(
+			&actors.UpdatePostMetadataMsg{PostID: postID, UserID: userID, Metadata: req.PostMetadata})
+		if err != nil {
+			writeActorError(w, r, err, "Failed to update post metadata")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.showPost(r, post))
+	}
+}
+
+// HandlePostLock lets a post's author lock it to new comments, or unlock it
+func (s *Server) HandlePostLock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req LockPostRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		postID, err := uuid.Parse(req.PostID)
+		if err != nil {
+			http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+			return
+		}
+
+		post, err := s.clients(r).Posts.Lock(&actors.LockPostMsg{PostID: postID, UserID: userID, Locked: req.Locked})
+		if err != nil {
+			writeActorError(w, r, err, "Failed to lock post")
 			return
 		}
 
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.showPost(r, post))
+	}
+}
+
+// PostVersion is a post's title and content as one edit left them. The diffs
+// are against the version before, and absent on the first.
+type PostVersion struct {
+	Title       string       `json:"title"`
+	Content     string       `json:"content"`
+	WrittenAt   time.Time    `json:"writtenAt"`
+	Current     bool         `json:"current,omitempty"`
+	TitleDiff   []diff.Chunk `json:"titleDiff,omitempty"`
+	ContentDiff []diff.Chunk `json:"contentDiff,omitempty"`
+}
+
+// HandlePostHistory returns a post's versions, newest first, with what each
+// edit changed
+func (s *Server) HandlePostHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		postID, err := uuid.Parse(r.URL.Query().Get("postId"))
+		if err != nil {
+			http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+			return
+		}
+		page, ok := parsePage(w, r, 20, 100)
+		if !ok {
+			return
+		}
+
+		requestingUserID, _ := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		post, err := s.clients(r).Posts.Get(&actors.GetPostMsg{PostID: postID, RequestingUserID: requestingUserID})
+		if err != nil {
+			writeActorError(w, r, err, "Failed to get post")
+			return
+		}
+		revisions, err := s.DB.GetPostRevisions(r.Context(), postID)
+		if err != nil {
+			writeActorError(w, r, err, "Failed to get post history")
+			return
+		}
+
+		mask := s.profanityMask(r)
+		text := func(t string) string {
+			if mask == nil {
+				return t
+			}
+			return mask(post.SubredditID, t)
+		}
+		current := &PostVersion{Title: text(post.Title), Content: text(post.Content), WrittenAt: post.CreatedAt, Current: true}
+		if post.EditedAt != nil {
+			current.WrittenAt = *post.EditedAt
+		}
+		versions := []*PostVersion{current}
+		for i := len(revisions) - 1; i >= 0; i-- {
+			rev := revisions[i]
+			versions = append(versions, &PostVersion{Title: text(rev.Title), Content: text(rev.Content), WrittenAt: rev.WrittenAt})
+		}
+
+		// Only the page's versions are diffed
+		result := slicePage(versions, page)
+		for i, v := range result.Items {
+			if prev := page.Offset + i + 1; prev < len(versions) {
+				v.TitleDiff = diff.Words(versions[prev].Title, v.Title)
+				v.ContentDiff = diff.Words(versions[prev].Content, v.Content)
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(result)
 	}
 }
 
+// parsePostSort reads the sort query parameter of a post listing, defaulting
+// to newest first.
+func parsePostSort(r *http.Request) (string, bool) {
+	sortOrder := r.URL.Query().Get("sort")
+	if sortOrder == "" {
+		return models.SortNew, true
+	}
+	return sortOrder, slices.Contains(models.PostSorts, sortOrder)
+}
+
 // HandleRecentPosts returns the most recent posts across all subreddits
 func (s *Server) HandleRecentPosts() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -306,42 +579,92 @@ func (s *Server) HandleRecentPosts() http.HandlerFunc {
 			return
 		}
 
-		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
-		if limit <= 0 {
-			limit = 20 // Default limit
+		page, ok := parsePage(w, r, 20, 100)
+		if !ok {
+			return
 		}
-
-		// Extract requesting user ID from context
-		requestingUserID := uuid.Nil // Default to Nil if no user is authenticated
-		if userIDStr, ok := r.Context().Value(middleware.UserIDKey).(string); ok {
-			if parsedUUID, err := uuid.Parse(userIDStr); err == nil {
-				requestingUserID = parsedUUID
-			} else {
-				log.Printf("HandleRecentPosts: Error parsing UserID from context: %v", err)
-			}
+		sortOrder, ok := parsePostSort(r)
+		if !ok {
+			http.Error(w, "Invalid sort, expected new, hot, top, controversial or rising", http.StatusBadRequest)
+			return
 		}
 
-		// Send message to PostActor
-		future := s.Context.RequestFuture(s.Engine.GetPostActor(), &actors.GetRecentPostsMsg{
-			Limit:            limit,
-			Offset:           offset,
+		// Votes and NSFW opt-ins are the requesting user's; Nil if unauthenticated
+		requestingUserID, _ := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+
+		// Newest first pages by keyset; the other sorts have no stable key
+		msg := &actors.GetRecentPostsMsg{
+			Limit:            page.Limit,
+			Offset:           page.Offset,
 			RequestingUserID: requestingUserID, // Pass the user ID
-		}, s.RequestTimeout)
+			Sort:             sortOrder,
+		}
+		if sortOrder == models.SortNew {
+			msg.After = page.After
+		}
+		posts, err := s.clients(r).Posts.Recent(msg)
+		if err != nil {
+			writeActorError(w, r, err, "Failed to fetch recent posts")
+			return
+		}
 
-		result, err := future.Result()
+		w.Header().Set("Content-Type", "application/json")
+		if sortOrder == models.SortNew {
+			json.NewEncoder(w).Encode(newKeysetPage(s.showPosts(r, posts), page, (*models.Post).Keyset))
+			return
+		}
+		json.NewEncoder(w).Encode(newPage(s.showPosts(r, posts), page))
+	}
+}
+
+// HandlePostFull returns a post together with the first page of its comments
+func (s *Server) HandlePostFull() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		postID, err := uuid.Parse(r.URL.Query().Get("id"))
 		if err != nil {
-			http.Error(w, "Failed to fetch recent posts", http.StatusInternalServerError)
+			http.Error(w, "Invalid post ID format", http.StatusBadRequest)
 			return
 		}
 
-		// Check for application errors
-		if appErr, ok := result.(*utils.AppError); ok {
-			http.Error(w, appErr.Error(), http.StatusInternalServerError)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit <= 0 {
+			limit = 20 // Default comment page size
+		}
+		if limit > 100 {
+			limit = 100
+		}
+
+		sortOrder := r.URL.Query().Get("sort")
+		switch sortOrder {
+		case "":
+			sortOrder = "top"
+		case "top", "new", "old":
+		default:
+			http.Error(w, "Invalid sort, expected top, new or old", http.StatusBadRequest)
+			return
+		}
+
+		requestingUserID, _ := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+
+		full, err := s.clients(r).Posts.GetWithComments(&actors.GetPostWithCommentsMsg{
+			PostID:           postID,
+			RequestingUserID: requestingUserID,
+			CommentLimit:     limit,
+			CommentSort:      sortOrder,
+		})
+		if err != nil {
+			writeActorError(w, r, err, "Failed to get post")
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(result)
+		shown := *dto.MaskPostWithComments(full, s.profanityMask(r))
+		shown.Post = s.linkMedia(dto.ScorePost(shown.Post, s.ScoreFuzzAge))
+		json.NewEncoder(w).Encode(&shown)
 	}
 }
@@ -1,13 +1,28 @@
 package handlers
 
 import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gator-swamp/internal/actorcall"
+	"gator-swamp/internal/actorclient"
 	"gator-swamp/internal/database"
+	"gator-swamp/internal/dto"
 	"gator-swamp/internal/engine"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/jobs"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/policy"
+	"gator-swamp/internal/presence"
+	"gator-swamp/internal/profanity"
+	"gator-swamp/internal/storage"
 	"gator-swamp/internal/utils"
 	"gator-swamp/internal/websocket"
-	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
 )
 
 // Server holds all server dependencies, including the actor system and engine
@@ -19,12 +34,21 @@ type Server struct {
 	Metrics            *utils.MetricsCollector
 	CommentActor       *actor.PID
 	DirectMessageActor *actor.PID
-	DB                 database.DBAdapter
-	RequestTimeout     time.Duration
+	SearchActor        *actor.PID
+	DB                 database.Store
+	ActorCalls         *actorcall.Policies
+	Actors             *actorclient.Clients
 	Hub                *websocket.Hub
 	PostActor          *actor.PID
 	SubredditActor     *actor.PID
 	UserSupervisor     *actor.PID
+	Storage            storage.Storage
+	Jobs               *jobs.Queue
+	Presence           *presence.Tracker
+	Profanity          *profanity.Filter       // Nil unless words to mask are configured
+	ScoreFuzzAge       time.Duration           // Posts younger than this show fuzzed vote counts
+	Policies           policy.Policies         // For handlers whose operation depends on the request
+	RateLimits         *middleware.RateLimiter // Nil unless rate limiting is enabled
 }
 
 // NewServer creates a new Server instance with the given components
@@ -36,12 +60,16 @@ func NewServer(
 	metrics *utils.MetricsCollector,
 	commentActor *actor.PID,
 	directMessageActor *actor.PID,
-	db database.DBAdapter,
+	searchActor *actor.PID,
+	db database.Store,
 	hub *websocket.Hub,
 	postActor *actor.PID,
 	subredditActor *actor.PID,
 	userSupervisor *actor.PID,
-	timeout time.Duration,
+	store storage.Storage,
+	jobQueue *jobs.Queue,
+	tracker *presence.Tracker,
+	actorCalls *actorcall.Policies,
 ) *Server {
 	return &Server{
 		System:             system,
@@ -51,11 +79,120 @@ func NewServer(
 		Metrics:            metrics,
 		CommentActor:       commentActor,
 		DirectMessageActor: directMessageActor,
+		SearchActor:        searchActor,
 		DB:                 db,
-		RequestTimeout:     timeout,
-		Hub:                hub,
-		PostActor:          postActor,
-		SubredditActor:     subredditActor,
-		UserSupervisor:     userSupervisor,
+		ActorCalls:         actorCalls,
+		Actors: actorclient.New(context, actorCalls, actorclient.PIDs{
+			Engine:         enginePID,
+			Post:           postActor,
+			Comment:        commentActor,
+			Subreddit:      subredditActor,
+			UserSupervisor: userSupervisor,
+			DirectMessage:  directMessageActor,
+			Search:         searchActor,
+		}),
+		Hub:            hub,
+		PostActor:      postActor,
+		SubredditActor: subredditActor,
+		UserSupervisor: userSupervisor,
+		Storage:        store,
+		Jobs:           jobQueue,
+		Presence:       tracker,
+	}
+}
+
+// writeActorError responds to a failed actor call with its AppError, or
+// with fallback for any other error.
+func writeActorError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	if appErr, ok := err.(*utils.AppError); ok {
+		i18n.WriteError(w, r, appErr)
+		return
+	}
+	http.Error(w, fallback, http.StatusInternalServerError)
+}
+
+// actingUser returns the authenticated user a request acts as. Older clients
+// still send their own ID in the body or query (claimed); it's optional, but
+// any other user's ID is rejected with 403 rather than acted on.
+func actingUser(w http.ResponseWriter, r *http.Request, claimed string) (uuid.UUID, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+	if claimed != "" {
+		if id, err := uuid.Parse(claimed); err != nil || id != userID {
+			http.Error(w, "Requests can only act as the authenticated user", http.StatusForbidden)
+			return uuid.Nil, false
+		}
+	}
+	return userID, true
+}
+
+// profanityMask returns the mask for a response to r, or nil if nothing
+// should be masked: no words are configured or the user turned masking off.
+// Subreddits that turned it off are left alone. Lookups fail open, showing
+// text unmasked rather than failing the request.
+func (s *Server) profanityMask(r *http.Request) dto.TextMask {
+	if s.Profanity == nil {
+		return nil
 	}
+	if userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID); ok {
+		prefs, err := s.DB.GetUserPreferences(r.Context(), userID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to get preferences for profanity masking", "user_id", userID, "error", err)
+			return nil
+		}
+		if !prefs.MaskProfanity {
+			return nil
+		}
+	}
+
+	masked := make(map[uuid.UUID]bool)
+	return func(subredditID uuid.UUID, text string) string {
+		mask, ok := masked[subredditID]
+		if !ok {
+			settings, err := s.DB.GetSubredditSettings(r.Context(), subredditID)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Failed to get subreddit settings for profanity masking", "subreddit_id", subredditID, "error", err)
+			}
+			mask = err == nil && settings.MaskProfanity
+			masked[subredditID] = mask
+		}
+		if !mask {
+			return text
+		}
+		return s.Profanity.Mask(text)
+	}
+}
+
+// showPost prepares a post for a response to r: masked for its viewer, with
+// its upvote ratio, display score and media URL.
+func (s *Server) showPost(r *http.Request, post *models.Post) *models.Post {
+	return s.linkMedia(dto.ScorePost(dto.MaskPost(post, s.profanityMask(r)), s.ScoreFuzzAge))
+}
+
+// showPosts applies showPost to each post.
+func (s *Server) showPosts(r *http.Request, posts []*models.Post) []*models.Post {
+	shown := dto.ScorePosts(dto.MaskPosts(posts, s.profanityMask(r)), s.ScoreFuzzAge)
+	for _, post := range shown {
+		s.linkMedia(post)
+	}
+	return shown
+}
+
+// linkMedia sets the URL of a post's media. The post must be a copy, such as
+// ScorePost returns, as cached posts are shared.
+func (s *Server) linkMedia(post *models.Post) *models.Post {
+	if post != nil && post.MediaKey != nil {
+		url := s.Storage.URL(*post.MediaKey)
+		post.MediaURL = &url
+	}
+	return post
+}
+
+// clients returns the actor clients for r, whose messages carry its
+// request ID.
+func (s *Server) clients(r *http.Request) *actorclient.Clients {
+	return s.Actors.For(r.Context())
 }
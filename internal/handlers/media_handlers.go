@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gator-swamp/internal/media"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// maxUploadBytes caps images uploaded to /upload
+const maxUploadBytes = 10 << 20
+
+// HandleMediaUpload stores a multipart "file" image for the current user and
+// records it, so posts and avatars can refer to it by ID.
+func (s *Server) HandleMediaUpload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes+1<<10) // Allow for multipart framing
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Image required (multipart field \"file\", max 10MB)", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, maxUploadBytes+1))
+		if err != nil {
+			http.Error(w, "Failed to read upload", http.StatusBadRequest)
+			return
+		}
+		if len(data) > maxUploadBytes {
+			http.Error(w, "Image exceeds 10MB", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		format, cfg, err := media.InspectImage(data)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid image: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		upload := &models.Media{
+			ID:          uuid.New(),
+			OwnerID:     userID,
+			ContentType: media.ContentType(format),
+			Size:        len(data),
+			Width:       cfg.Width,
+			Height:      cfg.Height,
+		}
+		upload.Key = media.UploadKey(userID, upload.ID, format)
+		if err := s.Storage.Put(r.Context(), upload.Key, bytes.NewReader(data), upload.ContentType); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to store upload", "user_id", userID, "error", err)
+			http.Error(w, "Failed to store image", http.StatusInternalServerError)
+			return
+		}
+		if err := s.DB.CreateMedia(r.Context(), upload); err != nil {
+			s.Storage.Delete(r.Context(), upload.Key)
+			writeActorError(w, r, err, "Failed to record image")
+			return
+		}
+		upload.URL = s.Storage.URL(upload.Key)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(upload)
+	}
+}
+
+// ownedMedia reads an upload of the current user's, for reuse e.g. as an
+// avatar. Others' uploads get 404, like missing ones.
+func (s *Server) ownedMedia(w http.ResponseWriter, r *http.Request, userID, mediaID uuid.UUID) ([]byte, bool) {
+	upload, err := s.DB.GetMedia(r.Context(), mediaID)
+	if err == nil && upload.OwnerID != userID {
+		err = utils.NewAppError(utils.ErrNotFound, "Media not found", nil)
+	}
+	if err != nil {
+		writeActorError(w, r, err, "Failed to get media")
+		return nil, false
+	}
+
+	file, err := s.Storage.Get(r.Context(), upload.Key)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to read upload", "key", upload.Key, "error", err)
+		http.Error(w, "Failed to read media", http.StatusInternalServerError)
+		return nil, false
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to read upload", "key", upload.Key, "error", err)
+		http.Error(w, "Failed to read media", http.StatusInternalServerError)
+		return nil, false
+	}
+	return data, true
+}
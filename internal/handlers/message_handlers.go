@@ -11,7 +11,7 @@ import (
 
 // SendMessageRequest represents a request to send a direct message
 type SendMessageRequest struct {
-	FromID  string `json:"fromId"`
+	FromID  string `json:"fromId,omitempty"` // Deprecated: the sender is the authenticated user
 	ToID    string `json:"toId"`
 	Content string `json:"content"`
 }
@@ -28,9 +28,8 @@ func (s *Server) HandleDirectMessages() http.HandlerFunc {
 				return
 			}
 
-			fromID, err := uuid.Parse(req.FromID)
-			if err != nil {
-				http.Error(w, "Invalid sender ID", http.StatusBadRequest)
+			fromID, ok := actingUser(w, r, req.FromID)
+			if !ok {
 				return
 			}
 
@@ -46,77 +45,62 @@ func (s *Server) HandleDirectMessages() http.HandlerFunc {
 				Content: req.Content,
 			}
 
-			future := s.Context.RequestFuture(s.DirectMessageActor, msg, s.RequestTimeout)
-			result, err := future.Result()
+			message, err := s.clients(r).Messages.Send(msg)
 			if err != nil {
-				http.Error(w, "Failed to send message", http.StatusInternalServerError)
+				writeActorError(w, r, err, "Failed to send message")
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(result)
+			json.NewEncoder(w).Encode(message)
 
 		case http.MethodGet:
 			// Get messages for a user
-			userID := r.URL.Query().Get("userId")
-			if userID == "" {
-				http.Error(w, "User ID required", http.StatusBadRequest)
+			parsedID, ok := actingUser(w, r, r.URL.Query().Get("userId"))
+			if !ok {
 				return
 			}
-
-			parsedID, err := uuid.Parse(userID)
-			if err != nil {
-				http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			page, ok := parsePage(w, r, 50, 200)
+			if !ok {
 				return
 			}
 
 			msg := &actors.GetUserMessagesMsg{UserID: parsedID}
-			future := s.Context.RequestFuture(s.DirectMessageActor, msg, s.RequestTimeout)
-			result, err := future.Result()
+			messages, err := s.clients(r).Messages.ForUser(msg)
 			if err != nil {
-				http.Error(w, "Failed to get messages", http.StatusInternalServerError)
+				writeActorError(w, r, err, "Failed to get messages")
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(result)
+			json.NewEncoder(w).Encode(slicePage(messages, page))
 
 		case http.MethodDelete:
 			// Delete a message
-			messageID := r.URL.Query().Get("messageId")
-			userID := r.URL.Query().Get("userId")
-
-			if messageID == "" || userID == "" {
-				http.Error(w, "Message ID and User ID required", http.StatusBadRequest)
+			parsedUserID, ok := actingUser(w, r, r.URL.Query().Get("userId"))
+			if !ok {
 				return
 			}
 
-			parsedMessageID, err := uuid.Parse(messageID)
+			parsedMessageID, err := uuid.Parse(r.URL.Query().Get("messageId"))
 			if err != nil {
 				http.Error(w, "Invalid message ID", http.StatusBadRequest)
 				return
 			}
 
-			parsedUserID, err := uuid.Parse(userID)
-			if err != nil {
-				http.Error(w, "Invalid user ID", http.StatusBadRequest)
-				return
-			}
-
 			msg := &actors.DeleteMessageMsg{
 				MessageID: parsedMessageID,
 				UserID:    parsedUserID,
 			}
 
-			future := s.Context.RequestFuture(s.DirectMessageActor, msg, s.RequestTimeout)
-			result, err := future.Result()
+			deleted, err := s.clients(r).Messages.Delete(msg)
 			if err != nil {
-				http.Error(w, "Failed to delete message", http.StatusInternalServerError)
+				writeActorError(w, r, err, "Failed to delete message")
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]bool{"success": result.(bool)})
+			json.NewEncoder(w).Encode(map[string]bool{"success": deleted})
 
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -132,40 +116,35 @@ func (s *Server) HandleConversation() http.HandlerFunc {
 			return
 		}
 
-		userID := r.URL.Query().Get("userId")
-		otherID := r.URL.Query().Get("otherUserId")
-
-		if userID == "" || otherID == "" {
-			http.Error(w, "Both user IDs required", http.StatusBadRequest)
+		parsedUserID, ok := actingUser(w, r, r.URL.Query().Get("userId"))
+		if !ok {
 			return
 		}
 
-		parsedUserID, err := uuid.Parse(userID)
+		parsedOtherID, err := uuid.Parse(r.URL.Query().Get("otherUserId"))
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			http.Error(w, "Invalid other user ID", http.StatusBadRequest)
 			return
 		}
-
-		parsedOtherID, err := uuid.Parse(otherID)
-		if err != nil {
-			http.Error(w, "Invalid other user ID", http.StatusBadRequest)
+		page, ok := parsePage(w, r, 50, 200)
+		if !ok {
 			return
 		}
 
 		msg := &actors.GetConversationMsg{
-			UserID1: parsedUserID,
-			UserID2: parsedOtherID,
+			UserID1:          parsedUserID,
+			UserID2:          parsedOtherID,
+			RequestingUserID: parsedUserID,
 		}
 
-		future := s.Context.RequestFuture(s.DirectMessageActor, msg, s.RequestTimeout)
-		result, err := future.Result()
+		messages, err := s.clients(r).Messages.Conversation(msg)
 		if err != nil {
-			http.Error(w, "Failed to get conversation", http.StatusInternalServerError)
+			writeActorError(w, r, err, "Failed to get conversation")
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(result)
+		json.NewEncoder(w).Encode(slicePage(messages, page))
 	}
 }
 
@@ -179,7 +158,7 @@ func (s *Server) HandleMarkMessageRead() http.HandlerFunc {
 
 		var req struct {
 			MessageIds []string `json:"messageIds"`
-			UserID     string   `json:"userId"`
+			UserID     string   `json:"userId,omitempty"` // Deprecated: optional, must match the token
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -187,9 +166,8 @@ func (s *Server) HandleMarkMessageRead() http.HandlerFunc {
 			return
 		}
 
-		userID, err := uuid.Parse(req.UserID)
-		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		userID, ok := actingUser(w, r, req.UserID)
+		if !ok {
 			return
 		}
 
@@ -204,20 +182,42 @@ func (s *Server) HandleMarkMessageRead() http.HandlerFunc {
 				MessageID: messageID,
 				UserID:    userID,
 			}
-			future := s.Context.RequestFuture(s.DirectMessageActor, msg, s.RequestTimeout)
-			result, err := future.Result()
-			if err != nil {
-				results[mid] = false
-				continue
-			}
-			if success, ok := result.(bool); ok && success {
-				results[mid] = true
-			} else {
-				results[mid] = false
-			}
+			success, err := s.clients(r).Messages.MarkRead(msg)
+			results[mid] = err == nil && success
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(results)
 	}
 }
+
+// UnreadCountResponse is how many direct messages a user hasn't read
+type UnreadCountResponse struct {
+	Count int `json:"count"`
+}
+
+// HandleUnreadCount returns the authenticated user's unread message count.
+// Connected clients are also pushed an unreadCount event whenever it
+// changes, so this is only needed on load.
+func (s *Server) HandleUnreadCount() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := actingUser(w, r, "")
+		if !ok {
+			return
+		}
+
+		count, err := s.clients(r).Messages.UnreadCount(&actors.GetUnreadCountMsg{UserID: userID})
+		if err != nil {
+			writeActorError(w, r, err, "Failed to count unread messages")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(UnreadCountResponse{Count: count})
+	}
+}
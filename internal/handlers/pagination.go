@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Page is the envelope every list endpoint responds with. Clients pass
+// NextCursor back as ?cursor= for the following page; it's empty once
+// HasMore is false. Total is only set where the full count is known anyway.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	HasMore    bool   `json:"hasMore"`
+	Total      *int   `json:"total,omitempty"`
+}
+
+// pageRequest is the window a list request asks for. Newest first listings
+// continue after a keyset, others at an offset; see newKeysetPage.
+type pageRequest struct {
+	Limit  int
+	Offset int
+	After  *models.Keyset
+}
+
+// parsePage reads ?limit= and ?cursor= (or the older ?offset=). An invalid
+// cursor gets 400.
+func parsePage(w http.ResponseWriter, r *http.Request, defaultLimit, maxLimit int) (pageRequest, bool) {
+	p := pageRequest{Limit: defaultLimit}
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 {
+		p.Limit = min(limit, maxLimit)
+	}
+
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		var err error
+		p.Offset, p.After, err = decodeCursor(cursor)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return p, false
+		}
+	} else if offset, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && offset > 0 {
+		p.Offset = offset
+	}
+	return p, true
+}
+
+// encodeCursor makes an opaque cursor for the page starting at offset.
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// encodeKeysetCursor makes an opaque cursor for the page after key.
+func encodeKeysetCursor(key models.Keyset) string {
+	raw := strconv.FormatInt(key.CreatedAt.UnixMicro(), 10) + ":" + key.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor reads either kind of cursor: an offset, or a keyset.
+func decodeCursor(cursor string) (int, *models.Keyset, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, nil, err
+	}
+	if micros, id, ok := strings.Cut(string(raw), ":"); ok {
+		n, err := strconv.ParseInt(micros, 10, 64)
+		if err != nil {
+			return 0, nil, err
+		}
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return 0, nil, err
+		}
+		return 0, &models.Keyset{CreatedAt: time.UnixMicro(n), ID: parsed}, nil
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, nil, strconv.ErrSyntax
+	}
+	return offset, nil, nil
+}
+
+// threadCursor is where a comment thread continues: replies to Parent
+// (top-level comments if nil) after After (from the first if nil).
+type threadCursor struct {
+	Parent *uuid.UUID
+	After  *uuid.UUID
+}
+
+// encodeThreadCursor makes an opaque cursor for a thread continuing after
+// after among the replies to parent.
+func encodeThreadCursor(parent, after *uuid.UUID) string {
+	ids := make([]string, 2)
+	for i, id := range []*uuid.UUID{parent, after} {
+		if id != nil {
+			ids[i] = id.String()
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(ids, ":")))
+}
+
+func decodeThreadCursor(cursor string) (threadCursor, error) {
+	var c threadCursor
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, err
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 2 {
+		return c, strconv.ErrSyntax
+	}
+	for i, dst := range []**uuid.UUID{&c.Parent, &c.After} {
+		if parts[i] == "" {
+			continue
+		}
+		id, err := uuid.Parse(parts[i])
+		if err != nil {
+			return c, err
+		}
+		*dst = &id
+	}
+	return c, nil
+}
+
+// newPage wraps items fetched for p with one item of lookahead: anything
+// past p.Limit is dropped and only tells that another page exists.
+func newPage[T any](items []T, p pageRequest) *Page[T] {
+	page := &Page[T]{Items: items}
+	if len(items) > p.Limit {
+		page.Items = items[:p.Limit]
+		page.HasMore = true
+		page.NextCursor = encodeCursor(p.Offset + p.Limit)
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	return page
+}
+
+// newKeysetPage is newPage for newest first listings, which the database
+// pages by keyset: the next cursor continues after the last item shown,
+// rather than at an offset it would have to scan to.
+func newKeysetPage[T any](items []T, p pageRequest, key func(T) models.Keyset) *Page[T] {
+	page := newPage(items, p)
+	if page.HasMore {
+		page.NextCursor = encodeKeysetCursor(key(page.Items[len(page.Items)-1]))
+	}
+	return page
+}
+
+// slicePage cuts p out of a fully loaded list, which also gives the total.
+func slicePage[T any](all []T, p pageRequest) *Page[T] {
+	total := len(all)
+	start := min(p.Offset, total)
+	end := min(start+p.Limit+1, total)
+	page := newPage(all[start:end], p)
+	page.Total = &total
+	return page
+}
+
+// mapPage converts a page's items, e.g. from models to DTOs.
+func mapPage[T, U any](page *Page[T], convert func([]T) []U) *Page[U] {
+	return &Page[U]{Items: convert(page.Items), NextCursor: page.NextCursor, HasMore: page.HasMore, Total: page.Total}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/jobs"
+	"gator-swamp/internal/storage"
+	"gator-swamp/internal/utils"
+)
+
+// HandleSitemap serves the sitemap last generated by the sitemap.generate task
+func (s *Server) HandleSitemap() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.serveGenerated(w, r, jobs.SitemapKey, "application/xml", nil)
+	}
+}
+
+// HandleSubredditIndex serves the public JSON index of a subreddit's posts
+func (s *Server) HandleSubredditIndex() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "Subreddit name is required", http.StatusBadRequest)
+			return
+		}
+
+		sub, err := s.DB.GetSubredditByName(r.Context(), name)
+		if err != nil {
+			if appErr, ok := err.(*utils.AppError); ok {
+				i18n.WriteError(w, r, appErr)
+				return
+			}
+			http.Error(w, "Failed to fetch subreddit", http.StatusInternalServerError)
+			return
+		}
+
+		empty := func() {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(&jobs.SubredditIndex{Subreddit: sub.Name, GeneratedAt: time.Now().UTC(), Posts: []jobs.SubredditIndexEntry{}})
+		}
+		// NSFW and quarantined subreddits are left out of the sitemap, and an
+		// index generated before they were rated mustn't be served either
+		if sub.NSFW || sub.Quarantined {
+			empty()
+			return
+		}
+
+		// Subreddits without posts get no generated index
+		s.serveGenerated(w, r, jobs.SubredditIndexKey(sub.ID), "application/json", empty)
+	}
+}
+
+// serveGenerated copies a file written by a scheduled task to the response.
+// If there's no such file it calls missing, or responds 404 if that's nil.
+func (s *Server) serveGenerated(w http.ResponseWriter, r *http.Request, key, contentType string, missing func()) {
+	file, err := s.Storage.Get(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		if missing != nil {
+			missing()
+			return
+		}
+		http.Error(w, "Not generated yet", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to open file", "key", key, "error", err)
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	io.Copy(w, file)
+}
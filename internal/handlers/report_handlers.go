@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const maxReportReasonLength = 500
+
+// ReportRequest reports a post, comment or user. Posts and comments are
+// reported to their subreddit's moderators; a user is reported to the
+// moderators of the subreddit named by SubredditID.
+type ReportRequest struct {
+	Type        models.ReportTarget `json:"type"` // "post", "comment" or "user"
+	ID          string              `json:"id"`
+	SubredditID string              `json:"subredditId,omitempty"` // Users only
+	Reason      string              `json:"reason"`
+}
+
+// CloseReportRequest resolves or dismisses a report
+type CloseReportRequest struct {
+	ID     string              `json:"id"`
+	Status models.ReportStatus `json:"status"` // "resolved" or "dismissed"
+	Note   string              `json:"note,omitempty"`
+}
+
+// HandleReport files a report of a post, comment or user.
+func (s *Server) HandleReport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req ReportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		targetID, err := uuid.Parse(req.ID)
+		if err != nil {
+			http.Error(w, "Invalid ID format", http.StatusBadRequest)
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Reason == "" || utf8.RuneCountInString(req.Reason) > maxReportReasonLength {
+			http.Error(w, fmt.Sprintf("Reason must be 1 to %d characters", maxReportReasonLength), http.StatusBadRequest)
+			return
+		}
+
+		report := &models.Report{
+			ID:         uuid.New(),
+			ReporterID: userID,
+			TargetType: req.Type,
+			TargetID:   targetID,
+			Reason:     req.Reason,
+			Status:     models.ReportOpen,
+		}
+		switch req.Type {
+		case models.ReportPost:
+			post, err := s.DB.GetPost(r.Context(), targetID, uuid.Nil)
+			if err != nil {
+				writeActorError(w, r, err, "Failed to get post")
+				return
+			}
+			report.SubredditID = post.SubredditID
+
+		case models.ReportComment:
+			comment, err := s.DB.GetComment(r.Context(), targetID, uuid.Nil)
+			if err != nil {
+				writeActorError(w, r, err, "Failed to get comment")
+				return
+			}
+			report.SubredditID = comment.SubredditID
+
+		case models.ReportUser:
+			if targetID == userID {
+				http.Error(w, "You can't report yourself", http.StatusBadRequest)
+				return
+			}
+			subredditID, err := uuid.Parse(req.SubredditID)
+			if err != nil {
+				http.Error(w, "Reporting a user needs the subredditId it happened in", http.StatusBadRequest)
+				return
+			}
+			if _, err := s.DB.GetUser(r.Context(), targetID); err != nil {
+				writeActorError(w, r, err, "Failed to get user")
+				return
+			}
+			if _, err := s.DB.GetSubredditByID(r.Context(), subredditID); err != nil {
+				writeActorError(w, r, err, "Failed to get subreddit")
+				return
+			}
+			report.SubredditID = subredditID
+
+		default:
+			http.Error(w, "Invalid type, expected post, comment or user", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.DB.CreateReport(r.Context(), report); err != nil {
+			writeActorError(w, r, err, "Failed to file report")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// HandleSubredditReports is the moderators' report queue: GET ?id= lists a
+// subreddit's reports (open ones unless ?status= says otherwise), and PUT
+// resolves or dismisses one. Each decision is recorded in the audit log.
+func (s *Server) HandleSubredditReports() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			subredditID, err := uuid.Parse(r.URL.Query().Get("id"))
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				return
+			}
+			status := models.ReportStatus(r.URL.Query().Get("status"))
+			switch status {
+			case "":
+				status = models.ReportOpen
+			case models.ReportOpen, models.ReportResolved, models.ReportDismissed:
+			default:
+				http.Error(w, "Invalid status, expected open, resolved or dismissed", http.StatusBadRequest)
+				return
+			}
+			page, ok := parsePage(w, r, 50, 200)
+			if !ok {
+				return
+			}
+			if _, ok := s.requireModerator(w, r, subredditID); !ok {
+				return
+			}
+
+			reports, err := s.DB.ListReports(r.Context(), subredditID, status, page.Limit+1, page.Offset)
+			if err != nil {
+				writeActorError(w, r, err, "Failed to list reports")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(newPage(reports, page))
+
+		case http.MethodPut:
+			var req CloseReportRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			id, err := uuid.Parse(req.ID)
+			if err != nil {
+				http.Error(w, "Invalid report ID format", http.StatusBadRequest)
+				return
+			}
+			action := models.AuditReportResolve
+			switch req.Status {
+			case models.ReportResolved:
+			case models.ReportDismissed:
+				action = models.AuditReportDismiss
+			default:
+				http.Error(w, "Invalid status, expected resolved or dismissed", http.StatusBadRequest)
+				return
+			}
+
+			report, err := s.DB.GetReport(r.Context(), id)
+			if err != nil {
+				writeActorError(w, r, err, "Failed to get report")
+				return
+			}
+			modID, ok := s.requireModerator(w, r, report.SubredditID)
+			if !ok {
+				return
+			}
+
+			report.Status = req.Status
+			report.ResolvedBy = &modID
+			report.Note = nil
+			if note := strings.TrimSpace(req.Note); note != "" {
+				report.Note = &note
+			}
+			if err := s.DB.CloseReport(r.Context(), report); err != nil {
+				writeActorError(w, r, err, "Failed to close report")
+				return
+			}
+
+			details, _ := json.Marshal(map[string]interface{}{
+				"reportId":    report.ID,
+				"subredditId": report.SubredditID,
+				"type":        report.TargetType,
+				"id":          report.TargetID,
+				"note":        report.Note,
+			})
+			entry := &models.AuditEntry{ActorID: modID, Action: action, Details: details}
+			if report.TargetType == models.ReportUser {
+				entry.SubjectID = &report.TargetID
+			}
+			if err := s.DB.RecordAudit(r.Context(), entry); err != nil {
+				slog.ErrorContext(r.Context(), "Failed to audit report", "action", action, "report_id", report.ID, "moderator_id", modID, "error", err)
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(report)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
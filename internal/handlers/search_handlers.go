@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"gator-swamp/internal/dto"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// HandleSearch finds posts, comments or subreddits (?type=) matching ?q=,
+// best match first or newest first (?sort=)
+func (s *Server) HandleSearch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		if query == "" {
+			http.Error(w, "Search query is required", http.StatusBadRequest)
+			return
+		}
+		page, ok := parsePage(w, r, 20, 100)
+		if !ok {
+			return
+		}
+		sort := r.URL.Query().Get("sort")
+		switch sort {
+		case "":
+			sort = models.SearchSortRelevance
+		case models.SearchSortRelevance, models.SearchSortNew:
+		default:
+			http.Error(w, "Invalid sort (expected relevance or new)", http.StatusBadRequest)
+			return
+		}
+		requestingUserID, _ := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+
+		kind := r.URL.Query().Get("type")
+		switch kind {
+		case "":
+			kind = models.SearchPosts
+		case models.SearchPosts, models.SearchComments, models.SearchSubreddits:
+		default:
+			http.Error(w, "Invalid type (expected posts, comments or subreddits)", http.StatusBadRequest)
+			return
+		}
+
+		results, err := s.clients(r).Search.Search(&actors.SearchMsg{
+			Query:    query,
+			Type:     kind,
+			Sort:     sort,
+			Limit:    page.Limit,
+			Offset:   page.Offset,
+			ViewerID: requestingUserID,
+		})
+		if err != nil {
+			writeActorError(w, r, err, "Search failed")
+			return
+		}
+
+		var result interface{}
+		switch kind {
+		case models.SearchComments:
+			result = searchPage(dto.MaskComments(results.Comments, s.profanityMask(r)), results.More, page)
+		case models.SearchSubreddits:
+			result = mapPage(searchPage(results.Subreddits, results.More, page), dto.NewSubreddits)
+		default:
+			result = searchPage(s.showPosts(r, results.Posts), results.More, page)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// searchPage pages a SearchActor reply. The actor says whether there's
+// another page, since posts it skipped don't count against the limit.
+func searchPage[T any](items []T, more bool, p pageRequest) *Page[T] {
+	page := newPage(items, p)
+	if more {
+		page.HasMore = true
+		page.NextCursor = encodeCursor(p.Offset + p.Limit)
+	}
+	return page
+}
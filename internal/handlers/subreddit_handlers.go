@@ -2,19 +2,31 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"gator-swamp/internal/dto"
 	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/jobs"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/storage"
 	"gator-swamp/internal/utils"
+	"io"
+	"log/slog"
 	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
 // CreateSubredditRequest represents a request to create a new subreddit
 type CreateSubredditRequest struct {
-	Name        string `json:"name"`        // Subreddit name
-	Description string `json:"description"` // Subreddit description
-	CreatorID   string `json:"creatorId"`   // Creator ID (UUID as string)
+	Name        string `json:"name"`                // Subreddit name
+	Description string `json:"description"`         // Subreddit description
+	CreatorID   string `json:"creatorId,omitempty"` // Deprecated: the creator is the authenticated user
 }
 
 // HandleSubreddits handles requests related to subreddits
@@ -28,14 +40,17 @@ func (s *Server) HandleSubreddits() http.HandlerFunc {
 
 			// If neither parameter is provided, list all subreddits
 			if name == "" && id == "" {
-				future := s.Context.RequestFuture(s.Engine.GetSubredditActor(), &actors.ListSubredditsMsg{}, s.RequestTimeout)
-				result, err := future.Result()
+				page, ok := parsePage(w, r, 50, 200)
+				if !ok {
+					return
+				}
+				subreddits, err := s.clients(r).Subreddits.List()
 				if err != nil {
-					http.Error(w, "Failed to get subreddits", http.StatusInternalServerError)
+					writeActorError(w, r, err, "Failed to get subreddits")
 					return
 				}
 				w.Header().Set("Content-Type", "application/json")
-				json.NewEncoder(w).Encode(result)
+				json.NewEncoder(w).Encode(mapPage(slicePage(subreddits, page), dto.NewSubreddits))
 				return
 			}
 
@@ -47,53 +62,27 @@ func (s *Server) HandleSubreddits() http.HandlerFunc {
 					return
 				}
 
-				future := s.Context.RequestFuture(s.Engine.GetSubredditActor(),
-					&actors.GetSubredditByIDMsg{SubredditID: subredditID},
-					s.RequestTimeout)
-
-				result, err := future.Result()
+				details, err := s.clients(r).Subreddits.GetByID(&actors.GetSubredditByIDMsg{SubredditID: subredditID})
 				if err != nil {
-					http.Error(w, "Failed to get subreddit", http.StatusInternalServerError)
-					return
-				}
-
-				if appErr, ok := result.(*utils.AppError); ok {
-					if appErr.Code == utils.ErrNotFound {
-						http.Error(w, "Subreddit not found", http.StatusNotFound)
-					} else {
-						http.Error(w, appErr.Error(), http.StatusInternalServerError)
-					}
+					writeActorError(w, r, err, "Failed to get subreddit")
 					return
 				}
 
 				w.Header().Set("Content-Type", "application/json")
-				json.NewEncoder(w).Encode(result)
+				json.NewEncoder(w).Encode(subredditDetailsDTO(details))
 				return
 			}
 
 			// If name is provided
 			if name != "" {
-				future := s.Context.RequestFuture(s.Engine.GetSubredditActor(),
-					&actors.GetSubredditByNameMsg{Name: name},
-					s.RequestTimeout)
-
-				result, err := future.Result()
+				details, err := s.clients(r).Subreddits.GetByName(&actors.GetSubredditByNameMsg{Name: name})
 				if err != nil {
-					http.Error(w, "Failed to get subreddit", http.StatusInternalServerError)
-					return
-				}
-
-				if appErr, ok := result.(*utils.AppError); ok {
-					if appErr.Code == utils.ErrNotFound {
-						http.Error(w, "Subreddit not found", http.StatusNotFound)
-					} else {
-						http.Error(w, appErr.Error(), http.StatusInternalServerError)
-					}
+					writeActorError(w, r, err, "Failed to get subreddit")
 					return
 				}
 
 				w.Header().Set("Content-Type", "application/json")
-				json.NewEncoder(w).Encode(result)
+				json.NewEncoder(w).Encode(subredditDetailsDTO(details))
 				return
 			}
 
@@ -104,9 +93,8 @@ func (s *Server) HandleSubreddits() http.HandlerFunc {
 				return
 			}
 
-			creatorID, err := uuid.Parse(req.CreatorID)
-			if err != nil {
-				http.Error(w, "Invalid creator ID format", http.StatusBadRequest)
+			creatorID, ok := actingUser(w, r, req.CreatorID)
+			if !ok {
 				return
 			}
 
@@ -118,32 +106,14 @@ func (s *Server) HandleSubreddits() http.HandlerFunc {
 			}
 
 			// Send to Engine for validation and processing
-			future := s.Context.RequestFuture(s.EnginePID, msg, s.RequestTimeout)
-			result, err := future.Result()
+			subreddit, err := s.clients(r).Subreddits.Create(msg)
 			if err != nil {
-				http.Error(w, fmt.Sprintf("Failed to create subreddit: %v", err), http.StatusInternalServerError)
-				return
-			}
-
-			// Check for application errors
-			if appErr, ok := result.(*utils.AppError); ok {
-				var statusCode int
-				switch appErr.Code {
-				case utils.ErrNotFound:
-					statusCode = http.StatusNotFound
-				case utils.ErrInvalidInput:
-					statusCode = http.StatusBadRequest
-				case utils.ErrUnauthorized:
-					statusCode = http.StatusUnauthorized
-				default:
-					statusCode = http.StatusInternalServerError
-				}
-				http.Error(w, appErr.Error(), statusCode)
+				writeActorError(w, r, err, "Failed to create subreddit")
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(result)
+			json.NewEncoder(w).Encode(dto.NewSubreddit(subreddit))
 
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -151,6 +121,42 @@ func (s *Server) HandleSubreddits() http.HandlerFunc {
 	}
 }
 
+// subredditDetailsDTO converts a SubredditActor lookup response.
+func subredditDetailsDTO(details *actors.SubredditDetails) *dto.Subreddit {
+	sub := dto.NewSubreddit(details.Subreddit)
+	sub.MembersOnline = &details.MembersOnline
+	return sub
+}
+
+// HandleTrendingSubreddits lists the subreddits with the most activity in the
+// last day, as of the last trending refresh
+func (s *Server) HandleTrendingSubreddits() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		page, ok := parsePage(w, r, 25, 25)
+		if !ok {
+			return
+		}
+
+		subreddits, err := s.DB.GetTrendingSubreddits(r.Context(), userID)
+		if err != nil {
+			writeActorError(w, r, err, "Failed to get trending subreddits")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(mapPage(slicePage(subreddits, page), dto.NewSubreddits))
+	}
+}
+
 // HandleSubredditMembers handles subreddit membership operations
 func (s *Server) HandleSubredditMembers() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -169,22 +175,26 @@ func (s *Server) HandleSubredditMembers() http.HandlerFunc {
 				return
 			}
 
+			page, ok := parsePage(w, r, 100, 500)
+			if !ok {
+				return
+			}
+
 			msg := &actors.GetSubredditMembersMsg{SubredditID: id}
-			future := s.Context.RequestFuture(s.Engine.GetSubredditActor(), msg, s.RequestTimeout)
-			result, err := future.Result()
+			memberIDs, err := s.clients(r).Subreddits.Members(msg)
 			if err != nil {
-				http.Error(w, "Failed to get members", http.StatusInternalServerError)
+				writeActorError(w, r, err, "Failed to get members")
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(result)
+			json.NewEncoder(w).Encode(slicePage(memberIDs, page))
 
 		case http.MethodPost:
 			// Join a subreddit
 			var req struct {
 				SubredditID string `json:"subredditId"`
-				UserID      string `json:"userId"`
+				UserID      string `json:"userId"` // Deprecated: optional, must match the token
 			}
 
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -198,32 +208,28 @@ func (s *Server) HandleSubredditMembers() http.HandlerFunc {
 				return
 			}
 
-			userID, err := uuid.Parse(req.UserID)
-			if err != nil {
-				http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			userID, ok := actingUser(w, r, req.UserID)
+			if !ok {
 				return
 			}
 
-			future := s.Context.RequestFuture(s.Engine.GetSubredditActor(),
-				&actors.JoinSubredditMsg{
-					SubredditID: subredditID,
-					UserID:      userID,
-				}, s.RequestTimeout)
-
-			result, err := future.Result()
+			joined, err := s.clients(r).Subreddits.Join(&actors.JoinSubredditMsg{
+				SubredditID: subredditID,
+				UserID:      userID,
+			})
 			if err != nil {
-				http.Error(w, "Failed to join subreddit", http.StatusInternalServerError)
+				writeActorError(w, r, err, "Failed to join subreddit")
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(result)
+			json.NewEncoder(w).Encode(joined)
 
 		case http.MethodDelete:
 			// Leave a subreddit
 			var req struct {
 				SubredditID string `json:"subredditId"`
-				UserID      string `json:"userId"`
+				UserID      string `json:"userId"` // Deprecated: optional, must match the token
 			}
 
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -237,24 +243,352 @@ func (s *Server) HandleSubredditMembers() http.HandlerFunc {
 				return
 			}
 
-			userID, err := uuid.Parse(req.UserID)
+			userID, ok := actingUser(w, r, req.UserID)
+			if !ok {
+				return
+			}
+
+			left, err := s.clients(r).Subreddits.Leave(&actors.LeaveSubredditMsg{
+				SubredditID: subredditID,
+				UserID:      userID,
+			})
 			if err != nil {
-				http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+				writeActorError(w, r, err, "Failed to leave subreddit")
 				return
 			}
 
-			future := s.Context.RequestFuture(s.Engine.GetSubredditActor(),
-				&actors.LeaveSubredditMsg{
-					SubredditID: subredditID,
-					UserID:      userID,
-				}, s.RequestTimeout)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(left)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// SubredditSettingsRequest updates a subreddit's submission settings
+type SubredditSettingsRequest struct {
+	SubredditID                string `json:"subredditId"`
+	AllowedPostTypes           string `json:"allowedPostTypes"` // any, text or link
+	MinAccountAgeDays          int    `json:"minAccountAgeDays"`
+	MinKarma                   int    `json:"minKarma"`
+	CommentsRequireApproval    bool   `json:"commentsRequireApproval"`
+	PostApprovalKarma          int    `json:"postApprovalKarma"`
+	PostApprovalAccountAgeDays int    `json:"postApprovalAccountAgeDays"`
+	MaskProfanity              *bool  `json:"maskProfanity,omitempty"` // Defaults to true
+}
+
+// SubredditRatingRequest marks a subreddit NSFW or quarantined. Omitted
+// flags are left unchanged.
+type SubredditRatingRequest struct {
+	SubredditID string `json:"subredditId"`
+	NSFW        *bool  `json:"nsfw,omitempty"`        // Moderators
+	Quarantined *bool  `json:"quarantined,omitempty"` // Admins only
+	Reason      string `json:"reason,omitempty"`      // Recorded in the audit log with quarantines
+}
+
+// ReviewCommentRequest approves or rejects a comment held for approval
+type ReviewCommentRequest struct {
+	SubredditID string `json:"subredditId"`
+	CommentID   string `json:"commentId"`
+	Approve     bool   `json:"approve"`
+}
+
+// ReviewPostRequest approves or rejects a post held for approval
+type ReviewPostRequest struct {
+	SubredditID string `json:"subredditId"`
+	PostID      string `json:"postId"`
+	Approve     bool   `json:"approve"`
+	Reason      string `json:"reason,omitempty"` // Optional, shown to the author
+}
+
+// requireModerator returns the requesting user's ID if they moderate the
+// subreddit, or writes an error and returns false.
+func (s *Server) requireModerator(w http.ResponseWriter, r *http.Request, subredditID uuid.UUID) (uuid.UUID, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+
+	user, err := s.DB.GetUser(r.Context(), userID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return uuid.Nil, false
+		}
+		http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+		return uuid.Nil, false
+	}
+	sub, err := s.DB.GetSubredditByID(r.Context(), subredditID)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			i18n.WriteError(w, r, appErr)
+			return uuid.Nil, false
+		}
+		http.Error(w, "Failed to get subreddit", http.StatusInternalServerError)
+		return uuid.Nil, false
+	}
+	isMod, err := actors.IsModerator(r.Context(), s.DB, user, sub)
+	if err != nil {
+		http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+		return uuid.Nil, false
+	}
+	if !isMod {
+		http.Error(w, "Moderator access required", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
 
-			result, err := future.Result()
+// HandleSubredditSettings reads (GET ?id=) or, for moderators, updates (PUT)
+// a subreddit's submission settings
+func (s *Server) HandleSubredditSettings() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			subredditID, err := uuid.Parse(r.URL.Query().Get("id"))
 			if err != nil {
-				http.Error(w, "Failed to leave subreddit", http.StatusInternalServerError)
+				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
 				return
 			}
+			settings, err := s.DB.GetSubredditSettings(r.Context(), subredditID)
+			if err != nil {
+				http.Error(w, "Failed to get subreddit settings", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(settings)
 
+		case http.MethodPut:
+			var req SubredditSettingsRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			subredditID, err := uuid.Parse(req.SubredditID)
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				return
+			}
+			switch req.AllowedPostTypes {
+			case "":
+				req.AllowedPostTypes = models.PostTypesAny
+			case models.PostTypesAny, models.PostTypesText, models.PostTypesLink:
+			default:
+				http.Error(w, "Invalid allowedPostTypes, expected any, text or link", http.StatusBadRequest)
+				return
+			}
+			if req.MinAccountAgeDays < 0 || req.MinKarma < 0 || req.PostApprovalKarma < 0 || req.PostApprovalAccountAgeDays < 0 {
+				http.Error(w, "Account age and karma thresholds can't be negative", http.StatusBadRequest)
+				return
+			}
+			if _, ok := s.requireModerator(w, r, subredditID); !ok {
+				return
+			}
+
+			settings := &models.SubredditSettings{
+				SubredditID:             subredditID,
+				AllowedPostTypes:        req.AllowedPostTypes,
+				MinAccountAgeDays:       req.MinAccountAgeDays,
+				MinKarma:                req.MinKarma,
+				CommentsRequireApproval: req.CommentsRequireApproval,
+
+				PostApprovalKarma:          req.PostApprovalKarma,
+				PostApprovalAccountAgeDays: req.PostApprovalAccountAgeDays,
+				MaskProfanity:              req.MaskProfanity == nil || *req.MaskProfanity,
+			}
+			if err := s.DB.SaveSubredditSettings(r.Context(), settings); err != nil {
+				if appErr, ok := err.(*utils.AppError); ok {
+					i18n.WriteError(w, r, appErr)
+					return
+				}
+				http.Error(w, "Failed to save subreddit settings", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(settings)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// HandleSubredditRating lets moderators mark a subreddit NSFW and admins
+// quarantine it. Either keeps its posts out of site-wide feeds for users who
+// haven't opted in.
+func (s *Server) HandleSubredditRating() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req SubredditRatingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		subredditID, err := uuid.Parse(req.SubredditID)
+		if err != nil {
+			http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+			return
+		}
+		if req.NSFW == nil && req.Quarantined == nil {
+			http.Error(w, "Nothing to update, expected nsfw or quarantined", http.StatusBadRequest)
+			return
+		}
+
+		// Admins moderate every subreddit, so they may set both
+		var adminID uuid.UUID
+		var ok bool
+		if req.Quarantined != nil {
+			adminID, ok = s.requireAdmin(w, r)
+		} else {
+			_, ok = s.requireModerator(w, r, subredditID)
+		}
+		if !ok {
+			return
+		}
+
+		subreddit, err := s.clients(r).Subreddits.SetRating(&actors.SetSubredditRatingMsg{
+			SubredditID: subredditID,
+			NSFW:        req.NSFW,
+			Quarantined: req.Quarantined,
+		})
+		if err != nil {
+			writeActorError(w, r, err, "Failed to update subreddit")
+			return
+		}
+		if req.Quarantined != nil {
+			action := models.AuditSubredditRelease
+			if *req.Quarantined {
+				action = models.AuditSubredditQuarantine
+			}
+			s.auditContent(r.Context(), adminID, action, models.ContentSubreddit, subredditID, req.Reason)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(dto.NewSubreddit(subreddit))
+	}
+}
+
+// HandlePendingComments lets moderators list (GET ?id=) and approve or
+// reject (POST) comments held for approval
+func (s *Server) HandlePendingComments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			subredditID, err := uuid.Parse(r.URL.Query().Get("id"))
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				return
+			}
+			if _, ok := s.requireModerator(w, r, subredditID); !ok {
+				return
+			}
+			comments, err := s.DB.ListPendingComments(r.Context(), subredditID)
+			if err != nil {
+				http.Error(w, "Failed to get pending comments", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(comments)
+
+		case http.MethodPost:
+			var req ReviewCommentRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			subredditID, err := uuid.Parse(req.SubredditID)
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				return
+			}
+			commentID, err := uuid.Parse(req.CommentID)
+			if err != nil {
+				http.Error(w, "Invalid comment ID format", http.StatusBadRequest)
+				return
+			}
+			if _, ok := s.requireModerator(w, r, subredditID); !ok {
+				return
+			}
+
+			result, err := s.clients(r).Comments.Review(&actors.ReviewCommentMsg{
+				SubredditID: subredditID,
+				CommentID:   commentID,
+				Approve:     req.Approve,
+			})
+			if err != nil {
+				writeActorError(w, r, err, "Failed to review comment")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(result)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// HandlePendingPosts lets moderators list (GET ?id=) and approve or reject
+// (POST) posts held for approval
+func (s *Server) HandlePendingPosts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			subredditID, err := uuid.Parse(r.URL.Query().Get("id"))
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				return
+			}
+			if _, ok := s.requireModerator(w, r, subredditID); !ok {
+				return
+			}
+			posts, err := s.DB.ListPendingPosts(r.Context(), subredditID)
+			if err != nil {
+				http.Error(w, "Failed to get pending posts", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(s.showPosts(r, posts))
+
+		case http.MethodPost:
+			var req ReviewPostRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			subredditID, err := uuid.Parse(req.SubredditID)
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				return
+			}
+			postID, err := uuid.Parse(req.PostID)
+			if err != nil {
+				http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+				return
+			}
+			if _, ok := s.requireModerator(w, r, subredditID); !ok {
+				return
+			}
+
+			result, err := s.clients(r).Posts.Review(&actors.ReviewPostMsg{
+				SubredditID: subredditID,
+				PostID:      postID,
+				Approve:     req.Approve,
+				Reason:      req.Reason,
+			})
+			if err != nil {
+				writeActorError(w, r, err, "Failed to review post")
+				return
+			}
+			if post, ok := result.(*models.Post); ok {
+				s.enqueueThumbnail(r, post)
+				result = s.showPost(r, post)
+			}
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(result)
 
@@ -263,3 +597,436 @@ func (s *Server) HandleSubredditMembers() http.HandlerFunc {
 		}
 	}
 }
+
+// ModeratorRequest appoints a subreddit moderator
+type ModeratorRequest struct {
+	SubredditID string `json:"subredditId"`
+	UserID      string `json:"userId"`
+}
+
+// HandleSubredditModerators lists a subreddit's moderators (GET ?id=), and
+// lets its creator appoint (POST) and dismiss (DELETE ?id=&userId=) them.
+// Moderators may dismiss themselves.
+func (s *Server) HandleSubredditModerators() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var (
+			mods               []*models.Moderator
+			subredditID, modID uuid.UUID
+			err                error
+		)
+		switch r.Method {
+		case http.MethodGet:
+			subredditID, err = uuid.Parse(r.URL.Query().Get("id"))
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				return
+			}
+			mods, err = s.clients(r).Subreddits.Moderators(&actors.GetModeratorsMsg{SubredditID: subredditID})
+
+		case http.MethodPost:
+			var req ModeratorRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			subredditID, err = uuid.Parse(req.SubredditID)
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				return
+			}
+			modID, err = uuid.Parse(req.UserID)
+			if err != nil {
+				http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+				return
+			}
+			mods, err = s.clients(r).Subreddits.AddModerator(
+				&actors.AddModeratorMsg{SubredditID: subredditID, UserID: modID, ActorID: userID})
+
+		case http.MethodDelete:
+			subredditID, err = uuid.Parse(r.URL.Query().Get("id"))
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				return
+			}
+			modID, err = uuid.Parse(r.URL.Query().Get("userId"))
+			if err != nil {
+				http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+				return
+			}
+			mods, err = s.clients(r).Subreddits.RemoveModerator(
+				&actors.RemoveModeratorMsg{SubredditID: subredditID, UserID: modID, ActorID: userID})
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err != nil {
+			writeActorError(w, r, err, "Failed to process moderators request")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(mods)
+	}
+}
+
+// FlairRequest creates (with SubredditID) or changes (with ID) a flair
+type FlairRequest struct {
+	ID          string  `json:"id,omitempty"`
+	SubredditID string  `json:"subredditId,omitempty"`
+	Text        string  `json:"text"`
+	Color       *string `json:"color,omitempty"` // #rrggbb
+}
+
+const maxFlairLength = 64
+
+var flairColorRE = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// validate trims the flair's text and checks it and the color.
+func (req *FlairRequest) validate() string {
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" || utf8.RuneCountInString(req.Text) > maxFlairLength {
+		return fmt.Sprintf("Flair text must be 1 to %d characters", maxFlairLength)
+	}
+	if req.Color != nil && !flairColorRE.MatchString(*req.Color) {
+		return "Invalid color, expected #rrggbb"
+	}
+	return ""
+}
+
+// HandleSubredditFlairs lists a subreddit's flairs (GET ?id=) and lets its
+// moderators create (POST), change (PUT) and delete (DELETE ?flairId=) them.
+func (s *Server) HandleSubredditFlairs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			subredditID, err := uuid.Parse(r.URL.Query().Get("id"))
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				return
+			}
+			flairs, err := s.DB.GetFlairs(r.Context(), subredditID)
+			if err != nil {
+				writeActorError(w, r, err, "Failed to get flairs")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(flairs)
+
+		case http.MethodPost, http.MethodPut:
+			var req FlairRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if msg := req.validate(); msg != "" {
+				http.Error(w, msg, http.StatusBadRequest)
+				return
+			}
+
+			flair := &models.Flair{Text: req.Text, Color: req.Color}
+			if r.Method == http.MethodPost {
+				subredditID, err := uuid.Parse(req.SubredditID)
+				if err != nil {
+					http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+					return
+				}
+				flair.ID, flair.SubredditID = uuid.New(), subredditID
+			} else {
+				id, err := uuid.Parse(req.ID)
+				if err != nil {
+					http.Error(w, "Invalid flair ID format", http.StatusBadRequest)
+					return
+				}
+				existing, err := s.DB.GetFlair(r.Context(), id)
+				if err != nil {
+					writeActorError(w, r, err, "Failed to get flair")
+					return
+				}
+				flair.ID, flair.SubredditID, flair.CreatedAt = existing.ID, existing.SubredditID, existing.CreatedAt
+			}
+			if _, ok := s.requireModerator(w, r, flair.SubredditID); !ok {
+				return
+			}
+
+			var err error
+			if r.Method == http.MethodPost {
+				err = s.DB.CreateFlair(r.Context(), flair)
+			} else {
+				err = s.DB.UpdateFlair(r.Context(), flair)
+			}
+			if err != nil {
+				writeActorError(w, r, err, "Failed to save flair")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusCreated)
+			}
+			json.NewEncoder(w).Encode(flair)
+
+		case http.MethodDelete:
+			id, err := uuid.Parse(r.URL.Query().Get("flairId"))
+			if err != nil {
+				http.Error(w, "Invalid flair ID format", http.StatusBadRequest)
+				return
+			}
+			flair, err := s.DB.GetFlair(r.Context(), id)
+			if err != nil {
+				writeActorError(w, r, err, "Failed to get flair")
+				return
+			}
+			if _, ok := s.requireModerator(w, r, flair.SubredditID); !ok {
+				return
+			}
+			if err := s.DB.DeleteFlair(r.Context(), id); err != nil {
+				writeActorError(w, r, err, "Failed to delete flair")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(&models.StatusResponse{Success: true, Message: "Flair deleted"})
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// ModeratorRemoveRequest removes a post or comment from a subreddit
+type ModeratorRemoveRequest struct {
+	Type   string `json:"type"` // "post" or "comment"
+	ID     string `json:"id"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// HandleModeratorRemove lets moderators remove any post or comment in their
+// subreddit. The Engine checks the requester moderates it.
+func (s *Server) HandleModeratorRemove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req ModeratorRemoveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		ct, id, ok := parseContent(req.Type, req.ID)
+		if !ok || ct == models.ContentSubreddit {
+			http.Error(w, "type must be post or comment, with a valid id", http.StatusBadRequest)
+			return
+		}
+
+		var result *models.StatusResponse
+		var err error
+		if ct == models.ContentComment {
+			result, err = s.clients(r).Comments.Remove(&actors.DeleteCommentMsg{CommentID: id, AuthorID: userID, Force: true})
+		} else {
+			result, err = s.clients(r).Posts.Remove(&actors.DeletePostMsg{PostID: id, UserID: userID, Force: true})
+		}
+		if err != nil {
+			writeActorError(w, r, err, "Failed to remove content")
+			return
+		}
+		s.auditContent(r.Context(), userID, models.AuditModeratorRemove, ct, id, req.Reason)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// ModeratorLockRequest locks or unlocks a post as a moderator
+type ModeratorLockRequest struct {
+	PostID string `json:"postId"`
+	Locked bool   `json:"locked"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// HandleModeratorLock lets moderators close a thread to new comments, or
+// reopen it. Unlike an author's lock, the author can't undo it.
+func (s *Server) HandleModeratorLock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req ModeratorLockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		postID, err := uuid.Parse(req.PostID)
+		if err != nil {
+			http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+			return
+		}
+
+		post, err := s.clients(r).Posts.ModeratorLock(
+			&actors.LockPostMsg{PostID: postID, UserID: userID, Locked: req.Locked, ByModerator: true})
+		if err != nil {
+			writeActorError(w, r, err, "Failed to lock post")
+			return
+		}
+		action := models.AuditModeratorUnlock
+		if req.Locked {
+			action = models.AuditModeratorLock
+		}
+		s.auditContent(r.Context(), userID, action, models.ContentPost, postID, req.Reason)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.showPost(r, post))
+	}
+}
+
+// SubredditExportRequest asks for an archive of a subreddit
+type SubredditExportRequest struct {
+	SubredditID string `json:"subredditId"`
+}
+
+// HandleSubredditExports lets moderators export a subreddit as a JSON
+// archive: POST queues an export, GET ?subredditId= lists its exports, GET
+// ?id= shows one, and GET ?id=&download=true serves its archive once done.
+func (s *Server) HandleSubredditExports() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			q := r.URL.Query()
+			if q.Get("id") == "" {
+				subredditID, err := uuid.Parse(q.Get("subredditId"))
+				if err != nil {
+					http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+					return
+				}
+				if _, ok := s.requireModerator(w, r, subredditID); !ok {
+					return
+				}
+				exports, err := s.DB.ListSubredditExports(r.Context(), subredditID)
+				if err != nil {
+					writeActorError(w, r, err, "Failed to list exports")
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(exports)
+				return
+			}
+
+			id, err := uuid.Parse(q.Get("id"))
+			if err != nil {
+				http.Error(w, "Invalid export ID format", http.StatusBadRequest)
+				return
+			}
+			export, err := s.DB.GetSubredditExport(r.Context(), id)
+			if err != nil {
+				writeActorError(w, r, err, "Failed to get export")
+				return
+			}
+			if _, ok := s.requireModerator(w, r, export.SubredditID); !ok {
+				return
+			}
+			if q.Get("download") != "true" {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(export)
+				return
+			}
+			if export.Status != models.ExportDone || export.Key == nil {
+				http.Error(w, "Export is not done", http.StatusConflict)
+				return
+			}
+			s.serveExport(w, r, export)
+
+		case http.MethodPost:
+			var req SubredditExportRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			subredditID, err := uuid.Parse(req.SubredditID)
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				return
+			}
+			userID, ok := s.requireModerator(w, r, subredditID)
+			if !ok {
+				return
+			}
+
+			export := &models.SubredditExport{
+				ID:          uuid.New(),
+				SubredditID: subredditID,
+				RequestedBy: userID,
+				Status:      models.ExportPending,
+			}
+			if err := s.DB.CreateSubredditExport(r.Context(), export); err != nil {
+				writeActorError(w, r, err, "Failed to create export")
+				return
+			}
+			if err := s.Jobs.Enqueue(r.Context(), jobs.TypeExportSubreddit, jobs.ExportPayload{ExportID: export.ID},
+				jobs.UniqueKey("subreddit-export:"+export.ID.String())); err != nil {
+				// Without a job the export would stay pending and block others
+				msg := "failed to queue export"
+				export.Status, export.Error = models.ExportFailed, &msg
+				if err := s.DB.UpdateSubredditExport(r.Context(), export); err != nil {
+					slog.ErrorContext(r.Context(), "Failed to mark export failed", "export_id", export.ID, "error", err)
+				}
+				http.Error(w, "Failed to queue export", http.StatusInternalServerError)
+				return
+			}
+
+			details, _ := json.Marshal(map[string]interface{}{"subredditId": subredditID, "exportId": export.ID})
+			if err := s.DB.RecordAudit(r.Context(), &models.AuditEntry{
+				ActorID: userID,
+				Action:  models.AuditSubredditExport,
+				Details: details,
+			}); err != nil {
+				slog.ErrorContext(r.Context(), "Failed to audit export", "export_id", export.ID, "subreddit_id", subredditID, "user_id", userID, "error", err)
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(export)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// serveExport copies a finished export's archive to the response as a
+// download.
+func (s *Server) serveExport(w http.ResponseWriter, r *http.Request, export *models.SubredditExport) {
+	file, err := s.Storage.Get(r.Context(), *export.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Export archive is gone", http.StatusGone)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to open export", "export_id", export.ID, "error", err)
+		http.Error(w, "Failed to read export", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="subreddit-%s-%s.json"`,
+		export.SubredditID, export.CreatedAt.UTC().Format("20060102-150405")))
+	w.Header().Set("Cache-Control", "private, no-store")
+	io.Copy(w, file)
+}
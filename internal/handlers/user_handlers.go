@@ -1,15 +1,25 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"gator-swamp/internal/dto"
 	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/language"
+	"gator-swamp/internal/media"
 	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/policy"
+	"gator-swamp/internal/ranking"
 	"gator-swamp/internal/types"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
-	"time"
+	"strings"
 
 	"gator-swamp/internal/utils"
 
@@ -30,6 +40,11 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// RefreshRequest carries a refresh token to exchange or revoke
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
 // LoginResponse represents a response to a login request
 type LoginResponse struct {
 	Success bool   `json:"success"`
@@ -52,25 +67,19 @@ func (s *Server) HandleUserRegistration() http.HandlerFunc {
 			return
 		}
 
-		future := s.Context.RequestFuture(
-			s.Engine.GetUserSupervisor(),
-			&actors.RegisterUserMsg{
-				Username: req.Username,
-				Email:    req.Email,
-				Password: req.Password,
-				Karma:    req.Karma,
-			},
-			s.RequestTimeout,
-		)
-
-		result, err := future.Result()
+		userState, err := s.clients(r).Users.Register(&actors.RegisterUserMsg{
+			Username: req.Username,
+			Email:    req.Email,
+			Password: req.Password,
+			Karma:    req.Karma,
+		})
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to register user: %v", err), http.StatusInternalServerError)
+			writeActorError(w, r, err, "Failed to register user")
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(result)
+		json.NewEncoder(w).Encode(s.profileDTO(userState, userState.ID).User)
 	}
 }
 
@@ -88,31 +97,12 @@ func (s *Server) HandleUserLogin() http.HandlerFunc {
 			return
 		}
 
-		log.Printf("HTTP Handler: Received login request for email: %s", req.Email)
-
-		future := s.Context.RequestFuture(
-			s.Engine.GetUserSupervisor(),
-			&actors.LoginMsg{
-				Email:    req.Email,
-				Password: req.Password,
-			},
-			s.RequestTimeout,
-		)
-
-		result, err := future.Result()
+		loginResp, err := s.clients(r).Users.Login(&actors.LoginMsg{
+			Email:    req.Email,
+			Password: req.Password,
+		})
 		if err != nil {
-			log.Printf("HTTP Handler: Error getting login result: %v", err)
-			http.Error(w, "Failed to process login", http.StatusInternalServerError)
-			return
-		}
-
-		log.Printf("HTTP Handler: Received raw result: %+v", result)
-
-		// Type assert the login response
-		loginResp, ok := result.(*types.LoginResponse)
-		if !ok {
-			log.Printf("HTTP Handler: Invalid response type: %T", result)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			writeActorError(w, r, err, "Failed to process login")
 			return
 		}
 
@@ -120,32 +110,119 @@ func (s *Server) HandleUserLogin() http.HandlerFunc {
 		if loginResp.Success {
 			userID, err := uuid.Parse(loginResp.UserID)
 			if err != nil {
-				log.Printf("HTTP Handler: Invalid user ID format: %v", err)
+				slog.ErrorContext(r.Context(), "Invalid user ID in login response", "error", err)
 				http.Error(w, "Internal server error", http.StatusInternalServerError)
 				return
 			}
 
 			// Generate JWT token
-			token, err := middleware.GenerateToken(userID)
+			token, err := middleware.GenerateToken(userID, loginResp.IsBot)
 			if err != nil {
-				log.Printf("HTTP Handler: Failed to generate token: %v", err)
+				slog.ErrorContext(r.Context(), "Failed to generate token", "user_id", userID, "error", err)
 				http.Error(w, "Failed to generate auth token", http.StatusInternalServerError)
 				return
 			}
 
-			// Add token to response
+			refreshToken, hash, expiresAt, err := middleware.GenerateRefreshToken()
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Failed to generate refresh token", "error", err)
+				http.Error(w, "Failed to generate auth token", http.StatusInternalServerError)
+				return
+			}
+			if err := s.DB.CreateRefreshToken(r.Context(), userID, hash, expiresAt); err != nil {
+				writeActorError(w, r, err, "Failed to generate auth token")
+				return
+			}
+
+			// Add tokens to response
 			loginResp.Token = token
+			loginResp.RefreshToken = refreshToken
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(loginResp); err != nil {
-			log.Printf("HTTP Handler: Failed to encode response: %v", err)
+			slog.ErrorContext(r.Context(), "Failed to encode response", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 	}
 }
 
+// HandleRefreshToken exchanges a refresh token for a new access token and
+// the next refresh token. The one sent can't be used again.
+func (s *Server) HandleRefreshToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			http.Error(w, "Refresh token required", http.StatusBadRequest)
+			return
+		}
+
+		refreshToken, hash, expiresAt, err := middleware.GenerateRefreshToken()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to generate refresh token", "error", err)
+			http.Error(w, "Failed to generate auth token", http.StatusInternalServerError)
+			return
+		}
+		userID, err := s.DB.RotateRefreshToken(r.Context(), middleware.HashRefreshToken(req.RefreshToken), hash, expiresAt)
+		if err != nil {
+			writeActorError(w, r, err, "Failed to refresh token")
+			return
+		}
+
+		user, err := s.DB.GetUser(r.Context(), userID)
+		if err != nil {
+			writeActorError(w, r, err, "Failed to refresh token")
+			return
+		}
+		token, err := middleware.GenerateToken(userID, user.IsBot)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to generate token", "user_id", userID, "error", err)
+			http.Error(w, "Failed to generate auth token", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&types.LoginResponse{
+			Success:      true,
+			Token:        token,
+			RefreshToken: refreshToken,
+			UserID:       userID.String(),
+			IsBot:        user.IsBot,
+		})
+	}
+}
+
+// HandleLogout revokes a refresh token. Access tokens already issued stay
+// valid until they expire.
+func (s *Server) HandleLogout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			http.Error(w, "Refresh token required", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.DB.RevokeRefreshToken(r.Context(), middleware.HashRefreshToken(req.RefreshToken)); err != nil {
+			writeActorError(w, r, err, "Failed to log out")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.StatusResponse{Success: true, Message: "Logged out"})
+	}
+}
+
 // HandleUserProfile handles requests to get a user's profile
 func (s *Server) HandleUserProfile() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -166,96 +243,127 @@ func (s *Server) HandleUserProfile() http.HandlerFunc {
 			return
 		}
 
-		future := s.Context.RequestFuture(
-			s.Engine.GetUserSupervisor(),
-			&actors.GetUserProfileMsg{UserID: userID},
-			s.RequestTimeout,
-		)
-
-		result, err := future.Result()
+		userState, err := s.clients(r).Users.Profile(&actors.GetUserProfileMsg{UserID: userID})
 		if err != nil {
-			http.Error(w, "Failed to get user profile", http.StatusInternalServerError)
+			writeActorError(w, r, err, "Failed to get user profile")
 			return
 		}
 
-		if result == nil {
-			http.Error(w, "User not found", http.StatusNotFound)
+		viewerID, _ := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.profileDTO(userState, viewerID))
+	}
+}
+
+// profileDTO converts UserState for viewerID. Only users themselves see
+// their email.
+func (s *Server) profileDTO(state *actors.UserState, viewerID uuid.UUID) *dto.Profile {
+	profile := &dto.Profile{
+		User: dto.User{
+			ID:            state.ID,
+			Username:      state.Username,
+			Karma:         state.Karma,
+			PostKarma:     state.PostKarma,
+			CommentKarma:  state.CommentKarma,
+			KarmaVelocity: state.KarmaVelocity,
+			IsConnected:   state.IsConnected,
+			LastActive:    state.LastActive,
+			CreatedAt:     state.CreatedAt,
+			Avatar:        media.GetAvatarURLs(s.Storage, state.ProfileImage),
+		},
+		SubredditIDs:   state.Subreddits,
+		SubredditNames: state.SubredditNames,
+	}
+	if state.ID == viewerID {
+		profile.Email = state.Email
+	}
+	if profile.SubredditIDs == nil {
+		profile.SubredditIDs = []uuid.UUID{}
+	}
+	if profile.SubredditNames == nil {
+		profile.SubredditNames = []string{}
+	}
+	return profile
+}
+
+// HandleGetAllUsers lists users a page at a time, optionally filtered by
+// username prefix (?q=) and karma (?minKarma=). Everyone sees a public
+// projection; admins can ask for full records with ?full=true.
+func (s *Server) HandleGetAllUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		userState, ok := result.(*actors.UserState)
+		page, ok := parsePage(w, r, 50, 200)
 		if !ok {
-			http.Error(w, "Invalid response type", http.StatusInternalServerError)
 			return
 		}
 
-		// Create response in the format you requested
-		response := struct {
-			ID            string    `json:"id"`
-			Username      string    `json:"username"`
-			Email         string    `json:"email"`
-			Karma         int       `json:"karma"`
-			IsConnected   bool      `json:"isConnected"`
-			LastActive    time.Time `json:"lastActive"`
-			SubredditID   []string  `json:"subredditID"`
-			SubredditName []string  `json:"subredditName"`
-		}{
-			ID:          userState.ID.String(),
-			Username:    userState.Username,
-			Email:       userState.Email,
-			Karma:       userState.Karma,
-			IsConnected: userState.IsConnected,
-			LastActive:  userState.LastActive,
+		filter := models.UserFilter{Username: strings.TrimSpace(r.URL.Query().Get("q"))}
+		if raw := r.URL.Query().Get("minKarma"); raw != "" {
+			minKarma, err := strconv.Atoi(raw)
+			if err != nil {
+				http.Error(w, "Invalid minKarma", http.StatusBadRequest)
+				return
+			}
+			filter.MinKarma = &minKarma
+		}
+
+		full := r.URL.Query().Get("full") == "true"
+		if full {
+			if _, ok := s.requireAdmin(w, r); !ok {
+				return
+			}
 		}
 
-		// Convert UUID slices to string slices
-		response.SubredditID = make([]string, len(userState.Subreddits))
-		for i, id := range userState.Subreddits {
-			response.SubredditID[i] = id.String()
+		users, err := s.DB.ListUsers(r.Context(), filter, page.Limit+1, page.After)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to fetch users", "error", err)
+			writeActorError(w, r, err, "Failed to fetch users")
+			return
 		}
-		response.SubredditName = userState.SubredditNames
 
+		listed := newKeysetPage(users, page, (*models.User).Keyset)
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		if full {
+			json.NewEncoder(w).Encode(mapPage(listed, func(users []*models.User) []*dto.AdminUser {
+				return dto.NewAdminUsers(users, s.Storage)
+			}))
+			return
+		}
+		json.NewEncoder(w).Encode(mapPage(listed, dto.NewPublicUsers))
 	}
 }
 
-// HandleGetAllUsers handles requests to get all users
-func (s *Server) HandleGetAllUsers() http.HandlerFunc {
+// HandleKarmaLeaderboard lists the users with the most karma a page at a
+// time: in total, or only from posts (?by=post) or comments (?by=comment).
+func (s *Server) HandleKarmaLeaderboard() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		log.Printf("HandleGetAllUsers: Fetching all users")
+		page, ok := parsePage(w, r, 25, 100)
+		if !ok {
+			return
+		}
 
-		// Use the DBAdapter to fetch users
-		users, err := s.DB.GetAllUsers(r.Context())
+		by := models.Leaderboard(r.URL.Query().Get("by"))
+		if by == "" {
+			by = models.LeaderboardKarma
+		}
+
+		users, err := s.DB.GetKarmaLeaderboard(r.Context(), by, page.Limit+1, page.Offset)
 		if err != nil {
-			log.Printf("HandleGetAllUsers: Error fetching users: %v", err)
-			// Check if it's an AppError
-			if appErr, ok := err.(*utils.AppError); ok {
-				// Map AppError code to HTTP status (add more cases as needed)
-				statusCode := http.StatusInternalServerError
-				if appErr.Code == utils.ErrDatabase {
-					statusCode = http.StatusInternalServerError
-				}
-				http.Error(w, appErr.Error(), statusCode)
-			} else {
-				// Generic internal error for other types of errors
-				http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
-			}
+			writeActorError(w, r, err, "Failed to get leaderboard")
 			return
 		}
 
-		log.Printf("HandleGetAllUsers: Returning %d users", len(users))
-
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(users); err != nil {
-			log.Printf("HandleGetAllUsers: Error encoding response: %v", err)
-			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		}
+		json.NewEncoder(w).Encode(mapPage(newPage(users, page), dto.NewPublicUsers))
 	}
 }
 
@@ -271,36 +379,402 @@ func (s *Server) HandleGetFeed() http.HandlerFunc {
 		userIDClaim := r.Context().Value(middleware.UserIDKey)
 		userID, ok := userIDClaim.(uuid.UUID)
 		if !ok {
-			log.Printf("HandleGetFeed: Invalid user ID type in token context key. Expected uuid.UUID, got %T", userIDClaim)
 			http.Error(w, "Authentication required", http.StatusUnauthorized)
 			return
 		}
 
-		// Parse limit and offset from query parameters
-		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
-		if limit <= 0 {
-			limit = 20 // Default limit
+		page, ok := parsePage(w, r, 20, 100)
+		if !ok {
+			return
 		}
-		if offset < 0 {
-			offset = 0 // Default offset
+		hideSeen := r.URL.Query().Get("hide_seen") == "true"
+		sortOrder, ok := parsePostSort(r)
+		if !ok {
+			http.Error(w, "Invalid sort, expected new, hot, top, controversial or rising", http.StatusBadRequest)
+			return
+		}
+		// Ranking experiments replace the sort; the header tells clients which ordering they got
+		rankingName := r.URL.Query().Get("ranking")
+		if _, ok := ranking.Experiments[rankingName]; rankingName != "" && !ok {
+			http.Error(w, "Unknown ranking experiment", http.StatusBadRequest)
+			return
 		}
 
+		// Newest first pages by keyset; other sorts and rankings have no stable key
+		keyset := sortOrder == models.SortNew && rankingName == ""
+
 		// Send request via Engine to UserSupervisor
-		future := s.Context.RequestFuture(s.EnginePID, &actors.GetUserFeedMsg{
+		msg := &actors.GetUserFeedMsg{
 			UserID:           userID, // User whose feed is requested
-			Limit:            limit,
-			Offset:           offset,
+			Limit:            page.Limit,
+			Offset:           page.Offset,
 			RequestingUserID: userID, // User making the request
-		}, s.RequestTimeout)
+			HideSeen:         hideSeen,
+			Sort:             sortOrder,
+			Ranking:          rankingName,
+		}
+		if keyset {
+			msg.After = page.After
+		}
+		posts, err := s.clients(r).Posts.Feed(msg)
+		if err != nil {
+			writeActorError(w, r, err, "Failed to get feed")
+			return
+		}
+		if rankingName == "" {
+			rankingName = ranking.Default
+		}
+		ranking.Served(rankingName)
+		w.Header().Set("X-Feed-Ranking", rankingName)
+
+		var feed *Page[*models.Post]
+		if keyset {
+			feed = newKeysetPage(s.showPosts(r, posts), page, (*models.Post).Keyset)
+		} else {
+			feed = newPage(s.showPosts(r, posts), page)
+			if hideSeen && feed.HasMore {
+				// Served posts drop out of the feed, so the next page starts where this one did
+				feed.NextCursor = encodeCursor(page.Offset)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(feed)
+	}
+}
+
+// HandleInbox returns the authenticated user's unread messages, replies and
+// mentions as one stream, newest first
+func (s *Server) HandleInbox() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		page, ok := parsePage(w, r, 25, 100)
+		if !ok {
+			return
+		}
+
+		items, err := s.DB.GetInbox(r.Context(), userID, page.Limit+1, page.Offset)
+		if err != nil {
+			if appErr, ok := err.(*utils.AppError); ok {
+				i18n.WriteError(w, r, appErr)
+				return
+			}
+			http.Error(w, "Failed to get inbox", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newPage(items, page))
+	}
+}
+
+// InboxReplyRequest is the text of a reply sent from the inbox
+type InboxReplyRequest struct {
+	Content string `json:"content"`
+}
+
+// InboxReplyResponse carries the reply: a message for a message, or a
+// comment for a reply or mention.
+type InboxReplyResponse struct {
+	Type    models.InboxItemType    `json:"type"`
+	Message *models.DirectMessage   `json:"message,omitempty"`
+	Comment *actors.CommentResponse `json:"comment,omitempty"`
+}
+
+// HandleInboxReply answers an inbox item in one call: a message gets a
+// message back, and a comment or post gets a comment. Either way the item
+// is marked read in the transaction that saves the answer.
+func (s *Server) HandleInboxReply() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		itemID, err := uuid.Parse(r.PathValue("notificationId"))
+		if err != nil {
+			http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+			return
+		}
+		var req InboxReplyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Content) == "" {
+			http.Error(w, "Reply content required", http.StatusBadRequest)
+			return
+		}
+
+		item, err := s.DB.GetInboxItem(r.Context(), userID, itemID)
+		if err != nil {
+			writeActorError(w, r, err, "Failed to get inbox item")
+			return
+		}
+
+		op := policy.OpCreateComment
+		if item.Type == models.InboxMessage {
+			op = policy.OpSendMessage
+		}
+		err = s.Policies.CheckUser(r.Context(), s.DB, op, userID)
+		if err == nil && middleware.IsBotRequest(r.Context()) {
+			err = policy.CheckBot(r.Context(), s.DB, op, userID, uuid.Nil)
+		}
+		if err != nil {
+			writeActorError(w, r, err, "Failed to check permissions")
+			return
+		}
+
+		resp := &InboxReplyResponse{Type: item.Type}
+		if item.Type == models.InboxMessage {
+			resp.Message, err = s.clients(r).Messages.Reply(&actors.ReplyToMessageMsg{MessageID: item.ID, UserID: userID, Content: req.Content})
+		} else {
+			// A post mention is answered on the post, a comment under the comment
+			var parentID *uuid.UUID
+			if item.ID != *item.PostID {
+				parentID = &item.ID
+			}
+			resp.Comment, err = s.clients(r).Comments.Create(&actors.CreateCommentMsg{
+				Content:     req.Content,
+				AuthorID:    userID,
+				PostID:      *item.PostID,
+				ParentID:    parentID,
+				InboxItemID: &item.ID,
+			})
+		}
+		if err != nil {
+			writeActorError(w, r, err, "Failed to send reply")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// maxAvatarBytes caps avatar uploads
+const maxAvatarBytes = 5 << 20
+
+// AvatarFromMediaRequest makes one of the user's uploads their avatar
+type AvatarFromMediaRequest struct {
+	MediaID string `json:"mediaId"`
+}
+
+// HandleAvatarUpload accepts a multipart "avatar" image for the current user,
+// or a JSON AvatarFromMediaRequest naming an image they uploaded to /upload.
+// The original is stored right away; resized variants are generated by a
+// background job, which then switches the profile over to the new avatar.
+func (s *Server) HandleAvatarUpload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var data []byte
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			var req AvatarFromMediaRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			mediaID, err := uuid.Parse(req.MediaID)
+			if err != nil {
+				http.Error(w, "Invalid media ID format", http.StatusBadRequest)
+				return
+			}
+			if data, ok = s.ownedMedia(w, r, userID, mediaID); !ok {
+				return
+			}
+		} else {
+			r.Body = http.MaxBytesReader(w, r.Body, maxAvatarBytes+1<<10) // Allow for multipart framing
+			file, _, err := r.FormFile("avatar")
+			if err != nil {
+				http.Error(w, "Avatar image required (multipart field \"avatar\", max 5MB)", http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+
+			data, err = io.ReadAll(io.LimitReader(file, maxAvatarBytes+1))
+			if err != nil {
+				http.Error(w, "Failed to read upload", http.StatusBadRequest)
+				return
+			}
+		}
+		if len(data) > maxAvatarBytes {
+			http.Error(w, "Avatar exceeds 5MB", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		format, err := media.CheckImage(data)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid avatar: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		key := media.AvatarKey(userID, uuid.New(), format)
+		if err := s.Storage.Put(r.Context(), key, bytes.NewReader(data), media.ContentType(format)); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to store avatar", "user_id", userID, "error", err)
+			http.Error(w, "Failed to store avatar", http.StatusInternalServerError)
+			return
+		}
 
-		result, err := future.Result()
+		err = s.Jobs.Enqueue(r.Context(), media.TypeProcessAvatar, media.AvatarPayload{
+			UserID: userID,
+			Key:    key,
+			Format: format,
+		})
 		if err != nil {
-			http.Error(w, "Failed to get feed", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Failed to enqueue avatar processing", "user_id", userID, "error", err)
+			s.Storage.Delete(r.Context(), key)
+			http.Error(w, "Failed to process avatar", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "processing",
+			"avatar": media.GetAvatarURLs(s.Storage, &key),
+		})
+	}
+}
+
+// HandleHeartbeat marks the current user active and connected. Clients
+// without a WebSocket call it every minute or so while in use; writes are
+// debounced, so calling it more often is harmless.
+func (s *Server) HandleHeartbeat() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		s.Presence.Touch(userID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// UserLimitsResponse is the current user's rate limit standing
+type UserLimitsResponse struct {
+	Enabled       bool                         `json:"enabled"`
+	WindowSeconds int                          `json:"windowSeconds,omitempty"`
+	Buckets       []middleware.RateLimitStatus `json:"buckets"`
+}
+
+// HandleUserLimits reports the current user's rate limit buckets on this
+// instance, with what's left of each and when it resets, so clients can
+// throttle themselves instead of running into 429s. Counting this request
+// included.
+func (s *Server) HandleUserLimits() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		response := UserLimitsResponse{Buckets: []middleware.RateLimitStatus{}}
+		if s.RateLimits != nil {
+			response.Enabled = true
+			response.WindowSeconds = int(s.RateLimits.Window().Seconds())
+			response.Buckets = s.RateLimits.Status(middleware.UserRateLimitClient(userID), middleware.IsBotRequest(r.Context()))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// UserPreferencesRequest replaces the authenticated user's preferences
+type UserPreferencesRequest struct {
+	ShowNSFW        bool     `json:"showNsfw"`
+	ShowQuarantined bool     `json:"showQuarantined"`
+	MaskProfanity   *bool    `json:"maskProfanity,omitempty"` // Defaults to true
+	Languages       []string `json:"languages,omitempty"`     // ISO 639-1 codes, e.g. "en"; empty for all
+}
+
+// HandleUserPreferences reads (GET) or replaces (PUT) the authenticated
+// user's preferences
+func (s *Server) HandleUserPreferences() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middleware.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var prefs *models.UserPreferences
+		switch r.Method {
+		case http.MethodGet:
+			var err error
+			prefs, err = s.DB.GetUserPreferences(r.Context(), userID)
+			if err != nil {
+				http.Error(w, "Failed to get preferences", http.StatusInternalServerError)
+				return
+			}
+
+		case http.MethodPut:
+			var req UserPreferencesRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			languages := []string{}
+			for _, code := range req.Languages {
+				code = strings.ToLower(strings.TrimSpace(code))
+				if !language.Supported(code) {
+					http.Error(w, fmt.Sprintf("Unsupported language %q; use one of %s", code, strings.Join(language.Languages, ", ")), http.StatusBadRequest)
+					return
+				}
+				if !slices.Contains(languages, code) {
+					languages = append(languages, code)
+				}
+			}
+			prefs = &models.UserPreferences{
+				UserID:          userID,
+				ShowNSFW:        req.ShowNSFW,
+				ShowQuarantined: req.ShowQuarantined,
+				MaskProfanity:   req.MaskProfanity == nil || *req.MaskProfanity,
+				Languages:       languages,
+			}
+			if err := s.DB.SaveUserPreferences(r.Context(), prefs); err != nil {
+				http.Error(w, "Failed to save preferences", http.StatusInternalServerError)
+				return
+			}
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(result)
+		json.NewEncoder(w).Encode(prefs)
 	}
 }
@@ -1,9 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"gator-swamp/internal/middleware"
 	"gator-swamp/internal/websocket"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -23,38 +24,53 @@ var upgrader = ws.Upgrader{
 // HandleWebSocket handles WebSocket connection requests.
 func (s *Server) HandleWebSocket() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Hub.IsClosing() {
+			http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+
 		// 1. Authenticate using JWT from query parameter
 		tokenString := r.URL.Query().Get("token")
 		if tokenString == "" {
-			log.Println("WebSocket connection failed: Missing token")
 			http.Error(w, "Missing authentication token", http.StatusUnauthorized)
 			return
 		}
-		log.Printf("WebSocket attempting auth with token: %s...", tokenString[:min(len(tokenString), 10)]) // Log prefix
 
 		claims, err := middleware.ValidateToken(tokenString)
 		if err != nil {
-			log.Printf("WebSocket connection failed: Invalid token: %v", err)
+			slog.InfoContext(r.Context(), "WebSocket connection rejected", "reason", "invalid token", "error", err)
 			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
 			return
 		}
 
+		if claims.IsImpersonation() {
+			http.Error(w, "Impersonation tokens cannot open WebSocket connections", http.StatusForbidden)
+			return
+		}
+
 		userID := claims.UserID
 		if userID == uuid.Nil {
-			log.Println("WebSocket connection failed: Nil userID in token claims")
 			http.Error(w, "Invalid user ID in token", http.StatusInternalServerError)
 			return
 		}
-		log.Printf("WebSocket token validated for User %s", userID)
+
+		if err := s.Hub.CheckCapacity(userID); err != nil {
+			slog.InfoContext(r.Context(), "WebSocket connection rejected", "user_id", userID, "error", err)
+			status := http.StatusServiceUnavailable
+			if errors.Is(err, websocket.ErrUserConnectionLimit) {
+				status = http.StatusTooManyRequests
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
 
 		// 2. Upgrade connection
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
-			log.Printf("WebSocket upgrade failed for User %s: %v", userID, err)
+			slog.WarnContext(r.Context(), "WebSocket upgrade failed", "user_id", userID, "error", err)
 			// Note: Cannot write HTTP error after successful upgrade attempt
 			return
 		}
-		log.Printf("WebSocket connection upgraded for User %s", userID)
 
 		// 3. Create and register the client (Use exported fields)
 		client := &websocket.Client{
@@ -65,7 +81,7 @@ func (s *Server) HandleWebSocket() http.HandlerFunc {
 		}
 		client.Hub.Register <- client // Use exported Hub and Register
 
-		log.Printf("WebSocket client registered for User %s", userID)
+		slog.InfoContext(r.Context(), "WebSocket client registered", "user_id", userID)
 
 		// 4. Start read and write pumps (Use exported methods)
 		go client.WritePump()
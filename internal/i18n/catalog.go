@@ -0,0 +1,54 @@
+package i18n
+
+import "gator-swamp/internal/utils"
+
+// catalog maps languages to a message per AppError code. English is listed
+// so it can be negotiated, but AppErrors already carry English messages.
+// Codes without a translation keep the English message.
+var catalog = map[string]map[string]string{
+	"en": {},
+	"es": {
+		utils.ErrNotFound:               "No se encontró el recurso",
+		utils.ErrDuplicate:              "El recurso ya existe",
+		utils.ErrInvalidInput:           "La solicitud no es válida",
+		utils.ErrUnauthorized:           "Se requiere autenticación",
+		utils.ErrForbidden:              "No tienes permiso para realizar esta acción",
+		utils.ErrInvalidToken:           "El token no es válido o ha caducado",
+		utils.ErrUserNotFound:           "No se encontró el usuario",
+		utils.ErrUserAlreadyExists:      "El usuario ya existe",
+		utils.ErrInsufficientKarma:      "No tienes suficiente karma",
+		utils.ErrInvalidCredentials:     "Correo electrónico o contraseña incorrectos",
+		utils.ErrSubredditNotFound:      "No se encontró el subreddit",
+		utils.ErrSubredditExists:        "El subreddit ya existe",
+		utils.ErrNotSubredditMember:     "No eres miembro de este subreddit",
+		utils.ErrAlreadySubredditMember: "Ya eres miembro de este subreddit",
+		utils.ErrActorTimeout:           "El servidor tardó demasiado en responder",
+		utils.ErrActorNotFound:          "No se encontró el recurso",
+		utils.ErrMessageRejected:        "La solicitud fue rechazada",
+		utils.ErrTooManyRequests:        "Demasiadas solicitudes; inténtalo más tarde",
+		utils.ErrUnavailable:            "El servicio no está disponible; inténtalo más tarde",
+		utils.ErrDatabase:               "Error interno del servidor",
+	},
+	"fr": {
+		utils.ErrNotFound:               "Ressource introuvable",
+		utils.ErrDuplicate:              "La ressource existe déjà",
+		utils.ErrInvalidInput:           "Requête invalide",
+		utils.ErrUnauthorized:           "Authentification requise",
+		utils.ErrForbidden:              "Vous n'êtes pas autorisé à effectuer cette action",
+		utils.ErrInvalidToken:           "Jeton invalide ou expiré",
+		utils.ErrUserNotFound:           "Utilisateur introuvable",
+		utils.ErrUserAlreadyExists:      "L'utilisateur existe déjà",
+		utils.ErrInsufficientKarma:      "Karma insuffisant",
+		utils.ErrInvalidCredentials:     "Adresse e-mail ou mot de passe incorrect",
+		utils.ErrSubredditNotFound:      "Subreddit introuvable",
+		utils.ErrSubredditExists:        "Le subreddit existe déjà",
+		utils.ErrNotSubredditMember:     "Vous n'êtes pas membre de ce subreddit",
+		utils.ErrAlreadySubredditMember: "Vous êtes déjà membre de ce subreddit",
+		utils.ErrActorTimeout:           "Le serveur a mis trop de temps à répondre",
+		utils.ErrActorNotFound:          "Ressource introuvable",
+		utils.ErrMessageRejected:        "La requête a été refusée",
+		utils.ErrTooManyRequests:        "Trop de requêtes ; réessayez plus tard",
+		utils.ErrUnavailable:            "Service indisponible ; réessayez plus tard",
+		utils.ErrDatabase:               "Erreur interne du serveur",
+	},
+}
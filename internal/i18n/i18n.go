@@ -0,0 +1,84 @@
+// Package i18n localizes API error messages. Each AppError code has a
+// message per supported language, picked by the request's Accept-Language
+// header. The code itself is always sent too, so clients can match on it
+// whatever the language.
+package i18n
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gator-swamp/internal/utils"
+)
+
+// DefaultLanguage is used when the client accepts none of the catalog's
+// languages. Its messages are the AppErrors' own, which are more specific
+// than the catalog's.
+const DefaultLanguage = "en"
+
+// ErrorResponse is the body of every AppError response.
+type ErrorResponse struct {
+	Error string `json:"error"` // Localized where a translation exists
+	Code  string `json:"code"`  // One of the utils.Err* codes
+}
+
+// Negotiate returns the supported language the client prefers most, from an
+// Accept-Language header such as "fr-CA,fr;q=0.9,en;q=0.5". Region subtags
+// fall back to their language.
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if _, ok := catalog[lang]; ok && q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+	// Stable, so equally weighted languages keep the client's order
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	if len(candidates) == 0 {
+		return DefaultLanguage
+	}
+	return candidates[0].lang
+}
+
+// Message returns the catalog message for code in lang, if there is one.
+func Message(lang, code string) (string, bool) {
+	msg, ok := catalog[lang][code]
+	return msg, ok
+}
+
+// WriteError responds with appErr's status and an ErrorResponse in the
+// request's language.
+func WriteError(w http.ResponseWriter, r *http.Request, appErr *utils.AppError) {
+	lang := Negotiate(r.Header.Get("Accept-Language"))
+	message := appErr.Message
+	if lang != DefaultLanguage {
+		if localized, ok := Message(lang, appErr.Code); ok {
+			message = localized
+		} else {
+			lang = DefaultLanguage
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+	w.WriteHeader(utils.AppErrorToHTTPStatus(appErr.Code))
+	json.NewEncoder(w).Encode(&ErrorResponse{Error: message, Code: appErr.Code})
+}
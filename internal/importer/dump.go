@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Records of a Reddit-format dump, as written by Pushshift and the Reddit
+// API. Only the fields the importer keeps are decoded.
+
+type redditSubreddit struct {
+	Name        string   `json:"display_name"`
+	Description string   `json:"public_description"`
+	Over18      bool     `json:"over18"`
+	Created     unixTime `json:"created_utc"`
+}
+
+type redditPost struct {
+	ID        string   `json:"id"`
+	Subreddit string   `json:"subreddit"`
+	Author    string   `json:"author"`
+	Title     string   `json:"title"`
+	Selftext  string   `json:"selftext"`
+	URL       string   `json:"url"`
+	IsSelf    bool     `json:"is_self"`
+	Score     int      `json:"score"`
+	Created   unixTime `json:"created_utc"`
+}
+
+type redditComment struct {
+	ID        string   `json:"id"`
+	LinkID    string   `json:"link_id"`   // "t3_" and the post's ID
+	ParentID  string   `json:"parent_id"` // "t1_" and a comment's ID, or the link ID for top-level comments
+	Subreddit string   `json:"subreddit"`
+	Author    string   `json:"author"`
+	Body      string   `json:"body"`
+	Score     int      `json:"score"`
+	Created   unixTime `json:"created_utc"`
+}
+
+// unixTime is a time in Unix seconds, which dumps write as an integer, a
+// float or a string depending on their age.
+type unixTime time.Time
+
+func (t *unixTime) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		return nil
+	}
+	secs, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("bad timestamp %s", data)
+	}
+	*t = unixTime(time.Unix(int64(secs), 0).UTC())
+	return nil
+}
+
+// dumpReader reads the records of a dump file: JSON objects one after
+// another, usually one per line, optionally gzipped. Objects wrapped the way
+// the Reddit API returns them, as {"kind": ..., "data": {...}}, are
+// unwrapped.
+type dumpReader struct {
+	file *os.File
+	dec  *json.Decoder
+	line int
+}
+
+func openDump(path string) (*dumpReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var r io.Reader = bufio.NewReaderSize(f, 1<<20)
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		r = gz
+	}
+	return &dumpReader{file: f, dec: json.NewDecoder(r)}, nil
+}
+
+// next decodes the next record into v, returning io.EOF after the last.
+func (d *dumpReader) next(v interface{}) error {
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		if err == io.EOF {
+			return err
+		}
+		return fmt.Errorf("record %d: %v", d.line+1, err)
+	}
+	d.line++
+
+	var wrapped struct {
+		Kind string          `json:"kind"`
+		Data json.RawMessage `json:"data"`
+	}
+	if bytes.Contains(raw, []byte(`"data"`)) && json.Unmarshal(raw, &wrapped) == nil && wrapped.Kind != "" && len(wrapped.Data) > 0 {
+		raw = wrapped.Data
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("record %d: %v", d.line, err)
+	}
+	return nil
+}
+
+// time is t, or now for a record without a timestamp.
+func (t unixTime) time() time.Time {
+	if time.Time(t).IsZero() {
+		return time.Now().UTC()
+	}
+	return time.Time(t)
+}
+
+func (d *dumpReader) Close() error {
+	return d.file.Close()
+}
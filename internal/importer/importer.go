@@ -0,0 +1,369 @@
+// Package importer loads Reddit-format dumps into the database through the
+// bulk insert methods of PostgresDB.
+//
+// Imported rows get IDs derived from their IDs in the dump, so importing the
+// same dump again adds nothing, and overlapping dumps can be loaded one after
+// another. Where each row came from is recorded in external_ids.
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/language"
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// idNamespace seeds the IDs of imported rows
+var idNamespace = uuid.MustParse("6a0f3c52-4b1e-4f7a-9d1c-0b8e2f4d7a61")
+
+// deletedAuthor is the author dumps show for deleted accounts. Their posts
+// and comments, and imported subreddits, belong to one placeholder user.
+const deletedAuthor = "[deleted]"
+
+// Options configure an Importer.
+type Options struct {
+	Source         string          // Recorded in external_ids and mixed into IDs; defaults to "reddit"
+	UsernamePrefix string          // Put before imported usernames, so they can't take local users' names
+	BatchSize      int             // Rows per bulk insert; defaults to 1000
+	Subreddits     map[string]bool // Only import these subreddits, by lowercase name; nil imports all
+	Limit          int             // Stop each file after this many records; 0 reads them all
+}
+
+// Stats counts what an Importer has read and written.
+type Stats struct {
+	Users, Subreddits int64 // Imported, including those an earlier import added
+	Posts, Comments   int64 // Added; ones already imported and comments on missing posts aren't counted
+	Read, Skipped     int64 // Records read, and those filtered out or unusable
+}
+
+// Importer reads dumps into the database.
+type Importer struct {
+	db    *database.PostgresDB
+	opts  Options
+	Stats Stats
+
+	users      map[string]uuid.UUID // Imported so far, by lowercase name
+	subreddits map[string]uuid.UUID // Likewise; existing subreddits of the same name are reused
+
+	pendingUsers      []*models.User
+	pendingSubreddits []*models.Subreddit
+	pendingPosts      []pendingPost
+	pendingComments   []*models.Comment
+	pendingIDs        []models.ExternalID
+}
+
+// pendingPost is a post waiting for its subreddit's ID, which isn't known
+// until the subreddit is inserted
+type pendingPost struct {
+	post      *models.Post
+	subreddit string
+}
+
+// New creates an Importer writing to db.
+func New(db *database.PostgresDB, opts Options) *Importer {
+	if opts.Source == "" {
+		opts.Source = "reddit"
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	return &Importer{
+		db:         db,
+		opts:       opts,
+		users:      make(map[string]uuid.UUID),
+		subreddits: make(map[string]uuid.UUID),
+	}
+}
+
+// id derives the ID of an imported row from its kind and external ID.
+func (im *Importer) id(kind models.ExternalKind, externalID string) uuid.UUID {
+	return uuid.NewSHA1(idNamespace, []byte(im.opts.Source+":"+string(kind)+":"+externalID))
+}
+
+func (im *Importer) wanted(subreddit string) bool {
+	return im.opts.Subreddits == nil || im.opts.Subreddits[strings.ToLower(subreddit)]
+}
+
+// ImportSubreddits reads a dump of subreddits. It's optional: subreddits
+// that posts mention are created anyway, but without descriptions.
+func (im *Importer) ImportSubreddits(ctx context.Context, path string) error {
+	return im.importFile(ctx, path, models.ExternalSubreddit, func(d *dumpReader) error {
+		var rec redditSubreddit
+		if err := d.next(&rec); err != nil {
+			return err
+		}
+		if rec.Name == "" || !im.wanted(rec.Name) {
+			im.Stats.Skipped++
+			return nil
+		}
+		im.subreddit(rec.Name, rec.Description, rec.Over18, rec.Created.time())
+		return nil
+	})
+}
+
+// ImportPosts reads a dump of submissions.
+func (im *Importer) ImportPosts(ctx context.Context, path string) error {
+	return im.importFile(ctx, path, models.ExternalPost, func(d *dumpReader) error {
+		var rec redditPost
+		if err := d.next(&rec); err != nil {
+			return err
+		}
+		title := strings.TrimSpace(rec.Title)
+		if rec.ID == "" || title == "" || rec.Subreddit == "" || !im.wanted(rec.Subreddit) {
+			im.Stats.Skipped++
+			return nil
+		}
+		created := rec.Created.time()
+		im.subreddit(rec.Subreddit, "", false, created)
+
+		externalID := "t3_" + rec.ID
+		post := &models.Post{
+			ID:        im.id(models.ExternalPost, externalID),
+			Title:     truncate(title, 300),
+			AuthorID:  im.user(rec.Author, created),
+			CreatedAt: created,
+			UpdatedAt: created,
+		}
+		post.Karma, post.Upvotes, post.Downvotes = votes(rec.Score)
+		if rec.IsSelf || rec.URL == "" {
+			post.Content = rec.Selftext
+		} else {
+			url := rec.URL
+			post.URL = &url
+		}
+		if lang := language.Detect(post.Title + "\n" + post.Content); lang != "" {
+			post.Language = &lang
+		}
+		im.pendingPosts = append(im.pendingPosts, pendingPost{post, strings.ToLower(rec.Subreddit)})
+		im.pendingIDs = append(im.pendingIDs, models.ExternalID{Source: im.opts.Source, Kind: models.ExternalPost, ExternalID: externalID, ID: post.ID})
+		return nil
+	})
+}
+
+// ImportComments reads a dump of comments. Comments on posts that weren't
+// imported are skipped, so import posts first.
+func (im *Importer) ImportComments(ctx context.Context, path string) error {
+	return im.importFile(ctx, path, models.ExternalComment, func(d *dumpReader) error {
+		var rec redditComment
+		if err := d.next(&rec); err != nil {
+			return err
+		}
+		if rec.ID == "" || !strings.HasPrefix(rec.LinkID, "t3_") || (rec.Subreddit != "" && !im.wanted(rec.Subreddit)) {
+			im.Stats.Skipped++
+			return nil
+		}
+		created := rec.Created.time()
+
+		externalID := "t1_" + rec.ID
+		comment := &models.Comment{
+			ID:        im.id(models.ExternalComment, externalID),
+			Content:   rec.Body,
+			AuthorID:  im.user(rec.Author, created),
+			PostID:    im.id(models.ExternalPost, rec.LinkID),
+			CreatedAt: created,
+			UpdatedAt: created,
+		}
+		comment.Karma, comment.Upvotes, comment.Downvotes = votes(rec.Score)
+		if strings.HasPrefix(rec.ParentID, "t1_") {
+			parentID := im.id(models.ExternalComment, rec.ParentID)
+			comment.ParentID = &parentID
+		}
+		im.pendingComments = append(im.pendingComments, comment)
+		im.pendingIDs = append(im.pendingIDs, models.ExternalID{Source: im.opts.Source, Kind: models.ExternalComment, ExternalID: externalID, ID: comment.ID})
+		return nil
+	})
+}
+
+// importFile reads records from path with read until the end or the limit,
+// flushing a batch whenever one fills up.
+func (im *Importer) importFile(ctx context.Context, path string, kind models.ExternalKind, read func(*dumpReader) error) error {
+	d, err := openDump(path)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	start := time.Now()
+	for n := 0; im.opts.Limit == 0 || n < im.opts.Limit; n++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := read(d); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		im.Stats.Read++
+		if im.batchFull() {
+			if err := im.Flush(ctx); err != nil {
+				return err
+			}
+			log.Printf("Imported %d records of %s so far", d.line, path)
+		}
+	}
+	if err := im.Flush(ctx); err != nil {
+		return err
+	}
+	log.Printf("Read %d %s records from %s in %v", d.line, kind, path, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func (im *Importer) batchFull() bool {
+	return len(im.pendingPosts) >= im.opts.BatchSize || len(im.pendingComments) >= im.opts.BatchSize ||
+		len(im.pendingUsers) >= im.opts.BatchSize || len(im.pendingSubreddits) >= im.opts.BatchSize
+}
+
+// user returns the ID of a dump's author, queueing them to be added the
+// first time they're seen.
+func (im *Importer) user(author string, firstSeen time.Time) uuid.UUID {
+	if author == "" {
+		author = deletedAuthor
+	}
+	key := strings.ToLower(author)
+	if id, ok := im.users[key]; ok {
+		return id
+	}
+	id := im.id(models.ExternalUser, key)
+	im.users[key] = id
+	im.pendingUsers = append(im.pendingUsers, &models.User{
+		ID:             id,
+		Username:       im.opts.UsernamePrefix + author,
+		Email:          id.String() + "@import.invalid",
+		HashedPassword: "!", // Matches no password, so nobody can log in as an imported user
+		CreatedAt:      firstSeen,
+	})
+	im.pendingIDs = append(im.pendingIDs, models.ExternalID{Source: im.opts.Source, Kind: models.ExternalUser, ExternalID: key, ID: id})
+	return id
+}
+
+// subreddit queues a subreddit to be added the first time it's seen. Its
+// owner is the deleted-account placeholder, so only admins moderate it until
+// moderators are appointed.
+func (im *Importer) subreddit(name, description string, nsfw bool, created time.Time) {
+	key := strings.ToLower(name)
+	if _, ok := im.subreddits[key]; ok {
+		return
+	}
+	id := im.id(models.ExternalSubreddit, key)
+	im.subreddits[key] = id
+	im.pendingSubreddits = append(im.pendingSubreddits, &models.Subreddit{
+		ID:          id,
+		Name:        name,
+		Description: description,
+		CreatorID:   im.user(deletedAuthor, created),
+		CreatedAt:   created,
+		NSFW:        nsfw,
+	})
+}
+
+// Flush writes everything queued: users first, then subreddits, posts and
+// comments, so each batch's references exist.
+func (im *Importer) Flush(ctx context.Context) error {
+	if len(im.pendingUsers) > 0 {
+		ids, err := im.db.BulkInsertUsers(ctx, im.pendingUsers)
+		if err != nil {
+			return err
+		}
+		for _, u := range im.pendingUsers {
+			if ids[u.Username] != u.ID {
+				return fmt.Errorf("username %s is taken by a user that wasn't imported from %s; import with a different username prefix", u.Username, im.opts.Source)
+			}
+		}
+		im.Stats.Users += int64(len(im.pendingUsers))
+		im.pendingUsers = im.pendingUsers[:0]
+	}
+
+	if len(im.pendingSubreddits) > 0 {
+		ids, err := im.db.BulkInsertSubreddits(ctx, im.pendingSubreddits)
+		if err != nil {
+			return err
+		}
+		for _, s := range im.pendingSubreddits {
+			key := strings.ToLower(s.Name)
+			id, ok := ids[s.Name]
+			if !ok {
+				return fmt.Errorf("subreddit %s wasn't imported", s.Name)
+			}
+			if id != s.ID {
+				log.Printf("Subreddit %s already exists; importing its posts into it", s.Name)
+			} else {
+				im.Stats.Subreddits++
+			}
+			im.subreddits[key] = id
+			im.pendingIDs = append(im.pendingIDs, models.ExternalID{Source: im.opts.Source, Kind: models.ExternalSubreddit, ExternalID: key, ID: id})
+		}
+		im.pendingSubreddits = im.pendingSubreddits[:0]
+	}
+
+	if len(im.pendingPosts) > 0 {
+		posts := make([]*models.Post, len(im.pendingPosts))
+		for i, p := range im.pendingPosts {
+			p.post.SubredditID = im.subreddits[p.subreddit]
+			posts[i] = p.post
+		}
+		added, err := im.db.BulkInsertPosts(ctx, posts)
+		if err != nil {
+			return err
+		}
+		im.Stats.Posts += added
+		im.pendingPosts = im.pendingPosts[:0]
+	}
+
+	if len(im.pendingComments) > 0 {
+		added, err := im.db.BulkInsertComments(ctx, im.pendingComments)
+		if err != nil {
+			return err
+		}
+		im.Stats.Comments += added
+		im.pendingComments = im.pendingComments[:0]
+	}
+
+	if len(im.pendingIDs) > 0 {
+		if err := im.db.SaveExternalIDs(ctx, im.pendingIDs); err != nil {
+			return err
+		}
+		im.pendingIDs = im.pendingIDs[:0]
+	}
+	return nil
+}
+
+// Finish brings the counters bulk inserts skip up to date: comment, reply,
+// member and post counts, and the karma of imported users.
+func (im *Importer) Finish(ctx context.Context) error {
+	if err := im.Flush(ctx); err != nil {
+		return err
+	}
+	start := time.Now()
+	fixed, err := im.db.ReconcileCounters(ctx)
+	if err != nil {
+		return err
+	}
+	synced, err := im.db.SyncImportedKarma(ctx, im.opts.Source)
+	if err != nil {
+		return err
+	}
+	log.Printf("Updated %d counters and %d users' karma in %v", fixed, synced, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// votes splits a dump's score into karma, upvotes and downvotes. Dumps only
+// keep the net score, so it's counted as upvotes or downvotes alone.
+func votes(score int) (karma, upvotes, downvotes int) {
+	return score, max(score, 0), max(-score, 0)
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,146 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// TypeExportSubreddit builds the archive of a SubredditExport.
+const TypeExportSubreddit = "subreddit.export"
+
+// exportPageSize is how many posts an export reads at a time
+const exportPageSize = 200
+
+// ExportPayload is the payload of TypeExportSubreddit.
+type ExportPayload struct {
+	ExportID uuid.UUID `json:"exportId"`
+}
+
+// SubredditExportKey is where an export's archive is stored.
+func SubredditExportKey(export *models.SubredditExport) string {
+	return "exports/" + export.SubredditID.String() + "/" + export.ID.String() + ".json"
+}
+
+// RegisterExportHandler wires subreddit exports, which write their archives
+// to store.
+func RegisterExportHandler(q *Queue, db database.Store, store storage.Storage) {
+	q.Register(TypeExportSubreddit, ExportSubredditHandler(db, store))
+}
+
+// ExportSubredditHandler builds a subreddit's archive and stores it. A
+// failed export isn't retried, since the subreddit may have changed by
+// then; it's marked failed and a moderator can request another.
+func ExportSubredditHandler(db database.Store, store storage.Storage) Handler {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		var p ExportPayload
+		if err := decode(payload, &p); err != nil {
+			return err
+		}
+		export, err := db.GetSubredditExport(ctx, p.ExportID)
+		if err != nil {
+			return err
+		}
+		if export.Status == models.ExportDone || export.Status == models.ExportFailed {
+			return nil
+		}
+
+		export.Status = models.ExportRunning
+		if err := db.UpdateSubredditExport(ctx, export); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		if err := buildSubredditArchive(ctx, db, store, export); err != nil {
+			msg := err.Error()
+			export.Status, export.Error = models.ExportFailed, &msg
+			if err := db.UpdateSubredditExport(ctx, export); err != nil {
+				slog.ErrorContext(ctx, "Failed to record export failure", "export_id", export.ID, "error", err)
+			}
+			return Permanent(fmt.Errorf("export %s of subreddit %s failed: %v", export.ID, export.SubredditID, err))
+		}
+
+		export.Status = models.ExportDone
+		if err := db.UpdateSubredditExport(ctx, export); err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "Exported subreddit", "subreddit_id", export.SubredditID, "posts", export.PostCount,
+			"comments", export.CommentCount, "bytes", export.SizeBytes, "duration", time.Since(start).Round(time.Millisecond))
+		return nil
+	}
+}
+
+// buildSubredditArchive reads the subreddit and writes its archive,
+// recording the key, size and counts on export.
+func buildSubredditArchive(ctx context.Context, db database.Store, store storage.Storage, export *models.SubredditExport) error {
+	sub, err := db.GetSubredditByID(ctx, export.SubredditID)
+	if err != nil {
+		return err
+	}
+	settings, err := db.GetSubredditSettings(ctx, sub.ID)
+	if err != nil {
+		return err
+	}
+	flairs, err := db.GetFlairs(ctx, sub.ID)
+	if err != nil {
+		return err
+	}
+	moderators, err := db.GetModerators(ctx, sub.ID)
+	if err != nil {
+		return err
+	}
+
+	archive := &models.SubredditArchive{
+		Version:    models.SubredditArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+		Subreddit:  sub,
+		Settings:   settings,
+		Flairs:     flairs,
+		Moderators: moderators,
+		Posts:      []*models.ArchivedPost{},
+	}
+	comments := 0
+	var after *models.Keyset
+	for {
+		posts, err := db.GetSubredditArchivePosts(ctx, sub.ID, exportPageSize, after)
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			postComments, err := db.GetPostComments(ctx, post.ID, uuid.Nil)
+			if err != nil {
+				return err
+			}
+			archive.Posts = append(archive.Posts, &models.ArchivedPost{Post: post, Comments: postComments})
+			comments += len(postComments)
+		}
+		if len(posts) < exportPageSize {
+			break
+		}
+		keyset := posts[len(posts)-1].Keyset()
+		after = &keyset
+	}
+
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return err
+	}
+	key := SubredditExportKey(export)
+	if err := store.Put(ctx, key, bytes.NewReader(data), "application/json"); err != nil {
+		return fmt.Errorf("failed to store archive: %v", err)
+	}
+	export.Key = &key
+	export.SizeBytes = int64(len(data))
+	export.PostCount = len(archive.Posts)
+	export.CommentCount = comments
+	return nil
+}
@@ -0,0 +1,255 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Job types handled by RegisterDefaultHandlers. Media processing jobs are
+// registered by the upload pipeline that produces them.
+const (
+	TypeSendEmail           = "email.send"
+	TypeDeliverWebhook      = "webhook.deliver"
+	TypeGenerateDigest      = "digest.generate"
+	TypeScheduleDigests     = "digest.schedule"
+	TypeReconcileCounters   = "counters.reconcile"
+	TypeSaveMessage         = "message.save"
+	TypeUpdateMessageStatus = "message.status"
+)
+
+// EmailPayload is the payload of TypeSendEmail.
+type EmailPayload struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// WebhookPayload is the payload of TypeDeliverWebhook. When Secret is set the
+// body is signed with HMAC-SHA256 in the X-Gator-Signature header.
+type WebhookPayload struct {
+	URL    string          `json:"url"`
+	Event  string          `json:"event"`
+	Body   json.RawMessage `json:"body"`
+	Secret string          `json:"secret,omitempty"`
+}
+
+// DigestPayload is the payload of TypeGenerateDigest.
+type DigestPayload struct {
+	UserID uuid.UUID `json:"userId"`
+}
+
+// MessageStatusPayload is the payload of TypeUpdateMessageStatus.
+type MessageStatusPayload struct {
+	MessageID   uuid.UUID `json:"messageId"`
+	IsRead      *bool     `json:"isRead,omitempty"`
+	IsDeleted   *bool     `json:"isDeleted,omitempty"`
+	IsDelivered *bool     `json:"isDelivered,omitempty"`
+}
+
+const (
+	digestPostLimit = 10
+	digestHourUTC   = 8 // Daily digests go out at 08:00 UTC
+)
+
+// RegisterDefaultHandlers wires the built-in job types.
+func RegisterDefaultHandlers(q *Queue, db database.Store, mail *config.MailConfig) {
+	q.Register(TypeSendEmail, EmailHandler(mail))
+	q.Register(TypeDeliverWebhook, WebhookHandler(&http.Client{Timeout: 10 * time.Second}))
+	q.Register(TypeGenerateDigest, DigestHandler(db, q))
+	q.Register(TypeScheduleDigests, ScheduleDigestsHandler(db, q))
+	q.Register(TypeReconcileCounters, ReconcileCountersHandler(db))
+	q.Register(TypeSaveMessage, SaveMessageHandler(db))
+	q.Register(TypeUpdateMessageStatus, UpdateMessageStatusHandler(db))
+}
+
+// decode unmarshals a payload, treating malformed payloads as permanent failures.
+func decode(payload json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(payload, v); err != nil {
+		return Permanent(fmt.Errorf("invalid payload: %v", err))
+	}
+	return nil
+}
+
+// EmailHandler sends email over SMTP, or logs it when no SMTP host is configured.
+func EmailHandler(mail *config.MailConfig) Handler {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		var p EmailPayload
+		if err := decode(payload, &p); err != nil {
+			return err
+		}
+		if p.To == "" {
+			return Permanent(fmt.Errorf("email has no recipient"))
+		}
+
+		if mail.Host == "" {
+			slog.InfoContext(ctx, "SMTP not configured, email not sent", "to", p.To, "subject", p.Subject)
+			return nil
+		}
+
+		msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+			mail.From, p.To, p.Subject, p.Body)
+		var auth smtp.Auth
+		if mail.Username != "" {
+			auth = smtp.PlainAuth("", mail.Username, mail.Password, mail.Host)
+		}
+		addr := fmt.Sprintf("%s:%d", mail.Host, mail.Port)
+		return smtp.SendMail(addr, auth, mail.From, []string{p.To}, []byte(msg))
+	}
+}
+
+// WebhookHandler POSTs the payload body to its URL. Client errors other than
+// 429 won't succeed on retry, so they fail the job permanently.
+func WebhookHandler(client *http.Client) Handler {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		var p WebhookPayload
+		if err := decode(payload, &p); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(p.Body))
+		if err != nil {
+			return Permanent(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Gator-Event", p.Event)
+		if p.Secret != "" {
+			mac := hmac.New(sha256.New, []byte(p.Secret))
+			mac.Write(p.Body)
+			req.Header.Set("X-Gator-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+
+		switch {
+		case resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+			return Permanent(fmt.Errorf("webhook %s rejected delivery: %s", p.URL, resp.Status))
+		default:
+			return fmt.Errorf("webhook %s returned %s", p.URL, resp.Status)
+		}
+	}
+}
+
+// DigestHandler builds a user's digest from the top of their feed and
+// enqueues it as an email. Users with nothing new get no email.
+func DigestHandler(db database.Store, q *Queue) Handler {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		var p DigestPayload
+		if err := decode(payload, &p); err != nil {
+			return err
+		}
+
+		user, err := db.GetUser(ctx, p.UserID)
+		if err != nil {
+			return err
+		}
+		posts, err := db.GetUserFeed(ctx, user.ID, digestPostLimit, 0, nil, user.ID, true, models.SortHot)
+		if err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			return nil
+		}
+
+		var body strings.Builder
+		fmt.Fprintf(&body, "Hi %s, here's what's new in your subreddits:\n\n", user.Username)
+		for _, post := range posts {
+			fmt.Fprintf(&body, "- r/%s: %s (%d points, %d comments)\n", post.SubredditName, post.Title, post.Karma, post.CommentCount)
+		}
+
+		return q.Enqueue(ctx, TypeSendEmail, EmailPayload{
+			To:      user.Email,
+			Subject: fmt.Sprintf("Your Gator Swamp digest: %d new posts", len(posts)),
+			Body:    body.String(),
+		}, UniqueKey(fmt.Sprintf("digest-email:%s:%s", user.ID, time.Now().UTC().Format("2006-01-02"))))
+	}
+}
+
+// ScheduleDigestsHandler fans out one digest job per user, then schedules
+// itself for the next day.
+func ScheduleDigestsHandler(db database.UserRepository, q *Queue) Handler {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		users, err := db.GetAllUsers(ctx)
+		if err != nil {
+			return err
+		}
+		today := time.Now().UTC().Format("2006-01-02")
+		for _, user := range users {
+			if err := q.Enqueue(ctx, TypeGenerateDigest, DigestPayload{UserID: user.ID},
+				UniqueKey(fmt.Sprintf("digest:%s:%s", user.ID, today))); err != nil {
+				return err
+			}
+		}
+		slog.InfoContext(ctx, "Scheduled digests", "users", len(users))
+		return ScheduleNextDigestRun(ctx, q)
+	}
+}
+
+// ScheduleNextDigestRun enqueues the digest fan-out for the next 08:00 UTC.
+// Safe to call on every startup; the unique key keeps one run per day.
+func ScheduleNextDigestRun(ctx context.Context, q *Queue) error {
+	now := time.Now().UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), digestHourUTC, 0, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return q.Enqueue(ctx, TypeScheduleDigests, struct{}{},
+		RunAt(next), UniqueKey("digest-schedule:"+next.Format("2006-01-02")))
+}
+
+// ReconcileCountersHandler repairs drifted denormalized counters.
+func ReconcileCountersHandler(db database.MaintenanceRepository) Handler {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		fixed, err := db.ReconcileCounters(ctx)
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "Reconciled counters", "rows", fixed)
+		return nil
+	}
+}
+
+// SaveMessageHandler persists a direct message.
+func SaveMessageHandler(db database.MessageRepository) Handler {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		var msg models.DirectMessage
+		if err := decode(payload, &msg); err != nil {
+			return err
+		}
+		return db.SaveMessage(ctx, &msg)
+	}
+}
+
+// UpdateMessageStatusHandler persists a direct message delivery/read/delete change.
+func UpdateMessageStatusHandler(db database.MessageRepository) Handler {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		var p MessageStatusPayload
+		if err := decode(payload, &p); err != nil {
+			return err
+		}
+		return db.UpdateMessageStatus(ctx, p.MessageID, p.IsRead, p.IsDeleted, p.IsDelivered)
+	}
+}
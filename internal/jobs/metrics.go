@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics for the job queue, exposed on /metrics.
+var (
+	jobsEnqueued = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_jobs_enqueued_total",
+		Help: "Background jobs enqueued, by type.",
+	}, []string{"type"})
+
+	jobsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_jobs_processed_total",
+		Help: "Background job attempts, by type and result (done, retry, failed).",
+	}, []string{"type", "result"})
+
+	jobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gator_job_duration_seconds",
+		Help:    "Time spent running background job handlers, by type.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"type"})
+)
+
+// Prometheus metrics for scheduled maintenance tasks.
+var (
+	taskRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_scheduled_task_runs_total",
+		Help: "Scheduled task runs on this instance, by task and result (done, failed).",
+	}, []string{"task", "result"})
+
+	taskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gator_scheduled_task_duration_seconds",
+		Help:    "Time spent running scheduled tasks, by task.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"task"})
+
+	taskRowsAffected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_scheduled_task_rows_affected_total",
+		Help: "Rows touched by scheduled tasks, by task.",
+	}, []string{"task"})
+
+	taskLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gator_scheduled_task_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful run of each scheduled task on this instance.",
+	}, []string{"task"})
+
+	retentionRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_retention_rows_total",
+		Help: "Rows purged by retention policies, by target and mode (deleted, would_delete).",
+	}, []string{"target", "mode"})
+)
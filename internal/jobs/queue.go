@@ -0,0 +1,247 @@
+// Package jobs runs background work from a persistent, Postgres-backed queue.
+// Jobs survive restarts, are retried with backoff, and can be processed by
+// several engine instances at once.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMaxAttempts = 5
+	maxBackoff         = time.Hour
+)
+
+// Handler processes one job's payload. Returning an error retries the job
+// with backoff until it runs out of attempts; wrap the error with Permanent
+// to fail it immediately.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying (e.g. a malformed payload).
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Options tunes the worker pool.
+type Options struct {
+	Workers      int           // Concurrent jobs per instance
+	PollInterval time.Duration // How often idle workers check for due jobs
+	Lease        time.Duration // How long a job may run before another worker reclaims it
+}
+
+// DefaultOptions returns the pool settings used when none are configured.
+func DefaultOptions() Options {
+	return Options{Workers: 4, PollInterval: time.Second, Lease: 5 * time.Minute}
+}
+
+// Queue enqueues jobs and runs registered handlers for them.
+type Queue struct {
+	db       database.JobRepository
+	opts     Options
+	handlers map[string]Handler
+	mu       sync.RWMutex
+	wake     chan struct{} // Nudges an idle worker when a job is enqueued locally
+}
+
+// NewQueue creates a queue backed by the jobs table.
+func NewQueue(db database.JobRepository, opts Options) *Queue {
+	defaults := DefaultOptions()
+	if opts.Workers <= 0 {
+		opts.Workers = defaults.Workers
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaults.PollInterval
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = defaults.Lease
+	}
+	return &Queue{
+		db:       db,
+		opts:     opts,
+		handlers: make(map[string]Handler),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler for a job type. Register handlers before Run.
+func (q *Queue) Register(jobType string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+// EnqueueOption customizes a job at enqueue time.
+type EnqueueOption func(*models.Job)
+
+// RunAt delays the job until t.
+func RunAt(t time.Time) EnqueueOption {
+	return func(j *models.Job) { j.RunAt = t }
+}
+
+// UniqueKey skips the enqueue if a pending or running job already has key.
+func UniqueKey(key string) EnqueueOption {
+	return func(j *models.Job) { j.UniqueKey = &key }
+}
+
+// MaxAttempts overrides how many times the job is tried before failing.
+func MaxAttempts(n int) EnqueueOption {
+	return func(j *models.Job) { j.MaxAttempts = n }
+}
+
+// Enqueue persists a job for payload, which is encoded as JSON.
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}, opts ...EnqueueOption) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s job payload: %v", jobType, err)
+	}
+
+	job := &models.Job{
+		ID:          uuid.New(),
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: defaultMaxAttempts,
+		RunAt:       time.Now(),
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	inserted, err := q.db.EnqueueJob(ctx, job)
+	if err != nil {
+		return err
+	}
+	if !inserted {
+		return nil // Already queued under the same unique key
+	}
+	jobsEnqueued.WithLabelValues(jobType).Inc()
+
+	if !job.RunAt.After(time.Now()) {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Run processes jobs until ctx is cancelled, then waits for in-flight jobs
+// to finish.
+func (q *Queue) Run(ctx context.Context) {
+	slog.Info("Job queue started", "workers", q.opts.Workers)
+	var wg sync.WaitGroup
+	for i := 0; i < q.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+	slog.Info("Job queue stopped")
+}
+
+func (q *Queue) work(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-q.wake:
+		}
+
+		// Keep draining while there is work; only sleep once the queue is empty
+		for ctx.Err() == nil {
+			claimed, err := q.db.ClaimJobs(ctx, 1, q.opts.Lease)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("Failed to claim jobs", "error", err)
+				}
+				break
+			}
+			if len(claimed) == 0 {
+				break
+			}
+			q.process(claimed[0])
+		}
+		timer.Reset(q.opts.PollInterval)
+	}
+}
+
+// process runs one claimed job. It uses its own context so a shutdown
+// doesn't abort a job halfway; the lease bounds how long it may take.
+func (q *Queue) process(job *models.Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.opts.Lease)
+	defer cancel()
+
+	q.mu.RLock()
+	handler, ok := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	start := time.Now()
+	var err error
+	if !ok {
+		err = Permanent(fmt.Errorf("no handler registered for job type %q", job.Type))
+	} else {
+		err = runHandler(ctx, handler, job.Payload)
+	}
+	jobDuration.WithLabelValues(job.Type).Observe(time.Since(start).Seconds())
+
+	if err == nil {
+		jobsProcessed.WithLabelValues(job.Type, "done").Inc()
+		if err := q.db.CompleteJob(ctx, job.ID); err != nil {
+			slog.ErrorContext(ctx, "Failed to mark job done", "job_id", job.ID, "error", err)
+		}
+		return
+	}
+
+	var permanent *permanentError
+	var retryAt *time.Time
+	if !errors.As(err, &permanent) && job.Attempts < job.MaxAttempts {
+		t := time.Now().Add(backoff(job.Attempts))
+		retryAt = &t
+		jobsProcessed.WithLabelValues(job.Type, "retry").Inc()
+		slog.WarnContext(ctx, "Job failed, retrying", "job_id", job.ID, "type", job.Type,
+			"attempt", job.Attempts, "max_attempts", job.MaxAttempts, "retry_at", t, "error", err)
+	} else {
+		jobsProcessed.WithLabelValues(job.Type, "failed").Inc()
+		slog.ErrorContext(ctx, "Job failed permanently", "job_id", job.ID, "type", job.Type, "attempts", job.Attempts, "error", err)
+	}
+	if err := q.db.FailJob(ctx, job.ID, err.Error(), retryAt); err != nil {
+		slog.ErrorContext(ctx, "Failed to record job failure", "job_id", job.ID, "error", err)
+	}
+}
+
+// runHandler turns handler panics into errors so one bad job can't kill a worker.
+func runHandler(ctx context.Context, handler Handler, payload json.RawMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return handler(ctx, payload)
+}
+
+// backoff grows quadratically with the attempt number: 10s, 40s, 90s, ...
+func backoff(attempt int) time.Duration {
+	d := time.Duration(attempt*attempt) * 10 * time.Second
+	return min(d, maxBackoff)
+}
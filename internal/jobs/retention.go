@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database"
+)
+
+// RetentionPolicy keeps a retention target's rows for MaxAge.
+type RetentionPolicy struct {
+	Target string // One of the database.Retention* targets
+	MaxAge time.Duration
+}
+
+// RetentionPolicies returns the enabled policies in cfg.
+func RetentionPolicies(cfg *config.RetentionConfig) []RetentionPolicy {
+	days := []struct {
+		target string
+		days   int
+	}{
+		{database.RetentionAuditLog, cfg.AuditDays},
+		{database.RetentionJobs, cfg.JobsDays},
+		{database.RetentionPostViews, cfg.PostViewsDays},
+		{database.RetentionRefreshTokens, cfg.RefreshTokensDays},
+		{database.RetentionDeletedComments, cfg.DeletedDays},
+		{database.RetentionDeletedPosts, cfg.DeletedDays},
+	}
+
+	var policies []RetentionPolicy
+	for _, d := range days {
+		if d.days > 0 {
+			policies = append(policies, RetentionPolicy{Target: d.target, MaxAge: time.Duration(d.days) * 24 * time.Hour})
+		}
+	}
+	return policies
+}
+
+// RegisterRetentionTasks adds a daily purge task per enabled policy. In dry
+// run mode the tasks only count and log the rows they would delete.
+func RegisterRetentionTasks(s *Scheduler, db database.MaintenanceRepository, cfg *config.RetentionConfig) {
+	for _, policy := range RetentionPolicies(cfg) {
+		s.Add(Task{Name: "retention." + policy.Target, Interval: 24 * time.Hour, Run: purgeTask(db, policy, cfg.DryRun)})
+	}
+}
+
+func purgeTask(db database.MaintenanceRepository, policy RetentionPolicy, dryRun bool) func(ctx context.Context) (int64, error) {
+	return func(ctx context.Context) (int64, error) {
+		rows, err := db.PurgeExpired(ctx, policy.Target, policy.MaxAge, dryRun)
+		if dryRun {
+			retentionRows.WithLabelValues(policy.Target, "would_delete").Add(float64(rows))
+			if err == nil {
+				slog.InfoContext(ctx, "Retention dry run", "target", policy.Target, "rows", rows, "max_age", policy.MaxAge)
+			}
+			return 0, err
+		}
+		retentionRows.WithLabelValues(policy.Target, "deleted").Add(float64(rows))
+		return rows, err
+	}
+}
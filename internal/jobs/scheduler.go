@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gator-swamp/internal/database"
+)
+
+// Task is a periodic maintenance task. Run returns the number of rows it
+// touched, which is exported as a metric.
+type Task struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) (int64, error)
+}
+
+// Scheduler runs periodic tasks. Each run takes a lease in the
+// scheduled_tasks table, so with several engine instances a task runs on
+// only one of them per interval.
+type Scheduler struct {
+	db    database.MaintenanceRepository
+	tasks []Task
+	tick  time.Duration
+}
+
+// NewScheduler creates a scheduler that checks for due tasks every tick.
+func NewScheduler(db database.MaintenanceRepository, tick time.Duration) *Scheduler {
+	if tick <= 0 {
+		tick = 30 * time.Second
+	}
+	return &Scheduler{db: db, tick: tick}
+}
+
+// Add registers a task. Add tasks before Run.
+func (s *Scheduler) Add(task Task) {
+	s.tasks = append(s.tasks, task)
+}
+
+// Run checks for due tasks until ctx is cancelled, then waits for running
+// tasks to finish.
+func (s *Scheduler) Run(ctx context.Context) {
+	slog.Info("Scheduler started", "tasks", len(s.tasks))
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+
+	var wg sync.WaitGroup
+	running := make(map[string]bool)
+	var mu sync.Mutex
+
+	for {
+		for _, task := range s.tasks {
+			mu.Lock()
+			busy := running[task.Name]
+			if !busy {
+				running[task.Name] = true
+			}
+			mu.Unlock()
+			if busy {
+				continue
+			}
+
+			wg.Add(1)
+			go func(task Task) {
+				defer wg.Done()
+				s.runTask(task)
+				mu.Lock()
+				delete(running, task.Name)
+				mu.Unlock()
+			}(task)
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			slog.Info("Scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runTask runs task if it is due and no other instance holds its lease. Like
+// job handlers, it uses its own context so shutdown doesn't abort it halfway.
+func (s *Scheduler) runTask(task Task) {
+	lease := max(task.Interval, time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), lease)
+	defer cancel()
+
+	acquired, err := s.db.AcquireTaskLease(ctx, task.Name, task.Interval, lease)
+	if err != nil {
+		slog.Error("Failed to acquire task lease", "task", task.Name, "error", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	start := time.Now()
+	rows, err := runTaskFunc(ctx, task)
+	taskDuration.WithLabelValues(task.Name).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		taskRuns.WithLabelValues(task.Name, "failed").Inc()
+		slog.Error("Scheduled task failed", "task", task.Name, "error", err)
+	} else {
+		taskRuns.WithLabelValues(task.Name, "done").Inc()
+		taskRowsAffected.WithLabelValues(task.Name).Add(float64(rows))
+		taskLastSuccess.WithLabelValues(task.Name).SetToCurrentTime()
+		if rows > 0 {
+			slog.Info("Scheduled task finished", "task", task.Name, "rows", rows, "duration", time.Since(start).Round(time.Millisecond))
+		}
+	}
+
+	if releaseErr := s.db.ReleaseTaskLease(ctx, task.Name, err); releaseErr != nil {
+		slog.Error("Failed to release task lease", "task", task.Name, "error", releaseErr)
+	}
+}
+
+// RegisterMaintenanceTasks adds the built-in maintenance tasks.
+func RegisterMaintenanceTasks(s *Scheduler, db database.MaintenanceRepository, archiveAfter time.Duration) {
+	s.Add(Task{Name: "hot_scores.decay", Interval: 5 * time.Minute, Run: func(ctx context.Context) (int64, error) {
+		return db.DecayHotScores(ctx, 7*24*time.Hour)
+	}})
+	s.Add(Task{Name: "karma_velocity.update", Interval: time.Hour, Run: db.UpdateKarmaVelocity})
+	s.Add(Task{Name: "trending.refresh", Interval: 10 * time.Minute, Run: func(ctx context.Context) (int64, error) {
+		return db.RefreshTrendingSubreddits(ctx, 24*time.Hour, 25)
+	}})
+	s.Add(Task{Name: "posts.archive", Interval: time.Hour, Run: func(ctx context.Context) (int64, error) {
+		return db.ArchiveOldPosts(ctx, archiveAfter)
+	}})
+	s.Add(Task{Name: "connections.cleanup", Interval: 10 * time.Minute, Run: func(ctx context.Context) (int64, error) {
+		return db.ClearStaleConnections(ctx, 5*time.Minute)
+	}})
+	s.Add(Task{Name: "counters.reconcile", Interval: 6 * time.Hour, Run: db.ReconcileCounters})
+}
+
+// runTaskFunc turns task panics into errors so the lease is still released.
+func runTaskFunc(ctx context.Context, task Task) (rows int64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return task.Run(ctx)
+}
@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	sitemapMaxURLs         = 50000 // The sitemap protocol's limit per file
+	subredditIndexMaxPosts = 1000
+)
+
+// SitemapKey is where the generated sitemap is stored.
+const SitemapKey = "sitemap/sitemap.xml"
+
+// SubredditIndexKey is where a subreddit's generated index is stored.
+func SubredditIndexKey(subredditID uuid.UUID) string {
+	return "sitemap/r/" + subredditID.String() + ".json"
+}
+
+// SubredditIndex lists a subreddit's public post URLs, most recently changed
+// first.
+type SubredditIndex struct {
+	Subreddit   string                `json:"subreddit"`
+	GeneratedAt time.Time             `json:"generatedAt"`
+	Posts       []SubredditIndexEntry `json:"posts"`
+}
+
+// SubredditIndexEntry is one post of a SubredditIndex.
+type SubredditIndexEntry struct {
+	ID      uuid.UUID `json:"id"`
+	Title   string    `json:"title"`
+	URL     string    `json:"url"`
+	LastMod time.Time `json:"lastmod"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// RegisterSitemapTask adds an hourly task that regenerates the sitemap and
+// subreddit indexes into store, linking to the web frontend at baseURL.
+func RegisterSitemapTask(s *Scheduler, db database.PostRepository, store storage.Storage, baseURL string) {
+	s.Add(Task{Name: "sitemap.generate", Interval: time.Hour, Run: func(ctx context.Context) (int64, error) {
+		return GenerateSitemap(ctx, db, store, baseURL)
+	}})
+}
+
+// GenerateSitemap writes sitemap.xml and one JSON index per subreddit with
+// posts. Frontend URLs are <baseURL>/r/<subreddit> and
+// <baseURL>/r/<subreddit>/comments/<post>. It returns the number of posts
+// listed.
+func GenerateSitemap(ctx context.Context, db database.PostRepository, store storage.Storage, baseURL string) (int64, error) {
+	entries, err := db.GetSitemapEntries(ctx, subredditIndexMaxPosts)
+	if err != nil {
+		return 0, err
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	now := time.Now().UTC()
+
+	// Entries come most recently changed first, so a subreddit's first entry
+	// is its last modification
+	urlSet := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	indexes := make(map[uuid.UUID]*SubredditIndex)
+	var postURLs []sitemapURL
+	for _, e := range entries {
+		subURL := baseURL + "/r/" + url.PathEscape(e.SubredditName)
+		postURL := subURL + "/comments/" + e.PostID.String()
+
+		index, ok := indexes[e.SubredditID]
+		if !ok {
+			index = &SubredditIndex{Subreddit: e.SubredditName, GeneratedAt: now}
+			indexes[e.SubredditID] = index
+			urlSet.URLs = append(urlSet.URLs, sitemapURL{Loc: subURL, LastMod: e.LastMod.UTC().Format(time.RFC3339)})
+		}
+		index.Posts = append(index.Posts, SubredditIndexEntry{ID: e.PostID, Title: e.Title, URL: postURL, LastMod: e.LastMod})
+		postURLs = append(postURLs, sitemapURL{Loc: postURL, LastMod: e.LastMod.UTC().Format(time.RFC3339)})
+	}
+	urlSet.URLs = append(urlSet.URLs, postURLs...)
+	if len(urlSet.URLs) > sitemapMaxURLs {
+		urlSet.URLs = urlSet.URLs[:sitemapMaxURLs]
+	}
+
+	for subredditID, index := range indexes {
+		data, err := json.Marshal(index)
+		if err != nil {
+			return 0, err
+		}
+		if err := store.Put(ctx, SubredditIndexKey(subredditID), bytes.NewReader(data), "application/json"); err != nil {
+			return 0, fmt.Errorf("failed to store index of r/%s: %v", index.Subreddit, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(urlSet); err != nil {
+		return 0, err
+	}
+	if err := store.Put(ctx, SitemapKey, &buf, "application/xml"); err != nil {
+		return 0, fmt.Errorf("failed to store sitemap: %v", err)
+	}
+	return int64(len(entries)), nil
+}
@@ -0,0 +1,129 @@
+// Package language guesses which language a post is written in. It is a
+// small heuristic classifier rather than a statistical model: text in a
+// script only one supported language uses is identified by its script, and
+// text in Latin script by which language's common words it uses most.
+// Detect only answers when the text makes it fairly clear.
+package language
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Languages are the languages Detect recognises, as ISO 639-1 codes.
+var Languages = []string{
+	"ar", "de", "el", "en", "es", "fr", "he", "hi", "it", "ja",
+	"ko", "nl", "pl", "pt", "ru", "sv", "th", "tr", "uk", "zh",
+}
+
+// Supported reports whether code is one of Languages.
+func Supported(code string) bool {
+	return slices.Contains(Languages, code)
+}
+
+// minWordHits is how many common words Latin-script text needs before its
+// language is guessed
+const minWordHits = 2
+
+// scripts identify the languages that are the only supported ones written in
+// a script. Cyrillic, Han and kana need a closer look, in Detect.
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Arabic, "ar"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Hangul, "ko"},
+	{unicode.Thai, "th"},
+}
+
+// Detect returns the language text is most likely written in, or "" if it's
+// too short or too mixed to tell.
+func Detect(text string) string {
+	var latin, cyrillic, ukrainian, han, kana, letters int
+	other := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				ukrainian++
+			}
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		default:
+			for _, s := range scripts {
+				if unicode.Is(s.table, r) {
+					other[s.lang]++
+					break
+				}
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// A script counts when it has at least half the letters, so a few
+	// foreign names or loanwords don't decide
+	majority := func(n int) bool { return n*2 >= letters }
+	switch {
+	case majority(kana + han):
+		if kana > 0 {
+			return "ja"
+		}
+		return "zh"
+	case majority(cyrillic):
+		if ukrainian > 0 {
+			return "uk"
+		}
+		return "ru"
+	}
+	for lang, n := range other {
+		if majority(n) {
+			return lang
+		}
+	}
+	if majority(latin) {
+		return detectLatin(text)
+	}
+	return ""
+}
+
+// detectLatin picks the Latin-script language whose common words text uses
+// most, if one clearly leads.
+func detectLatin(text string) string {
+	hits := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, word := range words {
+		for _, lang := range commonWords[word] {
+			hits[lang]++
+		}
+	}
+
+	best, bestHits, runnerUp := "", 0, 0
+	for lang, n := range hits {
+		if n > bestHits {
+			best, bestHits, runnerUp = lang, n, bestHits
+		} else if n > runnerUp {
+			runnerUp = n
+		}
+	}
+	if bestHits < minWordHits || bestHits == runnerUp {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,39 @@
+package language
+
+// words are frequent short words of each Latin-script language: articles,
+// pronouns, prepositions and the like, which turn up in almost any sentence.
+// Words several languages share count for each of them; the ones they don't
+// share tell them apart.
+var words = map[string][]string{
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "ich", "zu", "den", "mit", "sich", "auf",
+		"für", "es", "von", "dem", "auch", "wie", "aber", "wir", "sie", "sind", "noch", "nur", "oder", "wenn", "habe"},
+	"en": {"the", "and", "is", "are", "was", "were", "of", "to", "in", "that", "it", "for", "with", "you", "this",
+		"have", "not", "but", "on", "be", "what", "my", "they", "from", "would", "there", "about", "just", "i'm"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "en", "un", "una", "es", "por", "con", "para", "no", "se",
+		"lo", "como", "pero", "más", "este", "esta", "está", "muy", "también", "porque", "del", "al", "yo"},
+	"fr": {"le", "la", "les", "de", "des", "et", "est", "un", "une", "que", "qui", "dans", "pour", "pas", "sur",
+		"ce", "avec", "je", "il", "elle", "nous", "vous", "sont", "mais", "du", "au", "très", "cette", "c'est"},
+	"it": {"il", "lo", "la", "gli", "le", "di", "che", "e", "è", "un", "una", "per", "non", "sono", "con", "del",
+		"della", "mi", "ma", "anche", "questo", "questa", "come", "più", "ho", "perché", "molto", "nel"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "ik", "je", "op", "te", "zijn", "met", "voor",
+		"maar", "ook", "er", "als", "dit", "wat", "bij", "nog", "naar", "wel", "hij", "geen"},
+	"pl": {"i", "w", "nie", "na", "się", "z", "że", "do", "jest", "to", "jak", "ale", "co", "o", "tak", "po",
+		"od", "za", "jestem", "tylko", "czy", "już", "też", "jego", "może"},
+	"pt": {"o", "a", "os", "as", "de", "que", "e", "é", "um", "uma", "não", "em", "para", "com", "do", "da",
+		"dos", "das", "se", "mais", "por", "mas", "como", "eu", "você", "muito", "também", "isso"},
+	"sv": {"och", "att", "det", "som", "en", "är", "på", "av", "för", "med", "inte", "jag", "har", "den", "till",
+		"om", "var", "men", "ett", "så", "vi", "kan", "från", "eller", "hur"},
+	"tr": {"ve", "bir", "bu", "da", "de", "için", "ile", "çok", "ne", "ama", "gibi", "daha", "olarak", "var",
+		"yok", "ben", "sen", "değil", "mi", "ki", "her", "şey", "kadar"},
+}
+
+// commonWords maps each word to the languages it's common in
+var commonWords = func() map[string][]string {
+	index := make(map[string][]string)
+	for lang, list := range words {
+		for _, word := range list {
+			index[word] = append(index[word], lang)
+		}
+	}
+	return index
+}()
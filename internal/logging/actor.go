@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// RequestIDHeader is the message header a request's ID travels in between
+// actors.
+const RequestIDHeader = "request_id"
+
+// SenderMiddleware stamps the messages an actor sends, replies included,
+// with the request ID of the message it's handling. Give it to every actor's
+// props so IDs follow requests from actor to actor.
+func SenderMiddleware(next actor.SenderFunc) actor.SenderFunc {
+	return func(c actor.SenderContext, target *actor.PID, envelope *actor.MessageEnvelope) {
+		if envelope.GetHeader(RequestIDHeader) == "" {
+			if header := c.MessageHeader(); header != nil {
+				if id := header.Get(RequestIDHeader); id != "" {
+					envelope.SetHeader(RequestIDHeader, id)
+				}
+			}
+		}
+		next(c, target, envelope)
+	}
+}
+
+// Props returns props for the actor producer makes, with SenderMiddleware.
+func Props(producer actor.Producer, opts ...actor.PropsOption) *actor.Props {
+	return actor.PropsFromProducer(producer, append(opts, actor.WithSenderMiddleware(SenderMiddleware))...)
+}
+
+// Root returns a root context whose messages carry the request ID of ctx,
+// or root itself outside a request.
+func Root(root *actor.RootContext, ctx context.Context) *actor.RootContext {
+	id := RequestID(ctx)
+	if id == "" {
+		return root
+	}
+	return actor.NewRootContext(root.ActorSystem(), map[string]string{RequestIDHeader: id}, SenderMiddleware)
+}
+
+// Context returns a context for the work an actor does handling its current
+// message, carrying the message's request ID.
+func Context(c actor.Context) context.Context {
+	ctx := context.Background()
+	if header := c.MessageHeader(); header != nil {
+		if id := header.Get(RequestIDHeader); id != "" {
+			ctx = WithRequestID(ctx, id)
+		}
+	}
+	return ctx
+}
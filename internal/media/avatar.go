@@ -0,0 +1,145 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"log/slog"
+	"path"
+	"strings"
+
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/jobs"
+	"gator-swamp/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// TypeProcessAvatar resizes an uploaded avatar and makes it the user's profile image.
+const TypeProcessAvatar = "media.avatar"
+
+// Variant is a resized rendition of an upload.
+type Variant struct {
+	Name string
+	Size int // Width and height in pixels
+}
+
+// AvatarVariants are generated for every avatar upload.
+var AvatarVariants = []Variant{
+	{Name: "thumb", Size: 64},
+	{Name: "medium", Size: 256},
+}
+
+// AvatarURLs lists where each rendition of a user's avatar is served.
+type AvatarURLs struct {
+	Original string `json:"original"`
+	Thumb    string `json:"thumb"`
+	Medium   string `json:"medium"`
+}
+
+// AvatarPayload is the payload of TypeProcessAvatar.
+type AvatarPayload struct {
+	UserID uuid.UUID `json:"userId"`
+	Key    string    `json:"key"`    // Storage key of the original upload
+	Format string    `json:"format"` // As reported by CheckImage
+}
+
+// AvatarKey returns the storage key for an original avatar upload. Variants
+// are stored alongside it in the same directory.
+func AvatarKey(userID, uploadID uuid.UUID, format string) string {
+	return fmt.Sprintf("avatars/%s/%s/original.%s", userID, uploadID, format)
+}
+
+// UploadKey returns the storage key for an image uploaded to /upload.
+func UploadKey(ownerID, mediaID uuid.UUID, format string) string {
+	return fmt.Sprintf("uploads/%s/%s.%s", ownerID, mediaID, format)
+}
+
+// variantKey returns the storage key of a variant of the original at key.
+func variantKey(originalKey, name string) string {
+	format := strings.TrimPrefix(path.Ext(originalKey), ".")
+	return path.Join(path.Dir(originalKey), name+extension(format))
+}
+
+// GetAvatarURLs returns the URLs for the avatar whose original is stored at
+// key (users.profile_image), or nil when the user has no avatar.
+func GetAvatarURLs(store storage.Storage, key *string) *AvatarURLs {
+	if key == nil || *key == "" {
+		return nil
+	}
+	return &AvatarURLs{
+		Original: store.URL(*key),
+		Thumb:    store.URL(variantKey(*key, "thumb")),
+		Medium:   store.URL(variantKey(*key, "medium")),
+	}
+}
+
+// RegisterHandlers wires the media processing job types.
+func RegisterHandlers(q *jobs.Queue, db database.Store, store storage.Storage) {
+	q.Register(TypeProcessAvatar, ProcessAvatarHandler(db, store))
+	q.Register(TypePostThumbnail, ThumbnailHandler(db, store, NewFetchClient()))
+}
+
+// ProcessAvatarHandler generates the avatar variants, points the user's
+// profile image at the new upload and removes the previous one. Variants are
+// stored before the profile is updated, so clients never see missing sizes.
+func ProcessAvatarHandler(db database.UserRepository, store storage.Storage) jobs.Handler {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		var p AvatarPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return jobs.Permanent(fmt.Errorf("invalid payload: %v", err))
+		}
+
+		r, err := store.Get(ctx, p.Key)
+		if err == storage.ErrNotFound {
+			return jobs.Permanent(fmt.Errorf("avatar upload %s is missing", p.Key))
+		}
+		if err != nil {
+			return err
+		}
+		src, _, err := image.Decode(r)
+		r.Close()
+		if err != nil {
+			return jobs.Permanent(fmt.Errorf("failed to decode avatar %s: %v", p.Key, err))
+		}
+
+		for _, v := range AvatarVariants {
+			data, contentType, err := encode(Square(src, v.Size), p.Format)
+			if err != nil {
+				return jobs.Permanent(err)
+			}
+			if err := store.Put(ctx, variantKey(p.Key, v.Name), bytes.NewReader(data), contentType); err != nil {
+				return err
+			}
+		}
+
+		user, err := db.GetUser(ctx, p.UserID)
+		if err != nil {
+			return err
+		}
+		if err := db.UpdateUserProfileImage(ctx, p.UserID, p.Key); err != nil {
+			return err
+		}
+
+		if previous := user.ProfileImage; previous != nil && *previous != "" && *previous != p.Key {
+			deleteAvatar(ctx, store, *previous)
+		}
+		return nil
+	}
+}
+
+// deleteAvatar removes an avatar's original and variants, logging failures;
+// a leftover file only costs disk space.
+func deleteAvatar(ctx context.Context, store storage.Storage, key string) {
+	keys := []string{key}
+	for _, v := range AvatarVariants {
+		keys = append(keys, variantKey(key, v.Name))
+	}
+	for _, k := range keys {
+		if err := store.Delete(ctx, k); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete old avatar file", "key", k, "error", err)
+		}
+	}
+}
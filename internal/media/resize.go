@@ -0,0 +1,139 @@
+// Package media validates uploaded images and turns them into the resized
+// variants the clients display.
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	// Register decoders for the accepted upload formats
+	_ "image/gif"
+)
+
+// Formats accepted for upload, as reported by image.DecodeConfig.
+var allowedFormats = map[string]bool{"jpeg": true, "png": true, "gif": true}
+
+// maxPixels bounds decoded image size so a small, highly compressed upload
+// can't exhaust memory when decoded.
+const maxPixels = 40_000_000
+
+// CheckImage validates that data is an accepted image format of reasonable
+// dimensions and returns its format name ("jpeg", "png" or "gif").
+func CheckImage(data []byte) (string, error) {
+	format, _, err := InspectImage(data)
+	return format, err
+}
+
+// InspectImage is CheckImage that also returns the image's dimensions.
+func InspectImage(data []byte) (string, image.Config, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", cfg, fmt.Errorf("not a supported image: %v", err)
+	}
+	if !allowedFormats[format] {
+		return "", cfg, fmt.Errorf("unsupported image format %q", format)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return "", cfg, fmt.Errorf("image dimensions %dx%d out of range", cfg.Width, cfg.Height)
+	}
+	return format, cfg, nil
+}
+
+// Square center-crops src to a square and scales it to size x size,
+// averaging source pixels when shrinking.
+func Square(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side)
+
+	// Copy the crop into an RGBA buffer so sampling reads Pix directly
+	rgba := image.NewRGBA(crop)
+	offset := image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2)
+	draw.Draw(rgba, crop, src, offset, draw.Src)
+
+	return scale(rgba, size, size)
+}
+
+// Fit scales src down, keeping its aspect ratio, to fit within maxW x maxH.
+// Smaller images are copied at their original size.
+func Fit(src image.Image, maxW, maxH int) *image.RGBA {
+	b := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+
+	w, h := b.Dx(), b.Dy()
+	if w > maxW {
+		w, h = maxW, max(h*maxW/w, 1)
+	}
+	if h > maxH {
+		w, h = max(w*maxH/h, 1), maxH
+	}
+	return scale(rgba, w, h)
+}
+
+// scale resizes src (with bounds at the origin) to w x h by averaging the
+// source pixels that fall in each destination pixel.
+func scale(src *image.RGBA, w, h int) *image.RGBA {
+	srcW, srcH := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := y * srcH / h
+		y1 := max((y+1)*srcH/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := x * srcW / w
+			x1 := max((x+1)*srcW/w, x0+1)
+
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					bl += uint32(p[2])
+					a += uint32(p[3])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(bl / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// encode writes img as JPEG for photos, or PNG for formats that may carry
+// transparency. It returns the encoded bytes and their content type.
+func encode(img image.Image, sourceFormat string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if sourceFormat == "jpeg" {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// extension returns the file extension variants of sourceFormat are stored with.
+func extension(sourceFormat string) string {
+	if sourceFormat == "jpeg" {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+// ContentType returns the MIME type of an accepted upload format.
+func ContentType(format string) string {
+	return "image/" + format
+}
@@ -0,0 +1,159 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/jobs"
+	"gator-swamp/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// TypePostThumbnail generates the preview thumbnail of a link or image post.
+const TypePostThumbnail = "media.thumbnail"
+
+const (
+	thumbnailWidth  = 320
+	thumbnailHeight = 180
+	maxPageBytes    = 512 << 10 // Enough to reach the preview tags in a page's head
+	maxImageBytes   = 10 << 20
+)
+
+// ThumbnailPayload is the payload of TypePostThumbnail.
+type ThumbnailPayload struct {
+	PostID uuid.UUID `json:"postId"`
+	URL    string    `json:"url"`
+}
+
+// thumbnailKey returns the storage key for the thumbnail of source. Keys are
+// derived from the source URL, so posts linking the same page or image share
+// one cached thumbnail.
+func thumbnailKey(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return "thumbnails/" + hex.EncodeToString(sum[:16]) + ".jpg"
+}
+
+// ThumbnailHandler resolves a post's URL to an image (the URL itself, or the
+// page's og:image / twitter:image), stores a JPEG thumbnail of it and records
+// the thumbnail URL on the post. Links without a usable image are skipped.
+func ThumbnailHandler(db database.PostRepository, store storage.Storage, client *http.Client) jobs.Handler {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		var p ThumbnailPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return jobs.Permanent(fmt.Errorf("invalid payload: %v", err))
+		}
+
+		key := thumbnailKey(p.URL)
+		if cached, err := store.Get(ctx, key); err == nil {
+			cached.Close()
+			return db.UpdatePostThumbnail(ctx, p.PostID, store.URL(key))
+		}
+
+		img, err := fetchPreviewImage(ctx, client, p.URL)
+		if err != nil {
+			var status *statusError
+			if (errors.As(err, &status) && !status.retryable()) || errors.Is(err, errNoPreview) || errors.Is(err, errPrivateAddress) {
+				return jobs.Permanent(err)
+			}
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, flatten(Fit(img, thumbnailWidth, thumbnailHeight)), &jpeg.Options{Quality: 80}); err != nil {
+			return jobs.Permanent(err)
+		}
+		if err := store.Put(ctx, key, &buf, "image/jpeg"); err != nil {
+			return err
+		}
+		return db.UpdatePostThumbnail(ctx, p.PostID, store.URL(key))
+	}
+}
+
+// errNoPreview means the URL has no image to build a thumbnail from.
+var errNoPreview = errors.New("no preview image")
+
+// fetchPreviewImage fetches rawURL and returns the image it points to, either
+// directly or through the preview meta tags of an HTML page.
+func fetchPreviewImage(ctx context.Context, client *http.Client, rawURL string) (image.Image, error) {
+	body, contentType, err := fetch(ctx, client, rawURL, maxImageBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		imageURL := previewImageURL(body[:min(len(body), maxPageBytes)], rawURL)
+		if imageURL == "" {
+			return nil, fmt.Errorf("%w: %s has no og:image", errNoPreview, rawURL)
+		}
+		body, _, err = fetch(ctx, client, imageURL, maxImageBytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := CheckImage(body); err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoPreview, err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoPreview, err)
+	}
+	return img, nil
+}
+
+var (
+	metaTagPattern   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	metaAttrPattern  = regexp.MustCompile(`(?is)([a-z:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	previewImageTags = map[string]bool{"og:image": true, "og:image:url": true, "og:image:secure_url": true, "twitter:image": true}
+)
+
+// previewImageURL finds the first og:image or twitter:image meta tag in page
+// and resolves it against pageURL.
+func previewImageURL(page []byte, pageURL string) string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	for _, tag := range metaTagPattern.FindAll(page, -1) {
+		attrs := make(map[string]string)
+		for _, m := range metaAttrPattern.FindAllSubmatch(tag, -1) {
+			attrs[strings.ToLower(string(m[1]))] = string(m[2]) + string(m[3])
+		}
+		name := strings.ToLower(attrs["property"] + attrs["name"])
+		if !previewImageTags[name] || attrs["content"] == "" {
+			continue
+		}
+		ref, err := url.Parse(strings.TrimSpace(html.UnescapeString(attrs["content"])))
+		if err != nil {
+			continue
+		}
+		return base.ResolveReference(ref).String()
+	}
+	return ""
+}
+
+// flatten draws img over white, since JPEG has no transparency.
+func flatten(img *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+
+	"gator-swamp/internal/captcha"
+	"gator-swamp/internal/policy"
+
+	"github.com/google/uuid"
+)
+
+// CaptchaHeader carries the token from a solved CAPTCHA
+const CaptchaHeader = "X-Captcha-Token"
+
+// ApplyCaptcha requires a valid CAPTCHA token on the given methods, or on
+// all if none are given. A nil verifier lets every request through.
+func ApplyCaptcha(handler http.HandlerFunc, verifier captcha.Verifier, methods ...string) http.HandlerFunc {
+	if verifier == nil {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(methods) > 0 && !slices.Contains(methods, r.Method) {
+			handler(w, r)
+			return
+		}
+		if verifyCaptcha(w, r, verifier) {
+			handler(w, r)
+		}
+	}
+}
+
+// ApplyLowKarmaCaptcha is ApplyCaptcha for users with less than minKarma;
+// others needn't send a token, nor do admins or the bots they create. It
+// must run inside ApplyJWTMiddleware. A minKarma of 0 turns it off.
+func ApplyLowKarmaCaptcha(handler http.HandlerFunc, verifier captcha.Verifier, users policy.UserLookup, minKarma int, methods ...string) http.HandlerFunc {
+	if verifier == nil || minKarma <= 0 {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(methods) > 0 && !slices.Contains(methods, r.Method) {
+			handler(w, r)
+			return
+		}
+		userID, ok := r.Context().Value(UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		user, err := users.GetUser(r.Context(), userID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to load user for CAPTCHA check", "user_id", userID, "error", err)
+			http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+			return
+		}
+		if user.Karma >= minKarma || user.IsAdmin || user.IsBot || verifyCaptcha(w, r, verifier) {
+			handler(w, r)
+		}
+	}
+}
+
+// verifyCaptcha checks the request's token, responding with an error if it
+// isn't valid.
+func verifyCaptcha(w http.ResponseWriter, r *http.Request, verifier captcha.Verifier) bool {
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr
+	}
+	err = verifier.Verify(r.Context(), r.Header.Get(CaptchaHeader), remoteIP)
+	switch {
+	case errors.Is(err, captcha.ErrFailed):
+		http.Error(w, "CAPTCHA verification failed", http.StatusForbidden)
+		return false
+	case err != nil:
+		slog.ErrorContext(r.Context(), "CAPTCHA verification failed", "error", err)
+		http.Error(w, "CAPTCHA verification unavailable", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
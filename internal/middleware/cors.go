@@ -31,6 +31,28 @@ func DefaultCORSConfig(allowedOrigins []string) *CORSConfig {
 	}
 }
 
+// originAllowed reports whether origin matches one of AllowedOrigins: "*",
+// the origin itself, or a wildcard such as "https://*.example.com", which
+// matches any subdomain of example.com (but not example.com) over https.
+func (c *CORSConfig) originAllowed(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		prefix, suffix, ok := strings.Cut(allowed, "*.")
+		if !ok || !strings.HasPrefix(origin, prefix) {
+			continue
+		}
+		// What the wildcard stands for: one or more labels, and nothing else
+		sub, ok := strings.CutSuffix(origin[len(prefix):], "."+suffix)
+		if ok && sub != "" && !strings.ContainsAny(sub, "/:@") {
+			return true
+		}
+	}
+	return false
+}
+
 // CORSMiddleware configures CORS for all requests
 func CORSMiddleware(config *CORSConfig) func(http.Handler) http.Handler {
 	if config == nil {
@@ -41,16 +63,10 @@ func CORSMiddleware(config *CORSConfig) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
 
-			originAllowed := false
-			for _, allowedOrigin := range config.AllowedOrigins {
-				// If "*" is in the list or the exact Origin matches, allow it.
-				if allowedOrigin == "*" || allowedOrigin == origin {
-					originAllowed = true
-					break
-				}
-			}
+			// The response depends on the Origin, so caches must key on it
+			w.Header().Add("Vary", "Origin")
 
-			if originAllowed {
+			if config.originAllowed(origin) {
 				// If "*" is in AllowedOrigins, you can set the header to "*"
 				// or keep setting it to the request origin—whichever you prefer.
 				w.Header().Set("Access-Control-Allow-Origin", origin)
@@ -83,16 +99,11 @@ func ApplyCORS(handler http.HandlerFunc, config *CORSConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
 
-		// Check if the origin is allowed
-		originAllowed := false
-		for _, allowedOrigin := range config.AllowedOrigins {
-			if allowedOrigin == "*" || allowedOrigin == origin {
-				originAllowed = true
-				break
-			}
-		}
+		// The response depends on the Origin, so caches must key on it
+		w.Header().Add("Vary", "Origin")
 
-		if originAllowed {
+		// Check if the origin is allowed
+		if config.originAllowed(origin) {
 			// Set CORS headers
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Methods", strings.Join(config.AllowedMethods, ", "))
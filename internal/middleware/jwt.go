@@ -3,9 +3,13 @@ package middleware
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -15,35 +19,75 @@ import (
 )
 
 const (
-	// JWT secret key for signing tokens
-	// In production, this should be loaded from environment variables or a secure vault
-	jwtSecret = "gatorswamp_secret_key_should_be_loaded_from_env"
+	// Development key tokens are signed with unless SetJWTSecret is called
+	defaultJWTSecret = "gatorswamp_secret_key_should_be_loaded_from_env"
 
 	// Token expiration time - 24 hours
 	tokenExpiration = 24 * time.Hour
+
+	// Refresh tokens outlive access tokens; each is used once
+	refreshTokenExpiration = 30 * 24 * time.Hour
+
+	// Impersonation tokens are short-lived
+	impersonationExpiration = 30 * time.Minute
+
+	// ScopeImpersonation marks a token an admin minted to act as another user
+	ScopeImpersonation = "impersonation"
 )
 
+// jwtSecret is the key tokens are signed with
+var jwtSecret = defaultJWTSecret
+
+// SetJWTSecret sets the key tokens are signed with. Call it before serving;
+// tokens signed with the previous key stop validating.
+func SetJWTSecret(secret string) {
+	jwtSecret = secret
+}
+
 // Claims represents the JWT claims for our application
 type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
+	// Set only on impersonation tokens: the admin acting as UserID
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty"`
+	Scope          string     `json:"scope,omitempty"`
+	Bot            bool       `json:"bot,omitempty"` // Issued to a bot account
 	jwt.RegisteredClaims
 }
 
+// IsImpersonation reports whether the token was minted for impersonation.
+func (c *Claims) IsImpersonation() bool {
+	return c.Scope == ScopeImpersonation && c.ImpersonatorID != nil
+}
+
+// ImpersonationAllowedWrites lists the routes impersonation tokens may send
+// requests other than GET, HEAD and OPTIONS to. Every other request that
+// could change state is refused, so routes added later stay read-only to
+// impersonators until they're listed here.
+var ImpersonationAllowedWrites = map[string]bool{}
+
+// AuditImpersonatedRequest, when set, is called after every request made
+// with an impersonation token, with the response status.
+var AuditImpersonatedRequest func(r *http.Request, claims *Claims, status int)
+
 // UnprotectedRoutes defines routes that don't require JWT authentication
 var UnprotectedRoutes = map[string]bool{
 	"/health":        true,
 	"/user/register": true,
 	"/user/login":    true,
+	"/user/refresh":  true,
+	"/user/logout":   true,
 }
 
-// GenerateToken creates a new JWT token for the given user ID
-func GenerateToken(userID uuid.UUID) (string, error) {
+// GenerateToken creates a new JWT token for the given user ID. Bot accounts'
+// tokens say so, for their scopes and rate limits.
+func GenerateToken(userID uuid.UUID, bot bool) (string, error) {
 	// Create token expiration time
 	expirationTime := time.Now().Add(tokenExpiration)
 
 	// Create claims with user ID and standard claims
 	claims := &Claims{
 		UserID: userID,
+		Bot:    bot,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -65,6 +109,52 @@ func GenerateToken(userID uuid.UUID) (string, error) {
 	return tokenString, nil
 }
 
+// GenerateImpersonationToken creates a short-lived token that lets adminID act
+// as userID. The token's ID is returned for the audit trail.
+func GenerateImpersonationToken(userID, adminID uuid.UUID) (string, string, time.Time, error) {
+	now := time.Now()
+	expirationTime := now.Add(impersonationExpiration)
+	tokenID := uuid.New().String()
+
+	claims := &Claims{
+		UserID:         userID,
+		ImpersonatorID: &adminID,
+		Scope:          ScopeImpersonation,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "gator-swamp-api",
+			Subject:   userID.String(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(jwtSecret))
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return tokenString, tokenID, expirationTime, nil
+}
+
+// GenerateRefreshToken creates a random refresh token. Only its hash is
+// stored, so the token itself is returned to the client and then forgotten.
+func GenerateRefreshToken() (token, hash string, expiresAt time.Time, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", time.Time{}, err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), time.Now().Add(refreshTokenExpiration), nil
+}
+
+// HashRefreshToken returns the hash a refresh token is stored under.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // ValidateToken validates the provided JWT token
 func ValidateToken(tokenString string) (*Claims, error) {
 	// Parse token with claims
@@ -133,6 +223,14 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		// Set user ID in request context
 		ctx := r.Context()
 		ctx = SetUserIDInContext(ctx, claims.UserID)
+		if claims.Bot {
+			ctx = context.WithValue(ctx, BotKey, true)
+		}
+
+		if claims.IsImpersonation() {
+			serveImpersonated(next.ServeHTTP, w, r.WithContext(ctx), claims)
+			return
+		}
 
 		// Continue with request
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -167,7 +265,7 @@ func ApplyJWTMiddleware(handler http.HandlerFunc, path string) http.HandlerFunc
 		// Validate token
 		claims, err := ValidateToken(tokenString)
 		if err != nil {
-			log.Printf("JWT Error: %v", err)
+			slog.InfoContext(r.Context(), "Rejected token", "error", err)
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
@@ -181,20 +279,78 @@ func ApplyJWTMiddleware(handler http.HandlerFunc, path string) http.HandlerFunc
 		// Set user ID in request context
 		ctx := r.Context()
 		ctx = SetUserIDInContext(ctx, claims.UserID)
+		if claims.Bot {
+			ctx = context.WithValue(ctx, BotKey, true)
+		}
+
+		if claims.IsImpersonation() {
+			serveImpersonated(handler, w, r.WithContext(ctx), claims)
+			return
+		}
 
 		// Continue with handler
 		handler(w, r.WithContext(ctx))
 	}
 }
 
+// serveImpersonated runs handler for an impersonation token: writes are
+// refused, responses are flagged, and every request is audited.
+func serveImpersonated(handler http.HandlerFunc, w http.ResponseWriter, r *http.Request, claims *Claims) {
+	ctx := context.WithValue(r.Context(), ImpersonatorIDKey, *claims.ImpersonatorID)
+	r = r.WithContext(ctx)
+	w.Header().Set("X-Impersonated-By", claims.ImpersonatorID.String())
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	if !safeMethod(r.Method) && !ImpersonationAllowedWrites[r.URL.Path] {
+		http.Error(rec, "Not allowed while impersonating", http.StatusForbidden)
+	} else {
+		handler(rec, r)
+	}
+
+	if AuditImpersonatedRequest != nil {
+		AuditImpersonatedRequest(r, claims, rec.status)
+	}
+}
+
+// safeMethod reports whether method only reads.
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
 // Define a custom context key type to avoid collisions
 type contextKey string
 
 // UserIDKey is the key used to store the user ID in the context
 const UserIDKey contextKey = "user_id"
 
-// SetUserIDInContext saves the user ID in the request context
+// ImpersonatorIDKey holds the admin's user ID on requests made with an
+// impersonation token
+const ImpersonatorIDKey contextKey = "impersonator_id"
+
+// BotKey is set on requests made with a bot account's token
+const BotKey contextKey = "bot"
+
+// IsBotRequest reports whether the request was made with a bot's token.
+func IsBotRequest(ctx context.Context) bool {
+	bot, _ := ctx.Value(BotKey).(bool)
+	return bot
+}
+
+// SetUserIDInContext saves the user ID in the request context, and in its
+// access log line
 func SetUserIDInContext(ctx context.Context, userID uuid.UUID) context.Context {
+	noteAccessUser(ctx, userID)
 	return context.WithValue(ctx, UserIDKey, userID)
 }
 
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/policy"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// ApplyPolicy rejects requests from users who don't meet the policy for op,
+// and from bots whose scopes leave it out. Only the given methods are gated,
+// or all if none are given, so a route's reads can stay open. It must run
+// inside ApplyJWTMiddleware.
+func ApplyPolicy(handler http.HandlerFunc, policies policy.Policies, accounts policy.Accounts, op string, methods ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(methods) > 0 && !slices.Contains(methods, r.Method) {
+			handler(w, r)
+			return
+		}
+		userID, ok := r.Context().Value(UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		err := policies.CheckUser(r.Context(), accounts, op, userID)
+		if err == nil && IsBotRequest(r.Context()) {
+			err = policy.CheckBot(r.Context(), accounts, op, userID, uuid.Nil)
+		}
+		if err != nil {
+			if appErr, ok := err.(*utils.AppError); ok {
+				i18n.WriteError(w, r, appErr)
+				return
+			}
+			slog.ErrorContext(r.Context(), "Failed to check policy", "op", op, "user_id", userID, "error", err)
+			http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+			return
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,221 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Rate limit buckets. Each client's requests are counted in one of them.
+const (
+	RateLimitRead  = "read"  // GET and HEAD
+	RateLimitWrite = "write" // Everything else
+)
+
+// Rate limit headers, set on every counted response
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset" // Unix seconds
+	RateLimitBucketHeader    = "X-RateLimit-Bucket"
+)
+
+// rateLimitExempt are routes probes poll, which aren't counted
+var rateLimitExempt = map[string]bool{
+	"/health":      true,
+	"/health/full": true,
+	"/metrics":     true,
+}
+
+// RateLimitOptions configure a RateLimiter.
+type RateLimitOptions struct {
+	Window time.Duration
+	// Requests allowed per window, by bucket. A bucket that's missing or 0
+	// isn't limited.
+	Limits map[string]int
+	// Limits for bot accounts, in place of Limits; nil gives bots the same
+	// limits as everyone else
+	BotLimits map[string]int
+}
+
+// RateLimitStatus is a client's standing in one bucket.
+type RateLimitStatus struct {
+	Bucket    string    `json:"bucket"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+// RateLimiter counts requests per client and bucket in fixed windows, and
+// rejects them with 429 once a bucket is used up. Clients are users when the
+// request carries a valid token, IP addresses otherwise. Counts are kept in
+// memory, so each instance limits the requests it serves.
+type RateLimiter struct {
+	opts      RateLimitOptions
+	mu        sync.Mutex
+	windows   map[rateKey]*rateWindow
+	lastSweep time.Time
+}
+
+type rateKey struct {
+	client string
+	bucket string
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter creates a limiter with the given options.
+func NewRateLimiter(opts RateLimitOptions) *RateLimiter {
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+	return &RateLimiter{opts: opts, windows: make(map[rateKey]*rateWindow), lastSweep: time.Now()}
+}
+
+// Window is the length of the limiter's windows.
+func (l *RateLimiter) Window() time.Duration {
+	return l.opts.Window
+}
+
+// UserRateLimitClient is the client a user's authenticated requests are
+// counted under.
+func UserRateLimitClient(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
+
+// rateLimitClient identifies who a request is counted against, and whether
+// it's a bot account.
+func rateLimitClient(r *http.Request) (string, bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if claims, err := ValidateToken(token); err == nil {
+			return UserRateLimitClient(claims.UserID), claims.Bot
+		}
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return "ip:" + ip, false
+}
+
+// limits are the per-bucket limits for a bot or another client.
+func (l *RateLimiter) limits(bot bool) map[string]int {
+	if bot && l.opts.BotLimits != nil {
+		return l.opts.BotLimits
+	}
+	return l.opts.Limits
+}
+
+// rateLimitBucket is the bucket a request is counted in.
+func rateLimitBucket(r *http.Request) string {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return RateLimitRead
+	}
+	return RateLimitWrite
+}
+
+// Middleware counts each request against its client's bucket, sets the
+// X-RateLimit headers and rejects the request if the bucket is used up.
+// CORS preflights and health checks aren't counted.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions || rateLimitExempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		bucket := rateLimitBucket(r)
+		client, bot := rateLimitClient(r)
+		limits := l.limits(bot)
+		if limits[bucket] <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+		status, ok := l.take(client, bucket, limits[bucket], now)
+		h := w.Header()
+		h.Set(RateLimitLimitHeader, strconv.Itoa(status.Limit))
+		h.Set(RateLimitRemainingHeader, strconv.Itoa(status.Remaining))
+		h.Set(RateLimitResetHeader, strconv.FormatInt(status.ResetAt.Unix(), 10))
+		h.Set(RateLimitBucketHeader, bucket)
+		if !ok {
+			retryAfter := int(status.ResetAt.Sub(now).Seconds() + 0.999)
+			h.Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take counts a request, reporting false if the bucket was already used up.
+func (l *RateLimiter) take(client, bucket string, limit int, now time.Time) (RateLimitStatus, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweepLocked(now)
+
+	key := rateKey{client, bucket}
+	win := l.windows[key]
+	if win == nil || now.Sub(win.start) >= l.opts.Window {
+		win = &rateWindow{start: now}
+		l.windows[key] = win
+	}
+	ok := win.count < limit
+	if ok {
+		win.count++
+	}
+	return l.statusLocked(bucket, limit, win, now), ok
+}
+
+// Status reports the client's standing in every limited bucket, without
+// counting a request. bot picks the bot account limits.
+func (l *RateLimiter) Status(client string, bot bool) []RateLimitStatus {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limits := l.limits(bot)
+	statuses := make([]RateLimitStatus, 0, len(limits))
+	for _, bucket := range []string{RateLimitRead, RateLimitWrite} {
+		if limits[bucket] <= 0 {
+			continue
+		}
+		win := l.windows[rateKey{client, bucket}]
+		if win == nil || now.Sub(win.start) >= l.opts.Window {
+			win = &rateWindow{start: now}
+		}
+		statuses = append(statuses, l.statusLocked(bucket, limits[bucket], win, now))
+	}
+	return statuses
+}
+
+func (l *RateLimiter) statusLocked(bucket string, limit int, win *rateWindow, now time.Time) RateLimitStatus {
+	return RateLimitStatus{
+		Bucket:    bucket,
+		Limit:     limit,
+		Remaining: max(limit-win.count, 0),
+		ResetAt:   win.start.Add(l.opts.Window),
+	}
+}
+
+// sweepLocked drops expired windows once per window, so clients that went
+// away don't pile up.
+func (l *RateLimiter) sweepLocked(now time.Time) {
+	if now.Sub(l.lastSweep) < l.opts.Window {
+		return
+	}
+	for key, win := range l.windows {
+		if now.Sub(win.start) >= l.opts.Window {
+			delete(l.windows, key)
+		}
+	}
+	l.lastSweep = now
+}
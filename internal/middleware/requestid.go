@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	"gator-swamp/internal/logging"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries a request's ID. A well-formed one sent by a proxy
+// is kept, otherwise one is generated; either way it's echoed back.
+const RequestIDHeader = "X-Request-ID"
+
+// Request IDs taken from clients are short and printable, so they can't
+// forge log lines
+var requestIDRE = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID gives each request an ID, in its context (see
+// logging.RequestID) and the response's X-Request-ID header. Lines logged
+// with the request's context carry it, and so do the actor messages sent
+// for it, so the request can be followed from the handler to every actor it
+// reaches.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDRE.MatchString(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions.
+const (
+	AuditImpersonationStart   = "impersonation.start"
+	AuditImpersonationRequest = "impersonation.request"
+	AuditContentDelete        = "content.delete"
+	AuditContentRestore       = "content.restore"
+	AuditSubredditQuarantine  = "subreddit.quarantine"
+	AuditSubredditRelease     = "subreddit.unquarantine"
+	AuditUserMerge            = "user.merge"
+	AuditModeratorRemove      = "moderator.remove"
+	AuditModeratorLock        = "moderator.lock"
+	AuditModeratorUnlock      = "moderator.unlock"
+	AuditBotCreate            = "bot.create"
+	AuditBotUpdate            = "bot.update"
+	AuditSubredditExport      = "subreddit.export"
+	AuditReportResolve        = "report.resolve"
+	AuditReportDismiss        = "report.dismiss"
+)
+
+// AuditEntry records a privileged action in the audit_log table.
+type AuditEntry struct {
+	ID        uuid.UUID       `json:"id" db:"id"`
+	ActorID   uuid.UUID       `json:"actorId" db:"actor_id"`               // Who performed the action
+	SubjectID *uuid.UUID      `json:"subjectId,omitempty" db:"subject_id"` // User acted on or as, if any
+	Action    string          `json:"action" db:"action"`
+	Details   json.RawMessage `json:"details,omitempty" db:"details"`
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+}
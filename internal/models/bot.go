@@ -0,0 +1,32 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BotAccount is a user account run by a program, created by an admin. A bot
+// may only perform the operations in Scopes, and only posts and comments in
+// SubredditIDs when any are listed. Its content is marked as a bot's.
+type BotAccount struct {
+	UserID       uuid.UUID   `json:"userId"`
+	Username     string      `json:"username"`
+	Scopes       []string    `json:"scopes"`       // Operations it may perform, e.g. "post.create"
+	SubredditIDs []uuid.UUID `json:"subredditIds"` // Where it may post and comment; empty for anywhere
+	CreatedBy    uuid.UUID   `json:"createdBy"`
+	CreatedAt    time.Time   `json:"createdAt"`
+	UpdatedAt    time.Time   `json:"updatedAt"`
+}
+
+// Allows reports whether the bot may perform op.
+func (b *BotAccount) Allows(op string) bool {
+	return slices.Contains(b.Scopes, op)
+}
+
+// AllowsSubreddit reports whether the bot may post and comment in the
+// subreddit.
+func (b *BotAccount) AllowsSubreddit(subredditID uuid.UUID) bool {
+	return len(b.SubredditIDs) == 0 || slices.Contains(b.SubredditIDs, subredditID)
+}
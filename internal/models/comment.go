@@ -11,6 +11,7 @@ type Comment struct {
 	Content         string      `json:"content" db:"content"`
 	AuthorID        uuid.UUID   `json:"authorId" db:"author_id"`
 	AuthorUsername  string      `json:"authorUsername" db:"author_username"`
+	AuthorIsBot     bool        `json:"authorIsBot,omitempty" db:"author_is_bot"`
 	PostID          uuid.UUID   `json:"postId" db:"post_id"`
 	SubredditID     uuid.UUID   `json:"subredditId" db:"subreddit_id"`
 	ParentID        *uuid.UUID  `json:"parentId,omitempty" db:"parent_id"`
@@ -21,5 +22,17 @@ type Comment struct {
 	Upvotes         int         `json:"upvotes" db:"upvotes"`     // Added db tag
 	Downvotes       int         `json:"downvotes" db:"downvotes"` // Added db tag
 	Karma           int         `json:"karma" db:"karma"`
+	ReplyCount      int         `json:"replyCount" db:"reply_count"` // Direct replies; the post's commentCount counts all
 	CurrentUserVote *string     `json:"currentUserVote,omitempty" db:"current_user_vote"`
+	DeletedAt       *time.Time  `json:"deletedAt,omitempty" db:"deleted_at"` // Set when soft-deleted; only admins see these
+	InboxItemID     *uuid.UUID  `json:"-" db:"-"`                            // Inbox item the author answered with this comment; saving it marks the item read
+}
+
+// CommentNode is a comment in a thread window with the window's replies to
+// it. More is set when it has replies left out: past the window's width, or
+// below its depth.
+type CommentNode struct {
+	Comment *Comment
+	Replies []*CommentNode
+	More    bool
 }
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContentType names a soft-deletable content table.
+type ContentType string
+
+const (
+	ContentPost      ContentType = "post"
+	ContentComment   ContentType = "comment"
+	ContentSubreddit ContentType = "subreddit"
+)
+
+// DeletedContent summarizes a soft-deleted post, comment or subreddit for
+// admin review.
+type DeletedContent struct {
+	Type      ContentType `json:"type" db:"type"`
+	ID        uuid.UUID   `json:"id" db:"id"`
+	Summary   string      `json:"summary" db:"summary"` // Post title, comment excerpt or subreddit name
+	AuthorID  *uuid.UUID  `json:"authorId,omitempty" db:"author_id"`
+	DeletedAt time.Time   `json:"deletedAt" db:"deleted_at"`
+}
@@ -7,12 +7,15 @@ import (
 )
 
 type DirectMessage struct {
-	ID        uuid.UUID  `json:"id" db:"id"`
-	FromID    uuid.UUID  `json:"fromId" db:"sender_id"`
-	ToID      uuid.UUID  `json:"toId" db:"receiver_id"`
-	Content   string     `json:"content" db:"content"`
-	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
-	ReadAt    *time.Time `json:"readAt,omitempty" db:"read_at"`
-	IsRead    bool       `json:"isRead"`
-	IsDeleted bool       `json:"-"`
+	ID          uuid.UUID  `json:"id" db:"id"`
+	FromID      uuid.UUID  `json:"fromId" db:"sender_id"`
+	ToID        uuid.UUID  `json:"toId" db:"receiver_id"`
+	Content     string     `json:"content" db:"content"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty" db:"delivered_at"` // Pushed over WebSocket, or first fetched by the recipient
+	ReadAt      *time.Time `json:"readAt,omitempty" db:"read_at"`
+	IsDelivered bool       `json:"isDelivered"`
+	IsRead      bool       `json:"isRead"`
+	IsDeleted   bool       `json:"-"`
+	InReplyTo   *uuid.UUID `json:"inReplyTo,omitempty" db:"-"` // Message answered from the inbox; saving this one marks it read
 }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExportStatus is how far a subreddit export has got.
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending" // Queued
+	ExportRunning ExportStatus = "running"
+	ExportDone    ExportStatus = "done" // The archive can be downloaded
+	ExportFailed  ExportStatus = "failed"
+)
+
+// SubredditExport is a moderator's request for an archive of a subreddit,
+// built in the background.
+type SubredditExport struct {
+	ID           uuid.UUID    `json:"id" db:"id"`
+	SubredditID  uuid.UUID    `json:"subredditId" db:"subreddit_id"`
+	RequestedBy  uuid.UUID    `json:"requestedBy" db:"requested_by"`
+	Status       ExportStatus `json:"status" db:"status"`
+	Key          *string      `json:"-" db:"key"` // Storage key of the archive, once done
+	SizeBytes    int64        `json:"sizeBytes" db:"size_bytes"`
+	PostCount    int          `json:"postCount" db:"post_count"`
+	CommentCount int          `json:"commentCount" db:"comment_count"`
+	Error        *string      `json:"error,omitempty" db:"error"`
+	CreatedAt    time.Time    `json:"createdAt" db:"created_at"`
+	CompletedAt  *time.Time   `json:"completedAt,omitempty" db:"completed_at"`
+}
+
+// SubredditArchiveVersion is the format version of SubredditArchive.
+const SubredditArchiveVersion = 1
+
+// SubredditArchive is the JSON document a subreddit export produces: the
+// subreddit with its settings, flairs and moderators, and its posts, each
+// with its comments oldest first. Deleted posts and comments are left out.
+type SubredditArchive struct {
+	Version    int                `json:"version"`
+	ExportedAt time.Time          `json:"exportedAt"`
+	Subreddit  *Subreddit         `json:"subreddit"`
+	Settings   *SubredditSettings `json:"settings"`
+	Flairs     []*Flair           `json:"flairs"`
+	Moderators []*Moderator       `json:"moderators"`
+	Posts      []*ArchivedPost    `json:"posts"`
+}
+
+// ArchivedPost is a post in a SubredditArchive.
+type ArchivedPost struct {
+	*Post
+	Comments []*Comment `json:"comments"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "github.com/google/uuid"
+
+// ExternalKind is what an imported row is.
+type ExternalKind string
+
+const (
+	ExternalUser      ExternalKind = "user"
+	ExternalSubreddit ExternalKind = "subreddit"
+	ExternalPost      ExternalKind = "post"
+	ExternalComment   ExternalKind = "comment"
+)
+
+// ExternalID maps a row imported from another site to its ID there.
+type ExternalID struct {
+	Source     string       `db:"source"` // e.g. "reddit"
+	Kind       ExternalKind `db:"kind"`
+	ExternalID string       `db:"external_id"`
+	ID         uuid.UUID    `db:"id"`
+}
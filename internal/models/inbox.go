@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InboxItemType tells the kinds of inbox entries apart.
+type InboxItemType string
+
+const (
+	InboxMessage InboxItemType = "message" // Unread direct message
+	InboxReply   InboxItemType = "reply"   // Comment on the user's post or reply to their comment
+	InboxMention InboxItemType = "mention" // Post or comment naming u/<username>
+)
+
+// InboxItem is one entry of a user's inbox. ID is the message, comment or
+// post it refers to; PostID is set for replies and mentions.
+type InboxItem struct {
+	Type         InboxItemType `json:"type" db:"type"`
+	ID           uuid.UUID     `json:"id" db:"id"`
+	FromID       uuid.UUID     `json:"fromId" db:"from_id"`
+	FromUsername string        `json:"fromUsername" db:"from_username"`
+	PostID       *uuid.UUID    `json:"postId,omitempty" db:"post_id"`
+	Content      string        `json:"content" db:"content"`
+	CreatedAt    time.Time     `json:"createdAt" db:"created_at"`
+}
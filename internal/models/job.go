@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobStatus is the lifecycle state of a background job.
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed" // Out of attempts or permanently failed
+)
+
+// Job is a unit of background work persisted in the jobs table.
+type Job struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	Type        string          `json:"type" db:"type"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	Status      JobStatus       `json:"status" db:"status"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	MaxAttempts int             `json:"maxAttempts" db:"max_attempts"`
+	UniqueKey   *string         `json:"uniqueKey,omitempty" db:"unique_key"` // At most one pending/running job per key
+	RunAt       time.Time       `json:"runAt" db:"run_at"`
+	LockedUntil *time.Time      `json:"lockedUntil,omitempty" db:"locked_until"`
+	LastError   *string         `json:"lastError,omitempty" db:"last_error"`
+	CreatedAt   time.Time       `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updatedAt" db:"updated_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Media is an uploaded image that posts and profiles refer to by ID.
+type Media struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	OwnerID     uuid.UUID `json:"ownerId" db:"owner_id"`
+	Key         string    `json:"-" db:"key"` // Storage key
+	URL         string    `json:"url" db:"-"` // Set from Key for responses
+	ContentType string    `json:"contentType" db:"content_type"`
+	Size        int       `json:"size" db:"size_bytes"`
+	Width       int       `json:"width" db:"width"`
+	Height      int       `json:"height" db:"height"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+}
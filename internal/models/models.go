@@ -1,6 +1,12 @@
 // Package models contains all the data models for the application
 package models
 
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
 // StatusResponse is a generic struct for simple success/error messages.
 // It can be used by actor responses or HTTP handlers.
 type StatusResponse struct {
@@ -8,3 +14,21 @@ type StatusResponse struct {
 	Message string `json:"message,omitempty"`
 	Error   string `json:"error,omitempty"` // Optional error detail
 }
+
+// Keyset is where a newest-first listing continues: after the row created
+// at CreatedAt, with ID breaking ties. Unlike an offset it doesn't scan the
+// rows before it, and rows added meanwhile don't shift the next page.
+type Keyset struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Keyset is where a listing continues after p.
+func (p *Post) Keyset() Keyset {
+	return Keyset{CreatedAt: p.CreatedAt, ID: p.ID}
+}
+
+// Keyset is where a listing continues after u.
+func (u *User) Keyset() Keyset {
+	return Keyset{CreatedAt: u.CreatedAt, ID: u.ID}
+}
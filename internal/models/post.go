@@ -1,17 +1,34 @@
 package models
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Orderings for post listings. Hot uses the hot_score maintained by the
+// scheduler, so reads never recompute post ages.
+const (
+	SortNew           = "new"
+	SortHot           = "hot"
+	SortTop           = "top"           // Highest karma of all time
+	SortControversial = "controversial" // Many votes, evenly split
+	SortRising        = "rising"        // Karma gained per hour, over the last day's posts
+)
+
+// PostSorts lists the orderings post listings accept.
+var PostSorts = []string{SortNew, SortHot, SortTop, SortControversial, SortRising}
+
 type Post struct {
 	ID              uuid.UUID `json:"id" db:"id"`
 	Title           string    `json:"title" db:"title"`
 	Content         string    `json:"content" db:"content"`
 	AuthorID        uuid.UUID `json:"authorId" db:"author_id"`
 	AuthorUsername  string    `json:"authorUsername" db:"author_username"` // Added db tag
+	AuthorIsBot     bool      `json:"authorIsBot,omitempty" db:"author_is_bot"`
 	SubredditID     uuid.UUID `json:"subredditId" db:"subreddit_id"`
 	SubredditName   string    `json:"subredditName" db:"subreddit_name"` // Added db tag
 	CreatedAt       time.Time `json:"createdAt" db:"created_at"`
@@ -19,7 +36,79 @@ type Post struct {
 	Upvotes         int       `json:"upvotes" db:"upvotes"`      // Added db tag
 	Downvotes       int       `json:"downvotes" db:"downvotes"`  // Added db tag
 	Karma           int       `json:"karma" db:"karma"`
+	Score           int       `json:"score" db:"-"`                                     // Upvotes less downvotes, as shown; fuzzed while the post is new
+	UpvoteRatio     float64   `json:"upvoteRatio" db:"-"`                               // Share of votes that are upvotes, 0 without votes
 	CurrentUserVote *string   `json:"currentUserVote,omitempty" db:"current_user_vote"` // Added field for user's vote status (string: "up", "down", or nil)
 	// UserVotes      map[string]bool `json:"userVotes"` // Removed; now handled by RecordVote and potentially a separate query
-	CommentCount int `json:"commentCount" db:"comment_count"`
+	CommentCount      int        `json:"commentCount" db:"comment_count"`
+	URL               *string    `json:"url,omitempty" db:"url"`                     // Link or image URL for link posts
+	ThumbnailURL      *string    `json:"thumbnailUrl,omitempty" db:"thumbnail_url"`  // Preview image, set once generated
+	LockedByAuthor    bool       `json:"lockedByAuthor" db:"locked_by_author"`       // Author has closed the post to new comments
+	LockedByModerator bool       `json:"lockedByModerator" db:"locked_by_moderator"` // A moderator has, and only moderators can reopen it
+	Archived          bool       `json:"archived" db:"archived"`                     // Old enough that it takes no more votes or comments
+	EditedAt          *time.Time `json:"editedAt,omitempty" db:"edited_at"`          // Set when the author last changed the title or content
+	DeletedAt         *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`        // Set when soft-deleted; only admins see these
+	FlairID           *uuid.UUID `json:"flairId,omitempty" db:"flair_id"`            // One of the subreddit's flairs
+	FlairText         *string    `json:"flairText,omitempty" db:"flair_text"`        // Read from the flair, so renames show
+	FlairColor        *string    `json:"flairColor,omitempty" db:"flair_color"`      // Likewise
+	MediaID           *uuid.UUID `json:"mediaId,omitempty" db:"media_id"`            // An image the author uploaded
+	MediaKey          *string    `json:"-" db:"media_key"`                           // Its storage key
+	MediaURL          *string    `json:"mediaUrl,omitempty" db:"-"`                  // Set from MediaKey for responses
+	Pending           bool       `json:"pending,omitempty" db:"-"`                   // Held for moderator approval; only set when created
+	Language          *string    `json:"language,omitempty" db:"language"`           // Detected from the title and content; nil if it couldn't be
+	PostMetadata
+}
+
+// PostRevision is a post's title and content as they were before an edit.
+type PostRevision struct {
+	PostID     uuid.UUID `json:"postId" db:"post_id"`
+	Title      string    `json:"title" db:"title"`
+	Content    string    `json:"content" db:"content"`
+	WrittenAt  time.Time `json:"writtenAt" db:"written_at"`   // When it was posted, or the edit that wrote it
+	ReplacedAt time.Time `json:"replacedAt" db:"replaced_at"` // When the next edit replaced it
+}
+
+// PostMetadata is optional provenance the author can attach to a post and
+// edit later, e.g. for art and photography communities.
+type PostMetadata struct {
+	OriginalContent   bool    `json:"originalContent" db:"original_content"`               // Author made this themselves
+	SourceAttribution *string `json:"sourceAttribution,omitempty" db:"source_attribution"` // Credit for content that isn't original
+	License           *string `json:"license,omitempty" db:"license"`                      // One of Licenses
+}
+
+// MaxSourceAttributionLength caps PostMetadata.SourceAttribution.
+const MaxSourceAttributionLength = 500
+
+// Licenses are the licenses a post can be marked with.
+var Licenses = []string{
+	"all-rights-reserved",
+	"cc0",
+	"cc-by",
+	"cc-by-sa",
+	"cc-by-nc",
+	"cc-by-nc-sa",
+	"cc-by-nd",
+	"cc-by-nc-nd",
+}
+
+// Normalize drops empty attribution and license values, then checks the
+// license is known and the attribution isn't too long.
+func (m *PostMetadata) Normalize() error {
+	if m.SourceAttribution != nil {
+		if trimmed := strings.TrimSpace(*m.SourceAttribution); trimmed != "" {
+			m.SourceAttribution = &trimmed
+		} else {
+			m.SourceAttribution = nil
+		}
+	}
+	if m.License != nil && *m.License == "" {
+		m.License = nil
+	}
+	if m.SourceAttribution != nil && len(*m.SourceAttribution) > MaxSourceAttributionLength {
+		return fmt.Errorf("source attribution must be at most %d characters", MaxSourceAttributionLength)
+	}
+	if m.License != nil && !slices.Contains(Licenses, *m.License) {
+		return fmt.Errorf("unknown license %q; use one of %s", *m.License, strings.Join(Licenses, ", "))
+	}
+	return nil
 }
@@ -0,0 +1,10 @@
+package models
+
+// PostWithComments is a post together with the first page of its comments,
+// so a post page can be rendered from a single request.
+type PostWithComments struct {
+	Post          *Post      `json:"post"`
+	Comments      []*Comment `json:"comments"`
+	TotalComments int        `json:"totalComments"`
+	HasMore       bool       `json:"hasMore"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReportTarget is what a report is about.
+type ReportTarget string
+
+const (
+	ReportPost    ReportTarget = "post"
+	ReportComment ReportTarget = "comment"
+	ReportUser    ReportTarget = "user"
+)
+
+// ReportStatus is where a report stands in its subreddit's queue.
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"
+	ReportResolved  ReportStatus = "resolved"  // A moderator acted on it
+	ReportDismissed ReportStatus = "dismissed" // A moderator found nothing wrong
+)
+
+// Report flags a post, comment or user to the moderators of the subreddit
+// it happened in. Reporters stay anonymous to moderators.
+type Report struct {
+	ID          uuid.UUID    `json:"id" db:"id"`
+	ReporterID  uuid.UUID    `json:"-" db:"reporter_id"`
+	TargetType  ReportTarget `json:"targetType" db:"target_type"`
+	TargetID    uuid.UUID    `json:"targetId" db:"target_id"`
+	SubredditID uuid.UUID    `json:"subredditId" db:"subreddit_id"`
+	Reason      string       `json:"reason" db:"reason"`
+	Status      ReportStatus `json:"status" db:"status"`
+	ResolvedBy  *uuid.UUID   `json:"resolvedBy,omitempty" db:"resolved_by"`
+	Note        *string      `json:"note,omitempty" db:"note"` // The moderator's, on resolving or dismissing
+	CreatedAt   time.Time    `json:"createdAt" db:"created_at"`
+	ResolvedAt  *time.Time   `json:"resolvedAt,omitempty" db:"resolved_at"`
+}
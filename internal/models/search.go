@@ -0,0 +1,14 @@
+package models
+
+// Orderings for search results
+const (
+	SearchSortRelevance = "relevance" // Best match first
+	SearchSortNew       = "new"
+)
+
+// Kinds of content /search finds
+const (
+	SearchPosts      = "posts"
+	SearchComments   = "comments"
+	SearchSubreddits = "subreddits"
+)
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SitemapEntry is a public post listed in the sitemap and its subreddit's
+// index.
+type SitemapEntry struct {
+	PostID        uuid.UUID `db:"post_id"`
+	Title         string    `db:"title"`
+	SubredditID   uuid.UUID `db:"subreddit_id"`
+	SubredditName string    `db:"subreddit_name"`
+	LastMod       time.Time `db:"last_mod"`
+}
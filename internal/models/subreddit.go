@@ -7,11 +7,62 @@ import (
 )
 
 type Subreddit struct {
-	ID          uuid.UUID   `json:"id" db:"id"`
-	Name        string      `json:"name" db:"name"`
-	Description string      `json:"description" db:"description"`
-	CreatorID   uuid.UUID   `json:"creatorId" db:"created_by"`
-	Members     int         `json:"members" db:"member_count"`
-	CreatedAt   time.Time   `json:"createdAt" db:"created_at"`
-	Posts       []uuid.UUID `json:"posts"`
+	ID          uuid.UUID  `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Description string     `json:"description" db:"description"`
+	CreatorID   uuid.UUID  `json:"creatorId" db:"created_by"`
+	Members     int        `json:"members" db:"member_count"`
+	PostCount   int        `json:"postCount" db:"post_count"` // Posts not deleted
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	NSFW        bool       `json:"nsfw" db:"nsfw"`               // Set by moderators
+	Quarantined bool       `json:"quarantined" db:"quarantined"` // Set by admins
+}
+
+// Flair is a label a subreddit's moderators define for its posts.
+type Flair struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	SubredditID uuid.UUID `json:"subredditId" db:"subreddit_id"`
+	Text        string    `json:"text" db:"text"`
+	Color       *string   `json:"color,omitempty" db:"color"` // #rrggbb
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Post types a subreddit accepts
+const (
+	PostTypesAny  = "any"
+	PostTypesText = "text" // No link posts
+	PostTypesLink = "link" // Link posts only
+)
+
+// Moderator is a user appointed to moderate a subreddit by its creator or a
+// site admin. Both moderate without being listed.
+type Moderator struct {
+	SubredditID uuid.UUID  `json:"subredditId" db:"subreddit_id"`
+	UserID      uuid.UUID  `json:"userId" db:"user_id"`
+	Username    string     `json:"username" db:"username"`
+	AddedBy     *uuid.UUID `json:"addedBy,omitempty" db:"added_by"`
+	AddedAt     time.Time  `json:"addedAt" db:"added_at"`
+}
+
+// SubredditSettings are a subreddit's submission rules, set by its
+// moderators, who aren't held to them.
+type SubredditSettings struct {
+	SubredditID             uuid.UUID `json:"subredditId" db:"subreddit_id"`
+	AllowedPostTypes        string    `json:"allowedPostTypes" db:"allowed_post_types"`
+	MinAccountAgeDays       int       `json:"minAccountAgeDays" db:"min_account_age_days"`
+	MinKarma                int       `json:"minKarma" db:"min_karma"`
+	CommentsRequireApproval bool      `json:"commentsRequireApproval" db:"comments_require_approval"` // New comments wait for a moderator
+	// New posts wait for a moderator if their author has less karma or a
+	// younger account than these; 0 turns each off
+	PostApprovalKarma          int       `json:"postApprovalKarma" db:"post_approval_karma"`
+	PostApprovalAccountAgeDays int       `json:"postApprovalAccountAgeDays" db:"post_approval_account_age_days"`
+	MaskProfanity              bool      `json:"maskProfanity" db:"mask_profanity"` // Mask configured profanity when shown
+	UpdatedAt                  time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// DefaultSubredditSettings are the settings of a subreddit whose moderators
+// never changed them.
+func DefaultSubredditSettings(subredditID uuid.UUID) *SubredditSettings {
+	return &SubredditSettings{SubredditID: subredditID, AllowedPostTypes: PostTypesAny, MaskProfanity: true}
 }
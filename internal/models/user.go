@@ -12,9 +12,72 @@ type User struct {
 	Email          string      `json:"email" db:"email"`
 	HashedPassword string      `json:"-" db:"password_hash"`
 	Karma          int         `json:"karma" db:"karma"`
+	PostKarma      int         `json:"postKarma" db:"post_karma"`
+	CommentKarma   int         `json:"commentKarma" db:"comment_karma"`
 	CreatedAt      time.Time   `json:"createdAt" db:"created_at"`
 	UpdatedAt      time.Time   `json:"updatedAt" db:"updated_at"`
 	LastActive     time.Time   `json:"lastActive" db:"last_active"`
 	IsConnected    bool        `json:"isConnected" db:"is_connected"`
+	ProfileImage   *string     `json:"profileImage,omitempty" db:"profile_image"` // Storage key of the avatar's original upload
+	KarmaVelocity  float64     `json:"karmaVelocity" db:"karma_velocity"`         // Smoothed karma gained per hour
+	IsAdmin        bool        `json:"isAdmin" db:"is_admin"`
+	EmailVerified  bool        `json:"emailVerified" db:"email_verified"`
+	IsBot          bool        `json:"isBot" db:"is_bot"` // A BotAccount, limited to its scopes
 	Subreddits     []uuid.UUID `json:"subreddits"`
 }
+
+// UserFilter narrows a user listing. Zero values match every user.
+type UserFilter struct {
+	Username string // Usernames starting with this, ignoring case
+	MinKarma *int
+}
+
+// Leaderboard names the karma users are ranked by on a leaderboard.
+type Leaderboard string
+
+const (
+	LeaderboardKarma        Leaderboard = "karma"
+	LeaderboardPostKarma    Leaderboard = "post"
+	LeaderboardCommentKarma Leaderboard = "comment"
+)
+
+// UserPreferences are a user's opt-ins. Posts from NSFW and quarantined
+// subreddits are left out of site-wide feeds unless the matching one is set.
+type UserPreferences struct {
+	UserID          uuid.UUID `json:"userId" db:"user_id"`
+	ShowNSFW        bool      `json:"showNsfw" db:"show_nsfw"`
+	ShowQuarantined bool      `json:"showQuarantined" db:"show_quarantined"`
+	MaskProfanity   bool      `json:"maskProfanity" db:"mask_profanity"` // Where the subreddit masks it too
+	Languages       []string  `json:"languages"`                         // Feeds only show posts in these, or in no detected language; empty shows all
+	UpdatedAt       time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// AccountMerge reports what merging a duplicate account into a primary one
+// moved, or would move in a dry run. Votes both accounts cast on the same
+// content keep the primary's; the duplicate's are dropped and undone.
+type AccountMerge struct {
+	PrimaryID       uuid.UUID `json:"primaryId"`
+	DuplicateID     uuid.UUID `json:"duplicateId"`
+	DryRun          bool      `json:"dryRun"`
+	Posts           int64     `json:"posts"`
+	Comments        int64     `json:"comments"`
+	PendingPosts    int64     `json:"pendingPosts"`
+	PendingComments int64     `json:"pendingComments"`
+	Votes           int64     `json:"votes"`
+	DroppedVotes    int64     `json:"droppedVotes"`
+	Messages        int64     `json:"messages"`
+	Subreddits      int64     `json:"subreddits"`  // Created by the duplicate
+	Memberships     int64     `json:"memberships"` // Moved; shared ones are dropped
+	Karma           int       `json:"karma"`       // Added to the primary
+}
+
+// DefaultUserPreferences are the preferences of a user who never changed them.
+func DefaultUserPreferences(userID uuid.UUID) *UserPreferences {
+	return &UserPreferences{UserID: userID, MaskProfanity: true, Languages: []string{}}
+}
+
+// ShowsSubreddit reports whether content from sub may be shown, given its
+// NSFW and quarantine flags.
+func (p *UserPreferences) ShowsSubreddit(sub *Subreddit) bool {
+	return (!sub.NSFW || p.ShowNSFW) && (!sub.Quarantined || p.ShowQuarantined)
+}
@@ -0,0 +1,148 @@
+// Package policy holds the account requirements (age, karma) each operation
+// is gated on, and the scopes that limit bot accounts to some operations.
+// They're configured in one place and declared where the operation is
+// handled: by route with middleware.ApplyPolicy, or by an actor calling
+// Check.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// Operations that can be gated
+const (
+	OpCreateSubreddit = "subreddit.create"
+	OpCreatePost      = "post.create"
+	OpCreateComment   = "comment.create"
+	OpVote            = "vote"
+	OpSendMessage     = "message.send"
+)
+
+// Operations lists every gated operation.
+var Operations = []string{OpCreateSubreddit, OpCreatePost, OpCreateComment, OpVote, OpSendMessage}
+
+// Requirement is what an account needs to perform an operation. The zero
+// value lets everyone through.
+type Requirement struct {
+	MinAccountAge time.Duration
+	MinKarma      int
+}
+
+// Policies maps operations to their requirements. Operations without an
+// entry are open.
+type Policies map[string]Requirement
+
+// Defaults are the requirements before configuration.
+func Defaults() Policies {
+	return Policies{
+		OpCreateSubreddit: {MinKarma: 100},
+	}
+}
+
+// UserLookup loads the account being checked. database.UserRepository
+// satisfies it.
+type UserLookup interface {
+	GetUser(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+// BotLookup loads a bot's scopes. database.UserRepository satisfies it.
+type BotLookup interface {
+	GetBotAccount(ctx context.Context, userID uuid.UUID) (*models.BotAccount, error)
+}
+
+// Accounts loads what CheckUser and CheckBot need.
+type Accounts interface {
+	UserLookup
+	BotLookup
+}
+
+// CheckBot returns a Forbidden error if the bot userID isn't allowed to
+// perform op, or to perform it in subredditID when that isn't uuid.Nil.
+// Call it for bots only; the requirements in Policies apply to them too.
+func CheckBot(ctx context.Context, bots BotLookup, op string, userID, subredditID uuid.UUID) error {
+	bot, err := bots.GetBotAccount(ctx, userID)
+	if utils.IsErrorCode(err, utils.ErrNotFound) {
+		return utils.NewAppError(utils.ErrForbidden, "This bot account has no scopes", nil)
+	}
+	if err != nil {
+		return err
+	}
+	if !bot.Allows(op) {
+		return utils.NewAppError(utils.ErrForbidden, "This bot is not allowed to "+describe(op), nil)
+	}
+	if subredditID != uuid.Nil && !bot.AllowsSubreddit(subredditID) {
+		return utils.NewAppError(utils.ErrForbidden, "This bot is not allowed to "+describe(op)+" in this subreddit", nil)
+	}
+	return nil
+}
+
+// Check returns a Forbidden error saying what user lacks for op, or nil.
+// Admins are held to nothing.
+func (p Policies) Check(op string, user *models.User, now time.Time) *utils.AppError {
+	req, ok := p[op]
+	if !ok || user.IsAdmin {
+		return nil
+	}
+	if req.MinAccountAge > 0 && now.Sub(user.CreatedAt) < req.MinAccountAge {
+		return utils.NewAppError(utils.ErrForbidden,
+			fmt.Sprintf("Accounts must be at least %s old to %s", formatAge(req.MinAccountAge), describe(op)), nil)
+	}
+	if user.Karma < req.MinKarma {
+		return utils.NewAppError(utils.ErrForbidden,
+			fmt.Sprintf("Insufficient karma to %s (required: %d, current: %d)", describe(op), req.MinKarma, user.Karma), nil)
+	}
+	return nil
+}
+
+// CheckUser loads the user and checks them against op. Open operations
+// don't load anyone.
+func (p Policies) CheckUser(ctx context.Context, users UserLookup, op string, userID uuid.UUID) error {
+	if _, ok := p[op]; !ok {
+		return nil
+	}
+	user, err := users.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if appErr := p.Check(op, user, time.Now()); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// describe phrases op for error messages.
+func describe(op string) string {
+	switch op {
+	case OpCreateSubreddit:
+		return "create a subreddit"
+	case OpCreatePost:
+		return "post"
+	case OpCreateComment:
+		return "comment"
+	case OpVote:
+		return "vote"
+	case OpSendMessage:
+		return "send messages"
+	default:
+		return strings.ReplaceAll(op, ".", " ")
+	}
+}
+
+// formatAge phrases an account age in days, or hours when under a day.
+func formatAge(d time.Duration) string {
+	if days := int(d / (24 * time.Hour)); days >= 1 {
+		if days == 1 {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", days)
+	}
+	return fmt.Sprintf("%d hours", int(d/time.Hour))
+}
@@ -0,0 +1,12 @@
+package presence
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics for activity tracking, exposed on /metrics.
+var heartbeats = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gator_presence_heartbeats_total",
+	Help: "User activity heartbeats, by result (written, debounced).",
+}, []string{"result"})
@@ -0,0 +1,83 @@
+// Package presence keeps users.is_connected and last_active current from
+// client heartbeats and WebSocket pongs. Connections opening and closing are
+// recorded by the UserSupervisor, which also announces them.
+package presence
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gator-swamp/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const writeTimeout = 5 * time.Second
+
+// Tracker records user activity, writing to the database at most once per
+// interval per user so frequent heartbeats stay cheap.
+type Tracker struct {
+	db        database.UserRepository
+	interval  time.Duration
+	mu        sync.Mutex
+	lastWrite map[uuid.UUID]time.Time
+}
+
+// NewTracker creates a tracker that debounces activity writes to interval.
+func NewTracker(db database.UserRepository, interval time.Duration) *Tracker {
+	return &Tracker{
+		db:        db,
+		interval:  interval,
+		lastWrite: make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Touch marks the user active and connected. It returns false when the
+// write was skipped because the user was already recorded within interval.
+func (t *Tracker) Touch(userID uuid.UUID) bool {
+	now := time.Now()
+	t.mu.Lock()
+	if last, ok := t.lastWrite[userID]; ok && now.Sub(last) < t.interval {
+		t.mu.Unlock()
+		heartbeats.WithLabelValues("debounced").Inc()
+		return false
+	}
+	t.lastWrite[userID] = now
+	t.pruneLocked(now)
+	t.mu.Unlock()
+
+	heartbeats.WithLabelValues("written").Inc()
+	go t.write(userID, true)
+	return true
+}
+
+// Reset forgets the user's last write, so their next Touch is written
+// right away. Call it when they disconnect, which is recorded elsewhere.
+func (t *Tracker) Reset(userID uuid.UUID) {
+	t.mu.Lock()
+	delete(t.lastWrite, userID)
+	t.mu.Unlock()
+}
+
+func (t *Tracker) write(userID uuid.UUID, connected bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	if err := t.db.UpdateUserActivity(ctx, userID, connected); err != nil {
+		slog.ErrorContext(ctx, "Failed to record activity", "user_id", userID, "error", err)
+	}
+}
+
+// pruneLocked drops entries older than interval once the map grows, since
+// they no longer suppress any writes.
+func (t *Tracker) pruneLocked(now time.Time) {
+	if len(t.lastWrite) < 10000 {
+		return
+	}
+	for id, last := range t.lastWrite {
+		if now.Sub(last) >= t.interval {
+			delete(t.lastWrite, id)
+		}
+	}
+}
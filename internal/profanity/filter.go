@@ -0,0 +1,41 @@
+// Package profanity masks configured words in text shown to users. Stored
+// content is never changed.
+package profanity
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Filter masks whole-word, case-insensitive matches of its words. A nil
+// *Filter masks nothing.
+type Filter struct {
+	re *regexp.Regexp
+}
+
+// New builds a filter for words, ignoring blanks. It returns nil if there
+// are none.
+func New(words []string) *Filter {
+	var quoted []string
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+	return &Filter{re: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)}
+}
+
+// Mask replaces every letter of each match but the first with '*'.
+func (f *Filter) Mask(text string) string {
+	if f == nil {
+		return text
+	}
+	return f.re.ReplaceAllStringFunc(text, func(word string) string {
+		_, size := utf8.DecodeRuneInString(word)
+		return word[:size] + strings.Repeat("*", utf8.RuneCountInString(word[size:]))
+	})
+}
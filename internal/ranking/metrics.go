@@ -0,0 +1,18 @@
+package ranking
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// feedsServed counts feed pages by ranking, exposed on /metrics, so an
+// experiment's traffic can be compared with the default sort's.
+var feedsServed = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gator_feed_pages_total",
+	Help: "Feed pages served, by ranking (an experiment, or default).",
+}, []string{"ranking"})
+
+// Served counts a feed page served with the named ranking.
+func Served(ranking string) {
+	feedsServed.WithLabelValues(ranking).Inc()
+}
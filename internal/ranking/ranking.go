@@ -0,0 +1,114 @@
+// Package ranking orders feeds for ranking experiments. An experiment is a
+// weighted sum of scorers. A feed request names the experiment it wants;
+// without one the feed keeps its usual sort, so each experiment can be
+// compared against it.
+package ranking
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Default names the usual sort where feeds served are counted by ranking.
+const Default = "default"
+
+// Signals are what scorers know besides the post itself.
+type Signals struct {
+	Now time.Time
+	// The viewer's net votes on each author's posts and comments; only
+	// loaded for experiments that use the affinity scorer
+	Affinity map[uuid.UUID]int
+}
+
+// Scorer rates a post for a viewer, higher first. Scores fall in [-1, 1] so
+// an experiment's weights are comparable.
+type Scorer func(post *models.Post, s *Signals) float64
+
+// Names of the scorers experiments weigh
+const (
+	ScoreRecency  = "recency"
+	ScoreKarma    = "karma"
+	ScoreAffinity = "affinity"
+)
+
+// Scorers are the available scorers by name.
+var Scorers = map[string]Scorer{
+	ScoreRecency:  Recency,
+	ScoreKarma:    Karma,
+	ScoreAffinity: Affinity,
+}
+
+// recencyHalfLife is how long a post takes to lose half its recency score.
+const recencyHalfLife = 12 * time.Hour
+
+// Recency halves a post's score every recencyHalfLife since it was posted.
+func Recency(post *models.Post, s *Signals) float64 {
+	age := max(s.Now.Sub(post.CreatedAt), 0)
+	return math.Exp2(-float64(age) / float64(recencyHalfLife))
+}
+
+// karmaScale is the karma that scores 1. Post popularity is roughly Zipf
+// distributed, so karma is scored on a log scale and the few most popular
+// posts don't drown out everything else.
+const karmaScale = 10000
+
+// Karma scores a post's karma on a log scale, negative for negative karma.
+func Karma(post *models.Post, s *Signals) float64 {
+	score := math.Log1p(math.Abs(float64(post.Karma))) / math.Log1p(karmaScale)
+	return math.Copysign(math.Min(score, 1), float64(post.Karma))
+}
+
+// affinityScale is the net votes on an author at which affinity nears its
+// limit.
+const affinityScale = 5
+
+// Affinity scores how much the viewer has upvoted the post's author before,
+// or downvoted them when negative.
+func Affinity(post *models.Post, s *Signals) float64 {
+	return math.Tanh(float64(s.Affinity[post.AuthorID]) / affinityScale)
+}
+
+// Experiment weighs scorers by name.
+type Experiment map[string]float64
+
+// Experiments are the rankings feeds can ask for by name.
+var Experiments = map[string]Experiment{
+	"recency":  {ScoreRecency: 1},
+	"karma":    {ScoreKarma: 1, ScoreRecency: 0.5},
+	"affinity": {ScoreAffinity: 1, ScoreRecency: 0.5},
+	"blend":    {ScoreRecency: 1, ScoreKarma: 0.5, ScoreAffinity: 0.5},
+}
+
+// Uses reports whether the experiment weighs the named scorer, e.g. to skip
+// loading signals it doesn't need.
+func (e Experiment) Uses(scorer string) bool {
+	return e[scorer] != 0
+}
+
+// Score is the weighted sum of the experiment's scorers for a post.
+func (e Experiment) Score(post *models.Post, s *Signals) float64 {
+	var score float64
+	for name, weight := range e {
+		if scorer, ok := Scorers[name]; ok {
+			score += weight * scorer(post, s)
+		}
+	}
+	return score
+}
+
+// Rank sorts posts by score, best first. Posts that score the same keep
+// their order.
+func (e Experiment) Rank(posts []*models.Post, s *Signals) {
+	scores := make(map[uuid.UUID]float64, len(posts))
+	for _, post := range posts {
+		scores[post.ID] = e.Score(post, s)
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		return scores[posts[i].ID] > scores[posts[j].ID]
+	})
+}
@@ -0,0 +1,60 @@
+package search
+
+import (
+	"context"
+
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/models"
+)
+
+// reindexPageSize is how many posts Reindex reads at a time
+const reindexPageSize = 500
+
+// Reindex indexes every post that isn't deleted, subreddit by subreddit,
+// and returns how many it indexed. It backfills an index configured after
+// posts were written, and re-adds restored posts. progress, if set, is
+// called after each subreddit.
+func Reindex(ctx context.Context, provider Provider, db database.SubredditRepository, progress func(sub *models.Subreddit, indexed int)) (int, error) {
+	subs, err := db.GetAllSubreddits(ctx)
+	if err != nil {
+		return 0, err
+	}
+	indexed := 0
+	for _, sub := range subs {
+		var after *models.Keyset
+		for {
+			posts, err := db.GetSubredditArchivePosts(ctx, sub.ID, reindexPageSize, after)
+			if err != nil {
+				return indexed, err
+			}
+			for _, post := range posts {
+				if err := provider.Index(ctx, NewDocument(post)); err != nil {
+					return indexed, err
+				}
+				indexed++
+			}
+			if len(posts) < reindexPageSize {
+				break
+			}
+			keyset := posts[len(posts)-1].Keyset()
+			after = &keyset
+		}
+		if progress != nil {
+			progress(sub, indexed)
+		}
+	}
+	return indexed, nil
+}
+
+// NewDocument is the index document for post.
+func NewDocument(post *models.Post) *Document {
+	return &Document{
+		PostID:         post.ID,
+		Title:          post.Title,
+		Content:        post.Content,
+		SubredditID:    post.SubredditID,
+		SubredditName:  post.SubredditName,
+		AuthorUsername: post.AuthorUsername,
+		CreatedAt:      post.CreatedAt,
+	}
+}
@@ -0,0 +1,41 @@
+package search
+
+import (
+	"context"
+
+	"gator-swamp/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// PostgresProvider searches posts with Postgres full-text search, which is
+// always current, so there's nothing to index.
+type PostgresProvider struct {
+	db database.PostRepository
+}
+
+// NewPostgresProvider searches db.
+func NewPostgresProvider(db database.PostRepository) *PostgresProvider {
+	return &PostgresProvider{db: db}
+}
+
+// Search returns matching post IDs in sort order, leaving out NSFW and
+// quarantined subreddits viewerID didn't opt in to.
+func (p *PostgresProvider) Search(ctx context.Context, query, sort string, limit, offset int, viewerID uuid.UUID) ([]uuid.UUID, error) {
+	return p.db.SearchPosts(ctx, query, sort, limit, offset, viewerID)
+}
+
+// Index is a no-op.
+func (p *PostgresProvider) Index(ctx context.Context, doc *Document) error {
+	return nil
+}
+
+// Delete is a no-op.
+func (p *PostgresProvider) Delete(ctx context.Context, postID uuid.UUID) error {
+	return nil
+}
+
+// External is false.
+func (p *PostgresProvider) External() bool {
+	return false
+}
@@ -0,0 +1,61 @@
+// Package search finds posts by text. Postgres full-text search works out of
+// the box; an Elasticsearch or OpenSearch index can be used instead, kept up
+// to date from domain events by the engine's SearchActor.
+package search
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gator-swamp/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// Provider names accepted by NewProvider
+const (
+	ProviderPostgres      = "postgres"
+	ProviderElasticsearch = "elasticsearch" // Also OpenSearch, which shares the API
+)
+
+// Provider searches posts. Index and Delete keep an external index current;
+// providers that search the database directly ignore them. Comments and
+// subreddits are always searched in the database.
+type Provider interface {
+	// Search orders results by models.SearchSortRelevance or SearchSortNew.
+	// Providers that can, leave out posts viewerID hasn't opted in to seeing
+	// (see models.UserPreferences); the others leave that to the caller.
+	Search(ctx context.Context, query, sort string, limit, offset int, viewerID uuid.UUID) ([]uuid.UUID, error)
+	Index(ctx context.Context, doc *Document) error
+	Delete(ctx context.Context, postID uuid.UUID) error
+	// External reports whether the provider needs an indexer
+	External() bool
+}
+
+// Document is what an external index stores for a post.
+type Document struct {
+	PostID         uuid.UUID `json:"postId"`
+	Title          string    `json:"title"`
+	Content        string    `json:"content"`
+	SubredditID    uuid.UUID `json:"subredditId"`
+	SubredditName  string    `json:"subredditName"`
+	AuthorUsername string    `json:"authorUsername"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// NewProvider builds the provider named by kind. url and index locate the
+// external index and are ignored for Postgres.
+func NewProvider(kind, url, index string, db database.PostRepository) (Provider, error) {
+	switch kind {
+	case "", ProviderPostgres:
+		return NewPostgresProvider(db), nil
+	case ProviderElasticsearch, "opensearch":
+		if url == "" {
+			return nil, fmt.Errorf("a URL is required for the %s search provider", kind)
+		}
+		return NewElasticsearchProvider(url, index), nil
+	default:
+		return nil, fmt.Errorf("unknown search provider %q (expected postgres, elasticsearch or opensearch)", kind)
+	}
+}
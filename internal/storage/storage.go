@@ -0,0 +1,25 @@
+// Package storage keeps uploaded media (avatars, post images) behind a small
+// interface so the backend can change without touching the upload pipeline.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned by Get for a key that doesn't exist.
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage stores objects under slash-separated keys such as
+// "avatars/<user>/<upload>/thumb.jpg".
+type Storage interface {
+	// Put writes r under key, replacing any existing object.
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Get opens the object at key. The caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object at key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL clients use to fetch key.
+	URL(key string) string
+}
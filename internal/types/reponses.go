@@ -1,8 +1,10 @@
 package types
 
 type LoginResponse struct {
-	Success bool   `json:"success"`
-	Token   string `json:"token,omitempty"`
-	Error   string `json:"error,omitempty"`
-	UserID  string `json:"userId"`
+	Success      bool   `json:"success"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	Error        string `json:"error,omitempty"`
+	UserID       string `json:"userId"`
+	IsBot        bool   `json:"isBot,omitempty"`
 }
@@ -1,6 +1,9 @@
 package utils
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 type AppError struct {
 	Code    string
@@ -15,6 +18,11 @@ func (appErr *AppError) Error() string {
 	return appErr.Message
 }
 
+// Unwrap returns the original error so errors.Is and errors.As see through AppErrors.
+func (appErr *AppError) Unwrap() error {
+	return appErr.Origin
+}
+
 // Standard error codes for the application
 const (
 	// Resource errors
@@ -47,11 +55,20 @@ const (
 	// Rate limiting
 	ErrTooManyRequests = "TOO_MANY_REQUESTS"
 
+	// A dependency (e.g. the database) is down; retry later
+	ErrUnavailable = "UNAVAILABLE"
+
 	ErrDatabase = "database_error"
 )
 
-// Error creation helper functions
+// Error creation helper functions.
+// Wrapping an ErrUnavailable error keeps that code, so an outage surfaces as
+// unavailable however many layers rewrap it.
 func NewAppError(code string, message string, originalErr error) *AppError {
+	var origin *AppError
+	if errors.As(originalErr, &origin) && origin.Code == ErrUnavailable {
+		code = ErrUnavailable
+	}
 	return &AppError{
 		Code:    code,
 		Message: message,
@@ -128,6 +145,8 @@ func AppErrorToHTTPStatus(errorCode string) int {
 		return 409 // http.StatusConflict
 	case ErrTooManyRequests:
 		return 429 // http.StatusTooManyRequests
+	case ErrUnavailable:
+		return 503 // http.StatusServiceUnavailable
 	case ErrDatabase, ErrActorTimeout, ErrMessageRejected:
 		return 500 // http.StatusInternalServerError
 	default:
@@ -1,7 +1,9 @@
 package websocket
 
 import (
-	"log"
+	"encoding/json"
+	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,8 +22,21 @@ const (
 
 	// Maximum message size allowed from peer.
 	maxMessageSize = 512
+
+	// How long clients are told to wait before reconnecting after a shutdown.
+	reconnectAfter = 5 * time.Second
 )
 
+// shutdownCloseFrame tells clients the server is restarting (1012) and when
+// to reconnect. The reason is JSON so clients can parse the hint.
+var shutdownCloseFrame = websocket.FormatCloseMessage(websocket.CloseServiceRestart,
+	fmt.Sprintf(`{"type":"shutdown","reconnectAfterMs":%d}`, reconnectAfter.Milliseconds()))
+
+// limitCloseFrame rejects a connection that raced past CheckCapacity (1008).
+func limitCloseFrame(err error) []byte {
+	return websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error())
+}
+
 // Client is a middleman between the websocket connection and the hub.
 type Client struct {
 	Hub *Hub
@@ -34,6 +49,19 @@ type Client struct {
 
 	// Buffered channel of outbound messages.
 	Send chan []byte
+
+	// Close frame sent once the hub closes Send; set by the hub before closing.
+	closeFrame []byte
+
+	// Topics this connection subscribes to, guarded by the hub's mu.
+	topics map[string]bool
+}
+
+// clientMessage is a request sent by the client over the connection.
+type clientMessage struct {
+	Type   string    `json:"type"`  // "subscribe" or "unsubscribe"; "watchPost" and "unwatchPost" for older clients
+	Topic  string    `json:"topic"` // e.g. "post:<id>"
+	PostID uuid.UUID `json:"postId"`
 }
 
 // ReadPump pumps messages from the websocket connection to the hub.
@@ -42,21 +70,52 @@ func (c *Client) ReadPump() {
 	defer func() {
 		c.Hub.Unregister <- c
 		c.Conn.Close()
-		log.Printf("WebSocket Client ReadPump stopped for User %s", c.UserID)
+		slog.Debug("WebSocket read pump stopped", "user_id", c.UserID)
 	}()
 	c.Conn.SetReadLimit(maxMessageSize)
 	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.Conn.SetPongHandler(func(string) error { c.Conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
+	c.Conn.SetPongHandler(func(string) error {
+		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+		if c.Hub.OnActivity != nil {
+			c.Hub.OnActivity(c.UserID)
+		}
+		return nil
+	})
 	for {
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket read error for User %s: %v", c.UserID, err)
+				slog.Warn("WebSocket read failed", "user_id", c.UserID, "error", err)
 			}
 			break
 		}
-		// Placeholder for processing incoming client messages if needed in the future
-		log.Printf("Received (but not processed) message from User %s: %s", c.UserID, string(message))
+		var msg clientMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			slog.Debug("Ignoring malformed WebSocket message", "user_id", c.UserID, "error", err)
+			continue
+		}
+		// Older clients watch and unwatch posts by ID
+		switch msg.Type {
+		case "watchPost":
+			msg.Type, msg.Topic = "subscribe", PostTopic(msg.PostID)
+		case "unwatchPost":
+			msg.Type, msg.Topic = "unsubscribe", PostTopic(msg.PostID)
+		}
+		switch msg.Type {
+		case "subscribe", "unsubscribe":
+			topic, ok := ParseTopic(msg.Topic)
+			if !ok {
+				slog.Debug("Ignoring subscription to unknown topic", "user_id", c.UserID, "topic", msg.Topic)
+				continue
+			}
+			if msg.Type == "unsubscribe" {
+				c.Hub.Unsubscribe(c, topic)
+			} else if !c.Hub.Subscribe(c, topic) {
+				slog.Info("Subscription limit reached", "user_id", c.UserID, "topic", topic)
+			}
+		default:
+			slog.Debug("Ignoring WebSocket message", "user_id", c.UserID, "type", msg.Type)
+		}
 	}
 }
 
@@ -67,21 +126,22 @@ func (c *Client) WritePump() {
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
-		log.Printf("WebSocket Client WritePump stopped for User %s", c.UserID)
+		c.Hub.pumps.Done()
+		slog.Debug("WebSocket write pump stopped", "user_id", c.UserID)
 	}()
 	for {
 		select {
 		case message, ok := <-c.Send:
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
-				// The hub closed the channel.
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				// The hub closed the channel (shutdown or rejected registration).
+				c.Conn.WriteMessage(websocket.CloseMessage, c.closeFrame)
 				return
 			}
 
 			w, err := c.Conn.NextWriter(websocket.TextMessage)
 			if err != nil {
-				log.Printf("WebSocket write error (NextWriter) for User %s: %v", c.UserID, err)
+				slog.Warn("WebSocket write failed", "user_id", c.UserID, "error", err)
 				return
 			}
 			w.Write(message)
@@ -94,13 +154,13 @@ func (c *Client) WritePump() {
 			}
 
 			if err := w.Close(); err != nil {
-				log.Printf("WebSocket write error (Close) for User %s: %v", c.UserID, err)
+				slog.Warn("WebSocket write failed", "user_id", c.UserID, "error", err)
 				return
 			}
 		case <-ticker.C:
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				log.Printf("WebSocket write error (Ping) for User %s: %v", c.UserID, err)
+				slog.Warn("WebSocket ping failed", "user_id", c.UserID, "error", err)
 				return
 			}
 		}
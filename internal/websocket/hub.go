@@ -1,7 +1,9 @@
 package websocket
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -14,6 +16,12 @@ type MessageToSend struct {
 	Payload      []byte
 }
 
+// Errors returned by CheckCapacity.
+var (
+	ErrUserConnectionLimit = errors.New("too many WebSocket connections for this user")
+	ErrHubFull             = errors.New("WebSocket server is at connection capacity")
+)
+
 // Hub maintains the set of active clients and broadcasts messages.
 type Hub struct {
 	// Registered clients. Maps user ID to a set of active client connections.
@@ -33,30 +41,82 @@ type Hub struct {
 
 	// Mutex to protect concurrent access to the clients map.
 	mu sync.RWMutex
+
+	// Shutdown requests; once closing, new registrations are turned away.
+	shutdown chan struct{}
+	closing  bool
+
+	// Tracks running WritePumps so Shutdown can wait for pending sends to drain.
+	pumps sync.WaitGroup
+
+	// Connection limits; 0 means unlimited. Set before Run.
+	MaxConnsPerUser int
+	MaxConns        int
+
+	// Total registered connections, guarded by mu.
+	total int
+
+	// Connections subscribed to each topic, guarded by mu.
+	subscribers map[string]map[*Client]bool
+
+	// Optional activity hooks, set before Run. OnActivity is called when a
+	// client registers or answers a ping; OnOnline when a user's first
+	// connection opens, instead of OnActivity; OnOffline when a user's last
+	// connection closes.
+	// They must not block.
+	OnActivity func(userID uuid.UUID)
+	OnOnline   func(userID uuid.UUID)
+	OnOffline  func(userID uuid.UUID)
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		Broadcast:  make(chan []byte),
-		SendDirect: make(chan *MessageToSend),
-		Register:   make(chan *Client),
-		Unregister: make(chan *Client),
-		Clients:    make(map[uuid.UUID]map[*Client]bool),
+		Broadcast:   make(chan []byte),
+		SendDirect:  make(chan *MessageToSend),
+		Register:    make(chan *Client),
+		Unregister:  make(chan *Client),
+		Clients:     make(map[uuid.UUID]map[*Client]bool),
+		subscribers: make(map[string]map[*Client]bool),
+		shutdown:    make(chan struct{}),
 	}
 }
 
 // Run starts the hub's processing loop.
 func (h *Hub) Run() {
-	log.Println("WebSocket Hub started.")
+	slog.Info("WebSocket hub started")
 	for {
 		select {
 		case client := <-h.Register:
 			h.mu.Lock()
-			if _, ok := h.Clients[client.UserID]; !ok {
+			h.pumps.Add(1) // Released when the client's WritePump exits
+			if h.closing {
+				closeClient(client, shutdownCloseFrame)
+				h.mu.Unlock()
+				slog.Info("WebSocket client rejected", "user_id", client.UserID, "reason", "hub is shutting down")
+				continue
+			}
+			// Re-checked here since CheckCapacity ran before the upgrade and may race
+			if err := h.checkCapacityLocked(client.UserID); err != nil {
+				closeClient(client, limitCloseFrame(err))
+				h.mu.Unlock()
+				slog.Info("WebSocket client rejected", "user_id", client.UserID, "error", err)
+				continue
+			}
+			_, online := h.Clients[client.UserID]
+			if !online {
 				h.Clients[client.UserID] = make(map[*Client]bool)
+				connectedUsers.Inc()
 			}
 			h.Clients[client.UserID][client] = true
-			log.Printf("WebSocket Client registered for User %s. Total connections for user: %d", client.UserID, len(h.Clients[client.UserID]))
+			h.total++
+			activeConnections.Inc()
+			connectionsPerUser.Observe(float64(len(h.Clients[client.UserID])))
+			if !online && h.OnOnline != nil {
+				h.OnOnline(client.UserID)
+			} else if h.OnActivity != nil {
+				h.OnActivity(client.UserID)
+			}
+			slog.Debug("WebSocket client registered", "user_id", client.UserID, "connections", len(h.Clients[client.UserID]))
 			h.mu.Unlock()
 
 		case client := <-h.Unregister:
@@ -64,13 +124,18 @@ func (h *Hub) Run() {
 			if userClients, ok := h.Clients[client.UserID]; ok {
 				if _, clientOk := userClients[client]; clientOk {
 					delete(userClients, client)
+					h.unsubscribeAllLocked(client)
+					h.total--
+					activeConnections.Dec()
 					// Note: Closing client.Send channel is typically handled by the writePump upon error or hub closure.
 					if len(userClients) == 0 {
 						delete(h.Clients, client.UserID)
-						log.Printf("WebSocket Client unregistered. User %s has no more connections.", client.UserID)
-					} else {
-						log.Printf("WebSocket Client unregistered for User %s. Remaining connections: %d", client.UserID, len(userClients))
+						connectedUsers.Dec()
+						if h.OnOffline != nil {
+							h.OnOffline(client.UserID)
+						}
 					}
+					slog.Debug("WebSocket client unregistered", "user_id", client.UserID, "connections", len(userClients))
 				}
 			}
 			h.mu.Unlock()
@@ -81,37 +146,146 @@ func (h *Hub) Run() {
 				for client := range userClients {
 					select {
 					case client.Send <- message:
+						messagesPushed.WithLabelValues("broadcast").Inc()
 					default:
-						log.Printf("Broadcast send buffer full for client of User %s", client.UserID)
+						sendBufferDrops.Inc()
+						slog.Warn("Send buffer full, broadcast dropped", "user_id", client.UserID)
 					}
 				}
 			}
 			h.mu.RUnlock()
 
+		case <-h.shutdown:
+			h.mu.Lock()
+			h.closing = true
+			closed := 0
+			for userID, userClients := range h.Clients {
+				for client := range userClients {
+					// WritePump flushes what's already buffered, then sends the close frame
+					h.unsubscribeAllLocked(client)
+					closeClient(client, shutdownCloseFrame)
+					activeConnections.Dec()
+					closed++
+				}
+				delete(h.Clients, userID)
+				connectedUsers.Dec()
+			}
+			h.total = 0
+			h.mu.Unlock()
+			slog.Info("WebSocket hub shutting down", "connections", closed)
+
 		case directMessage := <-h.SendDirect:
 			h.mu.RLock()
 			if userClients, ok := h.Clients[directMessage.TargetUserID]; ok {
 				if len(userClients) > 0 {
-					log.Printf("Sending direct message to %d connections for User %s", len(userClients), directMessage.TargetUserID)
 					for client := range userClients {
 						select {
 						case client.Send <- directMessage.Payload:
-							log.Printf("Message successfully queued for client of User %s", client.UserID)
+							messagesPushed.WithLabelValues("direct").Inc()
 						default:
-							log.Printf("Send channel full for client of User %s. Message dropped for this client.", client.UserID)
+							sendBufferDrops.Inc()
+							slog.Warn("Send buffer full, message dropped", "user_id", client.UserID)
 						}
 					}
 				} else {
-					log.Printf("User %s found in map but has no active client connections.", directMessage.TargetUserID)
+					pushFailures.WithLabelValues("offline").Inc()
 				}
 			} else {
-				log.Printf("User %s not connected, cannot send direct message.", directMessage.TargetUserID)
+				pushFailures.WithLabelValues("offline").Inc()
+				slog.Debug("User not connected, direct message dropped", "user_id", directMessage.TargetUserID)
 			}
 			h.mu.RUnlock()
 		}
 	}
 }
 
+// Shutdown stops accepting registrations, asks every client to reconnect via
+// a close frame, and waits for their pending sends to drain. It returns
+// ctx.Err() if connections are still draining when ctx expires.
+// The hub keeps running afterwards so late Unregister calls don't block.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	select {
+	case h.shutdown <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		h.pumps.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		slog.Info("WebSocket hub drained all connections")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CheckCapacity reports whether a new connection for userID would currently
+// be accepted, so handlers can reject excess upgrades with an HTTP status
+// before upgrading. Returns ErrUserConnectionLimit or ErrHubFull otherwise.
+func (h *Hub) CheckCapacity(userID uuid.UUID) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.checkCapacityLocked(userID)
+}
+
+func (h *Hub) checkCapacityLocked(userID uuid.UUID) error {
+	if h.MaxConns > 0 && h.total >= h.MaxConns {
+		return ErrHubFull
+	}
+	if h.MaxConnsPerUser > 0 && len(h.Clients[userID]) >= h.MaxConnsPerUser {
+		return ErrUserConnectionLimit
+	}
+	return nil
+}
+
+// closeClient makes the client's WritePump flush its buffer and then send
+// frame. Callers must hold h.mu and the client must not be in h.Clients.
+func closeClient(client *Client, frame []byte) {
+	client.closeFrame = frame
+	close(client.Send)
+}
+
+// IsClosing reports whether Shutdown has been called.
+func (h *Hub) IsClosing() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.closing
+}
+
+// SendToConnected pushes payload to every connection of the given users that
+// are currently online, skipping offline users without queuing anything.
+// It never blocks; clients with a full send buffer miss the message.
+// Returns the number of users the payload was delivered to.
+func (h *Hub) SendToConnected(userIDs []uuid.UUID, payload []byte) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	delivered := 0
+	for _, userID := range userIDs {
+		userClients, ok := h.Clients[userID]
+		if !ok || len(userClients) == 0 {
+			continue
+		}
+		for client := range userClients {
+			select {
+			case client.Send <- payload:
+				messagesPushed.WithLabelValues("fanout").Inc()
+			default:
+				sendBufferDrops.Inc()
+				slog.Warn("Send buffer full, message dropped", "user_id", client.UserID)
+			}
+		}
+		delivered++
+	}
+	return delivered
+}
+
 // SendDirectMessage allows other parts of the application (like actors) to send a message
 // to a specific user via the WebSocket hub.
 func (h *Hub) SendDirectMessage(targetUserID uuid.UUID, payload []byte) {
@@ -121,8 +295,8 @@ func (h *Hub) SendDirectMessage(targetUserID uuid.UUID, payload []byte) {
 	}
 	select {
 	case h.SendDirect <- message:
-		log.Printf("Message queued in hub for User %s", targetUserID)
 	case <-time.After(1 * time.Second):
-		log.Printf("Timeout queuing message in hub's SendDirect channel for User %s. Hub might be busy or blocked.", targetUserID)
+		pushFailures.WithLabelValues("hub_timeout").Inc()
+		slog.Warn("Timed out queuing direct message in hub", "user_id", targetUserID)
 	}
 }
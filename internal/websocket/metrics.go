@@ -0,0 +1,40 @@
+package websocket
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics for the realtime layer, exposed on /metrics.
+var (
+	activeConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gator_websocket_active_connections",
+		Help: "Number of open WebSocket connections.",
+	})
+
+	connectedUsers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gator_websocket_connected_users",
+		Help: "Number of users with at least one open WebSocket connection.",
+	})
+
+	connectionsPerUser = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "gator_websocket_connections_per_user",
+		Help:    "Open connections held by a user, observed each time one of their connections registers.",
+		Buckets: []float64{1, 2, 3, 5, 10},
+	})
+
+	messagesPushed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_websocket_messages_pushed_total",
+		Help: "Messages queued to client connections, by kind (direct, broadcast, fanout, topic).",
+	}, []string{"kind"})
+
+	pushFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_websocket_push_failures_total",
+		Help: "Messages that could not be handed to any connection, by reason (offline, hub_timeout).",
+	}, []string{"reason"})
+
+	sendBufferDrops = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gator_websocket_send_buffer_drops_total",
+		Help: "Messages dropped because a client's send buffer was full.",
+	})
+)
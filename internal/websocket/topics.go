@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxTopics caps the topics one connection can subscribe to at once.
+const maxTopics = 50
+
+// Topics are "<kind>:<id>" strings. Only posts can be subscribed to so far.
+const topicPost = "post"
+
+// PostTopic is the topic of live updates to a post and its comments.
+func PostTopic(postID uuid.UUID) string {
+	return topicPost + ":" + postID.String()
+}
+
+// ParseTopic checks a topic a client asked for and returns it in canonical
+// form.
+func ParseTopic(raw string) (string, bool) {
+	kind, id, ok := strings.Cut(raw, ":")
+	if !ok || kind != topicPost {
+		return "", false
+	}
+	postID, err := uuid.Parse(id)
+	if err != nil {
+		return "", false
+	}
+	return PostTopic(postID), true
+}
+
+// Subscribe adds a registered connection to a topic's subscribers. It
+// returns false if the connection already has maxTopics subscriptions.
+func (h *Hub) Subscribe(client *Client, topic string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.Clients[client.UserID][client] {
+		return true // Unregistered or closing; nothing will be sent to it
+	}
+	if client.topics == nil {
+		client.topics = make(map[string]bool)
+	}
+	if client.topics[topic] {
+		return true
+	}
+	if len(client.topics) >= maxTopics {
+		return false
+	}
+	client.topics[topic] = true
+	if h.subscribers[topic] == nil {
+		h.subscribers[topic] = make(map[*Client]bool)
+	}
+	h.subscribers[topic][client] = true
+	return true
+}
+
+// Unsubscribe removes a connection from a topic's subscribers.
+func (h *Hub) Unsubscribe(client *Client, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unsubscribeLocked(client, topic)
+}
+
+func (h *Hub) unsubscribeLocked(client *Client, topic string) {
+	delete(client.topics, topic)
+	if subscribers, ok := h.subscribers[topic]; ok {
+		delete(subscribers, client)
+		if len(subscribers) == 0 {
+			delete(h.subscribers, topic)
+		}
+	}
+}
+
+// unsubscribeAllLocked drops every subscription of a connection that is
+// going away. Callers must hold h.mu.
+func (h *Hub) unsubscribeAllLocked(client *Client) {
+	for topic := range client.topics {
+		h.unsubscribeLocked(client, topic)
+	}
+}
+
+// HasSubscribers reports whether any connection on this instance subscribes
+// to a topic, so updates nobody here would see can be skipped.
+func (h *Hub) HasSubscribers(topic string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers[topic]) > 0
+}
+
+// Publish pushes payload to every connection subscribed to a topic. Like
+// SendToConnected it never blocks. Returns the number of connections the
+// payload was queued for.
+func (h *Hub) Publish(topic string, payload []byte) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	delivered := 0
+	for client := range h.subscribers[topic] {
+		select {
+		case client.Send <- payload:
+			messagesPushed.WithLabelValues("topic").Inc()
+			delivered++
+		default:
+			sendBufferDrops.Inc()
+			slog.Warn("Send buffer full, topic update dropped", "user_id", client.UserID, "topic", topic)
+		}
+	}
+	return delivered
+}
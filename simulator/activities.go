@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"gator-swamp/internal/models"
 	"log"
-	"math/rand"
 	"sync"
 	"time"
 
@@ -81,6 +80,21 @@ func (s *EnhancedSimulator) SimulateActivities(ctx context.Context) error {
 		}
 	}()
 
+	// Start read traffic after some posts are available
+	if s.config.ReadFrequency > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case <-ctx.Done():
+				return
+			case <-postsAvailable:
+				log.Printf("Starting reads after posts available...")
+				s.simulateReads(ctx)
+			}
+		}()
+	}
+
 	wg.Wait()
 	return nil
 }
@@ -105,8 +119,8 @@ func (s *EnhancedSimulator) simulatePosts(ctx context.Context, postsAvailable ch
 					continue
 				}
 
-				if rand.Float64() < (s.config.PostFrequency/3600.0)/2.0 {
-					subredditID := user.Subscriptions[rand.Intn(len(user.Subscriptions))]
+				if s.rng.Float64() < (s.config.PostFrequency/3600.0)/2.0 {
+					subredditID := user.Subscriptions[s.rng.Intn(len(user.Subscriptions))]
 
 					// Ensure membership before posting
 					joinData := map[string]interface{}{
@@ -209,7 +223,7 @@ func (s *EnhancedSimulator) simulateComments(ctx context.Context) {
 					continue
 				}
 
-				if rand.Float64() < (s.config.CommentFrequency/3600.0)/2.0 {
+				if s.rng.Float64() < (s.config.CommentFrequency/3600.0)/2.0 {
 					postID, err := s.getRandomPostToComment(user)
 					if err != nil {
 						log.Printf("Debug: Worker %d failed to get random post: %v", workerID, err)
@@ -282,30 +296,66 @@ func (s *EnhancedSimulator) simulateVotes(ctx context.Context) {
 					continue
 				}
 
-				if rand.Float64() < (s.config.VoteFrequency/3600.0)/2.0 {
+				if s.rng.Float64() < (s.config.VoteFrequency/3600.0)/2.0 {
 					postID, err := s.getRandomPostToVote(user)
 					if err != nil {
 						continue
 					}
 
-					if user.VotedPosts[postID] {
-						continue
+					s.mu.RLock()
+					previous, voted := user.VotedPosts[postID]
+					s.mu.RUnlock()
+
+					var direction models.VoteDirection
+					if !voted {
+						direction = models.VoteDown
+						if s.rng.Float64() < 0.7 {
+							direction = models.VoteUp
+						}
+					} else {
+						// Most users leave an existing vote alone; some change their mind
+						if s.rng.Float64() >= s.config.VoteChangeRate {
+							continue
+						}
+						switch {
+						case s.rng.Float64() < 0.5:
+							direction = models.VoteNone
+						case previous == models.VoteUp:
+							direction = models.VoteDown
+						default:
+							direction = models.VoteUp
+						}
 					}
 
-					isUpvote := rand.Float64() < 0.7
 					data := map[string]interface{}{
-						"userId":   user.ID.String(),
-						"postId":   postID.String(),
-						"isUpvote": isUpvote,
+						"userId":     user.ID.String(),
+						"postId":     postID.String(),
+						"isUpvote":   direction == models.VoteUp,
+						"removeVote": direction == models.VoteNone,
 					}
 
 					start := time.Now()
-					if _, err := s.makeRequest("POST", "/post/vote", data); err == nil {
+					_, err = s.makeRequest("POST", "/post/vote", data)
+					if err == nil {
 						s.mu.Lock()
-						user.VotedPosts[postID] = true
-						s.stats.TotalVotes++
+						if direction == models.VoteNone {
+							delete(user.VotedPosts, postID)
+						} else {
+							user.VotedPosts[postID] = direction
+						}
 						s.mu.Unlock()
-						log.Printf("Created vote by user %s (upvote: %v)", user.Username, isUpvote)
+
+						s.stats.mu.Lock()
+						switch {
+						case !voted:
+							s.stats.TotalVotes++
+						case direction == models.VoteNone:
+							s.stats.VotesRemoved++
+						default:
+							s.stats.VotesChanged++
+						}
+						s.stats.mu.Unlock()
+						log.Printf("Vote by user %s on post %s: %s -> %s", user.Username, postID, previous, direction)
 					}
 					s.recordRequestMetrics(start, err)
 				}
@@ -340,7 +390,7 @@ func (s *EnhancedSimulator) getRandomPostToComment(user *SimulatedUser) (uuid.UU
 
 	shuffledSubs := make([]uuid.UUID, len(user.Subscriptions))
 	copy(shuffledSubs, user.Subscriptions)
-	rand.Shuffle(len(shuffledSubs), func(i, j int) {
+	s.rng.Shuffle(len(shuffledSubs), func(i, j int) {
 		shuffledSubs[i], shuffledSubs[j] = shuffledSubs[j], shuffledSubs[i]
 	})
 
@@ -360,21 +410,22 @@ func (s *EnhancedSimulator) getRandomPostToComment(user *SimulatedUser) (uuid.UU
 			continue
 		}
 
-		// If it's not an error response, try to parse as array of posts
-		var posts []models.Post
-		if err := json.Unmarshal(resp, &posts); err != nil {
+		// If it's not an error response, try to parse as a page of posts
+		var page listPage[models.Post]
+		if err := json.Unmarshal(resp, &page); err != nil {
 			log.Printf("Debug: Error parsing posts: %v", err)
 			log.Printf("Debug: Raw API response: %s", string(resp))
 			continue
 		}
 
+		posts := page.Items
 		if len(posts) == 0 {
 			log.Printf("Debug: Empty posts array for subreddit %s", subredditID)
 			continue
 		}
 
 		// Select a random post
-		selectedPost := posts[rand.Intn(len(posts))]
+		selectedPost := posts[s.rng.Intn(len(posts))]
 		log.Printf("Debug: Successfully found post %s to comment on", selectedPost.ID)
 		return selectedPost.ID, nil
 	}
@@ -388,18 +439,19 @@ func (s *EnhancedSimulator) getRandomComment(postID uuid.UUID) (uuid.UUID, error
 		return uuid.Nil, err
 	}
 
-	var comments []struct {
+	var page listPage[struct {
 		ID string `json:"id"`
-	}
-	if err := json.Unmarshal(resp, &comments); err != nil {
+	}]
+	if err := json.Unmarshal(resp, &page); err != nil {
 		return uuid.Nil, err
 	}
+	comments := page.Items
 
 	if len(comments) == 0 {
 		return uuid.Nil, fmt.Errorf("no comments found")
 	}
 
-	commentID, err := uuid.Parse(comments[rand.Intn(len(comments))].ID)
+	commentID, err := uuid.Parse(comments[s.rng.Intn(len(comments))].ID)
 	if err != nil {
 		return uuid.Nil, err
 	}
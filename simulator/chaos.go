@@ -0,0 +1,172 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ChaosConfig controls fault injection on simulated requests.
+// All rates are probabilities in the range [0, 1] applied per request.
+type ChaosConfig struct {
+	Enabled          bool
+	AbortRate        float64       // Request is failed client-side before being sent
+	LatencyRate      float64       // Request is delayed before being sent
+	MaxLatency       time.Duration // Upper bound for injected latency
+	DropRate         float64       // Connection is cut while the request is in flight
+	RestartTolerance time.Duration // How long to wait for the engine to come back after an outage
+}
+
+// DefaultChaosConfig returns moderate fault injection settings.
+func DefaultChaosConfig() ChaosConfig {
+	return ChaosConfig{
+		Enabled:          true,
+		AbortRate:        0.02,
+		LatencyRate:      0.05,
+		MaxLatency:       2 * time.Second,
+		DropRate:         0.02,
+		RestartTolerance: 60 * time.Second,
+	}
+}
+
+// ChaosStats records injected faults and how the backend recovered from outages.
+// Fields are guarded by SimulationStats.mu.
+type ChaosStats struct {
+	InjectedAborts   int64
+	InjectedLatency  int64
+	InjectedDrops    int64
+	EngineOutages    int64
+	FailedRecoveries int64
+	RecoveryTimes    []time.Duration
+}
+
+func (cs ChaosStats) snapshot() ChaosStats {
+	cs.RecoveryTimes = append([]time.Duration(nil), cs.RecoveryTimes...)
+	return cs
+}
+
+// InjectedFailures is the number of requests that failed because of chaos, not the backend.
+func (cs ChaosStats) InjectedFailures() int64 {
+	return cs.InjectedAborts + cs.InjectedDrops
+}
+
+var errInjectedAbort = errors.New("chaos: request aborted before send")
+
+// applyChaos decides which faults to inject into the request. It returns the
+// (possibly rewritten) request and a cancel func that must always be called.
+func (s *EnhancedSimulator) applyChaos(req *http.Request) (*http.Request, context.CancelFunc, error) {
+	chaos := s.config.Chaos
+	if !chaos.Enabled {
+		return req, func() {}, nil
+	}
+
+	if s.rng.Float64() < chaos.AbortRate {
+		s.stats.mu.Lock()
+		s.stats.Chaos.InjectedAborts++
+		s.stats.mu.Unlock()
+		return nil, func() {}, errInjectedAbort
+	}
+
+	if chaos.MaxLatency > 0 && s.rng.Float64() < chaos.LatencyRate {
+		delay := time.Duration(s.rng.Int63n(int64(chaos.MaxLatency)))
+		s.stats.mu.Lock()
+		s.stats.Chaos.InjectedLatency++
+		s.stats.mu.Unlock()
+		time.Sleep(delay)
+	}
+
+	if s.rng.Float64() < chaos.DropRate {
+		// Cut the connection somewhere within the first 100ms of the request
+		dropAfter := time.Duration(s.rng.Int63n(int64(100 * time.Millisecond)))
+		ctx, cancel := context.WithTimeout(req.Context(), dropAfter)
+		s.stats.mu.Lock()
+		s.stats.Chaos.InjectedDrops++
+		s.stats.mu.Unlock()
+		return req.WithContext(ctx), cancel, nil
+	}
+
+	return req, func() {}, nil
+}
+
+// isConnectionRefused reports whether the engine is unreachable (e.g. restarting).
+func isConnectionRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// engineHealthy probes an engine's health endpoint, bypassing fault injection.
+func (s *EnhancedSimulator) engineHealthy(target string) bool {
+	resp, err := s.client.Get(target + "/health")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// awaitEngineRecovery blocks until the target engine answers health checks again or
+// the restart tolerance expires. Concurrent callers queue behind the first one
+// and return immediately once the engine is back.
+func (s *EnhancedSimulator) awaitEngineRecovery(target string) {
+	if !s.config.Chaos.Enabled || s.config.Chaos.RestartTolerance <= 0 {
+		return
+	}
+
+	s.outageMu.Lock()
+	defer s.outageMu.Unlock()
+
+	if s.engineHealthy(target) {
+		return
+	}
+
+	start := time.Now()
+	s.stats.mu.Lock()
+	s.stats.Chaos.EngineOutages++
+	s.stats.mu.Unlock()
+	log.Printf("Chaos: engine %s unreachable, waiting up to %v for recovery", target, s.config.Chaos.RestartTolerance)
+
+	deadline := start.Add(s.config.Chaos.RestartTolerance)
+	for time.Now().Before(deadline) {
+		time.Sleep(500 * time.Millisecond)
+		if s.engineHealthy(target) {
+			recovery := time.Since(start)
+			s.stats.mu.Lock()
+			s.stats.Chaos.RecoveryTimes = append(s.stats.Chaos.RecoveryTimes, recovery)
+			s.stats.mu.Unlock()
+			log.Printf("Chaos: engine recovered after %v", recovery)
+			return
+		}
+	}
+
+	s.stats.mu.Lock()
+	s.stats.Chaos.FailedRecoveries++
+	s.stats.mu.Unlock()
+	log.Printf("Chaos: engine did not recover within %v", s.config.Chaos.RestartTolerance)
+}
+
+// FormatChaosReport summarizes injected faults against backend-caused failures.
+func FormatChaosReport(m SimulationMetrics) string {
+	injected := m.Chaos.InjectedFailures()
+	backendErrors := int64(m.ErrorCount) - injected
+	if backendErrors < 0 {
+		backendErrors = 0
+	}
+
+	var avgRecovery time.Duration
+	if len(m.Chaos.RecoveryTimes) > 0 {
+		var total time.Duration
+		for _, d := range m.Chaos.RecoveryTimes {
+			total += d
+		}
+		avgRecovery = total / time.Duration(len(m.Chaos.RecoveryTimes))
+	}
+
+	return fmt.Sprintf(
+		"aborts=%d latency=%d drops=%d backendErrors=%d outages=%d recovered=%d failedRecoveries=%d avgRecovery=%v",
+		m.Chaos.InjectedAborts, m.Chaos.InjectedLatency, m.Chaos.InjectedDrops, backendErrors,
+		m.Chaos.EngineOutages, len(m.Chaos.RecoveryTimes), m.Chaos.FailedRecoveries, avgRecovery,
+	)
+}
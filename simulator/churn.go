@@ -0,0 +1,143 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const churnInterval = 10 * time.Second
+
+// simulateChurn continuously retires existing users and registers new ones
+// at ChurnRate (fraction of the population replaced per minute), so the
+// engine sees a changing population instead of a fixed one.
+func (s *EnhancedSimulator) simulateChurn(ctx context.Context) {
+	log.Printf("Starting user churn simulation (%.1f%% of users per minute)...", s.config.ChurnRate*100)
+	ticker := time.NewTicker(churnInterval)
+	defer ticker.Stop()
+
+	// Fractional churn carries over between ticks so low rates still churn eventually
+	var pending float64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.RLock()
+			population := len(s.users)
+			s.mu.RUnlock()
+
+			pending += s.config.ChurnRate * float64(population) * churnInterval.Minutes()
+			n := int(math.Floor(pending))
+			pending -= float64(n)
+
+			for i := 0; i < n && ctx.Err() == nil; i++ {
+				s.retireRandomUser(ctx)
+				if err := s.addChurnUser(ctx); err != nil {
+					log.Printf("Churn: failed to add user: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// retireRandomUser removes a user from the simulation, leaving their
+// subreddits and marking them disconnected in the engine.
+func (s *EnhancedSimulator) retireRandomUser(ctx context.Context) {
+	s.mu.Lock()
+	if len(s.users) <= 1 {
+		s.mu.Unlock()
+		return
+	}
+	idx := s.rng.Intn(len(s.users))
+	user := s.users[idx]
+	s.users = append(s.users[:idx], s.users[idx+1:]...)
+	wasConnected := user.IsConnected
+	user.IsConnected = false
+	subscriptions := append([]uuid.UUID(nil), user.Subscriptions...)
+	s.mu.Unlock()
+
+	for _, subredditID := range subscriptions {
+		data := map[string]interface{}{
+			"userId":      user.ID.String(),
+			"subredditId": subredditID.String(),
+		}
+		s.makeRequest("DELETE", "/subreddit/members", data) // Ignore error as this is just simulation
+	}
+
+	data := map[string]interface{}{
+		"userId": user.ID.String(),
+		"status": false,
+	}
+	s.makeRequest("PUT", "/user/profile", data) // Ignore error as this is just simulation
+
+	s.stats.mu.Lock()
+	s.stats.UsersRetired++
+	if wasConnected {
+		s.stats.ActiveUsers--
+	}
+	s.stats.mu.Unlock()
+	log.Printf("Churn: retired user %s", user.Username)
+}
+
+// addChurnUser registers a brand new user mid-run and subscribes them to a
+// few subreddits chosen by popularity.
+func (s *EnhancedSimulator) addChurnUser(ctx context.Context) error {
+	// Continue numbering after the initial population, keeping names unique across workers
+	count := s.config.WorkerCount
+	if count <= 1 {
+		count = 1
+	}
+	s.mu.Lock()
+	userNum := s.config.NumUsers + s.churnCount*count + s.config.WorkerIndex
+	s.churnCount++
+	s.mu.Unlock()
+
+	user := &SimulatedUser{
+		Username:      fmt.Sprintf("user_%d", userNum),
+		Email:         fmt.Sprintf("user_%d@test.com", userNum),
+		IsConnected:   true,
+		VotedPosts:    make(map[uuid.UUID]models.VoteDirection),
+		Posts:         make([]uuid.UUID, 0),
+		Comments:      make([]uuid.UUID, 0),
+		Subscriptions: make([]uuid.UUID, 0),
+	}
+	if err := s.registerUser(ctx, user); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	subreddits := append([]uuid.UUID(nil), s.subreddits...)
+	s.mu.RUnlock()
+
+	if len(subreddits) > 0 {
+		numJoins := min(s.getZipfNumber(len(subreddits)), len(subreddits))
+		s.rng.Shuffle(len(subreddits), func(i, j int) {
+			subreddits[i], subreddits[j] = subreddits[j], subreddits[i]
+		})
+		for _, subredditID := range subreddits[:numJoins] {
+			if err := s.joinSubreddit(ctx, user.ID, subredditID); err != nil {
+				log.Printf("Churn: failed to join subreddit: %v", err)
+				continue
+			}
+			user.Subscriptions = append(user.Subscriptions, subredditID)
+		}
+	}
+
+	s.mu.Lock()
+	s.users = append(s.users, user)
+	s.mu.Unlock()
+
+	s.stats.mu.Lock()
+	s.stats.UsersJoined++
+	s.stats.ActiveUsers++
+	s.stats.mu.Unlock()
+	log.Printf("Churn: added user %s with %d subscriptions", user.Username, len(user.Subscriptions))
+	return nil
+}
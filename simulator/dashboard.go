@@ -0,0 +1,145 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// percentile returns the p-th percentile (0-100) of an ascending slice.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted)-1) * p / 100)
+	return sorted[idx]
+}
+
+func sortedCopy(latencies []time.Duration) []time.Duration {
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// dashboardFrame is the data rendered on each dashboard refresh.
+type dashboardFrame struct {
+	elapsed       time.Duration
+	requestRate   float64 // Requests/sec over the last refresh interval
+	errorRate     float64 // Percentage of failures over the last refresh interval
+	totalRequests int64
+	totalFailures int64
+	p50, p90, p99 time.Duration
+	activeUsers   int
+	totalUsers    int
+	posts         int
+	comments      int
+	votes         int
+	endpoints     []endpointRow
+}
+
+type endpointRow struct {
+	key      string
+	requests int64
+	failures int64
+	p50, p99 time.Duration
+}
+
+// runDashboard redraws a live view of the simulation to out every second.
+func (s *EnhancedSimulator) runDashboard(ctx context.Context, out io.Writer) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var lastRequests, lastFailures int64
+	lastTick := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			frame := s.dashboardSnapshot()
+
+			interval := now.Sub(lastTick).Seconds()
+			deltaRequests := frame.totalRequests - lastRequests
+			deltaFailures := frame.totalFailures - lastFailures
+			if interval > 0 {
+				frame.requestRate = float64(deltaRequests) / interval
+			}
+			if deltaRequests > 0 {
+				frame.errorRate = float64(deltaFailures) / float64(deltaRequests) * 100
+			}
+			lastRequests, lastFailures, lastTick = frame.totalRequests, frame.totalFailures, now
+
+			renderDashboard(out, frame)
+		}
+	}
+}
+
+func (s *EnhancedSimulator) dashboardSnapshot() dashboardFrame {
+	activeUsers, totalUsers := 0, 0
+	s.mu.RLock()
+	for _, user := range s.users {
+		totalUsers++
+		if user.IsConnected {
+			activeUsers++
+		}
+	}
+	s.mu.RUnlock()
+
+	s.stats.mu.RLock()
+	defer s.stats.mu.RUnlock()
+
+	latencies := sortedCopy(s.stats.RequestLatencies)
+	frame := dashboardFrame{
+		elapsed:       time.Since(s.stats.StartTime),
+		totalRequests: s.stats.TotalRequests,
+		totalFailures: s.stats.FailedRequests,
+		p50:           percentile(latencies, 50),
+		p90:           percentile(latencies, 90),
+		p99:           percentile(latencies, 99),
+		activeUsers:   activeUsers,
+		totalUsers:    totalUsers,
+		posts:         s.stats.TotalPosts,
+		comments:      s.stats.TotalComments,
+		votes:         s.stats.TotalVotes,
+	}
+
+	for key, stats := range s.stats.Endpoints {
+		sorted := sortedCopy(stats.Latencies)
+		frame.endpoints = append(frame.endpoints, endpointRow{
+			key:      key,
+			requests: stats.Requests,
+			failures: stats.Failures,
+			p50:      percentile(sorted, 50),
+			p99:      percentile(sorted, 99),
+		})
+	}
+	sort.Slice(frame.endpoints, func(i, j int) bool {
+		return frame.endpoints[i].requests > frame.endpoints[j].requests
+	})
+
+	return frame
+}
+
+func renderDashboard(out io.Writer, f dashboardFrame) {
+	var b strings.Builder
+
+	// Move the cursor home and clear the screen before redrawing
+	b.WriteString("\033[H\033[2J")
+	fmt.Fprintf(&b, "Gator Swamp Simulator  (%s elapsed)\n\n", f.elapsed.Truncate(time.Second))
+	fmt.Fprintf(&b, "Requests:  %8.1f req/s   %d total\n", f.requestRate, f.totalRequests)
+	fmt.Fprintf(&b, "Errors:    %7.1f%%        %d total\n", f.errorRate, f.totalFailures)
+	fmt.Fprintf(&b, "Latency:   p50 %v   p90 %v   p99 %v\n", f.p50, f.p90, f.p99)
+	fmt.Fprintf(&b, "Users:     %d/%d active\n", f.activeUsers, f.totalUsers)
+	fmt.Fprintf(&b, "Content:   %d posts   %d comments   %d votes\n\n", f.posts, f.comments, f.votes)
+
+	fmt.Fprintf(&b, "%-32s %10s %10s %12s %12s\n", "ENDPOINT", "REQUESTS", "FAILURES", "P50", "P99")
+	for _, row := range f.endpoints {
+		fmt.Fprintf(&b, "%-32s %10d %10d %12v %12v\n", row.key, row.requests, row.failures, row.p50, row.p99)
+	}
+
+	io.WriteString(out, b.String())
+}
@@ -0,0 +1,217 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WorkerReport is the stats snapshot a worker streams to the coordinator.
+type WorkerReport struct {
+	WorkerID  string            `json:"workerId"`
+	Metrics   SimulationMetrics `json:"metrics"`
+	Timestamp time.Time         `json:"timestamp"`
+	Final     bool              `json:"final"`
+}
+
+// workerShare returns the global indices (out of total) this process is
+// responsible for. Without distributed mode every index belongs to us.
+func (s *EnhancedSimulator) workerShare(total int) []int {
+	count := s.config.WorkerCount
+	if count <= 1 {
+		count = 1
+	}
+
+	share := make([]int, 0, total/count+1)
+	for i := s.config.WorkerIndex; i < total; i += count {
+		share = append(share, i)
+	}
+	return share
+}
+
+func (s *EnhancedSimulator) workerID() string {
+	return fmt.Sprintf("worker-%d", s.config.WorkerIndex)
+}
+
+// streamStats periodically pushes this worker's metrics to the coordinator,
+// sending a final report when the simulation ends.
+func (s *EnhancedSimulator) streamStats(ctx context.Context) {
+	log.Printf("Streaming stats to coordinator at %s as %s", s.config.CoordinatorURL, s.workerID())
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.sendReport(true); err != nil {
+				log.Printf("Failed to send final report to coordinator: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := s.sendReport(false); err != nil {
+				log.Printf("Failed to send report to coordinator: %v", err)
+			}
+		}
+	}
+}
+
+func (s *EnhancedSimulator) sendReport(final bool) error {
+	report := WorkerReport{
+		WorkerID:  s.workerID(),
+		Metrics:   s.GetMetrics(),
+		Timestamp: time.Now(),
+		Final:     final,
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Post(s.config.CoordinatorURL+"/report", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("coordinator rejected report with status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Coordinator collects reports from simulator workers and merges them.
+type Coordinator struct {
+	expected int
+	reports  map[string]WorkerReport
+	mu       sync.RWMutex
+}
+
+// NewCoordinator creates a coordinator expecting the given number of workers.
+func NewCoordinator(expectedWorkers int) *Coordinator {
+	return &Coordinator{
+		expected: expectedWorkers,
+		reports:  make(map[string]WorkerReport),
+	}
+}
+
+// Handler returns the HTTP handler workers report to.
+func (c *Coordinator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/report", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var report WorkerReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil || report.WorkerID == "" {
+			http.Error(w, "Invalid report", http.StatusBadRequest)
+			return
+		}
+
+		c.mu.Lock()
+		c.reports[report.WorkerID] = report
+		c.mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// Merged combines the latest report from every worker into one set of metrics.
+func (c *Coordinator) Merged() SimulationMetrics {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var merged SimulationMetrics
+	var weightedLatency time.Duration
+	for _, report := range c.reports {
+		m := report.Metrics
+		merged.TotalUsers += m.TotalUsers
+		merged.ActiveUsers += m.ActiveUsers
+		merged.TotalPosts += m.TotalPosts
+		merged.TotalComments += m.TotalComments
+		merged.TotalVotes += m.TotalVotes
+		merged.RepostCount += m.RepostCount
+		merged.ErrorCount += m.ErrorCount
+		merged.TotalRequests += m.TotalRequests
+		merged.RequestsPerSecond += m.RequestsPerSecond
+		weightedLatency += m.AverageLatency * time.Duration(m.TotalRequests)
+
+		merged.Chaos.InjectedAborts += m.Chaos.InjectedAborts
+		merged.Chaos.InjectedLatency += m.Chaos.InjectedLatency
+		merged.Chaos.InjectedDrops += m.Chaos.InjectedDrops
+		merged.Chaos.EngineOutages += m.Chaos.EngineOutages
+		merged.Chaos.FailedRecoveries += m.Chaos.FailedRecoveries
+		merged.Chaos.RecoveryTimes = append(merged.Chaos.RecoveryTimes, m.Chaos.RecoveryTimes...)
+	}
+	if merged.TotalRequests > 0 {
+		merged.AverageLatency = weightedLatency / time.Duration(merged.TotalRequests)
+	}
+	return merged
+}
+
+// Workers returns the IDs of workers that have reported, and how many sent a final report.
+func (c *Coordinator) Workers() (ids []string, finished int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for id, report := range c.reports {
+		ids = append(ids, id)
+		if report.Final {
+			finished++
+		}
+	}
+	sort.Strings(ids)
+	return ids, finished
+}
+
+// Done reports whether every expected worker has sent its final report.
+func (c *Coordinator) Done() bool {
+	_, finished := c.Workers()
+	return c.expected > 0 && finished >= c.expected
+}
+
+// Run serves the report endpoint on addr and logs merged metrics until the
+// context is cancelled or all expected workers have finished.
+func (c *Coordinator) Run(ctx context.Context, addr string) error {
+	server := &http.Server{Addr: addr, Handler: c.Handler()}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+	log.Printf("Coordinator listening on %s, expecting %d workers", addr, c.expected)
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-errCh:
+			return err
+		case <-ctx.Done():
+			return server.Shutdown(context.Background())
+		case <-ticker.C:
+			ids, finished := c.Workers()
+			m := c.Merged()
+			log.Printf("\nMerged Metrics (%d workers reporting, %d finished): %v", len(ids), finished, ids)
+			log.Printf("- Request Rate: %.2f req/sec", m.RequestsPerSecond)
+			log.Printf("- Average Latency: %v", m.AverageLatency)
+			log.Printf("- Active Users: %d/%d", m.ActiveUsers, m.TotalUsers)
+			log.Printf("- Total Posts: %d, Comments: %d, Votes: %d", m.TotalPosts, m.TotalComments, m.TotalVotes)
+			log.Printf("- Failed Requests: %d/%d", m.ErrorCount, m.TotalRequests)
+
+			if c.Done() {
+				log.Printf("All %d workers finished", c.expected)
+				return server.Shutdown(context.Background())
+			}
+		}
+	}
+}
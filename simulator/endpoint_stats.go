@@ -0,0 +1,120 @@
+package simulator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	maxErrorSamples   = 3   // Distinct error bodies kept per endpoint
+	maxErrorSampleLen = 512 // Bytes of each error body kept
+)
+
+// EndpointStats tracks request outcomes for a single endpoint.
+type EndpointStats struct {
+	Requests     int64
+	Failures     int64
+	StatusCodes  map[int]int64 // Status 0 means no response (network error, timeout)
+	ErrorSamples []string      // First few distinct error bodies, for diagnosis
+	Latencies    []time.Duration
+}
+
+func newEndpointStats() *EndpointStats {
+	return &EndpointStats{StatusCodes: make(map[int]int64)}
+}
+
+// record adds one request outcome. A request failed if it got no response or
+// a 4xx/5xx status; failure carries the error text or response body.
+func (e *EndpointStats) record(latency time.Duration, status int, failure string) {
+	e.Requests++
+	e.StatusCodes[status]++
+	e.Latencies = append(e.Latencies, latency)
+
+	if status != 0 && status < 400 {
+		return
+	}
+	e.Failures++
+
+	failure = strings.TrimSpace(failure)
+	if failure == "" || len(e.ErrorSamples) >= maxErrorSamples {
+		return
+	}
+	for _, sample := range e.ErrorSamples {
+		if sample == failure {
+			return
+		}
+	}
+	e.ErrorSamples = append(e.ErrorSamples, failure)
+}
+
+// endpointKey normalizes a request into "METHOD /path", dropping the query string
+func endpointKey(method, endpoint string) string {
+	if i := strings.IndexByte(endpoint, '?'); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	return method + " " + endpoint
+}
+
+// EndpointBreakdown returns a copy of the per-endpoint stats.
+func (s *EnhancedSimulator) EndpointBreakdown() map[string]EndpointStats {
+	s.stats.mu.RLock()
+	defer s.stats.mu.RUnlock()
+	return copyStatsMap(s.stats.Endpoints)
+}
+
+func copyStatsMap(src map[string]*EndpointStats) map[string]EndpointStats {
+	breakdown := make(map[string]EndpointStats, len(src))
+	for key, stats := range src {
+		copied := *stats
+		copied.StatusCodes = make(map[int]int64, len(stats.StatusCodes))
+		for code, n := range stats.StatusCodes {
+			copied.StatusCodes[code] = n
+		}
+		copied.ErrorSamples = append([]string(nil), stats.ErrorSamples...)
+		copied.Latencies = nil // Not needed for reporting
+		breakdown[key] = copied
+	}
+	return breakdown
+}
+
+// FormatEndpointBreakdown renders per-endpoint outcomes, busiest endpoint first.
+func FormatEndpointBreakdown(breakdown map[string]EndpointStats) string {
+	keys := make([]string, 0, len(breakdown))
+	for key := range breakdown {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return breakdown[keys[i]].Requests > breakdown[keys[j]].Requests
+	})
+
+	var b strings.Builder
+	for _, key := range keys {
+		stats := breakdown[key]
+		fmt.Fprintf(&b, "- %s: %d requests, %d failed [%s]\n",
+			key, stats.Requests, stats.Failures, formatStatusCodes(stats.StatusCodes))
+		for _, sample := range stats.ErrorSamples {
+			fmt.Fprintf(&b, "    e.g. %q\n", sample)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func formatStatusCodes(codes map[int]int64) string {
+	statuses := make([]int, 0, len(codes))
+	for code := range codes {
+		statuses = append(statuses, code)
+	}
+	sort.Ints(statuses)
+
+	parts := make([]string, 0, len(statuses))
+	for _, code := range statuses {
+		label := fmt.Sprintf("%d", code)
+		if code == 0 {
+			label = "no response"
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d", label, codes[code]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,70 @@
+package simulator
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPConfig controls the client the simulator talks to the engine with.
+type HTTPConfig struct {
+	Timeout      time.Duration // Per attempt, including reading the body
+	MaxRetries   int           // Attempts after the first; 0 disables retries
+	RetryBackoff time.Duration // Wait before the first retry, doubled for each one after
+	MaxIdleConns int           // Idle connections kept open per engine instance
+}
+
+// DefaultHTTPConfig returns the client settings used when none are given.
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		Timeout:      10 * time.Second,
+		MaxRetries:   2,
+		RetryBackoff: 500 * time.Millisecond,
+		MaxIdleConns: 100,
+	}
+}
+
+// newHTTPClient builds the one client shared by all simulated users. The
+// default transport keeps only two idle connections per host, so with
+// hundreds of users most requests would open a new one.
+func newHTTPClient(cfg HTTPConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 0 // No overall cap; MaxIdleConnsPerHost bounds each instance
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConns
+	return &http.Client{Timeout: cfg.Timeout, Transport: transport}
+}
+
+// retryDelay is how long to wait before retry number attempt (1 for the
+// first retry).
+func (cfg HTTPConfig) retryDelay(attempt int) time.Duration {
+	return cfg.RetryBackoff << (attempt - 1)
+}
+
+// shouldRetry reports whether a failed attempt is worth repeating: err is
+// the transport error, or nil if a response with status came back. Reads are
+// retried after any network error or gateway failure. Writes are only
+// retried when the engine can't have acted on them (the connection was
+// refused, or the engine said it's unavailable), so retries don't duplicate
+// posts or votes. Rate limited requests aren't retried; rateLimitPauses holds
+// them back instead.
+func shouldRetry(method string, err error, status int) bool {
+	read := method == http.MethodGet || method == http.MethodHead
+	if err != nil {
+		if errors.Is(err, errInjectedAbort) {
+			return false
+		}
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return true
+		}
+		return read
+	}
+	switch status {
+	case http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return read
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gator-swamp/internal/models"
+)
+
+// ReadMix sets the relative weights of simulated read behaviors.
+type ReadMix struct {
+	Feed             float64 // GET /user/feed
+	Recent           float64 // GET /posts/recent
+	PostWithComments float64 // GET /post?id= followed by GET /comment/post
+}
+
+// DefaultReadMix favors feed browsing, as real users mostly land on their feed.
+func DefaultReadMix() ReadMix {
+	return ReadMix{Feed: 0.5, Recent: 0.2, PostWithComments: 0.3}
+}
+
+func (m ReadMix) total() float64 {
+	return m.Feed + m.Recent + m.PostWithComments
+}
+
+func (s *EnhancedSimulator) simulateReads(ctx context.Context) {
+	log.Printf("Starting read simulation...")
+
+	tickInterval := 500 * time.Millisecond
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	const numWorkers = 5
+	readJobs := make(chan *SimulatedUser, s.config.NumUsers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			for user := range readJobs {
+				if !user.IsConnected {
+					continue
+				}
+
+				if s.rng.Float64() < (s.config.ReadFrequency/3600.0)/2.0 {
+					start := time.Now()
+					err := s.performRead(user)
+					if err != nil {
+						log.Printf("Debug: Worker %d read failed: %v", workerID, err)
+					} else {
+						s.stats.mu.Lock()
+						s.stats.TotalReads++
+						s.stats.mu.Unlock()
+					}
+					s.recordRequestMetrics(start, err)
+				}
+			}
+		}(i)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			close(readJobs)
+			wg.Wait()
+			return
+		case <-ticker.C:
+			s.mu.RLock()
+			for _, user := range s.users {
+				if user.IsConnected {
+					select {
+					case readJobs <- user:
+					default: // Don't block if channel is full
+					}
+				}
+			}
+			s.mu.RUnlock()
+		}
+	}
+}
+
+// performRead picks one read behavior according to the configured mix.
+func (s *EnhancedSimulator) performRead(user *SimulatedUser) error {
+	mix := s.config.ReadMix
+	if mix.total() <= 0 {
+		mix = DefaultReadMix()
+	}
+
+	pick := s.rng.Float64() * mix.total()
+	switch {
+	case pick < mix.Feed:
+		_, err := s.makeUserRequest(user, "GET", "/user/feed?limit=20", nil)
+		return err
+	case pick < mix.Feed+mix.Recent:
+		_, err := s.makeUserRequest(user, "GET", "/posts/recent?limit=20", nil)
+		return err
+	default:
+		return s.openPostWithComments(user)
+	}
+}
+
+// openPostWithComments mimics a user clicking into a post from the recent list.
+func (s *EnhancedSimulator) openPostWithComments(user *SimulatedUser) error {
+	resp, err := s.makeUserRequest(user, "GET", "/posts/recent?limit=20", nil)
+	if err != nil {
+		return err
+	}
+
+	var page listPage[models.Post]
+	if err := json.Unmarshal(resp, &page); err != nil {
+		return fmt.Errorf("failed to parse recent posts: %v", err)
+	}
+	posts := page.Items
+	if len(posts) == 0 {
+		return nil
+	}
+
+	post := posts[s.rng.Intn(len(posts))]
+	if _, err := s.makeUserRequest(user, "GET", fmt.Sprintf("/post?id=%s", post.ID), nil); err != nil {
+		return err
+	}
+	_, err = s.makeUserRequest(user, "GET", fmt.Sprintf("/comment/post?postId=%s", post.ID), nil)
+	return err
+}
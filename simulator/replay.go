@@ -0,0 +1,170 @@
+package simulator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// RecordedRequest is one line of a traffic recording.
+type RecordedRequest struct {
+	Offset   time.Duration   `json:"offset"` // Time since recording started
+	Method   string          `json:"method"`
+	Endpoint string          `json:"endpoint"`
+	Body     json.RawMessage `json:"body,omitempty"`
+}
+
+// requestRecorder appends issued requests to a JSON-lines file.
+type requestRecorder struct {
+	start time.Time
+	file  *os.File
+	enc   *json.Encoder
+	mu    sync.Mutex
+}
+
+func newRequestRecorder(path string) (*requestRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &requestRecorder{start: time.Now(), file: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (r *requestRecorder) record(method, endpoint string, body []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := RecordedRequest{
+		Offset:   time.Since(r.start),
+		Method:   method,
+		Endpoint: endpoint,
+	}
+	if len(body) > 0 {
+		entry.Body = body
+	}
+	if err := r.enc.Encode(entry); err != nil {
+		log.Printf("Failed to record request %s %s: %v", method, endpoint, err)
+	}
+}
+
+func (r *requestRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// ReplayResult summarizes a replay run.
+type ReplayResult struct {
+	Requests       int
+	Failures       int
+	Duration       time.Duration
+	AverageLatency time.Duration
+	Endpoints      map[string]*EndpointStats
+}
+
+// Replay re-issues a recorded traffic file against engineURL. Requests keep
+// their recorded spacing divided by speed (2.0 replays twice as fast); a speed
+// of 0 or less sends them back to back. httpCfg sets the client's timeout and
+// pool; recorded requests are sent once each, without retries.
+func Replay(ctx context.Context, path, engineURL string, speed float64, httpCfg HTTPConfig) (*ReplayResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %v", err)
+	}
+	defer f.Close()
+
+	client := newHTTPClient(httpCfg)
+	result := &ReplayResult{Endpoints: make(map[string]*EndpointStats)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var totalLatency time.Duration
+
+	start := time.Now()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var entry RecordedRequest
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid recording line: %v", err)
+		}
+
+		if speed > 0 {
+			due := start.Add(time.Duration(float64(entry.Offset) / speed))
+			select {
+			case <-ctx.Done():
+				wg.Wait()
+				return result, ctx.Err()
+			case <-time.After(time.Until(due)):
+			}
+		} else if ctx.Err() != nil {
+			wg.Wait()
+			return result, ctx.Err()
+		}
+
+		// Issue concurrently so slow responses don't skew the recorded schedule
+		wg.Add(1)
+		go func(entry RecordedRequest) {
+			defer wg.Done()
+			reqStart := time.Now()
+			status, failure := replayRequest(ctx, client, engineURL, entry)
+			latency := time.Since(reqStart)
+
+			mu.Lock()
+			defer mu.Unlock()
+			result.Requests++
+			totalLatency += latency
+			if status == 0 || status >= 400 {
+				result.Failures++
+			}
+			key := endpointKey(entry.Method, entry.Endpoint)
+			stats, ok := result.Endpoints[key]
+			if !ok {
+				stats = newEndpointStats()
+				result.Endpoints[key] = stats
+			}
+			stats.record(latency, status, failure)
+		}(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %v", err)
+	}
+
+	wg.Wait()
+	result.Duration = time.Since(start)
+	if result.Requests > 0 {
+		result.AverageLatency = totalLatency / time.Duration(result.Requests)
+	}
+	return result, nil
+}
+
+// replayRequest issues one recorded request, returning the response status
+// (0 if none was received) and the error text or body on failure.
+func replayRequest(ctx context.Context, client *http.Client, engineURL string, entry RecordedRequest) (int, string) {
+	req, err := http.NewRequestWithContext(ctx, entry.Method, engineURL+entry.Endpoint, bytes.NewReader(entry.Body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorSampleLen))
+		return resp.StatusCode, string(body)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, ""
+}
@@ -5,14 +5,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
-	"math"
 	"math/rand"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 
+	"gator-swamp/internal/models"
+
 	"github.com/google/uuid"
 )
 
@@ -26,9 +28,25 @@ type SimConfig struct {
 	RepostPercentage float64
 	DisconnectRate   float64
 	ReconnectRate    float64
+	VoteChangeRate   float64 // Probability a user revisiting a voted post flips or removes the vote
+	ReadFrequency    float64 // Read actions per user per hour
+	ReadMix          ReadMix // Relative weights of the read behaviors
+	ChurnRate        float64 // Fraction of users retired and replaced per minute; 0 disables churn
 	ZipfS            float64
 	BatchSize        int
 	EngineURL        string
+	EngineURLs       []string // Optional set of engine instances; requests are spread round-robin
+	Seed             int64    // Seed for all simulator randomness; 0 picks a time-based seed
+	Chaos            ChaosConfig
+	Dashboard        bool       // Render a live terminal dashboard instead of periodic log dumps
+	RecordFile       string     // If set, every issued request is recorded here for later replay
+	HTTP             HTTPConfig // Zero value uses DefaultHTTPConfig
+
+	// Distributed mode: this process simulates every WorkerCount-th user and
+	// subreddit starting at WorkerIndex, and streams stats to CoordinatorURL.
+	WorkerIndex    int
+	WorkerCount    int
+	CoordinatorURL string
 }
 
 type SimulationStats struct {
@@ -43,7 +61,15 @@ type SimulationStats struct {
 	TotalComments    int
 	TotalVotes       int
 	RepostCount      int
+	TotalReads       int
+	VotesChanged     int // Votes flipped between up and down
+	VotesRemoved     int // Votes withdrawn
+	UsersJoined      int // Users registered mid-run by churn
+	UsersRetired     int // Users removed mid-run by churn
 	RequestLatencies []time.Duration
+	Endpoints        map[string]*EndpointStats // Keyed by "METHOD /path"
+	Targets          map[string]*EndpointStats // Keyed by engine base URL
+	Chaos            ChaosStats
 }
 
 // Track simulated users with their actor state
@@ -53,10 +79,11 @@ type SimulatedUser struct {
 	Email         string
 	IsConnected   bool
 	LastActive    time.Time
-	Posts         []uuid.UUID        // Track posts created by this user
-	Comments      []uuid.UUID        // Track comments made by this user
-	VotedPosts    map[uuid.UUID]bool // Track which posts user has voted on
-	Subscriptions []uuid.UUID        // Track subreddit subscriptions
+	Posts         []uuid.UUID                        // Track posts created by this user
+	Comments      []uuid.UUID                        // Track comments made by this user
+	VotedPosts    map[uuid.UUID]models.VoteDirection // Track the user's current vote on each post
+	Subscriptions []uuid.UUID                        // Track subreddit subscriptions
+	Token         string                             // JWT from login, used for authenticated reads
 }
 
 type EnhancedSimulator struct {
@@ -65,25 +92,52 @@ type EnhancedSimulator struct {
 	users      []*SimulatedUser
 	subreddits []uuid.UUID
 	client     *http.Client
+	rng        *rand.Rand // Seeded source shared by all goroutines
 	mu         sync.RWMutex
+	outageMu   sync.Mutex // Serializes engine recovery waits in chaos mode
+	recorder   *requestRecorder
+	nextTarget uint64 // Round-robin cursor into config.EngineURLs
+	churnCount int    // Users added by churn so far, guarded by mu
+	pauses     rateLimitPauses
 }
 
 func NewEnhancedSimulator(config SimConfig) *EnhancedSimulator {
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	log.Printf("Simulator using seed %d", config.Seed)
+	if len(config.EngineURLs) == 0 {
+		config.EngineURLs = []string{config.EngineURL}
+	}
+	config.EngineURL = config.EngineURLs[0]
+	if config.HTTP == (HTTPConfig{}) {
+		config.HTTP = DefaultHTTPConfig()
+	}
+
 	return &EnhancedSimulator{
 		config: config,
+		rng:    newSeededRand(config.Seed),
 		stats: &SimulationStats{
 			StartTime:        time.Now(),
 			RequestLatencies: make([]time.Duration, 0),
 		},
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client: newHTTPClient(config.HTTP),
 	}
 }
 
 func (s *EnhancedSimulator) Run(ctx context.Context) error {
 	log.Printf("Starting enhanced simulation...")
 
+	if s.config.RecordFile != "" {
+		recorder, err := newRequestRecorder(s.config.RecordFile)
+		if err != nil {
+			return fmt.Errorf("failed to open recording file: %v", err)
+		}
+		defer recorder.Close()
+		s.recorder = recorder
+		log.Printf("Recording requests to %s", s.config.RecordFile)
+	}
+
 	// Initialize users and subreddits first
 	if err := s.initialize(ctx); err != nil {
 		return fmt.Errorf("initialization failed: %v", err)
@@ -108,13 +162,35 @@ func (s *EnhancedSimulator) Run(ctx context.Context) error {
 		s.simulateConnectivity(ctx)
 	}()
 
+	// Replace users over time
+	if s.config.ChurnRate > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.simulateChurn(ctx)
+		}()
+	}
+
 	// Collect metrics
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		s.collectMetrics(ctx)
+		if s.config.Dashboard {
+			s.runDashboard(ctx, os.Stdout)
+		} else {
+			s.collectMetrics(ctx)
+		}
 	}()
 
+	// Stream stats to the coordinator in distributed mode
+	if s.config.CoordinatorURL != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.streamStats(ctx)
+		}()
+	}
+
 	wg.Wait()
 	return nil
 }
@@ -166,10 +242,6 @@ func (s *EnhancedSimulator) createInitialUsers(ctx context.Context) error {
 		go func(workerID int) {
 			defer wg.Done()
 
-			client := &http.Client{
-				Timeout: 5 * time.Second, // Increased timeout
-			}
-
 			for userNum := range userJobs {
 				// Wait for rate limiter
 				<-rateLimiter.C
@@ -178,36 +250,27 @@ func (s *EnhancedSimulator) createInitialUsers(ctx context.Context) error {
 					Username:      fmt.Sprintf("user_%d", userNum),
 					Email:         fmt.Sprintf("user_%d@test.com", userNum),
 					IsConnected:   true,
-					VotedPosts:    make(map[uuid.UUID]bool),
+					VotedPosts:    make(map[uuid.UUID]models.VoteDirection),
 					Posts:         make([]uuid.UUID, 0),
 					Comments:      make([]uuid.UUID, 0),
 					Subscriptions: make([]uuid.UUID, 0),
 				}
 
-				// Implement exponential backoff for retries
-				var err error
-				for retries := 0; retries < 3; retries++ {
-					if err = s.registerUserWithClient(ctx, user, client); err == nil {
-						results <- user
-						break
-					}
-					backoffDuration := time.Duration(math.Pow(2, float64(retries))) * time.Second
-					log.Printf("Worker %d: Retry %d for user %s after %v delay",
-						workerID, retries+1, user.Username, backoffDuration)
-					time.Sleep(backoffDuration)
-				}
-
-				if err != nil {
-					log.Printf("Worker %d: Failed to register user %s after retries: %v",
+				// Failed requests were already retried where that's safe
+				if err := s.registerUser(ctx, user); err != nil {
+					log.Printf("Worker %d: Failed to register user %s: %v",
 						workerID, user.Username, err)
+					continue
 				}
+				results <- user
 			}
 		}(i)
 	}
 
 	// Send jobs to workers
+	userShare := s.workerShare(s.config.NumUsers)
 	go func() {
-		for i := 0; i < s.config.NumUsers; i++ {
+		for _, i := range userShare {
 			userJobs <- i
 		}
 		close(userJobs)
@@ -231,8 +294,8 @@ func (s *EnhancedSimulator) createInitialUsers(ctx context.Context) error {
 		select {
 		case <-progressTicker.C:
 			log.Printf("Progress: %d/%d users created (%.2f%%)",
-				successCount, s.config.NumUsers,
-				float64(successCount)/float64(s.config.NumUsers)*100)
+				successCount, len(userShare),
+				float64(successCount)/float64(len(userShare))*100)
 		default:
 		}
 
@@ -244,7 +307,7 @@ func (s *EnhancedSimulator) createInitialUsers(ctx context.Context) error {
 	return nil
 }
 
-func (s *EnhancedSimulator) registerUserWithClient(ctx context.Context, user *SimulatedUser, client *http.Client) error {
+func (s *EnhancedSimulator) registerUser(ctx context.Context, user *SimulatedUser) error {
 	data := map[string]interface{}{
 		"username": user.Username,
 		"email":    user.Email,
@@ -253,7 +316,7 @@ func (s *EnhancedSimulator) registerUserWithClient(ctx context.Context, user *Si
 	}
 
 	// First verify if user already exists
-	existingResp, err := s.makeRequestWithClient(client, "GET",
+	existingResp, err := s.makeRequest("GET",
 		fmt.Sprintf("/user/profile?username=%s", user.Username), nil)
 	if err == nil {
 		// User might already exist, try to parse the response
@@ -270,7 +333,7 @@ func (s *EnhancedSimulator) registerUserWithClient(ctx context.Context, user *Si
 	}
 
 	// User doesn't exist, proceed with registration
-	resp, err := s.makeRequestWithClient(client, "POST", "/user/register", data)
+	resp, err := s.makeRequest("POST", "/user/register", data)
 	if err != nil {
 		return fmt.Errorf("failed to register user: %v", err)
 	}
@@ -295,56 +358,6 @@ func (s *EnhancedSimulator) registerUserWithClient(ctx context.Context, user *Si
 	return nil
 }
 
-// func (s *EnhancedSimulator) createInitialUsers(ctx context.Context) error {
-// 	s.users = make([]*SimulatedUser, 0, s.config.NumUsers)
-
-// 	// Create a rate limiter for registration requests
-// 	// rateLimiter := time.NewTicker(200 * time.Millisecond) // 5 requests per second
-// 	// new rateLimiter with 20 requests per second
-// 	rateLimiter := time.NewTicker(50 * time.Millisecond)
-// 	defer rateLimiter.Stop()
-
-// 	for i := 0; i < s.config.NumUsers; i++ {
-// 		user := &SimulatedUser{
-// 			Username:      fmt.Sprintf("user_%d", i),
-// 			Email:         fmt.Sprintf("user_%d@test.com", i),
-// 			IsConnected:   true,
-// 			VotedPosts:    make(map[uuid.UUID]bool),
-// 			Posts:         make([]uuid.UUID, 0),
-// 			Comments:      make([]uuid.UUID, 0),
-// 			Subscriptions: make([]uuid.UUID, 0),
-// 		}
-
-// 		// Implement retry logic
-// 		var err error
-// 		for retries := 0; retries < 3; retries++ {
-// 			<-rateLimiter.C // Wait for rate limiter
-
-// 			if err = s.registerUserWithRetry(ctx, user); err == nil {
-// 				break
-// 			}
-
-// 			log.Printf("Retry %d for user %s: %v", retries+1, user.Username, err)
-// 			time.Sleep(time.Duration(retries+1) * time.Second) // Exponential backoff
-// 		}
-
-// 		if err != nil {
-// 			log.Printf("Failed to register user %s after retries: %v", user.Username, err)
-// 			continue
-// 		}
-
-// 		s.users = append(s.users, user)
-
-// 		// Log progress every 10 users
-// 		if (i+1)%10 == 0 {
-// 			log.Printf("Created %d/%d users...", i+1, s.config.NumUsers)
-// 		}
-// 	}
-
-// 	log.Printf("Successfully created %d users", len(s.users))
-// 	return nil
-// }
-
 func (s *EnhancedSimulator) createSubredditsWithActiveUsers(ctx context.Context) error {
 	// Select top 10% of users as potential subreddit creators
 	numCreators := len(s.users) / 10
@@ -352,18 +365,18 @@ func (s *EnhancedSimulator) createSubredditsWithActiveUsers(ctx context.Context)
 	copy(creators, s.users[:numCreators])
 
 	// Shuffle the creators to randomize subreddit creation
-	rand.Shuffle(len(creators), func(i, j int) {
+	s.rng.Shuffle(len(creators), func(i, j int) {
 		creators[i], creators[j] = creators[j], creators[i]
 	})
 
 	s.subreddits = make([]uuid.UUID, 0, s.config.NumSubreddits)
 
-	for i := 0; i < s.config.NumSubreddits; i++ {
-		creator := creators[i%len(creators)] // Cycle through creators
+	for n, i := range s.workerShare(s.config.NumSubreddits) {
+		creator := creators[n%len(creators)] // Cycle through creators
 		subredditID := uuid.New()
 
 		// Create themed subreddits
-		theme := getRandomTheme()
+		theme := s.getRandomTheme()
 		name := fmt.Sprintf("%s_%d", theme, i)
 		description := fmt.Sprintf("A community for %s enthusiasts", theme)
 
@@ -385,14 +398,14 @@ func (s *EnhancedSimulator) createSubredditsWithActiveUsers(ctx context.Context)
 }
 
 // Helper function to generate random subreddit themes
-func getRandomTheme() string {
+func (s *EnhancedSimulator) getRandomTheme() string {
 	themes := []string{
 		"gaming", "tech", "science", "music", "movies",
 		"books", "sports", "food", "travel", "art",
 		"photography", "fitness", "programming", "news", "memes",
 		"history", "nature", "pets", "fashion", "diy",
 	}
-	return themes[rand.Intn(len(themes))]
+	return themes[s.rng.Intn(len(themes))]
 }
 
 func (s *EnhancedSimulator) simulateSubredditJoins(ctx context.Context) error {
@@ -400,8 +413,7 @@ func (s *EnhancedSimulator) simulateSubredditJoins(ctx context.Context) error {
 
 	// Calculate popularity distribution using Zipf's law
 	subredditPopularity := make([]int, len(s.subreddits))
-	zipf := rand.NewZipf(rand.New(rand.NewSource(time.Now().UnixNano())),
-		s.config.ZipfS, 1, uint64(len(s.users)))
+	zipf := rand.NewZipf(s.rng, s.config.ZipfS, 1, uint64(len(s.users)))
 
 	// For each user, determine number of subreddits to join
 	for _, user := range s.users {
@@ -418,7 +430,7 @@ func (s *EnhancedSimulator) simulateSubredditJoins(ctx context.Context) error {
 		// Get available subreddits
 		availableSubs := make([]uuid.UUID, len(s.subreddits))
 		copy(availableSubs, s.subreddits)
-		rand.Shuffle(len(availableSubs), func(i, j int) {
+		s.rng.Shuffle(len(availableSubs), func(i, j int) {
 			availableSubs[i], availableSubs[j] = availableSubs[j], availableSubs[i]
 		})
 
@@ -456,44 +468,13 @@ func (s *EnhancedSimulator) simulateSubredditJoins(ctx context.Context) error {
 }
 
 func (s *EnhancedSimulator) getZipfNumber(max int) int {
-	zipf := rand.NewZipf(rand.New(rand.NewSource(time.Now().UnixNano())),
-		s.config.ZipfS, 1, uint64(max))
+	zipf := rand.NewZipf(s.rng, s.config.ZipfS, 1, uint64(max))
 	return int(zipf.Uint64()) + 1
 }
 
 // Helper method to make HTTP requests
 func (s *EnhancedSimulator) makeRequest(method, endpoint string, data interface{}) ([]byte, error) {
-	var body []byte
-	var err error
-
-	if data != nil {
-		body, err = json.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	req, err := http.NewRequest(method, s.config.EngineURL+endpoint, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	start := time.Now()
-	resp, err := s.client.Do(req)
-	s.recordRequestMetrics(start, err)
-
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("request failed with status: %d", resp.StatusCode)
-	}
-
-	return ioutil.ReadAll(resp.Body)
+	return s.doRequest(method, endpoint, data, "")
 }
 
 func (s *EnhancedSimulator) simulateConnectivity(ctx context.Context) {
@@ -510,7 +491,7 @@ func (s *EnhancedSimulator) simulateConnectivity(ctx context.Context) {
 			for _, user := range s.users {
 				// Handle disconnection for connected users
 				if user.IsConnected {
-					if rand.Float64() < s.config.DisconnectRate {
+					if s.rng.Float64() < s.config.DisconnectRate {
 						user.IsConnected = false
 						s.stats.mu.Lock()
 						s.stats.ActiveUsers--
@@ -525,7 +506,7 @@ func (s *EnhancedSimulator) simulateConnectivity(ctx context.Context) {
 					}
 				} else {
 					// Handle reconnection for disconnected users
-					if rand.Float64() < s.config.ReconnectRate {
+					if s.rng.Float64() < s.config.ReconnectRate {
 						user.IsConnected = true
 						s.stats.mu.Lock()
 						s.stats.ActiveUsers++
@@ -563,60 +544,33 @@ func (s *EnhancedSimulator) recordRequestMetrics(start time.Time, err error) {
 	s.stats.AverageLatency = (totalLatency + latency) / time.Duration(s.stats.TotalRequests)
 }
 
-func (s *EnhancedSimulator) registerUserWithRetry(ctx context.Context, user *SimulatedUser) error {
-	data := map[string]interface{}{
-		"username": user.Username,
-		"email":    user.Email,
-		"password": "testpass123",
-		"karma":    300,
-	}
-
-	// Create custom client with shorter timeout
-	client := &http.Client{
-		Timeout: 800 * time.Millisecond,
-	}
-
-	// Register user
-	resp, err := s.makeRequestWithClient(client, "POST", "/user/register", data)
-	if err != nil {
-		return fmt.Errorf("failed to register user: %v", err)
-	}
-
-	var result struct {
-		ID string `json:"id"`
-	}
-	if err := json.Unmarshal(resp, &result); err != nil {
-		return fmt.Errorf("failed to parse registration response: %v", err)
-	}
-
-	// Parse user ID from response
-	registeredID, err := uuid.Parse(result.ID)
-	if err != nil {
-		return fmt.Errorf("invalid user ID returned: %v", err)
-	}
-
-	// Update the user's ID with the one returned from the server
-	user.ID = registeredID
+// recordEndpointMetrics tracks request outcomes per endpoint. A status of 0
+// means the request never got a response; failure holds the error text or body.
+func (s *EnhancedSimulator) recordEndpointMetrics(method, endpoint string, latency time.Duration, status int, failure string) {
+	key := endpointKey(method, endpoint)
 
-	// Wait a bit before trying to login
-	time.Sleep(100 * time.Millisecond)
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
 
-	// Login is optional - don't fail if it doesn't work
-	loginData := map[string]interface{}{
-		"email":    user.Email,
-		"password": "testpass123",
+	if s.stats.Endpoints == nil {
+		s.stats.Endpoints = make(map[string]*EndpointStats)
 	}
-
-	_, loginErr := s.makeRequestWithClient(client, "POST", "/user/login", loginData)
-	if loginErr != nil {
-		log.Printf("Note: Failed to login user %s: %v", user.Username, loginErr)
-		// Continue anyway as this isn't critical
+	stats, ok := s.stats.Endpoints[key]
+	if !ok {
+		stats = newEndpointStats()
+		s.stats.Endpoints[key] = stats
 	}
+	stats.record(latency, status, failure)
+}
 
-	return nil
+// makeUserRequest issues a request authenticated as the given user
+func (s *EnhancedSimulator) makeUserRequest(user *SimulatedUser, method, endpoint string, data interface{}) ([]byte, error) {
+	return s.doRequest(method, endpoint, data, user.Token)
 }
 
-func (s *EnhancedSimulator) makeRequestWithClient(client *http.Client, method, endpoint string, data interface{}) ([]byte, error) {
+// doRequest sends a request to the next engine instance, retrying it as
+// config.HTTP and shouldRetry allow. Every attempt counts in the metrics.
+func (s *EnhancedSimulator) doRequest(method, endpoint string, data interface{}, token string) ([]byte, error) {
 	var body []byte
 	var err error
 
@@ -627,27 +581,79 @@ func (s *EnhancedSimulator) makeRequestWithClient(client *http.Client, method, e
 		}
 	}
 
-	req, err := http.NewRequest(method, s.config.EngineURL+endpoint, bytes.NewBuffer(body))
+	if s.recorder != nil {
+		s.recorder.record(method, endpoint, body)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			time.Sleep(s.config.HTTP.retryDelay(attempt))
+		}
+		respBody, status, err := s.attemptRequest(method, endpoint, body, token)
+		if err == nil && status < 400 {
+			return respBody, nil
+		}
+		if attempt >= s.config.HTTP.MaxRetries || !shouldRetry(method, err, status) {
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("request failed with status: %d", status)
+		}
+	}
+}
+
+// attemptRequest sends a request once, returning the response body and
+// status, or the error if no response came back.
+func (s *EnhancedSimulator) attemptRequest(method, endpoint string, body []byte, token string) ([]byte, int, error) {
+	target := s.nextEngineURL()
+	req, err := http.NewRequest(method, target+endpoint, bytes.NewReader(body))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	// Apply fault injection (no-op unless chaos mode is enabled)
+	req, cancel, err := s.applyChaos(req)
+	if err != nil {
+		s.recordRequestMetrics(time.Now(), err)
+		return nil, 0, err
+	}
+	defer cancel()
+
+	// Stay within the engine's rate limits rather than collect 429s
+	s.pauses.wait(token, method)
 
 	start := time.Now()
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
+	latency := time.Since(start)
 	s.recordRequestMetrics(start, err)
 
 	if err != nil {
-		return nil, err
+		s.recordEndpointMetrics(method, endpoint, latency, 0, err.Error())
+		s.recordTargetMetrics(target, latency, 0, err.Error())
+		if isConnectionRefused(err) {
+			s.awaitEngineRecovery(target)
+		}
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
+	s.pauses.note(token, resp)
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("request failed with status: %d", resp.StatusCode)
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorSampleLen))
+		s.recordEndpointMetrics(method, endpoint, latency, resp.StatusCode, string(errBody))
+		s.recordTargetMetrics(target, latency, resp.StatusCode, string(errBody))
+		return nil, resp.StatusCode, nil
 	}
+	s.recordEndpointMetrics(method, endpoint, latency, resp.StatusCode, "")
+	s.recordTargetMetrics(target, latency, resp.StatusCode, "")
 
-	return ioutil.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
+	return respBody, resp.StatusCode, err
 }
 
 func (s *EnhancedSimulator) createSubreddit(ctx context.Context, id uuid.UUID, name, description string, creatorID uuid.UUID) error {
@@ -727,8 +733,14 @@ func (s *EnhancedSimulator) collectMetrics(ctx context.Context) {
 			log.Printf("- Total Comments: %d", s.stats.TotalComments)
 			log.Printf("- Total Votes: %d", s.stats.TotalVotes)
 			log.Printf("- Failed Requests: %d", s.stats.FailedRequests)
+			if s.config.Chaos.Enabled {
+				log.Printf("- Chaos: aborts=%d drops=%d latency=%d outages=%d",
+					s.stats.Chaos.InjectedAborts, s.stats.Chaos.InjectedDrops,
+					s.stats.Chaos.InjectedLatency, s.stats.Chaos.EngineOutages)
+			}
 
 			s.stats.mu.RUnlock()
+			log.Printf("- Per-endpoint:\n%s", FormatEndpointBreakdown(s.EndpointBreakdown()))
 		}
 	}
 }
@@ -741,9 +753,16 @@ type SimulationMetrics struct {
 	TotalComments     int
 	TotalVotes        int
 	RepostCount       int
+	TotalReads        int
+	VotesChanged      int
+	VotesRemoved      int
+	UsersJoined       int
+	UsersRetired      int
 	AverageLatency    time.Duration
 	ErrorCount        int
+	TotalRequests     int64
 	RequestsPerSecond float64
+	Chaos             ChaosStats
 }
 
 // GetMetrics returns the current simulation metrics
@@ -761,8 +780,15 @@ func (s *EnhancedSimulator) GetMetrics() SimulationMetrics {
 		TotalComments:     s.stats.TotalComments,
 		TotalVotes:        s.stats.TotalVotes,
 		RepostCount:       s.stats.RepostCount,
+		TotalReads:        s.stats.TotalReads,
+		VotesChanged:      s.stats.VotesChanged,
+		VotesRemoved:      s.stats.VotesRemoved,
+		UsersJoined:       s.stats.UsersJoined,
+		UsersRetired:      s.stats.UsersRetired,
 		AverageLatency:    s.stats.AverageLatency,
 		ErrorCount:        int(s.stats.FailedRequests),
+		TotalRequests:     s.stats.TotalRequests,
 		RequestsPerSecond: requestRate,
+		Chaos:             s.stats.Chaos.snapshot(),
 	}
 }
@@ -0,0 +1,40 @@
+package simulator
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// nextEngineURL picks the engine instance for the next request, round-robin.
+func (s *EnhancedSimulator) nextEngineURL() string {
+	targets := s.config.EngineURLs
+	if len(targets) == 1 {
+		return targets[0]
+	}
+	n := atomic.AddUint64(&s.nextTarget, 1) - 1
+	return targets[n%uint64(len(targets))]
+}
+
+// recordTargetMetrics tracks request outcomes per engine instance so uneven
+// load or a failing node stands out in multi-target runs.
+func (s *EnhancedSimulator) recordTargetMetrics(target string, latency time.Duration, status int, failure string) {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	if s.stats.Targets == nil {
+		s.stats.Targets = make(map[string]*EndpointStats)
+	}
+	stats, ok := s.stats.Targets[target]
+	if !ok {
+		stats = newEndpointStats()
+		s.stats.Targets[target] = stats
+	}
+	stats.record(latency, status, failure)
+}
+
+// TargetBreakdown returns a copy of the per-engine-instance stats.
+func (s *EnhancedSimulator) TargetBreakdown() map[string]EndpointStats {
+	s.stats.mu.RLock()
+	defer s.stats.mu.RUnlock()
+	return copyStatsMap(s.stats.Targets)
+}
@@ -0,0 +1,81 @@
+package simulator
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRateLimitPause bounds a wait on the engine's rate limit, in case a
+// reset time is far off or garbled
+const maxRateLimitPause = time.Minute
+
+// rateLimitPauses holds off requests of a token whose rate limit bucket the
+// engine reported used up, until the bucket resets. Anonymous requests share
+// the "" token, as they share an IP address on the engine.
+type rateLimitPauses struct {
+	mu    sync.Mutex
+	until map[rateLimitKey]time.Time
+}
+
+type rateLimitKey struct {
+	token  string
+	bucket string
+}
+
+// requestBucket is the engine's rate limit bucket for a method.
+func requestBucket(method string) string {
+	if method == http.MethodGet || method == http.MethodHead {
+		return "read"
+	}
+	return "write"
+}
+
+// wait sleeps until the bucket the request would count in has reset.
+func (p *rateLimitPauses) wait(token, method string) {
+	key := rateLimitKey{token, requestBucket(method)}
+	p.mu.Lock()
+	until, ok := p.until[key]
+	if ok && !time.Now().Before(until) {
+		delete(p.until, key)
+		ok = false
+	}
+	p.mu.Unlock()
+	if ok {
+		time.Sleep(time.Until(until))
+	}
+}
+
+// note reads the rate limit headers of a response, and pauses the token's
+// bucket if it's used up.
+func (p *rateLimitPauses) note(token string, resp *http.Response) {
+	var until time.Time
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			until = time.Now().Add(time.Duration(secs) * time.Second)
+		}
+	}
+	if until.IsZero() && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			until = time.Unix(reset, 0)
+		}
+	}
+	if until.IsZero() {
+		return
+	}
+	if limit := time.Now().Add(maxRateLimitPause); until.After(limit) {
+		until = limit
+	}
+
+	bucket := resp.Header.Get("X-RateLimit-Bucket")
+	if bucket == "" {
+		bucket = requestBucket(resp.Request.Method)
+	}
+	p.mu.Lock()
+	if p.until == nil {
+		p.until = make(map[rateLimitKey]time.Time)
+	}
+	p.until[rateLimitKey{token, bucket}] = until
+	p.mu.Unlock()
+}
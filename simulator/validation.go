@@ -0,0 +1,199 @@
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gator-swamp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// initialContentKarma is the karma a post or comment starts with on creation
+	initialContentKarma = 1
+	// initialUserKarma is the karma a newly registered user starts with
+	initialUserKarma = 300
+)
+
+// Discrepancy describes a single invariant violation found during validation.
+type Discrepancy struct {
+	Kind     string
+	EntityID uuid.UUID
+	Expected int
+	Actual   int
+}
+
+func (d Discrepancy) String() string {
+	return fmt.Sprintf("%s %s: expected %d, got %d", d.Kind, d.EntityID, d.Expected, d.Actual)
+}
+
+// ValidationReport summarizes a consistency check of backend state.
+type ValidationReport struct {
+	SubredditsChecked int
+	PostsChecked      int
+	CommentsChecked   int
+	UsersChecked      int
+	Discrepancies     []Discrepancy
+}
+
+// OK reports whether no invariant violations were found.
+func (r *ValidationReport) OK() bool {
+	return len(r.Discrepancies) == 0
+}
+
+func (r *ValidationReport) add(kind string, id uuid.UUID, expected, actual int) {
+	if expected != actual {
+		r.Discrepancies = append(r.Discrepancies, Discrepancy{kind, id, expected, actual})
+	}
+}
+
+// String renders the report for logging.
+func (r *ValidationReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "checked %d subreddits, %d posts, %d comments, %d users: %d discrepancies",
+		r.SubredditsChecked, r.PostsChecked, r.CommentsChecked, r.UsersChecked, len(r.Discrepancies))
+	for _, d := range r.Discrepancies {
+		fmt.Fprintf(&b, "\n  - %s", d)
+	}
+	return b.String()
+}
+
+// Validate fetches the simulated subreddits, posts, comments and users from the
+// API and verifies that the denormalized counters agree with the underlying data:
+//   - post comment_count equals the number of comments returned for the post
+//   - post and comment karma equals upvotes minus downvotes (plus the initial karma)
+//   - subreddit member_count equals the number of members
+//   - user karma equals the initial karma plus the karma earned on authored content
+//
+// It should be run after activity has stopped so counters are not in flight.
+func (s *EnhancedSimulator) Validate(ctx context.Context) (*ValidationReport, error) {
+	log.Printf("Validating backend consistency...")
+	report := &ValidationReport{}
+	earnedKarma := make(map[uuid.UUID]int)
+
+	s.mu.RLock()
+	subreddits := append([]uuid.UUID(nil), s.subreddits...)
+	users := make([]uuid.UUID, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, user.ID)
+	}
+	s.mu.RUnlock()
+
+	for _, subredditID := range subreddits {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		var subreddit models.Subreddit
+		if err := s.fetchJSON(ctx, fmt.Sprintf("/subreddit?id=%s", subredditID), &subreddit); err != nil {
+			return report, fmt.Errorf("failed to fetch subreddit %s: %v", subredditID, err)
+		}
+		members, err := fetchAll[uuid.UUID](ctx, s, fmt.Sprintf("/subreddit/members?id=%s", subredditID))
+		if err != nil {
+			return report, fmt.Errorf("failed to fetch members of %s: %v", subredditID, err)
+		}
+		report.add("subreddit member_count", subredditID, len(members), subreddit.Members)
+		report.SubredditsChecked++
+
+		posts, err := fetchAll[models.Post](ctx, s, fmt.Sprintf("/post?subredditId=%s", subredditID))
+		if err != nil {
+			return report, fmt.Errorf("failed to fetch posts of %s: %v", subredditID, err)
+		}
+
+		for _, post := range posts {
+			comments, err := fetchAll[models.Comment](ctx, s, fmt.Sprintf("/comment/post?postId=%s", post.ID))
+			if err != nil {
+				return report, fmt.Errorf("failed to fetch comments of post %s: %v", post.ID, err)
+			}
+
+			report.add("post comment_count", post.ID, len(comments), post.CommentCount)
+			report.add("post karma", post.ID, post.Upvotes-post.Downvotes+initialContentKarma, post.Karma)
+			earnedKarma[post.AuthorID] += post.Karma - initialContentKarma
+			report.PostsChecked++
+
+			for _, comment := range comments {
+				report.add("comment karma", comment.ID, comment.Upvotes-comment.Downvotes+initialContentKarma, comment.Karma)
+				earnedKarma[comment.AuthorID] += comment.Karma - initialContentKarma
+				report.CommentsChecked++
+			}
+		}
+	}
+
+	for _, userID := range users {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		var profile struct {
+			Karma int `json:"karma"`
+		}
+		if err := s.fetchJSON(ctx, fmt.Sprintf("/user/profile?userId=%s", userID), &profile); err != nil {
+			return report, fmt.Errorf("failed to fetch profile of %s: %v", userID, err)
+		}
+		report.add("user karma", userID, initialUserKarma+earnedKarma[userID], profile.Karma)
+		report.UsersChecked++
+	}
+
+	return report, nil
+}
+
+// listPage is the envelope the engine wraps list responses in.
+type listPage[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor"`
+	HasMore    bool   `json:"hasMore"`
+}
+
+// fetchAll GETs every page of a list endpoint, whose URL must already have a
+// query string, following nextCursor until hasMore is false.
+func fetchAll[T any](ctx context.Context, s *EnhancedSimulator, endpoint string) ([]T, error) {
+	items := []T{}
+	cursor := ""
+	for {
+		pageURL := endpoint + "&limit=500"
+		if cursor != "" {
+			pageURL += "&cursor=" + url.QueryEscape(cursor)
+		}
+		var page listPage[T]
+		if err := s.fetchJSON(ctx, pageURL, &page); err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		if !page.HasMore || page.NextCursor == "" {
+			return items, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// fetchJSON performs a GET against the engine and decodes the response body.
+// It bypasses fault injection so validation sees the backend's real state.
+func (s *EnhancedSimulator) fetchJSON(ctx context.Context, endpoint string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.config.EngineURL+endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, out)
+}